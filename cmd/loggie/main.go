@@ -30,17 +30,25 @@ import (
 	"github.com/loggie-io/loggie/pkg/control"
 	"github.com/loggie-io/loggie/pkg/core/cfg"
 	"github.com/loggie-io/loggie/pkg/core/global"
+	"github.com/loggie-io/loggie/pkg/core/health"
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/pressure"
 	"github.com/loggie-io/loggie/pkg/core/reloader"
 	"github.com/loggie-io/loggie/pkg/core/signals"
 	"github.com/loggie-io/loggie/pkg/core/sysconfig"
+	"github.com/loggie-io/loggie/pkg/discovery/git"
 	"github.com/loggie-io/loggie/pkg/discovery/kubernetes"
 	"github.com/loggie-io/loggie/pkg/eventbus"
+	promeExporter "github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
 	_ "github.com/loggie-io/loggie/pkg/include"
 	"github.com/loggie-io/loggie/pkg/ops"
+	"github.com/loggie-io/loggie/pkg/ops/authz"
+	"github.com/loggie-io/loggie/pkg/ops/fleet"
 	"github.com/loggie-io/loggie/pkg/ops/helper"
+	"github.com/loggie-io/loggie/pkg/pipeline"
 	"github.com/loggie-io/loggie/pkg/util/json"
 	"github.com/loggie-io/loggie/pkg/util/persistence"
+	"github.com/loggie-io/loggie/pkg/util/regex"
 	"github.com/loggie-io/loggie/pkg/util/yaml"
 	"github.com/pkg/errors"
 	"go.uber.org/automaxprocs/maxprocs"
@@ -90,6 +98,11 @@ func main() {
 	cfg.UnpackTypeDefaultsAndValidate(strings.ToLower(configType), globalConfigFile, &syscfg)
 	// register jsonEngine
 	json.SetDefaultEngine(syscfg.Loggie.JSONEngine)
+	// the regex engine is shared by all the pipelines, set up before compiling any pattern
+	regex.SetConfig(syscfg.Loggie.Regex)
+	// fleet labels are attached to the metrics, alerts and grpc batches
+	global.SetFleetLabels(syscfg.Loggie.Fleet)
+	promeExporter.SetConstLabels(global.FleetLabels())
 	// start eventBus listeners
 	eventbus.StartAndRun(syscfg.Loggie.MonitorEventBus)
 	// init log after error func
@@ -97,6 +110,8 @@ func main() {
 	log.AfterErrorConfig = syscfg.Loggie.ErrorAlertConfig
 
 	log.Info("pipelines config path: %s", pipelineConfigPath)
+	// node-local overrides are layered on the pipelines
+	control.SetOverrideConfig(syscfg.Loggie.Overrides)
 	// pipeline config file
 	pipecfgs, err := control.ReadPipelineConfig(pipelineConfigPath, configType, func(s os.FileInfo) bool {
 		return false
@@ -114,6 +129,10 @@ func main() {
 	}
 
 	persistence.SetConfig(syscfg.Loggie.Db)
+	health.SetConfig(syscfg.Loggie.Health)
+	pressure.SetConfig(syscfg.Loggie.NodePressure)
+	go pressure.Start(stopCh)
+	pipeline.SetQuarantineConfig(syscfg.Loggie.Quarantine)
 	defer persistence.StopDbHandler()
 
 	controller := control.NewController()
@@ -133,10 +152,23 @@ func main() {
 		go k8sDiscovery.Start(stopCh)
 	}
 
+	if syscfg.Loggie.Discovery.Git.Enabled {
+		if !syscfg.Loggie.Reload.Enabled {
+			log.Warn("reload is disabled, the pipelines pulled from git would not be applied until restarted")
+		}
+		gitcfg := syscfg.Loggie.Discovery.Git
+		gitcfg.ConfigFilePath = filepath.Dir(pipelineConfigPath)
+		gitSyncer := git.NewSyncer(&gitcfg)
+
+		go gitSyncer.Start(stopCh)
+	}
+
 	// api for debugging
 	helper.Setup(controller)
 	// api for get loggie Version
 	ops.Setup(controller)
+	// api for the fleet view of the agents reporting to this aggregator
+	fleet.Setup(&syscfg.Loggie.Inventory)
 
 	if syscfg.Loggie.Http.Enabled {
 		go func() {
@@ -150,7 +182,7 @@ func main() {
 			}
 
 			log.Info("http listen addr %s", listener.Addr().String())
-			if err = http.Serve(listener, nil); err != nil {
+			if err = http.Serve(listener, authz.Handler(syscfg.Loggie.Http.Auth, http.DefaultServeMux)); err != nil {
 				log.Fatal("http serve err: %v", err)
 			}
 		}()
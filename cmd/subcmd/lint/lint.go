@@ -0,0 +1,132 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/sysconfig"
+	"github.com/loggie-io/loggie/pkg/ops/lint"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	SubCommandLint = "lint"
+
+	formatText = "text"
+	formatJson = "json"
+)
+
+var (
+	lintCmd            *flag.FlagSet
+	globalConfigFile   string
+	pipelineConfigPath string
+	format             string
+	failOn             string
+)
+
+func init() {
+	lintCmd = flag.NewFlagSet(SubCommandLint, flag.ExitOnError)
+	lintCmd.StringVar(&globalConfigFile, "config.system", "", "global config file, defaults in it would be merged into pipelines")
+	lintCmd.StringVar(&pipelineConfigPath, "config.pipeline", "pipelines.yml", "pipeline config files, glob pattern is supported")
+	lintCmd.StringVar(&format, "format", formatText, "output format, text or json")
+	lintCmd.StringVar(&failOn, "failOn", string(lint.SeverityError), "exit with non-zero code when there are findings at least this severe, error, warning, info or none")
+}
+
+type report struct {
+	Findings []lint.Finding `json:"findings"`
+	Errors   int            `json:"errors"`
+	Warnings int            `json:"warnings"`
+	Infos    int            `json:"infos"`
+}
+
+func RunLint() error {
+	if len(os.Args) > 2 {
+		if err := lintCmd.Parse(os.Args[2:]); err != nil {
+			return err
+		}
+	}
+
+	if err := loadDefaults(); err != nil {
+		fmt.Fprintf(os.Stderr, "load system config %s failed: %v\n", globalConfigFile, err)
+		os.Exit(2)
+	}
+
+	files, err := filepath.Glob(pipelineConfigPath)
+	if err != nil || len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "no pipeline config files match %s\n", pipelineConfigPath)
+		os.Exit(2)
+	}
+
+	r := report{Findings: make([]lint.Finding, 0)}
+	rules := lint.DefaultRules()
+	for _, f := range files {
+		if s, err := os.Stat(f); err != nil || s.IsDir() {
+			continue
+		}
+		r.Findings = append(r.Findings, lint.LintFile(f, rules)...)
+	}
+
+	failed := false
+	for _, f := range r.Findings {
+		switch f.Severity {
+		case lint.SeverityError:
+			r.Errors++
+		case lint.SeverityWarning:
+			r.Warnings++
+		case lint.SeverityInfo:
+			r.Infos++
+		}
+		if failOn != "none" && f.Severity.AtLeast(lint.Severity(failOn)) {
+			failed = true
+		}
+	}
+
+	if format == formatJson {
+		out, _ := json.MarshalIndent(r, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		for _, f := range r.Findings {
+			fmt.Println(f.String())
+			if f.Explanation != "" {
+				fmt.Printf("    %s\n", f.Explanation)
+			}
+		}
+		fmt.Printf("%d errors, %d warnings, %d infos\n", r.Errors, r.Warnings, r.Infos)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+	return errors.New("exit")
+}
+
+// loadDefaults sets the default pipeline config which would be merged into every pipeline
+func loadDefaults() error {
+	if globalConfigFile == "" {
+		defaults := &sysconfig.Defaults{}
+		defaults.SetDefaults()
+		return nil
+	}
+	syscfg := &sysconfig.Config{}
+	return cfg.UnPackFromFile(globalConfigFile, syscfg).Defaults().Validate().Do()
+}
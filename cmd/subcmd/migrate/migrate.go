@@ -0,0 +1,107 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/loggie-io/loggie/pkg/ops/migrate"
+)
+
+const SubCommandMigrate = "migrate"
+
+var (
+	migrateCmd *flag.FlagSet
+	from       string
+	configFile string
+	outputFile string
+)
+
+func init() {
+	migrateCmd = flag.NewFlagSet(SubCommandMigrate, flag.ExitOnError)
+	migrateCmd.StringVar(&from, "from", migrate.FromFilebeat, "the agent which the config belongs to, filebeat or fluentbit")
+	migrateCmd.StringVar(&configFile, "config", "", "config file of the agent, such as filebeat.yml or fluent-bit.conf")
+	migrateCmd.StringVar(&outputFile, "output", "", "the converted pipeline config file, print to stdout if empty")
+}
+
+func RunMigrate() error {
+	if len(os.Args) > 2 {
+		if err := migrateCmd.Parse(os.Args[2:]); err != nil {
+			return err
+		}
+	}
+	if configFile == "" {
+		fmt.Fprintln(os.Stderr, "-config is required")
+		os.Exit(2)
+	}
+
+	result, err := convert()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "convert %s config %s failed: %v\n", from, configFile, err)
+		os.Exit(2)
+	}
+
+	out, err := result.YAML()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "marshal pipelines failed: %v\n", err)
+		os.Exit(2)
+	}
+
+	// unsupported features are kept as comments, so they would not be lost when the output is saved to a file
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# converted from %s config %s\n", from, configFile)
+	for _, u := range result.Unsupported {
+		fmt.Fprintf(buf, "# unsupported: %s\n", u.String())
+	}
+	buf.Write(out)
+
+	if outputFile == "" {
+		fmt.Print(buf.String())
+	} else if err := os.WriteFile(outputFile, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "write %s failed: %v\n", outputFile, err)
+		os.Exit(2)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d pipelines converted, %d unsupported features\n", len(result.Pipelines), len(result.Unsupported))
+	for _, u := range result.Unsupported {
+		fmt.Fprintf(os.Stderr, "  %s\n", u.String())
+	}
+	return errors.New("exit")
+}
+
+func convert() (*migrate.Result, error) {
+	switch from {
+	case migrate.FromFilebeat:
+		content, err := os.ReadFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		return migrate.Filebeat(content)
+
+	case migrate.FromFluentBit:
+		sections, err := migrate.LoadFluentBit(configFile)
+		if err != nil {
+			return nil, err
+		}
+		return migrate.FluentBit(sections)
+	}
+	return nil, fmt.Errorf("unknown agent %s, filebeat or fluentbit is supported", from)
+}
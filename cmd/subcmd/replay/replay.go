@@ -0,0 +1,99 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/ops/replay"
+)
+
+const SubCommandReplay = "replay"
+
+var (
+	replayCmd *flag.FlagSet
+	files     string
+	sinkFile  string
+	opts      = replay.DefaultOptions()
+)
+
+func init() {
+	replayCmd = flag.NewFlagSet(SubCommandReplay, flag.ExitOnError)
+	replayCmd.StringVar(&files, "files", "", "glob of the dead letter files written by the json codec, such as /data/deadletter/*.log")
+	replayCmd.StringVar(&sinkFile, "sink", "", "file contains the sink config which the events are replayed to, the same as the sink of pipeline")
+	replayCmd.StringVar(&opts.Pipeline, "pipeline", "", "only replay the events failed in this pipeline")
+	replayCmd.StringVar(&opts.FailureKey, "failureKey", opts.FailureKey, "header key of the failure metadata, the same as deadLetter.failureKey of pipeline")
+	replayCmd.StringVar(&opts.BodyKey, "bodyKey", opts.BodyKey, "header key of the event body, use message if beatsFormat of the json codec is enabled")
+	replayCmd.IntVar(&opts.BatchSize, "batchSize", opts.BatchSize, "events sent to the sink in a batch")
+	replayCmd.IntVar(&opts.MaxRetries, "maxRetries", opts.MaxRetries, "retries of a failed batch before the replay is aborted")
+}
+
+func RunReplay() error {
+	if len(os.Args) > 2 {
+		if err := replayCmd.Parse(os.Args[2:]); err != nil {
+			return err
+		}
+	}
+	if files == "" || sinkFile == "" {
+		fmt.Fprintln(os.Stderr, "-files and -sink are required")
+		os.Exit(2)
+	}
+
+	matches, err := filepath.Glob(files)
+	if err != nil || len(matches) == 0 {
+		fmt.Fprintf(os.Stderr, "no dead letter files matched %s\n", files)
+		os.Exit(2)
+	}
+
+	s, err := replay.StartSink(sinkFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "start sink failed: %v\n", err)
+		os.Exit(2)
+	}
+	defer s.Stop()
+
+	total := replay.Stats{}
+	for _, name := range matches {
+		stats, err := replayFile(name, s)
+		total.Replayed += stats.Replayed
+		total.Filtered += stats.Filtered
+		total.Invalid += stats.Invalid
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "replay %s failed after %d events: %v\n", name, stats.Replayed, err)
+			s.Stop()
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d replayed, %d filtered, %d invalid\n", name, stats.Replayed, stats.Filtered, stats.Invalid)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d files, %d events replayed, %d filtered, %d invalid\n", len(matches), total.Replayed, total.Filtered, total.Invalid)
+	return errors.New("exit")
+}
+
+func replayFile(name string, s api.Sink) (replay.Stats, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return replay.Stats{}, err
+	}
+	defer f.Close()
+	return replay.Replay(f, s, opts)
+}
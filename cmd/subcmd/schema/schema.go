@@ -0,0 +1,83 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/ops/schema"
+)
+
+const (
+	SubCommandSchema = "schema"
+)
+
+var (
+	schemaCmd *flag.FlagSet
+	component string
+	output    string
+)
+
+func init() {
+	schemaCmd = flag.NewFlagSet(SubCommandSchema, flag.ExitOnError)
+	schemaCmd.StringVar(&component, "component", "", "export the schema of a single component such as sink/kafka, the pipelines schema is exported by default")
+	schemaCmd.StringVar(&output, "output", "", "file to write the schema to, stdout by default")
+}
+
+// RunSchema exports the json schema of the pipelines config, which could be used by editors for completion and validation
+func RunSchema() error {
+	if len(os.Args) > 2 {
+		if err := schemaCmd.Parse(os.Args[2:]); err != nil {
+			return err
+		}
+	}
+
+	s, err := export()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export schema failed: %v\n", err)
+		os.Exit(1)
+	}
+	out, err := schema.Marshal(s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "marshal schema failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if output == "" {
+		fmt.Println(string(out))
+	} else if err := os.WriteFile(output, append(out, '\n'), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "write schema to %s failed: %v\n", output, err)
+		os.Exit(1)
+	}
+	return errors.New("exit")
+}
+
+func export() (*schema.Schema, error) {
+	if component == "" {
+		return schema.Pipelines()
+	}
+	parts := strings.SplitN(component, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("component %s should be in the form of category/type, such as sink/kafka", component)
+	}
+	return schema.Component(api.Category(parts[0]), api.Type(parts[1]))
+}
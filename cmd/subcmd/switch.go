@@ -19,6 +19,10 @@ package subcmd
 import (
 	"github.com/loggie-io/loggie/cmd/subcmd/genfiles"
 	"github.com/loggie-io/loggie/cmd/subcmd/inspect"
+	"github.com/loggie-io/loggie/cmd/subcmd/lint"
+	"github.com/loggie-io/loggie/cmd/subcmd/migrate"
+	"github.com/loggie-io/loggie/cmd/subcmd/replay"
+	"github.com/loggie-io/loggie/cmd/subcmd/schema"
 	"github.com/loggie-io/loggie/cmd/subcmd/version"
 	"os"
 )
@@ -38,6 +42,26 @@ func SwitchSubCommand() error {
 			return err
 		}
 
+	case lint.SubCommandLint:
+		if err := lint.RunLint(); err != nil {
+			return err
+		}
+
+	case migrate.SubCommandMigrate:
+		if err := migrate.RunMigrate(); err != nil {
+			return err
+		}
+
+	case replay.SubCommandReplay:
+		if err := replay.RunReplay(); err != nil {
+			return err
+		}
+
+	case schema.SubCommandSchema:
+		if err := schema.RunSchema(); err != nil {
+			return err
+		}
+
 	case version.SubCommandVersion:
 		if err := version.RunVersion(); err != nil {
 			return err
@@ -121,12 +121,29 @@ func ReadPipelineConfigFromFile(path string, ignore FileIgnore) (*PipelineConfig
 		allIgnored = false
 	}
 
+	overrides, err := overrideFiles()
+	if err != nil {
+		return nil, err
+	}
+	// the pipelines are read again when the overrides are modified
+	for _, m := range overrides {
+		s, err := os.Stat(m)
+		if err != nil {
+			return nil, err
+		}
+		if !ignore(s) {
+			allIgnored = false
+		}
+	}
+
 	// if all files are ignored, then do not read any file.
 	if allIgnored {
 		return pipecfgs, ErrIgnoreAllFile
 	}
 
 	// if any file should not be ignored, then all files are read.
+	overrideList := readOverrides(overrides)
+	appliedOverrides := make(map[string][]string)
 	for _, fn := range all {
 		pipes := &PipelineConfig{}
 		unpack := cfg.UnPackFromFile(fn, pipes)
@@ -136,6 +153,12 @@ func ReadPipelineConfigFromFile(path string, ignore FileIgnore) (*PipelineConfig
 		}
 
 		for _, p := range pipes.Pipelines {
+			if pip, from, ok := overridePipeline(p, overrideList); ok {
+				pipecfgs.AddPipelines([]pipeline.Config{pip})
+				appliedOverrides[p.Name] = from
+				continue
+			}
+
 			pip := p
 			if err := cfg.NewUnpack(nil, &pip, nil).Defaults().Validate().Do(); err != nil {
 				// ignore invalid pipeline, but continue to read other pipelines
@@ -146,9 +169,28 @@ func ReadPipelineConfigFromFile(path string, ignore FileIgnore) (*PipelineConfig
 			pipecfgs.AddPipelines([]pipeline.Config{pip})
 		}
 	}
+	setApplied(appliedOverrides)
 	return pipecfgs, nil
 }
 
+// overridePipeline returns the pipeline with the overrides applied, the overrides are not applied when
+// they make the pipeline invalid
+func overridePipeline(p pipeline.Config, overrides []*Override) (pipeline.Config, []string, bool) {
+	pip, from, err := applyOverrides(p, overrides)
+	if err != nil {
+		log.Error("apply overrides to pipeline %s failed: %v", p.Name, err)
+		return p, nil, false
+	}
+	if len(from) == 0 {
+		return p, nil, false
+	}
+	if err := cfg.NewUnpack(nil, &pip, nil).Defaults().Validate().Do(); err != nil {
+		log.Error("pipeline: %s configs invalid with overrides %v: %v, the overrides are not applied", p.Name, from, err)
+		return p, nil, false
+	}
+	return pip, from, true
+}
+
 func ReadPipelineConfigFromEnv(key string, _ FileIgnore) (*PipelineConfig, error) {
 	pipecfgs := &PipelineConfig{}
 	if err := cfg.UnpackFromEnv(key, pipecfgs).Defaults().Validate().Do(); err != nil {
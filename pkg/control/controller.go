@@ -17,6 +17,8 @@ limitations under the License.
 package control
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	_ "net/http/pprof"
 	"time"
 
@@ -24,6 +26,7 @@ import (
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/eventbus"
 	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/yaml"
 )
 
 type Controller struct {
@@ -134,5 +137,15 @@ func (c *Controller) reportMetric(p pipeline.Config, eventType eventbus.Componen
 		Name:             p.Name,
 		Time:             time.Now(),
 		ComponentConfigs: componentConfigs,
+		ConfigHash:       configHash(p),
 	})
 }
+
+func configHash(p pipeline.Config) string {
+	out, err := yaml.Marshal(p)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(out)
+	return hex.EncodeToString(sum[:8])
+}
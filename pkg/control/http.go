@@ -22,10 +22,38 @@ import (
 	"net/http"
 )
 
-const HandleCurrentPipelines = "/api/v1/controller/pipelines"
+const (
+	HandleCurrentPipelines = "/api/v1/controller/pipelines"
+	HandleOverrides        = "/api/v1/controller/overrides"
+)
 
 func (c *Controller) initHttp() {
 	http.HandleFunc(HandleCurrentPipelines, c.currentPipelinesHandler)
+	http.HandleFunc(HandleOverrides, c.overridesHandler)
+}
+
+type overridesResponse struct {
+	Path string `yaml:"path"`
+	// Pipelines are the overrides applied to each pipeline in order
+	Pipelines map[string][]string `yaml:"pipelines"`
+}
+
+// overridesHandler shows which overrides the pipelines are layered with, the effective pipelines are
+// shown by HandleCurrentPipelines
+func (c *Controller) overridesHandler(writer http.ResponseWriter, request *http.Request) {
+	data, err := yaml.Marshal(&overridesResponse{
+		Path:      overrideConfig.Path,
+		Pipelines: AppliedOverrides(),
+	})
+	if err != nil {
+		log.Warn("marshal overrides err: %v", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		writer.Write([]byte(err.Error()))
+		return
+	}
+
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(data)
 }
 
 func (c *Controller) currentPipelinesHandler(writer http.ResponseWriter, request *http.Request) {
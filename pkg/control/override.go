@@ -0,0 +1,294 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/yaml"
+	"github.com/pkg/errors"
+)
+
+// OverrideConfig points to the node-local override files, which are layered on the pipeline configs distributed to
+// every node, e.g. a higher rate limit on the big nodes.
+//
+// The precedence from low to high is:
+//  1. loggie.defaults in the system config, which only fill in what the pipelines leave unset
+//  2. the pipeline configs in the config.pipeline path, written by kubernetes discovery, git syncer or by hand
+//  3. the override files in lexical order of the file names, and the overrides in their order in a file
+type OverrideConfig struct {
+	// Path is a glob of the override files, e.g. /etc/loggie/overrides/*.yml
+	Path string `yaml:"path,omitempty"`
+}
+
+var (
+	overrideConfig OverrideConfig
+
+	appliedLock sync.RWMutex
+	// applied records the overrides of each pipeline in the last read
+	applied = make(map[string][]string)
+)
+
+func SetOverrideConfig(c OverrideConfig) {
+	overrideConfig = c
+}
+
+type OverrideFile struct {
+	Overrides []*Override `yaml:"overrides" validate:"dive,required"`
+}
+
+func (f *OverrideFile) Validate() error {
+	for i, o := range f.Overrides {
+		if err := o.Validate(); err != nil {
+			return errors.WithMessagef(err, "overrides[%d]", i)
+		}
+	}
+	return nil
+}
+
+// Override patches the pipelines whose names match.
+// The patch is deep merged into a pipeline: maps are merged by keys, a null value removes the key, the sources are
+// matched by name and the interceptors by type and name, which are appended when not matched, other values are replaced.
+type Override struct {
+	// Pipelines are the glob patterns of the pipeline names, all the pipelines are matched when empty
+	Pipelines []string      `yaml:"pipelines,omitempty"`
+	Patch     cfg.CommonCfg `yaml:"patch,omitempty"`
+
+	// from is file#index for the management api
+	from string
+}
+
+func (o *Override) Validate() error {
+	if len(o.Patch) == 0 {
+		return errors.New("patch is required")
+	}
+	if _, ok := o.Patch["name"]; ok {
+		return errors.New("the name of the pipelines cannot be overridden")
+	}
+	for _, p := range o.Pipelines {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return errors.WithMessagef(err, "invalid pipelines pattern %s", p)
+		}
+	}
+	return nil
+}
+
+func (o *Override) match(name string) bool {
+	if len(o.Pipelines) == 0 {
+		return true
+	}
+	for _, p := range o.Pipelines {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// overrideFiles returns the override files in lexical order
+func overrideFiles() ([]string, error) {
+	if overrideConfig.Path == "" {
+		return nil, nil
+	}
+	matches, err := filepath.Glob(overrideConfig.Path)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+
+	var files []string
+	for _, m := range matches {
+		s, err := os.Stat(m)
+		if err != nil {
+			return nil, err
+		}
+		if s.IsDir() {
+			continue
+		}
+		files = append(files, m)
+	}
+	return files, nil
+}
+
+func readOverrides(files []string) []*Override {
+	var overrides []*Override
+	for _, fn := range files {
+		f := &OverrideFile{}
+		if err := cfg.UnPackFromFile(fn, f).Validate().Do(); err != nil {
+			// an invalid file is skipped as a whole, rather than applying a part of it
+			log.Error("read override file %s failed: %v", fn, err)
+			continue
+		}
+		for i, o := range f.Overrides {
+			o.from = fmt.Sprintf("%s#%d", fn, i)
+			overrides = append(overrides, o)
+		}
+	}
+	return overrides
+}
+
+// applyOverrides returns the pipeline patched by the matched overrides and where they come from
+func applyOverrides(p pipeline.Config, overrides []*Override) (pipeline.Config, []string, error) {
+	var matched []*Override
+	for _, o := range overrides {
+		if o.match(p.Name) {
+			matched = append(matched, o)
+		}
+	}
+	if len(matched) == 0 {
+		return p, nil, nil
+	}
+
+	base, err := cfg.Pack(p)
+	if err != nil {
+		return p, nil, err
+	}
+	var from []string
+	for _, o := range matched {
+		// the patch is shared by the pipelines, and the maps of it would be merged into
+		patchPipeline(base, copyValue(map[string]interface{}(o.Patch)).(map[string]interface{}))
+		from = append(from, o.from)
+	}
+
+	out, err := yaml.Marshal(base)
+	if err != nil {
+		return p, nil, err
+	}
+	patched := pipeline.Config{}
+	if err := cfg.UnPackFromRaw(out, &patched).Do(); err != nil {
+		return p, nil, errors.WithMessage(err, "unpack overridden pipeline")
+	}
+	return patched, from, nil
+}
+
+func patchPipeline(base cfg.CommonCfg, patch cfg.CommonCfg) {
+	for k, v := range patch {
+		if v == nil {
+			delete(base, k)
+			continue
+		}
+		switch k {
+		case "sources":
+			base[k] = patchList(base[k], v, "name")
+		case "interceptors":
+			base[k] = patchList(base[k], v, "type", "name")
+		default:
+			base[k] = patchValue(base[k], v)
+		}
+	}
+}
+
+func patchValue(base interface{}, patch interface{}) interface{} {
+	b, okb := base.(map[interface{}]interface{})
+	p, okp := patch.(map[interface{}]interface{})
+	if !okb || !okp {
+		return patch
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(b, k)
+			continue
+		}
+		b[k] = patchValue(b[k], v)
+	}
+	return b
+}
+
+// patchList merges the items with the same keys, and appends the others
+func patchList(base interface{}, patch interface{}, keys ...string) interface{} {
+	b, okb := base.([]interface{})
+	p, okp := patch.([]interface{})
+	if !okb || !okp {
+		return patch
+	}
+
+	uid := func(item interface{}) string {
+		m, ok := item.(map[interface{}]interface{})
+		if !ok {
+			return ""
+		}
+		var id string
+		for _, k := range keys {
+			id += fmt.Sprintf("%v/", m[k])
+		}
+		return id
+	}
+
+	for _, item := range p {
+		id := uid(item)
+		merged := false
+		for i := range b {
+			if id != "" && uid(b[i]) == id {
+				b[i] = patchValue(b[i], item)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			b = append(b, item)
+		}
+	}
+	return b
+}
+
+func copyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			out[k] = copyValue(e)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[interface{}]interface{}, len(val))
+		for k, e := range val {
+			out[k] = copyValue(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, e := range val {
+			out[i] = copyValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+func setApplied(a map[string][]string) {
+	appliedLock.Lock()
+	defer appliedLock.Unlock()
+	applied = a
+}
+
+// AppliedOverrides returns the overrides of each pipeline in the last read
+func AppliedOverrides() map[string][]string {
+	appliedLock.RLock()
+	defer appliedLock.RUnlock()
+	out := make(map[string][]string, len(applied))
+	for k, v := range applied {
+		out[k] = v
+	}
+	return out
+}
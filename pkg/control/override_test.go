@@ -0,0 +1,145 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/limit"
+	_ "github.com/loggie-io/loggie/pkg/queue/channel"
+	_ "github.com/loggie-io/loggie/pkg/sink/dev"
+	_ "github.com/loggie-io/loggie/pkg/source/dev"
+)
+
+const distributedPipelines = `
+pipelines:
+  - name: k8s-app
+    sources:
+      - type: dev
+        name: a
+        qps: 10
+      - type: dev
+        name: b
+        qps: 10
+    interceptors:
+      - type: rateLimit
+        qps: 1000
+    queue:
+      type: channel
+    sink:
+      type: dev
+      printEvents: true
+  - name: local
+    sources:
+      - type: dev
+        name: a
+    queue:
+      type: channel
+    sink:
+      type: dev
+`
+
+func readWithOverrides(t *testing.T, overrides map[string]string) *PipelineConfig {
+	log.InitDefaultLogger()
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "pipelines.yml"), []byte(distributedPipelines), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "overrides"), 0755))
+	for name, content := range overrides {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "overrides", name), []byte(content), 0644))
+	}
+
+	SetOverrideConfig(OverrideConfig{Path: filepath.Join(dir, "overrides", "*.yml")})
+	defer SetOverrideConfig(OverrideConfig{})
+
+	pipes, err := ReadPipelineConfigFromFile(filepath.Join(dir, "*.yml"), func(s os.FileInfo) bool {
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Len(t, pipes.Pipelines, 2)
+	return pipes
+}
+
+func TestOverridePipelines(t *testing.T) {
+	pipes := readWithOverrides(t, map[string]string{
+		"10-big-node.yml": `
+overrides:
+  - pipelines: ["k8s-*"]
+    patch:
+      interceptors:
+        - type: rateLimit
+          qps: 5000
+      sources:
+        - name: b
+          qps: 100
+      sink:
+        printEvents: ~
+`,
+		// applied later, which takes precedence
+		"20-debug.yml": `
+overrides:
+  - patch:
+      sources:
+        - name: a
+          qps: 1
+`,
+	})
+
+	app := pipes.Pipelines[0]
+	assert.Equal(t, "k8s-app", app.Name)
+	assert.Equal(t, 5000, app.Interceptors[0].Properties["qps"])
+	assert.Len(t, app.Sources, 2)
+	assert.Equal(t, 1, app.Sources[0].Properties["qps"])
+	assert.Equal(t, 100, app.Sources[1].Properties["qps"])
+	_, ok := app.Sink.Properties["printEvents"]
+	assert.False(t, ok)
+
+	local := pipes.Pipelines[1]
+	assert.Equal(t, 1, local.Sources[0].Properties["qps"])
+
+	applied := AppliedOverrides()
+	assert.Len(t, applied["k8s-app"], 2)
+	assert.Contains(t, applied["k8s-app"][0], "10-big-node.yml#0")
+	assert.Len(t, applied["local"], 1)
+}
+
+func TestOverrideInvalid(t *testing.T) {
+	pipes := readWithOverrides(t, map[string]string{
+		// makes the pipeline invalid, which is not applied
+		"10-invalid.yml": `
+overrides:
+  - pipelines: ["local"]
+    patch:
+      sink:
+        type: unknown
+`,
+		// the name cannot be overridden, the file is skipped
+		"20-rename.yml": `
+overrides:
+  - patch:
+      name: other
+`,
+	})
+
+	assert.Equal(t, "dev", pipes.Pipelines[1].Sink.Type)
+	assert.Equal(t, "local", pipes.Pipelines[1].Name)
+	assert.Empty(t, AppliedOverrides())
+}
@@ -130,6 +130,14 @@ type Queue interface {
 	OutChan() chan Batch
 }
 
+// ShardedQueue aggregates the batches in shards, the sink workers prefer the batches of their own shard,
+// which are sent to OutChan instead when the worker of the shard is busy
+type ShardedQueue interface {
+	Queue
+	Shards() int
+	ShardOutChan(index int) chan Batch
+}
+
 type Invocation interface {
 	Consumers() []Consumer
 	Selector() Selector
@@ -19,16 +19,27 @@ package concurrency
 import (
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"github.com/loggie-io/loggie/pkg/core/log"
 )
 
+const (
+	ModeRtt  = "rtt"
+	ModeAimd = "aimd"
+)
+
 type Config struct {
-	Enable    bool       `yaml:"enabled,omitempty"`
+	Enable bool `yaml:"enabled,omitempty"`
+	// Mode is rtt by default, which is tuned by goroutine, rtt, ratio and duration, or aimd
+	Mode      string     `yaml:"mode,omitempty"`
 	Goroutine *Goroutine `yaml:"goroutine,omitempty"`
 	Rtt       *Rtt       `yaml:"rtt,omitempty"`
 	Ratio     *Ratio     `yaml:"ratio,omitempty"`
 	Duration  *Duration  `yaml:"duration,omitempty"`
+	Aimd      *Aimd      `yaml:"aimd,omitempty"`
 }
 
 func (c *Config) Validate() error {
@@ -36,6 +47,18 @@ func (c *Config) Validate() error {
 		return nil
 	}
 
+	switch c.Mode {
+	case "", ModeRtt:
+	case ModeAimd:
+		if c.Aimd != nil {
+			if err := c.Aimd.Validate(); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.Errorf("concurrency mode %s is not supported, should be rtt or aimd", c.Mode)
+	}
+
 	if c.Rtt != nil {
 		log.Debug("check blockJudgeThreshold")
 		blockJudgeThreshold := c.Rtt.BlockJudgeThreshold
@@ -112,6 +135,11 @@ func (c *Config) SetDefaults() {
 		}
 	}
 
+	if c.Aimd == nil {
+		c.Aimd = &Aimd{}
+	}
+	c.Aimd.setDefaults()
+
 	if c.Duration == nil {
 		c.Duration = &Duration{
 			Unstable: 15,
@@ -149,3 +177,52 @@ type Duration struct {
 	Unstable int `yaml:"unstable,omitempty" default:"15" validate:"gte=1"`
 	Stable   int `yaml:"stable,omitempty" default:"30" validate:"gte=1"`
 }
+
+// Aimd adjusts the in-flight batches of the sink by additive increase and multiplicative decrease.
+// The consumers are increased by Increase every Interval while the latency is low and the queue has pending batches,
+// and are multiplied by DecreaseFactor once a batch failed or the latency spiked in the interval.
+type Aimd struct {
+	Min            int           `yaml:"min,omitempty" default:"2" validate:"gte=2"`
+	Max            int           `yaml:"max,omitempty" default:"32" validate:"gte=2"`
+	Interval       time.Duration `yaml:"interval,omitempty" default:"5s"`
+	Increase       int           `yaml:"increase,omitempty" default:"1" validate:"gte=1"`
+	DecreaseFactor float64       `yaml:"decreaseFactor,omitempty" default:"0.5" validate:"gt=0,lt=1"`
+	// LatencyRatio is the spike compared with the baseline latency, which follows the lowest latency observed
+	LatencyRatio float64 `yaml:"latencyRatio,omitempty" default:"2" validate:"gt=1"`
+	// MaxLatency is a spike regardless of the baseline if it is positive
+	MaxLatency time.Duration `yaml:"maxLatency,omitempty"`
+}
+
+func (a *Aimd) setDefaults() {
+	if a.Min == 0 {
+		a.Min = 2
+	}
+	if a.Max == 0 {
+		a.Max = 32
+	}
+	if a.Interval == 0 {
+		a.Interval = 5 * time.Second
+	}
+	if a.Increase == 0 {
+		a.Increase = 1
+	}
+	if a.DecreaseFactor == 0 {
+		a.DecreaseFactor = 0.5
+	}
+	if a.LatencyRatio == 0 {
+		a.LatencyRatio = 2
+	}
+}
+
+func (a *Aimd) Validate() error {
+	if a.Min > a.Max {
+		return errors.New("min of aimd should not be greater than max")
+	}
+	if a.DecreaseFactor < 0 || a.DecreaseFactor >= 1 {
+		return errors.New("decreaseFactor of aimd should be between 0 and 1")
+	}
+	if a.LatencyRatio != 0 && a.LatencyRatio <= 1 {
+		return errors.New("latencyRatio of aimd should be greater than 1")
+	}
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/global"
 	"github.com/loggie-io/loggie/pkg/core/log"
 )
 
@@ -28,6 +29,7 @@ const (
 	sourceName   = "sourceName"
 	pipelineName = "pipelineName"
 	timestamp    = "timestamp"
+	fleet        = "fleet"
 
 	meta = "_meta"
 
@@ -76,6 +78,10 @@ func NewAlert(e api.Event, lineLimit int) Alert {
 		}
 	}
 
+	if labels := global.FleetLabels(); len(labels) > 0 {
+		allMeta[fleet] = labels
+	}
+
 	alert := Alert{
 		meta: allMeta,
 	}
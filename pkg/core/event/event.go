@@ -34,6 +34,9 @@ const (
 	SystemSourceKey      = SystemKeyPrefix + "SourceName"
 	SystemProductTimeKey = SystemKeyPrefix + "ProductTime"
 
+	// RawBodyKey keeps the original body received by the source before it is decoded
+	RawBodyKey = PrivateKeyPrefix + "RawBody"
+
 	Body = "body"
 )
 
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package global
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// FleetConfig groups the agents, the labels are attached to the exported metrics, alerts and grpc batches,
+// so the dashboards of the fleet could be sliced by them without relabeling at scrape time
+type FleetConfig struct {
+	Cluster string `yaml:"cluster,omitempty"`
+	Region  string `yaml:"region,omitempty"`
+	Env     string `yaml:"env,omitempty"`
+	Team    string `yaml:"team,omitempty"`
+	// Labels are the additional labels, such as idc: bj
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+var labelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels are the labels of the exported metrics, which could not be overridden by the fleet labels
+var reservedLabels = map[string]struct{}{
+	"pipeline":    {},
+	"source":      {},
+	"interceptor": {},
+	"sink":        {},
+	"type":        {},
+	"filename":    {},
+	"status":      {},
+}
+
+func (c *FleetConfig) Validate() error {
+	for name := range c.Labels {
+		if !labelNameRegex.MatchString(name) {
+			return fmt.Errorf("fleet label name %s is invalid", name)
+		}
+		if _, ok := reservedLabels[name]; ok {
+			return fmt.Errorf("fleet label name %s is reserved", name)
+		}
+	}
+	return nil
+}
+
+// labels merges the fields and labels, empty values are skipped
+func (c *FleetConfig) labels() map[string]string {
+	labels := make(map[string]string)
+	for k, v := range c.Labels {
+		if v != "" {
+			labels[k] = v
+		}
+	}
+	for k, v := range map[string]string{"cluster": c.Cluster, "region": c.Region, "env": c.Env, "team": c.Team} {
+		if v != "" {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+var fleetLabels = make(map[string]string)
+
+// SetFleetLabels should be called before the metrics are exported
+func SetFleetLabels(config FleetConfig) {
+	fleetLabels = config.labels()
+}
+
+// FleetLabels should not be modified by the callers
+func FleetLabels() map[string]string {
+	return fleetLabels
+}
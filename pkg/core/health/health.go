@@ -0,0 +1,102 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"sort"
+	"sync"
+)
+
+type Config struct {
+	// SinkFailureThreshold is the number of consecutive failed batches to mark a sink as not ready
+	SinkFailureThreshold int `yaml:"sinkFailureThreshold,omitempty" default:"5" validate:"gte=1"`
+	// QueueUsageThreshold marks a queue as not ready when the ratio of the buffered events exceeds it
+	QueueUsageThreshold float64 `yaml:"queueUsageThreshold,omitempty" default:"0.9" validate:"gt=0,lte=1"`
+}
+
+// Checker returns the reason why the component is not ready
+type Checker func() error
+
+type ComponentStatus struct {
+	Name   string `json:"name"`
+	Ready  bool   `json:"ready"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type Status struct {
+	Ready      bool              `json:"ready"`
+	Components []ComponentStatus `json:"components"`
+}
+
+var (
+	lock     sync.RWMutex
+	config   = Config{SinkFailureThreshold: 5, QueueUsageThreshold: 0.9}
+	checkers = make(map[string]Checker)
+)
+
+func SetConfig(c Config) {
+	lock.Lock()
+	defer lock.Unlock()
+	config = c
+}
+
+func GetConfig() Config {
+	lock.RLock()
+	defer lock.RUnlock()
+	return config
+}
+
+// Register adds the readiness check of a component, the check with the same name is replaced
+func Register(name string, checker Checker) {
+	lock.Lock()
+	defer lock.Unlock()
+	checkers[name] = checker
+}
+
+func Unregister(name string) {
+	lock.Lock()
+	defer lock.Unlock()
+	delete(checkers, name)
+}
+
+// Report runs all the checks, it is ready only when all the components are ready
+func Report() Status {
+	lock.RLock()
+	names := make([]string, 0, len(checkers))
+	snapshot := make(map[string]Checker, len(checkers))
+	for name, c := range checkers {
+		names = append(names, name)
+		snapshot[name] = c
+	}
+	lock.RUnlock()
+	sort.Strings(names)
+
+	status := Status{
+		Ready:      true,
+		Components: make([]ComponentStatus, 0, len(names)),
+	}
+	for _, name := range names {
+		cs := ComponentStatus{Name: name, Ready: true}
+		if err := snapshot[name](); err != nil {
+			cs.Ready = false
+			cs.Reason = err.Error()
+			status.Ready = false
+		}
+		status.Components = append(status.Components, cs)
+	}
+	return status
+}
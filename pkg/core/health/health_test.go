@@ -0,0 +1,49 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	defer func() {
+		Unregister("registry")
+		Unregister("pipeline/a/sink/es")
+	}()
+
+	assert.True(t, Report().Ready)
+
+	Register("registry", func() error { return nil })
+	Register("pipeline/a/sink/es", func() error { return errors.New("connection refused") })
+	status := Report()
+	assert.False(t, status.Ready)
+	assert.Equal(t, []ComponentStatus{
+		{Name: "pipeline/a/sink/es", Ready: false, Reason: "connection refused"},
+		{Name: "registry", Ready: true},
+	}, status.Components)
+
+	// replaced by the restarted component
+	Register("pipeline/a/sink/es", func() error { return nil })
+	assert.True(t, Report().Ready)
+
+	Unregister("pipeline/a/sink/es")
+	assert.Equal(t, 1, len(Report().Components))
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pressure
+
+import "syscall"
+
+// diskUsage returns the used ratios of the space and inodes of the file system,
+// the space reserved for root is excluded, the same as df
+func diskUsage(path string) (float64, float64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+
+	var usage, inodes float64
+	if total := float64(st.Blocks-st.Bfree) + float64(st.Bavail); total > 0 {
+		usage = float64(st.Blocks-st.Bfree) / total
+	}
+	if st.Files > 0 {
+		inodes = float64(st.Files-st.Ffree) / float64(st.Files)
+	}
+	return usage, inodes, nil
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pressure
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+)
+
+const (
+	pressureReason   = "NodePressure"
+	relievedReason   = "NodePressureRelieved"
+	pressuresKey     = "pressures"
+	pressureSinceKey = "since"
+)
+
+// Config protects the node when it is under resource pressure, a DaemonSet agent catching up a large backlog
+// on a node running out of disk or pids would make the incident worse
+type Config struct {
+	Enabled  bool          `yaml:"enabled,omitempty"`
+	Interval time.Duration `yaml:"interval,omitempty" default:"10s"`
+	// NodeConditions follows the DiskPressure, PIDPressure and MemoryPressure conditions of the node reported by
+	// kubelet, which are watched by the kubernetes discovery
+	NodeConditions *bool        `yaml:"nodeConditions,omitempty" default:"true"`
+	Disk           DiskConfig   `yaml:"disk,omitempty"`
+	PID            PIDConfig    `yaml:"pid,omitempty"`
+	Actions        ActionConfig `yaml:"actions,omitempty"`
+}
+
+type DiskConfig struct {
+	// Paths are the file systems checked, such as the host paths of the logs mounted into the agent
+	Paths []string `yaml:"paths,omitempty"`
+	// UsageThreshold and InodesThreshold are the used ratios of the space and inodes, 0 disables the check
+	UsageThreshold  float64 `yaml:"usageThreshold,omitempty" default:"0.9" validate:"gte=0,lte=1"`
+	InodesThreshold float64 `yaml:"inodesThreshold,omitempty" default:"0.9" validate:"gte=0,lte=1"`
+}
+
+type PIDConfig struct {
+	// UsageThreshold is the ratio of the tasks in /proc/loadavg to kernel.pid_max, 0 disables the check
+	UsageThreshold float64 `yaml:"usageThreshold,omitempty" default:"0.9" validate:"gte=0,lte=1"`
+}
+
+type ActionConfig struct {
+	// PauseCatchUp stops reading the files with more than CatchUpBytes unread, they are resumed once the pressure is relieved
+	PauseCatchUp *bool `yaml:"pauseCatchUp,omitempty" default:"true"`
+	CatchUpBytes int64 `yaml:"catchUpBytes,omitempty" default:"1048576" validate:"gte=0"`
+	// ReadBufferSize and MaxContinueRead shrink the reading of the file sources, 0 keeps the configured ones
+	ReadBufferSize  int `yaml:"readBufferSize,omitempty" default:"16384" validate:"gte=0"`
+	MaxContinueRead int `yaml:"maxContinueRead,omitempty" default:"1" validate:"gte=0"`
+}
+
+type State struct {
+	Under bool `json:"under"`
+	// Pressures are the reasons of the pressure, e.g. DiskPressure, disk /var/log usage 95.1%
+	Pressures []string  `json:"pressures,omitempty"`
+	Since     time.Time `json:"since,omitempty"`
+}
+
+var (
+	lock   sync.RWMutex
+	config Config
+	// the pressures reported by kubelet and found by the local probes
+	nodePressures  []string
+	localPressures []string
+	state          State
+
+	under = atomic.NewBool(false)
+)
+
+func SetConfig(c Config) {
+	lock.Lock()
+	defer lock.Unlock()
+	config = c
+}
+
+func GetConfig() Config {
+	lock.RLock()
+	defer lock.RUnlock()
+	return config
+}
+
+// Under is checked on the hot path of reading files, so it is an atomic flag
+func Under() bool {
+	return under.Load()
+}
+
+func Get() State {
+	lock.RLock()
+	defer lock.RUnlock()
+	s := state
+	s.Pressures = append([]string(nil), state.Pressures...)
+	return s
+}
+
+// SetNodeConditions sets the pressure conditions which are true in the node status
+func SetNodeConditions(conditions []string) {
+	lock.Lock()
+	defer lock.Unlock()
+	if config.NodeConditions != nil && !*config.NodeConditions {
+		return
+	}
+	nodePressures = conditions
+	update(time.Now())
+}
+
+func setLocalPressures(pressures []string, now time.Time) {
+	lock.Lock()
+	defer lock.Unlock()
+	localPressures = pressures
+	update(now)
+}
+
+// update merges the pressures and alerts when the node enters or leaves the pressure, the lock should be held
+func update(now time.Time) {
+	if !config.Enabled {
+		return
+	}
+
+	pressures := append(append([]string(nil), nodePressures...), localPressures...)
+	sort.Strings(pressures)
+	wasUnder := state.Under
+	state.Pressures = pressures
+	state.Under = len(pressures) > 0
+
+	switch {
+	case state.Under && !wasUnder:
+		state.Since = now
+		msg := fmt.Sprintf("node is under pressure: %s, the file sources are throttled", strings.Join(pressures, "; "))
+		log.Warn(msg)
+		alert(pressureReason, msg, now)
+	case !state.Under && wasUnder:
+		msg := fmt.Sprintf("node pressure is relieved after %s", now.Sub(state.Since).Round(time.Second))
+		log.Info(msg)
+		alert(relievedReason, msg, now)
+		state.Since = time.Time{}
+	}
+	under.Store(state.Under)
+}
+
+func alert(reason string, msg string, now time.Time) {
+	header := map[string]interface{}{
+		event.ReasonKey: reason,
+		pressuresKey:    append([]string(nil), state.Pressures...),
+	}
+	if !state.Since.IsZero() {
+		header[pressureSinceKey] = state.Since.Format(time.RFC3339)
+	}
+	meta := event.NewDefaultMeta()
+	meta.Set(event.SystemProductTimeKey, now)
+
+	e := event.NewEvent(header, []byte(msg))
+	e.Fill(meta, header, e.Body())
+	eventbus.PublishOrDrop(eventbus.LogAlertTopic, &e)
+}
+
+// CatchUpPaused returns whether a file with the unread bytes should not be read for now
+func CatchUpPaused(unread int64) bool {
+	if !Under() {
+		return false
+	}
+	c := GetConfig()
+	if c.Actions.PauseCatchUp != nil && !*c.Actions.PauseCatchUp {
+		return false
+	}
+	return unread > c.Actions.CatchUpBytes
+}
+
+// ReadBufferSize returns the read buffer size of the file sources, shrunk under pressure
+func ReadBufferSize(size int) int {
+	if !Under() {
+		return size
+	}
+	if shrunk := GetConfig().Actions.ReadBufferSize; shrunk > 0 && shrunk < size {
+		return shrunk
+	}
+	return size
+}
+
+// MaxContinueRead returns the times a file is read continuously, shrunk under pressure
+func MaxContinueRead(n int) int {
+	if !Under() {
+		return n
+	}
+	if shrunk := GetConfig().Actions.MaxContinueRead; shrunk > 0 && shrunk < n {
+		return shrunk
+	}
+	return n
+}
+
+// Start probes the local resources periodically until stopped
+func Start(stopCh <-chan struct{}) {
+	c := GetConfig()
+	if !c.Enabled {
+		return
+	}
+	log.Info("node pressure protection enabled, disk paths: %v", c.Disk.Paths)
+
+	t := time.NewTicker(c.Interval)
+	defer t.Stop()
+	for {
+		setLocalPressures(probe(&c), time.Now())
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func probe(c *Config) []string {
+	var pressures []string
+	if c.Disk.UsageThreshold > 0 || c.Disk.InodesThreshold > 0 {
+		for _, p := range c.Disk.Paths {
+			usage, inodes, err := diskUsage(p)
+			if err != nil {
+				log.Debug("get disk usage of %s error: %v", p, err)
+				continue
+			}
+			if c.Disk.UsageThreshold > 0 && usage >= c.Disk.UsageThreshold {
+				pressures = append(pressures, fmt.Sprintf("disk %s usage %.1f%%", p, usage*100))
+			}
+			if c.Disk.InodesThreshold > 0 && inodes >= c.Disk.InodesThreshold {
+				pressures = append(pressures, fmt.Sprintf("disk %s inodes usage %.1f%%", p, inodes*100))
+			}
+		}
+	}
+
+	if c.PID.UsageThreshold > 0 {
+		usage, err := pidUsage()
+		if err != nil {
+			log.Debug("get pid usage error: %v", err)
+		} else if usage >= c.PID.UsageThreshold {
+			pressures = append(pressures, fmt.Sprintf("pid usage %.1f%%", usage*100))
+		}
+	}
+	return pressures
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pressure
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func testConfig() Config {
+	pause := true
+	return Config{
+		Enabled: true,
+		Actions: ActionConfig{
+			PauseCatchUp:    &pause,
+			CatchUpBytes:    1024,
+			ReadBufferSize:  16384,
+			MaxContinueRead: 1,
+		},
+	}
+}
+
+func reset() {
+	SetConfig(Config{})
+	nodePressures = nil
+	localPressures = nil
+	state = State{}
+	under.Store(false)
+}
+
+func TestPressure(t *testing.T) {
+	log.InitDefaultLogger()
+	defer reset()
+	SetConfig(testConfig())
+
+	assert.False(t, Under())
+	assert.False(t, CatchUpPaused(1<<20))
+	assert.Equal(t, 65536, ReadBufferSize(65536))
+
+	now := time.Now()
+	SetNodeConditions([]string{"DiskPressure"})
+	setLocalPressures([]string{"pid usage 95.0%"}, now)
+	assert.True(t, Under())
+	assert.Equal(t, []string{"DiskPressure", "pid usage 95.0%"}, Get().Pressures)
+
+	assert.True(t, CatchUpPaused(2048))
+	assert.False(t, CatchUpPaused(512))
+	assert.Equal(t, 16384, ReadBufferSize(65536))
+	assert.Equal(t, 4096, ReadBufferSize(4096))
+	assert.Equal(t, 1, MaxContinueRead(16))
+
+	// relieved only when all the pressures are gone
+	SetNodeConditions(nil)
+	assert.True(t, Under())
+	setLocalPressures(nil, now.Add(time.Minute))
+	assert.False(t, Under())
+	assert.True(t, Get().Since.IsZero())
+}
+
+func TestPressureDisabled(t *testing.T) {
+	defer reset()
+	c := testConfig()
+	c.Enabled = false
+	SetConfig(c)
+
+	SetNodeConditions([]string{"DiskPressure"})
+	assert.False(t, Under())
+}
+
+func TestPidUsage(t *testing.T) {
+	dir := t.TempDir()
+	procPath = dir
+	defer func() { procPath = "/proc" }()
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sys", "kernel"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "loadavg"), []byte("0.20 0.18 0.12 1/900 11206\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sys", "kernel", "pid_max"), []byte("1000\n"), 0644))
+
+	usage, err := pidUsage()
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.9, usage, 0.0001)
+
+	c := testConfig()
+	c.PID.UsageThreshold = 0.8
+	assert.Equal(t, []string{"pid usage 90.0%"}, probe(&c))
+}
+
+func TestDiskUsage(t *testing.T) {
+	usage, inodes, err := diskUsage(t.TempDir())
+	assert.NoError(t, err)
+	assert.True(t, usage >= 0 && usage <= 1)
+	assert.True(t, inodes >= 0 && inodes <= 1)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pressure
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// procPath could be the /proc of the host mounted into the agent
+var procPath = "/proc"
+
+// pidUsage returns the ratio of the tasks to kernel.pid_max, the tasks are counted in the 4th field of /proc/loadavg,
+// e.g. 0.20 0.18 0.12 1/80 11206
+func pidUsage() (float64, error) {
+	loadavg, err := os.ReadFile(filepath.Join(procPath, "loadavg"))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(loadavg))
+	if len(fields) < 4 {
+		return 0, errors.Errorf("unexpected loadavg %q", string(loadavg))
+	}
+	entities := strings.SplitN(fields[3], "/", 2)
+	if len(entities) != 2 {
+		return 0, errors.Errorf("unexpected loadavg %q", string(loadavg))
+	}
+	tasks, err := strconv.ParseFloat(entities[1], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	pidMax, err := os.ReadFile(filepath.Join(procPath, "sys", "kernel", "pid_max"))
+	if err != nil {
+		return 0, err
+	}
+	max, err := strconv.ParseFloat(strings.TrimSpace(string(pidMax)), 64)
+	if err != nil {
+		return 0, err
+	}
+	if max <= 0 {
+		return 0, errors.Errorf("unexpected pid_max %v", max)
+	}
+	return tasks / max, nil
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/util/eventops"
+	timeutil "github.com/loggie-io/loggie/pkg/util/time"
+)
+
+// EventTimeConfig aligns the batches of a queue to the event time windows, so the time partitioned sinks
+// receive the batches bounded by the windows, which are flushed shortly after the windows end
+type EventTimeConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Window is the size of the buckets aligned in TimestampLocation, such as 1m or 1h
+	Window time.Duration `yaml:"window,omitempty" default:"1m"`
+	// Grace keeps a bucket open after its window ends for the delayed events
+	Grace time.Duration `yaml:"grace,omitempty" default:"5s"`
+	// MaxBuckets limits the buckets being aggregated, the oldest one is flushed when exceeded
+	MaxBuckets int `yaml:"maxBuckets,omitempty" default:"4"`
+	// TimestampKey is the field of the parsed event timestamp, the collect time of the event is used when it is empty
+	TimestampKey      string `yaml:"timestampKey,omitempty"`
+	TimestampLayout   string `yaml:"timestampLayout,omitempty" default:"2006-01-02T15:04:05Z07:00"` // support unix unix_ms
+	TimestampLocation string `yaml:"timestampLocation,omitempty"`                                   // "" indicate UTC, also support `Local`
+}
+
+func (c *EventTimeConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Window <= 0 {
+		return errors.New("eventTime window should be positive")
+	}
+	if c.MaxBuckets <= 0 {
+		return errors.New("eventTime maxBuckets should be positive")
+	}
+	if _, err := time.LoadLocation(c.TimestampLocation); err != nil {
+		return errors.WithMessagef(err, "load location %s", c.TimestampLocation)
+	}
+	return nil
+}
+
+type bucket struct {
+	start  time.Time
+	events []api.Event
+	bytes  int64
+}
+
+// EventTimeBuckets aggregates the events into the buckets of their event time windows, the events whose
+// timestamp could not be parsed are put into the window of the current time.
+// It is owned by the worker of a queue and not safe for concurrent use.
+type EventTimeBuckets struct {
+	config     *EventTimeConfig
+	location   *time.Location
+	batchSize  int
+	batchBytes int64
+	buckets    map[int64]*bucket
+	size       int
+	now        func() time.Time
+}
+
+func NewEventTimeBuckets(config *EventTimeConfig, batchSize int, batchBytes int64) (*EventTimeBuckets, error) {
+	location, err := time.LoadLocation(config.TimestampLocation)
+	if err != nil {
+		return nil, err
+	}
+	return &EventTimeBuckets{
+		config:     config,
+		location:   location,
+		batchSize:  batchSize,
+		batchBytes: batchBytes,
+		buckets:    make(map[int64]*bucket),
+		now:        time.Now,
+	}, nil
+}
+
+// Size is the number of events in all the buckets
+func (b *EventTimeBuckets) Size() int {
+	return b.size
+}
+
+// Add puts the event into its bucket, and returns the batches to be flushed when the bucket is full
+// or the buckets exceed maxBuckets
+func (b *EventTimeBuckets) Add(e api.Event) [][]api.Event {
+	start := b.windowStart(b.timestamp(e))
+	key := start.UnixNano()
+	bk, ok := b.buckets[key]
+	if !ok {
+		bk = &bucket{
+			start:  start,
+			events: make([]api.Event, 0, b.batchSize),
+		}
+		b.buckets[key] = bk
+	}
+	bk.events = append(bk.events, e)
+	bk.bytes += int64(len(e.Body()))
+	b.size++
+
+	var batches [][]api.Event
+	if len(bk.events) >= b.batchSize || bk.bytes >= b.batchBytes {
+		batches = append(batches, b.take(key, bk))
+	}
+	if len(b.buckets) > b.config.MaxBuckets {
+		first := true
+		var oldest int64
+		for k := range b.buckets {
+			if first || k < oldest {
+				oldest = k
+				first = false
+			}
+		}
+		batches = append(batches, b.take(oldest, b.buckets[oldest]))
+	}
+	return batches
+}
+
+// Expired returns the batches of the buckets whose window ended more than grace ago, in the order of the windows
+func (b *EventTimeBuckets) Expired() [][]api.Event {
+	now := b.now()
+	var keys []int64
+	for k, bk := range b.buckets {
+		if !now.Before(bk.start.Add(b.config.Window + b.config.Grace)) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	batches := make([][]api.Event, 0, len(keys))
+	for _, k := range keys {
+		batches = append(batches, b.take(k, b.buckets[k]))
+	}
+	return batches
+}
+
+func (b *EventTimeBuckets) take(key int64, bk *bucket) []api.Event {
+	delete(b.buckets, key)
+	b.size -= len(bk.events)
+	return bk.events
+}
+
+// windowStart truncates the time by the window in the location, so the hourly or daily windows are aligned
+// to the local clock rather than UTC
+func (b *EventTimeBuckets) windowStart(t time.Time) time.Time {
+	_, offset := t.In(b.location).Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(b.config.Window).Add(-shift)
+}
+
+func (b *EventTimeBuckets) timestamp(e api.Event) time.Time {
+	if b.config.TimestampKey == "" {
+		if e.Meta() != nil {
+			if v, ok := e.Meta().Get(event.SystemProductTimeKey); ok {
+				if t, ok := v.(time.Time); ok {
+					return t
+				}
+			}
+		}
+		return b.now()
+	}
+
+	val := eventops.GetString(e, b.config.TimestampKey)
+	if val == "" {
+		return b.now()
+	}
+	t, err := timeutil.Parse(val, b.config.TimestampLayout, b.location)
+	if err != nil {
+		return b.now()
+	}
+	return t
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+)
+
+func newTimeEvent(ts string) api.Event {
+	return event.NewEvent(map[string]interface{}{"ts": ts}, []byte("x"))
+}
+
+func bodies(batches [][]api.Event) [][]string {
+	var result [][]string
+	for _, es := range batches {
+		var b []string
+		for _, e := range es {
+			b = append(b, e.Header()["ts"].(string))
+		}
+		result = append(result, b)
+	}
+	return result
+}
+
+func TestEventTimeBuckets(t *testing.T) {
+	config := &EventTimeConfig{
+		Enabled:         true,
+		Window:          time.Minute,
+		Grace:           5 * time.Second,
+		MaxBuckets:      2,
+		TimestampKey:    "ts",
+		TimestampLayout: time.RFC3339,
+	}
+	b, err := NewEventTimeBuckets(config, 3, 1024)
+	assert.NoError(t, err)
+	now := time.Date(2023, 1, 1, 10, 1, 0, 0, time.UTC)
+	b.now = func() time.Time {
+		return now
+	}
+
+	assert.Empty(t, b.Add(newTimeEvent("2023-01-01T10:00:10Z")))
+	assert.Empty(t, b.Add(newTimeEvent("2023-01-01T10:01:10Z")))
+	assert.Empty(t, b.Add(newTimeEvent("2023-01-01T10:00:20Z")))
+	assert.Equal(t, 3, b.Size())
+
+	// the window of 10:00 is full
+	assert.Equal(t, [][]string{{"2023-01-01T10:00:10Z", "2023-01-01T10:00:20Z", "2023-01-01T10:00:30Z"}},
+		bodies(b.Add(newTimeEvent("2023-01-01T10:00:30Z"))))
+
+	// the late event of 10:00 opens the window again, but is not flushed within grace
+	assert.Empty(t, b.Add(newTimeEvent("2023-01-01T10:00:40Z")))
+	assert.Empty(t, b.Expired())
+
+	now = now.Add(5 * time.Second)
+	assert.Equal(t, [][]string{{"2023-01-01T10:00:40Z"}}, bodies(b.Expired()))
+
+	// the oldest window is flushed when the buckets exceed maxBuckets
+	assert.Empty(t, b.Add(newTimeEvent("2023-01-01T10:02:00Z")))
+	assert.Equal(t, [][]string{{"2023-01-01T10:01:10Z"}}, bodies(b.Add(newTimeEvent("2023-01-01T10:03:00Z"))))
+	assert.Equal(t, 2, b.Size())
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, [][]string{{"2023-01-01T10:02:00Z"}, {"2023-01-01T10:03:00Z"}}, bodies(b.Expired()))
+	assert.Equal(t, 0, b.Size())
+}
+
+func TestEventTimeBuckets_windowStart(t *testing.T) {
+	config := &EventTimeConfig{
+		Window:            24 * time.Hour,
+		TimestampLocation: "Asia/Shanghai",
+	}
+	b, err := NewEventTimeBuckets(config, 1, 1)
+	assert.NoError(t, err)
+
+	start := b.windowStart(time.Date(2023, 1, 1, 20, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2023, 1, 2, 0, 0, 0, 0, b.location).Unix(), start.Unix())
+}
@@ -33,6 +33,7 @@ type Config struct {
 	Parallelism int                `yaml:"parallelism,omitempty" default:"1" validate:"required,gte=1,lte=100"`
 	Codec       codec.Config       `yaml:"codec,omitempty" validate:"dive"`
 	Concurrency concurrency.Config `yaml:"concurrency,omitempty"`
+	Throttle    Throttle           `yaml:"throttle,omitempty"`
 }
 
 func (c *Config) DeepCopy() *Config {
@@ -47,6 +48,7 @@ func (c *Config) DeepCopy() *Config {
 	out.Properties = c.Properties.DeepCopy()
 	out.Parallelism = c.Parallelism
 	out.Codec = *c.Codec.DeepCopy()
+	out.Throttle = c.Throttle
 
 	return out
 }
@@ -56,6 +58,12 @@ func (c *Config) Validate() error {
 		return ErrSinkTypeRequired
 	}
 
+	if err := c.Concurrency.Validate(); err != nil {
+		return err
+	}
+	if err := c.Throttle.Validate(); err != nil {
+		return err
+	}
 	return c.Codec.Validate()
 }
 
@@ -73,6 +81,10 @@ func (c *Config) Merge(from *Config) {
 		c.Parallelism = from.Parallelism
 	}
 
+	if !c.Throttle.Enabled() {
+		c.Throttle = from.Throttle
+	}
+
 	if c.Codec.Type == "" {
 		c.Codec = from.Codec
 	} else {
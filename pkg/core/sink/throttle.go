@@ -0,0 +1,124 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sink
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/result"
+)
+
+// Throttle limits the throughput of the sink, so a single agent replaying the backlog could not saturate
+// the shared backend such as kafka or elasticsearch. The bytes are counted by the bodies of the events before encoded.
+type Throttle struct {
+	MaxBytesPerSecond  int64 `yaml:"maxBytesPerSecond,omitempty" validate:"gte=0"`
+	MaxEventsPerSecond int64 `yaml:"maxEventsPerSecond,omitempty" validate:"gte=0"`
+	// BurstBytes is allowed to be sent at once after being idle, the same as maxBytesPerSecond if not set
+	BurstBytes int64 `yaml:"burstBytes,omitempty" validate:"gte=0"`
+	// BurstEvents is the same as maxEventsPerSecond if not set
+	BurstEvents int64 `yaml:"burstEvents,omitempty" validate:"gte=0"`
+}
+
+func (t *Throttle) Enabled() bool {
+	return t.MaxBytesPerSecond > 0 || t.MaxEventsPerSecond > 0
+}
+
+func (t *Throttle) Validate() error {
+	if t.BurstBytes > 0 && t.MaxBytesPerSecond == 0 {
+		return errors.New("sink throttle burstBytes requires maxBytesPerSecond")
+	}
+	if t.BurstEvents > 0 && t.MaxEventsPerSecond == 0 {
+		return errors.New("sink throttle burstEvents requires maxEventsPerSecond")
+	}
+	return nil
+}
+
+// ThrottleInvoker waits for the limits before the batch is consumed by the next invoker,
+// it should be the innermost invoker so the retried batches are throttled as well
+type ThrottleInvoker struct {
+	next   Invoker
+	bytes  *rate.Limiter
+	events *rate.Limiter
+	ctx    context.Context
+}
+
+// NewThrottleInvoker returns next itself if the throttle is not enabled, the waiting is canceled after done is closed
+func NewThrottleInvoker(next Invoker, config Throttle, done <-chan struct{}) Invoker {
+	if !config.Enabled() {
+		return next
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-done
+		cancel()
+	}()
+	return &ThrottleInvoker{
+		next:   next,
+		bytes:  newLimiter(config.MaxBytesPerSecond, config.BurstBytes),
+		events: newLimiter(config.MaxEventsPerSecond, config.BurstEvents),
+		ctx:    ctx,
+	}
+}
+
+func newLimiter(perSecond int64, burst int64) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = perSecond
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), int(burst))
+}
+
+func (ti *ThrottleInvoker) Invoke(invocation Invocation) api.Result {
+	if ti.events != nil {
+		if err := wait(ti.ctx, ti.events, len(invocation.Batch.Events())); err != nil {
+			return result.Fail(err)
+		}
+	}
+	if ti.bytes != nil {
+		size := 0
+		for _, e := range invocation.Batch.Events() {
+			size += len(e.Body())
+		}
+		if err := wait(ti.ctx, ti.bytes, size); err != nil {
+			return result.Fail(err)
+		}
+	}
+	return ti.next.Invoke(invocation)
+}
+
+// wait takes n tokens in chunks of the burst, since a batch may be larger than the burst
+func wait(ctx context.Context, limiter *rate.Limiter, n int) error {
+	burst := limiter.Burst()
+	for n > 0 {
+		take := n
+		if take > burst {
+			take = burst
+		}
+		if err := limiter.WaitN(ctx, take); err != nil {
+			return errors.WithMessage(err, "sink throttle")
+		}
+		n -= take
+	}
+	return nil
+}
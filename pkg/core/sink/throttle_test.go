@@ -0,0 +1,97 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/result"
+)
+
+type countInvoker struct {
+	events int
+}
+
+func (c *countInvoker) Invoke(invocation Invocation) api.Result {
+	c.events += len(invocation.Batch.Events())
+	return result.Success()
+}
+
+func newBatch(n int, size int) api.Batch {
+	events := make([]api.Event, 0, n)
+	for i := 0; i < n; i++ {
+		events = append(events, event.NewEvent(nil, make([]byte, size)))
+	}
+	return batch.NewBatchWithEvents(events)
+}
+
+func TestThrottleDisabled(t *testing.T) {
+	next := &countInvoker{}
+	assert.Equal(t, Invoker(next), NewThrottleInvoker(next, Throttle{}, make(chan struct{})))
+}
+
+func TestThrottleEvents(t *testing.T) {
+	next := &countInvoker{}
+	invoker := NewThrottleInvoker(next, Throttle{MaxEventsPerSecond: 100, BurstEvents: 10}, make(chan struct{}))
+
+	start := time.Now()
+	// the first batch is sent with the burst, the rest are larger than the burst and wait 250ms each
+	for i := 0; i < 3; i++ {
+		res := invoker.Invoke(Invocation{Batch: newBatch(25, 1)})
+		assert.Equal(t, api.SUCCESS, res.Status())
+	}
+	assert.Equal(t, 75, next.events)
+	assert.GreaterOrEqual(t, time.Since(start), 600*time.Millisecond)
+}
+
+func TestThrottleBytes(t *testing.T) {
+	next := &countInvoker{}
+	invoker := NewThrottleInvoker(next, Throttle{MaxBytesPerSecond: 1000}, make(chan struct{}))
+
+	start := time.Now()
+	invoker.Invoke(Invocation{Batch: newBatch(10, 100)})
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	invoker.Invoke(Invocation{Batch: newBatch(3, 100)})
+	assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+}
+
+func TestThrottleDone(t *testing.T) {
+	next := &countInvoker{}
+	done := make(chan struct{})
+	invoker := NewThrottleInvoker(next, Throttle{MaxEventsPerSecond: 1}, done)
+	invoker.Invoke(Invocation{Batch: newBatch(1, 1)})
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(done)
+	}()
+	res := invoker.Invoke(Invocation{Batch: newBatch(10, 1)})
+	assert.Equal(t, api.FAIL, res.Status())
+	assert.Equal(t, 1, next.events)
+}
+
+func TestThrottleValidate(t *testing.T) {
+	assert.NoError(t, (&Throttle{MaxBytesPerSecond: 1024, BurstBytes: 4096}).Validate())
+	assert.Error(t, (&Throttle{BurstBytes: 4096}).Validate())
+	assert.Error(t, (&Throttle{BurstEvents: 10}).Validate())
+}
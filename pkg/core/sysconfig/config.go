@@ -17,8 +17,12 @@ limitations under the License.
 package sysconfig
 
 import (
+	"github.com/loggie-io/loggie/pkg/control"
+	"github.com/loggie-io/loggie/pkg/core/global"
+	"github.com/loggie-io/loggie/pkg/core/health"
 	"github.com/loggie-io/loggie/pkg/core/interceptor"
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/pressure"
 	"github.com/loggie-io/loggie/pkg/core/queue"
 	"github.com/loggie-io/loggie/pkg/core/reloader"
 	"github.com/loggie-io/loggie/pkg/core/sink"
@@ -28,22 +32,39 @@ import (
 	"github.com/loggie-io/loggie/pkg/interceptor/maxbytes"
 	"github.com/loggie-io/loggie/pkg/interceptor/metric"
 	"github.com/loggie-io/loggie/pkg/interceptor/retry"
+	"github.com/loggie-io/loggie/pkg/ops/authz"
+	"github.com/loggie-io/loggie/pkg/ops/fleet"
 	"github.com/loggie-io/loggie/pkg/pipeline"
 	"github.com/loggie-io/loggie/pkg/queue/channel"
 	"github.com/loggie-io/loggie/pkg/util/persistence"
+	"github.com/loggie-io/loggie/pkg/util/regex"
 )
 
 type Config struct {
 	Loggie Loggie `yaml:"loggie"`
 }
 
+func (c *Config) Validate() error {
+	if err := c.Loggie.Http.Auth.Validate(); err != nil {
+		return err
+	}
+	return c.Loggie.Fleet.Validate()
+}
+
 type Loggie struct {
 	Reload           reloader.ReloadConfig       `yaml:"reload"`
+	Overrides        control.OverrideConfig      `yaml:"overrides"`
 	Discovery        discovery.Config            `yaml:"discovery"`
 	Http             Http                        `yaml:"http" validate:"dive"`
 	MonitorEventBus  eventbus.Config             `yaml:"monitor"`
 	Defaults         Defaults                    `yaml:"defaults"`
 	Db               persistence.DbConfig        `yaml:"db"`
+	Health           health.Config               `yaml:"health"`
+	NodePressure     pressure.Config             `yaml:"nodePressure"`
+	Quarantine       pipeline.QuarantineConfig   `yaml:"interceptorQuarantine"`
+	Regex            regex.Config                `yaml:"regex"`
+	Fleet            global.FleetConfig          `yaml:"fleet"`
+	Inventory        fleet.Config                `yaml:"inventory"`
 	ErrorAlertConfig log.AfterErrorConfiguration `yaml:"errorAlert"`
 	JSONEngine       string                      `yaml:"jsonEngine,omitempty" default:"jsoniter" validate:"oneof=jsoniter sonic std go-json"`
 }
@@ -92,4 +113,6 @@ type Http struct {
 	Host     string `yaml:"host" default:"0.0.0.0"`
 	Port     int    `yaml:"port" default:"9196"`
 	RandPort bool   `yaml:"randPort" default:"false"`
+	// Auth authorizes the requests of the management api, which are not authorized if not enabled
+	Auth authz.Config `yaml:"auth"`
 }
@@ -17,10 +17,13 @@ limitations under the License.
 package discovery
 
 import (
+	"github.com/loggie-io/loggie/pkg/discovery/git"
 	kubernetes "github.com/loggie-io/loggie/pkg/discovery/kubernetes/controller"
 )
 
 type Config struct {
 	Enabled    bool              `yaml:"enabled"`
 	Kubernetes kubernetes.Config `yaml:"kubernetes" validate:"dive"`
+	// Git is enabled by itself, the pipelines are pulled from the repository and applied by the reloader
+	Git git.Config `yaml:"git"`
 }
@@ -0,0 +1,61 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	Url     string `yaml:"url"`
+	Branch  string `yaml:"branch" default:"main"`
+	// Path is the directory of the pipeline files in the repository, Files are matched in it
+	Path     string        `yaml:"path"`
+	Files    string        `yaml:"files" default:"*.yml"`
+	Interval time.Duration `yaml:"interval" default:"1m"`
+	Timeout  time.Duration `yaml:"timeout" default:"1m"`
+	WorkDir  string        `yaml:"workDir" default:"./data/git"`
+
+	// Username and Password are used by the https repository, Password could be an access token
+	Username   string `yaml:"username"`
+	Password   string `yaml:"password"`
+	SSHKeyFile string `yaml:"sshKeyFile"`
+
+	// WebhookSecret verifies the X-Hub-Signature-256 header of github or the X-Gitlab-Token header of gitlab
+	WebhookSecret string `yaml:"webhookSecret"`
+
+	ConfigFilePath string `yaml:"-"`
+}
+
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Url == "" {
+		return errors.New("git discovery url is required")
+	}
+	if c.Interval <= 0 {
+		return errors.New("git discovery interval should be greater than 0")
+	}
+	if c.Password != "" && c.SSHKeyFile != "" {
+		return errors.New("git discovery password and sshKeyFile cannot be set at the same time")
+	}
+	return nil
+}
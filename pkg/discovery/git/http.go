@@ -0,0 +1,98 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	HandleSyncPath   = "/api/v1/discovery/git/sync"
+	HandleStatusPath = "/api/v1/discovery/git/status"
+
+	githubSignatureHeader = "X-Hub-Signature-256"
+	gitlabTokenHeader     = "X-Gitlab-Token"
+
+	maxWebhookBodyBytes = 1 << 20
+)
+
+func (s *Syncer) initHttp() {
+	http.HandleFunc(HandleSyncPath, s.syncHandler)
+	http.HandleFunc(HandleStatusPath, s.statusHandler)
+}
+
+// syncHandler triggers a sync, it could be used as the push webhook of the repository
+func (s *Syncer) syncHandler(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(request.Body, maxWebhookBodyBytes))
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !s.verifyWebhook(request.Header, body) {
+		log.Warn("git discovery webhook from %s with invalid signature", request.RemoteAddr)
+		writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.Trigger()
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Syncer) verifyWebhook(header http.Header, body []byte) bool {
+	secret := s.config.WebhookSecret
+	if secret == "" {
+		return true
+	}
+
+	if token := header.Get(gitlabTokenHeader); token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+
+	signature := strings.TrimPrefix(header.Get(githubSignatureHeader), "sha256=")
+	expected, err := hex.DecodeString(signature)
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func (s *Syncer) statusHandler(writer http.ResponseWriter, request *http.Request) {
+	out, err := json.Marshal(s.Status())
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(out)
+}
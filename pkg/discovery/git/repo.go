@@ -0,0 +1,95 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const remoteName = "origin"
+
+// repo is a shallow checkout of a single branch, it is synced by the git command
+type repo struct {
+	config *Config
+	dir    string
+}
+
+func newRepo(config *Config) *repo {
+	return &repo{
+		config: config,
+		dir:    config.WorkDir,
+	}
+}
+
+// pull fetches the latest commit of the branch and checkouts it, the commit hash is returned
+func (r *repo) pull(ctx context.Context) (string, error) {
+	if _, err := os.Stat(filepath.Join(r.dir, ".git")); err != nil {
+		if err := os.MkdirAll(r.dir, 0755); err != nil {
+			return "", errors.WithMessagef(err, "create git work dir %s", r.dir)
+		}
+		if _, err := r.run(ctx, "init", "-q"); err != nil {
+			return "", err
+		}
+		if _, err := r.run(ctx, "remote", "add", remoteName, r.config.Url); err != nil {
+			return "", err
+		}
+	} else if _, err := r.run(ctx, "remote", "set-url", remoteName, r.config.Url); err != nil {
+		return "", err
+	}
+
+	if _, err := r.run(ctx, "fetch", "-q", "--depth", "1", remoteName, r.config.Branch); err != nil {
+		return "", err
+	}
+	if _, err := r.run(ctx, "reset", "-q", "--hard", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	if _, err := r.run(ctx, "clean", "-q", "-fdx"); err != nil {
+		return "", err
+	}
+	return r.run(ctx, "rev-parse", "HEAD")
+}
+
+func (r *repo) run(ctx context.Context, args ...string) (string, error) {
+	var cmdArgs []string
+	if r.config.Password != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(r.config.Username + ":" + r.config.Password))
+		cmdArgs = append(cmdArgs, "-c", "http.extraHeader=Authorization: Basic "+auth)
+	}
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := exec.CommandContext(ctx, "git", cmdArgs...)
+	cmd.Dir = r.dir
+	// never wait for the credentials from the terminal
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if r.config.SSHKeyFile != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new", r.config.SSHKeyFile))
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/control"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/util/yaml"
+)
+
+// GenerateConfigName is the pipeline file written to the config directory, it is applied by the reloader
+const GenerateConfigName = "git-pipelines.yml"
+
+type Status struct {
+	Url      string    `json:"url"`
+	Branch   string    `json:"branch"`
+	Commit   string    `json:"commit"`
+	Fetched  string    `json:"fetched"`
+	LastSync time.Time `json:"lastSync"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Syncer pulls the repository on the interval or when triggered by the webhook,
+// the pipelines are only applied after all of them are valid, otherwise the last valid commit is kept.
+type Syncer struct {
+	config  *Config
+	repo    *repo
+	trigger chan struct{}
+
+	lock   sync.RWMutex
+	status Status
+}
+
+func NewSyncer(config *Config) *Syncer {
+	return &Syncer{
+		config:  config,
+		repo:    newRepo(config),
+		trigger: make(chan struct{}, 1),
+		status: Status{
+			Url:    config.Url,
+			Branch: config.Branch,
+		},
+	}
+}
+
+func (s *Syncer) Start(stopCh <-chan struct{}) {
+	log.Info("git discovery starting, repository: %s, branch: %s", s.config.Url, s.config.Branch)
+	s.initHttp()
+
+	s.sync()
+	t := time.NewTicker(s.config.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stopCh:
+			log.Info("stop git discovery")
+			return
+		case <-t.C:
+			s.sync()
+		case <-s.trigger:
+			s.sync()
+		}
+	}
+}
+
+// Trigger requests a sync immediately, it does not block when a sync is already pending
+func (s *Syncer) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Syncer) Status() Status {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.status
+}
+
+func (s *Syncer) sync() {
+	fetched, changed, err := s.pullAndApply()
+
+	s.lock.Lock()
+	s.status.LastSync = time.Now()
+	if fetched != "" {
+		s.status.Fetched = fetched
+	}
+	s.status.Error = ""
+	if err != nil {
+		s.status.Error = err.Error()
+	} else {
+		s.status.Commit = fetched
+	}
+	status := s.status
+	s.lock.Unlock()
+
+	if err != nil {
+		log.Warn("git discovery sync %s failed: %v", s.config.Url, err)
+	} else if changed {
+		log.Info("git discovery applied commit %s of %s", fetched, s.config.Url)
+	}
+
+	eventbus.PublishOrDrop(eventbus.GitSyncTopic, eventbus.GitSyncMetricData{
+		Url:     status.Url,
+		Branch:  status.Branch,
+		Commit:  status.Commit,
+		Fetched: status.Fetched,
+		Changed: changed,
+		Error:   status.Error,
+		Time:    status.LastSync,
+	})
+}
+
+func (s *Syncer) pullAndApply() (string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	commit, err := s.repo.pull(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if commit == s.Status().Commit {
+		return commit, false, nil
+	}
+
+	content, err := loadPipelines(filepath.Join(s.repo.dir, s.config.Path), s.config.Files)
+	if err != nil {
+		return commit, false, errors.WithMessagef(err, "commit %s is invalid", commit)
+	}
+	if err := writeConfig(s.config.ConfigFilePath, content); err != nil {
+		return commit, false, err
+	}
+	return commit, true, nil
+}
+
+// loadPipelines reads and validates all the pipeline files in dir, the raw pipelines are returned without defaults
+func loadPipelines(dir string, files string) ([]byte, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, files))
+	if err != nil {
+		return nil, err
+	}
+
+	raw := &control.PipelineConfig{}
+	checked := &control.PipelineConfig{}
+	for _, m := range matches {
+		stat, err := os.Stat(m)
+		if err != nil {
+			return nil, err
+		}
+		if stat.IsDir() {
+			continue
+		}
+
+		name, _ := filepath.Rel(dir, m)
+		content, err := os.ReadFile(m)
+		if err != nil {
+			return nil, err
+		}
+
+		pipes := &control.PipelineConfig{}
+		if err := cfg.UnPackFromRaw(content, pipes).Do(); err != nil {
+			return nil, errors.WithMessagef(err, "read %s", name)
+		}
+		// unpack again, the defaults should not be written back
+		validated := &control.PipelineConfig{}
+		if err := cfg.UnPackFromRaw(content, validated).Defaults().Validate().Do(); err != nil {
+			return nil, errors.WithMessagef(err, "validate %s", name)
+		}
+
+		raw.AddPipelines(pipes.Pipelines)
+		checked.AddPipelines(validated.Pipelines)
+	}
+
+	if err := checked.ValidateUniquePipeName(); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(raw)
+}
+
+// writeConfig replaces the config file by rename, so the reloader would never read a partial file
+func writeConfig(dir string, content []byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, "."+GenerateConfigName+".tmp")
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, GenerateConfigName))
+}
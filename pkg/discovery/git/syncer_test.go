@@ -0,0 +1,154 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/control"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	_ "github.com/loggie-io/loggie/pkg/queue/channel"
+	_ "github.com/loggie-io/loggie/pkg/sink/dev"
+	_ "github.com/loggie-io/loggie/pkg/source/dev"
+)
+
+const validPipelines = `
+pipelines:
+  - name: demo
+    sources:
+      - type: dev
+        name: dev
+    queue:
+      type: channel
+    sink:
+      type: dev
+`
+
+const duplicatedPipelines = `
+pipelines:
+  - name: demo
+    sources:
+      - type: dev
+        name: other
+    queue:
+      type: channel
+    sink:
+      type: dev
+`
+
+func gitCommit(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=loggie", "-c", "user.email=loggie@localhost", "commit", "-q", "-m", "update"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+}
+
+func TestSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	log.InitDefaultLogger()
+
+	upstream := t.TempDir()
+	cmd := exec.Command("git", "init", "-q", "-b", "main")
+	cmd.Dir = upstream
+	assert.NoError(t, cmd.Run())
+	gitCommit(t, upstream, map[string]string{"pipelines/demo.yml": validPipelines, "README.md": "pipelines"})
+
+	config := &Config{
+		Enabled:        true,
+		Url:            upstream,
+		Path:           "pipelines",
+		ConfigFilePath: t.TempDir(),
+	}
+	assert.NoError(t, cfg.NewUnpack(nil, config, nil).Defaults().Validate().Do())
+	config.WorkDir = t.TempDir()
+	s := NewSyncer(config)
+
+	s.sync()
+	status := s.Status()
+	assert.Empty(t, status.Error)
+	assert.NotEmpty(t, status.Commit)
+	applied, err := control.ReadPipelineConfigFromFile(filepath.Join(config.ConfigFilePath, "*.yml"), func(s os.FileInfo) bool {
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Len(t, applied.Pipelines, 1)
+	assert.Equal(t, "demo", applied.Pipelines[0].Name)
+
+	// the duplicated pipeline name is rejected, and the last valid commit is kept
+	gitCommit(t, upstream, map[string]string{"pipelines/other.yml": duplicatedPipelines})
+	s.sync()
+	rejected := s.Status()
+	assert.NotEmpty(t, rejected.Error)
+	assert.Equal(t, status.Commit, rejected.Commit)
+	assert.NotEqual(t, status.Commit, rejected.Fetched)
+
+	// removing the pipelines from the repository stops them
+	assert.NoError(t, os.Remove(filepath.Join(upstream, "pipelines/demo.yml")))
+	assert.NoError(t, os.Remove(filepath.Join(upstream, "pipelines/other.yml")))
+	gitCommit(t, upstream, nil)
+	s.sync()
+	latest := s.Status()
+	assert.Empty(t, latest.Error)
+	assert.Equal(t, latest.Fetched, latest.Commit)
+	applied, err = control.ReadPipelineConfigFromFile(filepath.Join(config.ConfigFilePath, "*.yml"), func(s os.FileInfo) bool {
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Len(t, applied.Pipelines, 0)
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	s := NewSyncer(&Config{WebhookSecret: "secret"})
+	body := []byte(`{"ref":"refs/heads/main"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	github := http.Header{}
+	github.Set(githubSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	assert.True(t, s.verifyWebhook(github, body))
+	assert.False(t, s.verifyWebhook(github, []byte("{}")))
+
+	gitlab := http.Header{}
+	gitlab.Set(gitlabTokenHeader, "secret")
+	assert.True(t, s.verifyWebhook(gitlab, body))
+	gitlab.Set(gitlabTokenHeader, "wrong")
+	assert.False(t, s.verifyWebhook(gitlab, body))
+
+	assert.False(t, s.verifyWebhook(http.Header{}, body))
+	assert.True(t, NewSyncer(&Config{}).verifyWebhook(http.Header{}, body))
+}
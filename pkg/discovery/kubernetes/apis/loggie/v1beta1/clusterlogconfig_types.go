@@ -59,6 +59,9 @@ type Selector struct {
 
 type PodSelector struct {
 	LabelSelector map[string]string `json:"labelSelector,omitempty"`
+	// Profile makes a ClusterLogConfig only select the pods which reference it
+	// by the annotation `clusterlogconfig.loggie.io/profile`
+	Profile bool `json:"profile,omitempty"`
 }
 
 type NodeSelector struct {
@@ -114,6 +117,10 @@ func (in *ClusterLogConfig) Validate() error {
 		return errors.New("selector.cluster is required when selector.type=cluster")
 	}
 
+	if in.Spec.Selector.Profile && tp != SelectorTypePod {
+		return errors.New("selector.profile is only supported when selector.type=pod")
+	}
+
 	if in.Spec.Pipeline.Sources == "" {
 		return errors.New("pipeline sources is empty")
 	}
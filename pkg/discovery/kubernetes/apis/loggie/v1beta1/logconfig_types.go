@@ -49,6 +49,10 @@ func (in *LogConfig) Validate() error {
 		return errors.New("only selector.type:pod is supported in LogConfig")
 	}
 
+	if in.Spec.Selector.Profile {
+		return errors.New("selector.profile is only supported in ClusterLogConfig")
+	}
+
 	if in.Spec.Pipeline.Sources == "" {
 		return errors.New("pipeline sources is empty")
 	}
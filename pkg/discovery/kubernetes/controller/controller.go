@@ -25,7 +25,9 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/loggie-io/loggie/pkg/core/health"
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/pressure"
 	logconfigClientset "github.com/loggie-io/loggie/pkg/discovery/kubernetes/client/clientset/versioned"
 	logconfigSchema "github.com/loggie-io/loggie/pkg/discovery/kubernetes/client/clientset/versioned/scheme"
 	logconfigInformers "github.com/loggie-io/loggie/pkg/discovery/kubernetes/client/informers/externalversions/loggie/v1beta1"
@@ -59,6 +61,8 @@ const (
 
 	InjectorAnnotationKey       = "sidecar.loggie.io/inject"
 	InjectorAnnotationValueTrue = "true"
+
+	healthName = "discovery/kubernetes"
 )
 
 // Element the item add to queue
@@ -179,6 +183,7 @@ func NewController(
 			log.Panic("get node %s failed: %+v", config.NodeName, err)
 		}
 		controller.nodeInfo = node.DeepCopy()
+		pressure.SetNodeConditions(nodePressureConditions(node))
 	}
 
 	clusterLogConfigInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -454,6 +459,16 @@ func (c *Controller) Run(stopCh <-chan struct{}, cacheSyncs ...cache.InformerSyn
 	// Start the informer factories to begin populating the informer caches
 	log.Info("Starting controller")
 
+	health.Register(healthName, func() error {
+		for _, synced := range cacheSyncs {
+			if !synced() {
+				return fmt.Errorf("informer caches are not synced")
+			}
+		}
+		return nil
+	})
+	defer health.Unregister(healthName)
+
 	// Wait for the caches to be synced before starting workers
 	log.Info("Waiting for informer caches to sync")
 	if ok := cache.WaitForCacheSync(stopCh, cacheSyncs...); !ok {
@@ -22,6 +22,7 @@ import (
 
 	"github.com/loggie-io/loggie/pkg/control"
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/pressure"
 	logconfigv1beta1 "github.com/loggie-io/loggie/pkg/discovery/kubernetes/apis/loggie/v1beta1"
 	"github.com/loggie-io/loggie/pkg/util"
 	"github.com/pkg/errors"
@@ -125,9 +126,24 @@ func (c *Controller) reconcileNode(name string) error {
 	n := node.DeepCopy()
 	c.nodeInfo = n
 	log.Debug("set node labels: %v", n.Labels)
+	pressure.SetNodeConditions(nodePressureConditions(n))
 	return nil
 }
 
+// nodePressureConditions returns the pressure conditions of the node which are true
+func nodePressureConditions(node *corev1.Node) []string {
+	var conditions []string
+	for _, c := range node.Status.Conditions {
+		switch c.Type {
+		case corev1.NodeDiskPressure, corev1.NodePIDPressure, corev1.NodeMemoryPressure:
+			if c.Status == corev1.ConditionTrue {
+				conditions = append(conditions, string(c.Type))
+			}
+		}
+	}
+	return conditions
+}
+
 func (c *Controller) reconcileInterceptor(name string) error {
 	log.Info("start reconcile interceptor %s", name)
 
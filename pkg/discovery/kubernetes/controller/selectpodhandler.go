@@ -173,6 +173,11 @@ func (c *Controller) handlePodAddOrUpdate(pod *corev1.Pod) error {
 		return nil
 	}
 
+	if err := helper.ValidatePodProfiles(pod, c.clusterLogConfigLister); err != nil {
+		log.Warn("pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		c.record.Eventf(pod, corev1.EventTypeWarning, ReasonFailed, MessageSyncFailed, logconfigv1beta1.SelectorTypePod, err.Error())
+	}
+
 	c.handlePodAddOrUpdateOfLogConfig(pod)
 
 	c.handlePodAddOrUpdateOfClusterLogConfig(pod)
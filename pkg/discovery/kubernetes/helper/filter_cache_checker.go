@@ -195,6 +195,19 @@ func (p *filterCacheChecker) checkWorkload(pod *corev1.Pod) bool {
 	return true
 }
 
+// checkProfile checks whether the pod references the profile by annotation
+func (p *filterCacheChecker) checkProfile(pod *corev1.Pod) bool {
+	if p.lgc.Spec.Selector == nil || !p.lgc.Spec.Selector.Profile {
+		return true
+	}
+	for _, name := range PodProfiles(pod) {
+		if name == p.lgc.Name {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *filterCacheChecker) checkLabels(pod *corev1.Pod) bool {
 	if p.lgc.Spec.Selector == nil {
 		return true
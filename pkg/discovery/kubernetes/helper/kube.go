@@ -43,7 +43,13 @@ import (
 	logconfigLister "github.com/loggie-io/loggie/pkg/discovery/kubernetes/client/listers/loggie/v1beta1"
 )
 
-const MatchAllToken = "*"
+const (
+	MatchAllToken = "*"
+
+	// ProfileAnnotation references the comma separated ClusterLogConfig profiles maintained by the platform team,
+	// so that the pod is collected by them without embedding the full config
+	ProfileAnnotation = "clusterlogconfig.loggie.io/profile"
+)
 
 func IsPodReady(pod *corev1.Pod) bool {
 	if pod.Status.ContainerStatuses == nil || len(pod.Status.ContainerStatuses) <= 0 {
@@ -131,6 +137,41 @@ func GetPodRelatedLogConfigs(pod *corev1.Pod, lgcLister logconfigLister.LogConfi
 	return ret, nil
 }
 
+// PodProfiles returns the profile names referenced by the pod annotation
+func PodProfiles(pod *corev1.Pod) []string {
+	val, ok := pod.Annotations[ProfileAnnotation]
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, name := range strings.Split(val, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ValidatePodProfiles checks that each profile referenced by the pod exists and is a profile ClusterLogConfig
+func ValidatePodProfiles(pod *corev1.Pod, clgcLister logconfigLister.ClusterLogConfigLister) error {
+	var invalid []string
+	for _, name := range PodProfiles(pod) {
+		clgc, err := clgcLister.Get(name)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s(%v)", name, err))
+			continue
+		}
+		if clgc.Spec.Selector == nil || !clgc.Spec.Selector.Profile {
+			invalid = append(invalid, fmt.Sprintf("%s(not a profile)", name))
+		}
+	}
+	if len(invalid) > 0 {
+		return errors.Errorf("invalid profiles in annotation %s: %s", ProfileAnnotation, strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
 func GetPodRelatedClusterLogConfigs(pod *corev1.Pod, clgcLister logconfigLister.ClusterLogConfigLister, clientSet kubernetes.Interface) ([]*logconfigv1beta1.ClusterLogConfig, error) {
 	clgcList, err := clgcLister.List(labels.Everything())
 	if err != nil {
@@ -2,6 +2,12 @@ package helper
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	logconfigv1beta1 "github.com/loggie-io/loggie/pkg/discovery/kubernetes/apis/loggie/v1beta1"
 )
 
 func TestLabelsSubset(t *testing.T) {
@@ -142,3 +148,45 @@ func TestMatchSelector(t *testing.T) {
 		})
 	}
 }
+
+func TestConfirmProfile(t *testing.T) {
+	newPod := func(annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "app-0",
+				Namespace:       "default",
+				Annotations:     annotations,
+				OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: "app"}},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{ContainerID: "containerd://123"}},
+			},
+		}
+	}
+	newLgc := func(profile bool) *logconfigv1beta1.LogConfig {
+		lgc := &logconfigv1beta1.LogConfig{}
+		lgc.Name = "platform-kafka"
+		lgc.Spec.Selector = &logconfigv1beta1.Selector{Type: logconfigv1beta1.SelectorTypePod}
+		lgc.Spec.Selector.Profile = profile
+		return lgc
+	}
+
+	tests := []struct {
+		name        string
+		profile     bool
+		annotations map[string]string
+		want        bool
+	}{
+		{name: "referenced", profile: true, annotations: map[string]string{ProfileAnnotation: "platform-es, platform-kafka"}, want: true},
+		{name: "not referenced", profile: true, annotations: map[string]string{ProfileAnnotation: "platform-es"}, want: false},
+		{name: "no annotation", profile: true, want: false},
+		{name: "not a profile", profile: false, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewPodsConfirm(newLgc(tt.profile), nil).Confirm(newPod(tt.annotations))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
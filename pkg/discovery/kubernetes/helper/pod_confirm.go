@@ -37,6 +37,10 @@ func (p *PodsConfirm) Confirm(pod *corev1.Pod) (bool, error) {
 		return false, nil
 	}
 
+	if !p.cache.checkProfile(pod) {
+		return false, nil
+	}
+
 	if !p.cache.checkNamespace(pod) {
 		return false, nil
 	}
@@ -92,7 +92,8 @@ func (p *PodsFilter) Filter() ([]*corev1.Pod, error) {
 		return nil, err
 	}
 
-	if len(p.cache.namespaces) == 0 && len(p.cache.excludeNamespaces) == 0 && len(p.cache.workloadSelector) == 0 {
+	profile := p.lgc.Spec.Selector != nil && p.lgc.Spec.Selector.Profile
+	if !profile && len(p.cache.namespaces) == 0 && len(p.cache.excludeNamespaces) == 0 && len(p.cache.workloadSelector) == 0 {
 		return pods, nil
 	}
 
@@ -104,6 +105,10 @@ func (p *PodsFilter) Filter() ([]*corev1.Pod, error) {
 			continue
 		}
 
+		if !p.cache.checkProfile(pod) {
+			continue
+		}
+
 		if !p.cache.checkNamespace(pod) {
 			continue
 		}
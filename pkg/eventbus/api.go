@@ -17,15 +17,24 @@ limitations under the License.
 package eventbus
 
 import (
+	"path"
+	"strings"
 	"time"
 
 	"github.com/loggie-io/loggie/pkg/core/api"
 )
 
+// MaxChainDepth is the max times an event could be derived by the listeners, which cuts the loops of the chained listeners
+const MaxChainDepth = 8
+
 type Event struct {
 	Topic       string
 	PublishTime time.Time
 	Data        interface{}
+	// Publisher is the listener which derived the event from the events of other topics, empty for the events of components
+	Publisher string
+	// Depth is the times the event has been derived by the listeners
+	Depth int
 }
 
 func NewEvent(topic string, data interface{}) Event {
@@ -72,6 +81,7 @@ func WithAsync(async bool) SubscribeOpt {
 	}
 }
 
+// WithTopic subscribes the topic, it could be a wildcard pattern such as `*` or `sink*`
 func WithTopic(topic string) SubscribeOpt {
 	return func(s *Subscribe) {
 		if s.topics == nil {
@@ -81,6 +91,17 @@ func WithTopic(topic string) SubscribeOpt {
 	}
 }
 
+// isPattern reports whether the topic is a wildcard pattern such as `*` or `sink*`, which is matched by path.Match
+func isPattern(topic string) bool {
+	return strings.ContainsAny(topic, "*?[")
+}
+
+func validPattern(pattern string) bool {
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
+
+// WithTopics subscribes the topics, a topic could be a wildcard pattern such as `*` or `sink*`
 func WithTopics(topics []string) SubscribeOpt {
 	return func(s *Subscribe) {
 		if s.topics == nil {
@@ -46,6 +46,13 @@ var (
 	NormalizeTopic        = "normalize"
 	NoDataTopic           = "noDataAlert"
 	InfoTopic             = "info"
+	QuotaTopic            = "quota"
+	HostLimitTopic        = "hostLimit"
+	GitSyncTopic          = "gitSync"
+	FileCheckpointTopic   = "fileCheckpoint"
+	EnrichFileTopic       = "enrichFile"
+	FailoverTopic         = "failover"
+	CircuitBreakerTopic   = "circuitBreaker"
 )
 
 type BaseMetric struct {
@@ -70,6 +77,7 @@ type CollectMetricData struct {
 	LineNumber   int64 // file lines count
 	Lines        int64 // current line offset
 	FileSize     int64
+	SkippedBytes int64 // the bytes of an existing file skipped when starting to collect it
 	SourceFields map[string]interface{}
 }
 
@@ -145,6 +153,7 @@ type PipelineMetricData struct {
 	Name             string
 	Time             time.Time
 	ComponentConfigs []ComponentBaseConfig
+	ConfigHash       string // the hash of the pipeline config, which tells the agents running different configs
 }
 
 type NormalizeMetricData struct {
@@ -160,6 +169,85 @@ type NormalizeMetricEvent struct {
 	IsClear      bool
 }
 
+type QuotaMetricData struct {
+	BaseInterceptorMetric
+	WindowStart time.Time
+	Usages      []QuotaUsage
+}
+
+type QuotaUsage struct {
+	Key            string
+	Bytes          int64
+	Events         int64
+	LimitBytes     int64
+	LimitEvents    int64
+	ExceededEvents int64
+	ExceededBytes  int64
+}
+
+type EnrichFileMetricData struct {
+	BaseInterceptorMetric
+	Path       string
+	Entries    int
+	Mmapped    bool
+	LoadedAt   time.Time
+	Hits       uint64 // cumulative since the interceptor started
+	Misses     uint64
+	Reloads    uint64
+	LoadErrors uint64
+}
+
+type CircuitBreakerMetricData struct {
+	BaseInterceptorMetric
+	State    string // closed, open or halfOpen
+	Opens    uint64 // cumulative since the interceptor started
+	Rejected uint64 // batches shed while the breaker was open
+	Probes   uint64
+}
+
+type HostLimitMetricData struct {
+	PipelineName string
+	SinkName     string
+	Hosts        []HostLimitUsage
+}
+
+type HostLimitUsage struct {
+	Host          string
+	InFlight      int64
+	Waiting       int64
+	Throttled     int64 // requests queued by the limits in current period
+	WaitTime      time.Duration
+	LimitQPS      float64
+	LimitInFlight int
+}
+
+type FailoverMetricData struct {
+	PipelineName string
+	SinkName     string
+	Active       string // the name of the active sink
+	ActiveIndex  int    // 0 is the primary, the fallbacks start from 1
+	Switchovers  uint64 // cumulative since the sink started
+	Failbacks    uint64
+}
+
+type GitSyncMetricData struct {
+	Url     string
+	Branch  string
+	Commit  string // the commit applied currently
+	Fetched string // the latest fetched commit, it is not applied when the pipelines are invalid
+	Changed bool   // a new commit is applied in this sync
+	Error   string
+	Time    time.Time
+}
+
+type FileCheckpointMetricData struct {
+	BaseMetric
+	Files    int           // files with acked offsets which have not been checkpointed
+	LagBytes int64         // acked bytes which have not been checkpointed
+	LagTime  time.Duration // time since the oldest acked offset which has not been checkpointed
+	Stopped  bool          // the source has stopped
+}
+
 type ComponentBaseConfig struct {
 	Name     string
 	Type     api.Type
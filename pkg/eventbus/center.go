@@ -17,11 +17,15 @@ limitations under the License.
 package eventbus
 
 import (
+	"path"
+	"sync"
+	"time"
+
 	"github.com/loggie-io/loggie/pkg/core/cfg"
 	"github.com/loggie-io/loggie/pkg/core/context"
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/eventbus/export/logger"
-	"sync"
+	"github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
 )
 
 // asyncConsumerSize should always be 1 because concurrency may cause panic
@@ -31,6 +35,12 @@ func PublishOrDrop(topic string, data interface{}) {
 	defaultEventCenter.publishOrDrop(NewEvent(topic, data))
 }
 
+// PublishDerived publishes the data derived by the listener from the event onto the topic, so listeners could be chained.
+// The listener never receives the events published by itself, and the events derived more than MaxChainDepth times are dropped.
+func PublishDerived(listenerName string, from Event, topic string, data interface{}) {
+	defaultEventCenter.publishDerived(listenerName, from, topic, data)
+}
+
 func Registry(listenerName string, listenerFactory ListenerFactory, opts ...SubscribeOpt) {
 	RegistrySubscribe(NewSubscribe(listenerName, listenerFactory, opts...))
 }
@@ -63,11 +73,17 @@ type EventCenter struct {
 	done                   chan struct{}
 	name2Subscribe         map[string]*Subscribe
 	activeTopic2Subscribes map[string][]*Subscribe
+	wildcardSubscribes     []wildcardSubscribe
 	asyncConsumerSize      int
 	eventChan              chan Event
 	lock                   sync.Mutex
 }
 
+type wildcardSubscribe struct {
+	pattern   string
+	subscribe *Subscribe
+}
+
 func NewEventCenter(bufferSize int64, asyncConsumerSize int) *EventCenter {
 	ec := &EventCenter{
 		done:                   make(chan struct{}),
@@ -97,6 +113,11 @@ func (ec *EventCenter) registry(subscribe *Subscribe) {
 	if _, ok := ec.name2Subscribe[subscribe.listenerName]; ok {
 		log.Panic("listener name(%s) repeat!", subscribe.listenerName)
 	}
+	for _, topic := range subscribe.topics {
+		if isPattern(topic) && !validPattern(topic) {
+			log.Panic("topic pattern(%s) of listener %s is invalid", topic, subscribe.listenerName)
+		}
+	}
 	ec.name2Subscribe[subscribe.listenerName] = subscribe
 
 }
@@ -128,8 +149,23 @@ func (ec *EventCenter) publishOrDrop(event Event) {
 	}
 }
 
+func (ec *EventCenter) publishDerived(listenerName string, from Event, topic string, data interface{}) {
+	if from.Depth >= MaxChainDepth {
+		log.Warn("drop the event derived by listener %s onto topic %s, it has been derived %d times", listenerName, topic, from.Depth)
+		return
+	}
+	ec.publishOrDrop(Event{
+		Topic:       topic,
+		PublishTime: time.Now(),
+		Data:        data,
+		Publisher:   listenerName,
+		Depth:       from.Depth + 1,
+	})
+}
+
 func (ec *EventCenter) start(config Config) {
 	logger.Run(config.LoggerConfig)
+	prometheus.SetConfig(config.PrometheusConfig)
 
 	for name, conf := range config.ListenerConfigs {
 		subscribe, ok := ec.name2Subscribe[name]
@@ -163,6 +199,17 @@ func (ec *EventCenter) start(config Config) {
 func (ec *EventCenter) activeSubscribe(subscribe *Subscribe) {
 	ec.lock.Lock()
 	for _, topic := range subscribe.topics {
+		if isPattern(topic) {
+			if !validPattern(topic) {
+				log.Error("topic pattern(%s) of listener %s is invalid", topic, subscribe.listenerName)
+				continue
+			}
+			ec.wildcardSubscribes = append(ec.wildcardSubscribes, wildcardSubscribe{
+				pattern:   topic,
+				subscribe: subscribe,
+			})
+			continue
+		}
 		if subscribes, ok := ec.activeTopic2Subscribes[topic]; ok {
 			ec.activeTopic2Subscribes[topic] = append(subscribes, subscribe)
 		} else {
@@ -187,25 +234,57 @@ func (ec *EventCenter) inActiveSubscribe(subscribe *Subscribe) {
 			ec.activeTopic2Subscribes[topic] = newSubscribes
 		}
 	}
+	wildcards := make([]wildcardSubscribe, 0, len(ec.wildcardSubscribes))
+	for _, w := range ec.wildcardSubscribes {
+		if w.subscribe.listenerName != subscribe.listenerName {
+			wildcards = append(wildcards, w)
+		}
+	}
+	ec.wildcardSubscribes = wildcards
 	ec.lock.Unlock()
 }
 
+// subscribes returns the subscribes of the topic and the patterns matching it, every subscribe is returned once
+func (ec *EventCenter) subscribes(topic string) []*Subscribe {
+	ec.lock.Lock()
+	defer ec.lock.Unlock()
+	subscribes := ec.activeTopic2Subscribes[topic]
+	if len(ec.wildcardSubscribes) == 0 {
+		return subscribes
+	}
+
+	result := make([]*Subscribe, len(subscribes), len(subscribes)+len(ec.wildcardSubscribes))
+	copy(result, subscribes)
+	for _, w := range ec.wildcardSubscribes {
+		if ok, _ := path.Match(w.pattern, topic); !ok {
+			continue
+		}
+		exist := false
+		for _, s := range result {
+			if s == w.subscribe {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			result = append(result, w.subscribe)
+		}
+	}
+	return result
+}
+
 func (ec *EventCenter) run() {
 	for {
 		select {
 		case <-ec.done:
 			return
 		case e := <-ec.eventChan:
-			topic := e.Topic
-
-			ec.lock.Lock()
-			metas, ok := ec.activeTopic2Subscribes[topic]
-			ec.lock.Unlock()
-
-			if ok {
-				for _, subscribe := range metas {
-					subscribe.listener.Subscribe(e)
+			for _, subscribe := range ec.subscribes(e.Topic) {
+				// a listener does not receive the events derived by itself
+				if e.Publisher != "" && e.Publisher == subscribe.listenerName {
+					continue
 				}
+				subscribe.listener.Subscribe(e)
 			}
 		}
 	}
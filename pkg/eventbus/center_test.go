@@ -0,0 +1,130 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventbus
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+type fakeListener struct {
+	name string
+	ec   *EventCenter
+	// derive publishes the derived data of the event if it is not nil
+	derive func(e Event) (string, interface{})
+
+	lock   sync.Mutex
+	topics []string
+}
+
+func (l *fakeListener) Init(context api.Context) error { return nil }
+func (l *fakeListener) Start() error                   { return nil }
+func (l *fakeListener) Stop()                          {}
+func (l *fakeListener) Name() string                   { return l.name }
+func (l *fakeListener) Config() interface{}            { return nil }
+
+func (l *fakeListener) Subscribe(e Event) {
+	l.lock.Lock()
+	l.topics = append(l.topics, e.Topic)
+	l.lock.Unlock()
+	if l.derive != nil {
+		topic, data := l.derive(e)
+		l.ec.publishDerived(l.name, e, topic, data)
+	}
+}
+
+func (l *fakeListener) received() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]string{}, l.topics...)
+}
+
+func register(ec *EventCenter, l *fakeListener, topics ...string) *Subscribe {
+	s := NewSubscribe(l.name, func() Listener { return l }, WithTopics(topics))
+	ec.registryTemporary(s)
+	return s
+}
+
+func TestWildcardAndChaining(t *testing.T) {
+	log.InitDefaultLogger()
+	ec := NewEventCenter(64, 1)
+	go ec.run()
+	defer ec.Stop()
+
+	all := &fakeListener{name: "all"}
+	sinks := &fakeListener{name: "sinks"}
+	// composes the health from the sink and queue topics
+	health := &fakeListener{name: "health", ec: ec, derive: func(e Event) (string, interface{}) {
+		return "health", e.Topic
+	}}
+	alert := &fakeListener{name: "alert"}
+	register(ec, all, "*")
+	register(ec, sinks, "sink*", SinkMetricTopic)
+	register(ec, health, SinkMetricTopic, QueueMetricTopic, "health")
+	register(ec, alert, "health")
+
+	ec.publishOrDrop(NewEvent(SinkMetricTopic, nil))
+	ec.publishOrDrop(NewEvent(QueueMetricTopic, nil))
+
+	assert.Eventually(t, func() bool {
+		return len(alert.received()) == 2 && len(all.received()) == 4
+	}, time.Second, 10*time.Millisecond)
+	// subscribed by both a topic and a pattern, the event is received once
+	assert.Equal(t, []string{SinkMetricTopic}, sinks.received())
+	// the health listener does not receive the events derived by itself
+	assert.Equal(t, []string{SinkMetricTopic, QueueMetricTopic}, health.received())
+	assert.Equal(t, []string{"health", "health"}, alert.received())
+}
+
+func TestChainDepth(t *testing.T) {
+	log.InitDefaultLogger()
+	ec := NewEventCenter(64, 1)
+	go ec.run()
+	defer ec.Stop()
+
+	// the two listeners republish the events of each other forever
+	ping := &fakeListener{name: "ping", ec: ec, derive: func(e Event) (string, interface{}) { return "pong", nil }}
+	pong := &fakeListener{name: "pong", ec: ec, derive: func(e Event) (string, interface{}) { return "ping", nil }}
+	register(ec, ping, "ping")
+	s := register(ec, pong, "pong")
+
+	ec.publishOrDrop(NewEvent("ping", nil))
+	assert.Eventually(t, func() bool {
+		return len(ping.received())+len(pong.received()) == MaxChainDepth+1
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, MaxChainDepth+1, len(ping.received())+len(pong.received()))
+
+	ec.unRegistryTemporary(s)
+	assert.Empty(t, ec.subscribes("pong"))
+}
+
+func TestInactiveWildcard(t *testing.T) {
+	log.InitDefaultLogger()
+	ec := NewEventCenter(64, 1)
+	l := &fakeListener{name: "all"}
+	s := register(ec, l, "*", "bad[")
+	assert.Equal(t, []*Subscribe{s}, ec.subscribes("sink"))
+	ec.unRegistryTemporary(s)
+	assert.Empty(t, ec.subscribes("sink"))
+}
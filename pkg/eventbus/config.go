@@ -19,9 +19,12 @@ package eventbus
 import (
 	"github.com/loggie-io/loggie/pkg/core/cfg"
 	"github.com/loggie-io/loggie/pkg/eventbus/export/logger"
+	"github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
 )
 
 type Config struct {
 	LoggerConfig    logger.Config            `yaml:"logger"`
 	ListenerConfigs map[string]cfg.CommonCfg `yaml:"listeners"`
+	// PrometheusConfig guards the cardinality of the metrics exported by the listeners
+	PrometheusConfig prometheus.Config `yaml:"prometheus,omitempty"`
 }
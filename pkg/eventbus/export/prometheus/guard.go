@@ -0,0 +1,181 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+const (
+	OverflowAggregate = "aggregate"
+	OverflowDrop      = "drop"
+
+	// OverflowLabelValue replaces the label values of the aggregated series
+	OverflowLabelValue = "_overflow"
+	ListenerKey        = "listener"
+)
+
+type Config struct {
+	// MaxSeriesPerListener caps the series exported by each listener, 0 means unlimited
+	MaxSeriesPerListener int `yaml:"maxSeriesPerListener,omitempty" default:"10000" validate:"gte=0"`
+	// Overflow decides what to do with the new series beyond the cap, aggregate sums them into one series
+	// of each metric name whose label values are _overflow, drop discards them
+	Overflow string `yaml:"overflow,omitempty" default:"aggregate" validate:"oneof=aggregate drop"`
+}
+
+var (
+	overflowDesc = func(topic string) *Desc {
+		return NewDesc(
+			prometheus.BuildFQName(Loggie, "exporter", "overflow_series"),
+			"the series beyond the cap of the listener, which are aggregated or dropped",
+			nil, prometheus.Labels{ListenerKey: topic},
+		)
+	}
+)
+
+// guard tracks the series of each listener, the series exported last time are always kept,
+// so that the existing series would not flap when the new ones come beyond the cap
+type guard struct {
+	lock     sync.Mutex
+	config   Config
+	admitted map[string]map[string]struct{} // topic -> series
+	warned   map[string]bool
+}
+
+var defaultGuard = newGuard(Config{MaxSeriesPerListener: 10000, Overflow: OverflowAggregate})
+
+func newGuard(config Config) *guard {
+	return &guard{
+		config:   config,
+		admitted: make(map[string]map[string]struct{}),
+		warned:   make(map[string]bool),
+	}
+}
+
+// SetConfig sets the cardinality guard of the exported metrics
+func SetConfig(config Config) {
+	defaultGuard.lock.Lock()
+	defer defaultGuard.lock.Unlock()
+	defaultGuard.config = config
+}
+
+func (g *guard) limit(topic string, m ExportedMetrics) ExportedMetrics {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	max := g.config.MaxSeriesPerListener
+	if max <= 0 || len(m) <= max {
+		g.admit(topic, m)
+		return m
+	}
+
+	last := g.admitted[topic]
+	current := make(map[string]struct{}, max)
+	isAdmitted := make([]bool, len(m))
+	// keep the series exported last time at first
+	for i, metric := range m {
+		key := metric.Desc.String()
+		if _, ok := last[key]; ok && len(current) < max {
+			current[key] = struct{}{}
+			isAdmitted[i] = true
+		}
+	}
+
+	out := make(ExportedMetrics, 0, max+1)
+	var overflow []int
+	for i, metric := range m {
+		key := metric.Desc.String()
+		if !isAdmitted[i] {
+			if _, ok := current[key]; !ok && len(current) >= max {
+				overflow = append(overflow, i)
+				continue
+			}
+			current[key] = struct{}{}
+		}
+		out = append(out, metric)
+	}
+	g.admitted[topic] = current
+
+	if len(overflow) == 0 {
+		g.warned[topic] = false
+		return out
+	}
+	if !g.warned[topic] {
+		action := "dropped"
+		if g.config.Overflow == OverflowAggregate {
+			action = "aggregated"
+		}
+		log.Warn("metrics of listener %s exceed %d series, %d new series are %s", topic, max, len(overflow), action)
+		g.warned[topic] = true
+	}
+
+	if g.config.Overflow == OverflowAggregate {
+		out = append(out, aggregate(m, overflow)...)
+	}
+	out = append(out, ExportedMetric{
+		Desc:    overflowDesc(topic),
+		Eval:    float64(len(overflow)),
+		ValType: prometheus.GaugeValue,
+	})
+	return out
+}
+
+func (g *guard) admit(topic string, m ExportedMetrics) {
+	current := make(map[string]struct{}, len(m))
+	for _, metric := range m {
+		current[metric.Desc.String()] = struct{}{}
+	}
+	g.admitted[topic] = current
+	g.warned[topic] = false
+}
+
+// aggregate sums the overflowed series of the same metric name into one series
+func aggregate(m ExportedMetrics, overflow []int) ExportedMetrics {
+	var names []string
+	aggregated := make(map[string]*ExportedMetric)
+	for _, i := range overflow {
+		metric := m[i]
+		name := metric.Desc.Name
+		if a, ok := aggregated[name]; ok {
+			a.Eval += metric.Eval
+			continue
+		}
+
+		labels := make(prometheus.Labels, len(metric.Desc.Labels))
+		for k := range metric.Desc.Labels {
+			labels[k] = OverflowLabelValue
+		}
+		names = append(names, name)
+		aggregated[name] = &ExportedMetric{
+			Desc:    NewDesc(name, metric.Desc.Help, nil, labels),
+			Eval:    metric.Eval,
+			ValType: metric.ValType,
+		}
+	}
+
+	sort.Strings(names)
+	out := make(ExportedMetrics, 0, len(names))
+	for _, name := range names {
+		out = append(out, *aggregated[name])
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func newTestMetrics(pipelines ...string) ExportedMetrics {
+	var m ExportedMetrics
+	for _, p := range pipelines {
+		m = append(m, ExportedMetric{
+			Desc:    NewDesc("loggie_sink_success_event", "send event success count", nil, prometheus.Labels{PipelineNameKey: p}),
+			Eval:    1,
+			ValType: prometheus.GaugeValue,
+		})
+	}
+	return m
+}
+
+type series struct {
+	name  string
+	label string
+	value float64
+}
+
+func collect(m ExportedMetrics) []series {
+	var out []series
+	for _, metric := range m {
+		pb := &dto.Metric{}
+		prometheus.MustNewConstMetric(metric.Desc.Desc, metric.ValType, metric.Eval).Write(pb)
+		out = append(out, series{name: metric.Desc.Name, label: pb.Label[0].GetValue(), value: metric.Eval})
+	}
+	return out
+}
+
+func TestGuardAggregate(t *testing.T) {
+	log.InitDefaultLogger()
+	g := newGuard(Config{MaxSeriesPerListener: 2, Overflow: OverflowAggregate})
+
+	assert.Equal(t, []series{
+		{name: "loggie_sink_success_event", label: "a", value: 1},
+		{name: "loggie_sink_success_event", label: "b", value: 1},
+		{name: "loggie_sink_success_event", label: OverflowLabelValue, value: 2},
+		{name: "loggie_exporter_overflow_series", label: "sink", value: 2},
+	}, collect(g.limit("sink", newTestMetrics("a", "b", "c", "d"))))
+
+	// the series exported last time are kept, the vacancy of the removed one is taken by a new one
+	assert.Equal(t, []series{
+		{name: "loggie_sink_success_event", label: "c", value: 1},
+		{name: "loggie_sink_success_event", label: "b", value: 1},
+		{name: "loggie_sink_success_event", label: OverflowLabelValue, value: 1},
+		{name: "loggie_exporter_overflow_series", label: "sink", value: 1},
+	}, collect(g.limit("sink", newTestMetrics("c", "d", "b"))))
+
+	assert.Len(t, g.limit("sink", newTestMetrics("c", "d")), 2)
+}
+
+func TestGuardDrop(t *testing.T) {
+	log.InitDefaultLogger()
+	g := newGuard(Config{MaxSeriesPerListener: 1, Overflow: OverflowDrop})
+
+	assert.Equal(t, []series{
+		{name: "loggie_sink_success_event", label: "a", value: 1},
+		{name: "loggie_exporter_overflow_series", label: "sink", value: 1},
+	}, collect(g.limit("sink", newTestMetrics("a", "b"))))
+
+	// unlimited
+	g = newGuard(Config{})
+	assert.Len(t, g.limit("sink", newTestMetrics("a", "b", "c")), 3)
+}
+
+func TestAggregate(t *testing.T) {
+	m := ExportedMetrics{
+		{
+			Desc:    NewDesc("loggie_queue_size", `the "size" of queue`, nil, prometheus.Labels{PipelineNameKey: "a", QueueTypeKey: "memory"}),
+			Eval:    1,
+			ValType: prometheus.GaugeValue,
+		},
+		{
+			Desc:    NewDesc("loggie_queue_size", `the "size" of queue`, nil, prometheus.Labels{PipelineNameKey: "b", QueueTypeKey: "channel"}),
+			Eval:    2,
+			ValType: prometheus.GaugeValue,
+		},
+	}
+
+	out := aggregate(m, []int{0, 1})
+	assert.Len(t, out, 1)
+	assert.Equal(t, "loggie_queue_size", out[0].Desc.Name)
+	assert.Equal(t, `the "size" of queue`, out[0].Desc.Help)
+	assert.Equal(t, prometheus.Labels{PipelineNameKey: OverflowLabelValue, QueueTypeKey: OverflowLabelValue}, out[0].Desc.Labels)
+	assert.Equal(t, float64(3), out[0].Eval)
+}
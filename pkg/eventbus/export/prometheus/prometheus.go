@@ -29,10 +29,16 @@ const (
 	PipelineNameKey    = "pipeline"
 	SourceNameKey      = "source"
 	InterceptorNameKey = "interceptor"
+	SinkNameKey        = "sink"
 	QueueTypeKey       = "type"
 )
 
-var collector *Collector
+var (
+	collector *Collector
+
+	// constLabels are attached to the descs created by NewDesc
+	constLabels prometheus.Labels
+)
 
 func init() {
 	http.Handle("/metrics", HandlePromMetrics())
@@ -40,6 +46,12 @@ func init() {
 	prometheus.MustRegister(collector)
 }
 
+// SetConstLabels attaches the labels to all the exported metrics of loggie, the labels should not be used by the metrics.
+// It should be called before the metrics are exported.
+func SetConstLabels(labels map[string]string) {
+	constLabels = labels
+}
+
 // HandlePromMetrics export prometheus metrics
 func HandlePromMetrics() http.Handler {
 	return promhttp.Handler()
@@ -57,14 +69,47 @@ func NewCollector() *Collector {
 	}
 }
 
-type ExportedMetrics []struct {
-	Desc    *prometheus.Desc
+// Desc keeps the name, help and const labels of the prometheus desc, which are not exposed by the prometheus client,
+// so the cardinality guard aggregates the series with them
+type Desc struct {
+	*prometheus.Desc
+	Name   string
+	Help   string
+	Labels prometheus.Labels
+}
+
+// NewDesc has the same arguments as prometheus.NewDesc, the labels set by SetConstLabels are attached to the prometheus desc
+// but not kept in Labels, so they are not aggregated by the cardinality guard
+func NewDesc(fqName string, help string, variableLabels []string, labels prometheus.Labels) *Desc {
+	descLabels := labels
+	if len(constLabels) > 0 {
+		descLabels = make(prometheus.Labels, len(constLabels)+len(labels))
+		for k, v := range constLabels {
+			descLabels[k] = v
+		}
+		for k, v := range labels {
+			descLabels[k] = v
+		}
+	}
+	return &Desc{
+		Desc:   prometheus.NewDesc(fqName, help, variableLabels, descLabels),
+		Name:   fqName,
+		Help:   help,
+		Labels: labels,
+	}
+}
+
+type ExportedMetric struct {
+	Desc    *Desc
 	Eval    float64
 	ValType prometheus.ValueType
 }
 
+type ExportedMetrics []ExportedMetric
+
+// Export replaces the metrics of the topic, the series beyond the cap of the cardinality guard are aggregated or dropped
 func Export(topic string, m ExportedMetrics) {
-	collector.Metrics.Store(topic, m)
+	collector.Metrics.Store(topic, defaultGuard.limit(topic, m))
 }
 
 // Describe returns all descriptions of the collector.
@@ -72,7 +117,7 @@ func (b *Collector) Describe(ch chan<- *prometheus.Desc) {
 	b.Metrics.Range(func(key, value interface{}) bool {
 		m := value.(ExportedMetrics)
 		for _, metric := range m {
-			ch <- metric.Desc
+			ch <- metric.Desc.Desc
 		}
 		return true
 	})
@@ -85,7 +130,7 @@ func (b *Collector) Collect(ch chan<- prometheus.Metric) {
 	b.Metrics.Range(func(key, value interface{}) bool {
 		m := value.(ExportedMetrics)
 		for _, i := range m {
-			ch <- prometheus.MustNewConstMetric(i.Desc, i.ValType, i.Eval)
+			ch <- prometheus.MustNewConstMetric(i.Desc.Desc, i.ValType, i.Eval)
 		}
 		return true
 	})
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/global"
+)
+
+func TestSetConstLabels(t *testing.T) {
+	global.SetFleetLabels(global.FleetConfig{Cluster: "prod", Labels: map[string]string{"idc": "bj"}})
+	labels := global.FleetLabels()
+	defer func() {
+		global.SetFleetLabels(global.FleetConfig{})
+		SetConstLabels(nil)
+		collector.Metrics.Delete("fleet")
+	}()
+
+	SetConstLabels(labels)
+	Export("fleet", newTestMetrics("a"))
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+	exported := make(map[string]string)
+	for _, f := range families {
+		if f.GetName() != "loggie_sink_success_event" {
+			continue
+		}
+		// each series is exported once with the fleet labels
+		assert.Len(t, f.Metric, 1)
+		for _, l := range f.Metric[0].Label {
+			exported[l.GetName()] = l.GetValue()
+		}
+	}
+	assert.Equal(t, map[string]string{"cluster": "prod", "idc": "bj", PipelineNameKey: "a"}, exported)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package circuitbreaker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/eventbus/export/logger"
+	promeExporter "github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const name = "circuitBreaker"
+
+func init() {
+	eventbus.Registry(name, makeListener, eventbus.WithTopic(eventbus.CircuitBreakerTopic))
+}
+
+func makeListener() eventbus.Listener {
+	l := &Listener{
+		eventChan: make(chan eventbus.CircuitBreakerMetricData),
+		data:      make(map[string]eventbus.CircuitBreakerMetricData),
+		done:      make(chan struct{}),
+		config:    &Config{},
+	}
+	return l
+}
+
+type Config struct {
+	Period time.Duration `yaml:"period" default:"10s"`
+}
+
+type Listener struct {
+	config    *Config
+	eventChan chan eventbus.CircuitBreakerMetricData
+	data      map[string]eventbus.CircuitBreakerMetricData // key=pipelineName:interceptorName
+	done      chan struct{}
+}
+
+func (l *Listener) Name() string {
+	return name
+}
+
+func (l *Listener) Init(context api.Context) error {
+	return nil
+}
+
+func (l *Listener) Start() error {
+	go l.run()
+	return nil
+}
+
+func (l *Listener) Stop() {
+	close(l.done)
+}
+
+func (l *Listener) Config() interface{} {
+	return l.config
+}
+
+func (l *Listener) Subscribe(event eventbus.Event) {
+	e, ok := event.Data.(eventbus.CircuitBreakerMetricData)
+	if !ok {
+		log.Panic("type assert eventbus.CircuitBreakerMetricData failed: %v", ok)
+	}
+	l.eventChan <- e
+}
+
+func (l *Listener) run() {
+	tick := time.NewTicker(l.config.Period)
+	defer tick.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+
+		case e := <-l.eventChan:
+			l.data[fmt.Sprintf("%s:%s", e.PipelineName, e.InterceptorName)] = e
+
+		case <-tick.C:
+			l.exportPrometheus()
+			m, _ := json.Marshal(l.data)
+			logger.Export(eventbus.CircuitBreakerTopic, m)
+		}
+	}
+}
+
+var states = map[string]float64{
+	"closed":   0,
+	"halfOpen": 1,
+	"open":     2,
+}
+
+func buildFQName(name string) string {
+	return prometheus.BuildFQName(promeExporter.Loggie, "circuit_breaker", name)
+}
+
+func (l *Listener) exportPrometheus() {
+	metrics := promeExporter.ExportedMetrics{}
+	for _, d := range l.data {
+		labels := prometheus.Labels{
+			promeExporter.PipelineNameKey:    d.PipelineName,
+			promeExporter.InterceptorNameKey: d.InterceptorName,
+		}
+		m := promeExporter.ExportedMetrics{
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("state"), "state of the breaker, 0 is closed, 1 is half open and 2 is open", nil, labels),
+				Eval:    states[d.State],
+				ValType: prometheus.GaugeValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("opens_total"), "times the breaker opened", nil, labels),
+				Eval:    float64(d.Opens),
+				ValType: prometheus.CounterValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("rejected_total"), "batches shed while the breaker was open", nil, labels),
+				Eval:    float64(d.Rejected),
+				ValType: prometheus.CounterValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("probes_total"), "probe batches sent while the breaker was half open", nil, labels),
+				Eval:    float64(d.Probes),
+				ValType: prometheus.CounterValue,
+			},
+		}
+		metrics = append(metrics, m...)
+	}
+	promeExporter.Export(eventbus.CircuitBreakerTopic, metrics)
+}
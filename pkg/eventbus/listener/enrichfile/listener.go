@@ -0,0 +1,166 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package enrichfile
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/eventbus/export/logger"
+	promeExporter "github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	name = "enrichFile"
+
+	pathLabel = "path"
+)
+
+func init() {
+	eventbus.Registry(name, makeListener, eventbus.WithTopic(eventbus.EnrichFileTopic))
+}
+
+func makeListener() eventbus.Listener {
+	l := &Listener{
+		eventChan: make(chan eventbus.EnrichFileMetricData),
+		data:      make(map[string]eventbus.EnrichFileMetricData),
+		done:      make(chan struct{}),
+		config:    &Config{},
+	}
+	return l
+}
+
+type Config struct {
+	Period time.Duration `yaml:"period" default:"10s"`
+}
+
+type Listener struct {
+	config    *Config
+	eventChan chan eventbus.EnrichFileMetricData
+	data      map[string]eventbus.EnrichFileMetricData // key=pipelineName:interceptorName
+	done      chan struct{}
+}
+
+func (l *Listener) Name() string {
+	return name
+}
+
+func (l *Listener) Init(context api.Context) error {
+	return nil
+}
+
+func (l *Listener) Start() error {
+	go l.run()
+	return nil
+}
+
+func (l *Listener) Stop() {
+	close(l.done)
+}
+
+func (l *Listener) Config() interface{} {
+	return l.config
+}
+
+func (l *Listener) Subscribe(event eventbus.Event) {
+	e, ok := event.Data.(eventbus.EnrichFileMetricData)
+	if !ok {
+		log.Panic("type assert eventbus.EnrichFileMetricData failed: %v", ok)
+	}
+	l.eventChan <- e
+}
+
+func (l *Listener) run() {
+	tick := time.NewTicker(l.config.Period)
+	defer tick.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+
+		case e := <-l.eventChan:
+			l.data[fmt.Sprintf("%s:%s", e.PipelineName, e.InterceptorName)] = e
+
+		case <-tick.C:
+			l.exportPrometheus()
+			m, _ := json.Marshal(l.data)
+			logger.Export(eventbus.EnrichFileTopic, m)
+		}
+	}
+}
+
+func buildFQName(name string) string {
+	return prometheus.BuildFQName(promeExporter.Loggie, "enrich_file", name)
+}
+
+func (l *Listener) exportPrometheus() {
+	metrics := promeExporter.ExportedMetrics{}
+	for _, d := range l.data {
+		labels := prometheus.Labels{
+			promeExporter.PipelineNameKey:    d.PipelineName,
+			promeExporter.InterceptorNameKey: d.InterceptorName,
+			pathLabel:                        d.Path,
+		}
+		m := promeExporter.ExportedMetrics{
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("entries"), "entries of the loaded lookup file", nil, labels),
+				Eval:    float64(d.Entries),
+				ValType: prometheus.GaugeValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("hits_total"), "events enriched from the lookup file", nil, labels),
+				Eval:    float64(d.Hits),
+				ValType: prometheus.CounterValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("misses_total"), "events whose key is not found in the lookup file", nil, labels),
+				Eval:    float64(d.Misses),
+				ValType: prometheus.CounterValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("reloads_total"), "times the lookup file has been loaded", nil, labels),
+				Eval:    float64(d.Reloads),
+				ValType: prometheus.CounterValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("load_errors_total"), "times the lookup file failed to load", nil, labels),
+				Eval:    float64(d.LoadErrors),
+				ValType: prometheus.CounterValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("loaded_timestamp_seconds"), "unix time the lookup file was last loaded, 0 means never", nil, labels),
+				Eval:    float64(loadedAt(d.LoadedAt)),
+				ValType: prometheus.GaugeValue,
+			},
+		}
+		metrics = append(metrics, m...)
+	}
+	promeExporter.Export(eventbus.EnrichFileTopic, metrics)
+}
+
+func loadedAt(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
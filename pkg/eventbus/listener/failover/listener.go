@@ -0,0 +1,139 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/eventbus/export/logger"
+	promeExporter "github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const name = "failover"
+
+func init() {
+	eventbus.Registry(name, makeListener, eventbus.WithTopic(eventbus.FailoverTopic))
+}
+
+func makeListener() eventbus.Listener {
+	l := &Listener{
+		eventChan: make(chan eventbus.FailoverMetricData),
+		data:      make(map[string]eventbus.FailoverMetricData),
+		done:      make(chan struct{}),
+		config:    &Config{},
+	}
+	return l
+}
+
+type Config struct {
+	Period time.Duration `yaml:"period" default:"10s"`
+}
+
+type Listener struct {
+	config    *Config
+	eventChan chan eventbus.FailoverMetricData
+	data      map[string]eventbus.FailoverMetricData // key=pipelineName:sinkName
+	done      chan struct{}
+}
+
+func (l *Listener) Name() string {
+	return name
+}
+
+func (l *Listener) Init(context api.Context) error {
+	return nil
+}
+
+func (l *Listener) Start() error {
+	go l.run()
+	return nil
+}
+
+func (l *Listener) Stop() {
+	close(l.done)
+}
+
+func (l *Listener) Config() interface{} {
+	return l.config
+}
+
+func (l *Listener) Subscribe(event eventbus.Event) {
+	e, ok := event.Data.(eventbus.FailoverMetricData)
+	if !ok {
+		log.Panic("type assert eventbus.FailoverMetricData failed: %v", ok)
+	}
+	l.eventChan <- e
+}
+
+func (l *Listener) run() {
+	tick := time.NewTicker(l.config.Period)
+	defer tick.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+
+		case e := <-l.eventChan:
+			l.data[fmt.Sprintf("%s:%s", e.PipelineName, e.SinkName)] = e
+
+		case <-tick.C:
+			l.exportPrometheus()
+			m, _ := json.Marshal(l.data)
+			logger.Export(eventbus.FailoverTopic, m)
+		}
+	}
+}
+
+func buildFQName(name string) string {
+	return prometheus.BuildFQName(promeExporter.Loggie, "failover", name)
+}
+
+func (l *Listener) exportPrometheus() {
+	metrics := promeExporter.ExportedMetrics{}
+	for _, d := range l.data {
+		labels := prometheus.Labels{
+			promeExporter.PipelineNameKey: d.PipelineName,
+			promeExporter.SinkNameKey:     d.SinkName,
+		}
+		m := promeExporter.ExportedMetrics{
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("active_index"), "index of the active sink, 0 is the primary", nil, labels),
+				Eval:    float64(d.ActiveIndex),
+				ValType: prometheus.GaugeValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("switchovers_total"), "times the sink switched to a fallback", nil, labels),
+				Eval:    float64(d.Switchovers),
+				ValType: prometheus.CounterValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("failbacks_total"), "times the sink switched back to the primary", nil, labels),
+				Eval:    float64(d.Failbacks),
+				ValType: prometheus.CounterValue,
+			},
+		}
+		metrics = append(metrics, m...)
+	}
+	promeExporter.Export(eventbus.FailoverTopic, metrics)
+}
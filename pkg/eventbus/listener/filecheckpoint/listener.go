@@ -0,0 +1,144 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filecheckpoint
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/eventbus/export/logger"
+	promeExporter "github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const name = "fileCheckpoint"
+
+func init() {
+	eventbus.Registry(name, makeListener, eventbus.WithTopic(eventbus.FileCheckpointTopic))
+}
+
+func makeListener() eventbus.Listener {
+	l := &Listener{
+		eventChan: make(chan eventbus.FileCheckpointMetricData),
+		data:      make(map[string]eventbus.FileCheckpointMetricData),
+		done:      make(chan struct{}),
+		config:    &Config{},
+	}
+	return l
+}
+
+type Config struct {
+	Period time.Duration `yaml:"period" default:"10s"`
+}
+
+type Listener struct {
+	config    *Config
+	eventChan chan eventbus.FileCheckpointMetricData
+	data      map[string]eventbus.FileCheckpointMetricData // key=pipelineName:sourceName
+	done      chan struct{}
+}
+
+func (l *Listener) Name() string {
+	return name
+}
+
+func (l *Listener) Init(context api.Context) error {
+	return nil
+}
+
+func (l *Listener) Start() error {
+	go l.run()
+	return nil
+}
+
+func (l *Listener) Stop() {
+	close(l.done)
+}
+
+func (l *Listener) Config() interface{} {
+	return l.config
+}
+
+func (l *Listener) Subscribe(event eventbus.Event) {
+	e, ok := event.Data.(eventbus.FileCheckpointMetricData)
+	if !ok {
+		log.Panic("type assert eventbus.FileCheckpointMetricData failed: %v", ok)
+	}
+	l.eventChan <- e
+}
+
+func (l *Listener) run() {
+	tick := time.NewTicker(l.config.Period)
+	defer tick.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+
+		case e := <-l.eventChan:
+			key := fmt.Sprintf("%s:%s", e.PipelineName, e.SourceName)
+			if e.Stopped {
+				delete(l.data, key)
+				continue
+			}
+			l.data[key] = e
+
+		case <-tick.C:
+			l.exportPrometheus()
+			m, _ := json.Marshal(l.data)
+			logger.Export(eventbus.FileCheckpointTopic, m)
+		}
+	}
+}
+
+func buildFQName(name string) string {
+	return prometheus.BuildFQName(promeExporter.Loggie, "file_checkpoint", name)
+}
+
+func (l *Listener) exportPrometheus() {
+	metrics := promeExporter.ExportedMetrics{}
+	for _, d := range l.data {
+		labels := prometheus.Labels{
+			promeExporter.PipelineNameKey: d.PipelineName,
+			promeExporter.SourceNameKey:   d.SourceName,
+		}
+		m := promeExporter.ExportedMetrics{
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("lag_files"), "files with acked offsets which have not been checkpointed", nil, labels),
+				Eval:    float64(d.Files),
+				ValType: prometheus.GaugeValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("lag_bytes"), "acked bytes which have not been checkpointed to the registry", nil, labels),
+				Eval:    float64(d.LagBytes),
+				ValType: prometheus.GaugeValue,
+			},
+			{
+				Desc:    promeExporter.NewDesc(buildFQName("lag_seconds"), "time since the oldest acked offset which has not been checkpointed", nil, labels),
+				Eval:    d.LagTime.Seconds(),
+				ValType: prometheus.GaugeValue,
+			},
+		}
+		metrics = append(metrics, m...)
+	}
+	promeExporter.Export(eventbus.FileCheckpointTopic, metrics)
+}
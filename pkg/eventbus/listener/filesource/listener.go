@@ -41,6 +41,7 @@ func makeListener() eventbus.Listener {
 		eventChan: make(chan eventbus.CollectMetricData),
 		done:      make(chan struct{}),
 		data:      make(map[string]data),
+		skipped:   make(map[string]*skippedFile),
 		config:    &Config{},
 	}
 	return l
@@ -55,7 +56,8 @@ type Listener struct {
 	config    *Config
 	done      chan struct{}
 	eventChan chan eventbus.CollectMetricData
-	data      map[string]data // key=pipelineName+sourceName
+	data      map[string]data         // key=pipelineName+sourceName
+	skipped   map[string]*skippedFile // key=pipelineName+sourceName+fileName, kept as counters
 }
 
 type data struct {
@@ -75,6 +77,15 @@ type fileHarvester struct {
 	FileSize   int64   `json:"fileSize"` // It will not be brought when reporting. It is obtained by directly using OS. Stat (filename). Size() on the consumer side
 }
 
+// skippedFile is an existing file of which the data was skipped when starting to collect it
+type skippedFile struct {
+	PipelineName string
+	SourceName   string
+	SourceFields map[string]interface{}
+	FileName     string
+	Bytes        int64
+}
+
 func (l *Listener) Name() string {
 	return name
 }
@@ -115,7 +126,7 @@ func (l *Listener) exportPrometheus() {
 			labels[FileNameKey] = harvester.FileName
 			m1 := promeExporter.ExportedMetrics{
 				{
-					Desc: prometheus.NewDesc(
+					Desc: promeExporter.NewDesc(
 						buildFQName("file_size"),
 						"file size",
 						nil, labels,
@@ -124,7 +135,7 @@ func (l *Listener) exportPrometheus() {
 					ValType: prometheus.GaugeValue,
 				},
 				{
-					Desc: prometheus.NewDesc(
+					Desc: promeExporter.NewDesc(
 						buildFQName("file_offset"),
 						"file offset",
 						nil, labels,
@@ -133,7 +144,7 @@ func (l *Listener) exportPrometheus() {
 					ValType: prometheus.GaugeValue,
 				},
 				{
-					Desc: prometheus.NewDesc(
+					Desc: promeExporter.NewDesc(
 						buildFQName("line_number"),
 						"current read line number",
 						nil, labels,
@@ -142,7 +153,7 @@ func (l *Listener) exportPrometheus() {
 					ValType: prometheus.GaugeValue,
 				},
 				{
-					Desc: prometheus.NewDesc(
+					Desc: promeExporter.NewDesc(
 						buildFQName("line_qps"),
 						"current read line qps",
 						nil, labels,
@@ -156,6 +167,23 @@ func (l *Listener) exportPrometheus() {
 		}
 
 	}
+
+	for _, s := range l.skipped {
+		labels := prometheus.Labels{promeExporter.PipelineNameKey: s.PipelineName, promeExporter.SourceNameKey: s.SourceName}
+		eventbus.InjectFields(labels, s.SourceFields)
+		labels[FileNameKey] = s.FileName
+		m = append(m, promeExporter.ExportedMetrics{
+			{
+				Desc: promeExporter.NewDesc(
+					buildFQName("skipped_bytes"),
+					"bytes of existing files skipped when starting to collect them",
+					nil, labels,
+				),
+				Eval:    float64(s.Bytes),
+				ValType: prometheus.CounterValue,
+			},
+		}...)
+	}
 	promeExporter.Export(eventbus.FileSourceMetricTopic, m)
 }
 
@@ -215,6 +243,10 @@ func (l *Listener) consumer(e eventbus.CollectMetricData) {
 	buf.WriteString(e.SourceName)
 	key := buf.String()
 
+	if e.SkippedBytes > 0 {
+		l.skip(key, e)
+	}
+
 	metric, ok := l.data[key]
 	// if pipeline metrics not exist
 	if !ok {
@@ -263,3 +295,18 @@ func (l *Listener) consumer(e eventbus.CollectMetricData) {
 	}
 	harvester.TotalLine += e.Lines
 }
+
+func (l *Listener) skip(key string, e eventbus.CollectMetricData) {
+	key = key + "-" + e.FileName
+	s, ok := l.skipped[key]
+	if !ok {
+		s = &skippedFile{
+			PipelineName: e.PipelineName,
+			SourceName:   e.SourceName,
+			SourceFields: eventbus.GetFieldsByRef(l.config.FieldsRef, e.SourceFields),
+			FileName:     e.FileName,
+		}
+		l.skipped[key] = s
+	}
+	s.Bytes += e.SkippedBytes
+}
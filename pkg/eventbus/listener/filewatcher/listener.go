@@ -117,7 +117,7 @@ func (l *Listener) exportPrometheus() {
 
 		m1 := promeExporter.ExportedMetrics{
 			{
-				Desc: prometheus.NewDesc(
+				Desc: promeExporter.NewDesc(
 					buildFQName("active_file_count"),
 					"active file count total",
 					nil, labels,
@@ -126,7 +126,7 @@ func (l *Listener) exportPrometheus() {
 				ValType: prometheus.GaugeValue,
 			},
 			{
-				Desc: prometheus.NewDesc(
+				Desc: promeExporter.NewDesc(
 					buildFQName("inactive_file_count"),
 					"inactive file count",
 					nil, labels,
@@ -148,7 +148,7 @@ func (l *Listener) exportPrometheus() {
 
 			m2 := promeExporter.ExportedMetrics{
 				{
-					Desc: prometheus.NewDesc(
+					Desc: promeExporter.NewDesc(
 						buildFQName("file_size"),
 						"file size",
 						nil, labels,
@@ -157,7 +157,7 @@ func (l *Listener) exportPrometheus() {
 					ValType: prometheus.GaugeValue,
 				},
 				{
-					Desc: prometheus.NewDesc(
+					Desc: promeExporter.NewDesc(
 						buildFQName("file_ack_offset"),
 						"file ack offset",
 						nil, labels,
@@ -166,7 +166,7 @@ func (l *Listener) exportPrometheus() {
 					ValType: prometheus.GaugeValue,
 				},
 				{
-					Desc: prometheus.NewDesc(
+					Desc: promeExporter.NewDesc(
 						buildFQName("file_last_modify"),
 						"file last modify timestamp",
 						nil, labels,
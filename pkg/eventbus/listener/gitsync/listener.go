@@ -0,0 +1,195 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitsync
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/eventbus/export/logger"
+	promeExporter "github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	name = "gitSync"
+
+	urlLabel    = "url"
+	branchLabel = "branch"
+	commitLabel = "commit"
+)
+
+func init() {
+	eventbus.Registry(name, makeListener, eventbus.WithTopic(eventbus.GitSyncTopic))
+}
+
+func makeListener() eventbus.Listener {
+	l := &Listener{
+		eventChan: make(chan eventbus.GitSyncMetricData),
+		done:      make(chan struct{}),
+		config:    &Config{},
+	}
+	return l
+}
+
+type Config struct {
+	Period time.Duration `yaml:"period" default:"10s"`
+}
+
+type Listener struct {
+	config    *Config
+	eventChan chan eventbus.GitSyncMetricData
+	data      data
+	done      chan struct{}
+}
+
+type data struct {
+	Url         string    `json:"url"`
+	Branch      string    `json:"branch"`
+	Commit      string    `json:"commit"`
+	Fetched     string    `json:"fetched"`
+	Error       string    `json:"error,omitempty"`
+	LastSync    time.Time `json:"lastSync"`
+	SyncTotal   float64   `json:"syncTotal"`
+	FailedTotal float64   `json:"failedTotal"`
+	ApplyTotal  float64   `json:"applyTotal"`
+}
+
+func (l *Listener) Name() string {
+	return name
+}
+
+func (l *Listener) Init(context api.Context) error {
+	return nil
+}
+
+func (l *Listener) Start() error {
+	go l.run()
+	return nil
+}
+
+func (l *Listener) Stop() {
+	close(l.done)
+}
+
+func (l *Listener) Config() interface{} {
+	return l.config
+}
+
+func (l *Listener) Subscribe(event eventbus.Event) {
+	e, ok := event.Data.(eventbus.GitSyncMetricData)
+	if !ok {
+		log.Panic("type assert eventbus.GitSyncMetricData failed: %v", ok)
+	}
+	l.eventChan <- e
+}
+
+func (l *Listener) run() {
+	tick := time.NewTicker(l.config.Period)
+	defer tick.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+
+		case e := <-l.eventChan:
+			l.consume(e)
+
+		case <-tick.C:
+			if l.data.SyncTotal == 0 {
+				continue
+			}
+			l.exportPrometheus()
+			m, _ := json.Marshal(l.data)
+			logger.Export(eventbus.GitSyncTopic, m)
+		}
+	}
+}
+
+func (l *Listener) consume(e eventbus.GitSyncMetricData) {
+	l.data.Url = e.Url
+	l.data.Branch = e.Branch
+	l.data.Commit = e.Commit
+	l.data.Fetched = e.Fetched
+	l.data.Error = e.Error
+	l.data.LastSync = e.Time
+	l.data.SyncTotal++
+	if e.Error != "" {
+		l.data.FailedTotal++
+	}
+	if e.Changed {
+		l.data.ApplyTotal++
+	}
+}
+
+func buildFQName(name string) string {
+	return prometheus.BuildFQName(promeExporter.Loggie, "git_sync", name)
+}
+
+func (l *Listener) exportPrometheus() {
+	labels := prometheus.Labels{
+		urlLabel:    l.data.Url,
+		branchLabel: l.data.Branch,
+	}
+	commitLabels := prometheus.Labels{
+		urlLabel:    l.data.Url,
+		branchLabel: l.data.Branch,
+		commitLabel: l.data.Commit,
+	}
+	failed := 0
+	if l.data.Error != "" {
+		failed = 1
+	}
+
+	metrics := promeExporter.ExportedMetrics{
+		{
+			Desc:    promeExporter.NewDesc(buildFQName("commit_info"), "the commit of the pipelines applied currently", nil, commitLabels),
+			Eval:    float64(1),
+			ValType: prometheus.GaugeValue,
+		},
+		{
+			Desc:    promeExporter.NewDesc(buildFQName("total"), "sync total count", nil, labels),
+			Eval:    l.data.SyncTotal,
+			ValType: prometheus.CounterValue,
+		},
+		{
+			Desc:    promeExporter.NewDesc(buildFQName("failed_total"), "failed sync total count, including the invalid commits", nil, labels),
+			Eval:    l.data.FailedTotal,
+			ValType: prometheus.CounterValue,
+		},
+		{
+			Desc:    promeExporter.NewDesc(buildFQName("apply_total"), "applied commit total count", nil, labels),
+			Eval:    l.data.ApplyTotal,
+			ValType: prometheus.CounterValue,
+		},
+		{
+			Desc:    promeExporter.NewDesc(buildFQName("last_failed"), "whether the last sync failed", nil, labels),
+			Eval:    float64(failed),
+			ValType: prometheus.GaugeValue,
+		},
+		{
+			Desc:    promeExporter.NewDesc(buildFQName("last_sync_timestamp_seconds"), "the time of the last sync", nil, labels),
+			Eval:    float64(l.data.LastSync.Unix()),
+			ValType: prometheus.GaugeValue,
+		},
+	}
+	promeExporter.Export(eventbus.GitSyncTopic, metrics)
+}
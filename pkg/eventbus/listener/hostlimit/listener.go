@@ -0,0 +1,161 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostlimit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/eventbus/export/logger"
+	promeExporter "github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	name = "hostLimit"
+
+	hostLabel = "host"
+)
+
+func init() {
+	eventbus.Registry(name, makeListener, eventbus.WithTopic(eventbus.HostLimitTopic))
+}
+
+func makeListener() eventbus.Listener {
+	l := &Listener{
+		eventChan: make(chan eventbus.HostLimitMetricData),
+		data:      make(map[string]eventbus.HostLimitMetricData),
+		done:      make(chan struct{}),
+		config:    &Config{},
+	}
+	return l
+}
+
+type Config struct {
+	Period time.Duration `yaml:"period" default:"10s"`
+}
+
+type Listener struct {
+	config    *Config
+	eventChan chan eventbus.HostLimitMetricData
+	data      map[string]eventbus.HostLimitMetricData // key=pipelineName:sinkName
+	done      chan struct{}
+}
+
+func (l *Listener) Name() string {
+	return name
+}
+
+func (l *Listener) Init(context api.Context) error {
+	return nil
+}
+
+func (l *Listener) Start() error {
+	go l.run()
+	return nil
+}
+
+func (l *Listener) Stop() {
+	close(l.done)
+}
+
+func (l *Listener) Config() interface{} {
+	return l.config
+}
+
+func (l *Listener) Subscribe(event eventbus.Event) {
+	e, ok := event.Data.(eventbus.HostLimitMetricData)
+	if !ok {
+		log.Panic("type assert eventbus.HostLimitMetricData failed: %v", ok)
+	}
+	l.eventChan <- e
+}
+
+func (l *Listener) run() {
+	tick := time.NewTicker(l.config.Period)
+	defer tick.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+
+		case e := <-l.eventChan:
+			l.data[fmt.Sprintf("%s:%s", e.PipelineName, e.SinkName)] = e
+
+		case <-tick.C:
+			l.exportPrometheus()
+			m, _ := json.Marshal(l.data)
+			logger.Export(eventbus.HostLimitTopic, m)
+		}
+	}
+}
+
+func buildFQName(name string) string {
+	return prometheus.BuildFQName(promeExporter.Loggie, "host_limit", name)
+}
+
+func (l *Listener) exportPrometheus() {
+	metrics := promeExporter.ExportedMetrics{}
+	for _, d := range l.data {
+		for _, h := range d.Hosts {
+			labels := prometheus.Labels{
+				promeExporter.PipelineNameKey: d.PipelineName,
+				promeExporter.SinkNameKey:     d.SinkName,
+				hostLabel:                     h.Host,
+			}
+			m := promeExporter.ExportedMetrics{
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("in_flight_requests"), "requests being sent to the host", nil, labels),
+					Eval:    float64(h.InFlight),
+					ValType: prometheus.GaugeValue,
+				},
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("waiting_requests"), "requests queued by the limits of the host", nil, labels),
+					Eval:    float64(h.Waiting),
+					ValType: prometheus.GaugeValue,
+				},
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("throttled_requests"), "requests which were queued by the limits in current period", nil, labels),
+					Eval:    float64(h.Throttled),
+					ValType: prometheus.GaugeValue,
+				},
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("wait_seconds"), "time spent by the requests queued in current period", nil, labels),
+					Eval:    h.WaitTime.Seconds(),
+					ValType: prometheus.GaugeValue,
+				},
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("limit_qps"), "qps limit of the host, 0 means unlimited", nil, labels),
+					Eval:    h.LimitQPS,
+					ValType: prometheus.GaugeValue,
+				},
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("limit_in_flight"), "in-flight requests limit of the host, 0 means unlimited", nil, labels),
+					Eval:    float64(h.LimitInFlight),
+					ValType: prometheus.GaugeValue,
+				},
+			}
+			metrics = append(metrics, m...)
+		}
+	}
+	promeExporter.Export(eventbus.HostLimitTopic, metrics)
+}
@@ -93,7 +93,7 @@ func (l *Listener) exportPrometheus() {
 	}
 	metric := promeExporter.ExportedMetrics{
 		{
-			Desc: prometheus.NewDesc(
+			Desc: promeExporter.NewDesc(
 				prometheus.BuildFQName(promeExporter.Loggie, eventbus.InfoTopic, "status"),
 				"Loggie info",
 				nil, labels,
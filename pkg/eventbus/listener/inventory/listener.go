@@ -0,0 +1,253 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/global"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/ops/fleet"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+)
+
+const name = "inventory"
+
+func init() {
+	eventbus.Registry(name, makeListener, eventbus.WithTopics([]string{eventbus.PipelineTopic, eventbus.ErrorTopic}))
+}
+
+func makeListener() eventbus.Listener {
+	l := &Listener{
+		done:      make(chan struct{}),
+		config:    &Config{},
+		eventChan: make(chan eventbus.Event),
+		reports:   make(chan *fleet.Record, 1),
+		pipelines: make(map[string]fleet.Pipeline),
+	}
+	return l
+}
+
+// Config of reporting the inventory of the agent to the fleet view of the aggregators
+type Config struct {
+	Period time.Duration `yaml:"period" default:"1m"`
+	// Aggregators are the addresses of the management api of the aggregators, such as http://loggie-aggregator:9196,
+	// the record is reported to the first available one
+	Aggregators []string `yaml:"aggregators" validate:"required"`
+	// Token is sent as the bearer token, which should be granted the admin role when the authorization is enabled
+	Token   string        `yaml:"token,omitempty"`
+	Timeout time.Duration `yaml:"timeout" default:"10s"`
+	// MaxRecentErrors limits the distinct error messages in each record
+	MaxRecentErrors int `yaml:"maxRecentErrors" default:"10"`
+}
+
+type Listener struct {
+	config    *Config
+	done      chan struct{}
+	eventChan chan eventbus.Event
+	reports   chan *fleet.Record
+	client    *http.Client
+	startTime time.Time
+
+	pipelines map[string]fleet.Pipeline
+	errors    fleet.ErrorSummary
+}
+
+func (l *Listener) Name() string {
+	return name
+}
+
+func (l *Listener) Init(context api.Context) error {
+	return nil
+}
+
+func (l *Listener) Start() error {
+	l.startTime = time.Now()
+	l.client = &http.Client{
+		Timeout: l.config.Timeout,
+	}
+	go l.run()
+	go l.send()
+	return nil
+}
+
+func (l *Listener) Stop() {
+	close(l.done)
+}
+
+func (l *Listener) Subscribe(event eventbus.Event) {
+	l.eventChan <- event
+}
+
+func (l *Listener) Config() interface{} {
+	return l.config
+}
+
+func (l *Listener) run() {
+	tick := time.NewTicker(l.config.Period)
+	defer tick.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+
+		case e := <-l.eventChan:
+			l.consume(e)
+
+		case <-tick.C:
+			select {
+			case l.reports <- l.record():
+			default:
+				log.Warn("[inventory] the previous record is still being reported, skip this period")
+			}
+			l.errors = fleet.ErrorSummary{}
+		}
+	}
+}
+
+func (l *Listener) consume(e eventbus.Event) {
+	switch e.Topic {
+	case eventbus.PipelineTopic:
+		d, ok := e.Data.(eventbus.PipelineMetricData)
+		if !ok {
+			log.Panic("type assert eventbus.PipelineMetricData failed: %v", e)
+		}
+		if d.EventType == eventbus.ComponentStop {
+			delete(l.pipelines, d.Name)
+			return
+		}
+		components := make([]string, 0, len(d.ComponentConfigs))
+		for _, c := range d.ComponentConfigs {
+			components = append(components, fmt.Sprintf("%s/%s", c.Category, c.Code()))
+		}
+		l.pipelines[d.Name] = fleet.Pipeline{
+			Name:       d.Name,
+			ConfigHash: d.ConfigHash,
+			Components: components,
+			StartTime:  d.Time,
+		}
+
+	case eventbus.ErrorTopic:
+		d, ok := e.Data.(eventbus.ErrorMetricData)
+		if !ok {
+			log.Panic("type assert eventbus.ErrorMetricData failed: %v", e)
+		}
+		l.errors.Count++
+		l.errors.LastTime = time.Now()
+		for _, msg := range l.errors.Recent {
+			if msg == d.ErrorMsg {
+				return
+			}
+		}
+		if len(l.errors.Recent) < l.config.MaxRecentErrors {
+			l.errors.Recent = append(l.errors.Recent, d.ErrorMsg)
+		}
+	}
+}
+
+func (l *Listener) record() *fleet.Record {
+	pipelines := make([]fleet.Pipeline, 0, len(l.pipelines))
+	for _, p := range l.pipelines {
+		pipelines = append(pipelines, p)
+	}
+	sort.Slice(pipelines, func(i, j int) bool {
+		return pipelines[i].Name < pipelines[j].Name
+	})
+
+	hostname, _ := os.Hostname()
+	ips, _ := netutils.GetHostIPv4()
+	return &fleet.Record{
+		Node:       global.NodeName,
+		Hostname:   hostname,
+		IPs:        ips,
+		Version:    global.GetVersion(),
+		Labels:     global.FleetLabels(),
+		StartTime:  l.startTime,
+		ReportTime: time.Now(),
+		Interval:   l.config.Period,
+		ConfigHash: fleet.HashPipelines(pipelines),
+		Pipelines:  pipelines,
+		Errors:     l.errors,
+	}
+}
+
+// send reports the records out of the event loop, so the events are not blocked by the slow aggregators
+func (l *Listener) send() {
+	for {
+		select {
+		case <-l.done:
+			return
+
+		case r := <-l.reports:
+			body, err := json.Marshal(r)
+			if err != nil {
+				log.Warn("[inventory] marshal record failed: %v", err)
+				continue
+			}
+			// warn rather than error, otherwise the failure would be counted in the next record
+			if err := l.report(body); err != nil {
+				log.Warn("[inventory] report to aggregators failed: %v", err)
+			}
+		}
+	}
+}
+
+func (l *Listener) report(body []byte) error {
+	var errs []string
+	for _, addr := range l.config.Aggregators {
+		err := l.post(strings.TrimSuffix(addr, "/")+fleet.HandleInventory, body)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", addr, err))
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
+
+func (l *Listener) post(url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+l.config.Token)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		out, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("status %d: %s", resp.StatusCode, string(out))
+	}
+	return nil
+}
@@ -172,7 +172,7 @@ func (l *Listener) exportPrometheus() {
 
 			m1 := promeExporter.ExportedMetrics{
 				{
-					Desc: prometheus.NewDesc(
+					Desc: promeExporter.NewDesc(
 						buildFQName("error_count"),
 						"error count",
 						nil, labels,
@@ -119,7 +119,7 @@ func (l *Listener) exportPrometheus() {
 	for _, d := range l.data {
 		m := promeExporter.ExportedMetrics{
 			{
-				Desc: prometheus.NewDesc(
+				Desc: promeExporter.NewDesc(
 					prometheus.BuildFQName(promeExporter.Loggie, eventbus.QueueMetricTopic, "capacity"),
 					"queue capacity",
 					nil,
@@ -132,7 +132,7 @@ func (l *Listener) exportPrometheus() {
 				ValType: prometheus.GaugeValue,
 			},
 			{
-				Desc: prometheus.NewDesc(
+				Desc: promeExporter.NewDesc(
 					prometheus.BuildFQName(promeExporter.Loggie, eventbus.QueueMetricTopic, "size"),
 					"queue size",
 					nil,
@@ -145,7 +145,7 @@ func (l *Listener) exportPrometheus() {
 				ValType: prometheus.GaugeValue,
 			},
 			{
-				Desc: prometheus.NewDesc(
+				Desc: promeExporter.NewDesc(
 					prometheus.BuildFQName(promeExporter.Loggie, eventbus.QueueMetricTopic, "fill_percentage"),
 					"how full is queue",
 					nil,
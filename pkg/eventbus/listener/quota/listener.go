@@ -0,0 +1,161 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/eventbus/export/logger"
+	promeExporter "github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	name = "quota"
+
+	keyLabel = "key"
+)
+
+func init() {
+	eventbus.Registry(name, makeListener, eventbus.WithTopic(eventbus.QuotaTopic))
+}
+
+func makeListener() eventbus.Listener {
+	l := &Listener{
+		eventChan: make(chan eventbus.QuotaMetricData),
+		data:      make(map[string]eventbus.QuotaMetricData),
+		done:      make(chan struct{}),
+		config:    &Config{},
+	}
+	return l
+}
+
+type Config struct {
+	Period time.Duration `yaml:"period" default:"10s"`
+}
+
+type Listener struct {
+	config    *Config
+	eventChan chan eventbus.QuotaMetricData
+	data      map[string]eventbus.QuotaMetricData // key=pipelineName:interceptorName
+	done      chan struct{}
+}
+
+func (l *Listener) Name() string {
+	return name
+}
+
+func (l *Listener) Init(context api.Context) error {
+	return nil
+}
+
+func (l *Listener) Start() error {
+	go l.run()
+	return nil
+}
+
+func (l *Listener) Stop() {
+	close(l.done)
+}
+
+func (l *Listener) Config() interface{} {
+	return l.config
+}
+
+func (l *Listener) Subscribe(event eventbus.Event) {
+	e, ok := event.Data.(eventbus.QuotaMetricData)
+	if !ok {
+		log.Panic("type assert eventbus.QuotaMetricData failed: %v", ok)
+	}
+	l.eventChan <- e
+}
+
+func (l *Listener) run() {
+	tick := time.NewTicker(l.config.Period)
+	defer tick.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+
+		case e := <-l.eventChan:
+			l.data[fmt.Sprintf("%s:%s", e.PipelineName, e.InterceptorName)] = e
+
+		case <-tick.C:
+			l.exportPrometheus()
+			m, _ := json.Marshal(l.data)
+			logger.Export(eventbus.QuotaTopic, m)
+		}
+	}
+}
+
+func buildFQName(name string) string {
+	return prometheus.BuildFQName(promeExporter.Loggie, eventbus.QuotaTopic, name)
+}
+
+func (l *Listener) exportPrometheus() {
+	metrics := promeExporter.ExportedMetrics{}
+	for _, d := range l.data {
+		for _, u := range d.Usages {
+			labels := prometheus.Labels{
+				promeExporter.PipelineNameKey:    d.PipelineName,
+				promeExporter.InterceptorNameKey: d.InterceptorName,
+				keyLabel:                         u.Key,
+			}
+			m := promeExporter.ExportedMetrics{
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("used_bytes"), "bytes accounted to the key in current period", nil, labels),
+					Eval:    float64(u.Bytes),
+					ValType: prometheus.GaugeValue,
+				},
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("used_events"), "events accounted to the key in current period", nil, labels),
+					Eval:    float64(u.Events),
+					ValType: prometheus.GaugeValue,
+				},
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("limit_bytes"), "bytes limit of the key, 0 means unlimited", nil, labels),
+					Eval:    float64(u.LimitBytes),
+					ValType: prometheus.GaugeValue,
+				},
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("limit_events"), "events limit of the key, 0 means unlimited", nil, labels),
+					Eval:    float64(u.LimitEvents),
+					ValType: prometheus.GaugeValue,
+				},
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("exceeded_events"), "events over the quota in current period", nil, labels),
+					Eval:    float64(u.ExceededEvents),
+					ValType: prometheus.GaugeValue,
+				},
+				{
+					Desc:    promeExporter.NewDesc(buildFQName("exceeded_bytes"), "bytes over the quota in current period", nil, labels),
+					Eval:    float64(u.ExceededBytes),
+					ValType: prometheus.GaugeValue,
+				},
+			}
+			metrics = append(metrics, m...)
+		}
+	}
+	promeExporter.Export(eventbus.QuotaTopic, metrics)
+}
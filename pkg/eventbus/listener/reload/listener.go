@@ -106,7 +106,7 @@ func (l *Listener) export() {
 func (l *Listener) exportPrometheus() {
 	metric := promeExporter.ExportedMetrics{
 		{
-			Desc: prometheus.NewDesc(
+			Desc: promeExporter.NewDesc(
 				prometheus.BuildFQName(promeExporter.Loggie, eventbus.ReloadTopic, "total"),
 				"Loggie reload total count",
 				nil, nil,
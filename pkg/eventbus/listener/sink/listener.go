@@ -123,7 +123,7 @@ func (l *Listener) exportPrometheus() {
 	for _, d := range l.data {
 		m := promeExporter.ExportedMetrics{
 			{
-				Desc: prometheus.NewDesc(
+				Desc: promeExporter.NewDesc(
 					prometheus.BuildFQName(promeExporter.Loggie, eventbus.SinkMetricTopic, "success_event"),
 					"send event success count",
 					nil, prometheus.Labels{promeExporter.PipelineNameKey: d.PipelineName, promeExporter.SourceNameKey: d.SourceName},
@@ -132,7 +132,7 @@ func (l *Listener) exportPrometheus() {
 				ValType: prometheus.GaugeValue,
 			},
 			{
-				Desc: prometheus.NewDesc(
+				Desc: promeExporter.NewDesc(
 					prometheus.BuildFQName(promeExporter.Loggie, eventbus.SinkMetricTopic, "failed_event"),
 					"send event failed count",
 					nil, prometheus.Labels{promeExporter.PipelineNameKey: d.PipelineName, promeExporter.SourceNameKey: d.SourceName},
@@ -141,7 +141,7 @@ func (l *Listener) exportPrometheus() {
 				ValType: prometheus.GaugeValue,
 			},
 			{
-				Desc: prometheus.NewDesc(
+				Desc: promeExporter.NewDesc(
 					prometheus.BuildFQName(promeExporter.Loggie, eventbus.SinkMetricTopic, "event_qps"),
 					"send success event failed count",
 					nil, prometheus.Labels{promeExporter.PipelineNameKey: d.PipelineName, promeExporter.SourceNameKey: d.SourceName},
@@ -150,7 +150,7 @@ func (l *Listener) exportPrometheus() {
 				ValType: prometheus.GaugeValue,
 			},
 			{
-				Desc: prometheus.NewDesc(
+				Desc: promeExporter.NewDesc(
 					prometheus.BuildFQName(promeExporter.Loggie, eventbus.SinkMetricTopic, "goroutine_pool_size"),
 					"sink goroutine pool size",
 					nil, prometheus.Labels{promeExporter.PipelineNameKey: d.PipelineName, promeExporter.SourceNameKey: d.SourceName},
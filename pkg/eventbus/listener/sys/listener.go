@@ -141,7 +141,7 @@ func (l *Listener) getSysStat() error {
 func (l *Listener) exportPrometheus() {
 	metric := promeExporter.ExportedMetrics{
 		{
-			Desc: prometheus.NewDesc(
+			Desc: promeExporter.NewDesc(
 				prometheus.BuildFQName(promeExporter.Loggie, eventbus.SystemTopic, "mem_rss"),
 				"Loggie memory rss bytes",
 				nil, nil,
@@ -150,7 +150,7 @@ func (l *Listener) exportPrometheus() {
 			ValType: prometheus.GaugeValue,
 		},
 		{
-			Desc: prometheus.NewDesc(
+			Desc: promeExporter.NewDesc(
 				prometheus.BuildFQName(promeExporter.Loggie, eventbus.SystemTopic, "cpu_percent"),
 				"Loggie cpu percent",
 				nil, nil,
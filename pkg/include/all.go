@@ -20,46 +20,89 @@ package include
 
 import (
 	_ "github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
+	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/circuitbreaker"
+	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/enrichfile"
+	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/failover"
+	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/filecheckpoint"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/filesource"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/filewatcher"
+	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/gitsync"
+	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/hostlimit"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/info"
+	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/inventory"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/logalerting"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/normalize"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/pipeline"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/queue"
+	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/quota"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/reload"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/sink"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/sys"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/addcloudmeta"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/addhostmeta"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/addk8smeta"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/botfilter"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/circuitbreaker"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/cost"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/csv"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/enrichfile"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/json_decode"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/keystoredecrypt"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/limit"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/logalert"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/logalert/condition"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/maxage"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/maxbytes"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/metric"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/normalize"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/preserveraw"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/priority"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/quota"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/retry"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/router"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/schema"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/transformer"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/transformer/action"
 	_ "github.com/loggie-io/loggie/pkg/interceptor/transformer/condition"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/windowjoin"
 	_ "github.com/loggie-io/loggie/pkg/queue/channel"
 	_ "github.com/loggie-io/loggie/pkg/queue/memory"
 	_ "github.com/loggie-io/loggie/pkg/sink/alertwebhook"
+	_ "github.com/loggie-io/loggie/pkg/sink/azureblob"
+	_ "github.com/loggie-io/loggie/pkg/sink/cassandra"
+	_ "github.com/loggie-io/loggie/pkg/sink/clickhouse"
+	_ "github.com/loggie-io/loggie/pkg/sink/codec/avro"
 	_ "github.com/loggie-io/loggie/pkg/sink/codec/json"
+	_ "github.com/loggie-io/loggie/pkg/sink/codec/msgpack"
+	_ "github.com/loggie-io/loggie/pkg/sink/codec/protobuf"
 	_ "github.com/loggie-io/loggie/pkg/sink/codec/raw"
+	_ "github.com/loggie-io/loggie/pkg/sink/codec/template"
+	_ "github.com/loggie-io/loggie/pkg/sink/datadog"
 	_ "github.com/loggie-io/loggie/pkg/sink/dev"
 	_ "github.com/loggie-io/loggie/pkg/sink/elasticsearch"
+	_ "github.com/loggie-io/loggie/pkg/sink/eventhubs"
+	_ "github.com/loggie-io/loggie/pkg/sink/failover"
 	_ "github.com/loggie-io/loggie/pkg/sink/file"
 	_ "github.com/loggie-io/loggie/pkg/sink/franz"
+	_ "github.com/loggie-io/loggie/pkg/sink/gelf"
 	_ "github.com/loggie-io/loggie/pkg/sink/grpc"
+	_ "github.com/loggie-io/loggie/pkg/sink/iotdb"
 	_ "github.com/loggie-io/loggie/pkg/sink/kafka"
+	_ "github.com/loggie-io/loggie/pkg/sink/kinesis"
 	_ "github.com/loggie-io/loggie/pkg/sink/loki"
+	_ "github.com/loggie-io/loggie/pkg/sink/opensearch"
+	_ "github.com/loggie-io/loggie/pkg/sink/otlp"
+	_ "github.com/loggie-io/loggie/pkg/sink/pipeline"
+	_ "github.com/loggie-io/loggie/pkg/sink/pubsub"
 	_ "github.com/loggie-io/loggie/pkg/sink/pulsar"
 	_ "github.com/loggie-io/loggie/pkg/sink/rocketmq"
+	_ "github.com/loggie-io/loggie/pkg/sink/router"
+	_ "github.com/loggie-io/loggie/pkg/sink/s3"
 	_ "github.com/loggie-io/loggie/pkg/sink/sls"
+	_ "github.com/loggie-io/loggie/pkg/sink/splunk"
+	_ "github.com/loggie-io/loggie/pkg/sink/starrocks"
+	_ "github.com/loggie-io/loggie/pkg/sink/syslog"
+	_ "github.com/loggie-io/loggie/pkg/sink/tdengine"
 	_ "github.com/loggie-io/loggie/pkg/sink/zinc"
 	_ "github.com/loggie-io/loggie/pkg/source/codec/json"
 	_ "github.com/loggie-io/loggie/pkg/source/codec/regex"
@@ -71,6 +114,13 @@ import (
 	_ "github.com/loggie-io/loggie/pkg/source/grpc"
 	_ "github.com/loggie-io/loggie/pkg/source/kafka"
 	_ "github.com/loggie-io/loggie/pkg/source/kubernetes_event"
+	_ "github.com/loggie-io/loggie/pkg/source/mqtt"
+	_ "github.com/loggie-io/loggie/pkg/source/nats"
+	_ "github.com/loggie-io/loggie/pkg/source/otlp"
+	_ "github.com/loggie-io/loggie/pkg/source/pipeline"
 	_ "github.com/loggie-io/loggie/pkg/source/prometheus_exporter"
+	_ "github.com/loggie-io/loggie/pkg/source/pulsar"
+	_ "github.com/loggie-io/loggie/pkg/source/rocketmq"
+	_ "github.com/loggie-io/loggie/pkg/source/s3"
 	_ "github.com/loggie-io/loggie/pkg/source/unix"
 )
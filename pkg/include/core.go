@@ -20,9 +20,12 @@ package include
 
 import (
 	_ "github.com/loggie-io/loggie/pkg/eventbus/export/prometheus"
+	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/filecheckpoint"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/filesource"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/filewatcher"
+	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/gitsync"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/info"
+	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/inventory"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/logalerting"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/pipeline"
 	_ "github.com/loggie-io/loggie/pkg/eventbus/listener/queue"
@@ -44,8 +47,12 @@ import (
 	_ "github.com/loggie-io/loggie/pkg/queue/channel"
 	_ "github.com/loggie-io/loggie/pkg/queue/memory"
 	_ "github.com/loggie-io/loggie/pkg/sink/alertwebhook"
+	_ "github.com/loggie-io/loggie/pkg/sink/codec/avro"
 	_ "github.com/loggie-io/loggie/pkg/sink/codec/json"
+	_ "github.com/loggie-io/loggie/pkg/sink/codec/msgpack"
+	_ "github.com/loggie-io/loggie/pkg/sink/codec/protobuf"
 	_ "github.com/loggie-io/loggie/pkg/sink/codec/raw"
+	_ "github.com/loggie-io/loggie/pkg/sink/codec/template"
 	_ "github.com/loggie-io/loggie/pkg/sink/dev"
 	_ "github.com/loggie-io/loggie/pkg/sink/elasticsearch"
 	_ "github.com/loggie-io/loggie/pkg/sink/file"
@@ -0,0 +1,45 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addcloudmeta
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+)
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	FieldsName string `yaml:"fieldsName" default:"cloud"`
+	// Provider is one of aws, gcp, azure and aliyun, it is detected from the metadata services if empty
+	Provider string `yaml:"provider,omitempty" validate:"omitempty,oneof=aws gcp azure aliyun"`
+	// Endpoint overrides the metadata service address of the provider
+	Endpoint string        `yaml:"endpoint,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty" default:"3s"`
+	// Tags adds the instance tags, supported by aws with tags allowed in instance metadata, and azure
+	Tags bool `yaml:"tags,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	if c.Endpoint != "" && c.Provider == "" {
+		return errors.New("provider is required when endpoint is set")
+	}
+	return nil
+}
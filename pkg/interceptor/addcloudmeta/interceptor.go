@@ -0,0 +1,163 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addcloudmeta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const (
+	Type = "addCloudMeta"
+)
+
+func init() {
+	pipeline.Register(api.INTERCEPTOR, Type, makeInterceptor)
+}
+
+func makeInterceptor(info pipeline.Info) api.Component {
+	return &Interceptor{
+		config:   &Config{},
+		metadata: make(map[string]interface{}),
+	}
+}
+
+// Interceptor adds the metadata of the cloud instance, which is fetched from the metadata service once on start
+type Interceptor struct {
+	config *Config
+
+	metadata map[string]interface{}
+}
+
+func (icp *Interceptor) Config() interface{} {
+	return icp.config
+}
+
+func (icp *Interceptor) Category() api.Category {
+	return api.INTERCEPTOR
+}
+
+func (icp *Interceptor) Type() api.Type {
+	return Type
+}
+
+func (icp *Interceptor) String() string {
+	return fmt.Sprintf("%s/%s", icp.Category(), icp.Type())
+}
+
+func (icp *Interceptor) Init(context api.Context) error {
+	return nil
+}
+
+func (icp *Interceptor) Start() error {
+	endpoints := make(map[string]string, len(defaultEndpoints))
+	for p, e := range defaultEndpoints {
+		endpoints[p] = e
+	}
+	candidates := providers
+	if icp.config.Provider != "" {
+		candidates = []string{icp.config.Provider}
+		if icp.config.Endpoint != "" {
+			endpoints[icp.config.Provider] = strings.TrimSuffix(icp.config.Endpoint, "/")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), icp.config.Timeout)
+	defer cancel()
+	m, err := detect(ctx, candidates, endpoints, icp.config.Tags)
+	if err != nil {
+		return errors.WithMessage(err, "get cloud instance metadata")
+	}
+	log.Info("%s get metadata of %s instance %s", icp.String(), m.Provider, m.InstanceId)
+	icp.metadata = m.fields()
+	return nil
+}
+
+// detect requests the metadata services of the providers at the same time, and returns the first one in order
+// which responds successfully
+func detect(ctx context.Context, candidates []string, endpoints map[string]string, tags bool) (*metadata, error) {
+	type fetched struct {
+		m   *metadata
+		err error
+	}
+	results := make([]chan fetched, len(candidates))
+	for i, p := range candidates {
+		results[i] = make(chan fetched, 1)
+		go func(p string, out chan<- fetched) {
+			m, err := fetch(ctx, p, &fetcher{
+				client:   &http.Client{},
+				endpoint: endpoints[p],
+				tags:     tags,
+			})
+			out <- fetched{m: m, err: err}
+		}(p, results[i])
+	}
+
+	var errs []string
+	for i, p := range candidates {
+		r := <-results[i]
+		if r.err == nil {
+			return r.m, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", p, r.err))
+	}
+	return nil, errors.Errorf("no metadata service available, %s", strings.Join(errs, "; "))
+}
+
+func (icp *Interceptor) Stop() {
+}
+
+func (icp *Interceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
+	event := invocation.Event
+	header := event.Header()
+
+	metaClone := make(map[string]interface{}, len(icp.metadata))
+	for k, v := range icp.metadata {
+		if tags, ok := v.(map[string]string); ok {
+			tagsClone := make(map[string]interface{}, len(tags))
+			for tk, tv := range tags {
+				tagsClone[tk] = tv
+			}
+			v = tagsClone
+		}
+		metaClone[k] = v
+	}
+	header[icp.config.FieldsName] = metaClone
+
+	return invoker.Invoke(invocation)
+}
+
+func (icp *Interceptor) Order() int {
+	return icp.config.Order
+}
+
+func (icp *Interceptor) BelongTo() (componentTypes []string) {
+	return icp.config.BelongTo
+}
+
+func (icp *Interceptor) IgnoreRetry() bool {
+	return true
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addcloudmeta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func fakeMetadataService(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	// aws with IMDSv2 required
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		if r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, "token")
+	})
+	awsAuth := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("/latest/dynamic/instance-identity/document", awsAuth(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"accountId":"123456789012","availabilityZone":"us-east-1a","instanceId":"i-0abc","instanceType":"m5.large","region":"us-east-1"}`)
+	}))
+	mux.HandleFunc("/latest/meta-data/tags/instance", awsAuth(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "Name\nteam")
+	}))
+	mux.HandleFunc("/latest/meta-data/tags/instance/Name", awsAuth(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "web-0")
+	}))
+	mux.HandleFunc("/latest/meta-data/tags/instance/team", awsAuth(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "infra")
+	}))
+
+	mux.HandleFunc("/computeMetadata/v1/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"instance":{"id":8123456789012345678,"machineType":"projects/42/machineTypes/e2-medium","zone":"projects/42/zones/us-central1-a"},"project":{"projectId":"loggie"}}`)
+	})
+
+	mux.HandleFunc("/metadata/instance/compute", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"vmId":"02aab8a4","vmSize":"Standard_D2s_v3","location":"westeurope","zone":"1","subscriptionId":"sub-1","tagsList":[{"name":"env","value":"prod"}]}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestFetch(t *testing.T) {
+	log.InitDefaultLogger()
+	srv := fakeMetadataService(t)
+	defer srv.Close()
+
+	tests := []struct {
+		provider string
+		want     *metadata
+	}{
+		{
+			provider: ProviderAWS,
+			want: &metadata{
+				Provider:     ProviderAWS,
+				InstanceId:   "i-0abc",
+				InstanceType: "m5.large",
+				Region:       "us-east-1",
+				Zone:         "us-east-1a",
+				AccountId:    "123456789012",
+				Tags:         map[string]string{"Name": "web-0", "team": "infra"},
+			},
+		},
+		{
+			provider: ProviderGCP,
+			want: &metadata{
+				Provider:     ProviderGCP,
+				InstanceId:   "8123456789012345678",
+				InstanceType: "e2-medium",
+				Region:       "us-central1",
+				Zone:         "us-central1-a",
+				AccountId:    "loggie",
+			},
+		},
+		{
+			provider: ProviderAzure,
+			want: &metadata{
+				Provider:     ProviderAzure,
+				InstanceId:   "02aab8a4",
+				InstanceType: "Standard_D2s_v3",
+				Region:       "westeurope",
+				Zone:         "1",
+				AccountId:    "sub-1",
+				Tags:         map[string]string{"env": "prod"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			m, err := fetch(context.Background(), tt.provider, &fetcher{
+				client:   srv.Client(),
+				endpoint: srv.URL,
+				tags:     true,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, m)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	log.InitDefaultLogger()
+	srv := fakeMetadataService(t)
+	defer srv.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	defer down.Close()
+
+	m, err := detect(context.Background(), providers, map[string]string{
+		ProviderAWS:    down.URL,
+		ProviderGCP:    srv.URL,
+		ProviderAzure:  srv.URL,
+		ProviderAliyun: down.URL,
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderGCP, m.Provider)
+
+	_, err = detect(context.Background(), providers, map[string]string{
+		ProviderAWS:    down.URL,
+		ProviderGCP:    down.URL,
+		ProviderAzure:  down.URL,
+		ProviderAliyun: down.URL,
+	}, false)
+	assert.Error(t, err)
+}
+
+func TestFetchAliyun(t *testing.T) {
+	log.InitDefaultLogger()
+	// hardened mode is not enabled
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/latest/dynamic/instance-identity/document" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"zone-id":"cn-hangzhou-h","instance-id":"i-bp1","region-id":"cn-hangzhou","owner-account-id":"1234","instance-type":"ecs.g6.large"}`)
+	}))
+	defer srv.Close()
+
+	m, err := fetch(context.Background(), ProviderAliyun, &fetcher{client: srv.Client(), endpoint: srv.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, &metadata{
+		Provider:     ProviderAliyun,
+		InstanceId:   "i-bp1",
+		InstanceType: "ecs.g6.large",
+		Region:       "cn-hangzhou",
+		Zone:         "cn-hangzhou-h",
+		AccountId:    "1234",
+	}, m)
+}
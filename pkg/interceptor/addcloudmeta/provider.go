@@ -0,0 +1,322 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addcloudmeta
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	ProviderAWS    = "aws"
+	ProviderGCP    = "gcp"
+	ProviderAzure  = "azure"
+	ProviderAliyun = "aliyun"
+
+	tokenTTLSeconds = "21600"
+	tokenTimeout    = time.Second
+)
+
+// providers are detected in this order
+var providers = []string{ProviderAWS, ProviderGCP, ProviderAzure, ProviderAliyun}
+
+var defaultEndpoints = map[string]string{
+	ProviderAWS:    "http://169.254.169.254",
+	ProviderGCP:    "http://metadata.google.internal",
+	ProviderAzure:  "http://169.254.169.254",
+	ProviderAliyun: "http://100.100.100.200",
+}
+
+type metadata struct {
+	Provider     string
+	InstanceId   string
+	InstanceType string
+	Region       string
+	Zone         string
+	AccountId    string
+	Tags         map[string]string
+}
+
+func (m *metadata) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"provider":     m.Provider,
+		"instanceId":   m.InstanceId,
+		"instanceType": m.InstanceType,
+		"region":       m.Region,
+		"zone":         m.Zone,
+		"accountId":    m.AccountId,
+	}
+	if len(m.Tags) > 0 {
+		fields["tags"] = m.Tags
+	}
+	return fields
+}
+
+type fetcher struct {
+	client   *http.Client
+	endpoint string
+	tags     bool
+}
+
+func fetch(ctx context.Context, provider string, f *fetcher) (*metadata, error) {
+	switch provider {
+	case ProviderAWS:
+		return f.aws(ctx)
+	case ProviderGCP:
+		return f.gcp(ctx)
+	case ProviderAzure:
+		return f.azure(ctx)
+	case ProviderAliyun:
+		return f.aliyun(ctx)
+	}
+	return nil, errors.Errorf("cloud provider %s is not supported", provider)
+}
+
+type awsIdentity struct {
+	InstanceId       string `json:"instanceId"`
+	InstanceType     string `json:"instanceType"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone"`
+	AccountId        string `json:"accountId"`
+}
+
+func (f *fetcher) aws(ctx context.Context) (*metadata, error) {
+	header := http.Header{}
+	token, err := f.token(ctx, "X-aws-ec2-metadata-token-ttl-seconds")
+	if err != nil {
+		// IMDSv1 if the session token is not available
+		log.Debug("get aws imdsv2 token failed, fallback to imdsv1: %v", err)
+	} else {
+		header.Set("X-aws-ec2-metadata-token", token)
+	}
+
+	raw, err := f.get(ctx, "/latest/dynamic/instance-identity/document", header)
+	if err != nil {
+		return nil, err
+	}
+	id := &awsIdentity{}
+	if err := json.Unmarshal(raw, id); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal aws instance identity document")
+	}
+	m := &metadata{
+		Provider:     ProviderAWS,
+		InstanceId:   id.InstanceId,
+		InstanceType: id.InstanceType,
+		Region:       id.Region,
+		Zone:         id.AvailabilityZone,
+		AccountId:    id.AccountId,
+	}
+	if f.tags {
+		m.Tags = f.awsTags(ctx, header)
+	}
+	return m, nil
+}
+
+// awsTags returns nil if the instance tags are not allowed in instance metadata
+func (f *fetcher) awsTags(ctx context.Context, header http.Header) map[string]string {
+	raw, err := f.get(ctx, "/latest/meta-data/tags/instance", header)
+	if err != nil {
+		log.Warn("get aws instance tags failed, please check whether tags are allowed in instance metadata: %v", err)
+		return nil
+	}
+	tags := make(map[string]string)
+	for _, key := range strings.Split(strings.TrimSpace(string(raw)), "\n") {
+		if key == "" {
+			continue
+		}
+		value, err := f.get(ctx, "/latest/meta-data/tags/instance/"+url.PathEscape(key), header)
+		if err != nil {
+			log.Warn("get aws instance tag %s failed: %v", key, err)
+			continue
+		}
+		tags[key] = string(value)
+	}
+	return tags
+}
+
+type gcpMetadata struct {
+	Instance struct {
+		Id          stdjson.Number `json:"id"`
+		MachineType string         `json:"machineType"`
+		Zone        string         `json:"zone"`
+	} `json:"instance"`
+	Project struct {
+		ProjectId string `json:"projectId"`
+	} `json:"project"`
+}
+
+func (f *fetcher) gcp(ctx context.Context) (*metadata, error) {
+	header := http.Header{}
+	header.Set("Metadata-Flavor", "Google")
+	raw, err := f.get(ctx, "/computeMetadata/v1/?recursive=true", header)
+	if err != nil {
+		return nil, err
+	}
+	out := &gcpMetadata{}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal gcp metadata")
+	}
+
+	// zone is like projects/123456/zones/us-central1-a
+	zone := lastSegment(out.Instance.Zone)
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+	return &metadata{
+		Provider:     ProviderGCP,
+		InstanceId:   out.Instance.Id.String(),
+		InstanceType: lastSegment(out.Instance.MachineType),
+		Region:       region,
+		Zone:         zone,
+		AccountId:    out.Project.ProjectId,
+	}, nil
+}
+
+type azureCompute struct {
+	VmId           string `json:"vmId"`
+	VmSize         string `json:"vmSize"`
+	Location       string `json:"location"`
+	Zone           string `json:"zone"`
+	SubscriptionId string `json:"subscriptionId"`
+	TagsList       []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"tagsList"`
+}
+
+func (f *fetcher) azure(ctx context.Context) (*metadata, error) {
+	header := http.Header{}
+	header.Set("Metadata", "true")
+	raw, err := f.get(ctx, "/metadata/instance/compute?api-version=2021-02-01&format=json", header)
+	if err != nil {
+		return nil, err
+	}
+	out := &azureCompute{}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal azure instance metadata")
+	}
+	m := &metadata{
+		Provider:     ProviderAzure,
+		InstanceId:   out.VmId,
+		InstanceType: out.VmSize,
+		Region:       out.Location,
+		Zone:         out.Zone,
+		AccountId:    out.SubscriptionId,
+	}
+	if f.tags && len(out.TagsList) > 0 {
+		m.Tags = make(map[string]string, len(out.TagsList))
+		for _, t := range out.TagsList {
+			m.Tags[t.Name] = t.Value
+		}
+	}
+	return m, nil
+}
+
+type aliyunIdentity struct {
+	InstanceId     string `json:"instance-id"`
+	InstanceType   string `json:"instance-type"`
+	RegionId       string `json:"region-id"`
+	ZoneId         string `json:"zone-id"`
+	OwnerAccountId string `json:"owner-account-id"`
+}
+
+func (f *fetcher) aliyun(ctx context.Context) (*metadata, error) {
+	header := http.Header{}
+	token, err := f.token(ctx, "X-aliyun-ecs-metadata-token-ttl-seconds")
+	if err != nil {
+		log.Debug("get aliyun metadata token failed, fallback to normal mode: %v", err)
+	} else {
+		header.Set("X-aliyun-ecs-metadata-token", token)
+	}
+
+	raw, err := f.get(ctx, "/latest/dynamic/instance-identity/document", header)
+	if err != nil {
+		return nil, err
+	}
+	id := &aliyunIdentity{}
+	if err := json.Unmarshal(raw, id); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal aliyun instance identity document")
+	}
+	return &metadata{
+		Provider:     ProviderAliyun,
+		InstanceId:   id.InstanceId,
+		InstanceType: id.InstanceType,
+		Region:       id.RegionId,
+		Zone:         id.ZoneId,
+		AccountId:    id.OwnerAccountId,
+	}, nil
+}
+
+// token gets the session token of the metadata service, such as IMDSv2 of aws and the hardened mode of aliyun
+func (f *fetcher) token(ctx context.Context, ttlHeader string) (string, error) {
+	// the response would be dropped if the hop limit is exceeded such as in containers, do not wait too long
+	ctx, cancel := context.WithTimeout(ctx, tokenTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, f.endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(ttlHeader, tokenTTLSeconds)
+	out, err := f.do(req)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (f *fetcher) get(ctx context.Context, path string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return f.do(req)
+}
+
+func (f *fetcher) do(req *http.Request) ([]byte, error) {
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("request %s returned status %d", req.URL.Path, resp.StatusCode)
+	}
+	return body, nil
+}
+
+func lastSegment(s string) string {
+	return s[strings.LastIndex(s, "/")+1:]
+}
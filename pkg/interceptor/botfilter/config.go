@@ -0,0 +1,91 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package botfilter
+
+import (
+	"net"
+	"regexp"
+
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+	"github.com/pkg/errors"
+)
+
+const (
+	ActionDrop = "drop"
+	ActionTag  = "tag"
+)
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	// UserAgentKey and IPKey are the fields of the access log, IPKey may be a `ip:port` or a X-Forwarded-For list,
+	// the first address of the list is the client
+	UserAgentKey string `yaml:"userAgentKey,omitempty" default:"http_user_agent"`
+	IPKey        string `yaml:"ipKey,omitempty" default:"remote_addr"`
+
+	// Builtin enables the bundled user agent and ip range lists of the common crawlers
+	Builtin *bool `yaml:"builtin,omitempty" default:"true"`
+	// EmptyUserAgent classifies the events without user agent, or with `-`, as bots
+	EmptyUserAgent bool `yaml:"emptyUserAgent,omitempty"`
+	// UserAgents and IPRanges are the custom rules, which are matched before the bundled ones
+	UserAgents []UserAgentRule `yaml:"userAgents,omitempty" validate:"dive"`
+	IPRanges   []IPRangeRule   `yaml:"ipRanges,omitempty" validate:"dive"`
+	// Allow are the user agent substrings never classified as bots, such as the synthetic monitoring of your own
+	Allow []string `yaml:"allow,omitempty"`
+
+	// Action could be drop or tag, applied to the bot events
+	Action string `yaml:"action,omitempty" default:"tag" validate:"oneof=drop tag"`
+	// TagKey is the header key set to the name of the matched rule when action is tag
+	TagKey string `yaml:"tagKey,omitempty" default:"bot"`
+}
+
+// UserAgentRule matches the user agents which contain any of Contains case-insensitively, or match Regex
+type UserAgentRule struct {
+	Name     string   `yaml:"name,omitempty" validate:"required"`
+	Contains []string `yaml:"contains,omitempty"`
+	Regex    string   `yaml:"regex,omitempty"`
+}
+
+type IPRangeRule struct {
+	Name  string   `yaml:"name,omitempty" validate:"required"`
+	CIDRs []string `yaml:"cidrs,omitempty" validate:"required"`
+}
+
+func (c *Config) builtin() bool {
+	return c.Builtin == nil || *c.Builtin
+}
+
+func (c *Config) Validate() error {
+	for _, r := range c.UserAgents {
+		if len(r.Contains) == 0 && r.Regex == "" {
+			return errors.Errorf("contains or regex is required in user agent rule %s", r.Name)
+		}
+		if r.Regex != "" {
+			if _, err := regexp.Compile(r.Regex); err != nil {
+				return errors.WithMessagef(err, "compile regex of user agent rule %s", r.Name)
+			}
+		}
+	}
+	for _, r := range c.IPRanges {
+		for _, cidr := range r.CIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return errors.WithMessagef(err, "parse cidr of ip range rule %s", r.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package botfilter
+
+import (
+	"fmt"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/eventops"
+)
+
+const Type = "botFilter"
+
+func init() {
+	pipeline.Register(api.INTERCEPTOR, Type, makeInterceptor)
+}
+
+func makeInterceptor(info pipeline.Info) api.Component {
+	return &Interceptor{
+		config: &Config{},
+	}
+}
+
+type Interceptor struct {
+	name       string
+	config     *Config
+	classifier *classifier
+}
+
+func (i *Interceptor) Config() interface{} {
+	return i.config
+}
+
+func (i *Interceptor) Category() api.Category {
+	return api.INTERCEPTOR
+}
+
+func (i *Interceptor) Type() api.Type {
+	return Type
+}
+
+func (i *Interceptor) String() string {
+	return fmt.Sprintf("%s/%s", i.Category(), i.Type())
+}
+
+func (i *Interceptor) Init(context api.Context) error {
+	i.name = context.Name()
+	i.classifier = newClassifier(i.config)
+	return nil
+}
+
+func (i *Interceptor) Start() error {
+	return nil
+}
+
+func (i *Interceptor) Stop() {
+}
+
+func (i *Interceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
+	e := invocation.Event
+	name, ok := i.classifier.classify(eventops.GetString(e, i.config.UserAgentKey), eventops.GetString(e, i.config.IPKey))
+	if !ok {
+		return invoker.Invoke(invocation)
+	}
+
+	switch i.config.Action {
+	case ActionDrop:
+		return result.Drop()
+
+	case ActionTag:
+		header := e.Header()
+		if header == nil {
+			header = make(map[string]interface{})
+			e.Fill(e.Meta(), header, e.Body())
+		}
+		header[i.config.TagKey] = name
+	}
+	return invoker.Invoke(invocation)
+}
+
+func (i *Interceptor) Order() int {
+	return i.config.Order
+}
+
+func (i *Interceptor) BelongTo() (componentTypes []string) {
+	return i.config.BelongTo
+}
+
+func (i *Interceptor) IgnoreRetry() bool {
+	return true
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package botfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+)
+
+type fakeInvoker struct{}
+
+func (f *fakeInvoker) Invoke(invocation source.Invocation) api.Result {
+	return result.Success()
+}
+
+func newInterceptor(config Config) *Interceptor {
+	config.UserAgentKey = "ua"
+	config.IPKey = "ip"
+	config.TagKey = "bot"
+	if config.Action == "" {
+		config.Action = ActionTag
+	}
+	return &Interceptor{config: &config, classifier: newClassifier(&config)}
+}
+
+const browser = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+
+func TestIntercept(t *testing.T) {
+	log.InitDefaultLogger()
+	disabled := false
+	tests := []struct {
+		name       string
+		config     Config
+		header     map[string]interface{}
+		wantStatus api.Status
+		wantBot    interface{}
+	}{
+		{
+			name:       "browser",
+			header:     map[string]interface{}{"ua": browser, "ip": "10.0.0.1"},
+			wantStatus: api.SUCCESS,
+		},
+		{
+			name:       "googlebot",
+			header:     map[string]interface{}{"ua": "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"},
+			wantStatus: api.SUCCESS,
+			wantBot:    "googlebot",
+		},
+		{
+			name:       "generic",
+			header:     map[string]interface{}{"ua": "Mozilla/5.0 (compatible; FooCrawler 1.0)"},
+			wantStatus: api.SUCCESS,
+			wantBot:    "generic",
+		},
+		{
+			name:       "tool",
+			header:     map[string]interface{}{"ua": "curl/8.1.2"},
+			wantStatus: api.SUCCESS,
+			wantBot:    "tool",
+		},
+		{
+			name:       "ip range with port",
+			header:     map[string]interface{}{"ua": browser, "ip": "66.249.66.1:52311"},
+			wantStatus: api.SUCCESS,
+			wantBot:    "googlebot",
+		},
+		{
+			name:       "forwarded for",
+			header:     map[string]interface{}{"ua": browser, "ip": "157.55.39.10, 10.0.0.1"},
+			wantStatus: api.SUCCESS,
+			wantBot:    "bingbot",
+		},
+		{
+			name:       "empty user agent",
+			config:     Config{EmptyUserAgent: true},
+			header:     map[string]interface{}{"ua": "-"},
+			wantStatus: api.SUCCESS,
+			wantBot:    "empty",
+		},
+		{
+			name:       "custom first",
+			config:     Config{UserAgents: []UserAgentRule{{Name: "internal", Regex: `^probe/\d+`}, {Name: "partner", Contains: []string{"GoogleBot-Partner"}}}},
+			header:     map[string]interface{}{"ua": "googlebot-partner/1.0"},
+			wantStatus: api.SUCCESS,
+			wantBot:    "partner",
+		},
+		{
+			name:       "custom ip range",
+			config:     Config{IPRanges: []IPRangeRule{{Name: "scanner", CIDRs: []string{"192.0.2.0/24", "2001:db8::/32"}}}},
+			header:     map[string]interface{}{"ua": browser, "ip": "[2001:db8::1]:443"},
+			wantStatus: api.SUCCESS,
+			wantBot:    "scanner",
+		},
+		{
+			name:       "allowed",
+			config:     Config{Allow: []string{"UptimeBot"}},
+			header:     map[string]interface{}{"ua": "Mozilla/5.0 (compatible; UptimeBot/1.0)", "ip": "66.249.66.1"},
+			wantStatus: api.SUCCESS,
+		},
+		{
+			name:       "builtin disabled",
+			config:     Config{Builtin: &disabled},
+			header:     map[string]interface{}{"ua": "Googlebot/2.1", "ip": "66.249.66.1"},
+			wantStatus: api.SUCCESS,
+		},
+		{
+			name:       "drop",
+			config:     Config{Action: ActionDrop},
+			header:     map[string]interface{}{"ua": "Baiduspider/2.0"},
+			wantStatus: api.DROP,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := newInterceptor(tt.config)
+			e := event.NewEvent(tt.header, []byte("GET / HTTP/1.1"))
+			res := i.Intercept(&fakeInvoker{}, source.Invocation{Event: e})
+			assert.Equal(t, tt.wantStatus, res.Status())
+			assert.Equal(t, tt.wantBot, e.Header()["bot"])
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.Error(t, (&Config{UserAgents: []UserAgentRule{{Name: "a"}}}).Validate())
+	assert.Error(t, (&Config{UserAgents: []UserAgentRule{{Name: "a", Regex: "("}}}).Validate())
+	assert.Error(t, (&Config{IPRanges: []IPRangeRule{{Name: "a", CIDRs: []string{"10.0.0.1"}}}}).Validate())
+	assert.NoError(t, (&Config{IPRanges: []IPRangeRule{{Name: "a", CIDRs: []string{"10.0.0.0/8"}}}}).Validate())
+}
+
+func TestBuiltinRules(t *testing.T) {
+	c := &Config{UserAgents: builtinUserAgents, IPRanges: builtinIPRanges}
+	assert.NoError(t, c.Validate())
+}
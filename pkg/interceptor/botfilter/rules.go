@@ -0,0 +1,179 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package botfilter
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// builtinUserAgents are the crawlers, tools and generic bot tokens found in most access logs,
+// the specific ones come first so that they are named instead of being matched by the generic tokens
+var builtinUserAgents = []UserAgentRule{
+	{Name: "googlebot", Contains: []string{"googlebot", "adsbot-google", "mediapartners-google", "google-inspectiontool"}},
+	{Name: "bingbot", Contains: []string{"bingbot", "bingpreview", "msnbot", "adidxbot"}},
+	{Name: "baiduspider", Contains: []string{"baiduspider"}},
+	{Name: "yandexbot", Contains: []string{"yandexbot", "yandeximages", "yandexmobilebot"}},
+	{Name: "sogou", Contains: []string{"sogou web spider", "sogou inst spider"}},
+	{Name: "360spider", Contains: []string{"360spider", "haosouspider"}},
+	{Name: "bytespider", Contains: []string{"bytespider"}},
+	{Name: "yahoo", Contains: []string{"yahoo! slurp"}},
+	{Name: "duckduckbot", Contains: []string{"duckduckbot"}},
+	{Name: "applebot", Contains: []string{"applebot"}},
+	{Name: "petalbot", Contains: []string{"petalbot"}},
+	{Name: "ahrefsbot", Contains: []string{"ahrefsbot", "ahrefssiteaudit"}},
+	{Name: "semrushbot", Contains: []string{"semrushbot"}},
+	{Name: "mj12bot", Contains: []string{"mj12bot"}},
+	{Name: "dotbot", Contains: []string{"dotbot"}},
+	{Name: "ccbot", Contains: []string{"ccbot"}},
+	{Name: "gptbot", Contains: []string{"gptbot", "chatgpt-user"}},
+	{Name: "facebook", Contains: []string{"facebookexternalhit", "facebookbot", "meta-externalagent"}},
+	{Name: "twitterbot", Contains: []string{"twitterbot"}},
+	{Name: "linkedinbot", Contains: []string{"linkedinbot"}},
+	{Name: "tool", Contains: []string{"curl/", "wget/", "python-requests", "python-urllib", "go-http-client", "okhttp", "apache-httpclient", "libwww-perl", "scrapy", "headlesschrome", "phantomjs"}},
+	{Name: "generic", Regex: `(?i)(bot|crawler|spider)\b`},
+}
+
+// builtinIPRanges are part of the ranges published by the search engines for their crawlers,
+// extend them with ipRanges as the published lists change over time
+var builtinIPRanges = []IPRangeRule{
+	{Name: "googlebot", CIDRs: []string{"66.249.64.0/19", "2001:4860:4801::/48"}},
+	{Name: "bingbot", CIDRs: []string{"40.77.167.0/24", "157.55.39.0/24", "207.46.13.0/24", "52.167.144.0/24"}},
+	{Name: "baiduspider", CIDRs: []string{"180.76.15.0/24", "220.181.108.0/24", "116.179.32.0/24"}},
+	{Name: "yandexbot", CIDRs: []string{"5.255.253.0/24", "77.88.5.0/24", "213.180.203.0/24"}},
+	{Name: "applebot", CIDRs: []string{"17.241.208.0/20", "17.22.237.0/24"}},
+}
+
+type userAgentMatcher struct {
+	name     string
+	contains []string
+	regex    *regexp.Regexp
+}
+
+type ipRangeMatcher struct {
+	name string
+	nets []*net.IPNet
+}
+
+// classifier matches the user agent and client ip of an access log against the rules, custom rules first
+type classifier struct {
+	allow          []string
+	emptyUserAgent bool
+	userAgents     []userAgentMatcher
+	ipRanges       []ipRangeMatcher
+}
+
+// newClassifier compiles the rules, the config should be validated before
+func newClassifier(config *Config) *classifier {
+	c := &classifier{
+		emptyUserAgent: config.EmptyUserAgent,
+	}
+	for _, a := range config.Allow {
+		c.allow = append(c.allow, strings.ToLower(a))
+	}
+
+	uaRules := append([]UserAgentRule{}, config.UserAgents...)
+	ipRules := append([]IPRangeRule{}, config.IPRanges...)
+	if config.builtin() {
+		uaRules = append(uaRules, builtinUserAgents...)
+		ipRules = append(ipRules, builtinIPRanges...)
+	}
+
+	for _, r := range uaRules {
+		m := userAgentMatcher{name: r.Name}
+		for _, s := range r.Contains {
+			m.contains = append(m.contains, strings.ToLower(s))
+		}
+		if r.Regex != "" {
+			m.regex = regexp.MustCompile(r.Regex)
+		}
+		c.userAgents = append(c.userAgents, m)
+	}
+	for _, r := range ipRules {
+		m := ipRangeMatcher{name: r.Name}
+		for _, cidr := range r.CIDRs {
+			if _, n, err := net.ParseCIDR(cidr); err == nil {
+				m.nets = append(m.nets, n)
+			}
+		}
+		c.ipRanges = append(c.ipRanges, m)
+	}
+	return c
+}
+
+// classify returns the name of the matched rule, the user agent is matched before the ip
+func (c *classifier) classify(userAgent string, ip string) (string, bool) {
+	lower := strings.ToLower(userAgent)
+	for _, a := range c.allow {
+		if strings.Contains(lower, a) {
+			return "", false
+		}
+	}
+
+	if lower == "" || lower == "-" {
+		if c.emptyUserAgent {
+			return "empty", true
+		}
+	} else {
+		for _, m := range c.userAgents {
+			if m.match(userAgent, lower) {
+				return m.name, true
+			}
+		}
+	}
+
+	addr := parseIP(ip)
+	if addr == nil {
+		return "", false
+	}
+	for _, m := range c.ipRanges {
+		for _, n := range m.nets {
+			if n.Contains(addr) {
+				return m.name, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (m *userAgentMatcher) match(userAgent string, lower string) bool {
+	for _, s := range m.contains {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return m.regex != nil && m.regex.MatchString(userAgent)
+}
+
+// parseIP accepts an ip, `ip:port`, `[ipv6]:port` or a X-Forwarded-For list
+func parseIP(s string) net.IP {
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return net.ParseIP(host)
+	}
+	return nil
+}
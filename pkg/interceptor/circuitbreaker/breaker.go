@@ -0,0 +1,125 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+type state string
+
+const (
+	stateClosed   = state("closed")
+	stateOpen     = state("open")
+	stateHalfOpen = state("halfOpen")
+)
+
+type breaker struct {
+	name   string
+	config *Config
+
+	lock      sync.Mutex
+	state     state
+	failures  int // consecutive failures when closed
+	successes int // consecutive successful probes when half open
+	openedAt  time.Time
+	probing   bool
+
+	opens  uint64
+	probes uint64
+}
+
+func newBreaker(name string, config *Config) *breaker {
+	return &breaker{
+		name:   name,
+		config: config,
+		state:  stateClosed,
+	}
+}
+
+// allow returns whether a batch could be sent now, and whether it is a probe of the half open breaker
+func (b *breaker) allow(now time.Time) (ok bool, probe bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state == stateOpen && now.Sub(b.openedAt) >= b.config.OpenDuration {
+		b.state = stateHalfOpen
+		b.successes = 0
+	}
+
+	switch b.state {
+	case stateClosed:
+		return true, false
+	case stateHalfOpen:
+		if !b.probing {
+			b.probing = true
+			b.probes++
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// record the result of a batch allowed before, the results of the batches sent before the breaker opened are ignored
+func (b *breaker) record(probe bool, failed bool, now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if probe {
+		b.probing = false
+		if failed {
+			b.open(now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.state = stateClosed
+			b.failures = 0
+			log.Info("circuit breaker %s closed after %d successful probes", b.name, b.successes)
+		}
+		return
+	}
+
+	if b.state != stateClosed {
+		return
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.config.FailureThreshold {
+		b.open(now)
+	}
+}
+
+func (b *breaker) open(now time.Time) {
+	log.Warn("circuit breaker %s opened from %s, it would half-open after %s", b.name, b.state, b.config.OpenDuration)
+	b.state = stateOpen
+	b.openedAt = now
+	b.failures = 0
+	b.opens++
+}
+
+func (b *breaker) snapshot() (state, uint64, uint64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state, b.opens, b.probes
+}
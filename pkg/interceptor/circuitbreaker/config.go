@@ -0,0 +1,49 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package circuitbreaker
+
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+)
+
+const (
+	// ShedWait blocks the batches while the breaker is open, so the events are held back in the queue
+	// and the sources are paused once the queue is full
+	ShedWait = "wait"
+	// ShedDrop drops the batches with an error while the breaker is open, they are written to the
+	// dead letter sink of pipeline if there is one
+	ShedDrop = "drop"
+)
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	// FailureThreshold is the consecutive failed or slow batches which open the breaker
+	FailureThreshold int `yaml:"failureThreshold,omitempty" default:"5" validate:"gte=1"`
+	// SlowThreshold counts a successful batch which takes longer than it as a failure, 0 disables it
+	SlowThreshold time.Duration `yaml:"slowThreshold,omitempty"`
+	// OpenDuration is how long the breaker keeps open before it half-opens and sends probe batches
+	OpenDuration time.Duration `yaml:"openDuration,omitempty" default:"30s" validate:"gt=0"`
+	// HalfOpenProbes is the consecutive successful probe batches which close the breaker,
+	// only one probe is sent at a time and a failed probe opens the breaker again
+	HalfOpenProbes int    `yaml:"halfOpenProbes,omitempty" default:"1" validate:"gte=1"`
+	Shed           string `yaml:"shed,omitempty" default:"wait" validate:"oneof=wait drop"`
+
+	ReportInterval time.Duration `yaml:"reportInterval,omitempty" default:"10s"`
+}
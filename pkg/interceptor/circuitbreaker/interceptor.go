@@ -0,0 +1,176 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package circuitbreaker
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/sink"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const (
+	Type = "circuitBreaker"
+
+	waitInterval = 100 * time.Millisecond
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+func init() {
+	pipeline.Register(api.INTERCEPTOR, Type, makeInterceptor)
+}
+
+func makeInterceptor(info pipeline.Info) api.Component {
+	return &Interceptor{
+		pipelineName: info.PipelineName,
+		config:       &Config{},
+		done:         make(chan struct{}),
+		now:          time.Now,
+	}
+}
+
+type Interceptor struct {
+	pipelineName string
+	name         string
+	config       *Config
+	breaker      *breaker
+	done         chan struct{}
+	stopOnce     sync.Once
+	now          func() time.Time
+
+	rejected uint64
+}
+
+func (i *Interceptor) Config() interface{} {
+	return i.config
+}
+
+func (i *Interceptor) Category() api.Category {
+	return api.INTERCEPTOR
+}
+
+func (i *Interceptor) Type() api.Type {
+	return Type
+}
+
+func (i *Interceptor) String() string {
+	return fmt.Sprintf("%s/%s", i.Category(), i.Type())
+}
+
+func (i *Interceptor) Init(context api.Context) error {
+	i.name = context.Name()
+	i.breaker = newBreaker(fmt.Sprintf("%s/%s", i.pipelineName, i.name), i.config)
+	return nil
+}
+
+func (i *Interceptor) Start() error {
+	i.report()
+	go i.run()
+	return nil
+}
+
+func (i *Interceptor) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.done)
+	})
+}
+
+func (i *Interceptor) Intercept(invoker sink.Invoker, invocation sink.Invocation) api.Result {
+	ok, probe := i.breaker.allow(i.now())
+	if !ok {
+		atomic.AddUint64(&i.rejected, 1)
+		if i.config.Shed == ShedDrop {
+			return result.DropWith(ErrOpen)
+		}
+		if ok, probe = i.wait(); !ok {
+			return result.Fail(ErrOpen)
+		}
+	}
+
+	start := i.now()
+	r := invoker.Invoke(invocation)
+	failed := r.Status() != api.SUCCESS
+	if !failed && i.config.SlowThreshold > 0 && i.now().Sub(start) > i.config.SlowThreshold {
+		failed = true
+	}
+	i.breaker.record(probe, failed, i.now())
+	return r
+}
+
+// wait blocks until the batch is allowed by the breaker, or the interceptor is stopped
+func (i *Interceptor) wait() (ok bool, probe bool) {
+	t := time.NewTicker(waitInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-i.done:
+			return false, false
+		case <-t.C:
+			if ok, probe := i.breaker.allow(i.now()); ok {
+				return true, probe
+			}
+		}
+	}
+}
+
+func (i *Interceptor) run() {
+	t := time.NewTicker(i.config.ReportInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-i.done:
+			return
+		case <-t.C:
+			i.report()
+		}
+	}
+}
+
+func (i *Interceptor) report() {
+	s, opens, probes := i.breaker.snapshot()
+	eventbus.PublishOrDrop(eventbus.CircuitBreakerTopic, eventbus.CircuitBreakerMetricData{
+		BaseInterceptorMetric: eventbus.BaseInterceptorMetric{
+			PipelineName:    i.pipelineName,
+			InterceptorName: i.name,
+		},
+		State:    string(s),
+		Opens:    opens,
+		Rejected: atomic.LoadUint64(&i.rejected),
+		Probes:   probes,
+	})
+}
+
+func (i *Interceptor) Order() int {
+	return i.config.Order
+}
+
+func (i *Interceptor) BelongTo() (componentTypes []string) {
+	return i.config.BelongTo
+}
+
+func (i *Interceptor) IgnoreRetry() bool {
+	return false
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/sink"
+)
+
+type fakeInvoker struct {
+	fail    bool
+	latency time.Duration
+	clock   *time.Time
+	calls   int
+}
+
+func (f *fakeInvoker) Invoke(invocation sink.Invocation) api.Result {
+	f.calls++
+	*f.clock = f.clock.Add(f.latency)
+	if f.fail {
+		return result.Fail(errors.New("unavailable"))
+	}
+	return result.Success()
+}
+
+func newInterceptor(config Config) (*Interceptor, *time.Time) {
+	log.InitDefaultLogger()
+	clock := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	config.OpenDuration = 30 * time.Second
+	config.HalfOpenProbes = 2
+	i := &Interceptor{
+		config: &config,
+		done:   make(chan struct{}),
+		now: func() time.Time {
+			return clock
+		},
+	}
+	i.breaker = newBreaker("test", i.config)
+	return i, &clock
+}
+
+func TestBreaker(t *testing.T) {
+	i, clock := newInterceptor(Config{FailureThreshold: 3, Shed: ShedDrop})
+	invoker := &fakeInvoker{fail: true, clock: clock}
+	intercept := func() api.Result {
+		return i.Intercept(invoker, sink.Invocation{})
+	}
+
+	// consecutive failures open the breaker, a success in between resets them
+	intercept()
+	intercept()
+	invoker.fail = false
+	intercept()
+	invoker.fail = true
+	for n := 0; n < 3; n++ {
+		assert.Equal(t, api.FAIL, intercept().Status())
+	}
+	assert.Equal(t, stateOpen, i.breaker.state)
+	assert.Equal(t, uint64(1), i.breaker.opens)
+
+	// batches are shed while open
+	r := intercept()
+	assert.Equal(t, api.DROP, r.Status())
+	assert.Equal(t, ErrOpen, r.Error())
+	assert.Equal(t, 6, invoker.calls)
+	assert.Equal(t, uint64(1), i.rejected)
+
+	// a failed probe opens the breaker again
+	*clock = clock.Add(30 * time.Second)
+	assert.Equal(t, api.FAIL, intercept().Status())
+	assert.Equal(t, stateOpen, i.breaker.state)
+	assert.Equal(t, uint64(2), i.breaker.opens)
+
+	// only one probe is sent at a time when half open
+	*clock = clock.Add(30 * time.Second)
+	ok, probe := i.breaker.allow(*clock)
+	assert.True(t, ok)
+	assert.True(t, probe)
+	assert.Equal(t, stateHalfOpen, i.breaker.state)
+	ok, _ = i.breaker.allow(*clock)
+	assert.False(t, ok)
+	i.breaker.record(true, false, *clock)
+
+	// the breaker is closed after enough successful probes
+	invoker.fail = false
+	assert.Equal(t, api.SUCCESS, intercept().Status())
+	assert.Equal(t, stateClosed, i.breaker.state)
+	assert.Equal(t, uint64(3), i.breaker.probes)
+}
+
+func TestSlowBatches(t *testing.T) {
+	i, clock := newInterceptor(Config{FailureThreshold: 2, SlowThreshold: time.Second, Shed: ShedDrop})
+	invoker := &fakeInvoker{latency: 2 * time.Second, clock: clock}
+
+	assert.Equal(t, api.SUCCESS, i.Intercept(invoker, sink.Invocation{}).Status())
+	assert.Equal(t, api.SUCCESS, i.Intercept(invoker, sink.Invocation{}).Status())
+	assert.Equal(t, stateOpen, i.breaker.state)
+	assert.Equal(t, api.DROP, i.Intercept(invoker, sink.Invocation{}).Status())
+}
+
+func TestShedWait(t *testing.T) {
+	i, clock := newInterceptor(Config{FailureThreshold: 1, Shed: ShedWait})
+	invoker := &fakeInvoker{fail: true, clock: clock}
+	i.Intercept(invoker, sink.Invocation{})
+	assert.Equal(t, stateOpen, i.breaker.state)
+
+	// the batch is blocked until the interceptor is stopped
+	done := make(chan api.Result)
+	go func() {
+		done <- i.Intercept(invoker, sink.Invocation{})
+	}()
+	select {
+	case <-done:
+		t.Fatal("batch should be blocked while the breaker is open")
+	case <-time.After(3 * waitInterval):
+	}
+	i.Stop()
+	r := <-done
+	assert.Equal(t, api.FAIL, r.Status())
+	assert.Equal(t, 1, invoker.calls)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csv
+
+import (
+	"container/list"
+	"sync"
+)
+
+// headerCache keeps the header row of the most recently used files, keyed by the file job uid,
+// so that a rotated file with the same name gets its own header
+type headerCache struct {
+	lock    sync.Mutex
+	max     int
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type headerEntry struct {
+	uid     string
+	columns []string
+}
+
+func newHeaderCache(max int) *headerCache {
+	return &headerCache{
+		max:     max,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *headerCache) get(uid string) ([]string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.entries[uid]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*headerEntry).columns, true
+}
+
+func (c *headerCache) put(uid string, columns []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.entries[uid]; ok {
+		elem.Value.(*headerEntry).columns = columns
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[uid] = c.lru.PushFront(&headerEntry{uid: uid, columns: columns})
+	for c.lru.Len() > c.max {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*headerEntry).uid)
+	}
+}
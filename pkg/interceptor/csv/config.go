@@ -0,0 +1,54 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csv
+
+import (
+	"unicode/utf8"
+
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+	"github.com/pkg/errors"
+)
+
+const (
+	OnErrorKeep = "keep"
+	OnErrorDrop = "drop"
+)
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	// Target is the field of the csv line, `body` refers to the event body
+	Target string `yaml:"target,omitempty" default:"body"`
+	// To is the field where the columns are put, the columns are put into the header root when it is empty
+	To        string `yaml:"to,omitempty"`
+	Separator string `yaml:"separator,omitempty" default:","`
+	// Columns names the fields statically, otherwise the header row of each file is used and cached per file
+	Columns          []string `yaml:"columns,omitempty"`
+	DropHeader       *bool    `yaml:"dropHeader,omitempty" default:"true"`
+	LazyQuotes       bool     `yaml:"lazyQuotes,omitempty"`
+	TrimLeadingSpace bool     `yaml:"trimLeadingSpace,omitempty"`
+	MaxCachedFiles   int      `yaml:"maxCachedFiles,omitempty" default:"1024" validate:"gte=1"`
+	// OnError keeps the event unchanged or drops it when the line could not be parsed
+	OnError string `yaml:"onError,omitempty" default:"keep" validate:"oneof=keep drop"`
+}
+
+func (c *Config) Validate() error {
+	if utf8.RuneCountInString(c.Separator) != 1 {
+		return errors.Errorf("separator %q should be a single character", c.Separator)
+	}
+	return nil
+}
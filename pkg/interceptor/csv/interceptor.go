@@ -0,0 +1,250 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csv
+
+import (
+	"bufio"
+	encodingcsv "encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/source/file"
+	"github.com/loggie-io/loggie/pkg/util/eventops"
+	"github.com/loggie-io/loggie/pkg/util/persistence"
+	"github.com/pkg/errors"
+)
+
+const (
+	Type = "csv"
+
+	maxHeaderBytes = 64 * 1024
+)
+
+func init() {
+	pipeline.Register(api.INTERCEPTOR, Type, makeInterceptor)
+}
+
+func makeInterceptor(info pipeline.Info) api.Component {
+	return &Interceptor{
+		config: &Config{},
+	}
+}
+
+type Interceptor struct {
+	name    string
+	config  *Config
+	comma   rune
+	headers *headerCache
+}
+
+func (i *Interceptor) Config() interface{} {
+	return i.config
+}
+
+func (i *Interceptor) Category() api.Category {
+	return api.INTERCEPTOR
+}
+
+func (i *Interceptor) Type() api.Type {
+	return Type
+}
+
+func (i *Interceptor) String() string {
+	return fmt.Sprintf("%s/%s", i.Category(), i.Type())
+}
+
+func (i *Interceptor) Init(context api.Context) error {
+	i.name = context.Name()
+	i.comma, _ = utf8.DecodeRuneInString(i.config.Separator)
+	i.headers = newHeaderCache(i.config.MaxCachedFiles)
+	return nil
+}
+
+func (i *Interceptor) Start() error {
+	return nil
+}
+
+func (i *Interceptor) Stop() {
+}
+
+func (i *Interceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
+	e := invocation.Event
+	line := i.line(e)
+
+	columns := i.config.Columns
+	if len(columns) == 0 {
+		state := fileState(e)
+		if state == nil {
+			log.Debug("%s event %s is not collected from a file, the header row is unknown", i.String(), e.String())
+			return invoker.Invoke(invocation)
+		}
+
+		// the first line of each file is the header row
+		if state.Offset == 0 {
+			header, err := i.parse(line)
+			if err != nil {
+				log.Warn("%s parse header row of file %s failed: %v", i.String(), state.Filename, err)
+				return i.onError(invoker, invocation)
+			}
+			i.headers.put(state.JobUid, header)
+			if *i.config.DropHeader {
+				return result.Drop()
+			}
+			return invoker.Invoke(invocation)
+		}
+
+		header, err := i.header(state)
+		if err != nil {
+			log.Warn("%s get header row of file %s failed: %v", i.String(), state.Filename, err)
+			return i.onError(invoker, invocation)
+		}
+		columns = header
+	}
+
+	values, err := i.parse(line)
+	if err != nil {
+		log.Debug("%s parse line of event %s failed: %v", i.String(), e.String(), err)
+		return i.onError(invoker, invocation)
+	}
+	if len(values) != len(columns) {
+		log.Debug("%s event %s has %d values unequal to %d columns", i.String(), e.String(), len(values), len(columns))
+		return i.onError(invoker, invocation)
+	}
+
+	i.set(e, columns, values)
+	return invoker.Invoke(invocation)
+}
+
+func (i *Interceptor) onError(invoker source.Invoker, invocation source.Invocation) api.Result {
+	if i.config.OnError == OnErrorDrop {
+		return result.Drop()
+	}
+	return invoker.Invoke(invocation)
+}
+
+func (i *Interceptor) line(e api.Event) string {
+	if i.config.Target == event.Body {
+		return string(e.Body())
+	}
+	return eventops.GetString(e, i.config.Target)
+}
+
+func (i *Interceptor) set(e api.Event, columns []string, values []string) {
+	if e.Header() == nil {
+		e.Fill(e.Meta(), make(map[string]interface{}), e.Body())
+	}
+
+	if i.config.To == "" {
+		for idx, c := range columns {
+			eventops.Set(e, c, values[idx])
+		}
+	} else {
+		obj := make(map[string]interface{}, len(columns))
+		for idx, c := range columns {
+			obj[c] = values[idx]
+		}
+		eventops.Set(e, i.config.To, obj)
+	}
+
+	if i.config.Target != event.Body && i.config.Target != i.config.To {
+		eventops.Del(e, i.config.Target)
+	}
+}
+
+func (i *Interceptor) parse(line string) ([]string, error) {
+	r := encodingcsv.NewReader(strings.NewReader(line))
+	r.Comma = i.comma
+	r.LazyQuotes = i.config.LazyQuotes
+	r.TrimLeadingSpace = i.config.TrimLeadingSpace
+	r.FieldsPerRecord = -1
+	return r.Read()
+}
+
+// header returns the cached header row of the file, or reads it from the file when it is not cached,
+// e.g. loggie restarted in the middle of the file or the cache is full
+func (i *Interceptor) header(state *persistence.State) ([]string, error) {
+	if header, ok := i.headers.get(state.JobUid); ok {
+		return header, nil
+	}
+
+	line, err := readHeaderLine(state)
+	if err != nil {
+		return nil, err
+	}
+	header, err := i.parse(line)
+	if err != nil {
+		return nil, err
+	}
+	i.headers.put(state.JobUid, header)
+	return header, nil
+}
+
+func readHeaderLine(state *persistence.State) (string, error) {
+	f, err := os.Open(state.Filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// the file may have been rotated and the name refers to another file now
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if file.JobUid(info) != state.JobUid {
+		return "", errors.Errorf("file %s has been rotated", state.Filename)
+	}
+
+	line, err := bufio.NewReader(io.LimitReader(f, maxHeaderBytes)).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func fileState(e api.Event) *persistence.State {
+	if e.Meta() == nil {
+		return nil
+	}
+	s, ok := e.Meta().Get(file.SystemStateKey)
+	if !ok {
+		return nil
+	}
+	state, _ := s.(*persistence.State)
+	return state
+}
+
+func (i *Interceptor) Order() int {
+	return i.config.Order
+}
+
+func (i *Interceptor) BelongTo() (componentTypes []string) {
+	return i.config.BelongTo
+}
+
+func (i *Interceptor) IgnoreRetry() bool {
+	return true
+}
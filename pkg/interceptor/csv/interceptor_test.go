@@ -0,0 +1,113 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/source/file"
+	"github.com/loggie-io/loggie/pkg/util/persistence"
+)
+
+type fakeInvoker struct{}
+
+func (f *fakeInvoker) Invoke(invocation source.Invocation) api.Result {
+	return result.Success()
+}
+
+func newInterceptor(config Config) *Interceptor {
+	dropHeader := true
+	config.Target = event.Body
+	config.Separator = ","
+	config.DropHeader = &dropHeader
+	config.MaxCachedFiles = 2
+	config.OnError = OnErrorKeep
+	i := &Interceptor{config: &config}
+	i.comma = ','
+	i.headers = newHeaderCache(config.MaxCachedFiles)
+	return i
+}
+
+func fileEvent(line string, filename string, uid string, offset int64) api.Event {
+	meta := event.NewDefaultMeta()
+	meta.Set(file.SystemStateKey, &persistence.State{Filename: filename, JobUid: uid, Offset: offset})
+	e := event.NewEvent(map[string]interface{}{}, []byte(line))
+	e.Fill(meta, e.Header(), e.Body())
+	return e
+}
+
+func intercept(i *Interceptor, e api.Event) api.Result {
+	return i.Intercept(&fakeInvoker{}, source.Invocation{Event: e})
+}
+
+func TestInterceptHeaderRow(t *testing.T) {
+	log.InitDefaultLogger()
+	i := newInterceptor(Config{})
+
+	assert.Equal(t, api.DROP, intercept(i, fileEvent("name,age", "/tmp/a.csv", "1-1", 0)).Status())
+
+	e := fileEvent(`tom,"18"`, "/tmp/a.csv", "1-1", 9)
+	assert.Equal(t, api.SUCCESS, intercept(i, e).Status())
+	assert.Equal(t, map[string]interface{}{"name": "tom", "age": "18"}, e.Header())
+
+	// the rotated file has a new uid and its own header
+	assert.Equal(t, api.DROP, intercept(i, fileEvent("id,level,msg", "/tmp/a.csv", "2-1", 0)).Status())
+	e = fileEvent("1,info,hello", "/tmp/a.csv", "2-1", 13)
+	intercept(i, e)
+	assert.Equal(t, map[string]interface{}{"id": "1", "level": "info", "msg": "hello"}, e.Header())
+
+	// mismatched columns are kept unchanged
+	e = fileEvent("1,info", "/tmp/a.csv", "2-1", 26)
+	intercept(i, e)
+	assert.Equal(t, map[string]interface{}{}, e.Header())
+}
+
+func TestInterceptReadHeaderFromFile(t *testing.T) {
+	log.InitDefaultLogger()
+	name := filepath.Join(t.TempDir(), "report.csv")
+	assert.NoError(t, os.WriteFile(name, []byte("host;cpu\r\nnode1;0.5\r\n"), 0644))
+	info, err := os.Stat(name)
+	assert.NoError(t, err)
+
+	i := newInterceptor(Config{To: "report"})
+	i.comma = ';'
+	e := fileEvent("node1;0.5", name, file.JobUid(info), 10)
+	assert.Equal(t, api.SUCCESS, intercept(i, e).Status())
+	assert.Equal(t, map[string]interface{}{"report": map[string]interface{}{"host": "node1", "cpu": "0.5"}}, e.Header())
+
+	// the file has been replaced, the header is unknown
+	e = fileEvent("node1;0.5", name, "0-0", 10)
+	intercept(i, e)
+	assert.Equal(t, map[string]interface{}{}, e.Header())
+}
+
+func TestInterceptStaticColumns(t *testing.T) {
+	log.InitDefaultLogger()
+	i := newInterceptor(Config{Columns: []string{"a", "b"}})
+	e := event.NewEvent(map[string]interface{}{}, []byte("1,2"))
+	assert.Equal(t, api.SUCCESS, intercept(i, e).Status())
+	assert.Equal(t, map[string]interface{}{"a": "1", "b": "2"}, e.Header())
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package enrichfile
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+	"github.com/pkg/errors"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	// Path is the lookup file, such as a service catalog or an ip to rack mapping
+	Path string `yaml:"path,omitempty" validate:"required"`
+	// Format is inferred from the file extension when it is empty.
+	// A csv file names its columns in the header row, a json file is either an object keyed by the lookup key
+	// or an array of objects
+	Format string `yaml:"format,omitempty"`
+	// Key is the field of the event which is looked up
+	Key string `yaml:"key,omitempty" validate:"required"`
+	// KeyColumn is the column of a csv file, or the field of the objects in a json array, the table is keyed by
+	KeyColumn string `yaml:"keyColumn,omitempty" default:"key"`
+	Separator string `yaml:"separator,omitempty" default:","`
+	// Fields are the columns added to the event, all the columns except the key column are added when it is empty
+	Fields []string `yaml:"fields,omitempty"`
+	// To is the field where the columns are put, the columns are put into the header root when it is empty
+	To string `yaml:"to,omitempty"`
+
+	// ReloadInterval is how often the file is checked, it is reloaded only when its size or modification time changed
+	ReloadInterval time.Duration `yaml:"reloadInterval,omitempty" default:"30s"`
+	// MmapThreshold is the size in bytes from which a csv file is mapped into memory and only its keys are indexed,
+	// the rows are parsed on lookup. 0 disables mmap
+	MmapThreshold  int64         `yaml:"mmapThreshold,omitempty" default:"67108864"`
+	ReportInterval time.Duration `yaml:"reportInterval,omitempty" default:"10s"`
+}
+
+func (c *Config) SetDefaults() {
+	if c.Format == "" {
+		switch strings.ToLower(filepath.Ext(c.Path)) {
+		case ".json":
+			c.Format = FormatJSON
+		default:
+			c.Format = FormatCSV
+		}
+	}
+}
+
+func (c *Config) Validate() error {
+	if c.Format != FormatCSV && c.Format != FormatJSON {
+		return errors.Errorf("format %s is not supported, should be csv or json", c.Format)
+	}
+	if c.Format == FormatCSV && utf8.RuneCountInString(c.Separator) != 1 {
+		return errors.Errorf("separator %q should be a single character", c.Separator)
+	}
+	if c.ReloadInterval <= 0 {
+		return errors.New("reloadInterval should be positive")
+	}
+	return nil
+}
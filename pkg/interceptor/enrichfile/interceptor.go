@@ -0,0 +1,230 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package enrichfile
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/eventops"
+)
+
+const Type = "enrichFile"
+
+func init() {
+	pipeline.Register(api.INTERCEPTOR, Type, makeInterceptor)
+}
+
+func makeInterceptor(info pipeline.Info) api.Component {
+	return &Interceptor{
+		pipelineName: info.PipelineName,
+		config:       &Config{},
+		done:         make(chan struct{}),
+	}
+}
+
+type Interceptor struct {
+	pipelineName string
+	name         string
+	config       *Config
+	done         chan struct{}
+	stopOnce     sync.Once
+
+	// lock guards the table, lookups hold the read lock so that a replaced table could be closed safely
+	lock     sync.RWMutex
+	table    table
+	version  fileVersion
+	loadedAt time.Time
+
+	hits       uint64
+	misses     uint64
+	reloads    uint64
+	loadErrors uint64
+}
+
+func (i *Interceptor) Config() interface{} {
+	return i.config
+}
+
+func (i *Interceptor) Category() api.Category {
+	return api.INTERCEPTOR
+}
+
+func (i *Interceptor) Type() api.Type {
+	return Type
+}
+
+func (i *Interceptor) String() string {
+	return fmt.Sprintf("%s/%s", i.Category(), i.Type())
+}
+
+func (i *Interceptor) Init(context api.Context) error {
+	i.name = context.Name()
+	return nil
+}
+
+func (i *Interceptor) Start() error {
+	// the lookup file may be provisioned after loggie started, all the events would miss until it is loaded
+	i.reload()
+	go i.run()
+	return nil
+}
+
+func (i *Interceptor) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.done)
+	})
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.table != nil {
+		i.table.close()
+		i.table = nil
+	}
+}
+
+func (i *Interceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
+	e := invocation.Event
+
+	key := eventops.GetString(e, i.config.Key)
+	fields, ok := i.lookup(key)
+	if !ok {
+		atomic.AddUint64(&i.misses, 1)
+		return invoker.Invoke(invocation)
+	}
+	atomic.AddUint64(&i.hits, 1)
+
+	if e.Header() == nil {
+		e.Fill(e.Meta(), make(map[string]interface{}), e.Body())
+	}
+	if i.config.To == "" {
+		for k, v := range fields {
+			eventops.Set(e, k, v)
+		}
+	} else {
+		eventops.Set(e, i.config.To, fields)
+	}
+	return invoker.Invoke(invocation)
+}
+
+func (i *Interceptor) lookup(key string) (map[string]interface{}, bool) {
+	if key == "" {
+		return nil, false
+	}
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	if i.table == nil {
+		return nil, false
+	}
+	return i.table.lookup(key)
+}
+
+func (i *Interceptor) run() {
+	reload := time.NewTicker(i.config.ReloadInterval)
+	defer reload.Stop()
+	report := time.NewTicker(i.config.ReportInterval)
+	defer report.Stop()
+
+	for {
+		select {
+		case <-i.done:
+			return
+		case <-reload.C:
+			i.reload()
+		case <-report.C:
+			i.report()
+		}
+	}
+}
+
+// reload loads the file again when it changed, the previous table is kept when the file could not be loaded
+func (i *Interceptor) reload() {
+	i.lock.RLock()
+	loaded := i.table != nil
+	version := i.version
+	i.lock.RUnlock()
+
+	if loaded {
+		info, err := os.Stat(i.config.Path)
+		if err == nil && versionOf(info) == version {
+			return
+		}
+	}
+
+	t, v, err := load(i.config)
+	if err != nil {
+		atomic.AddUint64(&i.loadErrors, 1)
+		log.Warn("%s %s load lookup file %s failed: %v", i.String(), i.name, i.config.Path, err)
+		return
+	}
+
+	i.lock.Lock()
+	previous := i.table
+	i.table = t
+	i.version = v
+	i.loadedAt = time.Now()
+	i.lock.Unlock()
+
+	if previous != nil {
+		previous.close()
+	}
+	atomic.AddUint64(&i.reloads, 1)
+	log.Info("%s %s loaded %d entries from lookup file %s, mmap: %t", i.String(), i.name, t.len(), i.config.Path, t.mmapped())
+}
+
+func (i *Interceptor) report() {
+	i.lock.RLock()
+	data := eventbus.EnrichFileMetricData{
+		BaseInterceptorMetric: eventbus.BaseInterceptorMetric{
+			PipelineName:    i.pipelineName,
+			InterceptorName: i.name,
+		},
+		Path:     i.config.Path,
+		LoadedAt: i.loadedAt,
+	}
+	if i.table != nil {
+		data.Entries = i.table.len()
+		data.Mmapped = i.table.mmapped()
+	}
+	i.lock.RUnlock()
+
+	data.Hits = atomic.LoadUint64(&i.hits)
+	data.Misses = atomic.LoadUint64(&i.misses)
+	data.Reloads = atomic.LoadUint64(&i.reloads)
+	data.LoadErrors = atomic.LoadUint64(&i.loadErrors)
+	eventbus.PublishOrDrop(eventbus.EnrichFileTopic, data)
+}
+
+func (i *Interceptor) Order() int {
+	return i.config.Order
+}
+
+func (i *Interceptor) BelongTo() (componentTypes []string) {
+	return i.config.BelongTo
+}
+
+func (i *Interceptor) IgnoreRetry() bool {
+	return true
+}
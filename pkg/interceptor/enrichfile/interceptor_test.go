@@ -0,0 +1,144 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package enrichfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+)
+
+type fakeInvoker struct{}
+
+func (f *fakeInvoker) Invoke(invocation source.Invocation) api.Result {
+	return result.Success()
+}
+
+func newInterceptor(config Config) *Interceptor {
+	log.InitDefaultLogger()
+	if config.KeyColumn == "" {
+		config.KeyColumn = "key"
+	}
+	config.Separator = ","
+	config.ReloadInterval = time.Minute
+	config.SetDefaults()
+	return &Interceptor{config: &config, done: make(chan struct{})}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func intercept(i *Interceptor, header map[string]interface{}) map[string]interface{} {
+	e := event.NewEvent(header, []byte("body"))
+	i.Intercept(&fakeInvoker{}, source.Invocation{Event: e})
+	return e.Header()
+}
+
+const servicesCSV = "service,team,tier\ncheckout,payments,0\r\n\nsearch,discovery,1\nbroken,\"x\n"
+
+func TestInterceptCSV(t *testing.T) {
+	for _, threshold := range []int64{0, 1} {
+		path := filepath.Join(t.TempDir(), "services.csv")
+		writeFile(t, path, servicesCSV)
+
+		i := newInterceptor(Config{Path: path, Key: "svc", KeyColumn: "service", MmapThreshold: threshold})
+		i.reload()
+		assert.Equal(t, threshold > 0, i.table.mmapped())
+		assert.Equal(t, 2, i.table.len())
+
+		assert.Equal(t, map[string]interface{}{"svc": "checkout", "team": "payments", "tier": "0"},
+			intercept(i, map[string]interface{}{"svc": "checkout"}))
+		assert.Equal(t, map[string]interface{}{"svc": "unknown"},
+			intercept(i, map[string]interface{}{"svc": "unknown"}))
+		assert.Equal(t, map[string]interface{}{"other": "x"},
+			intercept(i, map[string]interface{}{"other": "x"}))
+		assert.Equal(t, uint64(1), i.hits)
+		assert.Equal(t, uint64(2), i.misses)
+		i.Stop()
+	}
+}
+
+func TestInterceptCSVFieldsTo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.csv")
+	writeFile(t, path, servicesCSV)
+
+	i := newInterceptor(Config{Path: path, Key: "svc", KeyColumn: "service", Fields: []string{"team"}, To: "catalog"})
+	i.reload()
+	assert.Equal(t, map[string]interface{}{
+		"svc":     "search",
+		"catalog": map[string]interface{}{"team": "discovery"},
+	}, intercept(i, map[string]interface{}{"svc": "search"}))
+}
+
+func TestInterceptJSON(t *testing.T) {
+	dir := t.TempDir()
+	object := filepath.Join(dir, "racks.json")
+	writeFile(t, object, `{"10.0.1.12": {"rack": "r12", "room": "a"}}`)
+	array := filepath.Join(dir, "hosts.json")
+	writeFile(t, array, `[{"ip": "10.0.1.12", "rack": "r12"}, {"ip": "10.0.1.13", "rack": "r13"}]`)
+
+	i := newInterceptor(Config{Path: object, Key: "ip"})
+	i.reload()
+	assert.Equal(t, map[string]interface{}{"ip": "10.0.1.12", "rack": "r12", "room": "a"},
+		intercept(i, map[string]interface{}{"ip": "10.0.1.12"}))
+
+	i = newInterceptor(Config{Path: array, Key: "ip", KeyColumn: "ip"})
+	i.reload()
+	assert.Equal(t, 2, i.table.len())
+	assert.Equal(t, map[string]interface{}{"ip": "10.0.1.13", "rack": "r13"},
+		intercept(i, map[string]interface{}{"ip": "10.0.1.13"}))
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.csv")
+
+	i := newInterceptor(Config{Path: path, Key: "svc", KeyColumn: "service"})
+	i.reload()
+	assert.Nil(t, i.table)
+	assert.Equal(t, uint64(1), i.loadErrors)
+
+	writeFile(t, path, servicesCSV)
+	i.reload()
+	assert.Equal(t, 2, i.table.len())
+	assert.Equal(t, uint64(1), i.reloads)
+
+	// unchanged file is not loaded again
+	i.reload()
+	assert.Equal(t, uint64(1), i.reloads)
+
+	// the previous table is kept when the new file is invalid
+	writeFile(t, path, "name,team\ncheckout,payments,extra,columns\n")
+	i.reload()
+	assert.Equal(t, uint64(2), i.loadErrors)
+	assert.Equal(t, 2, i.table.len())
+
+	writeFile(t, path, "service,team\nbilling,payments\n")
+	i.reload()
+	assert.Equal(t, uint64(2), i.reloads)
+	assert.Equal(t, map[string]interface{}{"svc": "billing", "team": "payments"},
+		intercept(i, map[string]interface{}{"svc": "billing"}))
+}
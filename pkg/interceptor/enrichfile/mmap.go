@@ -0,0 +1,46 @@
+//go:build !windows
+
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package enrichfile
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func mmapFile(path string, size int64) ([]byte, func(), error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the mapping stays valid after the file is closed
+	defer f.Close()
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	unmap := func() {
+		if err := syscall.Munmap(data); err != nil {
+			log.Warn("munmap %s error: %v", path, err)
+		}
+	}
+	return data, unmap, nil
+}
@@ -0,0 +1,346 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package enrichfile
+
+import (
+	"bytes"
+	encodingcsv "encoding/csv"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/pkg/errors"
+)
+
+// table is a loaded lookup file
+type table interface {
+	// lookup returns a copy of the fields of the key, which could be modified by the caller
+	lookup(key string) (map[string]interface{}, bool)
+	len() int
+	mmapped() bool
+	close()
+}
+
+// fileVersion identifies the content of the file which has been loaded
+type fileVersion struct {
+	size    int64
+	modTime time.Time
+}
+
+func versionOf(info os.FileInfo) fileVersion {
+	return fileVersion{
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+}
+
+func load(config *Config) (table, fileVersion, error) {
+	info, err := os.Stat(config.Path)
+	if err != nil {
+		return nil, fileVersion{}, err
+	}
+	version := versionOf(info)
+
+	if config.Format == FormatCSV && config.MmapThreshold > 0 && info.Size() >= config.MmapThreshold {
+		data, unmap, err := mmapFile(config.Path, info.Size())
+		if err != nil {
+			return nil, version, errors.WithMessage(err, "mmap")
+		}
+		t, err := newMmapTable(config, data, unmap)
+		if err != nil {
+			unmap()
+			return nil, version, err
+		}
+		return t, version, nil
+	}
+
+	data, err := os.ReadFile(config.Path)
+	if err != nil {
+		return nil, version, err
+	}
+	var t table
+	if config.Format == FormatJSON {
+		t, err = newJSONTable(config, data)
+	} else {
+		t, err = newCSVTable(config, data)
+	}
+	if err != nil {
+		return nil, version, err
+	}
+	return t, version, nil
+}
+
+// memTable keeps all the rows in memory
+type memTable struct {
+	rows map[string]map[string]interface{}
+}
+
+func (t *memTable) lookup(key string) (map[string]interface{}, bool) {
+	row, ok := t.rows[key]
+	if !ok {
+		return nil, false
+	}
+	out := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		out[k] = v
+	}
+	return out, true
+}
+
+func (t *memTable) len() int {
+	return len(t.rows)
+}
+
+func (t *memTable) mmapped() bool {
+	return false
+}
+
+func (t *memTable) close() {
+}
+
+func newJSONTable(config *Config, data []byte) (*memTable, error) {
+	var content interface{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal json")
+	}
+
+	t := &memTable{rows: make(map[string]map[string]interface{})}
+	switch c := content.(type) {
+	case map[string]interface{}:
+		for key, v := range c {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.Errorf("value of key %s is not an object", key)
+			}
+			t.rows[key] = pick(obj, config.Fields, "")
+		}
+
+	case []interface{}:
+		for idx, v := range c {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.Errorf("element %d is not an object", idx)
+			}
+			key, ok := obj[config.KeyColumn]
+			if !ok || key == nil {
+				return nil, errors.Errorf("element %d has no key field %s", idx, config.KeyColumn)
+			}
+			t.rows[fmt.Sprint(key)] = pick(obj, config.Fields, config.KeyColumn)
+		}
+
+	default:
+		return nil, errors.New("json lookup file should be an object or an array of objects")
+	}
+	return t, nil
+}
+
+// pick returns the fields of the object, or all of them except the key field when fields is empty
+func pick(obj map[string]interface{}, fields []string, keyField string) map[string]interface{} {
+	if len(fields) == 0 {
+		delete(obj, keyField)
+		return obj
+	}
+	out := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		if v, ok := obj[f]; ok {
+			out[f] = v
+		}
+	}
+	return out
+}
+
+// csvSchema resolves the columns of a csv file from its header row.
+// Each line is one row, quoted fields spanning lines are not supported.
+type csvSchema struct {
+	comma    rune
+	header   []string
+	keyIdx   int
+	fieldIdx []int
+}
+
+func newCSVSchema(config *Config, headerLine []byte) (*csvSchema, error) {
+	s := &csvSchema{}
+	s.comma, _ = utf8.DecodeRuneInString(config.Separator)
+
+	header, err := s.parse(headerLine)
+	if err != nil {
+		return nil, errors.WithMessage(err, "parse header row")
+	}
+	s.header = header
+
+	s.keyIdx = -1
+	columns := make(map[string]int, len(header))
+	for idx, c := range header {
+		columns[c] = idx
+		if c == config.KeyColumn {
+			s.keyIdx = idx
+		}
+	}
+	if s.keyIdx < 0 {
+		return nil, errors.Errorf("key column %s is not found in header row", config.KeyColumn)
+	}
+
+	if len(config.Fields) == 0 {
+		for idx := range header {
+			if idx != s.keyIdx {
+				s.fieldIdx = append(s.fieldIdx, idx)
+			}
+		}
+		return s, nil
+	}
+	for _, f := range config.Fields {
+		idx, ok := columns[f]
+		if !ok {
+			return nil, errors.Errorf("field %s is not found in header row", f)
+		}
+		s.fieldIdx = append(s.fieldIdx, idx)
+	}
+	return s, nil
+}
+
+func (s *csvSchema) parse(line []byte) ([]string, error) {
+	r := encodingcsv.NewReader(bytes.NewReader(line))
+	r.Comma = s.comma
+	r.FieldsPerRecord = len(s.header)
+	return r.Read()
+}
+
+func (s *csvSchema) row(values []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(s.fieldIdx))
+	for _, idx := range s.fieldIdx {
+		out[s.header[idx]] = values[idx]
+	}
+	return out
+}
+
+// eachLine calls fn with the offset and content of every non-empty line
+func eachLine(data []byte, fn func(offset int, line []byte) error) error {
+	offset := 0
+	for offset < len(data) {
+		end := len(data)
+		if idx := bytes.IndexByte(data[offset:], '\n'); idx >= 0 {
+			end = offset + idx
+		}
+		if line := bytes.TrimRight(data[offset:end], "\r"); len(line) > 0 {
+			if err := fn(offset, line); err != nil {
+				return err
+			}
+		}
+		offset = end + 1
+	}
+	return nil
+}
+
+// scanCSV parses the header row and calls fn with every valid row after it
+func scanCSV(config *Config, data []byte, fn func(schema *csvSchema, offset int, values []string)) (*csvSchema, error) {
+	var schema *csvSchema
+	invalid := 0
+	err := eachLine(data, func(offset int, line []byte) error {
+		if schema == nil {
+			s, err := newCSVSchema(config, line)
+			if err != nil {
+				return err
+			}
+			schema = s
+			return nil
+		}
+
+		values, err := schema.parse(line)
+		if err != nil {
+			invalid++
+			return nil
+		}
+		fn(schema, offset, values)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return nil, errors.New("header row is not found")
+	}
+	if invalid > 0 {
+		log.Warn("%d invalid rows of lookup file %s are ignored", invalid, config.Path)
+	}
+	return schema, nil
+}
+
+func newCSVTable(config *Config, data []byte) (*memTable, error) {
+	t := &memTable{rows: make(map[string]map[string]interface{})}
+	_, err := scanCSV(config, data, func(schema *csvSchema, offset int, values []string) {
+		t.rows[values[schema.keyIdx]] = schema.row(values)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// mmapTable maps a large csv file into memory and only indexes the offsets of the rows by key
+type mmapTable struct {
+	data   []byte
+	unmap  func()
+	schema *csvSchema
+	index  map[string]int
+}
+
+func newMmapTable(config *Config, data []byte, unmap func()) (*mmapTable, error) {
+	t := &mmapTable{
+		data:  data,
+		unmap: unmap,
+		index: make(map[string]int),
+	}
+	schema, err := scanCSV(config, data, func(schema *csvSchema, offset int, values []string) {
+		t.index[values[schema.keyIdx]] = offset
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.schema = schema
+	return t, nil
+}
+
+func (t *mmapTable) lookup(key string) (map[string]interface{}, bool) {
+	offset, ok := t.index[key]
+	if !ok {
+		return nil, false
+	}
+	line := t.data[offset:]
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	values, err := t.schema.parse(bytes.TrimRight(line, "\r"))
+	if err != nil {
+		return nil, false
+	}
+	return t.schema.row(values), true
+}
+
+func (t *mmapTable) len() int {
+	return len(t.index)
+}
+
+func (t *mmapTable) mmapped() bool {
+	return true
+}
+
+func (t *mmapTable) close() {
+	t.unmap()
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keystoredecrypt
+
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+)
+
+const (
+	Order = 450
+
+	FailureKeep = "keep"
+	FailureDrop = "drop"
+)
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	// Targets are the fields to decrypt, body means the event body
+	Targets []string `yaml:"targets,omitempty" default:"[\"body\"]" validate:"required"`
+	// Keystore is a local yaml file of the keys, whose keys are the key ids and values are the base64 encoded AES keys,
+	// the file is reloaded when it is modified, so the keys could be rotated without restarting
+	Keystore       string        `yaml:"keystore,omitempty" validate:"required"`
+	ReloadInterval time.Duration `yaml:"reloadInterval,omitempty" default:"1m"`
+	// Prefix marks the encrypted values, such as enc:v1:<key id>:<base64 of nonce|ciphertext|tag>,
+	// the values without the prefix are regarded as plaintext and kept as they are
+	Prefix string `yaml:"prefix,omitempty" default:"enc:v1:"`
+	// OnFailure could be keep or drop, keep sends the encrypted value as it is when the decryption failed
+	OnFailure string `yaml:"onFailure,omitempty" default:"keep" validate:"oneof=keep drop"`
+}
+
+func (c *Config) SetDefaults() {
+	if c != nil {
+		c.ExtensionConfig.Order = Order
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keystoredecrypt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	eventer "github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/eventops"
+)
+
+const Type = "keystoreDecrypt"
+
+func init() {
+	pipeline.Register(api.INTERCEPTOR, Type, makeInterceptor)
+}
+
+func makeInterceptor(info pipeline.Info) api.Component {
+	return NewInterceptor()
+}
+
+func NewInterceptor() *Interceptor {
+	return &Interceptor{
+		config: &Config{},
+		done:   make(chan struct{}),
+	}
+}
+
+// Interceptor decrypts the values encrypted by the applications with AES-GCM before writing them to the disk
+type Interceptor struct {
+	name     string
+	config   *Config
+	keystore *keystore
+	done     chan struct{}
+}
+
+func (i *Interceptor) Config() interface{} {
+	return i.config
+}
+
+func (i *Interceptor) Category() api.Category {
+	return api.INTERCEPTOR
+}
+
+func (i *Interceptor) Type() api.Type {
+	return Type
+}
+
+func (i *Interceptor) String() string {
+	return fmt.Sprintf("%s/%s", i.Category(), i.Type())
+}
+
+func (i *Interceptor) Init(context api.Context) error {
+	i.name = context.Name()
+	i.keystore = newKeystore(i.config.Keystore)
+	if _, err := i.keystore.reload(); err != nil {
+		return errors.WithMessage(err, "load keystore")
+	}
+	return nil
+}
+
+func (i *Interceptor) Start() error {
+	if i.config.ReloadInterval > 0 {
+		go i.run()
+	}
+	return nil
+}
+
+func (i *Interceptor) Stop() {
+	close(i.done)
+}
+
+func (i *Interceptor) run() {
+	ticker := time.NewTicker(i.config.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.done:
+			return
+		case <-ticker.C:
+			reloaded, err := i.keystore.reload()
+			if err != nil {
+				log.Warn("[%s] reload keystore error: %v", i.name, err)
+				continue
+			}
+			if reloaded {
+				log.Info("[%s] keystore %s reloaded", i.name, i.config.Keystore)
+			}
+		}
+	}
+}
+
+func (i *Interceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
+	if err := i.decryptEvent(invocation.Event); err != nil {
+		if i.config.OnFailure == FailureDrop {
+			return result.DropWith(err)
+		}
+		log.Debug("[%s] %v", i.name, err)
+	}
+	return invoker.Invoke(invocation)
+}
+
+// decryptEvent decrypts every target, the target failed is kept as it is
+func (i *Interceptor) decryptEvent(e api.Event) error {
+	var failed []string
+	for _, target := range i.config.Targets {
+		val := eventops.GetBytes(e, target)
+		if len(val) == 0 || !strings.HasPrefix(string(val), i.config.Prefix) {
+			continue
+		}
+
+		plain, err := i.decrypt(string(val[len(i.config.Prefix):]))
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", target, err))
+			continue
+		}
+		if target == eventer.Body {
+			e.Fill(e.Meta(), e.Header(), plain)
+		} else {
+			eventops.Set(e, target, string(plain))
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("decrypt %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// decrypt opens the value of <key id>:<base64 of nonce|ciphertext|tag>
+func (i *Interceptor) decrypt(value string) ([]byte, error) {
+	id, encoded, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return nil, errors.New("key id not found")
+	}
+	gcm, ok := i.keystore.get(id)
+	if !ok {
+		return nil, errors.Errorf("key %s not found in keystore", id)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.WithMessage(err, "decode base64")
+	}
+	if len(data) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func (i *Interceptor) Order() int {
+	return i.config.Order
+}
+
+func (i *Interceptor) BelongTo() (componentTypes []string) {
+	return i.config.BelongTo
+}
+
+func (i *Interceptor) IgnoreRetry() bool {
+	return true
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keystoredecrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+)
+
+func encrypt(t *testing.T, key []byte, id string, plain string) string {
+	block, err := aes.NewCipher(key)
+	assert.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	assert.NoError(t, err)
+	nonce := make([]byte, gcm.NonceSize())
+	_, _ = rand.Read(nonce)
+	return "enc:v1:" + id + ":" + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plain), nil))
+}
+
+func writeKeystore(t *testing.T, path string, keys map[string][]byte) {
+	var content string
+	for id, key := range keys {
+		content += id + ": " + base64.StdEncoding.EncodeToString(key) + "\n"
+	}
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func newTestInterceptor(t *testing.T, keystore string) *Interceptor {
+	i := NewInterceptor()
+	raw := `
+keystore: ` + keystore + `
+targets: ["body", "fields.secret"]
+`
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), i.config).Defaults().Validate().Do())
+	assert.NoError(t, i.Init(context.NewContext("decrypt", Type, api.INTERCEPTOR, nil)))
+	return i
+}
+
+func TestDecryptEvent(t *testing.T) {
+	key1 := []byte("0123456789abcdef0123456789abcdef")
+	key2 := []byte("fedcba9876543210")
+	path := filepath.Join(t.TempDir(), "keystore.yml")
+	writeKeystore(t, path, map[string][]byte{"k1": key1, "k2": key2})
+	i := newTestInterceptor(t, path)
+
+	e := event.NewEvent(map[string]interface{}{
+		"fields": map[string]interface{}{
+			"secret": encrypt(t, key2, "k2", "card=4111"),
+			"plain":  "enc:v1:k1:not encrypted target",
+		},
+	}, []byte(encrypt(t, key1, "k1", "user login ok")))
+	assert.NoError(t, i.decryptEvent(e))
+	assert.Equal(t, "user login ok", string(e.Body()))
+	assert.Equal(t, "card=4111", e.Header()["fields"].(map[string]interface{})["secret"])
+	assert.Equal(t, "enc:v1:k1:not encrypted target", e.Header()["fields"].(map[string]interface{})["plain"])
+
+	// plaintext is kept
+	e = event.NewEvent(map[string]interface{}{}, []byte("plain log"))
+	assert.NoError(t, i.decryptEvent(e))
+	assert.Equal(t, "plain log", string(e.Body()))
+
+	// unknown key and tampered ciphertext
+	e = event.NewEvent(map[string]interface{}{}, []byte(encrypt(t, key1, "k3", "x")))
+	assert.Error(t, i.decryptEvent(e))
+	tampered := encrypt(t, key2, "k1", "x")
+	e = event.NewEvent(map[string]interface{}{}, []byte(tampered))
+	assert.Error(t, i.decryptEvent(e))
+	assert.Equal(t, tampered, string(e.Body()))
+}
+
+func TestKeystoreReload(t *testing.T) {
+	key1 := []byte("0123456789abcdef")
+	key2 := []byte("abcdef0123456789")
+	path := filepath.Join(t.TempDir(), "keystore.yml")
+	writeKeystore(t, path, map[string][]byte{"k1": key1})
+	i := newTestInterceptor(t, path)
+
+	_, ok := i.keystore.get("k2")
+	assert.False(t, ok)
+
+	writeKeystore(t, path, map[string][]byte{"k1": key1, "k2": key2})
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	reloaded, err := i.keystore.reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	_, ok = i.keystore.get("k2")
+	assert.True(t, ok)
+
+	// the invalid keystore is not applied
+	assert.NoError(t, os.WriteFile(path, []byte("k1: short"), 0600))
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Minute)))
+	_, err = i.keystore.reload()
+	assert.Error(t, err)
+	_, ok = i.keystore.get("k2")
+	assert.True(t, ok)
+}
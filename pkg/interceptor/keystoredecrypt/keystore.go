@@ -0,0 +1,107 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keystoredecrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/yaml"
+)
+
+// keystore keeps the AES-GCM ciphers of the keys by id
+type keystore struct {
+	path string
+
+	lock    sync.RWMutex
+	ciphers map[string]cipher.AEAD
+	modTime time.Time
+}
+
+func newKeystore(path string) *keystore {
+	return &keystore{
+		path: path,
+	}
+}
+
+func (k *keystore) get(id string) (cipher.AEAD, bool) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+	c, ok := k.ciphers[id]
+	return c, ok
+}
+
+// reload loads the keystore if it was modified, the current keys are kept when the new keystore is invalid
+func (k *keystore) reload() (bool, error) {
+	info, err := os.Stat(k.path)
+	if err != nil {
+		return false, err
+	}
+	k.lock.RLock()
+	modTime := k.modTime
+	k.lock.RUnlock()
+	if info.ModTime().Equal(modTime) {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(k.path)
+	if err != nil {
+		return false, err
+	}
+	ciphers, err := parseKeystore(content)
+	if err != nil {
+		return false, errors.WithMessagef(err, "parse keystore %s", k.path)
+	}
+
+	k.lock.Lock()
+	k.ciphers = ciphers
+	k.modTime = info.ModTime()
+	k.lock.Unlock()
+	return true, nil
+}
+
+func parseKeystore(content []byte) (map[string]cipher.AEAD, error) {
+	keys := make(map[string]string)
+	if err := yaml.Unmarshal(content, &keys); err != nil {
+		return nil, err
+	}
+
+	ciphers := make(map[string]cipher.AEAD, len(keys))
+	for id, encoded := range keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "decode key %s", id)
+		}
+		// the key size selects AES-128, AES-192 or AES-256
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "key %s", id)
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "key %s", id)
+		}
+		ciphers[id] = gcm
+	}
+	return ciphers, nil
+}
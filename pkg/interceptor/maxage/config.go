@@ -0,0 +1,59 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maxage
+
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+	"github.com/pkg/errors"
+)
+
+const (
+	ActionDrop  = "drop"
+	ActionTag   = "tag"
+	ActionRoute = "route"
+)
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	MaxAge time.Duration `yaml:"maxAge,omitempty" validate:"required"`
+	// TimestampKey is the field of the parsed event timestamp, the collect time of the event is used when it is empty
+	TimestampKey      string `yaml:"timestampKey,omitempty"`
+	TimestampLayout   string `yaml:"timestampLayout,omitempty" default:"2006-01-02T15:04:05Z07:00"` // support unix unix_ms
+	TimestampLocation string `yaml:"timestampLocation,omitempty"`                                   // "" indicate UTC, also support `Local`
+
+	// Action could be drop, tag or route, applied to events older than MaxAge
+	Action string `yaml:"action,omitempty" default:"drop" validate:"oneof=drop tag route"`
+	// TagKey is the header key set to true for late events when action is tag
+	TagKey string `yaml:"tagKey,omitempty" default:"late"`
+	// RouteKey and Route are the header key and destination of late events when action is route,
+	// sinks could refer to it in templates such as `index: ${route.index}`
+	RouteKey string                 `yaml:"routeKey,omitempty" default:"route"`
+	Route    map[string]interface{} `yaml:"route,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	if c.Action == ActionRoute && len(c.Route) == 0 {
+		return errors.New("route is required when action is route")
+	}
+	if _, err := time.LoadLocation(c.TimestampLocation); err != nil {
+		return errors.WithMessagef(err, "load location %s", c.TimestampLocation)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maxage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/eventops"
+	timeutil "github.com/loggie-io/loggie/pkg/util/time"
+)
+
+const Type = "maxAge"
+
+func init() {
+	pipeline.Register(api.INTERCEPTOR, Type, makeInterceptor)
+}
+
+func makeInterceptor(info pipeline.Info) api.Component {
+	return &Interceptor{
+		config: &Config{},
+		now:    time.Now,
+	}
+}
+
+type Interceptor struct {
+	name     string
+	config   *Config
+	location *time.Location
+	now      func() time.Time
+}
+
+func (i *Interceptor) Config() interface{} {
+	return i.config
+}
+
+func (i *Interceptor) Category() api.Category {
+	return api.INTERCEPTOR
+}
+
+func (i *Interceptor) Type() api.Type {
+	return Type
+}
+
+func (i *Interceptor) String() string {
+	return fmt.Sprintf("%s/%s", i.Category(), i.Type())
+}
+
+func (i *Interceptor) Init(context api.Context) error {
+	i.name = context.Name()
+	location, err := time.LoadLocation(i.config.TimestampLocation)
+	if err != nil {
+		return err
+	}
+	i.location = location
+	return nil
+}
+
+func (i *Interceptor) Start() error {
+	return nil
+}
+
+func (i *Interceptor) Stop() {
+}
+
+func (i *Interceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
+	e := invocation.Event
+	timestamp, ok := i.timestamp(e)
+	if !ok || i.now().Sub(timestamp) <= i.config.MaxAge {
+		return invoker.Invoke(invocation)
+	}
+
+	switch i.config.Action {
+	case ActionDrop:
+		return result.Drop()
+
+	case ActionTag:
+		i.header(e)[i.config.TagKey] = true
+
+	case ActionRoute:
+		route := make(map[string]interface{}, len(i.config.Route))
+		for k, v := range i.config.Route {
+			route[k] = v
+		}
+		i.header(e)[i.config.RouteKey] = route
+	}
+	return invoker.Invoke(invocation)
+}
+
+// timestamp returns the parsed event time, or the collect time when timestampKey is not configured.
+// Events whose timestamp could not be parsed are never considered as late.
+func (i *Interceptor) timestamp(e api.Event) (time.Time, bool) {
+	if i.config.TimestampKey == "" {
+		if e.Meta() == nil {
+			return time.Time{}, false
+		}
+		v, ok := e.Meta().Get(event.SystemProductTimeKey)
+		if !ok {
+			return time.Time{}, false
+		}
+		t, ok := v.(time.Time)
+		return t, ok
+	}
+
+	val := eventops.GetString(e, i.config.TimestampKey)
+	if val == "" {
+		return time.Time{}, false
+	}
+	t, err := timeutil.Parse(val, i.config.TimestampLayout, i.location)
+	if err != nil {
+		log.Debug("%s parse timestamp %s of field %s failed: %v", i.String(), val, i.config.TimestampKey, err)
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (i *Interceptor) header(e api.Event) map[string]interface{} {
+	header := e.Header()
+	if header == nil {
+		header = make(map[string]interface{})
+		e.Fill(e.Meta(), header, e.Body())
+	}
+	return header
+}
+
+func (i *Interceptor) Order() int {
+	return i.config.Order
+}
+
+func (i *Interceptor) BelongTo() (componentTypes []string) {
+	return i.config.BelongTo
+}
+
+func (i *Interceptor) IgnoreRetry() bool {
+	return true
+}
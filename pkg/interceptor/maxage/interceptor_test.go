@@ -0,0 +1,106 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maxage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	timeutil "github.com/loggie-io/loggie/pkg/util/time"
+)
+
+type fakeInvoker struct{}
+
+func (f *fakeInvoker) Invoke(invocation source.Invocation) api.Result {
+	return result.Success()
+}
+
+func TestIntercept(t *testing.T) {
+	log.InitDefaultLogger()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		config      Config
+		header      map[string]interface{}
+		productTime time.Time
+		wantStatus  api.Status
+		wantHeader  map[string]interface{}
+	}{
+		{
+			name:       "fresh",
+			config:     Config{MaxAge: time.Hour, TimestampKey: "ts", TimestampLayout: time.RFC3339, Action: ActionDrop},
+			header:     map[string]interface{}{"ts": "2023-06-01T11:30:00Z"},
+			wantStatus: api.SUCCESS,
+			wantHeader: map[string]interface{}{"ts": "2023-06-01T11:30:00Z"},
+		},
+		{
+			name:       "drop",
+			config:     Config{MaxAge: time.Hour, TimestampKey: "ts", TimestampLayout: time.RFC3339, Action: ActionDrop},
+			header:     map[string]interface{}{"ts": "2023-05-01T11:30:00Z"},
+			wantStatus: api.DROP,
+			wantHeader: map[string]interface{}{"ts": "2023-05-01T11:30:00Z"},
+		},
+		{
+			name:       "tag unix_ms",
+			config:     Config{MaxAge: time.Hour, TimestampKey: "ts", TimestampLayout: timeutil.LayoutUnixMs, Action: ActionTag, TagKey: "late"},
+			header:     map[string]interface{}{"ts": "1685613600000"},
+			wantStatus: api.SUCCESS,
+			wantHeader: map[string]interface{}{"ts": "1685613600000", "late": true},
+		},
+		{
+			name:       "unparsable",
+			config:     Config{MaxAge: time.Hour, TimestampKey: "ts", TimestampLayout: time.RFC3339, Action: ActionDrop},
+			header:     map[string]interface{}{"ts": "yesterday"},
+			wantStatus: api.SUCCESS,
+			wantHeader: map[string]interface{}{"ts": "yesterday"},
+		},
+		{
+			name:        "route by collect time",
+			config:      Config{MaxAge: time.Hour, Action: ActionRoute, RouteKey: "route", Route: map[string]interface{}{"index": "archive"}},
+			header:      map[string]interface{}{},
+			productTime: now.Add(-2 * time.Hour),
+			wantStatus:  api.SUCCESS,
+			wantHeader:  map[string]interface{}{"route": map[string]interface{}{"index": "archive"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			i := &Interceptor{
+				config:   &config,
+				location: time.UTC,
+				now:      func() time.Time { return now },
+			}
+			e := event.NewEvent(tt.header, []byte("msg"))
+			e.Fill(event.NewDefaultMeta(), e.Header(), e.Body())
+			if !tt.productTime.IsZero() {
+				e.Meta().Set(event.SystemProductTimeKey, tt.productTime)
+			}
+
+			res := i.Intercept(&fakeInvoker{}, source.Invocation{Event: e})
+			assert.Equal(t, tt.wantStatus, res.Status())
+			assert.Equal(t, tt.wantHeader, e.Header())
+		})
+	}
+}
@@ -16,7 +16,11 @@ limitations under the License.
 
 package maxbytes
 
-import "github.com/loggie-io/loggie/pkg/core/interceptor"
+import (
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+	"github.com/loggie-io/loggie/pkg/util/priority"
+	"github.com/pkg/errors"
+)
 
 const Order = 500
 
@@ -27,6 +31,20 @@ type Config struct {
 	// The default is 128KB (131072)
 	MaxBytes int    `yaml:"maxBytes,omitempty" default:"131072" validate:"gte=0"`
 	Target   string `yaml:"target,omitempty" default:"body"`
+	// Priorities overrides maxBytes for the events classified by the priority interceptor, such as low: 4096
+	Priorities map[string]int `yaml:"priorities,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	for name, n := range c.Priorities {
+		if _, err := priority.Parse(name); err != nil {
+			return err
+		}
+		if n < 0 {
+			return errors.Errorf("maxBytes of priority %s should not be negative", name)
+		}
+	}
+	return nil
 }
 
 func (c *Config) SetDefaults() {
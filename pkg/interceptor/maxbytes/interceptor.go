@@ -23,6 +23,7 @@ import (
 	"github.com/loggie-io/loggie/pkg/core/source"
 	"github.com/loggie-io/loggie/pkg/pipeline"
 	"github.com/loggie-io/loggie/pkg/util/eventops"
+	"github.com/loggie-io/loggie/pkg/util/priority"
 	"unicode/utf8"
 )
 
@@ -43,7 +44,8 @@ func NewInterceptor() *Interceptor {
 }
 
 type Interceptor struct {
-	config *Config
+	config     *Config
+	priorities map[priority.Level]int
 }
 
 func (i *Interceptor) Config() interface{} {
@@ -63,6 +65,17 @@ func (i *Interceptor) String() string {
 }
 
 func (i *Interceptor) Init(context api.Context) error {
+	if len(i.config.Priorities) == 0 {
+		return nil
+	}
+	i.priorities = make(map[priority.Level]int, len(i.config.Priorities))
+	for name, n := range i.config.Priorities {
+		l, err := priority.Parse(name)
+		if err != nil {
+			return err
+		}
+		i.priorities[l] = n
+	}
 	return nil
 }
 
@@ -75,10 +88,19 @@ func (i *Interceptor) Stop() {
 
 func (i *Interceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
 	event := invocation.Event
-	maxBytes(event, i.config.Target, i.config.MaxBytes)
+	maxBytes(event, i.config.Target, i.limit(event))
 	return invoker.Invoke(invocation)
 }
 
+func (i *Interceptor) limit(event api.Event) int {
+	if i.priorities != nil {
+		if n, ok := i.priorities[priority.Of(event)]; ok {
+			return n
+		}
+	}
+	return i.config.MaxBytes
+}
+
 // if the length of the target field of the event exceeds maxBytes, then truncate it
 func maxBytes(event api.Event, target string, maxBytes int) {
 	val := eventops.GetBytes(event, target)
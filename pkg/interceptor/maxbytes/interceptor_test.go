@@ -3,6 +3,7 @@ package maxbytes
 import (
 	"github.com/loggie-io/loggie/pkg/core/api"
 	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/util/priority"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -53,3 +54,15 @@ func Test_maxBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestLimitByPriority(t *testing.T) {
+	i := NewInterceptor()
+	i.config.MaxBytes = 100
+	i.config.Priorities = map[string]int{"low": 10}
+	assert.NoError(t, i.Init(nil))
+
+	e := event.NewEvent(map[string]interface{}{}, []byte("log"))
+	assert.Equal(t, 100, i.limit(e))
+	priority.Set(e, priority.Low)
+	assert.Equal(t, 10, i.limit(e))
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"encoding/base64"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+)
+
+const (
+	ProcessorBase64Decode = "base64Decode"
+	ProcessorBase64Encode = "base64Encode"
+
+	Base64Std    = "std"
+	Base64Url    = "url"
+	Base64RawStd = "rawStd"
+	Base64RawUrl = "rawUrl"
+)
+
+type Base64Processor struct {
+	name        string
+	config      *Base64Config
+	interceptor *Interceptor
+	encoding    *base64.Encoding
+}
+
+type Base64Config struct {
+	CodingConfig `yaml:",inline"`
+	// Encoding could be std, url, rawStd or rawUrl, the raw encodings are without padding
+	Encoding string `yaml:"encoding,omitempty" default:"std" validate:"oneof=std url rawStd rawUrl"`
+}
+
+func init() {
+	register(ProcessorBase64Decode, func() Processor {
+		return NewBase64Processor(ProcessorBase64Decode)
+	})
+	register(ProcessorBase64Encode, func() Processor {
+		return NewBase64Processor(ProcessorBase64Encode)
+	})
+}
+
+func NewBase64Processor(name string) *Base64Processor {
+	return &Base64Processor{
+		name:   name,
+		config: &Base64Config{},
+	}
+}
+
+func (p *Base64Processor) Config() interface{} {
+	return p.config
+}
+
+func (p *Base64Processor) Init(interceptor *Interceptor) {
+	p.interceptor = interceptor
+	switch p.config.Encoding {
+	case Base64Url:
+		p.encoding = base64.URLEncoding
+	case Base64RawStd:
+		p.encoding = base64.RawStdEncoding
+	case Base64RawUrl:
+		p.encoding = base64.RawURLEncoding
+	default:
+		p.encoding = base64.StdEncoding
+	}
+}
+
+func (p *Base64Processor) GetName() string {
+	return p.name
+}
+
+func (p *Base64Processor) Process(e api.Event) error {
+	if p.config == nil {
+		return nil
+	}
+
+	if p.name == ProcessorBase64Encode {
+		processCoding(e, &p.config.CodingConfig, p.encode, p, p.interceptor)
+		return nil
+	}
+	processCoding(e, &p.config.CodingConfig, p.decode, p, p.interceptor)
+	return nil
+}
+
+func (p *Base64Processor) decode(val string) (string, error) {
+	out, err := p.encoding.DecodeString(val)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (p *Base64Processor) encode(val string) (string, error) {
+	return p.encoding.EncodeToString([]byte(val)), nil
+}
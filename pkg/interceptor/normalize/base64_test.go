@@ -0,0 +1,123 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/event"
+)
+
+func TestBase64Processor_Process(t *testing.T) {
+	tests := []struct {
+		name       string
+		processor  string
+		encoding   string
+		onError    string
+		targets    []string
+		header     map[string]interface{}
+		body       string
+		wantHeader map[string]interface{}
+		wantBody   string
+		wantFailed bool
+	}{
+		{
+			name:       "decode body",
+			processor:  ProcessorBase64Decode,
+			targets:    []string{event.Body},
+			body:       "aGVsbG8gd29ybGQ=",
+			wantHeader: map[string]interface{}{},
+			wantBody:   "hello world",
+		},
+		{
+			name:       "decode nested field",
+			processor:  ProcessorBase64Decode,
+			targets:    []string{"fields.token", "missing"},
+			header:     map[string]interface{}{"fields": map[string]interface{}{"token": "aGk="}},
+			wantHeader: map[string]interface{}{"fields": map[string]interface{}{"token": "hi"}},
+		},
+		{
+			name:       "decode raw url",
+			processor:  ProcessorBase64Decode,
+			encoding:   Base64RawUrl,
+			targets:    []string{"token"},
+			header:     map[string]interface{}{"token": "Pz8_"},
+			wantHeader: map[string]interface{}{"token": "???"},
+		},
+		{
+			name:       "encode url",
+			processor:  ProcessorBase64Encode,
+			encoding:   Base64Url,
+			targets:    []string{"token"},
+			header:     map[string]interface{}{"token": "??"},
+			wantHeader: map[string]interface{}{"token": "Pz8="},
+		},
+		{
+			name:       "invalid value kept",
+			processor:  ProcessorBase64Decode,
+			targets:    []string{"token", event.Body},
+			header:     map[string]interface{}{"token": "!!"},
+			body:       "!!",
+			wantHeader: map[string]interface{}{"token": "!!"},
+			wantBody:   "!!",
+			wantFailed: true,
+		},
+		{
+			name:       "invalid value dropped",
+			processor:  ProcessorBase64Decode,
+			onError:    OnErrorDrop,
+			targets:    []string{"token", event.Body},
+			header:     map[string]interface{}{"token": "!!"},
+			body:       "!!",
+			wantHeader: map[string]interface{}{},
+			wantFailed: true,
+		},
+		{
+			name:       "not a string",
+			processor:  ProcessorBase64Decode,
+			targets:    []string{"token"},
+			header:     map[string]interface{}{"token": 1},
+			wantHeader: map[string]interface{}{"token": 1},
+			wantFailed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewBase64Processor(tt.processor)
+			p.config.Targets = tt.targets
+			p.config.Encoding = tt.encoding
+			p.config.OnError = tt.onError
+			p.config.IgnoreError = true
+			interceptor := newTestInterceptor()
+			p.Init(interceptor)
+
+			header := tt.header
+			if header == nil {
+				header = make(map[string]interface{})
+			}
+			e := event.NewEvent(header, []byte(tt.body))
+			assert.NoError(t, p.Process(e))
+			assert.Equal(t, tt.wantHeader, e.Header())
+			assert.Equal(t, tt.wantBody, string(e.Body()))
+
+			_, failed := interceptor.MetricContext.MetricMap[tt.processor]
+			assert.Equal(t, tt.wantFailed, failed)
+		})
+	}
+}
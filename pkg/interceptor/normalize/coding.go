@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const (
+	OnErrorKeep = "keep"
+	OnErrorDrop = "drop"
+)
+
+// CodingConfig is shared by the processors which decode or encode field values
+type CodingConfig struct {
+	// Targets are the fields to be decoded or encoded, `body` refers to the event body
+	Targets []string `yaml:"targets,omitempty" validate:"required"`
+	// OnError keeps the original value or drops the field when the value could not be decoded
+	OnError     string `yaml:"onError,omitempty" default:"keep" validate:"oneof=keep drop"`
+	IgnoreError bool   `yaml:"ignoreError"`
+}
+
+type codingFunc func(val string) (string, error)
+
+// processCoding replaces the value of each target with the result of fn
+func processCoding(e api.Event, config *CodingConfig, fn codingFunc, p Processor, interceptor *Interceptor) {
+	header := e.Header()
+	if header == nil {
+		header = make(map[string]interface{})
+		e.Fill(e.Meta(), header, e.Body())
+	}
+	obj := runtime.NewObject(header)
+
+	for _, target := range config.Targets {
+		if target == event.Body {
+			out, err := fn(string(e.Body()))
+			if err != nil {
+				LogErrorWithIgnore(config.IgnoreError, "%s body of event %s failed: %v", p.GetName(), e.String(), err)
+				interceptor.reportMetric(p)
+				if config.OnError == OnErrorDrop {
+					e.Fill(e.Meta(), header, []byte{})
+				}
+				continue
+			}
+			e.Fill(e.Meta(), header, []byte(out))
+			continue
+		}
+
+		val := obj.GetPath(target)
+		if val.IsNull() {
+			continue
+		}
+		str, err := val.String()
+		if err != nil {
+			LogErrorWithIgnore(config.IgnoreError, "%s field %s is not a string", p.GetName(), target)
+			interceptor.reportMetric(p)
+			continue
+		}
+		out, err := fn(str)
+		if err != nil {
+			LogErrorWithIgnore(config.IgnoreError, "%s field %s failed: %v", p.GetName(), target, err)
+			interceptor.reportMetric(p)
+			if config.OnError == OnErrorDrop {
+				obj.DelPath(target)
+			}
+			continue
+		}
+		obj.SetPath(target, out)
+	}
+}
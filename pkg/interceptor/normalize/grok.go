@@ -0,0 +1,182 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/grok"
+	"github.com/loggie-io/loggie/pkg/util/regex"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+	"github.com/pkg/errors"
+)
+
+const ProcessorGrok = "grok"
+
+// GrokProcessor parses the logs by the grok expressions, so the expressions of logstash/filebeat could be reused
+type GrokProcessor struct {
+	config      *GrokConfig
+	interceptor *Interceptor
+	expressions []*grok.Expression
+}
+
+type GrokConfig struct {
+	Target string `yaml:"target,omitempty" default:"body"`
+	// Match are the grok expressions tried in order, the first matched one wins
+	Match []string `yaml:"match,omitempty" validate:"required,min=1"`
+	// Patterns are the custom patterns, which override the standard and the loaded ones with the same name
+	Patterns map[string]string `yaml:"patterns,omitempty"`
+	// PatternFiles are the pattern files or directories in the format of `NAME regex` lines
+	PatternFiles      []string `yaml:"patternFiles,omitempty"`
+	KeepEmptyCaptures bool     `yaml:"keepEmptyCaptures,omitempty"`
+	UnderRoot         bool     `yaml:"underRoot,omitempty" default:"true"`
+	// TagOnFailure is appended to the TagKey of the events not matched by any expression, empty means no tag
+	TagOnFailure string `yaml:"tagOnFailure,omitempty" default:"_grokparsefailure"`
+	TagKey       string `yaml:"tagKey,omitempty" default:"tags"`
+	IgnoreError  bool   `yaml:"ignoreError"`
+}
+
+func (c *GrokConfig) Validate() error {
+	_, err := c.compile()
+	return err
+}
+
+func (c *GrokConfig) compile() ([]*grok.Expression, error) {
+	g := grok.New()
+	for _, p := range c.PatternFiles {
+		if err := g.AddPatternsFromPath(p); err != nil {
+			return nil, errors.WithMessagef(err, "load grok patterns from %s", p)
+		}
+	}
+	for name, expr := range c.Patterns {
+		g.AddPattern(name, expr)
+	}
+
+	var expressions []*grok.Expression
+	for _, m := range c.Match {
+		exp, err := g.Compile(m)
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, exp)
+	}
+	return expressions, nil
+}
+
+func init() {
+	register(ProcessorGrok, func() Processor {
+		return NewGrokProcessor()
+	})
+}
+
+func NewGrokProcessor() *GrokProcessor {
+	return &GrokProcessor{
+		config: &GrokConfig{},
+	}
+}
+
+func (r *GrokProcessor) Config() interface{} {
+	return r.config
+}
+
+func (r *GrokProcessor) GetName() string {
+	return ProcessorGrok
+}
+
+func (r *GrokProcessor) Init(interceptor *Interceptor) {
+	r.interceptor = interceptor
+	expressions, err := r.config.compile()
+	if err != nil {
+		log.Panic("compile grok %v failed: %v", r.config.Match, err)
+	}
+	log.Info("grok match: %v", r.config.Match)
+	r.expressions = expressions
+}
+
+func (r *GrokProcessor) Process(e api.Event) error {
+	if r.config == nil {
+		return nil
+	}
+
+	header := e.Header()
+	if header == nil {
+		header = make(map[string]interface{})
+	}
+
+	var target string
+	if r.config.Target == event.Body {
+		target = string(e.Body())
+	} else {
+		val, err := runtime.NewObject(header).GetPath(r.config.Target).String()
+		if err != nil {
+			LogErrorWithIgnore(r.config.IgnoreError, "get target %s failed: %v", r.config.Target, err)
+			r.interceptor.reportMetric(r)
+			return nil
+		}
+		if val == "" {
+			log.Debug("target %s value is empty, event is: %s", r.config.Target, e.String())
+			return nil
+		}
+		target = val
+	}
+
+	// the expressions share the time budget of the event
+	budget := regex.NewBudget()
+	for _, exp := range r.expressions {
+		fields, err := exp.MatchWithin(budget, target, r.config.KeepEmptyCaptures)
+		if err != nil {
+			LogErrorWithIgnore(r.config.IgnoreError, "match grok %s failed: %v", exp.String(), err)
+			break
+		}
+		if fields == nil {
+			continue
+		}
+
+		if r.config.UnderRoot {
+			for k, v := range fields {
+				header[k] = v
+			}
+		} else {
+			header[SystemLogBody] = fields
+		}
+		return nil
+	}
+
+	LogErrorWithIgnore(r.config.IgnoreError, "log is not matched with grok %v", r.config.Match)
+	log.Debug("grok failed event: %s", e.String())
+	r.tagFailure(header)
+	r.interceptor.reportMetric(r)
+	return nil
+}
+
+func (r *GrokProcessor) tagFailure(header map[string]interface{}) {
+	if r.config.TagOnFailure == "" {
+		return
+	}
+
+	switch tags := header[r.config.TagKey].(type) {
+	case []string:
+		header[r.config.TagKey] = append(tags, r.config.TagOnFailure)
+	case []interface{}:
+		header[r.config.TagKey] = append(tags, r.config.TagOnFailure)
+	case string:
+		header[r.config.TagKey] = []string{tags, r.config.TagOnFailure}
+	default:
+		header[r.config.TagKey] = []string{r.config.TagOnFailure}
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"strings"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const ProcessorJsonExpand = "jsonExpand"
+
+// JsonExpandProcessor parses the fields whose value is a json encoded object or array in place,
+// such as the log field of docker json logs which contains the json logs of the application
+type JsonExpandProcessor struct {
+	config      *JsonExpandConfig
+	interceptor *Interceptor
+}
+
+type JsonExpandConfig struct {
+	// Targets are the fields to be expanded, all the fields of the header are detected if empty
+	Targets []string `yaml:"targets,omitempty"`
+	// MaxDepth limits the levels of json strings nested in the expanded values to be parsed
+	MaxDepth    int  `yaml:"maxDepth,omitempty" default:"1" validate:"gte=1"`
+	IgnoreError bool `yaml:"ignoreError"`
+}
+
+func init() {
+	register(ProcessorJsonExpand, func() Processor {
+		return NewJsonExpandProcessor()
+	})
+}
+
+func NewJsonExpandProcessor() *JsonExpandProcessor {
+	return &JsonExpandProcessor{
+		config: &JsonExpandConfig{},
+	}
+}
+
+func (p *JsonExpandProcessor) Config() interface{} {
+	return p.config
+}
+
+func (p *JsonExpandProcessor) Init(interceptor *Interceptor) {
+	p.interceptor = interceptor
+}
+
+func (p *JsonExpandProcessor) GetName() string {
+	return ProcessorJsonExpand
+}
+
+func (p *JsonExpandProcessor) Process(e api.Event) error {
+	if p.config == nil {
+		return nil
+	}
+
+	header := e.Header()
+	if header == nil {
+		return nil
+	}
+
+	if len(p.config.Targets) == 0 {
+		for k, v := range header {
+			header[k] = p.expand(v, 0)
+		}
+		return nil
+	}
+
+	obj := runtime.NewObject(header)
+	for _, target := range p.config.Targets {
+		val := obj.GetPath(target)
+		if val.IsNull() {
+			continue
+		}
+		str, err := val.String()
+		if err != nil {
+			// already expanded, the nested json strings are still detected
+			obj.SetPath(target, p.expand(val.Value(), 0))
+			continue
+		}
+		if !maybeJson(str) {
+			continue
+		}
+		var out interface{}
+		if err := json.Unmarshal([]byte(str), &out); err != nil {
+			LogErrorWithIgnore(p.config.IgnoreError, "%s field %s failed: %v", p.GetName(), target, err)
+			p.interceptor.reportMetric(p)
+			continue
+		}
+		obj.SetPath(target, p.expand(out, 1))
+	}
+	return nil
+}
+
+// expand parses the json strings in the value recursively, depth is the levels of json strings already parsed,
+// the values which are not valid json are kept as they are
+func (p *JsonExpandProcessor) expand(value interface{}, depth int) interface{} {
+	switch v := value.(type) {
+	case string:
+		if depth >= p.config.MaxDepth || !maybeJson(v) {
+			return v
+		}
+		var out interface{}
+		if err := json.Unmarshal([]byte(v), &out); err != nil {
+			return v
+		}
+		return p.expand(out, depth+1)
+
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = p.expand(val, depth)
+		}
+		return v
+
+	case []interface{}:
+		for i, val := range v {
+			v[i] = p.expand(val, depth)
+		}
+		return v
+	}
+	return value
+}
+
+// maybeJson only detects objects and arrays, scalars such as "123" or "true" are not expanded
+func maybeJson(s string) bool {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return false
+	}
+	return (s[0] == '{' && s[len(s)-1] == '}') || (s[0] == '[' && s[len(s)-1] == ']')
+}
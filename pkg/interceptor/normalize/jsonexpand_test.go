@@ -0,0 +1,128 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/event"
+)
+
+func TestJsonExpandProcessor_Process(t *testing.T) {
+	tests := []struct {
+		name        string
+		targets     []string
+		maxDepth    int
+		ignoreError bool
+		header      map[string]interface{}
+		wantHeader  map[string]interface{}
+		wantFailed  bool
+	}{
+		{
+			name:    "target",
+			targets: []string{"log"},
+			header:  map[string]interface{}{"log": `{"level":"info","tags":["a"]}`, "stream": "stdout"},
+			wantHeader: map[string]interface{}{
+				"log":    map[string]interface{}{"level": "info", "tags": []interface{}{"a"}},
+				"stream": "stdout",
+			},
+		},
+		{
+			name:    "nested target",
+			targets: []string{"fields.log", "missing"},
+			header:  map[string]interface{}{"fields": map[string]interface{}{"log": ` [1, 2] `}},
+			wantHeader: map[string]interface{}{
+				"fields": map[string]interface{}{"log": []interface{}{float64(1), float64(2)}},
+			},
+		},
+		{
+			name:       "nested json kept within max depth",
+			targets:    []string{"log"},
+			maxDepth:   1,
+			header:     map[string]interface{}{"log": `{"msg":"{\"a\":1}"}`},
+			wantHeader: map[string]interface{}{"log": map[string]interface{}{"msg": `{"a":1}`}},
+		},
+		{
+			name:     "nested json expanded",
+			targets:  []string{"log"},
+			maxDepth: 2,
+			header:   map[string]interface{}{"log": `{"msg":"{\"a\":1}"}`},
+			wantHeader: map[string]interface{}{
+				"log": map[string]interface{}{"msg": map[string]interface{}{"a": float64(1)}},
+			},
+		},
+		{
+			name:     "nested json of expanded target",
+			targets:  []string{"log"},
+			maxDepth: 1,
+			header:   map[string]interface{}{"log": map[string]interface{}{"msg": `{"a":1}`}},
+			wantHeader: map[string]interface{}{
+				"log": map[string]interface{}{"msg": map[string]interface{}{"a": float64(1)}},
+			},
+		},
+		{
+			name:     "all fields",
+			maxDepth: 1,
+			header:   map[string]interface{}{"a": `{"b":"c"}`, "d": "123", "e": `{broken}`},
+			wantHeader: map[string]interface{}{
+				"a": map[string]interface{}{"b": "c"},
+				"d": "123",
+				"e": `{broken}`,
+			},
+		},
+		{
+			name:       "scalar not expanded",
+			targets:    []string{"log"},
+			header:     map[string]interface{}{"log": "true"},
+			wantHeader: map[string]interface{}{"log": "true"},
+		},
+		{
+			name:       "invalid json",
+			targets:    []string{"log"},
+			header:     map[string]interface{}{"log": `{"level":}`},
+			wantHeader: map[string]interface{}{"log": `{"level":}`},
+			wantFailed: true,
+		},
+		{
+			name:        "invalid json ignored",
+			targets:     []string{"log"},
+			ignoreError: true,
+			header:      map[string]interface{}{"log": `{"level":}`},
+			wantHeader:  map[string]interface{}{"log": `{"level":}`},
+			wantFailed:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewJsonExpandProcessor()
+			p.config.Targets = tt.targets
+			p.config.MaxDepth = tt.maxDepth
+			p.config.IgnoreError = tt.ignoreError
+			interceptor := newTestInterceptor()
+			p.Init(interceptor)
+
+			e := event.NewEvent(tt.header, []byte{})
+			assert.NoError(t, p.Process(e))
+			assert.Equal(t, tt.wantHeader, e.Header())
+
+			_, failed := interceptor.MetricContext.MetricMap[ProcessorJsonExpand]
+			assert.Equal(t, tt.wantFailed, failed)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/regex"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const ProcessorLog4j = "log4j"
+
+// Log4jProcessor parses the logs by the layout pattern of the log4j/logback appender, so the pattern could be
+// pasted from the logging config of applications instead of writing a regex
+type Log4jProcessor struct {
+	config      *Log4jConfig
+	interceptor *Interceptor
+	regex       *regex.Regex
+}
+
+type Log4jConfig struct {
+	Target string `yaml:"target,omitempty" default:"body"`
+	// Pattern is the layout pattern, such as `%d{yyyy-MM-dd HH:mm:ss.SSS} %-5p [%t] %c - %m%n`
+	Pattern     string `yaml:"pattern,omitempty" validate:"required"`
+	UnderRoot   bool   `yaml:"underRoot,omitempty" default:"true"`
+	IgnoreError bool   `yaml:"ignoreError"`
+}
+
+func (c *Log4jConfig) Validate() error {
+	expr, err := Log4jToRegex(c.Pattern)
+	if err != nil {
+		return err
+	}
+	return regex.Validate(expr)
+}
+
+func init() {
+	register(ProcessorLog4j, func() Processor {
+		return NewLog4jProcessor()
+	})
+}
+
+func NewLog4jProcessor() *Log4jProcessor {
+	return &Log4jProcessor{
+		config: &Log4jConfig{},
+	}
+}
+
+func (r *Log4jProcessor) Config() interface{} {
+	return r.config
+}
+
+func (r *Log4jProcessor) GetName() string {
+	return ProcessorLog4j
+}
+
+func (r *Log4jProcessor) Init(interceptor *Interceptor) {
+	r.interceptor = interceptor
+	expr, err := Log4jToRegex(r.config.Pattern)
+	if err != nil {
+		log.Panic("translate log4j pattern %s failed: %v", r.config.Pattern, err)
+	}
+	log.Info("log4j pattern: %s, regex: %s", r.config.Pattern, expr)
+	r.regex = regex.MustCompile(expr)
+}
+
+func (r *Log4jProcessor) Process(e api.Event) error {
+	if r.config == nil {
+		return nil
+	}
+
+	header := e.Header()
+	if header == nil {
+		header = make(map[string]interface{})
+	}
+
+	var target string
+	if r.config.Target == event.Body {
+		target = string(e.Body())
+	} else {
+		val, err := runtime.NewObject(header).GetPath(r.config.Target).String()
+		if err != nil {
+			LogErrorWithIgnore(r.config.IgnoreError, "get target %s failed: %v", r.config.Target, err)
+			r.interceptor.reportMetric(r)
+			return nil
+		}
+		if val == "" {
+			log.Debug("target %s value is empty, event is: %s", r.config.Target, e.String())
+			return nil
+		}
+		target = val
+	}
+
+	fields, err := r.regex.MatchGroupWithin(regex.NewBudget(), target)
+	if err != nil {
+		LogErrorWithIgnore(r.config.IgnoreError, "match log4j pattern %s failed: %v", r.config.Pattern, err)
+		r.interceptor.reportMetric(r)
+		return nil
+	}
+	if len(fields) == 0 {
+		LogErrorWithIgnore(r.config.IgnoreError, "log is not matched with log4j pattern %s", r.config.Pattern)
+		log.Debug("log4j failed event: %s", e.String())
+		r.interceptor.reportMetric(r)
+		return nil
+	}
+
+	if r.config.UnderRoot {
+		for k, v := range fields {
+			header[k] = v
+		}
+	} else {
+		header[SystemLogBody] = fields
+	}
+	return nil
+}
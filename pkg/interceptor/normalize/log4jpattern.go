@@ -0,0 +1,530 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// log4jNode is a piece of a log4j/logback layout pattern, which is either a literal, a converter or a group of nodes
+type log4jNode struct {
+	literal string
+	// expr is the regex of a converter without a field, such as %n
+	expr      string
+	field     string
+	converter string
+	// padLeft and padRight are the spaces added by the minimum width of the format modifiers
+	padLeft  bool
+	padRight bool
+	children []log4jNode
+	// optional groups are rendered only when the values inside are not empty, such as %notEmpty{[%marker]}
+	optional bool
+}
+
+type log4jConverter struct {
+	field string
+	expr  string
+}
+
+const (
+	log4jMessage   = "message"
+	log4jException = "exception"
+)
+
+// log4jConverters are the conversion words of log4j2 and logback which could be parsed from the output
+var log4jConverters = map[string]log4jConverter{}
+
+func init() {
+	add := func(c log4jConverter, words ...string) {
+		for _, w := range words {
+			log4jConverters[w] = c
+		}
+	}
+	add(log4jConverter{"timestamp", ""}, "d", "date")
+	add(log4jConverter{"level", `[A-Za-z]+`}, "p", "le", "level")
+	add(log4jConverter{"thread", `.*?`}, "t", "tn", "thread", "threadName")
+	add(log4jConverter{"threadId", `\d+`}, "T", "tid", "threadId")
+	add(log4jConverter{"logger", `\S+`}, "c", "lo", "logger")
+	add(log4jConverter{"class", `\S+`}, "C", "class")
+	add(log4jConverter{"method", `\S+`}, "M", "method")
+	add(log4jConverter{"file", `\S+`}, "F", "file")
+	add(log4jConverter{"line", `\d+`}, "L", "line")
+	add(log4jConverter{"location", `\S+`}, "l", "location", "caller")
+	add(log4jConverter{log4jMessage, `.*`}, "m", "msg", "message")
+	add(log4jConverter{"mdc", `.*?`}, "X", "mdc", "MDC", "K", "map", "MAP")
+	add(log4jConverter{"ndc", `.*?`}, "x", "NDC", "ndc")
+	add(log4jConverter{"marker", `\S*`}, "marker", "markerSimpleName")
+	add(log4jConverter{"relative", `\d+`}, "r", "relative")
+	add(log4jConverter{"sequence", `\d+`}, "sn", "sequenceNumber")
+	add(log4jConverter{"nano", `\d+`}, "N", "nano")
+	add(log4jConverter{"pid", `\d+`}, "pid", "processId")
+	add(log4jConverter{"uuid", `\S+`}, "u", "uuid")
+	add(log4jConverter{"context", `\S+`}, "cn", "contextName")
+	add(log4jConverter{log4jException, `(?s:.*)`}, "ex", "exception", "throwable", "xEx", "xException",
+		"xThrowable", "rEx", "rException", "rThrowable", "wEx", "wex", "nopex", "nopexception")
+	add(log4jConverter{"", `(?:\r?\n)?`}, "n")
+}
+
+// log4jWrappers only style the output of the patterns they wrap, such as %highlight{%-5level} or %clr(%d){faint}
+var log4jWrappers = map[string]bool{}
+
+func init() {
+	for _, w := range []string{"highlight", "style", "clr", "black", "red", "green", "yellow", "blue", "magenta",
+		"cyan", "white", "gray", "boldRed", "boldGreen", "boldYellow", "boldBlue", "boldMagenta", "boldCyan",
+		"boldWhite", "notEmpty", "varsNotEmpty", "variablesNotEmpty", "encode", "enc", "maxLen", "maxLength"} {
+		log4jWrappers[w] = true
+	}
+}
+
+var log4jNamedDates = map[string]string{
+	"DEFAULT":       `\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}[,.]\d{3}`,
+	"ISO8601":       `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}[,.]\d{3}`,
+	"ISO8601_BASIC": `\d{8}T\d{6}[,.]\d{3}`,
+	"ABSOLUTE":      `\d{2}:\d{2}:\d{2}[,.]\d{3}`,
+	"DATE":          `\d{2} [A-Za-z]+ \d{4} \d{2}:\d{2}:\d{2}[,.]\d{3}`,
+	"COMPACT":       `\d{17}`,
+	"UNIX":          `\d+`,
+	"UNIX_MILLIS":   `\d+`,
+}
+
+// Log4jToRegex translates a log4j/logback layout pattern such as `%d %-5p [%t] %c - %m%n` to a regex with the named groups
+func Log4jToRegex(pattern string) (string, error) {
+	p := &log4jParser{pattern: pattern}
+	nodes, err := p.parse(0)
+	if err != nil {
+		return "", err
+	}
+	if p.pos < len(pattern) {
+		return "", errors.Errorf("unexpected %q at %d of pattern %s", pattern[p.pos], p.pos, pattern)
+	}
+
+	r := &log4jRenderer{
+		fields:       make(map[string]int),
+		hasException: hasLog4jException(nodes),
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	r.render(&b, nodes)
+	b.WriteString("$")
+	if len(r.fields) == 0 {
+		return "", errors.Errorf("no converter is found in pattern %s", pattern)
+	}
+	return b.String(), nil
+}
+
+type log4jParser struct {
+	pattern string
+	pos     int
+}
+
+// parse consumes the pattern until the end, or the close bracket of the group if end is not 0
+func (p *log4jParser) parse(end byte) ([]log4jNode, error) {
+	var nodes []log4jNode
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			nodes = append(nodes, log4jNode{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for p.pos < len(p.pattern) {
+		c := p.pattern[p.pos]
+		if end != 0 && c == end {
+			break
+		}
+		if c == '\\' && p.pos+1 < len(p.pattern) && end != 0 {
+			// escaped brackets inside the groups
+			literal.WriteByte(p.pattern[p.pos+1])
+			p.pos += 2
+			continue
+		}
+		if c == '$' && p.pos+1 < len(p.pattern) && p.pattern[p.pos+1] == '{' {
+			flush()
+			placeholder, err := p.parsePlaceholder()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, placeholder...)
+			continue
+		}
+		if c != '%' {
+			literal.WriteByte(c)
+			p.pos++
+			continue
+		}
+		if p.pos+1 < len(p.pattern) && p.pattern[p.pos+1] == '%' {
+			literal.WriteByte('%')
+			p.pos += 2
+			continue
+		}
+
+		flush()
+		node, err := p.parseConverter()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	flush()
+	return nodes, nil
+}
+
+func (p *log4jParser) parseConverter() (log4jNode, error) {
+	start := p.pos
+	p.pos++ // skip %
+
+	node := log4jNode{}
+	// format modifiers, such as %-5p, %20.30c
+	leftAlign := false
+	if p.peek() == '-' {
+		leftAlign = true
+		p.pos++
+	}
+	minWidth := p.readWhile(func(c byte) bool { return c >= '0' && c <= '9' })
+	if p.peek() == '.' {
+		p.pos++
+		if p.peek() == '-' {
+			p.pos++
+		}
+		p.readWhile(func(c byte) bool { return c >= '0' && c <= '9' })
+	}
+	if minWidth != "" {
+		if w, _ := strconv.Atoi(minWidth); w > 0 {
+			node.padLeft = !leftAlign
+			node.padRight = leftAlign
+		}
+	}
+
+	word := p.readWhile(func(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' })
+
+	// logback style groups, such as %-30(%d [%t]) or %clr(%d){faint}, the parentheses following
+	// the other converters are literals, such as %M(%F:%L)
+	if p.peek() == '(' && (word == "" || log4jWrappers[word]) {
+		children, err := p.parseGroup(')')
+		if err != nil {
+			return node, err
+		}
+		node.children = children
+		if _, err := p.readOptions(); err != nil {
+			return node, err
+		}
+		return node, nil
+	}
+	if word == "" {
+		return node, errors.Errorf("missing conversion word at %d", start)
+	}
+
+	if log4jWrappers[word] {
+		// log4j2 style wrappers, such as %highlight{%-5level}{FATAL=red}, the first option is the wrapped pattern
+		if p.peek() != '{' {
+			return node, errors.Errorf("missing the pattern of %s at %d", word, start)
+		}
+		children, err := p.parseGroup('}')
+		if err != nil {
+			return node, err
+		}
+		node.children = children
+		node.optional = strings.HasSuffix(strings.ToLower(word), "notempty")
+		if _, err := p.readOptions(); err != nil {
+			return node, err
+		}
+		return node, nil
+	}
+
+	conv, ok := log4jConverters[word]
+	if !ok {
+		return node, errors.Errorf("unsupported conversion word %s at %d", word, start)
+	}
+	options, err := p.readOptions()
+	if err != nil {
+		return node, err
+	}
+
+	node.converter = word
+	node.field = conv.field
+	node.expr = conv.expr
+	switch conv.field {
+	case "timestamp":
+		format := ""
+		if len(options) > 0 {
+			format = options[0]
+		}
+		if node.expr, err = log4jDateToRegex(format); err != nil {
+			return node, err
+		}
+	case "mdc":
+		// %X{traceId} is parsed into the field traceId
+		if len(options) > 0 && options[0] != "" {
+			node.field = options[0]
+		}
+	case "":
+		node.padLeft, node.padRight = false, false
+	}
+	return node, nil
+}
+
+func (p *log4jParser) parseGroup(end byte) ([]log4jNode, error) {
+	start := p.pos
+	p.pos++ // skip the open bracket
+	children, err := p.parse(end)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != end {
+		return nil, errors.Errorf("unclosed %q at %d", p.pattern[start], start)
+	}
+	p.pos++
+	return children, nil
+}
+
+// parsePlaceholder parses the unresolved property placeholders of spring boot. The default value with converters
+// is parsed as a pattern, such as ${LOG_LEVEL_PATTERN:-%5p}, otherwise the placeholder matches any non-space value,
+// such as ${PID:- } which is resolved to the process id
+func (p *log4jParser) parsePlaceholder() ([]log4jNode, error) {
+	start := p.pos
+	p.pos++ // skip $
+	options, err := p.readOptions()
+	if err != nil {
+		return nil, err
+	}
+	value := []log4jNode{{expr: `\S*`}}
+	i := strings.Index(options[0], ":-")
+	if i < 0 {
+		return value, nil
+	}
+	sub := &log4jParser{pattern: options[0][i+2:]}
+	nodes, err := sub.parse(0)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "parse the default value of placeholder at %d", start)
+	}
+	if !hasLog4jConverter(nodes) {
+		return value, nil
+	}
+	return nodes, nil
+}
+
+// readOptions reads the options in braces following the conversion word, such as {yyyy-MM-dd}{GMT+8}
+func (p *log4jParser) readOptions() ([]string, error) {
+	var options []string
+	for p.peek() == '{' {
+		start := p.pos
+		level := 0
+		for ; p.pos < len(p.pattern); p.pos++ {
+			if p.pattern[p.pos] == '{' {
+				level++
+			} else if p.pattern[p.pos] == '}' {
+				level--
+				if level == 0 {
+					break
+				}
+			}
+		}
+		if level != 0 {
+			return nil, errors.Errorf("unclosed '{' at %d", start)
+		}
+		options = append(options, p.pattern[start+1:p.pos])
+		p.pos++
+	}
+	return options, nil
+}
+
+func (p *log4jParser) peek() byte {
+	if p.pos < len(p.pattern) {
+		return p.pattern[p.pos]
+	}
+	return 0
+}
+
+func (p *log4jParser) readWhile(f func(c byte) bool) string {
+	start := p.pos
+	for p.pos < len(p.pattern) && f(p.pattern[p.pos]) {
+		p.pos++
+	}
+	return p.pattern[start:p.pos]
+}
+
+func hasLog4jConverter(nodes []log4jNode) bool {
+	for _, n := range nodes {
+		if n.field != "" || n.expr != "" || hasLog4jConverter(n.children) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLog4jException(nodes []log4jNode) bool {
+	for _, n := range nodes {
+		if n.field == log4jException || hasLog4jException(n.children) {
+			return true
+		}
+	}
+	return false
+}
+
+type log4jRenderer struct {
+	// fields counts the names of the groups, the duplicated names are suffixed by the count
+	fields       map[string]int
+	hasException bool
+}
+
+var log4jFieldReplacer = regexp.MustCompile(`\W`)
+
+func (r *log4jRenderer) render(b *strings.Builder, nodes []log4jNode) {
+	for _, n := range nodes {
+		if n.padLeft {
+			b.WriteString(`\s*`)
+		}
+		switch {
+		case n.children != nil:
+			if n.optional {
+				b.WriteString("(?:")
+			}
+			r.render(b, n.children)
+			if n.optional {
+				b.WriteString(")?")
+			}
+		case n.field != "":
+			expr := n.expr
+			// the message is the last part of the output when there is no exception converter,
+			// log4j2 appends the stack traces after it by default
+			if n.field == log4jMessage && !r.hasException {
+				expr = `(?s:.*)`
+			}
+			fmt.Fprintf(b, "(?P<%s>%s)", r.fieldName(n.field), expr)
+		case n.expr != "":
+			b.WriteString(n.expr)
+		default:
+			renderLog4jLiteral(b, n.literal)
+		}
+		if n.padRight {
+			b.WriteString(`\s*`)
+		}
+	}
+}
+
+func (r *log4jRenderer) fieldName(field string) string {
+	name := log4jFieldReplacer.ReplaceAllString(field, "_")
+	r.fields[name]++
+	if count := r.fields[name]; count > 1 {
+		return fmt.Sprintf("%s_%d", name, count)
+	}
+	return name
+}
+
+// renderLog4jLiteral matches the spaces loosely, since the padded values may be followed by any number of spaces
+func renderLog4jLiteral(b *strings.Builder, literal string) {
+	space := false
+	for _, c := range literal {
+		if unicode.IsSpace(c) {
+			if !space {
+				b.WriteString(`\s+`)
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteString(regexp.QuoteMeta(string(c)))
+	}
+}
+
+// log4jDateToRegex translates the date format of SimpleDateFormat or DateTimeFormatter, such as yyyy-MM-dd HH:mm:ss.SSS
+func log4jDateToRegex(format string) (string, error) {
+	if format == "" {
+		format = "DEFAULT"
+	}
+	if expr, ok := log4jNamedDates[format]; ok {
+		return expr, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		c := format[i]
+		if c == '\'' {
+			// quoted literal, '' is a single quote
+			end := strings.IndexByte(format[i+1:], '\'')
+			if end < 0 {
+				return "", errors.Errorf("unclosed quote in date format %s", format)
+			}
+			if end == 0 {
+				b.WriteString("'")
+			} else {
+				b.WriteString(regexp.QuoteMeta(format[i+1 : i+1+end]))
+			}
+			i += end + 2
+			continue
+		}
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			b.WriteString(regexp.QuoteMeta(string(c)))
+			i++
+			continue
+		}
+
+		n := 1
+		for i+n < len(format) && format[i+n] == c {
+			n++
+		}
+		i += n
+		switch c {
+		case 'y', 'u', 'Y':
+			if n == 2 {
+				b.WriteString(`\d{2}`)
+			} else {
+				b.WriteString(`\d{4}`)
+			}
+		case 'M', 'L':
+			if n >= 3 {
+				b.WriteString(`[A-Za-z]+`)
+			} else {
+				log4jDigits(&b, n)
+			}
+		case 'd', 'H', 'h', 'k', 'K', 'm', 's':
+			log4jDigits(&b, n)
+		case 'D':
+			b.WriteString(`\d{1,3}`)
+		case 'S', 'n':
+			fmt.Fprintf(&b, `\d{%d}`, n)
+		case 'E':
+			b.WriteString(`[A-Za-z]+`)
+		case 'a':
+			b.WriteString(`[AaPp][Mm]`)
+		case 'z', 'V':
+			b.WriteString(`\S+`)
+		case 'Z':
+			b.WriteString(`[+-]\d{4}`)
+		case 'X', 'x':
+			b.WriteString(`(?:Z|[+-]\d{2}(?::?\d{2})?)`)
+		default:
+			return "", errors.Errorf("unsupported letter %c in date format %s", c, format)
+		}
+	}
+	return b.String(), nil
+}
+
+func log4jDigits(b *strings.Builder, n int) {
+	if n == 1 {
+		b.WriteString(`\d{1,2}`)
+	} else {
+		fmt.Fprintf(b, `\d{%d}`, n)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog4jToRegex(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		line    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "log4j",
+			pattern: `%d %-5p [%t] %c - %m%n`,
+			line:    "2023-01-02 10:11:12,345 INFO  [main] com.example.App - started",
+			want: map[string]string{
+				"timestamp": "2023-01-02 10:11:12,345",
+				"level":     "INFO",
+				"thread":    "main",
+				"logger":    "com.example.App",
+				"message":   "started",
+			},
+		},
+		{
+			name:    "log4j2 highlight",
+			pattern: `%d{ISO8601} %highlight{%-5level}{FATAL=red} [%t] %logger{36}:%L - %msg%n`,
+			line:    "2023-01-02T10:11:12.345 WARN  [pool-1 thread-2] c.e.Service:42 - slow\n  at c.e.Service.run",
+			want: map[string]string{
+				"timestamp": "2023-01-02T10:11:12.345",
+				"level":     "WARN",
+				"thread":    "pool-1 thread-2",
+				"logger":    "c.e.Service",
+				"line":      "42",
+				"message":   "slow\n  at c.e.Service.run",
+			},
+		},
+		{
+			name:    "logback",
+			pattern: `%d{yyyy-MM-dd HH:mm:ss.SSS} [%thread] %-5level %logger{36} %X{traceId} - %msg%n`,
+			line:    "2023-01-02 10:11:12.345 [http-nio-8080-exec-1] DEBUG o.s.web.Servlet abc123 - GET /",
+			want: map[string]string{
+				"timestamp": "2023-01-02 10:11:12.345",
+				"thread":    "http-nio-8080-exec-1",
+				"level":     "DEBUG",
+				"logger":    "o.s.web.Servlet",
+				"traceId":   "abc123",
+				"message":   "GET /",
+			},
+		},
+		{
+			name:    "spring boot",
+			pattern: `%clr(%d{yyyy-MM-dd HH:mm:ss.SSS}){faint} %clr(${LOG_LEVEL_PATTERN:-%5p}) %clr(${PID:- }){magenta} --- [%15.15t] %-40.40logger{39} : %m%n%wEx`,
+			line:    "2023-01-02 10:11:12.345 ERROR 1234 --- [           main] o.s.boot.SpringApplication               : failed\njava.lang.IllegalStateException: boom",
+			want: map[string]string{
+				"timestamp": "2023-01-02 10:11:12.345",
+				"level":     "ERROR",
+				"thread":    "main",
+				"logger":    "o.s.boot.SpringApplication",
+				"message":   "failed",
+				"exception": "java.lang.IllegalStateException: boom",
+			},
+		},
+		{
+			name:    "optional marker",
+			pattern: `%d{HH:mm:ss} %notEmpty{[%marker] }%m`,
+			line:    "10:11:12 plain",
+			want: map[string]string{
+				"timestamp": "10:11:12",
+				"marker":    "",
+				"message":   "plain",
+			},
+		},
+		{
+			name:    "unsupported conversion word",
+			pattern: `%d %foo %m`,
+			wantErr: true,
+		},
+		{
+			name:    "unclosed option",
+			pattern: `%d{yyyy-MM-dd %m`,
+			wantErr: true,
+		},
+		{
+			name:    "no converter",
+			pattern: `plain text`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Log4jToRegex(tt.pattern)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			re := regexp.MustCompile(expr)
+			match := re.FindStringSubmatch(tt.line)
+			assert.NotNil(t, match, expr)
+			got := make(map[string]string)
+			for i, name := range re.SubexpNames() {
+				if name != "" && i < len(match) {
+					got[name] = match[i]
+				}
+			}
+			assert.Equal(t, tt.want, got, expr)
+		})
+	}
+}
@@ -17,12 +17,10 @@ limitations under the License.
 package normalize
 
 import (
-	"regexp"
-
 	"github.com/loggie-io/loggie/pkg/core/api"
 	"github.com/loggie-io/loggie/pkg/core/event"
 	"github.com/loggie-io/loggie/pkg/core/log"
-	"github.com/loggie-io/loggie/pkg/util"
+	"github.com/loggie-io/loggie/pkg/util/regex"
 	"github.com/loggie-io/loggie/pkg/util/runtime"
 )
 
@@ -31,7 +29,7 @@ const ProcessorRegex = "regex"
 type RegexProcessor struct {
 	config      *RegexConfig
 	interceptor *Interceptor
-	regex       *regexp.Regexp
+	regex       *regex.Regex
 }
 
 type RegexConfig struct {
@@ -41,6 +39,10 @@ type RegexConfig struct {
 	IgnoreError bool   `yaml:"ignoreError"`
 }
 
+func (c *RegexConfig) Validate() error {
+	return regex.Validate(c.Pattern)
+}
+
 func init() {
 	register(ProcessorRegex, func() Processor {
 		return NewRegexProcessor()
@@ -64,7 +66,7 @@ func (r *RegexProcessor) GetName() string {
 func (r *RegexProcessor) Init(interceptor *Interceptor) {
 	log.Info("regex pattern: %s", r.config.Pattern)
 	r.interceptor = interceptor
-	r.regex = util.MustCompilePatternWithJavaStyle(r.config.Pattern)
+	r.regex = regex.MustCompile(r.config.Pattern)
 }
 
 func (r *RegexProcessor) Process(e api.Event) error {
@@ -77,12 +79,16 @@ func (r *RegexProcessor) Process(e api.Event) error {
 		header = make(map[string]interface{})
 	}
 
-	var paramsMap map[string]string
+	var (
+		paramsMap map[string]string
+		err       error
+	)
 	if r.config.Target == event.Body {
-		paramsMap = util.MatchGroupWithRegex(r.regex, string(e.Body()))
+		paramsMap, err = r.regex.MatchGroupWithin(regex.NewBudget(), string(e.Body()))
 	} else {
+		var targetVal string
 		obj := runtime.NewObject(header)
-		targetVal, err := obj.GetPath(r.config.Target).String()
+		targetVal, err = obj.GetPath(r.config.Target).String()
 		if err != nil {
 			LogErrorWithIgnore(r.config.IgnoreError, "get target %s failed: %v", r.config.Target, err)
 			log.Debug("regex failed event: %s", e.String())
@@ -94,7 +100,12 @@ func (r *RegexProcessor) Process(e api.Event) error {
 			return nil
 		}
 
-		paramsMap = util.MatchGroupWithRegex(r.regex, targetVal)
+		paramsMap, err = r.regex.MatchGroupWithin(regex.NewBudget(), targetVal)
+	}
+	if err != nil {
+		LogErrorWithIgnore(r.config.IgnoreError, "match group with regex %s failed: %v", r.regex.String(), err)
+		r.interceptor.reportMetric(r)
+		return nil
 	}
 
 	pl := len(paramsMap)
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/util/regex"
+)
+
+func init() {
+	log.InitDefaultLogger()
+}
+
+func newTestInterceptor() *Interceptor {
+	return &Interceptor{
+		MetricContext: &eventbus.NormalizeMetricEvent{
+			MetricMap: make(map[string]*eventbus.NormalizeMetricData),
+		},
+	}
+}
+
+func TestRegexProcessor_Process(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		header     map[string]interface{}
+		body       string
+		timeout    time.Duration
+		wantHeader map[string]interface{}
+		wantFailed bool
+	}{
+		{
+			name:   "body",
+			target: event.Body,
+			body:   "10.0.0.1 GET",
+			wantHeader: map[string]interface{}{
+				"ip":     "10.0.0.1",
+				"method": "GET",
+			},
+		},
+		{
+			name:   "header target",
+			target: "log",
+			header: map[string]interface{}{"log": "10.0.0.1 GET"},
+			wantHeader: map[string]interface{}{
+				"log":    "10.0.0.1 GET",
+				"ip":     "10.0.0.1",
+				"method": "GET",
+			},
+		},
+		{
+			name:       "body timeout",
+			target:     event.Body,
+			body:       "10.0.0.1 GET",
+			timeout:    time.Nanosecond,
+			wantHeader: map[string]interface{}{},
+			wantFailed: true,
+		},
+		{
+			name:       "header target timeout",
+			target:     "log",
+			header:     map[string]interface{}{"log": "10.0.0.1 GET"},
+			timeout:    time.Nanosecond,
+			wantHeader: map[string]interface{}{"log": "10.0.0.1 GET"},
+			wantFailed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regex.SetConfig(regex.Config{CacheSize: 1024, Timeout: tt.timeout})
+			defer regex.SetConfig(regex.Config{CacheSize: 1024})
+
+			p := NewRegexProcessor()
+			p.config.Target = tt.target
+			p.config.Pattern = `(?<ip>\S+) (?<method>\S+)`
+			p.config.UnderRoot = true
+			p.config.IgnoreError = true
+			interceptor := newTestInterceptor()
+			p.Init(interceptor)
+
+			header := tt.header
+			if header == nil {
+				header = make(map[string]interface{})
+			}
+			e := event.NewEvent(header, []byte(tt.body))
+			assert.NoError(t, p.Process(e))
+			assert.Equal(t, tt.wantHeader, e.Header())
+
+			_, failed := interceptor.MetricContext.MetricMap[ProcessorRegex]
+			assert.Equal(t, tt.wantFailed, failed)
+		})
+	}
+}
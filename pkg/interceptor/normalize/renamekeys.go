@@ -0,0 +1,252 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/util/regex"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+	"github.com/pkg/errors"
+)
+
+const (
+	ProcessorRenameKeys = "renameKeys"
+
+	OnConflictKeep     = "keep"
+	OnConflictOverride = "override"
+)
+
+// RenameKeysProcessor transforms many keys in one pass, such as lowercasing the inconsistent field names of applications,
+// or replacing the dots which lead to field conflicts in elasticsearch
+type RenameKeysProcessor struct {
+	config      *RenameKeysConfig
+	interceptor *Interceptor
+	replacer    *strings.Replacer
+	rules       []renameRule
+}
+
+type RenameKeysConfig struct {
+	// Targets are the objects whose keys are transformed, the keys of the header are transformed if empty
+	Targets []string `yaml:"targets,omitempty"`
+	// Recursive transforms the keys of the nested objects as well
+	Recursive *bool `yaml:"recursive,omitempty" default:"true"`
+	Lowercase bool  `yaml:"lowercase,omitempty"`
+	// Replace replaces the substrings of keys, applied after lowercase
+	Replace []ReplaceRule `yaml:"replace,omitempty"`
+	// Rename renames the keys matching the pattern, applied after replace and only the first matched one is used
+	Rename []RenameRule `yaml:"rename,omitempty"`
+	// OnConflict decides what to do when the transformed key already exists,
+	// keep leaves the key untransformed and override overwrites the existing value
+	OnConflict  string `yaml:"onConflict,omitempty" default:"keep" validate:"oneof=keep override"`
+	IgnoreError bool   `yaml:"ignoreError"`
+}
+
+type ReplaceRule struct {
+	From string `yaml:"from,omitempty" validate:"required"`
+	// To could be empty, which removes the substrings
+	To string `yaml:"to,omitempty"`
+}
+
+type RenameRule struct {
+	Pattern string `yaml:"pattern,omitempty" validate:"required"`
+	// To could refer to the capture groups of the pattern, such as $1 or ${name}
+	To string `yaml:"to,omitempty" validate:"required"`
+}
+
+type renameRule struct {
+	regex *regex.Regex
+	to    string
+}
+
+func (c *RenameKeysConfig) Validate() error {
+	if !c.Lowercase && len(c.Replace) == 0 && len(c.Rename) == 0 {
+		return errors.New("one of lowercase, replace or rename is required")
+	}
+	for _, r := range c.Rename {
+		if err := regex.Validate(r.Pattern); err != nil {
+			return errors.WithMessagef(err, "rename pattern %s", r.Pattern)
+		}
+	}
+	return nil
+}
+
+func init() {
+	register(ProcessorRenameKeys, func() Processor {
+		return NewRenameKeysProcessor()
+	})
+}
+
+func NewRenameKeysProcessor() *RenameKeysProcessor {
+	return &RenameKeysProcessor{
+		config: &RenameKeysConfig{},
+	}
+}
+
+func (p *RenameKeysProcessor) Config() interface{} {
+	return p.config
+}
+
+func (p *RenameKeysProcessor) Init(interceptor *Interceptor) {
+	p.interceptor = interceptor
+
+	if len(p.config.Replace) > 0 {
+		var oldnew []string
+		for _, r := range p.config.Replace {
+			oldnew = append(oldnew, r.From, r.To)
+		}
+		p.replacer = strings.NewReplacer(oldnew...)
+	}
+
+	p.rules = nil
+	for _, r := range p.config.Rename {
+		p.rules = append(p.rules, renameRule{
+			regex: regex.MustCompile(r.Pattern),
+			to:    r.To,
+		})
+	}
+}
+
+func (p *RenameKeysProcessor) GetName() string {
+	return ProcessorRenameKeys
+}
+
+func (p *RenameKeysProcessor) Process(e api.Event) error {
+	if p.config == nil {
+		return nil
+	}
+
+	header := e.Header()
+	if header == nil {
+		return nil
+	}
+
+	// all the keys of the event share the budget
+	budget := regex.NewBudget()
+	if len(p.config.Targets) == 0 {
+		return p.transform(budget, header)
+	}
+
+	obj := runtime.NewObject(header)
+	for _, target := range p.config.Targets {
+		m, ok := obj.GetPath(target).Value().(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if err := p.transform(budget, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *RenameKeysProcessor) transform(budget *regex.Budget, m map[string]interface{}) error {
+	// the keys are renamed into a new map, so a renamed key would neither overwrite nor be visited as a key not transformed yet,
+	// and the keys are sorted to resolve the conflicts in the same way for every event
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make(map[string]interface{}, len(m))
+	renamed := make(map[string]string)
+	var renamedKeys []string
+	exhausted := false
+	for _, k := range keys {
+		if *p.config.Recursive && !exhausted {
+			if err := p.transformValue(budget, m[k]); err != nil {
+				return err
+			}
+		}
+
+		newKey := k
+		if !exhausted {
+			key, err := p.key(budget, k)
+			if err != nil {
+				LogErrorWithIgnore(p.config.IgnoreError, "%s key %s failed: %v", p.GetName(), k, err)
+				p.interceptor.reportMetric(p)
+				// the budget is exhausted, the rest keys would time out too and are kept
+				exhausted = errors.Is(err, regex.ErrTimeout)
+			} else if key != "" {
+				newKey = key
+			}
+		}
+		if newKey == k {
+			out[k] = m[k]
+			continue
+		}
+		renamed[k] = newKey
+		renamedKeys = append(renamedKeys, k)
+	}
+
+	// the keys not transformed take precedence over the renamed ones
+	for _, k := range renamedKeys {
+		newKey := renamed[k]
+		if _, exist := out[newKey]; exist && p.config.OnConflict == OnConflictKeep {
+			newKey = k
+		}
+		out[newKey] = m[k]
+	}
+
+	// the map is updated in place as it could be referred by its parent
+	for k := range m {
+		delete(m, k)
+	}
+	for k, v := range out {
+		m[k] = v
+	}
+	return nil
+}
+
+func (p *RenameKeysProcessor) transformValue(budget *regex.Budget, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return p.transform(budget, v)
+
+	case []interface{}:
+		for _, val := range v {
+			if err := p.transformValue(budget, val); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// key applies lowercase, replace and rename in order
+func (p *RenameKeysProcessor) key(budget *regex.Budget, k string) (string, error) {
+	if p.config.Lowercase {
+		k = strings.ToLower(k)
+	}
+	if p.replacer != nil {
+		k = p.replacer.Replace(k)
+	}
+	for _, r := range p.rules {
+		matched, err := r.regex.MatchStringWithin(budget, k)
+		if err != nil {
+			return "", err
+		}
+		if !matched {
+			continue
+		}
+		return r.regex.ReplaceAllStringWithin(budget, k, r.to)
+	}
+	return k, nil
+}
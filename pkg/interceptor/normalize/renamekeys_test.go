@@ -0,0 +1,143 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/event"
+)
+
+func TestRenameKeysProcessor_Process(t *testing.T) {
+	tests := []struct {
+		name         string
+		targets      []string
+		notRecursive bool
+		lowercase    bool
+		replace      []ReplaceRule
+		rename       []RenameRule
+		onConflict   string
+		header       map[string]interface{}
+		wantHeader   map[string]interface{}
+	}{
+		{
+			name:       "lowercase",
+			lowercase:  true,
+			header:     map[string]interface{}{"Level": "info", "MSG": "a"},
+			wantHeader: map[string]interface{}{"level": "info", "msg": "a"},
+		},
+		{
+			name:       "replace after lowercase",
+			lowercase:  true,
+			replace:    []ReplaceRule{{From: ".", To: "_"}, {From: "-"}},
+			header:     map[string]interface{}{"K8s.Pod-Name": "p"},
+			wantHeader: map[string]interface{}{"k8s_podname": "p"},
+		},
+		{
+			name: "rename by capture groups",
+			rename: []RenameRule{
+				{Pattern: `^x_(?<name>\w+)$`, To: "${name}"},
+				{Pattern: `^(\w+)_id$`, To: "$1"},
+				{Pattern: `^app$`, To: "unused"},
+			},
+			header:     map[string]interface{}{"x_app": "web", "trace_id": "t", "level": "info"},
+			wantHeader: map[string]interface{}{"app": "web", "trace": "t", "level": "info"},
+		},
+		{
+			name: "renamed keys are not visited again",
+			rename: []RenameRule{
+				{Pattern: `^x$`, To: "y"},
+				{Pattern: `^y$`, To: "z"},
+			},
+			onConflict: OnConflictOverride,
+			header:     map[string]interface{}{"x": 1, "y": 2},
+			wantHeader: map[string]interface{}{"y": 1, "z": 2},
+		},
+		{
+			name:       "conflict kept",
+			lowercase:  true,
+			header:     map[string]interface{}{"Level": "INFO", "level": "info"},
+			wantHeader: map[string]interface{}{"Level": "INFO", "level": "info"},
+		},
+		{
+			name:       "conflict overridden",
+			lowercase:  true,
+			onConflict: OnConflictOverride,
+			header:     map[string]interface{}{"Level": "INFO", "level": "info"},
+			wantHeader: map[string]interface{}{"level": "INFO"},
+		},
+		{
+			name:      "targets",
+			targets:   []string{"fields", "missing", "body"},
+			lowercase: true,
+			header: map[string]interface{}{
+				"Top":    "t",
+				"fields": map[string]interface{}{"App": "web"},
+				"body":   "Not an object",
+			},
+			wantHeader: map[string]interface{}{
+				"Top":    "t",
+				"fields": map[string]interface{}{"app": "web"},
+				"body":   "Not an object",
+			},
+		},
+		{
+			name:      "nested objects",
+			lowercase: true,
+			header: map[string]interface{}{
+				"A": map[string]interface{}{
+					"B": []interface{}{map[string]interface{}{"C": 1}, "D"},
+				},
+			},
+			wantHeader: map[string]interface{}{
+				"a": map[string]interface{}{
+					"b": []interface{}{map[string]interface{}{"c": 1}, "D"},
+				},
+			},
+		},
+		{
+			name:         "not recursive",
+			lowercase:    true,
+			notRecursive: true,
+			header:       map[string]interface{}{"A": map[string]interface{}{"B": 1}},
+			wantHeader:   map[string]interface{}{"a": map[string]interface{}{"B": 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewRenameKeysProcessor()
+			recursive := !tt.notRecursive
+			p.config.Targets = tt.targets
+			p.config.Recursive = &recursive
+			p.config.Lowercase = tt.lowercase
+			p.config.Replace = tt.replace
+			p.config.Rename = tt.rename
+			p.config.OnConflict = tt.onConflict
+			if p.config.OnConflict == "" {
+				p.config.OnConflict = OnConflictKeep
+			}
+			assert.NoError(t, p.config.Validate())
+			p.Init(newTestInterceptor())
+
+			e := event.NewEvent(tt.header, []byte("a"))
+			assert.NoError(t, p.Process(e))
+			assert.Equal(t, tt.wantHeader, e.Header())
+		})
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"net/url"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+)
+
+const ProcessorUrlDecode = "urlDecode"
+
+type UrlDecodeProcessor struct {
+	config      *UrlDecodeConfig
+	interceptor *Interceptor
+}
+
+type UrlDecodeConfig struct {
+	CodingConfig `yaml:",inline"`
+	// Query decodes '+' into space as in query strings, otherwise only percent encoded characters are decoded
+	Query *bool `yaml:"query,omitempty" default:"true"`
+}
+
+func init() {
+	register(ProcessorUrlDecode, func() Processor {
+		return NewUrlDecodeProcessor()
+	})
+}
+
+func NewUrlDecodeProcessor() *UrlDecodeProcessor {
+	return &UrlDecodeProcessor{
+		config: &UrlDecodeConfig{},
+	}
+}
+
+func (p *UrlDecodeProcessor) Config() interface{} {
+	return p.config
+}
+
+func (p *UrlDecodeProcessor) Init(interceptor *Interceptor) {
+	p.interceptor = interceptor
+}
+
+func (p *UrlDecodeProcessor) GetName() string {
+	return ProcessorUrlDecode
+}
+
+func (p *UrlDecodeProcessor) Process(e api.Event) error {
+	if p.config == nil {
+		return nil
+	}
+
+	decode := url.PathUnescape
+	if p.config.Query == nil || *p.config.Query {
+		decode = url.QueryUnescape
+	}
+	processCoding(e, &p.config.CodingConfig, decode, p, p.interceptor)
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/event"
+)
+
+func TestUrlDecodeProcessor_Process(t *testing.T) {
+	path := false
+	tests := []struct {
+		name       string
+		query      *bool
+		onError    string
+		header     map[string]interface{}
+		wantHeader map[string]interface{}
+		wantFailed bool
+	}{
+		{
+			name:       "query",
+			header:     map[string]interface{}{"url": "/search?q=a+b%26c"},
+			wantHeader: map[string]interface{}{"url": "/search?q=a b&c"},
+		},
+		{
+			name:       "path",
+			query:      &path,
+			header:     map[string]interface{}{"url": "/a+b/%E4%BD%A0%E5%A5%BD"},
+			wantHeader: map[string]interface{}{"url": "/a+b/你好"},
+		},
+		{
+			name:       "missing",
+			header:     map[string]interface{}{},
+			wantHeader: map[string]interface{}{},
+		},
+		{
+			name:       "invalid escape kept",
+			header:     map[string]interface{}{"url": "/%zz"},
+			wantHeader: map[string]interface{}{"url": "/%zz"},
+			wantFailed: true,
+		},
+		{
+			name:       "invalid escape dropped",
+			onError:    OnErrorDrop,
+			header:     map[string]interface{}{"url": "/%zz"},
+			wantHeader: map[string]interface{}{},
+			wantFailed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewUrlDecodeProcessor()
+			p.config.Targets = []string{"url"}
+			p.config.Query = tt.query
+			p.config.OnError = tt.onError
+			p.config.IgnoreError = true
+			interceptor := newTestInterceptor()
+			p.Init(interceptor)
+
+			e := event.NewEvent(tt.header, []byte{})
+			assert.NoError(t, p.Process(e))
+			assert.Equal(t, tt.wantHeader, e.Header())
+
+			_, failed := interceptor.MetricContext.MetricMap[ProcessorUrlDecode]
+			assert.Equal(t, tt.wantFailed, failed)
+		})
+	}
+}
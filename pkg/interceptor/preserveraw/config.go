@@ -0,0 +1,42 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preserveraw
+
+import "github.com/loggie-io/loggie/pkg/core/interceptor"
+
+// Order runs the interceptor before any other ones which may modify the body
+const Order = 100
+
+const (
+	TargetMeta   = "meta"
+	TargetHeader = "header"
+)
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	// Target could be meta or header, raw bytes kept in meta are only visible to the sink codec `raw` with `original: true`,
+	// while those in header are encoded by sinks as a normal field
+	Target    string `yaml:"target,omitempty" default:"meta" validate:"oneof=meta header"`
+	HeaderKey string `yaml:"headerKey,omitempty" default:"raw"`
+}
+
+func (c *Config) SetDefaults() {
+	if c != nil && c.ExtensionConfig.Order == interceptor.DefaultOrder {
+		c.ExtensionConfig.Order = Order
+	}
+}
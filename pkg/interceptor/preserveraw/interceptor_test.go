@@ -0,0 +1,77 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preserveraw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+)
+
+type fakeInvoker struct{}
+
+func (f *fakeInvoker) Invoke(invocation source.Invocation) api.Result {
+	return result.Success()
+}
+
+func TestIntercept(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		keptRaw    []byte
+		wantMeta   interface{}
+		wantHeader interface{}
+	}{
+		{
+			name:     "meta",
+			target:   TargetMeta,
+			wantMeta: []byte(`{"a":"b"}`),
+		},
+		{
+			name:     "kept by source codec",
+			target:   TargetMeta,
+			keptRaw:  []byte(`{"log":"{\"a\":\"b\"}"}`),
+			wantMeta: []byte(`{"log":"{\"a\":\"b\"}"}`),
+		},
+		{
+			name:       "header",
+			target:     TargetHeader,
+			wantHeader: `{"a":"b"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &Interceptor{config: &Config{Target: tt.target, HeaderKey: "raw"}}
+			e := event.NewEvent(map[string]interface{}{}, []byte(`{"a":"b"}`))
+			e.Fill(event.NewDefaultMeta(), e.Header(), e.Body())
+			if tt.keptRaw != nil {
+				e.Meta().Set(event.RawBodyKey, tt.keptRaw)
+			}
+
+			i.Intercept(&fakeInvoker{}, source.Invocation{Event: e})
+
+			raw, _ := e.Meta().Get(event.RawBodyKey)
+			assert.Equal(t, tt.wantMeta, raw)
+			assert.Equal(t, tt.wantHeader, e.Header()["raw"])
+		})
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package priority
+
+import (
+	"path"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+)
+
+// Order runs the classification ahead of maxbytes and quota, which degrade the events by their priorities.
+// Raise it when the rules match the fields decoded by the later interceptors.
+const Order = 400
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	// Rules are matched in order and the first matched one assigns the priority
+	Rules   []Rule `yaml:"rules,omitempty" validate:"dive"`
+	Default string `yaml:"default,omitempty" default:"normal" validate:"oneof=critical high normal low"`
+	// HeaderKey sets the priority name in the header as well when not empty, so the sinks could refer to it such as ${priority}
+	HeaderKey string `yaml:"headerKey,omitempty"`
+}
+
+type Rule struct {
+	Name string `yaml:"name,omitempty"`
+	// Match maps the field of event, e.g. fields.level or _k8s.namespace, to the accepted values,
+	// a rule matches when all the fields match any of their values, glob patterns such as `pay-*` are supported
+	Match    map[string]Values `yaml:"match,omitempty" validate:"required"`
+	Priority string            `yaml:"priority,omitempty" validate:"required,oneof=critical high normal low"`
+}
+
+// Values could be unmarshalled from a single string or a list of strings
+type Values []string
+
+func (v *Values) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*v = Values{single}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*v = list
+	return nil
+}
+
+func (c *Config) SetDefaults() {
+	if c != nil && c.ExtensionConfig.Order == interceptor.DefaultOrder {
+		c.ExtensionConfig.Order = Order
+	}
+}
+
+func (c *Config) Validate() error {
+	for i, r := range c.Rules {
+		for field, values := range r.Match {
+			if len(values) == 0 {
+				return errors.Errorf("priority rule %d: values of field %s are empty", i, field)
+			}
+			for _, v := range values {
+				if _, err := path.Match(v, ""); err != nil {
+					return errors.WithMessagef(err, "priority rule %d: invalid pattern %s of field %s", i, v, field)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package priority
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/eventops"
+	"github.com/loggie-io/loggie/pkg/util/priority"
+)
+
+const Type = "priority"
+
+func init() {
+	pipeline.Register(api.INTERCEPTOR, Type, makeInterceptor)
+}
+
+func makeInterceptor(info pipeline.Info) api.Component {
+	return &Interceptor{
+		config: &Config{},
+	}
+}
+
+type rule struct {
+	match    map[string]Values
+	priority priority.Level
+}
+
+type Interceptor struct {
+	config       *Config
+	rules        []rule
+	defaultLevel priority.Level
+}
+
+func (i *Interceptor) Config() interface{} {
+	return i.config
+}
+
+func (i *Interceptor) Category() api.Category {
+	return api.INTERCEPTOR
+}
+
+func (i *Interceptor) Type() api.Type {
+	return Type
+}
+
+func (i *Interceptor) String() string {
+	return fmt.Sprintf("%s/%s", i.Category(), i.Type())
+}
+
+func (i *Interceptor) Init(context api.Context) error {
+	for _, r := range i.config.Rules {
+		l, err := priority.Parse(r.Priority)
+		if err != nil {
+			return err
+		}
+		i.rules = append(i.rules, rule{
+			match:    r.Match,
+			priority: l,
+		})
+	}
+	l, err := priority.Parse(i.config.Default)
+	if err != nil {
+		return err
+	}
+	i.defaultLevel = l
+	return nil
+}
+
+func (i *Interceptor) Start() error {
+	return nil
+}
+
+func (i *Interceptor) Stop() {
+}
+
+func (i *Interceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
+	e := invocation.Event
+	l := i.classify(e)
+	priority.Set(e, l)
+
+	if i.config.HeaderKey != "" {
+		header := e.Header()
+		if header == nil {
+			header = make(map[string]interface{})
+			e.Fill(e.Meta(), header, e.Body())
+		}
+		header[i.config.HeaderKey] = l.String()
+	}
+	return invoker.Invoke(invocation)
+}
+
+func (i *Interceptor) classify(e api.Event) priority.Level {
+	for _, r := range i.rules {
+		if r.matches(e) {
+			return r.priority
+		}
+	}
+	return i.defaultLevel
+}
+
+func (r *rule) matches(e api.Event) bool {
+	for field, values := range r.match {
+		actual := fieldString(e, field)
+		matched := false
+		for _, v := range values {
+			if ok, _ := path.Match(v, actual); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldString formats the numbers as well, such as a status of 500
+func fieldString(e api.Event, field string) string {
+	switch v := eventops.Get(e, field).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (i *Interceptor) Order() int {
+	return i.config.Order
+}
+
+func (i *Interceptor) BelongTo() (componentTypes []string) {
+	return i.config.BelongTo
+}
+
+func (i *Interceptor) IgnoreRetry() bool {
+	return true
+}
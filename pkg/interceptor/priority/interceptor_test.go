@@ -0,0 +1,120 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package priority
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/priority"
+)
+
+type fakeInvoker struct{}
+
+func (f *fakeInvoker) Invoke(invocation source.Invocation) api.Result {
+	return result.Success()
+}
+
+func TestIntercept(t *testing.T) {
+	raw := `
+rules:
+  - name: audit
+    match:
+      fields.type: audit
+    priority: critical
+  - match:
+      fields.level: ["ERROR", "FATAL"]
+      _k8s.namespace: "pay-*"
+    priority: high
+  - match:
+      status: "5??"
+    priority: high
+  - match:
+      fields.level: DEBUG
+    priority: low
+headerKey: priority
+`
+	i := makeInterceptor(pipeline.Info{}).(*Interceptor)
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), i.config).Defaults().Validate().Do())
+	assert.NoError(t, i.Init(context.NewContext("priority", Type, api.INTERCEPTOR, nil)))
+	assert.Equal(t, Order, i.Order())
+
+	tests := []struct {
+		name   string
+		header map[string]interface{}
+		want   priority.Level
+	}{
+		{
+			name:   "first matched rule wins",
+			header: map[string]interface{}{"fields": map[string]interface{}{"type": "audit", "level": "DEBUG"}},
+			want:   priority.Critical,
+		},
+		{
+			name: "all fields matched",
+			header: map[string]interface{}{
+				"fields": map[string]interface{}{"level": "ERROR"},
+				"_k8s":   map[string]interface{}{"namespace": "pay-prod"},
+			},
+			want: priority.High,
+		},
+		{
+			name: "one field unmatched",
+			header: map[string]interface{}{
+				"fields": map[string]interface{}{"level": "ERROR"},
+				"_k8s":   map[string]interface{}{"namespace": "default"},
+			},
+			want: priority.Normal,
+		},
+		{
+			name:   "number field",
+			header: map[string]interface{}{"status": 503},
+			want:   priority.High,
+		},
+		{
+			name:   "low",
+			header: map[string]interface{}{"fields": map[string]interface{}{"level": "DEBUG"}},
+			want:   priority.Low,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := event.NewEvent(tt.header, []byte("log"))
+			i.Intercept(&fakeInvoker{}, source.Invocation{Event: e})
+			assert.Equal(t, tt.want, priority.Of(e))
+			assert.Equal(t, tt.want.String(), e.Header()["priority"])
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	c := &Config{}
+	assert.Error(t, cfg.UnPackFromRaw([]byte("rules:\n  - match:\n      a: b\n    priority: urgent"), c).Defaults().Validate().Do())
+
+	c = &Config{}
+	assert.Error(t, cfg.UnPackFromRaw([]byte("rules:\n  - priority: high"), c).Defaults().Validate().Do())
+
+	c = &Config{}
+	assert.Error(t, cfg.UnPackFromRaw([]byte("rules:\n  - match:\n      a: '[b'\n    priority: high"), c).Defaults().Validate().Do())
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/priority"
+	"github.com/pkg/errors"
+)
+
+const (
+	PeriodHourly = "hourly"
+	PeriodDaily  = "daily"
+
+	ActionDrop   = "drop"
+	ActionSample = "sample"
+	ActionTag    = "tag"
+)
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	// Key is rendered from the event, e.g. ${kubernetes.namespace}/${kubernetes.app}
+	Key string `yaml:"key,omitempty" validate:"required"`
+	// Period is the reset schedule of the quota, could be hourly or daily
+	Period string `yaml:"period,omitempty" default:"daily" validate:"oneof=hourly daily"`
+	// Location is the timezone used to align the reset time, e.g. Local, UTC, Asia/Shanghai
+	Location string `yaml:"location,omitempty" default:"Local"`
+
+	Default   Limit            `yaml:"default,omitempty"`
+	Overrides map[string]Limit `yaml:"overrides,omitempty"`
+
+	Action      string `yaml:"action,omitempty" default:"drop" validate:"oneof=drop sample tag"`
+	SampleEvery int    `yaml:"sampleEvery,omitempty" default:"100" validate:"gte=1"`
+	TagKey      string `yaml:"tagKey,omitempty" default:"quotaExceeded"`
+	// ExemptPriorities are the priorities assigned by the priority interceptor, the events of which are
+	// accounted in the quota but never dropped, sampled or tagged
+	ExemptPriorities []string `yaml:"exemptPriorities,omitempty"`
+
+	// MaxKeys limits the number of keys tracked, the rest are accounted to one overflow key
+	MaxKeys        int           `yaml:"maxKeys,omitempty" default:"10000" validate:"gte=1"`
+	ReportInterval time.Duration `yaml:"reportInterval,omitempty" default:"10s"`
+}
+
+// Limit of a key in one period, zero means unlimited
+type Limit struct {
+	MaxBytes  int64 `yaml:"maxBytes,omitempty" validate:"gte=0"`
+	MaxEvents int64 `yaml:"maxEvents,omitempty" validate:"gte=0"`
+}
+
+func (c *Config) Validate() error {
+	if err := pattern.Validate(c.Key); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(c.Location); err != nil {
+		return errors.WithMessagef(err, "load location %s", c.Location)
+	}
+	if _, err := priority.ParseAll(c.ExemptPriorities); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/priority"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const Type = "quota"
+
+func init() {
+	pipeline.Register(api.INTERCEPTOR, Type, makeInterceptor)
+}
+
+func makeInterceptor(info pipeline.Info) api.Component {
+	return &Interceptor{
+		pipelineName: info.PipelineName,
+		config:       &Config{},
+		done:         make(chan struct{}),
+	}
+}
+
+type Interceptor struct {
+	pipelineName string
+	name         string
+	config       *Config
+	done         chan struct{}
+
+	patternLock sync.Mutex
+	keyPattern  *pattern.Pattern
+	counter     *counter
+	exempt      map[priority.Level]struct{}
+}
+
+func (i *Interceptor) Config() interface{} {
+	return i.config
+}
+
+func (i *Interceptor) Category() api.Category {
+	return api.INTERCEPTOR
+}
+
+func (i *Interceptor) Type() api.Type {
+	return Type
+}
+
+func (i *Interceptor) String() string {
+	return fmt.Sprintf("%s/%s", i.Category(), i.Type())
+}
+
+func (i *Interceptor) Init(context api.Context) error {
+	i.name = context.Name()
+
+	p, err := pattern.Init(i.config.Key)
+	if err != nil {
+		return err
+	}
+	i.keyPattern = p
+
+	exempt, err := priority.ParseAll(i.config.ExemptPriorities)
+	if err != nil {
+		return err
+	}
+	i.exempt = exempt
+
+	location, err := time.LoadLocation(i.config.Location)
+	if err != nil {
+		return err
+	}
+	i.counter = newCounter(i.config, location)
+	return nil
+}
+
+func (i *Interceptor) Start() error {
+	go i.report()
+	return nil
+}
+
+func (i *Interceptor) Stop() {
+	close(i.done)
+}
+
+func (i *Interceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
+	e := invocation.Event
+	key := i.renderKey(e)
+
+	allowed, exceeded := i.counter.take(key, int64(len(e.Body())), time.Now())
+	if allowed {
+		return invoker.Invoke(invocation)
+	}
+	if _, ok := i.exempt[priority.Of(e)]; ok {
+		return invoker.Invoke(invocation)
+	}
+
+	switch i.config.Action {
+	case ActionTag:
+		e.Header()[i.config.TagKey] = true
+	case ActionSample:
+		// keep the first one of every sampleEvery exceeded events
+		if (exceeded-1)%int64(i.config.SampleEvery) != 0 {
+			return result.Drop()
+		}
+	default:
+		return result.Drop()
+	}
+	return invoker.Invoke(invocation)
+}
+
+func (i *Interceptor) renderKey(e api.Event) string {
+	i.patternLock.Lock()
+	defer i.patternLock.Unlock()
+
+	key, err := i.keyPattern.WithObject(runtime.NewObject(e.Header())).Render()
+	if err != nil {
+		log.Debug("render quota key error: %v", err)
+		return ""
+	}
+	return key
+}
+
+func (i *Interceptor) report() {
+	t := time.NewTicker(i.config.ReportInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-i.done:
+			return
+		case <-t.C:
+			windowStart, usages := i.counter.snapshot()
+			eventbus.PublishOrDrop(eventbus.QuotaTopic, eventbus.QuotaMetricData{
+				BaseInterceptorMetric: eventbus.BaseInterceptorMetric{
+					PipelineName:    i.pipelineName,
+					InterceptorName: i.name,
+				},
+				WindowStart: windowStart,
+				Usages:      usages,
+			})
+		}
+	}
+}
+
+func (i *Interceptor) Order() int {
+	return i.config.Order
+}
+
+func (i *Interceptor) BelongTo() (componentTypes []string) {
+	return i.config.BelongTo
+}
+
+func (i *Interceptor) IgnoreRetry() bool {
+	return true
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/eventbus"
+)
+
+const overflowKey = "__overflow__"
+
+type usage struct {
+	limit         Limit
+	bytes         int64
+	events        int64
+	exceeded      int64
+	exceededBytes int64
+}
+
+// counter accounts the bytes and events of every key in the current period
+type counter struct {
+	lock        sync.Mutex
+	period      string
+	location    *time.Location
+	defaults    Limit
+	overrides   map[string]Limit
+	maxKeys     int
+	windowStart time.Time
+	keys        map[string]*usage
+}
+
+func newCounter(config *Config, location *time.Location) *counter {
+	return &counter{
+		period:    config.Period,
+		location:  location,
+		defaults:  config.Default,
+		overrides: config.Overrides,
+		maxKeys:   config.MaxKeys,
+		keys:      make(map[string]*usage),
+	}
+}
+
+func (c *counter) windowOf(now time.Time) time.Time {
+	t := now.In(c.location)
+	if c.period == PeriodHourly {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, c.location)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.location)
+}
+
+// take accounts the event to the key, and returns false when the quota of the key is exceeded
+func (c *counter) take(key string, size int64, now time.Time) (allowed bool, exceeded int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if w := c.windowOf(now); !w.Equal(c.windowStart) {
+		c.windowStart = w
+		c.keys = make(map[string]*usage)
+	}
+
+	u, ok := c.keys[key]
+	if !ok {
+		if len(c.keys) >= c.maxKeys {
+			key = overflowKey
+			u, ok = c.keys[key]
+		}
+		if !ok {
+			limit, has := c.overrides[key]
+			if !has {
+				limit = c.defaults
+			}
+			u = &usage{limit: limit}
+			c.keys[key] = u
+		}
+	}
+
+	if (u.limit.MaxBytes > 0 && u.bytes+size > u.limit.MaxBytes) ||
+		(u.limit.MaxEvents > 0 && u.events+1 > u.limit.MaxEvents) {
+		u.exceeded++
+		u.exceededBytes += size
+		return false, u.exceeded
+	}
+
+	u.bytes += size
+	u.events++
+	return true, 0
+}
+
+func (c *counter) snapshot() (time.Time, []eventbus.QuotaUsage) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	usages := make([]eventbus.QuotaUsage, 0, len(c.keys))
+	for k, u := range c.keys {
+		usages = append(usages, eventbus.QuotaUsage{
+			Key:            k,
+			Bytes:          u.bytes,
+			Events:         u.events,
+			LimitBytes:     u.limit.MaxBytes,
+			LimitEvents:    u.limit.MaxEvents,
+			ExceededEvents: u.exceeded,
+			ExceededBytes:  u.exceededBytes,
+		})
+	}
+	return c.windowStart, usages
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterTake(t *testing.T) {
+	c := newCounter(&Config{
+		Period:  PeriodDaily,
+		Default: Limit{MaxBytes: 10},
+		Overrides: map[string]Limit{
+			"vip": {MaxEvents: 3},
+		},
+		MaxKeys: 2,
+	}, time.UTC)
+
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	ok, _ := c.take("a", 6, now)
+	assert.True(t, ok)
+	ok, exceeded := c.take("a", 6, now)
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), exceeded)
+
+	for n := 0; n < 3; n++ {
+		ok, _ = c.take("vip", 100, now)
+		assert.True(t, ok)
+	}
+	ok, _ = c.take("vip", 1, now)
+	assert.False(t, ok)
+
+	// keys beyond maxKeys are accounted to the overflow key
+	c.take("b", 1, now)
+	_, usages := c.snapshot()
+	keys := make([]string, 0)
+	for _, u := range usages {
+		keys = append(keys, u.Key)
+	}
+	assert.ElementsMatch(t, []string{"a", "vip", overflowKey}, keys)
+
+	// quota is reset in the next day
+	ok, _ = c.take("a", 6, now.Add(24*time.Hour))
+	assert.True(t, ok)
+}
+
+func TestCounterHourlyWindow(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	c := newCounter(&Config{Period: PeriodHourly, MaxKeys: 10}, loc)
+
+	now := time.Date(2023, 5, 1, 10, 59, 0, 0, loc)
+	assert.Equal(t, time.Date(2023, 5, 1, 10, 0, 0, 0, loc), c.windowOf(now))
+	assert.Equal(t, time.Date(2023, 5, 1, 11, 0, 0, 0, loc), c.windowOf(now.Add(time.Minute)))
+}
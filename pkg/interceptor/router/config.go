@@ -0,0 +1,35 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+)
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	// TablePath is the routing table file, it is reloaded when modified without restarting the pipeline
+	TablePath      string        `yaml:"tablePath,omitempty" validate:"required"`
+	ReloadInterval time.Duration `yaml:"reloadInterval,omitempty" default:"10s"`
+	// RouteKey is the header key where the destination of the matched route is set,
+	// sinks could refer to it in templates such as `topic: ${route.topic}`
+	RouteKey      string `yaml:"routeKey,omitempty" default:"route"`
+	DropUnmatched bool   `yaml:"dropUnmatched,omitempty"`
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/pkg/errors"
+)
+
+const (
+	Type = "router"
+
+	fRouteName = "name"
+)
+
+func init() {
+	pipeline.Register(api.INTERCEPTOR, Type, makeInterceptor)
+}
+
+func makeInterceptor(info pipeline.Info) api.Component {
+	return &Interceptor{
+		config: &Config{},
+		done:   make(chan struct{}),
+	}
+}
+
+type Interceptor struct {
+	name    string
+	config  *Config
+	done    chan struct{}
+	table   atomic.Value // *Table
+	modTime time.Time
+	size    int64
+}
+
+func (i *Interceptor) Config() interface{} {
+	return i.config
+}
+
+func (i *Interceptor) Category() api.Category {
+	return api.INTERCEPTOR
+}
+
+func (i *Interceptor) Type() api.Type {
+	return Type
+}
+
+func (i *Interceptor) String() string {
+	return fmt.Sprintf("%s/%s", i.Category(), i.Type())
+}
+
+func (i *Interceptor) Init(context api.Context) error {
+	i.name = context.Name()
+	if _, err := i.reload(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (i *Interceptor) Start() error {
+	go i.watch()
+	return nil
+}
+
+func (i *Interceptor) Stop() {
+	close(i.done)
+}
+
+func (i *Interceptor) watch() {
+	t := time.NewTicker(i.config.ReloadInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-i.done:
+			return
+		case <-t.C:
+			reloaded, err := i.reload()
+			if err != nil {
+				// keep routing with the previous table
+				log.Warn("reload routing table %s failed: %v", i.config.TablePath, err)
+				continue
+			}
+			if reloaded {
+				log.Info("routing table %s reloaded", i.config.TablePath)
+			}
+		}
+	}
+}
+
+// reload reads the routing table when the file has been modified
+func (i *Interceptor) reload() (bool, error) {
+	stat, err := os.Stat(i.config.TablePath)
+	if err != nil {
+		return false, err
+	}
+	if stat.ModTime().Equal(i.modTime) && stat.Size() == i.size {
+		return false, nil
+	}
+
+	table := &Table{}
+	if err := cfg.UnPackFromFile(i.config.TablePath, table).Defaults().Validate().Do(); err != nil {
+		return false, errors.WithMessagef(err, "unpack routing table %s", i.config.TablePath)
+	}
+	i.table.Store(table)
+	i.modTime = stat.ModTime()
+	i.size = stat.Size()
+	return true, nil
+}
+
+func (i *Interceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
+	e := invocation.Event
+	table := i.table.Load().(*Table)
+
+	name, destination, ok := table.Route(e)
+	if !ok {
+		if i.config.DropUnmatched {
+			return result.Drop()
+		}
+		return invoker.Invoke(invocation)
+	}
+
+	route := make(map[string]interface{}, len(destination)+1)
+	for k, v := range destination {
+		route[k] = v
+	}
+	if name != "" {
+		route[fRouteName] = name
+	}
+	header := e.Header()
+	if header == nil {
+		header = make(map[string]interface{})
+		e.Fill(e.Meta(), header, e.Body())
+	}
+	header[i.config.RouteKey] = route
+	return invoker.Invoke(invocation)
+}
+
+func (i *Interceptor) Order() int {
+	return i.config.Order
+}
+
+func (i *Interceptor) BelongTo() (componentTypes []string) {
+	return i.config.BelongTo
+}
+
+func (i *Interceptor) IgnoreRetry() bool {
+	return true
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/util/eventops"
+)
+
+// Table maps match conditions to sink destinations, routes are matched in order and the first matched one wins
+type Table struct {
+	Routes []Route `yaml:"routes,omitempty" validate:"dive"`
+	// Default is the destination of the events which do not match any route
+	Default map[string]interface{} `yaml:"default,omitempty"`
+}
+
+type Route struct {
+	Name string `yaml:"name,omitempty" validate:"required"`
+	// Match maps the field of event, e.g. fields.tenant or _k8s.namespace, to the accepted values,
+	// a route matches when all the fields match any of their values, glob patterns such as `pay-*` are supported
+	Match       map[string]Values      `yaml:"match,omitempty" validate:"required"`
+	Destination map[string]interface{} `yaml:"destination,omitempty" validate:"required"`
+}
+
+// Values could be unmarshalled from a single string or a list of strings
+type Values []string
+
+func (v *Values) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*v = Values{single}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*v = list
+	return nil
+}
+
+func (t *Table) Validate() error {
+	names := make(map[string]struct{})
+	for _, r := range t.Routes {
+		if _, ok := names[r.Name]; ok {
+			return errors.Errorf("route %s is duplicated", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		for field, values := range r.Match {
+			if len(values) == 0 {
+				return errors.Errorf("route %s: values of field %s are empty", r.Name, field)
+			}
+			for _, v := range values {
+				if _, err := path.Match(v, ""); err != nil {
+					return errors.WithMessagef(err, "route %s: invalid pattern %s of field %s", r.Name, v, field)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Route returns the name and destination of the first matched route
+func (t *Table) Route(e api.Event) (string, map[string]interface{}, bool) {
+	for _, r := range t.Routes {
+		if r.matches(e) {
+			return r.Name, r.Destination, true
+		}
+	}
+	return "", t.Default, len(t.Default) > 0
+}
+
+func (r *Route) matches(e api.Event) bool {
+	for field, values := range r.Match {
+		actual := fieldString(e, field)
+		matched := false
+		for _, v := range values {
+			if ok, _ := path.Match(v, actual); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func fieldString(e api.Event, field string) string {
+	switch v := eventops.Get(e, field).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/event"
+)
+
+const table = `
+routes:
+  - name: payments
+    match:
+      fields.tenant: payments
+      _k8s.namespace: ["pay-*", "billing"]
+    destination:
+      topic: payments-logs
+  - name: audit
+    match:
+      fields.logType: audit
+    destination:
+      topic: audit-logs
+default:
+  topic: unrouted-logs
+`
+
+func TestTableRoute(t *testing.T) {
+	tb := &Table{}
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(table), tb).Defaults().Validate().Do())
+
+	tests := []struct {
+		name      string
+		header    map[string]interface{}
+		wantRoute string
+		wantTopic string
+	}{
+		{
+			name: "glob matched",
+			header: map[string]interface{}{
+				"fields": map[string]interface{}{"tenant": "payments"},
+				"_k8s":   map[string]interface{}{"namespace": "pay-prod"},
+			},
+			wantRoute: "payments",
+			wantTopic: "payments-logs",
+		},
+		{
+			name: "partially matched falls through",
+			header: map[string]interface{}{
+				"fields": map[string]interface{}{"tenant": "payments", "logType": "audit"},
+				"_k8s":   map[string]interface{}{"namespace": "default"},
+			},
+			wantRoute: "audit",
+			wantTopic: "audit-logs",
+		},
+		{
+			name:      "default",
+			header:    map[string]interface{}{},
+			wantTopic: "unrouted-logs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := event.NewEvent(tt.header, []byte("test"))
+			name, destination, ok := tb.Route(e)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantRoute, name)
+			assert.Equal(t, tt.wantTopic, destination["topic"])
+		})
+	}
+}
+
+func TestTableValidate(t *testing.T) {
+	tb := &Table{}
+	err := cfg.UnPackFromRaw([]byte(`
+routes:
+  - name: a
+    match:
+      fields.tenant: "[a"
+    destination:
+      topic: a
+`), tb).Defaults().Validate().Do()
+	assert.Error(t, err)
+}
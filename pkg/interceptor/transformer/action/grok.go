@@ -23,6 +23,7 @@ import (
 	"github.com/loggie-io/loggie/pkg/core/cfg"
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/util/eventops"
+	"github.com/loggie-io/loggie/pkg/util/regex"
 	"github.com/pkg/errors"
 	"io"
 	"net/http"
@@ -82,7 +83,7 @@ type grokConfig struct {
 }
 
 type grok struct {
-	p           *regexp.Regexp
+	p           *regex.Regex
 	subexpNames []string
 	ignoreBlank bool
 
@@ -126,7 +127,10 @@ func (r *grokAction) act(e api.Event) error {
 	// get field value
 	val := eventops.GetString(e, r.key)
 
-	rst := r.grok.grok(val)
+	rst, err := r.grok.grok(val)
+	if err != nil {
+		return errors.WithMessagef(err, "match group with grok %s", r.config.Match)
+	}
 	if len(rst) == 0 {
 		return errors.Errorf("match group with grok %s is empty", r.config.Match)
 	}
@@ -162,9 +166,10 @@ func NewGrok(match string, patternPaths []string, ignoreBlank bool, pattern map[
 		}
 	}
 	finalPattern := grok.translateMatchPattern(match)
-	p, err := regexp.Compile(finalPattern)
+	p, err := regex.Compile(finalPattern)
 	if err != nil {
 		log.Error("could not build grok:%s", err)
+		return grok
 	}
 	grok.p = p
 	grok.subexpNames = p.SubexpNames()
@@ -172,9 +177,16 @@ func NewGrok(match string, patternPaths []string, ignoreBlank bool, pattern map[
 	return grok
 }
 
-func (grok *grok) grok(input string) map[string]interface{} {
+func (grok *grok) grok(input string) (map[string]interface{}, error) {
+	if grok.p == nil {
+		return nil, errors.New("grok is not built")
+	}
+	match, err := grok.p.FindStringSubmatchWithin(regex.NewBudget(), input)
+	if err != nil {
+		return nil, err
+	}
 	rst := make(map[string]interface{})
-	for i, substring := range grok.p.FindStringSubmatch(input) {
+	for i, substring := range match {
 		if grok.subexpNames[i] == "" {
 			continue
 		}
@@ -183,7 +195,7 @@ func (grok *grok) grok(input string) map[string]interface{} {
 		}
 		rst[grok.subexpNames[i]] = substring
 	}
-	return rst
+	return rst, nil
 }
 
 func (grok *grok) loadPatterns() {
@@ -19,10 +19,9 @@ package action
 import (
 	"github.com/loggie-io/loggie/pkg/core/api"
 	"github.com/loggie-io/loggie/pkg/core/cfg"
-	"github.com/loggie-io/loggie/pkg/util"
 	"github.com/loggie-io/loggie/pkg/util/eventops"
+	"github.com/loggie-io/loggie/pkg/util/regex"
 	"github.com/pkg/errors"
-	"regexp"
 )
 
 const (
@@ -39,7 +38,7 @@ func init() {
 type Regex struct {
 	key   string
 	to    string
-	reg   *regexp.Regexp
+	reg   *regex.Regex
 	extra *regexExtra
 }
 
@@ -47,12 +46,12 @@ type regexExtra struct {
 	Pattern string `yaml:"pattern,omitempty"`
 }
 
-func (re *regexExtra) compile() (*regexp.Regexp, error) {
+func (re *regexExtra) compile() (*regex.Regex, error) {
 	if re.Pattern == "" {
 		return nil, errors.New("regex pattern is required")
 	}
 
-	p, err := util.CompilePatternWithJavaStyle(re.Pattern)
+	p, err := regex.Compile(re.Pattern)
 	if err != nil {
 		return nil, err
 	}
@@ -96,14 +95,20 @@ func (r *Regex) act(e api.Event) error {
 	// get field value
 	val := eventops.GetString(e, r.key)
 
+	matchedMap, err := r.reg.MatchGroupWithin(regex.NewBudget(), val)
+	if err != nil {
+		return errors.WithMessagef(err, "match group with regex %s", r.extra.Pattern)
+	}
+
 	if r.to == HeaderRoot {
-		matchCount := util.MatchGroupWithRegexAndHeader(r.reg, val, header)
-		if matchCount == 0 {
+		if matchedMap == nil {
 			return errors.Errorf("match group with regex %s is empty", r.extra.Pattern)
 		}
+		for k, v := range matchedMap {
+			header[k] = v
+		}
 
 	} else {
-		matchedMap := util.MatchGroupWithRegex(r.reg, val)
 		eventops.Set(e, r.to, matchedMap)
 	}
 
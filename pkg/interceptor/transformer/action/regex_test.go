@@ -19,8 +19,7 @@ package action
 import (
 	"github.com/loggie-io/loggie/pkg/core/api"
 	"github.com/loggie-io/loggie/pkg/core/event"
-	"github.com/loggie-io/loggie/pkg/util"
-	"regexp"
+	"github.com/loggie-io/loggie/pkg/util/regex"
 	"testing"
 )
 
@@ -31,7 +30,7 @@ func TestRegex_act(t *testing.T) {
 	type fields struct {
 		key   string
 		to    string
-		reg   *regexp.Regexp
+		reg   *regex.Regex
 		extra *regexExtra
 	}
 	type args struct {
@@ -49,7 +48,7 @@ func TestRegex_act(t *testing.T) {
 			fields: fields{
 				key: "body",
 				to:  HeaderRoot,
-				reg: util.MustCompilePatternWithJavaStyle(pattern),
+				reg: regex.MustCompile(pattern),
 				extra: &regexExtra{
 					Pattern: pattern,
 				},
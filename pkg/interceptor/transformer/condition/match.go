@@ -18,9 +18,10 @@ package condition
 
 import (
 	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/util/eventops"
+	"github.com/loggie-io/loggie/pkg/util/regex"
 	"github.com/pkg/errors"
-	"regexp"
 	"strings"
 )
 
@@ -32,7 +33,7 @@ const (
 // Match check whether the field value contains any match of the regular expression
 type Match struct {
 	field   string
-	pattern *regexp.Regexp
+	pattern *regex.Regex
 }
 
 func init() {
@@ -62,18 +63,23 @@ func NewMatch(args []string) (*Match, error) {
 		}
 	}
 
-	regex, err := regexp.Compile(args[1])
+	pattern, err := regex.Compile(args[1])
 	if err != nil {
 		return nil, err
 	}
 
 	return &Match{
 		field:   args[0],
-		pattern: regex,
+		pattern: pattern,
 	}, nil
 }
 
 func (m *Match) Check(e api.Event) bool {
 	fieldVal := eventops.GetString(e, m.field)
-	return m.pattern.MatchString(fieldVal)
+	matched, err := m.pattern.MatchStringWithin(regex.NewBudget(), fieldVal)
+	if err != nil {
+		log.Warn("match %s with regex %s failed: %v", m.field, m.pattern.String(), err)
+		return false
+	}
+	return matched
 }
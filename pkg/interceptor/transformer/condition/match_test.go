@@ -19,8 +19,8 @@ package condition
 import (
 	"github.com/loggie-io/loggie/pkg/core/api"
 	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/util/regex"
 	"github.com/stretchr/testify/assert"
-	"regexp"
 	"testing"
 )
 
@@ -29,7 +29,7 @@ func TestMatch_Check(t *testing.T) {
 
 	type fields struct {
 		field   string
-		pattern *regexp.Regexp
+		pattern *regex.Regex
 	}
 	type args struct {
 		e api.Event
@@ -44,7 +44,7 @@ func TestMatch_Check(t *testing.T) {
 			name: "ok-1",
 			fields: fields{
 				field:   "a.b",
-				pattern: regexp.MustCompile(`^2022`),
+				pattern: regex.MustCompile(`^2022`),
 			},
 			args: args{
 				e: event.NewEvent(map[string]interface{}{
@@ -59,7 +59,7 @@ func TestMatch_Check(t *testing.T) {
 			name: "not match body",
 			fields: fields{
 				field:   "body",
-				pattern: regexp.MustCompile(`^2022`),
+				pattern: regex.MustCompile(`^2022`),
 			},
 			args: args{
 				e: event.NewEvent(map[string]interface{}{
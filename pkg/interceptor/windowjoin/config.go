@@ -0,0 +1,66 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package windowjoin
+
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+	"github.com/pkg/errors"
+)
+
+const (
+	UnmatchedEmit = "emit"
+	UnmatchedDrop = "drop"
+)
+
+type Config struct {
+	interceptor.ExtensionConfig `yaml:",inline"`
+
+	// Key is the field of the correlation key, such as requestId, events without key are passed through
+	Key string `yaml:"key,omitempty" validate:"required"`
+	// RoleField and Roles pair the events by their side, such as type with [request, response], so only events
+	// of different roles are joined, and the headers of each side are nested under its role in the joined event.
+	// Without RoleField, any two events of the same key are joined and the fields of the later one take precedence.
+	RoleField string   `yaml:"roleField,omitempty"`
+	Roles     []string `yaml:"roles,omitempty"`
+	// Timeout is the window waiting for the other event of the pair
+	Timeout time.Duration `yaml:"timeout,omitempty" default:"30s"`
+	// Unmatched could be emit or drop, applied to the events not joined within the timeout or evicted by the memory bounds
+	Unmatched string `yaml:"unmatched,omitempty" default:"emit" validate:"oneof=emit drop"`
+	// UnmatchedTag is the header key set to true on the emitted unmatched events, empty means not tagged
+	UnmatchedTag string `yaml:"unmatchedTag,omitempty" default:"joinUnmatched"`
+	// MaxPending and MaxPendingBytes bound the events waiting to be joined, the oldest ones are evicted when exceeded
+	MaxPending      int `yaml:"maxPending,omitempty" default:"10000" validate:"gte=1"`
+	MaxPendingBytes int `yaml:"maxPendingBytes,omitempty" default:"33554432" validate:"gte=1"`
+}
+
+func (c *Config) Validate() error {
+	if c.Timeout <= 0 {
+		return errors.New("timeout should be positive")
+	}
+	if c.RoleField == "" {
+		if len(c.Roles) > 0 {
+			return errors.New("roleField is required when roles are set")
+		}
+		return nil
+	}
+	if len(c.Roles) != 2 || c.Roles[0] == "" || c.Roles[1] == "" || c.Roles[0] == c.Roles[1] {
+		return errors.New("roles should be two different values of roleField")
+	}
+	return nil
+}
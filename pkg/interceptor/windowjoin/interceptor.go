@@ -0,0 +1,328 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package windowjoin
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/eventops"
+)
+
+const Type = "windowJoin"
+
+func init() {
+	pipeline.Register(api.INTERCEPTOR, Type, makeInterceptor)
+}
+
+func makeInterceptor(info pipeline.Info) api.Component {
+	return &Interceptor{
+		config:    &Config{},
+		eventPool: info.EventPool,
+		now:       time.Now,
+		done:      make(chan struct{}),
+	}
+}
+
+// pending is an event waiting for the other one of its pair. The event is kept out of the pool
+// instead of copied, so the source would not commit it until it is emitted to the sink.
+type pending struct {
+	key     string
+	role    string
+	event   api.Event
+	invoker source.Invoker
+	queue   api.Queue
+	size    int
+	arrived time.Time
+	elem    *list.Element
+}
+
+type Interceptor struct {
+	name      string
+	config    *Config
+	eventPool *event.Pool
+	now       func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]*pending
+	order   *list.List // pending events in arrival order
+	bytes   int
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func (i *Interceptor) Config() interface{} {
+	return i.config
+}
+
+func (i *Interceptor) Category() api.Category {
+	return api.INTERCEPTOR
+}
+
+func (i *Interceptor) Type() api.Type {
+	return Type
+}
+
+func (i *Interceptor) String() string {
+	return fmt.Sprintf("%s/%s", i.Category(), i.Type())
+}
+
+func (i *Interceptor) Init(context api.Context) error {
+	i.name = context.Name()
+	i.pending = make(map[string]*pending)
+	i.order = list.New()
+	return nil
+}
+
+func (i *Interceptor) Start() error {
+	go i.run()
+	return nil
+}
+
+// Stop abandons the pending events, since the queue may have been stopped. They have not been committed yet,
+// so would be collected again by the sources which support resuming.
+func (i *Interceptor) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.done)
+		i.mu.Lock()
+		rest := i.evict(func(p *pending) bool { return true })
+		i.mu.Unlock()
+		if len(rest) > 0 {
+			log.Info("%s abandon %d pending events", i.String(), len(rest))
+		}
+		for _, p := range rest {
+			i.release(p.event)
+		}
+	})
+}
+
+func (i *Interceptor) run() {
+	interval := i.config.Timeout / 10
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.done:
+			return
+		case <-ticker.C:
+			i.expire()
+		}
+	}
+}
+
+func (i *Interceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
+	e := invocation.Event
+	key := fieldString(e, i.config.Key)
+	if key == "" {
+		return invoker.Invoke(invocation)
+	}
+	var role string
+	if i.config.RoleField != "" {
+		role = fieldString(e, i.config.RoleField)
+		if role != i.config.Roles[0] && role != i.config.Roles[1] {
+			return invoker.Invoke(invocation)
+		}
+	}
+
+	i.mu.Lock()
+	held, ok := i.pending[key]
+	if ok {
+		i.remove(held)
+	}
+	if ok && (role == "" || held.role != role) {
+		i.mu.Unlock()
+		// the held event carries the joined content, so that the current one which is dropped could be
+		// committed by the source right away
+		i.join(held, e, role)
+		i.emit(held.event, invoker, invocation.Queue)
+		return result.Drop()
+	}
+
+	var evicted []*pending
+	if ok {
+		// the same role arrived again, the previous one would never be matched
+		evicted = append(evicted, held)
+	}
+	i.add(&pending{
+		key:     key,
+		role:    role,
+		event:   e,
+		invoker: invoker,
+		queue:   invocation.Queue,
+		size:    eventSize(e),
+		arrived: i.now(),
+	})
+	evicted = append(evicted, i.evict(func(p *pending) bool {
+		return len(i.pending) > i.config.MaxPending || i.bytes > i.config.MaxPendingBytes
+	})...)
+	i.mu.Unlock()
+
+	i.unmatched(evicted)
+	return result.Success()
+}
+
+// join merges the current event into the held one
+func (i *Interceptor) join(held *pending, e api.Event, role string) {
+	header := make(map[string]interface{})
+	if role == "" {
+		for k, v := range held.event.Header() {
+			header[k] = v
+		}
+		for k, v := range e.Header() {
+			header[k] = v
+		}
+	} else {
+		header[held.role] = held.event.Header()
+		header[role] = e.Header()
+	}
+
+	var body []byte
+	if hb, cb := held.event.Body(), e.Body(); len(hb) > 0 && len(cb) > 0 {
+		body = make([]byte, 0, len(hb)+len(cb)+1)
+		body = append(body, hb...)
+		body = append(body, '\n')
+		body = append(body, cb...)
+	} else if len(hb) > 0 {
+		body = hb
+	} else {
+		body = cb
+	}
+	held.event.Fill(held.event.Meta(), header, body)
+	if role != "" {
+		eventops.Set(held.event, i.config.Key, held.key)
+	}
+}
+
+func (i *Interceptor) emit(e api.Event, invoker source.Invoker, queue api.Queue) {
+	res := invoker.Invoke(source.Invocation{
+		Event: e,
+		Queue: queue,
+	})
+	switch res.Status() {
+	case api.DROP:
+		i.release(e)
+	case api.FAIL:
+		log.Error("%s emit event failed: %v", i.String(), res.Error())
+	}
+}
+
+func (i *Interceptor) unmatched(ps []*pending) {
+	for _, p := range ps {
+		if i.config.Unmatched == UnmatchedDrop {
+			i.release(p.event)
+			continue
+		}
+		if i.config.UnmatchedTag != "" {
+			header := p.event.Header()
+			if header == nil {
+				header = make(map[string]interface{})
+				p.event.Fill(p.event.Meta(), header, p.event.Body())
+			}
+			header[i.config.UnmatchedTag] = true
+		}
+		i.emit(p.event, p.invoker, p.queue)
+	}
+}
+
+func (i *Interceptor) release(e api.Event) {
+	if i.eventPool != nil {
+		i.eventPool.Put(e)
+	}
+}
+
+func (i *Interceptor) expire() {
+	deadline := i.now().Add(-i.config.Timeout)
+	i.mu.Lock()
+	expired := i.evict(func(p *pending) bool {
+		return !p.arrived.After(deadline)
+	})
+	i.mu.Unlock()
+
+	if len(expired) > 0 {
+		log.Debug("%s %d events are not joined in %s", i.String(), len(expired), i.config.Timeout)
+	}
+	i.unmatched(expired)
+}
+
+func (i *Interceptor) add(p *pending) {
+	p.elem = i.order.PushBack(p)
+	i.pending[p.key] = p
+	i.bytes += p.size
+}
+
+func (i *Interceptor) remove(p *pending) {
+	i.order.Remove(p.elem)
+	delete(i.pending, p.key)
+	i.bytes -= p.size
+}
+
+// evict removes the oldest pending events while cond holds, the caller should hold the lock
+func (i *Interceptor) evict(cond func(p *pending) bool) []*pending {
+	var evicted []*pending
+	for elem := i.order.Front(); elem != nil; elem = i.order.Front() {
+		p := elem.Value.(*pending)
+		if !cond(p) {
+			break
+		}
+		i.remove(p)
+		evicted = append(evicted, p)
+	}
+	return evicted
+}
+
+func eventSize(e api.Event) int {
+	// the header is not measured, which is usually much smaller than the body before being decoded
+	return len(e.Body()) + 64
+}
+
+// fieldString formats the numbers as well, such as a requestId of 1001
+func fieldString(e api.Event, field string) string {
+	switch v := eventops.Get(e, field).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (i *Interceptor) Order() int {
+	return i.config.Order
+}
+
+func (i *Interceptor) BelongTo() (componentTypes []string) {
+	return i.config.BelongTo
+}
+
+func (i *Interceptor) IgnoreRetry() bool {
+	return true
+}
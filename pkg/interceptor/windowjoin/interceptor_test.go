@@ -0,0 +1,158 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package windowjoin
+
+import (
+	"container/list"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+)
+
+type recordInvoker struct {
+	events []api.Event
+}
+
+func (r *recordInvoker) Invoke(invocation source.Invocation) api.Result {
+	r.events = append(r.events, invocation.Event)
+	return result.Success()
+}
+
+func newInterceptor(config Config, now *time.Time) *Interceptor {
+	i := &Interceptor{
+		config:  &config,
+		now:     func() time.Time { return *now },
+		done:    make(chan struct{}),
+		pending: make(map[string]*pending),
+		order:   list.New(),
+	}
+	return i
+}
+
+func intercept(i *Interceptor, invoker source.Invoker, header map[string]interface{}, body string) api.Status {
+	return i.Intercept(invoker, source.Invocation{Event: event.NewEvent(header, []byte(body))}).Status()
+}
+
+func TestIntercept_Roles(t *testing.T) {
+	log.InitDefaultLogger()
+	now := time.Now()
+	i := newInterceptor(Config{
+		Key:             "id",
+		RoleField:       "type",
+		Roles:           []string{"request", "response"},
+		Timeout:         time.Minute,
+		Unmatched:       UnmatchedEmit,
+		UnmatchedTag:    "joinUnmatched",
+		MaxPending:      10,
+		MaxPendingBytes: 1024,
+	}, &now)
+	invoker := &recordInvoker{}
+
+	assert.Equal(t, api.SUCCESS, intercept(i, invoker, map[string]interface{}{"id": 1, "type": "request", "path": "/a"}, "GET /a"))
+	assert.Equal(t, api.SUCCESS, intercept(i, invoker, map[string]interface{}{"type": "request"}, "no key"))
+	assert.Equal(t, api.SUCCESS, intercept(i, invoker, map[string]interface{}{"id": 2, "type": "other"}, "other role"))
+	assert.Len(t, invoker.events, 2)
+
+	assert.Equal(t, api.DROP, intercept(i, invoker, map[string]interface{}{"id": 1, "type": "response", "status": 200}, "200 OK"))
+	assert.Len(t, invoker.events, 3)
+	joined := invoker.events[2]
+	assert.Equal(t, map[string]interface{}{
+		"id":       "1",
+		"request":  map[string]interface{}{"id": 1, "type": "request", "path": "/a"},
+		"response": map[string]interface{}{"id": 1, "type": "response", "status": 200},
+	}, joined.Header())
+	assert.Equal(t, "GET /a\n200 OK", string(joined.Body()))
+	assert.Equal(t, 0, i.order.Len())
+
+	// the repeated request replaces the previous one, which would never be matched
+	intercept(i, invoker, map[string]interface{}{"id": 3, "type": "request"}, "first")
+	intercept(i, invoker, map[string]interface{}{"id": 3, "type": "request"}, "second")
+	assert.Len(t, invoker.events, 4)
+	assert.Equal(t, "first", string(invoker.events[3].Body()))
+	assert.Equal(t, true, invoker.events[3].Header()["joinUnmatched"])
+	assert.Equal(t, 1, i.order.Len())
+}
+
+func TestIntercept_Merge(t *testing.T) {
+	log.InitDefaultLogger()
+	now := time.Now()
+	i := newInterceptor(Config{Key: "trace.id", Timeout: time.Minute, MaxPending: 10, MaxPendingBytes: 1024}, &now)
+	invoker := &recordInvoker{}
+
+	intercept(i, invoker, map[string]interface{}{"trace": map[string]interface{}{"id": "t1"}, "a": 1, "b": 1}, "")
+	intercept(i, invoker, map[string]interface{}{"trace": map[string]interface{}{"id": "t1"}, "b": 2}, "second")
+	assert.Len(t, invoker.events, 1)
+	assert.Equal(t, map[string]interface{}{"trace": map[string]interface{}{"id": "t1"}, "a": 1, "b": 2}, invoker.events[0].Header())
+	assert.Equal(t, "second", string(invoker.events[0].Body()))
+}
+
+func TestIntercept_Unmatched(t *testing.T) {
+	log.InitDefaultLogger()
+	now := time.Now()
+	config := Config{
+		Key:             "id",
+		Timeout:         time.Minute,
+		Unmatched:       UnmatchedEmit,
+		UnmatchedTag:    "joinUnmatched",
+		MaxPending:      2,
+		MaxPendingBytes: 1024,
+	}
+	i := newInterceptor(config, &now)
+	invoker := &recordInvoker{}
+
+	intercept(i, invoker, map[string]interface{}{"id": "a"}, "a")
+	now = now.Add(30 * time.Second)
+	intercept(i, invoker, map[string]interface{}{"id": "b"}, "b")
+	intercept(i, invoker, map[string]interface{}{"id": "c"}, "c")
+	// the oldest one is evicted by maxPending
+	assert.Len(t, invoker.events, 1)
+	assert.Equal(t, "a", string(invoker.events[0].Body()))
+
+	now = now.Add(31 * time.Second)
+	i.expire()
+	assert.Len(t, invoker.events, 1)
+	now = now.Add(30 * time.Second)
+	i.expire()
+	assert.Len(t, invoker.events, 3)
+	for _, e := range invoker.events {
+		assert.Equal(t, true, e.Header()["joinUnmatched"])
+	}
+
+	config.Unmatched = UnmatchedDrop
+	i = newInterceptor(config, &now)
+	invoker = &recordInvoker{}
+	intercept(i, invoker, map[string]interface{}{"id": "a"}, "a")
+	now = now.Add(time.Hour)
+	i.expire()
+	assert.Len(t, invoker.events, 0)
+	assert.Equal(t, 0, i.bytes)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&Config{Key: "id", Timeout: time.Second}).Validate())
+	assert.NoError(t, (&Config{Key: "id", Timeout: time.Second, RoleField: "type", Roles: []string{"req", "resp"}}).Validate())
+	assert.Error(t, (&Config{Key: "id", Timeout: time.Second, Roles: []string{"req", "resp"}}).Validate())
+	assert.Error(t, (&Config{Key: "id", Timeout: time.Second, RoleField: "type", Roles: []string{"req", "req"}}).Validate())
+	assert.Error(t, (&Config{Key: "id", Timeout: time.Second, RoleField: "type"}).Validate())
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authz
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNoCredential      = errors.New("no credential")
+	ErrInvalidCredential = errors.New("invalid credential")
+)
+
+// Principal is who sends the request
+type Principal struct {
+	Subject  string
+	Role     string
+	Provider string
+}
+
+// authenticator returns matched false if the credential is not for it, so the next one would be tried
+type authenticator interface {
+	name() string
+	authenticate(r *http.Request, token string) (principal *Principal, matched bool, err error)
+}
+
+// Authorizer authorizes the requests of the management api, the viewers could only read
+// and the changing requests such as syncing or pausing the pipelines require the admins
+type Authorizer struct {
+	config         *Config
+	public         map[string]struct{}
+	authenticators []authenticator
+}
+
+func New(config *Config) *Authorizer {
+	a := &Authorizer{
+		config: config,
+		public: make(map[string]struct{}),
+	}
+	for _, p := range config.Public {
+		a.public[p] = struct{}{}
+	}
+	if len(config.Tokens) > 0 {
+		a.authenticators = append(a.authenticators, newStaticTokens(config.Tokens))
+	}
+	if config.OIDC != nil {
+		a.authenticators = append(a.authenticators, newOIDC(config.OIDC))
+	}
+	if config.Exec != nil {
+		a.authenticators = append(a.authenticators, newExec(config.Exec))
+	}
+	return a
+}
+
+// Handler wraps the handler with authorization, the handler is returned directly if not enabled
+func Handler(config Config, next http.Handler) http.Handler {
+	if !config.Enabled {
+		return next
+	}
+	log.Info("management api authorization enabled")
+	return New(&config).Wrap(next)
+}
+
+func (a *Authorizer) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if _, ok := a.public[request.URL.Path]; ok {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		principal, err := a.authenticate(request)
+		if err != nil {
+			audit(request, nil, http.StatusUnauthorized, err.Error())
+			writer.Header().Set("WWW-Authenticate", `Bearer realm="loggie"`)
+			writer.WriteHeader(http.StatusUnauthorized)
+			writer.Write([]byte(err.Error()))
+			return
+		}
+
+		if !permitted(principal.Role, request.Method) {
+			audit(request, principal, http.StatusForbidden, "permission denied")
+			writer.WriteHeader(http.StatusForbidden)
+			writer.Write([]byte("permission denied"))
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+		next.ServeHTTP(recorder, request)
+		if !readOnly(request.Method) || a.config.AuditReads {
+			audit(request, principal, recorder.status, "")
+		}
+	})
+}
+
+func (a *Authorizer) authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	for _, au := range a.authenticators {
+		// the exec plugin could decide on the other headers, so it is tried without the bearer token as well
+		if token == "" && au.name() != providerExec {
+			continue
+		}
+		principal, matched, err := au.authenticate(r, token)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "%s", au.name())
+		}
+		if !matched {
+			continue
+		}
+		// the principal without any role is authenticated but permitted nothing
+		if principal.Role != "" && !validRole(principal.Role) {
+			return nil, errors.Errorf("%s: role %q of %s is invalid", au.name(), principal.Role, principal.Subject)
+		}
+		principal.Provider = au.name()
+		return principal, nil
+	}
+	if token == "" {
+		return nil, ErrNoCredential
+	}
+	return nil, ErrInvalidCredential
+}
+
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > len("bearer ") && strings.EqualFold(h[:len("bearer ")], "bearer ") {
+		return strings.TrimSpace(h[len("bearer "):])
+	}
+	return ""
+}
+
+func readOnly(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func permitted(role string, method string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	return role == RoleViewer && readOnly(method)
+}
+
+// audit logs who requested which api, and which pipeline it is about
+func audit(r *http.Request, principal *Principal, status int, reason string) {
+	subject, role, provider := "-", "-", "-"
+	if principal != nil {
+		subject, role, provider = principal.Subject, principal.Role, principal.Provider
+	}
+	pipeline := pipelineOf(r)
+	if pipeline == "" {
+		pipeline = "-"
+	}
+	msg := "[audit] subject: %s, role: %s, provider: %s, remote: %s, method: %s, uri: %s, pipeline: %s, status: %d"
+	if reason == "" {
+		log.Info(msg, subject, role, provider, r.RemoteAddr, r.Method, r.URL.RequestURI(), pipeline, status)
+		return
+	}
+	log.Warn(msg+", reason: %s", subject, role, provider, r.RemoteAddr, r.Method, r.URL.RequestURI(), pipeline, status, reason)
+}
+
+// pipelineOf returns the pipeline in the query, or in the path such as /api/v1/pipeline/<name>/sink/dev
+func pipelineOf(r *http.Request) string {
+	if p := r.URL.Query().Get("pipeline"); p != "" {
+		return p
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == "pipeline" || parts[i] == "pipelines" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+const providerStatic = "static"
+
+type staticTokens struct {
+	tokens []StaticToken
+}
+
+func newStaticTokens(tokens []StaticToken) *staticTokens {
+	return &staticTokens{tokens: tokens}
+}
+
+func (s *staticTokens) name() string {
+	return providerStatic
+}
+
+func (s *staticTokens) authenticate(_ *http.Request, token string) (*Principal, bool, error) {
+	var found *StaticToken
+	// all the tokens are compared in constant time, so the matched one could not be guessed by timing
+	for i := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(s.tokens[i].Token), []byte(token)) == 1 {
+			found = &s.tokens[i]
+		}
+	}
+	if found == nil {
+		return nil, false, nil
+	}
+	return &Principal{Subject: found.Name, Role: found.Role}, true, nil
+}
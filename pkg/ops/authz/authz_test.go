@@ -0,0 +1,181 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authz
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+func TestMain(m *testing.M) {
+	log.InitDefaultLogger()
+	os.Exit(m.Run())
+}
+
+func serve(t *testing.T, a *Authorizer, method string, path string, token string) int {
+	handler := a.Wrap(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestStaticTokens(t *testing.T) {
+	config := &Config{}
+	err := cfg.UnPackFromRaw([]byte(`
+enabled: true
+tokens:
+- name: ops
+  token: admin-token
+  role: admin
+- name: grafana
+  token: viewer-token
+`), config).Defaults().Validate().Do()
+	assert.NoError(t, err)
+	assert.Equal(t, RoleViewer, config.Tokens[1].Role)
+	a := New(config)
+
+	assert.Equal(t, http.StatusOK, serve(t, a, http.MethodGet, "/health", ""))
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, http.MethodGet, "/api/v1/controller/pipelines", ""))
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, http.MethodGet, "/api/v1/controller/pipelines", "unknown"))
+	assert.Equal(t, http.StatusOK, serve(t, a, http.MethodGet, "/api/v1/controller/pipelines", "viewer-token"))
+	assert.Equal(t, http.StatusForbidden, serve(t, a, http.MethodPost, "/api/v1/discovery/git/sync", "viewer-token"))
+	assert.Equal(t, http.StatusOK, serve(t, a, http.MethodPost, "/api/v1/discovery/git/sync", "admin-token"))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, (&Config{}).Validate())
+	assert.Error(t, (&Config{Enabled: true}).Validate())
+	assert.Error(t, (&Config{Enabled: true, Tokens: []StaticToken{{Name: "a", Token: "1"}, {Name: "a", Token: "2"}}}).Validate())
+	assert.Error(t, (&Config{Enabled: true, OIDC: &OIDCConfig{RoleMapping: map[string]string{"ops": "root"}}}).Validate())
+}
+
+func TestPipelineOf(t *testing.T) {
+	assert.Equal(t, "local", pipelineOf(httptest.NewRequest(http.MethodPost, "/api/v1/pipeline/local/sink/dev", nil)))
+	assert.Equal(t, "web", pipelineOf(httptest.NewRequest(http.MethodPost, "/api/v1/pause?pipeline=web", nil)))
+	assert.Equal(t, "", pipelineOf(httptest.NewRequest(http.MethodGet, "/api/v1/help", nil)))
+}
+
+func TestOIDC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc(wellKnownPath, func(writer http.ResponseWriter, request *http.Request) {
+		out, _ := json.Marshal(map[string]string{"jwks_uri": issuer + "/keys"})
+		writer.Write(out)
+	})
+	mux.HandleFunc("/keys", func(writer http.ResponseWriter, request *http.Request) {
+		out, _ := json.Marshal(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "k1",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+		writer.Write(out)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	issuer = server.URL
+
+	a := New(&Config{
+		Enabled: true,
+		OIDC: &OIDCConfig{
+			Issuer:          issuer,
+			Audience:        "loggie",
+			SubjectClaim:    "email",
+			RoleClaim:       "groups",
+			RoleMapping:     map[string]string{"sre": RoleAdmin},
+			RefreshInterval: time.Minute,
+			Timeout:         time.Second,
+		},
+	})
+
+	sign := func(kid string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		s, err := token.SignedString(key)
+		assert.NoError(t, err)
+		return s
+	}
+	claims := func(groups ...interface{}) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":    issuer,
+			"aud":    "loggie",
+			"email":  "alice@example.com",
+			"groups": groups,
+			"exp":    time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	assert.Equal(t, http.StatusOK, serve(t, a, http.MethodPost, "/api/v1/discovery/git/sync", sign("k1", claims("dev", "sre"))))
+	assert.Equal(t, http.StatusOK, serve(t, a, http.MethodGet, "/api/v1/controller/pipelines", sign("k1", claims("viewer"))))
+	assert.Equal(t, http.StatusForbidden, serve(t, a, http.MethodPost, "/api/v1/discovery/git/sync", sign("k1", claims("viewer"))))
+	assert.Equal(t, http.StatusForbidden, serve(t, a, http.MethodGet, "/api/v1/controller/pipelines", sign("k1", claims("dev"))))
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, http.MethodGet, "/api/v1/controller/pipelines", sign("k2", claims("sre"))))
+
+	expired := claims("sre")
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, http.MethodGet, "/api/v1/controller/pipelines", sign("k1", expired)))
+
+	otherAudience := claims("sre")
+	otherAudience["aud"] = "other"
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, http.MethodGet, "/api/v1/controller/pipelines", sign("k1", otherAudience)))
+}
+
+func TestExec(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "authz.sh")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+if grep -q '"token":"secret"'; then
+  echo '{"allowed": true, "subject": "bob", "role": "admin"}'
+else
+  echo '{"allowed": false, "reason": "unknown token"}'
+fi
+`), 0755)
+	assert.NoError(t, err)
+
+	a := New(&Config{
+		Enabled: true,
+		Exec:    &ExecConfig{Command: script, Timeout: 5 * time.Second},
+	})
+	assert.Equal(t, http.StatusOK, serve(t, a, http.MethodPost, "/api/v1/pipeline/local/sink/dev", "secret"))
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, http.MethodPost, "/api/v1/pipeline/local/sink/dev", "guess"))
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authz
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// RoleViewer could only read, such as listing the pipelines
+	RoleViewer = "viewer"
+	// RoleAdmin could read and change the agent, such as syncing or pausing the pipelines
+	RoleAdmin = "admin"
+)
+
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Public are the paths requested without authorization, such as the probes and the metrics scraping
+	Public []string      `yaml:"public,omitempty" default:"[\"/health\",\"/version\",\"/metrics\"]"`
+	Tokens []StaticToken `yaml:"tokens,omitempty" validate:"dive"`
+	OIDC   *OIDCConfig   `yaml:"oidc,omitempty"`
+	Exec   *ExecConfig   `yaml:"exec,omitempty"`
+	// AuditReads logs the allowed read requests as well, the changing and denied requests are always logged
+	AuditReads bool `yaml:"auditReads,omitempty"`
+}
+
+type StaticToken struct {
+	Name  string `yaml:"name,omitempty" validate:"required"`
+	Token string `yaml:"token,omitempty" validate:"required"`
+	Role  string `yaml:"role,omitempty" default:"viewer" validate:"oneof=viewer admin"`
+}
+
+type OIDCConfig struct {
+	Issuer string `yaml:"issuer,omitempty" validate:"required"`
+	// Audience is checked against the aud claim when not empty
+	Audience string `yaml:"audience,omitempty"`
+	// JWKSUrl is discovered from the openid configuration of the issuer if empty
+	JWKSUrl      string `yaml:"jwksUrl,omitempty"`
+	SubjectClaim string `yaml:"subjectClaim,omitempty" default:"sub"`
+	// RoleClaim could be a string or an array of strings
+	RoleClaim string `yaml:"roleClaim,omitempty" default:"roles"`
+	// RoleMapping maps the values of the role claim to the roles, such as the groups of the identity provider
+	RoleMapping     map[string]string `yaml:"roleMapping,omitempty"`
+	RefreshInterval time.Duration     `yaml:"refreshInterval,omitempty" default:"10m"`
+	Timeout         time.Duration     `yaml:"timeout,omitempty" default:"10s"`
+}
+
+// ExecConfig runs the command for each request, the request is written to its stdin as json,
+// and it writes the decision as json to stdout, such as {"allowed": true, "subject": "alice", "role": "admin"}
+type ExecConfig struct {
+	Command string        `yaml:"command,omitempty" validate:"required"`
+	Args    []string      `yaml:"args,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty" default:"5s"`
+}
+
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Tokens) == 0 && c.OIDC == nil && c.Exec == nil {
+		return errors.New("one of tokens, oidc or exec is required when authorization is enabled")
+	}
+	names := make(map[string]struct{})
+	for _, t := range c.Tokens {
+		if _, ok := names[t.Name]; ok {
+			return errors.Errorf("token name %s is duplicated", t.Name)
+		}
+		names[t.Name] = struct{}{}
+	}
+	if c.OIDC != nil {
+		for k, v := range c.OIDC.RoleMapping {
+			if !validRole(v) {
+				return errors.Errorf("role %s mapped from %s is invalid", v, k)
+			}
+		}
+	}
+	return nil
+}
+
+func validRole(role string) bool {
+	return role == RoleViewer || role == RoleAdmin
+}
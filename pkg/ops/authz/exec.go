@@ -0,0 +1,101 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authz
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os/exec"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/pkg/errors"
+)
+
+const providerExec = "exec"
+
+type execRequest struct {
+	Method   string              `json:"method"`
+	Path     string              `json:"path"`
+	Query    string              `json:"query,omitempty"`
+	Remote   string              `json:"remote"`
+	Pipeline string              `json:"pipeline,omitempty"`
+	Token    string              `json:"token,omitempty"`
+	Header   map[string][]string `json:"header,omitempty"`
+}
+
+type execResponse struct {
+	Allowed bool   `json:"allowed"`
+	Subject string `json:"subject"`
+	Role    string `json:"role"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// execPlugin delegates the decision to an external command, such as the authorization service of the company
+type execPlugin struct {
+	config *ExecConfig
+}
+
+func newExec(config *ExecConfig) *execPlugin {
+	return &execPlugin{config: config}
+}
+
+func (e *execPlugin) name() string {
+	return providerExec
+}
+
+func (e *execPlugin) authenticate(r *http.Request, token string) (*Principal, bool, error) {
+	header := r.Header.Clone()
+	// the token is passed in the token field
+	header.Del("Authorization")
+	in, err := json.Marshal(&execRequest{
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Query:    r.URL.RawQuery,
+		Remote:   r.RemoteAddr,
+		Pipeline: pipelineOf(r),
+		Token:    token,
+		Header:   header,
+	})
+	if err != nil {
+		return nil, true, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), e.config.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, e.config.Command, e.config.Args...)
+	cmd.Stdin = bytes.NewReader(in)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, true, errors.Errorf("run %s failed: %v, stderr: %s", e.config.Command, err, stderr.String())
+	}
+
+	resp := &execResponse{}
+	if err := json.Unmarshal(out, resp); err != nil {
+		return nil, true, errors.WithMessagef(err, "unmarshal output of %s", e.config.Command)
+	}
+	if !resp.Allowed {
+		reason := resp.Reason
+		if reason == "" {
+			reason = "denied"
+		}
+		return nil, true, errors.New(reason)
+	}
+	return &Principal{Subject: resp.Subject, Role: resp.Role}, true, nil
+}
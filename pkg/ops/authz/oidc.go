@@ -0,0 +1,275 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authz
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/pkg/errors"
+)
+
+const (
+	providerOIDC = "oidc"
+
+	wellKnownPath = "/.well-known/openid-configuration"
+	// minRefreshInterval limits the refreshing of the keys caused by the tokens with unknown key ids
+	minRefreshInterval = time.Minute
+	maxDocumentBytes   = 1 << 20
+)
+
+var validMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// oidc validates the id tokens or access tokens in jwt issued by the identity provider,
+// the signing keys are fetched from the jwks url and refreshed periodically
+type oidc struct {
+	config *OIDCConfig
+	client *http.Client
+
+	lock      sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+func newOIDC(config *OIDCConfig) *oidc {
+	return &oidc{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+func (o *oidc) name() string {
+	return providerOIDC
+}
+
+func (o *oidc) authenticate(_ *http.Request, token string) (*Principal, bool, error) {
+	// not a jwt, it may be a static token or for the exec plugin
+	if strings.Count(token, ".") != 2 {
+		return nil, false, nil
+	}
+
+	parser := &jwt.Parser{ValidMethods: validMethods}
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(token, claims, o.keyFunc); err != nil {
+		return nil, true, err
+	}
+
+	now := jwt.TimeFunc().Unix()
+	if !claims.VerifyExpiresAt(now, true) {
+		return nil, true, errors.New("token is expired or without exp")
+	}
+	if !claims.VerifyIssuer(o.config.Issuer, true) {
+		return nil, true, errors.Errorf("issuer %v is not trusted", claims["iss"])
+	}
+	if o.config.Audience != "" && !claims.VerifyAudience(o.config.Audience, true) {
+		return nil, true, errors.Errorf("audience %v is not accepted", claims["aud"])
+	}
+
+	subject, _ := claims[o.config.SubjectClaim].(string)
+	if subject == "" {
+		return nil, true, errors.Errorf("claim %s is missing", o.config.SubjectClaim)
+	}
+	return &Principal{Subject: subject, Role: o.role(claims[o.config.RoleClaim])}, true, nil
+}
+
+// role returns the most privileged role of the claim values
+func (o *oidc) role(claim interface{}) string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []interface{}:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	role := ""
+	for _, v := range values {
+		if mapped, ok := o.config.RoleMapping[v]; ok {
+			v = mapped
+		}
+		if v == RoleAdmin {
+			return RoleAdmin
+		}
+		if v == RoleViewer {
+			role = RoleViewer
+		}
+	}
+	return role
+}
+
+func (o *oidc) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	key, ok := o.keys[kid]
+	expired := time.Since(o.fetchedAt) > o.config.RefreshInterval
+	// the keys may be rotated, refresh when the key id is unknown
+	if (!ok && time.Since(o.fetchedAt) > minRefreshInterval) || expired {
+		if err := o.refresh(); err != nil {
+			if ok {
+				// the cached key is still used when the identity provider is unavailable
+				return key, nil
+			}
+			return nil, err
+		}
+		key, ok = o.keys[kid]
+	}
+	if !ok {
+		return nil, errors.Errorf("signing key %q is not found", kid)
+	}
+	return key, nil
+}
+
+type discoveryDocument struct {
+	JWKSUri string `json:"jwks_uri"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (o *oidc) refresh() error {
+	o.fetchedAt = time.Now()
+
+	jwksUrl := o.config.JWKSUrl
+	if jwksUrl == "" {
+		doc := &discoveryDocument{}
+		if err := o.get(strings.TrimSuffix(o.config.Issuer, "/")+wellKnownPath, doc); err != nil {
+			return errors.WithMessage(err, "discover openid configuration")
+		}
+		if doc.JWKSUri == "" {
+			return errors.New("jwks_uri is missing in the openid configuration")
+		}
+		jwksUrl = doc.JWKSUri
+	}
+
+	set := &jsonWebKeySet{}
+	if err := o.get(jwksUrl, set); err != nil {
+		return errors.WithMessage(err, "fetch jwks")
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			// the keys of the other types are ignored
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	o.keys = keys
+	return nil
+}
+
+func (o *oidc) get(url string, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), o.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentBytes))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("curve %s is not supported", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("key type %s is not supported", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
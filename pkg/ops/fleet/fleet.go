@@ -0,0 +1,336 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fleet
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	HandleInventory = "/api/v1/fleet/inventory"
+	HandleAgents    = "/api/v1/fleet/agents"
+
+	StatusStale       = "stale"
+	StatusOutdated    = "outdated"
+	StatusConfigDrift = "configDrift"
+	StatusErrors      = "errors"
+
+	maxRecordBytes = 1 << 20
+)
+
+// Config of the fleet view on the aggregator, the agents report to it by the inventory listener
+type Config struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// ExpectedVersion flags the agents of the other versions as outdated,
+	// otherwise the agents older than the newest version in the fleet are flagged
+	ExpectedVersion string `yaml:"expectedVersion,omitempty"`
+	// StalePeriods flags the agents which have not reported for the periods as stale
+	StalePeriods int `yaml:"stalePeriods,omitempty" default:"3" validate:"gte=1"`
+	// Retention removes the agents which have not reported for the duration
+	Retention time.Duration `yaml:"retention,omitempty" default:"24h"`
+	// DriftLabels group the agents by the fleet labels, the agents whose config differs from the most common one
+	// in the group are flagged, since the agents of a group are expected to run the same pipelines
+	DriftLabels []string `yaml:"driftLabels,omitempty" default:"[\"cluster\",\"env\"]"`
+}
+
+// Agent is the latest record of an agent and its status in the fleet
+type Agent struct {
+	Record
+	ReceivedAt time.Time `json:"receivedAt"`
+	RemoteAddr string    `json:"remoteAddr"`
+	Status     []string  `json:"status,omitempty"`
+}
+
+type View struct {
+	Total int `json:"total"`
+	// Versions counts the agents of each version
+	Versions map[string]int `json:"versions"`
+	// Flagged counts the agents of each status
+	Flagged map[string]int `json:"flagged"`
+	Agents  []Agent        `json:"agents"`
+}
+
+type Store struct {
+	config *Config
+	lock   sync.Mutex
+	agents map[string]*Agent
+}
+
+func NewStore(config *Config) *Store {
+	return &Store{
+		config: config,
+		agents: make(map[string]*Agent),
+	}
+}
+
+func Setup(config *Config) {
+	if !config.Enabled {
+		return
+	}
+	s := NewStore(config)
+	http.HandleFunc(HandleInventory, s.inventoryHandler)
+	http.HandleFunc(HandleAgents, s.agentsHandler)
+	log.Info("fleet view is enabled, the agents could report to %s", HandleInventory)
+}
+
+// Report replaces the previous record of the agent
+func (s *Store) Report(r Record, remoteAddr string) error {
+	if r.Node == "" {
+		return errors.New("node of the record is required")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.agents[r.Node] = &Agent{
+		Record:     r,
+		ReceivedAt: time.Now(),
+		RemoteAddr: remoteAddr,
+	}
+	return nil
+}
+
+// View returns the agents sorted by the node names, the agents exceeding the retention are removed
+func (s *Store) View() *View {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	agents := make([]Agent, 0, len(s.agents))
+	for node, a := range s.agents {
+		if s.config.Retention > 0 && now.Sub(a.ReceivedAt) > s.config.Retention {
+			delete(s.agents, node)
+			continue
+		}
+		agents = append(agents, *a)
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		return agents[i].Node < agents[j].Node
+	})
+
+	view := &View{
+		Total:    len(agents),
+		Versions: make(map[string]int),
+		Flagged:  make(map[string]int),
+	}
+	newest := newestVersion(agents)
+	majority := s.majorityConfigs(agents)
+	for i := range agents {
+		a := &agents[i]
+		view.Versions[a.Version]++
+
+		var status []string
+		if a.Interval > 0 && now.Sub(a.ReceivedAt) > time.Duration(s.config.StalePeriods)*a.Interval {
+			status = append(status, StatusStale)
+		}
+		if s.outdated(a.Version, newest) {
+			status = append(status, StatusOutdated)
+		}
+		if hash, ok := majority[s.group(a)]; ok && a.ConfigHash != hash {
+			status = append(status, StatusConfigDrift)
+		}
+		if a.Errors.Count > 0 {
+			status = append(status, StatusErrors)
+		}
+		for _, st := range status {
+			view.Flagged[st]++
+		}
+		a.Status = status
+	}
+	view.Agents = agents
+	return view
+}
+
+// outdated compares the version with the expected version, the versions other than it and not newer are outdated.
+// Without the expected version, the versions older than the newest one in the fleet are outdated,
+// and the versions which could not be parsed are not flagged, such as the development builds.
+func (s *Store) outdated(version string, newest string) bool {
+	if s.config.ExpectedVersion != "" {
+		return version != s.config.ExpectedVersion && !newer(version, s.config.ExpectedVersion)
+	}
+	return newer(newest, version)
+}
+
+func (s *Store) group(a *Agent) string {
+	values := make([]string, 0, len(s.config.DriftLabels))
+	for _, l := range s.config.DriftLabels {
+		values = append(values, a.Labels[l])
+	}
+	return strings.Join(values, "/")
+}
+
+// majorityConfigs returns the most common config hash of each group, the groups with a single agent or a tie are skipped
+func (s *Store) majorityConfigs(agents []Agent) map[string]string {
+	counts := make(map[string]map[string]int)
+	for i := range agents {
+		g := s.group(&agents[i])
+		if counts[g] == nil {
+			counts[g] = make(map[string]int)
+		}
+		counts[g][agents[i].ConfigHash]++
+	}
+
+	majority := make(map[string]string)
+	for g, hashes := range counts {
+		if len(hashes) < 2 {
+			continue
+		}
+		best, bestCount, tie := "", 0, false
+		for hash, count := range hashes {
+			switch {
+			case count > bestCount:
+				best, bestCount, tie = hash, count, false
+			case count == bestCount:
+				tie = true
+			}
+		}
+		if !tie {
+			majority[g] = best
+		}
+	}
+	return majority
+}
+
+// newestVersion returns the newest semantic version of the agents, the versions which could not be parsed are ignored
+func newestVersion(agents []Agent) string {
+	newest := ""
+	for _, a := range agents {
+		if _, ok := parseVersion(a.Version); !ok {
+			continue
+		}
+		if newest == "" || newer(a.Version, newest) {
+			newest = a.Version
+		}
+	}
+	return newest
+}
+
+// newer returns whether the version a is newer than b, false if any of them could not be parsed
+func newer(a string, b string) bool {
+	va, ok := parseVersion(a)
+	if !ok {
+		return false
+	}
+	vb, ok := parseVersion(b)
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+// parseVersion parses the versions such as v1.4.0 or 1.4.0-rc1, the pre-release and build parts are ignored
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, false
+	}
+	parts := strings.Split(v, ".")
+	out := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		out = append(out, n)
+	}
+	return out, true
+}
+
+func (s *Store) inventoryHandler(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(request.Body, maxRecordBytes))
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	record := Record{}
+	if err := json.Unmarshal(body, &record); err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	if err := s.Report(record, request.RemoteAddr); err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// agentsHandler shows the fleet view, the agents could be filtered by the status, such as ?status=outdated,configDrift
+func (s *Store) agentsHandler(writer http.ResponseWriter, request *http.Request) {
+	view := s.View()
+	if filter := request.URL.Query().Get("status"); filter != "" {
+		wanted := strings.Split(filter, ",")
+		agents := make([]Agent, 0)
+		for _, a := range view.Agents {
+			if hasAny(a.Status, wanted) {
+				agents = append(agents, a)
+			}
+		}
+		view.Agents = agents
+	}
+
+	out, err := json.Marshal(view)
+	if err != nil {
+		log.Warn("marshal fleet view err: %v", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(out)
+}
+
+func hasAny(status []string, wanted []string) bool {
+	for _, s := range status {
+		for _, w := range wanted {
+			if s == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fleet
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+func newTestStore() *Store {
+	return NewStore(&Config{
+		Enabled:      true,
+		StalePeriods: 3,
+		Retention:    time.Hour,
+		DriftLabels:  []string{"cluster"},
+	})
+}
+
+func record(node string, version string, cluster string, configHash string) Record {
+	return Record{
+		Node:       node,
+		Version:    version,
+		Labels:     map[string]string{"cluster": cluster},
+		Interval:   time.Minute,
+		ConfigHash: configHash,
+	}
+}
+
+func statusOf(view *View) map[string][]string {
+	status := make(map[string][]string)
+	for _, a := range view.Agents {
+		status[a.Node] = a.Status
+	}
+	return status
+}
+
+func TestStore_View(t *testing.T) {
+	s := newTestStore()
+	assert.NoError(t, s.Report(record("node-1", "v1.5.0", "prod", "aaa"), ""))
+	assert.NoError(t, s.Report(record("node-2", "v1.5.0", "prod", "aaa"), ""))
+	assert.NoError(t, s.Report(record("node-3", "v1.4.2", "prod", "bbb"), ""))
+	// a single agent in the group is never drifted
+	assert.NoError(t, s.Report(record("node-4", "v1.5.0-rc1", "test", "ccc"), ""))
+	failing := record("node-5", "unknown", "test", "ccc")
+	failing.Errors = ErrorSummary{Count: 2, Recent: []string{"start pipeline error"}}
+	assert.NoError(t, s.Report(failing, ""))
+	assert.NoError(t, s.Report(record("node-6", "v1.5.0", "dev", "ddd"), ""))
+	assert.Error(t, s.Report(Record{}, ""))
+
+	s.agents["node-6"].ReceivedAt = time.Now().Add(-5 * time.Minute)
+	s.agents["node-7"] = &Agent{Record: record("node-7", "v1.5.0", "dev", "ddd"), ReceivedAt: time.Now().Add(-2 * time.Hour)}
+
+	view := s.View()
+	assert.Equal(t, 6, view.Total)
+	assert.Equal(t, map[string][]string{
+		"node-1": nil,
+		"node-2": nil,
+		"node-3": {StatusOutdated, StatusConfigDrift},
+		"node-4": nil,
+		"node-5": {StatusErrors},
+		"node-6": {StatusStale},
+	}, statusOf(view))
+	assert.Equal(t, map[string]int{StatusOutdated: 1, StatusConfigDrift: 1, StatusErrors: 1, StatusStale: 1}, view.Flagged)
+	assert.Equal(t, 3, view.Versions["v1.5.0"])
+	_, ok := s.agents["node-7"]
+	assert.False(t, ok, "agents exceeding the retention should be removed")
+
+	// the expected version flags the newer versions as well as the unparsed ones
+	s.config.ExpectedVersion = "v1.4.2"
+	status := statusOf(s.View())
+	assert.Equal(t, []string{StatusConfigDrift}, status["node-3"])
+	assert.Nil(t, status["node-1"])
+	assert.Equal(t, []string{StatusOutdated, StatusErrors}, status["node-5"])
+}
+
+func TestNewer(t *testing.T) {
+	assert.True(t, newer("v1.10.0", "v1.9.3"))
+	assert.True(t, newer("1.5.1", "v1.5"))
+	assert.False(t, newer("v1.5.0", "v1.5"))
+	assert.False(t, newer("v1.5.0-rc1", "v1.5.0"))
+	assert.False(t, newer("unknown", "v1.5.0"))
+}
+
+func TestStore_Handlers(t *testing.T) {
+	s := newTestStore()
+
+	body, err := json.Marshal(record("node-1", "v1.5.0", "prod", "aaa"))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.inventoryHandler(w, httptest.NewRequest(http.MethodPost, HandleInventory, bytes.NewReader(body)))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	body, err = json.Marshal(record("node-2", "v1.4.0", "prod", "aaa"))
+	assert.NoError(t, err)
+	w = httptest.NewRecorder()
+	s.inventoryHandler(w, httptest.NewRequest(http.MethodPost, HandleInventory, bytes.NewReader(body)))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	w = httptest.NewRecorder()
+	s.inventoryHandler(w, httptest.NewRequest(http.MethodPost, HandleInventory, bytes.NewReader([]byte(`{"version": "v1"}`))))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	s.inventoryHandler(w, httptest.NewRequest(http.MethodGet, HandleInventory, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = httptest.NewRecorder()
+	s.agentsHandler(w, httptest.NewRequest(http.MethodGet, HandleAgents+"?status=outdated,stale", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	view := &View{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), view))
+	assert.Equal(t, 2, view.Total)
+	assert.Len(t, view.Agents, 1)
+	assert.Equal(t, "node-2", view.Agents[0].Node)
+	assert.Equal(t, "192.0.2.1:1234", view.Agents[0].RemoteAddr)
+}
+
+func TestHashPipelines(t *testing.T) {
+	a := HashPipelines([]Pipeline{{Name: "a", ConfigHash: "1"}, {Name: "b", ConfigHash: "2"}})
+	b := HashPipelines([]Pipeline{{Name: "b", ConfigHash: "2"}, {Name: "a", ConfigHash: "1"}})
+	c := HashPipelines([]Pipeline{{Name: "a", ConfigHash: "1"}, {Name: "b", ConfigHash: "3"}})
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+}
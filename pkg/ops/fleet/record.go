@@ -0,0 +1,80 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fleet collects the inventory records reported by the agents on the aggregator, and shows the fleet view,
+// so the outdated or misconfigured agents could be found in a single place.
+package fleet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Record is the inventory of an agent, which is reported periodically by the inventory listener
+type Record struct {
+	Node     string   `json:"node"`
+	Hostname string   `json:"hostname,omitempty"`
+	IPs      []string `json:"ips,omitempty"`
+	Version  string   `json:"version"`
+	// Labels are the fleet labels of the agent, such as cluster: prod
+	Labels     map[string]string `json:"labels,omitempty"`
+	StartTime  time.Time         `json:"startTime"`
+	ReportTime time.Time         `json:"reportTime"`
+	// Interval is the reporting period, the agent is regarded as stale when it is missing for several periods
+	Interval time.Duration `json:"interval"`
+	// ConfigHash is the hash of all the pipeline configs
+	ConfigHash string       `json:"configHash"`
+	Pipelines  []Pipeline   `json:"pipelines"`
+	Errors     ErrorSummary `json:"errors"`
+}
+
+type Pipeline struct {
+	Name       string `json:"name"`
+	ConfigHash string `json:"configHash,omitempty"`
+	// Components are in the form of category/type:name, such as source/file:access
+	Components []string  `json:"components,omitempty"`
+	StartTime  time.Time `json:"startTime"`
+}
+
+// ErrorSummary is the errors logged in the last period
+type ErrorSummary struct {
+	Count int `json:"count"`
+	// Recent are the distinct messages of the latest errors
+	Recent   []string  `json:"recent,omitempty"`
+	LastTime time.Time `json:"lastTime,omitempty"`
+}
+
+// HashPipelines hashes the config hashes of the pipelines in the order of names
+func HashPipelines(pipelines []Pipeline) string {
+	sorted := make([]Pipeline, len(pipelines))
+	copy(sorted, pipelines)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var sb strings.Builder
+	for _, p := range sorted {
+		sb.WriteString(p.Name)
+		sb.WriteString("=")
+		sb.WriteString(p.ConfigHash)
+		sb.WriteString("\n")
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"net/http"
+
+	"github.com/loggie-io/loggie/pkg/core/health"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	HandleHealth = "/health"
+)
+
+// HealthHandler reports the readiness of each component, responds 503 if any of them is not ready,
+// which could be used as the readiness probe of kubernetes
+func HealthHandler(writer http.ResponseWriter, request *http.Request) {
+	status := health.Report()
+	out, err := json.Marshal(status)
+	if err != nil {
+		log.Warn("marshal health status failed: %v", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	if status.Ready {
+		writer.WriteHeader(http.StatusOK)
+	} else {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writer.Write(out)
+}
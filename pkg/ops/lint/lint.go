@@ -0,0 +1,139 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/loggie-io/loggie/pkg/control"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+var severityLevel = map[Severity]int{
+	SeverityInfo:    1,
+	SeverityWarning: 2,
+	SeverityError:   3,
+}
+
+// AtLeast reports whether s is as severe as other
+func (s Severity) AtLeast(other Severity) bool {
+	return severityLevel[s] >= severityLevel[other]
+}
+
+// Finding is a problem reported by a rule
+type Finding struct {
+	Rule        string   `json:"rule"`
+	Severity    Severity `json:"severity"`
+	File        string   `json:"file,omitempty"`
+	Pipeline    string   `json:"pipeline,omitempty"`
+	Component   string   `json:"component,omitempty"`
+	Field       string   `json:"field,omitempty"`
+	Message     string   `json:"message"`
+	Explanation string   `json:"explanation,omitempty"`
+}
+
+func (f Finding) String() string {
+	location := f.Pipeline
+	if f.Component != "" {
+		location += " " + f.Component
+	}
+	if f.Field != "" {
+		location += " " + f.Field
+	}
+	if f.File != "" {
+		location = f.File + ": " + location
+	}
+	return fmt.Sprintf("[%s] %s: %s (%s)", f.Severity, location, f.Message, f.Rule)
+}
+
+// Rule checks a pipeline and reports findings without rule name, severity and explanation, which are filled by Lint
+type Rule struct {
+	Name        string
+	Severity    Severity
+	Explanation string
+	Check       func(p *pipeline.Config) []Finding
+}
+
+// LintFile reads pipelines from the file and lints them, pipelines which could not pass the validation are also linted.
+func LintFile(path string, rules []Rule) []Finding {
+	pipes := &control.PipelineConfig{}
+	if err := cfg.UnPackFromFile(path, pipes).Do(); err != nil {
+		return []Finding{{
+			Rule:     RuleInvalidConfig,
+			Severity: SeverityError,
+			File:     path,
+			Message:  err.Error(),
+		}}
+	}
+
+	var findings []Finding
+	if err := pipes.ValidateUniquePipeName(); err != nil {
+		findings = append(findings, Finding{
+			Rule:     RuleInvalidConfig,
+			Severity: SeverityError,
+			File:     path,
+			Message:  err.Error(),
+		})
+	}
+	for i := range pipes.Pipelines {
+		p := &pipes.Pipelines[i]
+		if err := cfg.NewUnpack(nil, p, nil).Defaults().Validate().Do(); err != nil {
+			findings = append(findings, Finding{
+				Rule:     RuleInvalidConfig,
+				Severity: SeverityError,
+				Pipeline: p.Name,
+				Message:  err.Error(),
+			})
+		}
+		findings = append(findings, Lint(p, rules)...)
+	}
+
+	for i := range findings {
+		findings[i].File = path
+	}
+	return findings
+}
+
+// Lint applies the rules to the pipeline
+func Lint(p *pipeline.Config, rules []Rule) []Finding {
+	var findings []Finding
+	for _, r := range rules {
+		for _, f := range r.Check(p) {
+			f.Rule = r.Name
+			if f.Severity == "" {
+				f.Severity = r.Severity
+			}
+			f.Explanation = r.Explanation
+			f.Pipeline = p.Name
+			findings = append(findings, f)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityLevel[findings[i].Severity] > severityLevel[findings[j].Severity]
+	})
+	return findings
+}
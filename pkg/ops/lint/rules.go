@@ -0,0 +1,424 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/interceptor/limit"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util"
+)
+
+const (
+	RuleInvalidConfig           = "invalid-config"
+	RuleUnboundedRegex          = "unbounded-regex"
+	RuleMissingMultiline        = "missing-multiline"
+	RuleHighCardinalityTemplate = "high-cardinality-template"
+	RuleMissingRateLimit        = "missing-rate-limit"
+	RuleDeprecatedField         = "deprecated-field"
+	RuleInterceptorTest         = "interceptor-test"
+
+	fileSourceType = "file"
+)
+
+// DefaultRules returns all the built-in rules
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			Name:        RuleUnboundedRegex,
+			Severity:    SeverityWarning,
+			Explanation: "regular expressions are evaluated against every event, leading or repeated wildcards such as .* make each match scan the whole line and the captured groups ambiguous, anchor the expression and use specific character classes instead",
+			Check:       checkUnboundedRegex,
+		},
+		{
+			Name:        RuleMissingMultiline,
+			Severity:    SeverityWarning,
+			Explanation: "java applications print stack traces over multiple lines, without multi-line aggregation every line of a stack trace becomes a separate event",
+			Check:       checkMissingMultiline,
+		},
+		{
+			Name:        RuleHighCardinalityTemplate,
+			Severity:    SeverityWarning,
+			Explanation: "sink targets such as topics, indices and files are created for each rendered value, rendering them with per pod or per request fields creates unbounded numbers of targets on the backend",
+			Check:       checkHighCardinalityTemplate,
+		},
+		{
+			Name:        RuleMissingRateLimit,
+			Severity:    SeverityInfo,
+			Explanation: "without a rateLimit interceptor a single noisy application could saturate the sink and delay the logs of other pipelines",
+			Check:       checkMissingRateLimit,
+		},
+		{
+			Name:        RuleDeprecatedField,
+			Severity:    SeverityWarning,
+			Explanation: "deprecated fields are kept for compatibility only and may be removed in a future release",
+			Check:       checkDeprecatedField,
+		},
+		{
+			Name:        RuleInterceptorTest,
+			Severity:    SeverityError,
+			Explanation: "the tests of the interceptor feed the sample events through the configured parsing rules, a failed test means the rules do not transform the events as expected any more",
+			Check:       checkInterceptorTests,
+		},
+	}
+}
+
+func componentName(category api.Category, typename string, name string) string {
+	if name == "" {
+		return fmt.Sprintf("%s/%s", category, typename)
+	}
+	return fmt.Sprintf("%s/%s/%s", category, typename, name)
+}
+
+// walk calls fn with every string value in the properties, path is joined by '.'
+func walk(path string, v interface{}, fn func(path string, value string)) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch val := v.(type) {
+	case string:
+		fn(path, val)
+	case cfg.CommonCfg:
+		walk(path, map[string]interface{}(val), fn)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walk(join(k), val[k], fn)
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = item
+		}
+		walk(path, m, fn)
+	case []interface{}:
+		for i, item := range val {
+			walk(fmt.Sprintf("%s[%d]", path, i), item, fn)
+		}
+	case []string:
+		for i, item := range val {
+			fn(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	}
+}
+
+// lastKey returns the last key of the path without the index
+func lastKey(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		path = path[i+1:]
+	}
+	if i := strings.Index(path, "["); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+type component struct {
+	name       string
+	typename   string
+	properties cfg.CommonCfg
+}
+
+func components(p *pipeline.Config) []component {
+	var all []component
+	for _, s := range p.Sources {
+		all = append(all, component{componentName(api.SOURCE, s.Type, s.Name), s.Type, s.Properties})
+	}
+	for _, i := range p.Interceptors {
+		all = append(all, component{componentName(api.INTERCEPTOR, i.Type, i.Name), i.Type, i.Properties})
+	}
+	if p.Sink != nil {
+		all = append(all, component{componentName(api.SINK, p.Sink.Type, p.Sink.Name), p.Sink.Type, p.Sink.Properties})
+	}
+	return all
+}
+
+var regexKeys = map[string]bool{
+	"pattern":      true,
+	"regex":        true,
+	"excludeFiles": true,
+}
+
+var repeatedWildcard = regexp.MustCompile(`\.[*+]`)
+
+func checkUnboundedRegex(p *pipeline.Config) []Finding {
+	var findings []Finding
+	for _, c := range components(p) {
+		walk("", c.properties, func(path string, value string) {
+			if !regexKeys[lastKey(path)] || value == "" {
+				return
+			}
+			// templates and grok patterns are not regular expressions
+			if strings.Contains(value, "${") || strings.Contains(value, "%{") {
+				return
+			}
+
+			f := Finding{Component: c.name, Field: path}
+			if _, err := util.CompilePatternWithJavaStyle(value); err != nil {
+				f.Severity = SeverityError
+				f.Message = fmt.Sprintf("invalid regular expression %q: %v", value, err)
+				findings = append(findings, f)
+				return
+			}
+
+			trimmed := strings.TrimPrefix(value, "^")
+			switch {
+			case strings.HasPrefix(trimmed, ".*") || strings.HasPrefix(trimmed, ".+"):
+				f.Message = fmt.Sprintf("regular expression %q starts with an unbounded wildcard", value)
+			case len(repeatedWildcard.FindAllString(value, -1)) >= 3:
+				f.Message = fmt.Sprintf("regular expression %q contains too many unbounded wildcards", value)
+			case c.typename == fileSourceType && path == "multi.pattern" && !strings.HasPrefix(value, "^"):
+				f.Message = fmt.Sprintf("multi-line pattern %q is not anchored to the beginning of line with ^", value)
+			default:
+				return
+			}
+			findings = append(findings, f)
+		})
+	}
+	return findings
+}
+
+var javaHints = []string{"java", "tomcat", "catalina", "spring", "jboss", "wildfly", "jetty"}
+
+func checkMissingMultiline(p *pipeline.Config) []Finding {
+	var findings []Finding
+	for _, s := range p.Sources {
+		if s.Type != fileSourceType {
+			continue
+		}
+		if multi, ok := s.Properties["multi"]; ok && isActive(multi) {
+			continue
+		}
+
+		var candidates []string
+		candidates = append(candidates, s.Name)
+		walk("", s.Properties["paths"], func(path string, value string) {
+			candidates = append(candidates, value)
+		})
+		for _, c := range candidates {
+			hint := matchHint(strings.ToLower(c))
+			if hint == "" {
+				continue
+			}
+			findings = append(findings, Finding{
+				Component: componentName(api.SOURCE, s.Type, s.Name),
+				Field:     "multi",
+				Message:   fmt.Sprintf("source looks like a %s application (%s) but multi-line is not active", hint, c),
+			})
+			break
+		}
+	}
+	return findings
+}
+
+func matchHint(s string) string {
+	for _, h := range javaHints {
+		if strings.Contains(s, h) {
+			return h
+		}
+	}
+	return ""
+}
+
+func isActive(multi interface{}) bool {
+	var active bool
+	walkAny(multi, func(key string, v interface{}) {
+		if key == "active" {
+			active, _ = v.(bool)
+		}
+	})
+	return active
+}
+
+// walkAny calls fn with every key and value of a map
+func walkAny(v interface{}, fn func(key string, v interface{})) {
+	switch val := v.(type) {
+	case cfg.CommonCfg:
+		for k, item := range val {
+			fn(k, item)
+		}
+	case map[string]interface{}:
+		for k, item := range val {
+			fn(k, item)
+		}
+	case map[interface{}]interface{}:
+		for k, item := range val {
+			fn(fmt.Sprint(k), item)
+		}
+	}
+}
+
+// sink fields which become the name of a created target
+var targetKeys = map[string]bool{
+	"topic":        true,
+	"index":        true,
+	"defaultIndex": true,
+	"filename":     true,
+	"tag":          true,
+	"logstore":     true,
+}
+
+var (
+	templateVar = regexp.MustCompile(`\${([^}]+)}`)
+	separators  = strings.NewReplacer(".", " ", "_", " ", "-", " ", "[", " ", "]", " ")
+
+	highCardinalityTokens = map[string]bool{
+		"uid":    true,
+		"ip":     true,
+		"offset": true,
+	}
+	highCardinalityNames = []string{"podname", "podip", "poduid", "containerid", "traceid", "requestid", "spanid", "sessionid"}
+)
+
+func isHighCardinality(variable string) bool {
+	// time format such as ${+YYYY.MM.DD}
+	if strings.HasPrefix(variable, "+") {
+		return false
+	}
+	tokens := strings.Fields(separators.Replace(strings.ToLower(variable)))
+	for _, t := range tokens {
+		if highCardinalityTokens[t] {
+			return true
+		}
+	}
+	joined := strings.Join(tokens, "")
+	for _, n := range highCardinalityNames {
+		if strings.Contains(joined, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkHighCardinalityTemplate(p *pipeline.Config) []Finding {
+	if p.Sink == nil {
+		return nil
+	}
+	var findings []Finding
+	walk("", p.Sink.Properties, func(path string, value string) {
+		if !targetKeys[lastKey(path)] {
+			return
+		}
+		for _, m := range templateVar.FindAllStringSubmatch(value, -1) {
+			if !isHighCardinality(m[1]) {
+				continue
+			}
+			findings = append(findings, Finding{
+				Component: componentName(api.SINK, p.Sink.Type, p.Sink.Name),
+				Field:     path,
+				Message:   fmt.Sprintf("%s is rendered with high cardinality variable ${%s}", value, m[1]),
+			})
+		}
+	})
+	return findings
+}
+
+func checkMissingRateLimit(p *pipeline.Config) []Finding {
+	for _, i := range p.Interceptors {
+		if i.Type == limit.Type && (i.Enabled == nil || *i.Enabled) {
+			return nil
+		}
+	}
+	return []Finding{{
+		Field:   "interceptors",
+		Message: fmt.Sprintf("no %s interceptor is configured", limit.Type),
+	}}
+}
+
+type deprecation struct {
+	category    api.Category
+	typename    string
+	path        string
+	replacement string
+}
+
+var deprecations = []deprecation{
+	{api.SOURCE, fileSourceType, "watcher.cleanFiles", "cleanFiles"},
+	{api.SOURCE, fileSourceType, "watcher.fdHoldTimeoutWhenInactive", "fdHoldTimeoutWhenInactive"},
+	{api.SOURCE, fileSourceType, "watcher.fdHoldTimeoutWhenRemove", "fdHoldTimeoutWhenRemove"},
+	{api.SOURCE, fileSourceType, "watcher.readFromTail", "readFromTail"},
+	{api.SOURCE, fileSourceType, "readChanSize", ""},
+	{api.SOURCE, "kafka", "topic", "topics"},
+	{api.SINK, "kafka", "sasl.userName", "sasl.username"},
+	{api.SINK, "elasticsearch", "gzip", "compress"},
+	{api.INTERCEPTOR, "normalize", "", "interceptor transformer"},
+}
+
+func hasPath(properties cfg.CommonCfg, path string) bool {
+	var v interface{} = properties
+	for _, key := range strings.Split(path, ".") {
+		found := false
+		walkAny(v, func(k string, item interface{}) {
+			if k == key {
+				v = item
+				found = true
+			}
+		})
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func checkDeprecatedField(p *pipeline.Config) []Finding {
+	var findings []Finding
+	check := func(category api.Category, typename string, name string, properties cfg.CommonCfg) {
+		for _, d := range deprecations {
+			if d.category != category || d.typename != typename {
+				continue
+			}
+			f := Finding{Component: componentName(category, typename, name), Field: d.path}
+			if d.path == "" {
+				f.Message = fmt.Sprintf("%s %s is deprecated", category, typename)
+			} else if hasPath(properties, d.path) {
+				f.Message = fmt.Sprintf("field %s is deprecated", d.path)
+			} else {
+				continue
+			}
+			if d.replacement != "" {
+				f.Message += fmt.Sprintf(", use %s instead", d.replacement)
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	for _, s := range p.Sources {
+		check(api.SOURCE, s.Type, s.Name, s.Properties)
+	}
+	for _, i := range p.Interceptors {
+		check(api.INTERCEPTOR, i.Type, i.Name, i.Properties)
+	}
+	if p.Sink != nil {
+		check(api.SINK, p.Sink.Type, p.Sink.Name, p.Sink.Properties)
+	}
+	return findings
+}
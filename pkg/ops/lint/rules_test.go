@@ -0,0 +1,190 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/control"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/normalize"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/transformer"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/transformer/action"
+	_ "github.com/loggie-io/loggie/pkg/interceptor/transformer/condition"
+)
+
+const pipelines = `
+pipelines:
+  - name: java
+    sources:
+      - type: file
+        name: tomcat
+        paths: ["/var/log/tomcat/*.log"]
+        excludeFiles: [".*\\.gz$"]
+        watcher:
+          readFromTail: true
+      - type: file
+        name: app
+        paths: ["/var/log/app/*.log"]
+        multi:
+          active: true
+          pattern: '\d{4}-\d{2}-\d{2}'
+    interceptors:
+      - type: transformer
+        actions:
+          - action: regex(body)
+            pattern: '(?<ip>\S+) (?<rest>.*)'
+    sink:
+      type: kafka
+      topic: log-${_k8s.pod.name}-${+YYYY.MM.DD}
+      partitionKey: ${_k8s.pod.name}
+      sasl:
+        userName: loggie
+
+  - name: limited
+    sources:
+      - type: file
+        name: nginx
+        paths: ["/var/log/nginx/access.log"]
+    interceptors:
+      - type: rateLimit
+        qps: 1000
+    sink:
+      type: elasticsearch
+      index: nginx-${fields.service}-${+YYYY.MM.DD}
+`
+
+func lintRaw(t *testing.T) map[string][]Finding {
+	pipes := &control.PipelineConfig{}
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(pipelines), pipes).Do())
+
+	out := make(map[string][]Finding)
+	for i := range pipes.Pipelines {
+		p := &pipes.Pipelines[i]
+		for _, f := range Lint(p, DefaultRules()) {
+			out[p.Name+"/"+f.Rule] = append(out[p.Name+"/"+f.Rule], f)
+		}
+	}
+	return out
+}
+
+func TestRules(t *testing.T) {
+	findings := lintRaw(t)
+
+	regex := findings["java/"+RuleUnboundedRegex]
+	if assert.Len(t, regex, 2) {
+		assert.Equal(t, "excludeFiles[0]", regex[0].Field)
+		assert.Equal(t, "multi.pattern", regex[1].Field)
+	}
+
+	multi := findings["java/"+RuleMissingMultiline]
+	if assert.Len(t, multi, 1) {
+		assert.Equal(t, "source/file/tomcat", multi[0].Component)
+	}
+
+	card := findings["java/"+RuleHighCardinalityTemplate]
+	if assert.Len(t, card, 1) {
+		assert.Equal(t, "topic", card[0].Field)
+	}
+
+	assert.Len(t, findings["java/"+RuleMissingRateLimit], 1)
+	assert.Len(t, findings["java/"+RuleDeprecatedField], 2)
+
+	for _, r := range []string{RuleUnboundedRegex, RuleMissingMultiline, RuleHighCardinalityTemplate, RuleMissingRateLimit, RuleDeprecatedField} {
+		assert.Empty(t, findings["limited/"+r], r)
+	}
+}
+
+func TestIsHighCardinality(t *testing.T) {
+	assert.True(t, isHighCardinality("_k8s.pod.name"))
+	assert.True(t, isHighCardinality("fields.traceId"))
+	assert.True(t, isHighCardinality("state.offset"))
+	assert.False(t, isHighCardinality("_k8s.namespace"))
+	assert.False(t, isHighCardinality("+YYYY.MM.DD"))
+	assert.False(t, isHighCardinality("fields.zipcode"))
+}
+
+const testedPipelines = `
+pipelines:
+  - name: tested
+    sources:
+      - type: file
+        paths: ["/var/log/app/*.log"]
+    interceptors:
+      - type: normalize
+        processors:
+          - regex:
+              pattern: '(?<ip>\S+) (?<method>\S+) (?<path>\S+)'
+          - drop:
+              targets: ["method"]
+        tests:
+          - name: access log
+            input:
+              body: '10.0.0.1 GET /index.html'
+            expected:
+              header:
+                ip: 10.0.0.1
+                path: /index.html
+          - name: regressed
+            input:
+              body: '10.0.0.1 GET /index.html'
+              header:
+                fields:
+                  app: web
+            expected:
+              header:
+                ip: 10.0.0.2
+                path: /index.html
+                fields:
+                  app: web
+      - type: transformer
+        actions:
+          - action: add(status, 500)
+          - if: equal(fields.level, DEBUG)
+            then:
+              - action: drop()
+        tests:
+          - input:
+              header:
+                fields:
+                  level: DEBUG
+            dropped: true
+          - input:
+              body: 'error'
+            expected:
+              body: 'error'
+              header:
+                status: 500
+    sink:
+      type: dev
+`
+
+func TestInterceptorTests(t *testing.T) {
+	log.InitDefaultLogger()
+	pipes := &control.PipelineConfig{}
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(testedPipelines), pipes).Do())
+	findings := checkInterceptorTests(&pipes.Pipelines[0])
+
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, "interceptor/normalize", findings[0].Component)
+		assert.Contains(t, findings[0].Message, "test regressed failed: header is")
+		assert.Contains(t, findings[0].Message, `"ip":"10.0.0.2"`)
+	}
+}
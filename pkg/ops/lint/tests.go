@@ -0,0 +1,216 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const testsKey = "tests"
+
+// TestCase is a sample event and the event expected after the interceptor, which is configured in the `tests` of the
+// interceptor alongside its other fields, such as the processors of normalize or the actions of transformer:
+//
+//	tests:
+//	  - name: access log
+//	    input:
+//	      body: '10.0.0.1 GET /index.html'
+//	    expected:
+//	      header:
+//	        ip: 10.0.0.1
+//	        path: /index.html
+//
+// The body and header of the expected event are only compared when set, the header is compared as a whole.
+type TestCase struct {
+	Name     string    `yaml:"name,omitempty"`
+	Input    TestEvent `yaml:"input,omitempty"`
+	Expected TestEvent `yaml:"expected,omitempty"`
+	// Dropped expects the event to be dropped by the interceptor
+	Dropped bool `yaml:"dropped,omitempty"`
+}
+
+type TestEvent struct {
+	Body   *string                `yaml:"body,omitempty"`
+	Header map[string]interface{} `yaml:"header,omitempty"`
+}
+
+type testCases struct {
+	Tests []TestCase `yaml:"tests,omitempty"`
+}
+
+type captureInvoker struct {
+	event api.Event
+}
+
+func (c *captureInvoker) Invoke(invocation source.Invocation) api.Result {
+	c.event = invocation.Event
+	return result.Success()
+}
+
+// checkInterceptorTests runs the tests of the interceptors, the interceptors which could not pass the validation
+// are reported by the invalid config findings and skipped
+func checkInterceptorTests(p *pipeline.Config) []Finding {
+	var findings []Finding
+	for _, ic := range p.Interceptors {
+		if _, ok := ic.Properties[testsKey]; !ok {
+			continue
+		}
+		name := componentName(api.INTERCEPTOR, ic.Type, ic.Name)
+		for _, msg := range runInterceptorTests(p.Name, ic) {
+			findings = append(findings, Finding{
+				Component: name,
+				Field:     testsKey,
+				Message:   msg,
+			})
+		}
+	}
+	return findings
+}
+
+func runInterceptorTests(pipelineName string, ic *interceptor.Config) []string {
+	cases := &testCases{}
+	if err := cfg.UnpackFromCommonCfg(cfg.CommonCfg{testsKey: ic.Properties[testsKey]}, cases).Do(); err != nil {
+		return []string{fmt.Sprintf("unpack tests: %v", err)}
+	}
+
+	component, err := pipeline.GetWithType(api.INTERCEPTOR, api.Type(ic.Type), pipeline.Info{PipelineName: pipelineName})
+	if err != nil {
+		return []string{err.Error()}
+	}
+	icp, ok := component.(source.Interceptor)
+	if !ok {
+		return []string{fmt.Sprintf("interceptor %s does not intercept the events from sources and could not be tested", ic.Type)}
+	}
+
+	properties := ic.Properties.DeepCopy()
+	properties.Remove(testsKey)
+	if err := cfg.UnpackFromCommonCfg(properties, component.Config()).Defaults().Validate().Do(); err != nil {
+		return nil
+	}
+	if err := component.Init(context.NewContext(ic.Name, api.Type(ic.Type), api.INTERCEPTOR, properties)); err != nil {
+		return []string{fmt.Sprintf("init interceptor: %v", err)}
+	}
+	if err := component.Start(); err != nil {
+		return []string{fmt.Sprintf("start interceptor: %v", err)}
+	}
+	defer component.Stop()
+
+	var failures []string
+	for i, c := range cases.Tests {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		if msg := runTestCase(icp, &c); msg != "" {
+			failures = append(failures, fmt.Sprintf("test %s failed: %s", name, msg))
+		}
+	}
+	return failures
+}
+
+// runTestCase returns the difference between the intercepted and the expected event
+func runTestCase(icp source.Interceptor, c *TestCase) string {
+	header, _ := stringKeys(c.Input.Header).(map[string]interface{})
+	if header == nil {
+		header = make(map[string]interface{})
+	}
+	var body []byte
+	if c.Input.Body != nil {
+		body = []byte(*c.Input.Body)
+	}
+	e := event.NewEvent(header, body)
+	e.Fill(event.NewDefaultMeta(), e.Header(), e.Body())
+
+	invoker := &captureInvoker{}
+	res := icp.Intercept(invoker, source.Invocation{Event: e})
+	dropped := res.Status() == api.DROP || invoker.event == nil
+	if res.Status() == api.FAIL {
+		return fmt.Sprintf("interceptor failed: %v", res.Error())
+	}
+	if dropped != c.Dropped {
+		if dropped {
+			return "event is dropped"
+		}
+		return "event is not dropped"
+	}
+	if dropped {
+		return ""
+	}
+
+	out := invoker.event
+	if c.Expected.Body != nil && string(out.Body()) != *c.Expected.Body {
+		return fmt.Sprintf("body is %q, expected %q", string(out.Body()), *c.Expected.Body)
+	}
+	if c.Expected.Header != nil {
+		actual, expected := canonical(out.Header()), canonical(stringKeys(c.Expected.Header))
+		if !reflect.DeepEqual(actual, expected) {
+			a, _ := json.Marshal(actual)
+			x, _ := json.Marshal(expected)
+			return fmt.Sprintf("header is %s, expected %s", a, x)
+		}
+	}
+	return ""
+}
+
+// stringKeys converts the nested maps unmarshalled from yaml to map[string]interface{}, which the interceptors work on
+func stringKeys(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[fmt.Sprint(k)] = stringKeys(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = stringKeys(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = stringKeys(item)
+		}
+		return out
+	}
+	return v
+}
+
+// canonical unifies the types of the values through json, such as the numbers of int and int64
+func canonical(v interface{}) interface{} {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return v
+	}
+	return out
+}
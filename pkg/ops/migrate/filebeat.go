@@ -0,0 +1,589 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/loggie-io/loggie/pkg/util/yaml"
+	"github.com/pkg/errors"
+)
+
+const (
+	FromFilebeat = "filebeat"
+
+	filebeatMessageKey = "message"
+)
+
+var (
+	filebeatFieldRegex = regexp.MustCompile(`%\{\[([^\]]+)\]\}`)
+	filebeatTimeRegex  = regexp.MustCompile(`%\{\+([^}]+)\}`)
+
+	// options which are tuning of filebeat itself, loggie has its own defaults for them
+	filebeatIgnoredInputKeys = map[string]bool{
+		"close_inactive": true, "close_renamed": true, "close_removed": true, "close_eof": true, "close_timeout": true,
+		"close": true, "clean_inactive": true, "clean_removed": true, "scan_frequency": true, "harvester_buffer_size": true,
+		"harvester_limit": true, "backoff": true, "max_backoff": true, "backoff_factor": true, "symlinks": true,
+		"file_identity": true, "take_over": true, "max_bytes": true, "message_max_bytes": true, "buffer_size": true,
+	}
+	filebeatIgnoredTopKeys = map[string]bool{
+		"setup": true, "logging": true, "monitoring": true, "http": true, "path": true, "seccomp": true, "name": true,
+	}
+)
+
+// Filebeat converts a filebeat.yml into a loggie pipeline, with all the inputs as file sources and the output as sink
+func Filebeat(content []byte) (*Result, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal filebeat config")
+	}
+	conf, ok := expand(raw).(map[string]interface{})
+	if !ok {
+		return nil, errors.New("filebeat config should be a map")
+	}
+
+	r := &Result{}
+	p := Pipeline{Name: FromFilebeat}
+
+	globalFields := getMap(conf, "fields")
+	globalUnderRoot := getBool(conf, "fields_under_root")
+	for _, key := range sortedKeys(conf) {
+		switch key {
+		case "filebeat", "processors", "output", "fields", "fields_under_root":
+		default:
+			if !filebeatIgnoredTopKeys[key] {
+				r.unsupported(key, "not converted")
+			}
+		}
+	}
+
+	fb := getMap(conf, "filebeat")
+	for _, key := range sortedKeys(fb) {
+		switch key {
+		case "inputs":
+		case "registry":
+			// loggie keeps the offsets in its own db
+		default:
+			r.unsupported("filebeat."+key, "filebeat modules and autodiscover are not converted, use loggie sources and kubernetes discovery instead")
+		}
+	}
+
+	for i, in := range getList(fb, "inputs") {
+		input, ok := in.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := fmt.Sprintf("filebeat.inputs[%d]", i)
+		src, icps := r.filebeatInput(path, i, input)
+		if src == nil {
+			continue
+		}
+		if len(src.Fields) == 0 && len(globalFields) > 0 {
+			src.Fields = globalFields
+			src.FieldsUnderRoot = globalUnderRoot
+		}
+		p.Sources = append(p.Sources, *src)
+		p.Interceptors = append(p.Interceptors, icps...)
+	}
+	if len(p.Sources) == 0 {
+		return nil, errors.New("no enabled log, filestream or container inputs found in filebeat config")
+	}
+
+	steps, icps := r.filebeatProcessors("processors", getList(conf, "processors"), nil)
+	if len(steps) > 0 {
+		p.Interceptors = append(p.Interceptors, transformer("processors", nil, steps))
+	}
+	p.Interceptors = append(p.Interceptors, icps...)
+
+	p.Sink = r.filebeatOutput(getMap(conf, "output"))
+	r.Pipelines = append(r.Pipelines, p)
+	return r, nil
+}
+
+func (r *Result) filebeatInput(path string, index int, input map[string]interface{}) (*Source, []Interceptor) {
+	typ := getString(input, "type")
+	if typ == "" {
+		typ = "log"
+	}
+	if enabled, ok := input["enabled"].(bool); ok && !enabled {
+		return nil, nil
+	}
+	switch typ {
+	case "log", "filestream", "container":
+	default:
+		r.unsupported(path, "input type %s is not converted", typ)
+		return nil, nil
+	}
+
+	name := getString(input, "id")
+	if name == "" {
+		name = fmt.Sprintf("%s-%d", typ, index)
+	}
+	src := &Source{
+		Type:            fileSourceType,
+		Name:            name,
+		Paths:           getStrings(input, "paths"),
+		Fields:          getMap(input, "fields"),
+		FieldsUnderRoot: getBool(input, "fields_under_root"),
+		IgnoreOlder:     getString(input, "ignore_older"),
+		ReadFromTail:    getBool(input, "tail_files"),
+	}
+	if enc := getString(input, "encoding"); enc != "" && enc != "plain" {
+		src.Charset = enc
+	}
+	src.ExcludeFiles = append(src.ExcludeFiles, getStrings(input, "exclude_files")...)
+
+	var steps []Step
+	for _, key := range sortedKeys(input) {
+		keyPath := path + "." + key
+		switch key {
+		case "type", "id", "enabled", "paths", "fields", "fields_under_root", "ignore_older", "tail_files",
+			"encoding", "exclude_files", "processors":
+		case "exclude_lines":
+			for _, re := range getStrings(input, key) {
+				steps = append(steps, dropIf(condition("match", "body", re)))
+			}
+		case "include_lines":
+			var conds []string
+			for _, re := range getStrings(input, key) {
+				conds = append(conds, "NOT "+condition("match", "body", re))
+			}
+			if len(conds) > 0 {
+				steps = append(steps, dropIf(strings.Join(conds, " AND ")))
+			}
+		case "multiline":
+			src.Multi = r.filebeatMultiline(keyPath, getMap(input, key))
+		case "json":
+			steps = append(steps, r.filebeatJson(keyPath, getMap(input, key))...)
+		case "parsers":
+			for j, p := range getList(input, key) {
+				parser, _ := p.(map[string]interface{})
+				parserPath := fmt.Sprintf("%s[%d]", keyPath, j)
+				if m, ok := parser["multiline"]; ok {
+					multi, _ := m.(map[string]interface{})
+					src.Multi = r.filebeatMultiline(parserPath+".multiline", multi)
+				} else if nd, ok := parser["ndjson"]; ok {
+					ndjson, _ := nd.(map[string]interface{})
+					steps = append(steps, r.filebeatNdjson(parserPath+".ndjson", ndjson)...)
+				} else if _, ok := parser["container"]; ok {
+					r.unsupported(parserPath, "container log format is not decoded, collect the container logs with the loggie kubernetes discovery instead")
+				} else {
+					r.unsupported(parserPath, "parser is not converted")
+				}
+			}
+		case "prospector":
+			src.ExcludeFiles = append(src.ExcludeFiles, getStrings(getMap(getMap(input, key), "scanner"), "exclude_files")...)
+		case "stream", "format":
+			if typ != "container" {
+				r.unsupported(keyPath, "not converted")
+			}
+		default:
+			if !filebeatIgnoredInputKeys[key] {
+				r.unsupported(keyPath, "not converted")
+			}
+		}
+	}
+	if typ == "container" {
+		r.unsupported(path, "container log format is not decoded, collect the container logs with the loggie kubernetes discovery instead")
+	}
+
+	// line filters and parsers run before the processors of the input
+	procSteps, icps := r.filebeatProcessors(path+".processors", getList(input, "processors"), []string{name})
+	steps = append(steps, procSteps...)
+	if len(steps) > 0 {
+		icps = append([]Interceptor{transformer(name, []string{name}, steps)}, icps...)
+	}
+	return src, icps
+}
+
+// filebeatMultiline converts the multiline of pattern type, loggie only supports the pattern which matches the first line of a message
+func (r *Result) filebeatMultiline(path string, m map[string]interface{}) *Multi {
+	if t := getString(m, "type"); t != "" && t != "pattern" {
+		r.unsupported(path, "multiline type %s is not supported", t)
+		return nil
+	}
+	pattern := getString(m, "pattern")
+	if pattern == "" {
+		return nil
+	}
+	match := getString(m, "match")
+	if !getBool(m, "negate") || match != "after" {
+		r.unsupported(path, "only `negate: true` with `match: after` could be converted, the pattern should match the first line of a message")
+		return nil
+	}
+	if _, ok := m["flush_pattern"]; ok {
+		r.unsupported(path+".flush_pattern", "not converted")
+	}
+	return &Multi{
+		Active:   true,
+		Pattern:  pattern,
+		MaxLines: getInt(m, "max_lines"),
+		Timeout:  getString(m, "timeout"),
+	}
+}
+
+func (r *Result) filebeatJson(path string, m map[string]interface{}) []Step {
+	key := getString(m, "message_key")
+	if key != "" {
+		r.unsupported(path+".message_key", "not converted")
+	}
+	if getBool(m, "keys_under_root") {
+		return []Step{action("jsonDecode", "body")}
+	}
+	return []Step{action("jsonDecode", "body", "json")}
+}
+
+func (r *Result) filebeatNdjson(path string, m map[string]interface{}) []Step {
+	field := getString(m, "field")
+	if field == "" {
+		field = filebeatMessageKey
+	}
+	target, ok := m["target"].(string)
+	if !ok || target == "" {
+		return []Step{action("jsonDecode", filebeatField(field))}
+	}
+	return []Step{action("jsonDecode", filebeatField(field), target)}
+}
+
+// filebeatProcessors converts the processors to transformer actions, while the metadata processors are converted to their own interceptors
+func (r *Result) filebeatProcessors(path string, processors []interface{}, belongTo []string) ([]Step, []Interceptor) {
+	var steps []Step
+	var icps []Interceptor
+	for i, p := range processors {
+		processor, ok := p.(map[string]interface{})
+		if !ok || len(processor) != 1 {
+			continue
+		}
+		for typ, c := range processor {
+			conf, _ := c.(map[string]interface{})
+			procPath := fmt.Sprintf("%s[%d].%s", path, i, typ)
+
+			switch typ {
+			case "add_host_metadata":
+				icps = append(icps, Interceptor{
+					Type:     "addHostMeta",
+					BelongTo: belongTo,
+					Properties: map[string]interface{}{
+						"addFields": map[string]string{
+							"name":         "${hostname}",
+							"ip":           "${ip}",
+							"os":           "${os}",
+							"architecture": "${kernelArch}",
+						},
+					},
+				})
+				continue
+			case "add_cloud_metadata":
+				icps = append(icps, Interceptor{Type: "addCloudMeta", BelongTo: belongTo})
+				continue
+			}
+
+			s, err := filebeatProcessor(typ, conf)
+			if err != nil {
+				r.unsupported(procPath, err.Error())
+				continue
+			}
+			cond := ""
+			if w, ok := conf["when"]; ok {
+				cond, err = filebeatCondition(w)
+				if err != nil {
+					r.unsupported(procPath+".when", err.Error())
+					continue
+				}
+			}
+			steps = append(steps, when(cond, s)...)
+		}
+	}
+
+	return steps, icps
+}
+
+func filebeatProcessor(typ string, conf map[string]interface{}) ([]Step, error) {
+	switch typ {
+	case "add_fields":
+		target, ok := conf["target"].(string)
+		if !ok {
+			target = "fields"
+		}
+		fields := flatten(getMap(conf, "fields"))
+		var steps []Step
+		for _, k := range sortedKeys(fields) {
+			key := k
+			if target != "" {
+				key = target + "." + k
+			}
+			value := fmt.Sprint(fields[k])
+			if !argSafe(value) {
+				return nil, errors.Errorf("value of field %s could not be used in transformer actions", k)
+			}
+			steps = append(steps, action("add", key, value))
+		}
+		return steps, nil
+
+	case "drop_fields":
+		fields := getStrings(conf, "fields")
+		if len(fields) == 0 {
+			return nil, nil
+		}
+		for i, f := range fields {
+			fields[i] = filebeatField(f)
+		}
+		return []Step{action("del", fields...)}, nil
+
+	case "rename":
+		var steps []Step
+		for _, f := range getList(conf, "fields") {
+			m, _ := f.(map[string]interface{})
+			steps = append(steps, action("move", filebeatField(getString(m, "from")), filebeatField(getString(m, "to"))))
+		}
+		return steps, nil
+
+	case "copy_fields":
+		var steps []Step
+		for _, f := range getList(conf, "fields") {
+			m, _ := f.(map[string]interface{})
+			steps = append(steps, action("copy", filebeatField(getString(m, "from")), filebeatField(getString(m, "to"))))
+		}
+		return steps, nil
+
+	case "decode_json_fields":
+		target, hasTarget := conf["target"].(string)
+		var steps []Step
+		for _, f := range getStrings(conf, "fields") {
+			key := filebeatField(f)
+			switch {
+			case !hasTarget:
+				steps = append(steps, action("jsonDecode", key, key))
+			case target == "":
+				steps = append(steps, action("jsonDecode", key))
+			default:
+				steps = append(steps, action("jsonDecode", key, target))
+			}
+		}
+		return steps, nil
+
+	case "dissect":
+		tokenizer := getString(conf, "tokenizer")
+		pattern, err := dissectToRegex(tokenizer)
+		if err != nil {
+			return nil, err
+		}
+		field := getString(conf, "field")
+		if field == "" {
+			field = filebeatMessageKey
+		}
+		prefix, ok := conf["target_prefix"].(string)
+		if !ok {
+			prefix = "dissect"
+		}
+		step := action("regex", filebeatField(field))
+		if prefix != "" {
+			step = action("regex", filebeatField(field), prefix)
+		}
+		step.Pattern = pattern
+		return []Step{step}, nil
+
+	case "drop_event":
+		if _, ok := conf["when"]; !ok {
+			return nil, errors.New("drop_event without when condition drops all the events")
+		}
+		return []Step{action("dropEvent")}, nil
+
+	case "add_kubernetes_metadata", "add_docker_metadata":
+		return nil, errors.New("use the loggie kubernetes discovery or addK8sMeta interceptor instead")
+	}
+	return nil, errors.Errorf("processor %s is not converted", typ)
+}
+
+// filebeatCondition converts the condition of processors, loggie conditions could not mix AND with OR
+func filebeatCondition(c interface{}) (string, error) {
+	m, ok := c.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return "", errors.New("condition should have exactly one operator")
+	}
+
+	for op, v := range m {
+		switch op {
+		case "equals", "contains", "regexp":
+			name := map[string]string{"equals": "equal", "contains": "contain", "regexp": "match"}[op]
+			fields, _ := v.(map[string]interface{})
+			fields = flatten(fields)
+			var conds []string
+			for _, f := range sortedKeys(fields) {
+				value := fmt.Sprint(fields[f])
+				if op != "regexp" && !argSafe(value) {
+					return "", errors.Errorf("value of %s could not be used in conditions", f)
+				}
+				conds = append(conds, condition(name, filebeatField(f), value))
+			}
+			return strings.Join(conds, " AND "), nil
+
+		case "has_fields":
+			fields, _ := v.([]interface{})
+			var conds []string
+			for _, f := range fields {
+				conds = append(conds, condition("exist", filebeatField(fmt.Sprint(f))))
+			}
+			return strings.Join(conds, " AND "), nil
+
+		case "not":
+			cond, err := filebeatCondition(v)
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(cond, " AND ") || strings.Contains(cond, " OR ") {
+				return "", errors.New("not with multiple conditions is not supported")
+			}
+			return "NOT " + cond, nil
+
+		case "and", "or":
+			list, _ := v.([]interface{})
+			var conds []string
+			for _, sub := range list {
+				cond, err := filebeatCondition(sub)
+				if err != nil {
+					return "", err
+				}
+				if strings.Contains(cond, " AND ") || strings.Contains(cond, " OR ") {
+					if op == "or" || strings.Contains(cond, " OR ") {
+						return "", errors.New("nested and/or conditions are not supported")
+					}
+				}
+				conds = append(conds, cond)
+			}
+			return strings.Join(conds, " "+strings.ToUpper(op)+" "), nil
+		}
+		return "", errors.Errorf("condition %s is not supported", op)
+	}
+	return "", nil
+}
+
+func (r *Result) filebeatOutput(outputs map[string]interface{}) *Sink {
+	if len(outputs) == 0 {
+		r.unsupported("output", "no output found, a dev sink is used")
+		return &Sink{Type: "dev", Properties: map[string]interface{}{"printEvents": true}}
+	}
+
+	var sink *Sink
+	for _, typ := range sortedKeys(outputs) {
+		conf := getMap(outputs, typ)
+		path := "output." + typ
+		if enabled, ok := conf["enabled"].(bool); ok && !enabled {
+			continue
+		}
+		if sink != nil {
+			r.unsupported(path, "only one output could be enabled")
+			continue
+		}
+
+		props := make(map[string]interface{})
+		known := map[string]bool{"enabled": true}
+		switch typ {
+		case "elasticsearch":
+			protocol := getString(conf, "protocol")
+			var hosts []string
+			for _, h := range getStrings(conf, "hosts") {
+				if !strings.Contains(h, "://") && protocol != "" {
+					h = protocol + "://" + h
+				}
+				hosts = append(hosts, h)
+			}
+			props["hosts"] = hosts
+			setIf(props, "username", getString(conf, "username"))
+			setIf(props, "password", getString(conf, "password"))
+			if key := getString(conf, "api_key"); key != "" {
+				// filebeat takes id:api_key while loggie takes the base64 encoded form
+				if strings.Contains(key, ":") {
+					key = base64.StdEncoding.EncodeToString([]byte(key))
+				}
+				props["apiKey"] = key
+			}
+			setIf(props, "index", filebeatPattern(getString(conf, "index")))
+			if pipeline := getString(conf, "pipeline"); pipeline != "" {
+				props["parameters"] = map[string]string{"pipeline": pipeline}
+			}
+			for _, k := range []string{"hosts", "protocol", "username", "password", "api_key", "index", "pipeline",
+				"worker", "bulk_max_size", "compression_level", "timeout", "backoff"} {
+				known[k] = true
+			}
+
+		case "kafka":
+			props["brokers"] = getStrings(conf, "hosts")
+			setIf(props, "topic", filebeatPattern(getString(conf, "topic")))
+			if c := getString(conf, "compression"); c != "" {
+				props["compression"] = c
+			}
+			for _, k := range []string{"hosts", "topic", "compression", "worker", "bulk_max_size", "timeout", "backoff"} {
+				known[k] = true
+			}
+
+		case "console":
+			props["printEvents"] = true
+			typ = "dev"
+			known["pretty"] = true
+			known["codec"] = true
+
+		default:
+			r.unsupported(path, "output %s is not converted", typ)
+			continue
+		}
+
+		for k := range conf {
+			if !known[k] {
+				r.unsupported(path+"."+k, "not converted")
+			}
+		}
+		sink = &Sink{Type: typ, Properties: props}
+	}
+	return sink
+}
+
+// filebeatPattern converts the format strings such as `filebeat-%{[fields.app]}-%{+yyyy.MM.dd}` to loggie patterns
+func filebeatPattern(s string) string {
+	s = filebeatFieldRegex.ReplaceAllStringFunc(s, func(m string) string {
+		field := filebeatFieldRegex.FindStringSubmatch(m)[1]
+		return "${" + filebeatField(field) + "}"
+	})
+	return filebeatTimeRegex.ReplaceAllString(s, "$${+$1}")
+}
+
+// filebeatField converts the field name, the message of filebeat is the body of loggie
+func filebeatField(f string) string {
+	if f == filebeatMessageKey {
+		return "body"
+	}
+	return f
+}
+
+func setIf(props map[string]interface{}, key string, value string) {
+	if value != "" {
+		props[key] = value
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,663 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	FromFluentBit = "fluentbit"
+
+	fluentBitLogKey = "log"
+
+	sectionService = "SERVICE"
+	sectionInput   = "INPUT"
+	sectionFilter  = "FILTER"
+	sectionOutput  = "OUTPUT"
+	sectionParser  = "PARSER"
+
+	directiveInclude = "@INCLUDE"
+)
+
+var (
+	fluentBitAccessorRegex = regexp.MustCompile(`\['([^']*)'\]`)
+	onigurumaGroupRegex    = regexp.MustCompile(`\(\?<([A-Za-z_])`)
+	strftimeReplacer       = strings.NewReplacer("%Y", "YYYY", "%m", "MM", "%d", "DD", "%H", "hh")
+
+	// options which are tuning of fluent bit itself, loggie has its own defaults for them
+	fluentBitIgnoredKeys = map[string]bool{
+		"name": true, "alias": true, "tag": true, "match": true, "match_regex": true, "db": true, "db.sync": true,
+		"db.locking": true, "db.journal_mode": true, "mem_buf_limit": true, "buffer_chunk_size": true,
+		"buffer_max_size": true, "refresh_interval": true, "rotate_wait": true, "skip_long_lines": true,
+		"skip_empty_lines": true, "storage.type": true, "retry_limit": true, "workers": true, "log_level": true,
+		"storage.total_limit_size": true, "tls.verify": true, "suppress_type_name": true, "buffer_size": true,
+		"trace_output": true, "trace_error": true, "replace_dots": true, "static_files_processed_per_run": true,
+	}
+)
+
+// Section is a section of the fluent bit classic config format, such as [INPUT],
+// the keys of entries are lower cased for they are case insensitive
+type Section struct {
+	Name    string
+	Entries []Entry
+}
+
+type Entry struct {
+	Key   string
+	Value string
+}
+
+func (s *Section) get(key string) string {
+	for _, e := range s.Entries {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return ""
+}
+
+func (s *Section) getAll(key string) []string {
+	var values []string
+	for _, e := range s.Entries {
+		if e.Key == key {
+			values = append(values, e.Value)
+		}
+	}
+	return values
+}
+
+func (s *Section) getBool(key string) bool {
+	switch strings.ToLower(s.get(key)) {
+	case "on", "true", "yes":
+		return true
+	}
+	return false
+}
+
+func (s *Section) alias(index int) string {
+	if a := s.get("alias"); a != "" {
+		return a
+	}
+	return fmt.Sprintf("%s-%d", s.get("name"), index)
+}
+
+// ParseFluentBit parses the classic config format of fluent bit, @INCLUDE directives are returned as sections without entries
+func ParseFluentBit(content []byte) ([]Section, error) {
+	var sections []Section
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			if !strings.HasSuffix(text, "]") {
+				return nil, errors.Errorf("line %d: invalid section %s", line, text)
+			}
+			sections = append(sections, Section{Name: strings.ToUpper(strings.Trim(text, "[]"))})
+			continue
+		}
+		key, value := splitEntry(text)
+		if strings.HasPrefix(key, "@") {
+			sections = append(sections, Section{Name: strings.ToUpper(key), Entries: []Entry{{Key: key, Value: value}}})
+			continue
+		}
+		if len(sections) == 0 {
+			return nil, errors.Errorf("line %d: entry %s is out of any section", line, key)
+		}
+		cur := &sections[len(sections)-1]
+		cur.Entries = append(cur.Entries, Entry{Key: strings.ToLower(key), Value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+func splitEntry(text string) (string, string) {
+	i := strings.IndexAny(text, " \t")
+	if i < 0 {
+		return text, ""
+	}
+	return text[:i], strings.TrimSpace(text[i:])
+}
+
+// LoadFluentBit reads the fluent bit config file, with the files of @INCLUDE and Parsers_File in the [SERVICE] section
+func LoadFluentBit(path string) ([]Section, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := ParseFluentBit(content)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "parse %s", path)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(f string) string {
+		if filepath.IsAbs(f) {
+			return f
+		}
+		return filepath.Join(dir, f)
+	}
+
+	var out []Section
+	for _, s := range sections {
+		var includes []string
+		switch s.Name {
+		case directiveInclude:
+			includes, err = filepath.Glob(resolve(s.Entries[0].Value))
+			if err != nil {
+				return nil, err
+			}
+		case sectionService:
+			for _, f := range s.getAll("parsers_file") {
+				includes = append(includes, resolve(f))
+			}
+			out = append(out, s)
+		default:
+			out = append(out, s)
+		}
+
+		for _, f := range includes {
+			sub, err := LoadFluentBit(f)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, sub...)
+		}
+	}
+	return out, nil
+}
+
+type fluentBitInput struct {
+	source Source
+	tag    string
+	steps  []Step
+}
+
+// FluentBit converts the sections of fluent bit config into loggie pipelines, every output is converted to a
+// pipeline whose sources are the tail inputs matched by the output
+func FluentBit(sections []Section) (*Result, error) {
+	r := &Result{}
+
+	parsers := make(map[string]*Section)
+	for i := range sections {
+		if sections[i].Name == sectionParser {
+			parsers[sections[i].get("name")] = &sections[i]
+		}
+	}
+
+	var inputs []fluentBitInput
+	var filters []*Section
+	var outputs []*Section
+	inputIndex := 0
+	for i := range sections {
+		s := &sections[i]
+		switch s.Name {
+		case sectionInput:
+			if in := r.fluentBitInput(s, inputIndex, parsers); in != nil {
+				inputs = append(inputs, *in)
+			}
+			inputIndex++
+		case sectionFilter:
+			filters = append(filters, s)
+		case sectionOutput:
+			outputs = append(outputs, s)
+		case sectionService, sectionParser:
+		default:
+			r.unsupported(fmt.Sprintf("[%s]", s.Name), "section is not converted")
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, errors.New("no tail inputs found in fluent bit config")
+	}
+
+	matched := make(map[string]bool)
+	for i, o := range outputs {
+		path := fmt.Sprintf("[OUTPUT] %s", o.alias(i))
+		sink := r.fluentBitOutput(path, o)
+		if sink == nil {
+			continue
+		}
+
+		p := Pipeline{Name: o.alias(i), Sink: sink}
+		var inputSteps []Interceptor
+		for _, in := range inputs {
+			if !fluentBitMatch(o, in.tag) {
+				continue
+			}
+			matched[in.source.Name] = true
+			p.Sources = append(p.Sources, in.source)
+			if len(in.steps) > 0 {
+				inputSteps = append(inputSteps, transformer(in.source.Name, []string{in.source.Name}, in.steps))
+			}
+		}
+		if len(p.Sources) == 0 {
+			r.unsupported(path, "no tail inputs match %s", o.get("match"))
+			continue
+		}
+		p.Interceptors = append(p.Interceptors, inputSteps...)
+		p.Interceptors = append(p.Interceptors, r.fluentBitFilters(filters, inputs, p.Sources, parsers)...)
+		r.Pipelines = append(r.Pipelines, p)
+	}
+
+	for _, in := range inputs {
+		if !matched[in.source.Name] {
+			r.unsupported(fmt.Sprintf("[INPUT] %s", in.source.Name), "not matched by any converted output")
+		}
+	}
+	return r, nil
+}
+
+func (r *Result) fluentBitInput(s *Section, index int, parsers map[string]*Section) *fluentBitInput {
+	name := s.get("name")
+	path := fmt.Sprintf("[INPUT] %s", s.alias(index))
+	if name != "tail" {
+		r.unsupported(path, "input %s is not converted", name)
+		return nil
+	}
+
+	in := &fluentBitInput{
+		tag: s.get("tag"),
+		source: Source{
+			Type:         fileSourceType,
+			Name:         s.alias(index),
+			IgnoreOlder:  s.get("ignore_older"),
+			ReadFromTail: !s.getBool("read_from_head"),
+		},
+	}
+	if in.tag == "" {
+		in.tag = fmt.Sprintf("tail.%d", index)
+	}
+
+	for _, e := range s.Entries {
+		keyPath := path + " " + e.Key
+		switch e.Key {
+		case "path":
+			in.source.Paths = append(in.source.Paths, splitList(e.Value)...)
+		case "exclude_path":
+			for _, glob := range splitList(e.Value) {
+				in.source.ExcludeFiles = append(in.source.ExcludeFiles, globToRegex(glob))
+			}
+		case "read_from_head", "ignore_older":
+		case "parser":
+			in.steps = append(in.steps, r.fluentBitParser(keyPath, parsers, e.Value, "body")...)
+		case "multiline":
+		case "parser_firstline":
+			if !s.getBool("multiline") {
+				continue
+			}
+			p, ok := parsers[e.Value]
+			if !ok || p.get("regex") == "" {
+				r.unsupported(keyPath, "regex parser %s is not found", e.Value)
+				continue
+			}
+			// the regex of the first line parser matches the first line of a message, just like the multi pattern
+			in.source.Multi = &Multi{Active: true, Pattern: goRegex(p.get("regex"))}
+		case "multiline.parser":
+			r.unsupported(keyPath, "multiline parsers are not converted, use the multi config of the file source instead")
+		case "path_key":
+			r.unsupported(keyPath, "enable the addonMeta of the file source to get the file name instead")
+		case "key":
+			if e.Value != fluentBitLogKey {
+				r.unsupported(keyPath, "loggie always keeps the line in body")
+			}
+		default:
+			if !fluentBitIgnoredKeys[e.Key] {
+				r.unsupported(keyPath, "not converted")
+			}
+		}
+	}
+	if len(in.source.Paths) == 0 {
+		r.unsupported(path, "path is required")
+		return nil
+	}
+	return in
+}
+
+// fluentBitParser converts a regex or json parser to the transformer actions which parse the key
+func (r *Result) fluentBitParser(path string, parsers map[string]*Section, name string, key string) []Step {
+	p, ok := parsers[name]
+	if !ok {
+		r.unsupported(path, "parser %s is not found, add the parsers file to Parsers_File of [SERVICE]", name)
+		return nil
+	}
+	if p.get("time_key") != "" {
+		r.unsupported(path, "time_key of parser %s is not converted, use the timestamp action of transformer instead", name)
+	}
+	switch strings.ToLower(p.get("format")) {
+	case "regex":
+		step := action("regex", key)
+		step.Pattern = goRegex(p.get("regex"))
+		return []Step{step}
+	case "json":
+		return []Step{action("jsonDecode", key)}
+	}
+	r.unsupported(path, "format %s of parser %s is not converted", p.get("format"), name)
+	return nil
+}
+
+// fluentBitFilters converts the filters matching the sources of a pipeline, belongTo is set when some sources are not matched
+func (r *Result) fluentBitFilters(filters []*Section, inputs []fluentBitInput, sources []Source, parsers map[string]*Section) []Interceptor {
+	inPipeline := make(map[string]bool)
+	for _, s := range sources {
+		inPipeline[s.Name] = true
+	}
+
+	var icps []Interceptor
+	for i, f := range filters {
+		var belongTo []string
+		for _, in := range inputs {
+			if inPipeline[in.source.Name] && fluentBitMatch(f, in.tag) {
+				belongTo = append(belongTo, in.source.Name)
+			}
+		}
+		if len(belongTo) == 0 {
+			continue
+		}
+		if len(belongTo) == len(sources) {
+			belongTo = nil
+		}
+
+		path := fmt.Sprintf("[FILTER] %s", f.alias(i))
+		steps := r.fluentBitFilter(path, f, parsers)
+		if len(steps) > 0 {
+			icps = append(icps, transformer(f.alias(i), belongTo, steps))
+		}
+	}
+	return icps
+}
+
+func (r *Result) fluentBitFilter(path string, f *Section, parsers map[string]*Section) []Step {
+	name := f.get("name")
+	var steps []Step
+	keyValue := func(e Entry) (string, string, bool) {
+		k, v := splitEntry(e.Value)
+		if k == "" || v == "" {
+			r.unsupported(path+" "+e.Key, "key and value are required")
+			return "", "", false
+		}
+		return fluentBitField(k), v, true
+	}
+	addStep := func(e Entry, step Step, args ...string) {
+		for _, a := range args {
+			if !argSafe(a) {
+				r.unsupported(path+" "+e.Key, "%s could not be used in transformer actions", a)
+				return
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	switch name {
+	case "grep":
+		if op := strings.ToLower(f.get("logical_op")); op != "" && op != "legacy" {
+			r.unsupported(path+" logical_op", "not converted, the rules are applied one by one")
+		}
+		for _, e := range f.Entries {
+			switch e.Key {
+			case "regex", "exclude":
+				key, re, ok := keyValue(e)
+				if !ok {
+					continue
+				}
+				cond := condition("match", key, re)
+				if e.Key == "regex" {
+					cond = "NOT " + cond
+				}
+				steps = append(steps, dropIf(cond))
+			case "name", "match", "match_regex", "alias", "logical_op":
+			default:
+				r.unsupported(path+" "+e.Key, "not converted")
+			}
+		}
+
+	case "modify", "record_modifier":
+		for _, e := range f.Entries {
+			switch e.Key {
+			case "set", "add", "record":
+				key, value, ok := keyValue(e)
+				if !ok {
+					continue
+				}
+				step := action("add", key, value)
+				if e.Key == "add" {
+					// add of modify only takes effect when the key does not exist
+					step = Step{If: "NOT " + condition("exist", key), Then: []Step{step}}
+				}
+				addStep(e, step, key, value)
+			case "rename", "hard_rename", "copy", "hard_copy":
+				from, to, ok := keyValue(e)
+				if !ok {
+					continue
+				}
+				act := "move"
+				if strings.HasSuffix(e.Key, "copy") {
+					act = "copy"
+				}
+				addStep(e, action(act, from, fluentBitField(to)), from, to)
+			case "remove", "remove_key":
+				key := fluentBitField(e.Value)
+				addStep(e, action("del", key), key)
+			case "name", "match", "match_regex", "alias":
+			default:
+				r.unsupported(path+" "+e.Key, "not converted")
+			}
+		}
+
+	case "parser":
+		key := fluentBitField(f.get("key_name"))
+		for i, p := range f.getAll("parser") {
+			if i > 0 {
+				r.unsupported(path+" parser", "only the first parser %s is converted", f.getAll("parser")[0])
+				break
+			}
+			steps = append(steps, r.fluentBitParser(path+" parser", parsers, p, key)...)
+		}
+		if f.getBool("reserve_data") {
+			r.unsupported(path+" reserve_data", "the other fields are always kept")
+		}
+
+	case "kubernetes":
+		r.unsupported(path, "use the loggie kubernetes discovery or addK8sMeta interceptor instead")
+
+	default:
+		r.unsupported(path, "filter %s is not converted", name)
+	}
+	return steps
+}
+
+func (r *Result) fluentBitOutput(path string, o *Section) *Sink {
+	name := o.get("name")
+	props := make(map[string]interface{})
+	known := make(map[string]bool)
+	scheme := "http"
+	if o.getBool("tls") {
+		scheme = "https"
+	}
+	known["tls"] = true
+
+	switch name {
+	case "es":
+		if cloud := o.get("cloud_id"); cloud != "" {
+			props["cloudId"] = cloud
+		} else {
+			host := o.get("host")
+			if host == "" {
+				host = "127.0.0.1"
+			}
+			port := o.get("port")
+			if port == "" {
+				port = "9200"
+			}
+			props["hosts"] = []string{fmt.Sprintf("%s://%s:%s%s", scheme, host, port, o.get("path"))}
+		}
+		if auth := o.get("cloud_auth"); auth != "" {
+			user, pass, _ := strings.Cut(auth, ":")
+			props["username"] = user
+			props["password"] = pass
+		}
+		setIf(props, "username", o.get("http_user"))
+		setIf(props, "password", o.get("http_passwd"))
+
+		index := o.get("index")
+		if index == "" {
+			index = "fluent-bit"
+		}
+		if o.getBool("logstash_format") {
+			prefix := o.get("logstash_prefix")
+			if prefix == "" {
+				prefix = "logstash"
+			}
+			layout := o.get("logstash_dateformat")
+			if layout == "" {
+				layout = "%Y.%m.%d"
+			}
+			index = fmt.Sprintf("%s-${+%s}", prefix, strftimeReplacer.Replace(layout))
+		}
+		props["index"] = index
+		if pipeline := o.get("pipeline"); pipeline != "" {
+			props["parameters"] = map[string]string{"pipeline": pipeline}
+		}
+		name = "elasticsearch"
+		for _, k := range []string{"cloud_id", "cloud_auth", "host", "port", "path", "http_user", "http_passwd", "index",
+			"logstash_format", "logstash_prefix", "logstash_dateformat", "pipeline", "type"} {
+			known[k] = true
+		}
+
+	case "kafka":
+		props["brokers"] = splitList(o.get("brokers"))
+		topics := splitList(o.get("topics"))
+		if len(topics) == 0 {
+			topics = []string{"fluent-bit"}
+		}
+		if len(topics) > 1 {
+			r.unsupported(path+" topics", "only the first topic %s is converted, use topic patterns of the kafka sink instead", topics[0])
+		}
+		props["topic"] = topics[0]
+		known["brokers"] = true
+		known["topics"] = true
+
+	case "loki":
+		host := o.get("host")
+		if host == "" {
+			host = "127.0.0.1"
+		}
+		port := o.get("port")
+		if port == "" {
+			port = "3100"
+		}
+		uri := o.get("uri")
+		if uri == "" {
+			uri = "/loki/api/v1/push"
+		}
+		props["url"] = fmt.Sprintf("%s://%s:%s%s", scheme, host, port, uri)
+		setIf(props, "tenantId", o.get("tenant_id"))
+		labels := make(map[string]string)
+		for _, l := range splitList(o.get("labels")) {
+			k, v, ok := strings.Cut(l, "=")
+			if !ok {
+				r.unsupported(path+" labels", "label %s is not converted", l)
+				continue
+			}
+			labels[k] = fluentBitValue(v)
+		}
+		if len(labels) > 0 {
+			props["labels"] = labels
+		}
+		for _, k := range []string{"host", "port", "uri", "tenant_id", "labels"} {
+			known[k] = true
+		}
+
+	case "stdout":
+		name = "dev"
+		props["printEvents"] = true
+		known["format"] = true
+
+	default:
+		r.unsupported(path, "output %s is not converted", name)
+		return nil
+	}
+
+	for _, e := range o.Entries {
+		if !known[e.Key] && !fluentBitIgnoredKeys[e.Key] {
+			r.unsupported(path+" "+e.Key, "not converted")
+		}
+	}
+	return &Sink{Type: name, Properties: props}
+}
+
+// fluentBitMatch reports whether the tag is matched by the Match or Match_Regex of the section
+func fluentBitMatch(s *Section, tag string) bool {
+	if re := s.get("match_regex"); re != "" {
+		matched, err := regexp.MatchString(re, tag)
+		return err == nil && matched
+	}
+	match := s.get("match")
+	if match == "" {
+		return false
+	}
+	pattern := "^" + strings.ReplaceAll(regexp.QuoteMeta(match), `\*`, ".*") + "$"
+	matched, _ := regexp.MatchString(pattern, tag)
+	return matched
+}
+
+// fluentBitField converts the record accessor such as $kubernetes['labels']['app'] to a loggie field name,
+// the log key of fluent bit is the body of loggie
+func fluentBitField(f string) string {
+	f = strings.TrimPrefix(f, "$")
+	f = fluentBitAccessorRegex.ReplaceAllString(f, ".$1")
+	if f == fluentBitLogKey {
+		return "body"
+	}
+	return f
+}
+
+// fluentBitValue converts the record accessor values to loggie patterns, other values are kept as is
+func fluentBitValue(v string) string {
+	if strings.HasPrefix(v, "$") {
+		return "${" + fluentBitField(v) + "}"
+	}
+	return strings.Trim(v, `"`)
+}
+
+// goRegex converts the named groups of oniguruma regex such as (?<name>...) used by fluent bit to the go syntax
+func goRegex(re string) string {
+	return onigurumaGroupRegex.ReplaceAllString(re, "(?P<$1")
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/loggie-io/loggie/pkg/util/yaml"
+)
+
+const (
+	transformerType = "transformer"
+	fileSourceType  = "file"
+)
+
+// Unsupported is a feature of the original config which could not be converted
+type Unsupported struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (u Unsupported) String() string {
+	return fmt.Sprintf("%s: %s", u.Path, u.Message)
+}
+
+// Result is the converted loggie pipelines and the features which are not converted
+type Result struct {
+	Pipelines   []Pipeline    `yaml:"pipelines"`
+	Unsupported []Unsupported `yaml:"-"`
+}
+
+// unsupported records a feature which is not converted, features shared by pipelines are only recorded once
+func (r *Result) unsupported(path string, format string, args ...interface{}) {
+	u := Unsupported{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	}
+	for _, exist := range r.Unsupported {
+		if exist == u {
+			return
+		}
+	}
+	r.Unsupported = append(r.Unsupported, u)
+}
+
+// YAML renders the pipelines as the content of a loggie pipeline config file
+func (r *Result) YAML() ([]byte, error) {
+	return yaml.Marshal(r)
+}
+
+type Pipeline struct {
+	Name         string        `yaml:"name"`
+	Sources      []Source      `yaml:"sources"`
+	Interceptors []Interceptor `yaml:"interceptors,omitempty"`
+	Sink         *Sink         `yaml:"sink,omitempty"`
+}
+
+type Source struct {
+	Type            string                 `yaml:"type"`
+	Name            string                 `yaml:"name"`
+	Paths           []string               `yaml:"paths"`
+	ExcludeFiles    []string               `yaml:"excludeFiles,omitempty"`
+	IgnoreOlder     string                 `yaml:"ignoreOlder,omitempty"`
+	ReadFromTail    bool                   `yaml:"readFromTail,omitempty"`
+	Charset         string                 `yaml:"charset,omitempty"`
+	Multi           *Multi                 `yaml:"multi,omitempty"`
+	Fields          map[string]interface{} `yaml:"fields,omitempty"`
+	FieldsUnderRoot bool                   `yaml:"fieldsUnderRoot,omitempty"`
+}
+
+type Multi struct {
+	Active   bool   `yaml:"active"`
+	Pattern  string `yaml:"pattern"`
+	MaxLines int    `yaml:"maxLines,omitempty"`
+	Timeout  string `yaml:"timeout,omitempty"`
+}
+
+type Interceptor struct {
+	Type       string                 `yaml:"type"`
+	Name       string                 `yaml:"name,omitempty"`
+	BelongTo   []string               `yaml:"belongTo,omitempty"`
+	Actions    []Step                 `yaml:"actions,omitempty"`
+	Properties map[string]interface{} `yaml:",inline"`
+}
+
+// Step is an action of the transformer interceptor, or an if-then workflow when If is set
+type Step struct {
+	Action  string `yaml:"action,omitempty"`
+	Pattern string `yaml:"pattern,omitempty"`
+	If      string `yaml:"if,omitempty"`
+	Then    []Step `yaml:"then,omitempty"`
+}
+
+type Sink struct {
+	Type       string                 `yaml:"type"`
+	Properties map[string]interface{} `yaml:",inline"`
+}
+
+func action(name string, args ...string) Step {
+	return Step{Action: fmt.Sprintf("%s(%s)", name, strings.Join(args, ", "))}
+}
+
+func condition(name string, args ...string) string {
+	return fmt.Sprintf("%s(%s)", name, strings.Join(args, ", "))
+}
+
+// when wraps the steps with a condition, or returns them as is when the condition is empty
+func when(cond string, steps []Step) []Step {
+	if cond == "" || len(steps) == 0 {
+		return steps
+	}
+	return []Step{{If: cond, Then: steps}}
+}
+
+func dropIf(cond string) Step {
+	return Step{If: cond, Then: []Step{action("dropEvent")}}
+}
+
+func transformer(name string, belongTo []string, steps []Step) Interceptor {
+	return Interceptor{
+		Type:     transformerType,
+		Name:     name,
+		BelongTo: belongTo,
+		Actions:  steps,
+	}
+}
+
+// argSafe reports whether the value could be used as an argument of the transformer expressions,
+// which are split by commas
+func argSafe(value string) bool {
+	return !strings.ContainsAny(value, ",()") && strings.TrimSpace(value) == value && value != ""
+}
+
+// globToRegex converts a glob pattern to a regular expression, for the excludeFiles of loggie are regular patterns
+func globToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+var (
+	dissectKeyRegex = regexp.MustCompile(`%\{([^}]*)\}`)
+	groupNameRegex  = regexp.MustCompile(`^\w+$`)
+)
+
+// dissectToRegex converts a dissect tokenizer such as `%{ts} [%{level}] %{msg}` to a regular expression with named groups
+func dissectToRegex(tokenizer string) (string, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	matches := dissectKeyRegex.FindAllStringSubmatchIndex(tokenizer, -1)
+	for i, m := range matches {
+		b.WriteString(regexp.QuoteMeta(tokenizer[last:m[0]]))
+		key := tokenizer[m[2]:m[3]]
+		lazy := "*?"
+		if i == len(matches)-1 && m[1] == len(tokenizer) {
+			lazy = "*"
+		}
+		switch {
+		case key == "" || strings.HasPrefix(key, "?"):
+			// skipped fields
+			b.WriteString(".")
+			b.WriteString(lazy)
+		case strings.ContainsAny(key, "+&*/->"):
+			return "", fmt.Errorf("dissect modifier in %%{%s} is not supported", key)
+		case !groupNameRegex.MatchString(key):
+			return "", fmt.Errorf("dissect key %s could not be used as a group name", key)
+		default:
+			b.WriteString(fmt.Sprintf("(?P<%s>.%s)", key, lazy))
+		}
+		last = m[1]
+	}
+	b.WriteString(regexp.QuoteMeta(tokenizer[last:]))
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// expand converts the maps decoded from yaml to map[string]interface{}, and expands the dotted keys
+// such as `multiline.pattern` to nested maps like filebeat does
+func expand(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{})
+		for k, item := range val {
+			setPath(out, strings.Split(fmt.Sprint(k), "."), expand(item))
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			out = append(out, expand(item))
+		}
+		return out
+	}
+	return v
+}
+
+func setPath(m map[string]interface{}, keys []string, value interface{}) {
+	if len(keys) == 1 {
+		if exist, ok := m[keys[0]].(map[string]interface{}); ok {
+			if sub, ok := value.(map[string]interface{}); ok {
+				for k, v := range sub {
+					setPath(exist, []string{k}, v)
+				}
+				return
+			}
+		}
+		m[keys[0]] = value
+		return
+	}
+	sub, ok := m[keys[0]].(map[string]interface{})
+	if !ok {
+		sub = make(map[string]interface{})
+		m[keys[0]] = sub
+	}
+	setPath(sub, keys[1:], value)
+}
+
+func getMap(m map[string]interface{}, key string) map[string]interface{} {
+	v, _ := m[key].(map[string]interface{})
+	return v
+}
+
+func getList(m map[string]interface{}, key string) []interface{} {
+	v, _ := m[key].([]interface{})
+	return v
+}
+
+func getString(m map[string]interface{}, key string) string {
+	v, ok := m[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// getStrings returns a list of strings, a single string is also accepted
+func getStrings(m map[string]interface{}, key string) []string {
+	switch v := m[key].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			out = append(out, fmt.Sprint(item))
+		}
+		return out
+	}
+	return nil
+}
+
+func getBool(m map[string]interface{}, key string) bool {
+	v, _ := m[key].(bool)
+	return v
+}
+
+func getInt(m map[string]interface{}, key string) int {
+	v, _ := m[key].(int)
+	return v
+}
+
+// flatten is the reverse of expand, which is used where the dotted keys are field names
+func flatten(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for k, v := range m {
+		if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+			for sk, sv := range flatten(sub) {
+				out[k+"."+sk] = sv
+			}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/control"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+)
+
+const filebeatConfig = `
+filebeat.inputs:
+  - type: log
+    paths: ["/var/log/app/*.log"]
+    exclude_lines: ['^DBG']
+    fields:
+      app: web
+    multiline.pattern: '^\d{4}-'
+    multiline.negate: true
+    multiline.match: after
+    processors:
+      - dissect:
+          tokenizer: "%{ts} [%{level}] %{msg}"
+  - type: filestream
+    id: nginx
+    paths: ["/var/log/nginx/access.log"]
+    parsers:
+      - ndjson:
+          target: ""
+      - multiline:
+          pattern: '^\s'
+          negate: false
+          match: after
+  - type: udp
+processors:
+  - add_host_metadata: ~
+  - drop_fields:
+      fields: ["agent", "ecs"]
+  - drop_event:
+      when:
+        and:
+          - equals:
+              log.level: debug
+          - not:
+              has_fields: ["error"]
+  - script:
+      lang: javascript
+output.kafka:
+  hosts: ["kafka:9092"]
+  topic: "log-%{[fields.app]}"
+  required_acks: 1
+`
+
+const fluentBitConfig = `
+[SERVICE]
+    Flush 1
+
+[INPUT]
+    Name   tail
+    Alias  app
+    Path   /var/log/app/*.log, /var/log/app/*.txt
+    Exclude_Path *.gz
+    Tag    app.*
+    Parser nginx
+    Read_from_Head On
+
+[INPUT]
+    Name tail
+    Path /var/log/sys.log
+    Tag  sys
+
+[INPUT]
+    Name cpu
+
+[FILTER]
+    Name    grep
+    Match   app.*
+    Regex   $log ERROR
+    Exclude status 2\d\d
+
+[FILTER]
+    Name   record_modifier
+    Match  *
+    Record cluster prod
+    Remove_key password
+
+[OUTPUT]
+    Name  es
+    Match *
+    Host  es.local
+    tls   On
+    Logstash_Format On
+    Logstash_Prefix app
+
+[OUTPUT]
+    Name  http
+    Match sys
+
+[PARSER]
+    Name   nginx
+    Format regex
+    Regex  ^(?<remote>[^ ]*) (?<code>\d+)$
+`
+
+func unsupportedPaths(r *Result) []string {
+	var paths []string
+	for _, u := range r.Unsupported {
+		paths = append(paths, u.Path)
+	}
+	return paths
+}
+
+func assertLoadable(t *testing.T, r *Result) {
+	out, err := r.YAML()
+	assert.NoError(t, err)
+	pipes := &control.PipelineConfig{}
+	assert.NoError(t, cfg.UnPackFromRaw(out, pipes).Do())
+	assert.Equal(t, len(r.Pipelines), len(pipes.Pipelines))
+}
+
+func TestFilebeat(t *testing.T) {
+	r, err := Filebeat([]byte(filebeatConfig))
+	assert.NoError(t, err)
+	assertLoadable(t, r)
+
+	assert.Len(t, r.Pipelines, 1)
+	p := r.Pipelines[0]
+	assert.Len(t, p.Sources, 2)
+
+	log := p.Sources[0]
+	assert.Equal(t, "log-0", log.Name)
+	assert.Equal(t, map[string]interface{}{"app": "web"}, log.Fields)
+	assert.Equal(t, &Multi{Active: true, Pattern: `^\d{4}-`}, log.Multi)
+	assert.Nil(t, p.Sources[1].Multi)
+
+	assert.Len(t, p.Interceptors, 4)
+	assert.Equal(t, []string{"log-0"}, p.Interceptors[0].BelongTo)
+	assert.Equal(t, []Step{
+		dropIf("match(body, ^DBG)"),
+		{Action: "regex(body, dissect)", Pattern: `^(?P<ts>.*?) \[(?P<level>.*?)\] (?P<msg>.*)$`},
+	}, p.Interceptors[0].Actions)
+	assert.Equal(t, []Step{action("jsonDecode", "body")}, p.Interceptors[1].Actions)
+	assert.Equal(t, []Step{
+		action("del", "agent", "ecs"),
+		{If: "equal(log.level, debug) AND NOT exist(error)", Then: []Step{action("dropEvent")}},
+	}, p.Interceptors[2].Actions)
+	assert.Equal(t, "addHostMeta", p.Interceptors[3].Type)
+
+	assert.Equal(t, "kafka", p.Sink.Type)
+	assert.Equal(t, []string{"kafka:9092"}, p.Sink.Properties["brokers"])
+	assert.Equal(t, "log-${fields.app}", p.Sink.Properties["topic"])
+
+	assert.Equal(t, []string{
+		"filebeat.inputs[1].parsers[1].multiline",
+		"filebeat.inputs[2]",
+		"processors[3].script",
+		"output.kafka.required_acks",
+	}, unsupportedPaths(r))
+}
+
+func TestFilebeatCondition(t *testing.T) {
+	tests := []struct {
+		name    string
+		when    map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "regexp",
+			when: map[string]interface{}{"regexp": map[string]interface{}{"message": "^ERR"}},
+			want: "match(body, ^ERR)",
+		},
+		{
+			name: "or",
+			when: map[string]interface{}{"or": []interface{}{
+				map[string]interface{}{"contains": map[string]interface{}{"message": "timeout"}},
+				map[string]interface{}{"equals": map[string]interface{}{"level": "error"}},
+			}},
+			want: "contain(body, timeout) OR equal(level, error)",
+		},
+		{
+			name: "or with multiple fields",
+			when: map[string]interface{}{"or": []interface{}{
+				map[string]interface{}{"equals": map[string]interface{}{"a": "1", "b": "2"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "range",
+			when:    map[string]interface{}{"range": map[string]interface{}{"code.gte": 500}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filebeatCondition(tt.when)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFilebeatPattern(t *testing.T) {
+	assert.Equal(t, "filebeat-${agent.version}-${+yyyy.MM.dd}", filebeatPattern("filebeat-%{[agent.version]}-%{+yyyy.MM.dd}"))
+	assert.Equal(t, "${body}", filebeatPattern("%{[message]}"))
+}
+
+func TestDissectToRegex(t *testing.T) {
+	re, err := dissectToRegex("%{ip} - %{?ignore} [%{ts}]")
+	assert.NoError(t, err)
+	assert.Equal(t, `^(?P<ip>.*?) - .*? \[(?P<ts>.*?)\]$`, re)
+
+	_, err = dissectToRegex("%{+ts} %{+ts}")
+	assert.Error(t, err)
+}
+
+func TestFluentBit(t *testing.T) {
+	sections, err := ParseFluentBit([]byte(fluentBitConfig))
+	assert.NoError(t, err)
+	r, err := FluentBit(sections)
+	assert.NoError(t, err)
+	assertLoadable(t, r)
+
+	assert.Len(t, r.Pipelines, 1)
+	p := r.Pipelines[0]
+	assert.Equal(t, "es-0", p.Name)
+	assert.Len(t, p.Sources, 2)
+
+	app := p.Sources[0]
+	assert.Equal(t, "app", app.Name)
+	assert.Equal(t, []string{"/var/log/app/*.log", "/var/log/app/*.txt"}, app.Paths)
+	assert.Equal(t, []string{`^[^/]*\.gz$`}, app.ExcludeFiles)
+	assert.False(t, app.ReadFromTail)
+	assert.Equal(t, "tail-1", p.Sources[1].Name)
+	assert.True(t, p.Sources[1].ReadFromTail)
+
+	assert.Len(t, p.Interceptors, 3)
+	assert.Equal(t, []Step{{Action: "regex(body)", Pattern: `^(?P<remote>[^ ]*) (?P<code>\d+)$`}}, p.Interceptors[0].Actions)
+	assert.Equal(t, []string{"app"}, p.Interceptors[1].BelongTo)
+	assert.Equal(t, []Step{
+		dropIf("NOT match(body, ERROR)"),
+		dropIf(`match(status, 2\d\d)`),
+	}, p.Interceptors[1].Actions)
+	assert.Nil(t, p.Interceptors[2].BelongTo)
+	assert.Equal(t, []Step{action("add", "cluster", "prod"), action("del", "password")}, p.Interceptors[2].Actions)
+
+	assert.Equal(t, "elasticsearch", p.Sink.Type)
+	assert.Equal(t, []string{"https://es.local:9200"}, p.Sink.Properties["hosts"])
+	assert.Equal(t, "app-${+YYYY.MM.DD}", p.Sink.Properties["index"])
+
+	assert.Equal(t, []string{
+		"[INPUT] cpu-2",
+		"[OUTPUT] http-1",
+	}, unsupportedPaths(r))
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	stdjson "encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/sink"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+type Options struct {
+	// FailureKey is the header key of the failure metadata added by the dead letter sink
+	FailureKey string
+	// BodyKey is the header key of the body written by the json codec, `message` if beatsFormat is enabled
+	BodyKey string
+	// Pipeline only replays the events failed in this pipeline if not empty
+	Pipeline   string
+	BatchSize  int
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		FailureKey: pipeline.DefaultDeadLetterFailureKey,
+		BodyKey:    event.Body,
+		BatchSize:  100,
+		MaxRetries: 3,
+		Backoff:    time.Second,
+	}
+}
+
+type Stats struct {
+	Replayed int
+	Filtered int
+	Invalid  int
+}
+
+// StartSink starts the sink which the dead letter events are replayed to from a file contains the sink config,
+// the same as the `sink` field of pipeline
+func StartSink(path string) (api.Sink, error) {
+	config := &sink.Config{}
+	if err := cfg.UnPackFromFile(path, config).Defaults().Validate().Do(); err != nil {
+		return nil, errors.WithMessagef(err, "unpack sink config %s", path)
+	}
+	info := pipeline.Info{
+		PipelineName: "replay",
+		SurviveChan:  make(chan api.Batch),
+		Epoch:        pipeline.NewEpoch("replay"),
+		R:            pipeline.NewRegisterCenter(),
+		SinkCount:    1,
+		EventPool:    event.NewDefaultPool(1),
+	}
+	if err := pipeline.ValidateSink(info, config); err != nil {
+		return nil, err
+	}
+	return pipeline.StartSink(info, "replay", config)
+}
+
+// Replay reads the dead letter events written by the json codec line by line,
+// removes the failure metadata and sends them to the sink in batches
+func Replay(r io.Reader, s api.Sink, opts Options) (Stats, error) {
+	stats := Stats{}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1
+	}
+
+	events := make([]api.Event, 0, opts.BatchSize)
+	flush := func() error {
+		if len(events) == 0 {
+			return nil
+		}
+		if err := send(s, events, opts); err != nil {
+			return err
+		}
+		stats.Replayed += len(events)
+		events = make([]api.Event, 0, opts.BatchSize)
+		return nil
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			e, ok := parse(line, opts)
+			if !ok {
+				stats.Invalid++
+			} else if e == nil {
+				stats.Filtered++
+			} else {
+				events = append(events, e)
+				if len(events) >= opts.BatchSize {
+					if err := flush(); err != nil {
+						return stats, err
+					}
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, errors.WithMessage(err, "read dead letter events")
+		}
+	}
+	return stats, flush()
+}
+
+// parse returns false if the line is not a dead letter event, and a nil event if it is filtered
+func parse(line []byte, opts Options) (api.Event, bool) {
+	header := make(map[string]interface{})
+	if err := stdjson.Unmarshal(line, &header); err != nil {
+		return nil, false
+	}
+	failure, ok := header[opts.FailureKey].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if opts.Pipeline != "" && failure[pipeline.FailurePipeline] != opts.Pipeline {
+		return nil, true
+	}
+	delete(header, opts.FailureKey)
+
+	var body []byte
+	if b, ok := header[opts.BodyKey].(string); ok {
+		body = []byte(b)
+		delete(header, opts.BodyKey)
+	}
+	return event.NewEvent(header, body), true
+}
+
+func send(s api.Sink, events []api.Event, opts Options) error {
+	b := batch.NewBatchWithEvents(events)
+	defer batch.ReleaseBatch(b)
+
+	for i := 0; ; i++ {
+		result := s.Consume(b)
+		if result.Status() == api.SUCCESS {
+			return nil
+		}
+		if i >= opts.MaxRetries {
+			return errors.Errorf("replay %d events failed: %v", len(events), result.Error())
+		}
+		time.Sleep(opts.Backoff)
+	}
+}
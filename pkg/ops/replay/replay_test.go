@@ -0,0 +1,90 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/result"
+)
+
+type fakeSink struct {
+	fails   int
+	batches [][]api.Event
+}
+
+func (s *fakeSink) Category() api.Category         { return api.SINK }
+func (s *fakeSink) Type() api.Type                 { return "fake" }
+func (s *fakeSink) String() string                 { return "sink/fake" }
+func (s *fakeSink) Init(context api.Context) error { return nil }
+func (s *fakeSink) Start() error                   { return nil }
+func (s *fakeSink) Stop()                          {}
+func (s *fakeSink) Config() interface{}            { return &struct{}{} }
+
+func (s *fakeSink) Consume(batch api.Batch) api.Result {
+	if s.fails > 0 {
+		s.fails--
+		return result.Fail(nil)
+	}
+	events := make([]api.Event, len(batch.Events()))
+	copy(events, batch.Events())
+	s.batches = append(s.batches, events)
+	return result.Success()
+}
+
+const deadLetters = `{"body":"a","fields":{"app":"web"},"deadLetter":{"pipeline":"local","reason":"retry reaches the limit"}}
+{"body":"b","deadLetter":{"pipeline":"other","reason":"rejected"}}
+
+not json
+{"body":"c","deadLetter":{"pipeline":"local","reason":"rejected"}}
+{"body":"d"}
+{"body":"e","deadLetter":{"pipeline":"local","reason":"rejected"}}`
+
+func TestReplay(t *testing.T) {
+	s := &fakeSink{fails: 1}
+	opts := DefaultOptions()
+	opts.BatchSize = 2
+	opts.Backoff = 0
+	opts.Pipeline = "local"
+
+	stats, err := Replay(strings.NewReader(deadLetters), s, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, Stats{Replayed: 3, Filtered: 1, Invalid: 2}, stats)
+
+	assert.Len(t, s.batches, 2)
+	assert.Len(t, s.batches[0], 2)
+	first := s.batches[0][0]
+	assert.Equal(t, "a", string(first.Body()))
+	assert.Equal(t, map[string]interface{}{"fields": map[string]interface{}{"app": "web"}}, first.Header())
+	assert.Equal(t, "c", string(s.batches[0][1].Body()))
+	assert.Equal(t, "e", string(s.batches[1][0].Body()))
+}
+
+func TestReplayFailed(t *testing.T) {
+	s := &fakeSink{fails: 10}
+	opts := DefaultOptions()
+	opts.MaxRetries = 1
+	opts.Backoff = 0
+
+	stats, err := Replay(strings.NewReader(deadLetters), s, opts)
+	assert.Error(t, err)
+	assert.Equal(t, 0, stats.Replayed)
+}
@@ -0,0 +1,361 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/interceptor"
+	"github.com/loggie-io/loggie/pkg/core/queue"
+	"github.com/loggie-io/loggie/pkg/core/sink"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const (
+	draft = "https://json-schema.org/draft/2020-12/schema"
+
+	// durationPattern matches the durations parsed by time.ParseDuration, such as 30s or 1h30m
+	durationPattern = `^-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$|^0$`
+)
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	timeType        = reflect.TypeOf(time.Time{})
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+// Categories are the component categories of a pipeline
+var Categories = []api.Category{api.SOURCE, api.INTERCEPTOR, api.QUEUE, api.SINK}
+
+// Schema is a subset of JSON Schema draft 2020-12
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Const                interface{}        `json:"const,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	If                   *Schema            `json:"if,omitempty"`
+	Then                 *Schema            `json:"then,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Marshal formats the schema as indented json
+func Marshal(s *Schema) ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// Component returns the schema of the type specific config of a registered component
+func Component(category api.Category, typename api.Type) (s *Schema, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("make component %s/%s: %v", category, typename, r)
+		}
+	}()
+
+	c, err := pipeline.GetWithType(category, typename, info())
+	if err != nil {
+		return nil, err
+	}
+	s = &Schema{Type: "object"}
+	if conf := c.Config(); conf != nil {
+		s = FromType(reflect.TypeOf(conf))
+	}
+	s.Title = fmt.Sprintf("%s/%s", category, typename)
+	return s, nil
+}
+
+// Pipelines returns the schema of the pipelines config file, including all the registered components.
+// The components are dispatched by their type, so editors could complete the fields of each type.
+func Pipelines() (*Schema, error) {
+	root := &Schema{
+		Schema: draft,
+		Title:  "loggie pipelines",
+		Type:   "object",
+		Properties: map[string]*Schema{
+			"pipelines": {Type: "array", Items: ref("pipeline")},
+		},
+		Required: []string{"pipelines"},
+		Defs:     make(map[string]*Schema),
+	}
+
+	p := FromType(reflect.TypeOf(pipeline.Config{}))
+	p.Properties["sources"] = &Schema{Type: "array", Items: ref(string(api.SOURCE))}
+	p.Properties["interceptors"] = &Schema{Type: "array", Items: ref(string(api.INTERCEPTOR))}
+	p.Properties["queue"] = ref(string(api.QUEUE))
+	p.Properties["sink"] = ref(string(api.SINK))
+	root.Defs["pipeline"] = p
+
+	for _, category := range Categories {
+		common := FromType(commonConfigType(category))
+		var types []interface{}
+		for _, typename := range pipeline.RegisteredTypes(category) {
+			s, err := Component(category, typename)
+			if err != nil {
+				return nil, err
+			}
+			name := fmt.Sprintf("%s.%s", category, typename)
+			root.Defs[name] = s
+			types = append(types, string(typename))
+			common.AllOf = append(common.AllOf, &Schema{
+				If: &Schema{
+					Properties: map[string]*Schema{"type": {Const: string(typename)}},
+					Required:   []string{"type"},
+				},
+				Then: ref(name),
+			})
+		}
+		if t, ok := common.Properties["type"]; ok {
+			t.Enum = types
+		}
+		root.Defs[string(category)] = common
+	}
+	return root, nil
+}
+
+// info is used to make the components only for their configs, which are never started
+func info() pipeline.Info {
+	return pipeline.Info{
+		PipelineName: "schema",
+		SurviveChan:  make(chan api.Batch),
+		Epoch:        pipeline.NewEpoch("schema"),
+		R:            pipeline.NewRegisterCenter(),
+		SinkCount:    1,
+		EventPool:    event.NewDefaultPool(1),
+	}
+}
+
+func commonConfigType(category api.Category) reflect.Type {
+	switch category {
+	case api.SOURCE:
+		return reflect.TypeOf(source.Config{})
+	case api.INTERCEPTOR:
+		return reflect.TypeOf(interceptor.Config{})
+	case api.QUEUE:
+		return reflect.TypeOf(queue.Config{})
+	default:
+		return reflect.TypeOf(sink.Config{})
+	}
+}
+
+func ref(def string) *Schema {
+	return &Schema{Ref: "#/$defs/" + def}
+}
+
+// FromType reflects the schema of a config struct by its yaml, default and validate tags
+func FromType(t reflect.Type) *Schema {
+	return fromType(t, make(map[reflect.Type]bool))
+}
+
+func fromType(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		return &Schema{Type: "string", Pattern: durationPattern}
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case reflect.PtrTo(t).Implements(unmarshalerType):
+		// the format is decided by the type itself
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string"}
+		}
+		return &Schema{Type: "array", Items: fromType(t.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: fromType(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return &Schema{Type: "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		addFields(s, t, visiting)
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+func addFields(s *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name, inline, ok := yamlName(f)
+		if !ok {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if inline {
+			switch ft.Kind() {
+			case reflect.Struct:
+				addFields(s, ft, visiting)
+			case reflect.Map:
+				// such as the properties of components, which are defined by their types
+				s.AdditionalProperties = fromType(ft.Elem(), visiting)
+			}
+			continue
+		}
+
+		fs := fromType(f.Type, visiting)
+		// the defaults are filled before validating, so the fields with defaults could be absent
+		if applyTags(fs, f, ft) && fs.Default == nil {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// yamlName returns the key of the field in yaml, the same as yaml.v2 does
+func yamlName(f reflect.StructField) (name string, inline bool, ok bool) {
+	tag := f.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	for _, flag := range parts[1:] {
+		if flag == "inline" {
+			inline = true
+		}
+	}
+	name = parts[0]
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	return name, inline, true
+}
+
+// applyTags sets the default value and the constraints, returns whether the field is required
+func applyTags(s *Schema, f reflect.StructField, t reflect.Type) (required bool) {
+	if d, ok := f.Tag.Lookup("default"); ok {
+		s.Default = parseValue(d, t)
+	}
+
+	for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+		name, param := rule, ""
+		if idx := strings.Index(rule, "="); idx >= 0 {
+			name, param = rule[:idx], rule[idx+1:]
+		}
+		switch name {
+		case "dive":
+			// the rest rules are applied to the elements
+			return required
+		case "required":
+			required = true
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, parseValue(v, t))
+			}
+		case "gte", "gt", "lte", "lt":
+			if s.Type != "integer" && s.Type != "number" {
+				continue
+			}
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			switch name {
+			case "gte":
+				s.Minimum = float(n)
+			case "gt":
+				s.ExclusiveMinimum = float(n)
+			case "lte":
+				s.Maximum = float(n)
+			case "lt":
+				s.ExclusiveMaximum = float(n)
+			}
+		}
+	}
+	return required
+}
+
+// parseValue converts the value in the tags to the type of the field, the raw string is kept when it fails
+func parseValue(v string, t reflect.Type) interface{} {
+	if t == durationType {
+		return v
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		var out interface{}
+		if err := json.Unmarshal([]byte(v), &out); err == nil {
+			return out
+		}
+	}
+	return v
+}
+
+func float(n float64) *float64 {
+	return &n
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	_ "github.com/loggie-io/loggie/pkg/include"
+)
+
+type nested struct {
+	Host string `yaml:"host,omitempty" validate:"required"`
+}
+
+type inlined struct {
+	Order int `yaml:"order,omitempty" default:"900"`
+}
+
+type testConfig struct {
+	inlined  `yaml:",inline"`
+	Mode     string            `yaml:"mode,omitempty" default:"auto" validate:"oneof=auto always never"`
+	Level    int               `yaml:"level,omitempty" default:"5" validate:"oneof=0 5 9"`
+	Workers  int               `yaml:"workers,omitempty" default:"1" validate:"gte=1,lte=100"`
+	Enabled  *bool             `yaml:"enabled,omitempty" default:"true"`
+	Timeout  time.Duration     `yaml:"timeout,omitempty" default:"30s"`
+	Paths    []string          `yaml:"paths,omitempty" validate:"required"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
+	Backends []nested          `yaml:"backends,omitempty" validate:"dive,required"`
+	Ignored  string            `yaml:"-"`
+	private  string
+}
+
+func TestFromType(t *testing.T) {
+	s := FromType(reflect.TypeOf(&testConfig{}))
+
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, []string{"paths"}, s.Required)
+	assert.ElementsMatch(t, []string{"order", "mode", "level", "workers", "enabled", "timeout", "paths", "labels", "backends"}, keys(s.Properties))
+
+	assert.Equal(t, int64(900), s.Properties["order"].Default)
+	assert.Equal(t, []interface{}{"auto", "always", "never"}, s.Properties["mode"].Enum)
+	assert.Equal(t, []interface{}{int64(0), int64(5), int64(9)}, s.Properties["level"].Enum)
+	assert.Equal(t, 1.0, *s.Properties["workers"].Minimum)
+	assert.Equal(t, 100.0, *s.Properties["workers"].Maximum)
+	assert.Equal(t, &Schema{Type: "boolean", Default: true}, s.Properties["enabled"])
+	assert.Equal(t, &Schema{Type: "string", Pattern: durationPattern, Default: "30s"}, s.Properties["timeout"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}, s.Properties["labels"])
+
+	backend := s.Properties["backends"].Items
+	assert.Equal(t, []string{"host"}, backend.Required)
+}
+
+func TestPipelines(t *testing.T) {
+	log.InitDefaultLogger()
+	s, err := Pipelines()
+	assert.NoError(t, err)
+
+	for _, category := range Categories {
+		def, ok := s.Defs[string(category)]
+		assert.True(t, ok)
+		assert.NotEmpty(t, def.Properties["type"].Enum)
+		for _, c := range def.AllOf {
+			_, ok := s.Defs[c.Then.Ref[len("#/$defs/"):]]
+			assert.True(t, ok, c.Then.Ref)
+		}
+	}
+
+	kafka := s.Defs["sink.kafka"]
+	assert.Equal(t, "loggie", kafka.Properties["topic"].Default)
+	assert.Contains(t, s.Defs[string(api.SINK)].Properties, "parallelism")
+
+	_, err = Marshal(s)
+	assert.NoError(t, err)
+}
+
+func keys(m map[string]*Schema) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -39,6 +39,7 @@ func Setup(controller *control.Controller) {
 		controller: controller,
 	}
 	http.HandleFunc(HandleVersion, VersionIns.VersionHandler)
+	http.HandleFunc(HandleHealth, HealthHandler)
 }
 
 func (h *Version) VersionHandler(writer http.ResponseWriter, request *http.Request) {
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/concurrency"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+)
+
+// baselineWeigh lets the baseline follow a latency which keeps higher slowly, such as the batches become larger
+const baselineWeigh = 0.05
+
+// aimd tunes the sink consumers of pipeline by additive increase and multiplicative decrease
+type aimd struct {
+	config *concurrency.Aimd
+	// baseline is the latency in microseconds when the sink is not overloaded
+	baseline float64
+}
+
+func newAimd(config *concurrency.Aimd) *aimd {
+	return &aimd{config: config}
+}
+
+// next returns the target consumers with the latencies and failures of the last interval,
+// it only increases when the queue has pending batches, since more consumers would not help otherwise
+func (a *aimd) next(current int, rtts []int64, failures int, pending bool) int {
+	spiked := false
+	if len(rtts) > 0 {
+		var sum int64
+		for _, rtt := range rtts {
+			sum += rtt
+		}
+		latency := float64(sum) / float64(len(rtts))
+		spiked = a.spiked(latency)
+		if !spiked {
+			a.observe(latency)
+		}
+	}
+
+	target := current
+	if failures > 0 || spiked {
+		target = int(float64(current) * a.config.DecreaseFactor)
+	} else if pending && len(rtts) > 0 {
+		target = current + a.config.Increase
+	}
+
+	if target < a.config.Min {
+		target = a.config.Min
+	}
+	if target > a.config.Max {
+		target = a.config.Max
+	}
+	return target
+}
+
+func (a *aimd) spiked(latency float64) bool {
+	if a.config.MaxLatency > 0 && latency > float64(a.config.MaxLatency.Microseconds()) {
+		return true
+	}
+	return a.baseline > 0 && latency > a.baseline*a.config.LatencyRatio
+}
+
+func (a *aimd) observe(latency float64) {
+	if a.baseline == 0 || latency < a.baseline {
+		a.baseline = latency
+		return
+	}
+	a.baseline += (latency - a.baseline) * baselineWeigh
+}
+
+func (p *Pipeline) startAimdController() {
+	a := newAimd(p.concurrency.Aimd)
+	failedChannel := p.flowPool.GetFailedChannel()
+	q := p.sinkinfo.Queue.OutChan()
+
+	go func() {
+		timer := time.NewTicker(a.config.Interval)
+		defer timer.Stop()
+		failures := 0
+		for {
+			select {
+			case <-p.done:
+				return
+
+			case <-failedChannel:
+				failures++
+
+			case <-timer.C:
+				// the target follows the capacity, the running workers may be fewer while they are idle
+				current := p.gpool.Cap()
+				target := a.next(current, p.flowPool.DequeueAllRtt(), failures, len(q) > 0)
+				failures = 0
+				if target != current {
+					log.Info("pipeline %s tune sink consumers from %d to %d, baseline latency %.0fus", p.name, current, target, a.baseline)
+					failures = p.tuneGPoolDraining(target, failedChannel)
+				}
+				p.publishGoroutinePoolSize()
+			}
+		}
+	}()
+}
+
+// tuneGPoolDraining tunes the goroutine pool while receiving the failed results, otherwise the consumers which
+// report failures would block the pool from being decreased. It returns the failures received.
+func (p *Pipeline) tuneGPoolDraining(target int, failedChannel chan api.Result) int {
+	var failures int32
+	stop := make(chan struct{})
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-failedChannel:
+				atomic.AddInt32(&failures, 1)
+			}
+		}
+	}()
+	p.tuneGPool(target)
+	close(stop)
+	<-drained
+	return int(atomic.LoadInt32(&failures))
+}
+
+func (p *Pipeline) publishGoroutinePoolSize() {
+	for _, source := range p.config.Sources {
+		sinkMetricData := eventbus.SinkMetricData{
+			BaseMetric: eventbus.BaseMetric{
+				PipelineName: p.name,
+				SourceName:   source.Name,
+			},
+			GoroutinePoolSize: p.gpool.Cap(),
+		}
+		eventbus.PublishOrDrop(eventbus.SinkMetricTopic, sinkMetricData)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/concurrency"
+)
+
+func newTestAimd(baseline float64) *aimd {
+	a := newAimd(&concurrency.Aimd{
+		Min:            2,
+		Max:            8,
+		Increase:       1,
+		DecreaseFactor: 0.5,
+		LatencyRatio:   2,
+		MaxLatency:     time.Second,
+	})
+	a.baseline = baseline
+	return a
+}
+
+func TestAimd_next(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseline float64
+		current  int
+		rtts     []int64
+		failures int
+		pending  bool
+		want     int
+	}{
+		{
+			name:    "increase when pending",
+			current: 4,
+			rtts:    []int64{100},
+			pending: true,
+			want:    5,
+		},
+		{
+			name:    "keep when not pending",
+			current: 4,
+			rtts:    []int64{100},
+			want:    4,
+		},
+		{
+			name:    "keep without latencies",
+			current: 4,
+			pending: true,
+			want:    4,
+		},
+		{
+			name:     "decrease on failures",
+			current:  6,
+			rtts:     []int64{100},
+			failures: 1,
+			pending:  true,
+			want:     3,
+		},
+		{
+			name:     "decrease on spike of baseline",
+			baseline: 100,
+			current:  6,
+			rtts:     []int64{200, 300},
+			pending:  true,
+			want:     3,
+		},
+		{
+			name:     "decrease on max latency",
+			baseline: 2e6,
+			current:  6,
+			rtts:     []int64{1500000},
+			pending:  true,
+			want:     3,
+		},
+		{
+			name:     "clamp to min",
+			current:  3,
+			failures: 1,
+			want:     2,
+		},
+		{
+			name:    "clamp to max",
+			current: 8,
+			rtts:    []int64{100},
+			pending: true,
+			want:    8,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAimd(tt.baseline)
+			assert.Equal(t, tt.want, a.next(tt.current, tt.rtts, tt.failures, tt.pending))
+		})
+	}
+}
+
+func TestAimd_spiked(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseline float64
+		latency  float64
+		want     bool
+	}{
+		{name: "no baseline", latency: 500000},
+		{name: "below ratio", baseline: 100, latency: 200},
+		{name: "above ratio", baseline: 100, latency: 201, want: true},
+		{name: "above max latency", latency: 1000001, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAimd(tt.baseline)
+			assert.Equal(t, tt.want, a.spiked(tt.latency))
+		})
+	}
+}
+
+func TestAimd_observe(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseline float64
+		latency  float64
+		want     float64
+	}{
+		{name: "first latency", latency: 100, want: 100},
+		{name: "follow lower latency", baseline: 100, latency: 80, want: 80},
+		{name: "follow higher latency slowly", baseline: 100, latency: 140, want: 102},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAimd(tt.baseline)
+			a.observe(tt.latency)
+			assert.InDelta(t, tt.want, a.baseline, 1e-9)
+		})
+	}
+
+	// the spikes are not observed by the baseline
+	a := newTestAimd(100)
+	a.next(4, []int64{1000}, 0, true)
+	assert.Equal(t, float64(100), a.baseline)
+	a.next(4, []int64{150}, 0, true)
+	assert.InDelta(t, 102.5, a.baseline, 1e-9)
+}
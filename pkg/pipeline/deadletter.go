@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/sink"
+)
+
+const (
+	DefaultDeadLetterFailureKey = "deadLetter"
+
+	FailurePipeline = "pipeline"
+	FailureSource   = "source"
+	FailureSink     = "sink"
+	FailureReason   = "reason"
+	FailureTime     = "time"
+)
+
+// DeadLetterConfig writes the events dropped with an error by the sink of pipeline, such as the retries are exhausted
+// or the events are rejected permanently, to the dead letter sink instead of discarding them.
+// The failure metadata is added to the header with FailureKey, so the events could be replayed by `loggie replay` later.
+type DeadLetterConfig struct {
+	Sink       *sink.Config  `yaml:"sink,omitempty" validate:"required"`
+	FailureKey string        `yaml:"failureKey,omitempty" default:"deadLetter"`
+	MaxRetries int           `yaml:"maxRetries,omitempty" default:"3" validate:"gte=0"`
+	Backoff    time.Duration `yaml:"backoff,omitempty" default:"1s"`
+}
+
+func (c *DeadLetterConfig) DeepCopy() *DeadLetterConfig {
+	if c == nil {
+		return nil
+	}
+	out := *c
+	out.Sink = c.Sink.DeepCopy()
+	return &out
+}
+
+func (c *DeadLetterConfig) failureKey() string {
+	if c.FailureKey == "" {
+		return DefaultDeadLetterFailureKey
+	}
+	return c.FailureKey
+}
+
+type deadLetter struct {
+	pipelineName string
+	sinkName     string
+	config       *DeadLetterConfig
+	sink         api.Sink
+	// done is closed when the pipeline is stopping, the retries are given up
+	done <-chan struct{}
+}
+
+func startDeadLetter(info Info, sinkName string, config *DeadLetterConfig, done <-chan struct{}) (*deadLetter, error) {
+	s, err := StartSink(info, info.PipelineName+"-deadLetter", config.Sink)
+	if err != nil {
+		return nil, errors.WithMessage(err, "start dead letter sink")
+	}
+	log.Info("dead letter sink %s of pipeline %s started", config.Sink.Type, info.PipelineName)
+	return &deadLetter{
+		pipelineName: info.PipelineName,
+		sinkName:     sinkName,
+		config:       config,
+		sink:         s,
+		done:         done,
+	}, nil
+}
+
+func (d *deadLetter) stop() {
+	d.sink.Stop()
+}
+
+// write sends the dropped batch to the dead letter sink, the events of batch are not modified
+// since they are committed to the source and released afterwards
+func (d *deadLetter) write(b api.Batch, reason error) {
+	events := b.Events()
+	if len(events) == 0 {
+		return
+	}
+
+	now := time.Now().Format(time.RFC3339Nano)
+	key := d.config.failureKey()
+	letters := make([]api.Event, 0, len(events))
+	for _, e := range events {
+		header := make(map[string]interface{}, len(e.Header())+1)
+		for k, v := range e.Header() {
+			header[k] = v
+		}
+		header[key] = map[string]interface{}{
+			FailurePipeline: d.pipelineName,
+			FailureSource:   sourceName(e),
+			FailureSink:     d.sinkName,
+			FailureReason:   reason.Error(),
+			FailureTime:     now,
+		}
+		// the body would be reused by the source after the event is released
+		body := make([]byte, len(e.Body()))
+		copy(body, e.Body())
+		letters = append(letters, event.NewEvent(header, body))
+	}
+
+	db := batch.NewBatchWithEvents(letters)
+	defer batch.ReleaseBatch(db)
+	for i := 0; ; i++ {
+		result := d.sink.Consume(db)
+		if result.Status() == api.SUCCESS {
+			log.Warn("%d events of pipeline %s are written to dead letter sink", len(letters), d.pipelineName)
+			return
+		}
+		if i >= d.config.MaxRetries {
+			log.Error("write %d events of pipeline %s to dead letter sink failed, drop them: %v", len(letters), d.pipelineName, result.Error())
+			return
+		}
+		// the sink consumer is blocked by the backoff, which should not delay the pipeline stopping
+		timer := time.NewTimer(d.config.Backoff)
+		select {
+		case <-d.done:
+			timer.Stop()
+			log.Error("pipeline %s is stopping, drop %d events failed to write to dead letter sink: %v", d.pipelineName, len(letters), result.Error())
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func sourceName(e api.Event) string {
+	if e.Meta() == nil {
+		return ""
+	}
+	name, _ := e.Meta().Get(event.SystemSourceKey)
+	source, _ := name.(string)
+	return source
+}
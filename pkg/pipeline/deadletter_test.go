@@ -0,0 +1,162 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/result"
+)
+
+type fakeDeadLetterSink struct {
+	failures int
+	consumed int
+	letters  []api.Event
+}
+
+func (f *fakeDeadLetterSink) Config() interface{}            { return nil }
+func (f *fakeDeadLetterSink) Category() api.Category         { return api.SINK }
+func (f *fakeDeadLetterSink) Type() api.Type                 { return "fake" }
+func (f *fakeDeadLetterSink) String() string                 { return "sink/fake" }
+func (f *fakeDeadLetterSink) Init(context api.Context) error { return nil }
+func (f *fakeDeadLetterSink) Start() error                   { return nil }
+func (f *fakeDeadLetterSink) Stop()                          {}
+
+func (f *fakeDeadLetterSink) Consume(b api.Batch) api.Result {
+	f.consumed++
+	if f.consumed <= f.failures {
+		return result.Fail(errors.New("dead letter sink is down"))
+	}
+	f.letters = append(f.letters, b.Events()...)
+	return result.Success()
+}
+
+func newTestDeadLetter(s api.Sink, maxRetries int) *deadLetter {
+	return &deadLetter{
+		pipelineName: "local",
+		sinkName:     "es",
+		config:       &DeadLetterConfig{MaxRetries: maxRetries},
+		sink:         s,
+		done:         make(chan struct{}),
+	}
+}
+
+func TestDeadLetter_write(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		maxRetries   int
+		wantConsumed int
+		wantLetters  int
+	}{
+		{
+			name:         "written",
+			maxRetries:   3,
+			wantConsumed: 1,
+			wantLetters:  2,
+		},
+		{
+			name:         "written after retries",
+			failures:     2,
+			maxRetries:   3,
+			wantConsumed: 3,
+			wantLetters:  2,
+		},
+		{
+			name:         "dropped when the retries are exhausted",
+			failures:     5,
+			maxRetries:   2,
+			wantConsumed: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &fakeDeadLetterSink{failures: tt.failures}
+			d := newTestDeadLetter(s, tt.maxRetries)
+
+			meta := event.NewDefaultMeta()
+			meta.Set(event.SystemSourceKey, "file")
+			withMeta := event.NewEvent(map[string]interface{}{"app": "web"}, []byte("a"))
+			withMeta.Fill(meta, withMeta.Header(), withMeta.Body())
+			events := []api.Event{
+				withMeta,
+				event.NewEvent(map[string]interface{}{}, []byte("b")),
+			}
+			d.write(batch.NewBatchWithEvents(events), errors.New("retry reaches the limit"))
+			assert.Equal(t, tt.wantConsumed, s.consumed)
+			assert.Len(t, s.letters, tt.wantLetters)
+		})
+	}
+}
+
+func TestDeadLetter_writeStopping(t *testing.T) {
+	s := &fakeDeadLetterSink{failures: 5}
+	d := newTestDeadLetter(s, 3)
+	d.config.Backoff = time.Hour
+	done := make(chan struct{})
+	d.done = done
+
+	written := make(chan struct{})
+	go func() {
+		d.write(batch.NewBatchWithEvents([]api.Event{event.NewEvent(map[string]interface{}{}, []byte("a"))}), errors.New("rejected"))
+		close(written)
+	}()
+
+	// the retries waiting for the backoff are given up once the pipeline is stopping
+	close(done)
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the dead letter is still retrying after the pipeline is stopped")
+	}
+	assert.Equal(t, 1, s.consumed)
+	assert.Empty(t, s.letters)
+}
+
+func TestDeadLetter_writeMetadata(t *testing.T) {
+	s := &fakeDeadLetterSink{}
+	d := newTestDeadLetter(s, 0)
+	d.config.FailureKey = "failure"
+
+	meta := event.NewDefaultMeta()
+	meta.Set(event.SystemSourceKey, "file")
+	e := event.NewEvent(map[string]interface{}{"app": "web"}, []byte("body"))
+	e.Fill(meta, e.Header(), e.Body())
+	d.write(batch.NewBatchWithEvents([]api.Event{e}), errors.New("rejected"))
+
+	// the released event does not change the letter
+	e.Body()[0] = 'x'
+	e.Release()
+
+	assert.Len(t, s.letters, 1)
+	letter := s.letters[0]
+	assert.Equal(t, []byte("body"), letter.Body())
+	assert.Equal(t, "web", letter.Header()["app"])
+	failure := letter.Header()["failure"].(map[string]interface{})
+	assert.Equal(t, "local", failure[FailurePipeline])
+	assert.Equal(t, "file", failure[FailureSource])
+	assert.Equal(t, "es", failure[FailureSink])
+	assert.Equal(t, "rejected", failure[FailureReason])
+	assert.NotEmpty(t, failure[FailureTime])
+}
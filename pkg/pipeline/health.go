@@ -0,0 +1,62 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/health"
+)
+
+// sinkHealth marks the sink as not ready after the consecutive failed batches reach the threshold,
+// such as the backend is not connectable
+type sinkHealth struct {
+	name string
+
+	lock     sync.Mutex
+	failures int
+	lastErr  error
+}
+
+func newSinkHealth(pipelineName string, sinkName string) *sinkHealth {
+	return &sinkHealth{
+		name: fmt.Sprintf("pipeline/%s/sink/%s", pipelineName, sinkName),
+	}
+}
+
+func (h *sinkHealth) record(result api.Result) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if result.Status() == api.FAIL {
+		h.failures++
+		h.lastErr = result.Error()
+		return
+	}
+	h.failures = 0
+	h.lastErr = nil
+}
+
+func (h *sinkHealth) check() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.failures < health.GetConfig().SinkFailureThreshold {
+		return nil
+	}
+	return fmt.Errorf("%d consecutive batches failed, last error: %v", h.failures, h.lastErr)
+}
@@ -36,6 +36,7 @@ type Config struct {
 	Interceptors []*interceptor.Config `yaml:"interceptors,omitempty"`
 	Sources      []*source.Config      `yaml:"sources,omitempty" validate:"dive,required"`
 	Sink         *sink.Config          `yaml:"sink,omitempty" validate:"dive,required"`
+	DeadLetter   *DeadLetterConfig     `yaml:"deadLetter,omitempty"`
 }
 
 func (c *Config) SetDefaults() {
@@ -73,6 +74,7 @@ func (c *Config) DeepCopy() *Config {
 	}
 
 	out.Sink = c.Sink.DeepCopy()
+	out.DeadLetter = c.DeadLetter.DeepCopy()
 
 	return out
 }
@@ -19,6 +19,8 @@ package pipeline
 import (
 	"fmt"
 	"github.com/pkg/errors"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/loggie-io/loggie/pkg/core/api"
@@ -46,6 +48,22 @@ func Register(category api.Category, typename api.Type, factory Factory) {
 	codeFactory[code] = factory
 }
 
+// RegisteredTypes returns the sorted types of the registered components of the category
+func RegisteredTypes(category api.Category) []api.Type {
+	prefix := string(category) + "/"
+	var types []api.Type
+	for code := range codeFactory {
+		if !strings.HasPrefix(code, prefix) {
+			continue
+		}
+		types = append(types, api.Type(strings.TrimSuffix(strings.TrimPrefix(code, prefix), "/")))
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i] < types[j]
+	})
+	return types
+}
+
 func GetWithType(category api.Category, typename api.Type, info Info) (api.Component, error) {
 	code := codeWithoutName(category, typename)
 	factory, ok := codeFactory[code]
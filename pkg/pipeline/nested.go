@@ -0,0 +1,82 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/sink"
+	sinkcodec "github.com/loggie-io/loggie/pkg/sink/codec"
+)
+
+// ValidateSink validates the config of a sink nested in another sink, such as the routes of the router sink
+func ValidateSink(info Info, config *sink.Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	component, err := GetWithType(api.SINK, api.Type(config.Type), info)
+	if err != nil {
+		return err
+	}
+	return cfg.UnpackFromCommonCfg(config.Properties, component.Config()).Defaults().Validate().Do()
+}
+
+// StartSink starts a sink nested in another sink the same as the sink of pipeline,
+// except the sink is not registered in the pipeline and its dependency interceptors are not invoked
+func StartSink(info Info, name string, config *sink.Config) (api.Sink, error) {
+	ctx := context.NewContext(name, api.Type(config.Type), api.SINK, config.Properties)
+	component, err := GetWithType(api.SINK, ctx.Type(), info)
+	if err != nil {
+		return nil, err
+	}
+	si, ok := component.(api.Sink)
+	if !ok {
+		return nil, errors.Errorf("component %s is not a sink", config.Type)
+	}
+
+	if sc, ok := component.(sinkcodec.SinkCodec); ok {
+		codecConf := config.Codec
+		if codecConf.Type == "" {
+			codecConf.Type = "json"
+		}
+		cod, ok := sinkcodec.Get(codecConf.Type)
+		if !ok {
+			return nil, errors.Errorf("codec %s cannot be found", codecConf.Type)
+		}
+		if conf, ok := cod.(api.Config); ok {
+			if err := cfg.UnpackFromCommonCfg(codecConf.CommonCfg, conf.Config()).Defaults().Do(); err != nil {
+				return nil, errors.WithMessage(err, "unpack codec config")
+			}
+		}
+		cod.Init(&codecConf)
+		sc.SetCodec(cod)
+	}
+
+	if err := cfg.UnpackFromCommonCfg(ctx.Properties(), component.Config()).Defaults().Do(); err != nil {
+		return nil, errors.WithMessagef(err, "unpack sink %s", config.Type)
+	}
+	if err := component.Init(ctx); err != nil {
+		return nil, errors.WithMessagef(err, "init sink %s", config.Type)
+	}
+	if err := component.Start(); err != nil {
+		return nil, errors.WithMessagef(err, "start sink %s", config.Type)
+	}
+	return si, nil
+}
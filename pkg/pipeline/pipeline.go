@@ -23,6 +23,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/panjf2000/ants/v2"
@@ -34,6 +35,7 @@ import (
 	"github.com/loggie-io/loggie/pkg/core/context"
 	"github.com/loggie-io/loggie/pkg/core/event"
 	"github.com/loggie-io/loggie/pkg/core/flowdatapool"
+	"github.com/loggie-io/loggie/pkg/core/health"
 	"github.com/loggie-io/loggie/pkg/core/interceptor"
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/core/queue"
@@ -74,12 +76,16 @@ type Pipeline struct {
 	countDown     sync.WaitGroup
 	retryOutFuncs []api.OutFunc
 	index         uint32
+	sinkWorkers   uint32 // the sink workers started, assigned to the shards of a sharded queue in turn
 	epoch         *Epoch
 	envMap        map[string]interface{}
 	pathMap       map[string]interface{}
 	outfunc       api.OutFunc
 	retryoutfunc  api.OutFunc
 	sinkinfo      sink.Info
+	sinkHealth    *sinkHealth
+	quarantines   *quarantines
+	deadLetter    *deadLetter
 	concurrency   concurrency.Config
 
 	Running bool
@@ -96,6 +102,7 @@ func NewPipeline(pipelineConfig *Config) *Pipeline {
 			SurviveChan: make(chan api.Batch, pipelineConfig.Sink.Parallelism+1),
 		},
 		r:           registerCenter,
+		quarantines: newQuarantines(pipelineConfig.Name),
 		concurrency: pipelineConfig.Sink.Concurrency,
 	}
 }
@@ -111,6 +118,13 @@ func (p *Pipeline) Stop() {
 	p.cleanOutChan(done)
 	// 0. stop sink consumer
 	p.stopSinkConsumer()
+	if p.sinkHealth != nil {
+		health.Unregister(p.sinkHealth.name)
+	}
+	if p.deadLetter != nil {
+		p.deadLetter.stop()
+		p.deadLetter = nil
+	}
 	// 1. stop source product
 	p.stopSourceProduct()
 	// 2. stop queue
@@ -251,6 +265,13 @@ func (p *Pipeline) Start() error {
 	if err := p.startSink(pipelineConfig.Sink); err != nil {
 		return err
 	}
+	if pipelineConfig.DeadLetter != nil {
+		d, err := startDeadLetter(p.info, pipelineConfig.Sink.Name, pipelineConfig.DeadLetter, p.done)
+		if err != nil {
+			return err
+		}
+		p.deadLetter = d
+	}
 	// 3. start source
 	if err := p.startSource(pipelineConfig.Sources); err != nil {
 		return err
@@ -359,6 +380,9 @@ func (p *Pipeline) afterSinkConsumer(b api.Batch, result api.Result) {
 	// commit to source and release batch
 	// we use the if/else instead of switch/case cause of performance in golang
 	status := result.Status()
+	if p.sinkHealth != nil {
+		p.sinkHealth.record(result)
+	}
 	if status == api.SUCCESS {
 		p.finalizeBatch(b)
 		return
@@ -372,6 +396,9 @@ func (p *Pipeline) afterSinkConsumer(b api.Batch, result api.Result) {
 	if status == api.DROP {
 		if result.Error() != nil {
 			log.Error("drop batch due to: %s", result.Error())
+			if p.deadLetter != nil {
+				p.deadLetter.write(b, result.Error())
+			}
 		}
 		p.finalizeBatch(b)
 		return
@@ -436,6 +463,15 @@ func (p *Pipeline) validate() error {
 		return err
 	}
 
+	if dl := pipelineConfig.DeadLetter; dl != nil {
+		if dl.Sink == nil || dl.Sink.Type == "" {
+			return errors.New("deadLetter.sink is required")
+		}
+		if err := ValidateSink(p.info, dl.Sink); err != nil {
+			return errors.WithMessage(err, "validate dead letter sink")
+		}
+	}
+
 	unique := make(map[string]struct{})
 	if len(pipelineConfig.Sources) == 0 {
 		return ErrPipelineSourceRequired
@@ -502,15 +538,18 @@ func (p *Pipeline) startSinkConsumer(sinkConfig *sink.Config) {
 		Interceptors: interceptors,
 	}
 	p.sinkinfo = si
+	p.sinkHealth = newSinkHealth(p.name, sinkConfig.Name)
+	health.Register(p.sinkHealth.name, p.sinkHealth.check)
 	// combine component default interceptors
 	interceptors = append(interceptors, collectComponentDependencySinkInterceptors(si.Sink)...)
 	interceptors = append(interceptors, collectComponentDependencySinkInterceptors(si.Queue)...)
 
 	p.flowPool = flowdatapool.InitDataPool(100)
 	p.flowPool.SetEnabled(p.concurrency.Enable)
-	invoker := &sink.SubscribeInvoker{}
-	sinkInvokerChain := buildSinkInvokerChain(invoker, interceptors, false)
-	retrySinkInvokerChain := buildSinkInvokerChain(invoker, interceptors, true)
+	// throttled as the innermost invoker, so the batches retried by the interceptors are limited as well
+	invoker := sink.NewThrottleInvoker(&sink.SubscribeInvoker{}, sinkConfig.Throttle, p.done)
+	sinkInvokerChain := buildSinkInvokerChain(invoker, interceptors, false, p.quarantines)
+	retrySinkInvokerChain := buildSinkInvokerChain(invoker, interceptors, true, p.quarantines)
 	outFunc := func(batch api.Batch) api.Result {
 		result := sinkInvokerChain.Invoke(sink.Invocation{
 			Batch:    batch,
@@ -538,7 +577,11 @@ func (p *Pipeline) startSinkConsumer(sinkConfig *sink.Config) {
 	p.flowPoolDone = make(chan struct{})
 
 	concurrencyEnabled := p.concurrency.Enable
-	if concurrencyEnabled {
+	if concurrencyEnabled && p.concurrency.Mode == concurrency.ModeAimd {
+		p.gpoolMaxSize = p.concurrency.Aimd.Max
+		p.tuneGPool(p.concurrency.Aimd.Min)
+		p.startAimdController()
+	} else if concurrencyEnabled {
 		p.gpoolMaxSize = p.concurrency.Goroutine.MaxGoroutine
 		p.tuneGPool(2)
 		p.startGPoolCalculator()
@@ -560,6 +603,12 @@ func (p *Pipeline) sinkInvokeLoop(info sink.Info, outFunc api.OutFunc) {
 	}()
 	q := info.Queue
 	outChan := q.OutChan()
+	// a nil shardChan is never selected
+	var shardChan chan api.Batch
+	if sq, ok := q.(api.ShardedQueue); ok && sq.Shards() > 1 {
+		worker := atomic.AddUint32(&p.sinkWorkers, 1) - 1
+		shardChan = sq.ShardOutChan(int(worker % uint32(sq.Shards())))
+	}
 	for {
 		select {
 		case <-p.done:
@@ -567,6 +616,9 @@ func (p *Pipeline) sinkInvokeLoop(info sink.Info, outFunc api.OutFunc) {
 		case b := <-outChan:
 			result := outFunc(b)
 			p.afterSinkConsumer(b, result)
+		case b := <-shardChan:
+			result := outFunc(b)
+			p.afterSinkConsumer(b, result)
 		case <-p.flowPoolDone:
 			return
 		}
@@ -813,17 +865,7 @@ func (p *Pipeline) startGPoolCalculator() {
 				}
 
 			}
-			sources := p.config.Sources
-			for _, source := range sources {
-				sinkMetricData := eventbus.SinkMetricData{
-					BaseMetric: eventbus.BaseMetric{
-						PipelineName: p.name,
-						SourceName:   source.Name,
-					},
-					GoroutinePoolSize: p.gpool.Cap(),
-				}
-				eventbus.PublishOrDrop(eventbus.SinkMetricTopic, sinkMetricData)
-			}
+			p.publishGoroutinePoolSize()
 		}
 	}()
 
@@ -876,7 +918,7 @@ func (p *Pipeline) stopGPool() {
 
 }
 
-func buildSinkInvokerChain(invoker sink.Invoker, interceptors []sink.Interceptor, retry bool) sink.Invoker {
+func buildSinkInvokerChain(invoker sink.Invoker, interceptors []sink.Interceptor, retry bool, qs *quarantines) sink.Invoker {
 	l := len(interceptors)
 	if l == 0 {
 		return invoker
@@ -896,10 +938,19 @@ func buildSinkInvokerChain(invoker sink.Invoker, interceptors []sink.Interceptor
 			}
 		}
 		next := last
-		last = &sink.AbstractInvoker{
-			DoInvoke: func(invocation sink.Invocation) api.Result {
-				return tempInterceptor.Intercept(next, invocation)
-			},
+		if q := qs.get(tempInterceptor); q != nil {
+			guard := &sinkGuard{next: next}
+			last = &sink.AbstractInvoker{
+				DoInvoke: func(invocation sink.Invocation) api.Result {
+					return q.interceptSink(tempInterceptor, guard, invocation)
+				},
+			}
+		} else {
+			last = &sink.AbstractInvoker{
+				DoInvoke: func(invocation sink.Invocation) api.Result {
+					return tempInterceptor.Intercept(next, invocation)
+				},
+			}
 		}
 
 		interceptorChainName.WriteString(sortableInterceptor[i].String())
@@ -911,6 +962,9 @@ func buildSinkInvokerChain(invoker sink.Invoker, interceptors []sink.Interceptor
 	} else {
 		log.Info("sink interceptor chain: %s", interceptorChainName.String())
 	}
+	if qs != nil {
+		return unwrapSinkPanics(last)
+	}
 	return last
 }
 
@@ -988,7 +1042,7 @@ func (p *Pipeline) startSourceProduct(sourceConfigs []*source.Config) {
 		p.initFieldsFromEnv(sc.FieldsFromEnv)
 		p.initFieldsFromPath(sc.FieldsFromPath)
 
-		sourceInvokerChain := buildSourceInvokerChain(sourceConfig.Name, &source.PublishInvoker{}, si.Interceptors)
+		sourceInvokerChain := buildSourceInvokerChain(sourceConfig.Name, &source.PublishInvoker{}, si.Interceptors, p.quarantines)
 		productFunc := func(e api.Event) api.Result {
 			p.fillEventMetaAndHeader(e, *sourceConfig)
 
@@ -1122,7 +1176,7 @@ func AddSourceFields(header map[string]interface{}, fields map[string]interface{
 	header[fieldsKey] = fieldsCopy
 }
 
-func buildSourceInvokerChain(sourceName string, invoker source.Invoker, interceptors []source.Interceptor) source.Invoker {
+func buildSourceInvokerChain(sourceName string, invoker source.Invoker, interceptors []source.Interceptor, qs *quarantines) source.Invoker {
 	l := len(interceptors)
 	if l == 0 {
 		return invoker
@@ -1145,10 +1199,19 @@ func buildSourceInvokerChain(sourceName string, invoker source.Invoker, intercep
 			}
 		}
 		next := last
-		last = &source.AbstractInvoker{
-			DoInvoke: func(invocation source.Invocation) api.Result {
-				return tempInterceptor.Intercept(next, invocation)
-			},
+		if q := qs.get(tempInterceptor); q != nil {
+			guard := &sourceGuard{next: next}
+			last = &source.AbstractInvoker{
+				DoInvoke: func(invocation source.Invocation) api.Result {
+					return q.interceptSource(tempInterceptor, guard, invocation)
+				},
+			}
+		} else {
+			last = &source.AbstractInvoker{
+				DoInvoke: func(invocation source.Invocation) api.Result {
+					return tempInterceptor.Intercept(next, invocation)
+				},
+			}
 		}
 
 		interceptorChainName.WriteString(sortableInterceptor[i].String())
@@ -1158,6 +1221,9 @@ func buildSourceInvokerChain(sourceName string, invoker source.Invoker, intercep
 	interceptorChainName.WriteString("queue")
 	log.Info("source %s interceptor chain: %s", sourceName, interceptorChainName.String())
 
+	if qs != nil {
+		return unwrapSourcePanics(last)
+	}
 	return last
 }
 
@@ -0,0 +1,239 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/sink"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+)
+
+const (
+	quarantineReason = "InterceptorQuarantined"
+
+	quarantineComponentKey = "component"
+	quarantinePanicsKey    = "panics"
+)
+
+// QuarantineConfig recovers the panics of the interceptors, the interceptor is bypassed in its pipeline
+// after MaxPanics panics, until the pipeline is reloaded
+type QuarantineConfig struct {
+	Enabled   *bool `yaml:"enabled,omitempty" default:"true"`
+	MaxPanics int   `yaml:"maxPanics,omitempty" default:"3" validate:"gte=1"`
+}
+
+var quarantineConfig = QuarantineConfig{
+	MaxPanics: 3,
+}
+
+func SetQuarantineConfig(config QuarantineConfig) {
+	quarantineConfig = config
+}
+
+func (c QuarantineConfig) enabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// quarantines keeps the quarantine of each interceptor in a pipeline, the source and sink chains share them
+type quarantines struct {
+	pipelineName string
+
+	lock  sync.Mutex
+	index map[api.Interceptor]*quarantine
+}
+
+func newQuarantines(pipelineName string) *quarantines {
+	if !quarantineConfig.enabled() {
+		return nil
+	}
+	return &quarantines{
+		pipelineName: pipelineName,
+		index:        make(map[api.Interceptor]*quarantine),
+	}
+}
+
+func (qs *quarantines) get(i api.Interceptor) *quarantine {
+	if qs == nil {
+		return nil
+	}
+	qs.lock.Lock()
+	defer qs.lock.Unlock()
+	q, ok := qs.index[i]
+	if !ok {
+		q = &quarantine{
+			pipelineName: qs.pipelineName,
+			name:         i.String(),
+			maxPanics:    int32(quarantineConfig.MaxPanics),
+		}
+		qs.index[i] = q
+	}
+	return q
+}
+
+type quarantine struct {
+	pipelineName string
+	name         string
+	maxPanics    int32
+
+	panics   int32
+	bypassed int32
+}
+
+func (q *quarantine) isBypassed() bool {
+	return atomic.LoadInt32(&q.bypassed) == 1
+}
+
+func (q *quarantine) onPanic(r interface{}) {
+	n := atomic.AddInt32(&q.panics, 1)
+	log.Error("pipeline %s interceptor %s panic(%d/%d): %v\n%s", q.pipelineName, q.name, n, q.maxPanics, r, debug.Stack())
+	if n >= q.maxPanics && atomic.CompareAndSwapInt32(&q.bypassed, 0, 1) {
+		msg := fmt.Sprintf("interceptor %s is quarantined after %d panics, events would pass through it until the pipeline is reloaded", q.name, n)
+		log.Error("pipeline %s %s", q.pipelineName, msg)
+
+		e := q.alertEvent(n, time.Now(), msg)
+		eventbus.PublishOrDrop(eventbus.LogAlertTopic, &e)
+	}
+}
+
+// alertEvent is sent to the logAlert listener once the interceptor is quarantined
+func (q *quarantine) alertEvent(panics int32, now time.Time, msg string) api.Event {
+	header := map[string]interface{}{
+		event.ReasonKey:        quarantineReason,
+		quarantineComponentKey: q.name,
+		quarantinePanicsKey:    panics,
+	}
+
+	meta := event.NewDefaultMeta()
+	meta.Set(event.SystemProductTimeKey, now)
+	meta.Set(event.SystemPipelineKey, q.pipelineName)
+
+	e := event.NewEvent(header, []byte(msg))
+	e.Fill(meta, header, e.Body())
+	return e
+}
+
+// downstreamPanic wraps the panics raised below an interceptor, so the quarantine of the interceptor passes
+// them on instead of blaming the interceptor, and the head of the chain unwraps them
+type downstreamPanic struct {
+	value interface{}
+}
+
+func markDownstreamPanic() {
+	if r := recover(); r != nil {
+		if _, ok := r.(downstreamPanic); ok {
+			panic(r)
+		}
+		panic(downstreamPanic{value: r})
+	}
+}
+
+func unwrapDownstreamPanic() {
+	if r := recover(); r != nil {
+		if d, ok := r.(downstreamPanic); ok {
+			panic(d.value)
+		}
+		panic(r)
+	}
+}
+
+// sourceGuard is the invoker given to a quarantined interceptor, it is built once in the chain
+type sourceGuard struct {
+	next source.Invoker
+}
+
+func (g *sourceGuard) Invoke(invocation source.Invocation) api.Result {
+	defer markDownstreamPanic()
+	return g.next.Invoke(invocation)
+}
+
+// interceptSource passes the event to the next invoker when the interceptor panics. The interceptor may have
+// called the next invoker before panicking, then the event is passed again.
+func (q *quarantine) interceptSource(i source.Interceptor, guard *sourceGuard, invocation source.Invocation) (res api.Result) {
+	if q.isBypassed() {
+		return guard.next.Invoke(invocation)
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if _, ok := r.(downstreamPanic); ok {
+			panic(r)
+		}
+		q.onPanic(r)
+		res = guard.next.Invoke(invocation)
+	}()
+	return i.Intercept(guard, invocation)
+}
+
+// unwrapSourcePanics is the head of a chain with quarantined interceptors, the panics of the chain are raised
+// with their original values
+func unwrapSourcePanics(invoker source.Invoker) source.Invoker {
+	return &source.AbstractInvoker{
+		DoInvoke: func(invocation source.Invocation) api.Result {
+			defer unwrapDownstreamPanic()
+			return invoker.Invoke(invocation)
+		},
+	}
+}
+
+type sinkGuard struct {
+	next sink.Invoker
+}
+
+func (g *sinkGuard) Invoke(invocation sink.Invocation) api.Result {
+	defer markDownstreamPanic()
+	return g.next.Invoke(invocation)
+}
+
+func (q *quarantine) interceptSink(i sink.Interceptor, guard *sinkGuard, invocation sink.Invocation) (res api.Result) {
+	if q.isBypassed() {
+		return guard.next.Invoke(invocation)
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if _, ok := r.(downstreamPanic); ok {
+			panic(r)
+		}
+		q.onPanic(r)
+		res = guard.next.Invoke(invocation)
+	}()
+	return i.Intercept(guard, invocation)
+}
+
+func unwrapSinkPanics(invoker sink.Invoker) sink.Invoker {
+	return &sink.AbstractInvoker{
+		DoInvoke: func(invocation sink.Invocation) api.Result {
+			defer unwrapDownstreamPanic()
+			return invoker.Invoke(invocation)
+		},
+	}
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/sink"
+	"github.com/loggie-io/loggie/pkg/core/source"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+)
+
+func init() {
+	log.InitDefaultLogger()
+}
+
+type fakeInterceptor struct {
+	name        string
+	panicBefore bool
+	panicAfter  bool
+	calls       int
+}
+
+func (f *fakeInterceptor) Config() interface{}            { return nil }
+func (f *fakeInterceptor) Category() api.Category         { return api.INTERCEPTOR }
+func (f *fakeInterceptor) Type() api.Type                 { return api.Type(f.name) }
+func (f *fakeInterceptor) String() string                 { return f.name }
+func (f *fakeInterceptor) Init(context api.Context) error { return nil }
+func (f *fakeInterceptor) Start() error                   { return nil }
+func (f *fakeInterceptor) Stop()                          {}
+
+func (f *fakeInterceptor) intercept(next func() api.Result) api.Result {
+	f.calls++
+	if f.panicBefore {
+		panic("interceptor " + f.name)
+	}
+	res := next()
+	if f.panicAfter {
+		panic("interceptor " + f.name)
+	}
+	return res
+}
+
+type fakeSourceInterceptor struct {
+	fakeInterceptor
+}
+
+func (f *fakeSourceInterceptor) Intercept(invoker source.Invoker, invocation source.Invocation) api.Result {
+	return f.intercept(func() api.Result {
+		return invoker.Invoke(invocation)
+	})
+}
+
+type fakeSinkInterceptor struct {
+	fakeInterceptor
+}
+
+func (f *fakeSinkInterceptor) Intercept(invoker sink.Invoker, invocation sink.Invocation) api.Result {
+	return f.intercept(func() api.Result {
+		return invoker.Invoke(invocation)
+	})
+}
+
+type countInvoker struct {
+	panicValue interface{}
+	calls      int
+	res        api.Result
+}
+
+func (c *countInvoker) invoke() api.Result {
+	c.calls++
+	if c.panicValue != nil {
+		panic(c.panicValue)
+	}
+	return c.res
+}
+
+func (c *countInvoker) source() source.Invoker {
+	return &source.AbstractInvoker{
+		DoInvoke: func(invocation source.Invocation) api.Result {
+			return c.invoke()
+		},
+	}
+}
+
+func (c *countInvoker) sink() sink.Invoker {
+	return &sink.AbstractInvoker{
+		DoInvoke: func(invocation sink.Invocation) api.Result {
+			return c.invoke()
+		},
+	}
+}
+
+func setTestQuarantineConfig(t *testing.T, enabled bool, maxPanics int) {
+	SetQuarantineConfig(QuarantineConfig{Enabled: &enabled, MaxPanics: maxPanics})
+	t.Cleanup(func() {
+		SetQuarantineConfig(QuarantineConfig{MaxPanics: 3})
+	})
+}
+
+func TestQuarantine_SourceChain(t *testing.T) {
+	tests := []struct {
+		name        string
+		panicBefore bool
+		panicAfter  bool
+		invokes     int
+		// the expected calls of the interceptor and of the invoker after it
+		wantIntercepts int
+		wantInvokes    int
+		wantPanics     int32
+		wantBypassed   bool
+	}{
+		{
+			name:           "no panic",
+			invokes:        3,
+			wantIntercepts: 3,
+			wantInvokes:    3,
+		},
+		{
+			name:           "recover panic before next",
+			panicBefore:    true,
+			invokes:        1,
+			wantIntercepts: 1,
+			wantInvokes:    1,
+			wantPanics:     1,
+		},
+		{
+			name:           "recover panic after next passes the event again",
+			panicAfter:     true,
+			invokes:        1,
+			wantIntercepts: 1,
+			wantInvokes:    2,
+			wantPanics:     1,
+		},
+		{
+			name:           "bypassed after max panics",
+			panicBefore:    true,
+			invokes:        5,
+			wantIntercepts: 2,
+			wantInvokes:    5,
+			wantPanics:     2,
+			wantBypassed:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestQuarantineConfig(t, true, 2)
+
+			i := &fakeSourceInterceptor{fakeInterceptor{name: "fake", panicBefore: tt.panicBefore, panicAfter: tt.panicAfter}}
+			invoker := &countInvoker{res: result.Success()}
+			qs := newQuarantines("test")
+			chain := buildSourceInvokerChain("test", invoker.source(), []source.Interceptor{i}, qs)
+
+			for n := 0; n < tt.invokes; n++ {
+				res := chain.Invoke(source.Invocation{Event: event.NewEvent(nil, []byte("event"))})
+				assert.Equal(t, invoker.res, res)
+			}
+			assert.Equal(t, tt.wantIntercepts, i.calls)
+			assert.Equal(t, tt.wantInvokes, invoker.calls)
+
+			q := qs.get(i)
+			assert.Equal(t, tt.wantPanics, q.panics)
+			assert.Equal(t, tt.wantBypassed, q.isBypassed())
+		})
+	}
+}
+
+// alertListener receives the alerts of the component, the other tests may have published alerts
+type alertListener struct {
+	component string
+	alerts    chan api.Event
+}
+
+func (l *alertListener) Init(context api.Context) error { return nil }
+func (l *alertListener) Start() error                   { return nil }
+func (l *alertListener) Stop()                          {}
+func (l *alertListener) Name() string                   { return "quarantineAlert" }
+func (l *alertListener) Config() interface{}            { return nil }
+
+func (l *alertListener) Subscribe(e eventbus.Event) {
+	alert := *(e.Data.(*api.Event))
+	if alert.Header()[quarantineComponentKey] == l.component {
+		l.alerts <- alert
+	}
+}
+
+var startEventCenter sync.Once
+
+func TestQuarantine_Alert(t *testing.T) {
+	setTestQuarantineConfig(t, true, 2)
+
+	startEventCenter.Do(func() {
+		eventbus.StartAndRun(eventbus.Config{})
+	})
+	listener := &alertListener{component: "alerted", alerts: make(chan api.Event, 8)}
+	subscribe := eventbus.RegistryTemporary(listener.Name(), func() eventbus.Listener {
+		return listener
+	}, eventbus.WithTopic(eventbus.LogAlertTopic))
+	defer eventbus.UnRegistrySubscribeTemporary(subscribe)
+
+	i := &fakeSinkInterceptor{fakeInterceptor{name: "alerted", panicBefore: true}}
+	invoker := &countInvoker{res: result.Success()}
+	chain := buildSinkInvokerChain(invoker.sink(), []sink.Interceptor{i}, false, newQuarantines("test"))
+
+	chain.Invoke(sink.Invocation{})
+	select {
+	case <-listener.alerts:
+		t.Fatal("the alert is published before the interceptor is quarantined")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the bypassed interceptor does not panic again
+	for n := 0; n < 3; n++ {
+		chain.Invoke(sink.Invocation{})
+	}
+	select {
+	case e := <-listener.alerts:
+		assert.Equal(t, quarantineReason, e.Header()[event.ReasonKey])
+		assert.Equal(t, "alerted", e.Header()[quarantineComponentKey])
+		assert.Equal(t, int32(2), e.Header()[quarantinePanicsKey])
+		pipelineName, _ := e.Meta().Get(event.SystemPipelineKey)
+		assert.Equal(t, "test", pipelineName)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the alert is not published")
+	}
+	select {
+	case <-listener.alerts:
+		t.Fatal("the alert is published more than once")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestQuarantine_Disabled(t *testing.T) {
+	setTestQuarantineConfig(t, false, 2)
+
+	qs := newQuarantines("test")
+	assert.Nil(t, qs)
+
+	i := &fakeSourceInterceptor{fakeInterceptor{name: "fake", panicBefore: true}}
+	invoker := &countInvoker{res: result.Success()}
+	chain := buildSourceInvokerChain("test", invoker.source(), []source.Interceptor{i}, qs)
+	assert.PanicsWithValue(t, "interceptor fake", func() {
+		chain.Invoke(source.Invocation{})
+	})
+}
+
+func TestQuarantine_DownstreamPanic(t *testing.T) {
+	setTestQuarantineConfig(t, true, 1)
+
+	outer := &fakeSinkInterceptor{fakeInterceptor{name: "outer"}}
+	inner := &fakeSinkInterceptor{fakeInterceptor{name: "inner"}}
+	invoker := &countInvoker{panicValue: "sink"}
+	qs := newQuarantines("test")
+	chain := buildSinkInvokerChain(invoker.sink(), []sink.Interceptor{outer, inner}, false, qs)
+
+	// the panic of the sink is raised with its original value, and the interceptors are not blamed
+	assert.PanicsWithValue(t, "sink", func() {
+		chain.Invoke(sink.Invocation{})
+	})
+	assert.False(t, qs.get(outer).isBypassed())
+	assert.False(t, qs.get(inner).isBypassed())
+
+	// the panic of the inner interceptor is not blamed on the outer one
+	invoker.panicValue = nil
+	invoker.res = result.Success()
+	inner.panicBefore = true
+	assert.Equal(t, invoker.res, chain.Invoke(sink.Invocation{}))
+	assert.Equal(t, int32(0), qs.get(outer).panics)
+	assert.True(t, qs.get(inner).isBypassed())
+
+	// the bypassed interceptor is skipped
+	assert.Equal(t, invoker.res, chain.Invoke(sink.Invocation{}))
+	assert.Equal(t, 3, outer.calls)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestQuarantine_NoAllocs(t *testing.T) {
+	setTestQuarantineConfig(t, true, 1)
+
+	i := &fakeSinkInterceptor{fakeInterceptor{name: "fake"}}
+	invoker := &countInvoker{res: result.Success()}
+	chain := buildSinkInvokerChain(invoker.sink(), []sink.Interceptor{i}, false, newQuarantines("test"))
+
+	invocation := sink.Invocation{}
+	allocs := testing.AllocsPerRun(100, func() {
+		chain.Invoke(invocation)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
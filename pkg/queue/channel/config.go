@@ -16,10 +16,20 @@ limitations under the License.
 
 package channel
 
-import "time"
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/queue"
+)
 
 type Config struct {
 	BatchSize          int           `yaml:"batchSize" default:"2048"`
 	BatchBytes         int64         `yaml:"batchBytes" default:"33554432"` // default:32MB
 	BatchAggMaxTimeout time.Duration `yaml:"batchAggTimeout" default:"1s"`
+	// EventTime aligns the batches to the event time windows instead of batchAggTimeout
+	EventTime queue.EventTimeConfig `yaml:"eventTime,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	return c.EventTime.Validate()
 }
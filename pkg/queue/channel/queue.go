@@ -24,7 +24,9 @@ import (
 
 	"github.com/loggie-io/loggie/pkg/core/api"
 	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/health"
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/queue"
 	"github.com/loggie-io/loggie/pkg/core/spi"
 	"github.com/loggie-io/loggie/pkg/eventbus"
 	"github.com/loggie-io/loggie/pkg/pipeline"
@@ -57,6 +59,7 @@ type Queue struct {
 	out          chan api.Batch
 	listeners    []spi.QueueListener
 	countDown    *sync.WaitGroup
+	buckets      *queue.EventTimeBuckets
 }
 
 func (c *Queue) Type() api.Type {
@@ -85,6 +88,14 @@ func (c *Queue) Init(context api.Context) error {
 		c.config.BatchSize)
 	c.out = make(chan api.Batch, c.sinkCount)
 	c.in = make(chan api.Event, 16)
+	if c.config.EventTime.Enabled {
+		buckets, err := queue.NewEventTimeBuckets(&c.config.EventTime, c.config.BatchSize, c.config.BatchBytes)
+		if err != nil {
+			return err
+		}
+		c.buckets = buckets
+		log.Info("%s batches are aligned to event time window %s", c.String(), c.config.EventTime.Window)
+	}
 	return nil
 }
 
@@ -96,6 +107,21 @@ func (c *Queue) Start() error {
 	}
 	log.Info("queue listeners: %s", listeners.String())
 	go c.worker()
+	health.Register(c.healthName(), c.checkHealth)
+	return nil
+}
+
+func (c *Queue) healthName() string {
+	return fmt.Sprintf("pipeline/%s/queue/%s", c.pipelineName, c.name)
+}
+
+// checkHealth reports the queue as not ready when the in channel is almost full, the worker is blocked
+// by the sink which consumes slower than the events are produced
+func (c *Queue) checkHealth() error {
+	usage := float64(len(c.in)) / float64(cap(c.in))
+	if threshold := health.GetConfig().QueueUsageThreshold; usage > threshold {
+		return fmt.Errorf("queue usage %.2f exceeds threshold %.2f", usage, threshold)
+	}
 	return nil
 }
 
@@ -133,6 +159,13 @@ func (c *Queue) worker() {
 			})
 			return
 		case e := <-c.in:
+			if c.buckets != nil {
+				for _, es := range c.buckets.Add(e) {
+					c.flushEvents(es)
+				}
+				size = c.buckets.Size()
+				continue
+			}
 			if size == 0 {
 				firstEventAppendTime = time.Now()
 			}
@@ -143,10 +176,16 @@ func (c *Queue) worker() {
 				flush()
 			}
 		case <-flusher.C:
-			// Instead of going to flush directly, check whether the first event of batch append time exceeds the timeout.
-			// In order to ensure the integrity of batch as much as possible
-			// if size>0, firstEventAppendTime must be updated
-			if size > 0 && time.Since(firstEventAppendTime) > timeout {
+			if c.buckets != nil {
+				// the buckets are flushed when their windows end, instead of the timeout
+				for _, es := range c.buckets.Expired() {
+					c.flushEvents(es)
+				}
+				size = c.buckets.Size()
+			} else if size > 0 && time.Since(firstEventAppendTime) > timeout {
+				// Instead of going to flush directly, check whether the first event of batch append time exceeds the timeout.
+				// In order to ensure the integrity of batch as much as possible
+				// if size>0, firstEventAppendTime must be updated
 				flush()
 			}
 			eventbus.PublishOrDrop(eventbus.QueueMetricTopic, eventbus.QueueMetricData{
@@ -160,6 +199,7 @@ func (c *Queue) worker() {
 }
 
 func (c *Queue) Stop() {
+	health.Unregister(c.healthName())
 	close(c.done)
 	c.countDown.Wait()
 	log.Info("[%s]channel queue stop", c.pipelineName)
@@ -177,6 +217,11 @@ func (c *Queue) OutChan() chan api.Batch {
 	return c.out
 }
 
+func (c *Queue) flushEvents(events []api.Event) {
+	c.beforeQueueConvertBatch(events)
+	c.out <- batch.NewBatchWithEvents(events)
+}
+
 func (c *Queue) beforeQueueConvertBatch(events []api.Event) {
 	for _, listener := range c.listeners {
 		listener.BeforeQueueConvertBatch(events)
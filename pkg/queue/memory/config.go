@@ -16,7 +16,11 @@ limitations under the License.
 
 package memory
 
-import "time"
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/queue"
+)
 
 type Config struct {
 	BatchSize          int           `yaml:"batchSize" default:"2048"`
@@ -24,4 +28,15 @@ type Config struct {
 	BatchBytes         int64         `yaml:"batchBytes" default:"33554432"` // default:32MB
 	BatchAggMaxTimeout time.Duration `yaml:"batchAggTimeout" default:"1s"`
 	CleanDataTimeout   time.Duration `yaml:"cleanDataTimeout" default:"5s"`
+	// Shards is the number of ring buffers which are consumed and batched independently, 1 by default and
+	// -1 means GOMAXPROCS. The memory used is multiplied by the shards. The events are written to any idle
+	// shard, so the events of a source, even a single file, are no longer sent in order with more than one shard.
+	Shards int `yaml:"shards" validate:"gte=-1"`
+	// EventTime aligns the batches to the event time windows instead of batchAggTimeout, the shards
+	// aggregate their own buckets
+	EventTime queue.EventTimeConfig `yaml:"eventTime,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	return c.EventTime.Validate()
 }
@@ -18,15 +18,19 @@ package memory
 
 import (
 	"fmt"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/smartystreets-prototypes/go-disruptor"
 
 	"github.com/loggie-io/loggie/pkg/core/api"
 	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/health"
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/queue"
 	"github.com/loggie-io/loggie/pkg/core/result"
 	"github.com/loggie-io/loggie/pkg/core/spi"
 	"github.com/loggie-io/loggie/pkg/eventbus"
@@ -51,19 +55,34 @@ func makeQueue(info pipeline.Info) api.Component {
 }
 
 type Queue struct {
-	pipelineName   string
-	sinkCount      int
-	config         *Config
-	done           chan struct{}
-	name           string
+	pipelineName string
+	sinkCount    int
+	config       *Config
+	done         chan struct{}
+	name         string
+	shards       []*shard
+	next         uint32
+	reservations int64
+	out          chan api.Batch
+	listeners    []spi.QueueListener
+	countDown    *sync.WaitGroup
+}
+
+// shard is a disruptor with its own ring buffer and batches, the events of a producer are written to
+// any shard which is not locked by the others, so producers on different cores would not contend with each other.
+type shard struct {
+	index          int
+	lock           sync.Mutex
 	ringBuffer     []api.Event
 	ringBufferMask int64
-	ringBufferSize int64
 	d              *disruptor.Disruptor
-	reservations   int64
-	out            chan api.Batch
-	listeners      []spi.QueueListener
-	countDown      *sync.WaitGroup
+	// size of the batch being aggregated
+	size int64
+	// buffered is the number of events in the ring buffer which have not been read by the consumer
+	buffered int64
+	// out is preferred by the sink worker of the shard, it is nil with a single shard
+	out      chan api.Batch
+	consumer *innerConsumer
 }
 
 func (c *Queue) Type() api.Type {
@@ -96,18 +115,46 @@ func (c *Queue) Init(context api.Context) error {
 	}
 	log.Info("%s batch size: %d; batch buffer factor: %d", c.String(),
 		c.config.BatchSize, c.config.BatchBufferFactor)
-	c.ringBufferSize = int64(c.config.BatchSize * c.config.BatchBufferFactor)
-	c.ringBufferMask = c.ringBufferSize - 1
-	c.ringBuffer = make([]api.Event, c.ringBufferSize)
+	shards := c.config.Shards
+	if shards < 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	if shards == 0 {
+		shards = 1
+	}
+	log.Info("%s shards: %d", c.String(), shards)
+
+	ringBufferSize := int64(c.config.BatchSize * c.config.BatchBufferFactor)
 	c.reservations = 1
 	c.out = make(chan api.Batch, c.sinkCount)
-
-	// init disruptor
-	d := disruptor.New(
-		disruptor.WithCapacity(c.ringBufferSize),
-		disruptor.WithConsumerGroup(newConsumer(c)),
-	)
-	c.d = &d
+	c.shards = make([]*shard, 0, shards)
+	for i := 0; i < shards; i++ {
+		s := &shard{
+			index:          i,
+			ringBuffer:     make([]api.Event, ringBufferSize),
+			ringBufferMask: ringBufferSize - 1,
+		}
+		if shards > 1 {
+			// unbuffered, so the batches are never left in a shard without its sink worker waiting
+			s.out = make(chan api.Batch)
+		}
+		var buckets *queue.EventTimeBuckets
+		if c.config.EventTime.Enabled {
+			b, err := queue.NewEventTimeBuckets(&c.config.EventTime, c.config.BatchSize, c.config.BatchBytes)
+			if err != nil {
+				return err
+			}
+			buckets = b
+		}
+		// init disruptor
+		s.consumer = newConsumer(c, s, buckets)
+		d := disruptor.New(
+			disruptor.WithCapacity(ringBufferSize),
+			disruptor.WithConsumerGroup(s.consumer),
+		)
+		s.d = &d
+		c.shards = append(c.shards, s)
+	}
 	return nil
 }
 
@@ -118,23 +165,54 @@ func (c *Queue) Start() error {
 		listeners.WriteString(" ")
 	}
 	log.Info("queue listeners: %s", listeners.String())
-	go c.startInnerConsumer()
+	// the consumers are started after all the shards are built, since the first one reports the metric of all
+	for _, s := range c.shards {
+		// counted before the goroutines start, so Stop waits for them even if called right after Start
+		c.countDown.Add(2)
+		go s.consumer.run()
+		go c.startInnerConsumer(s)
+	}
+	health.Register(c.healthName(), c.checkHealth)
 	return nil
 }
 
-func (c *Queue) startInnerConsumer() {
+func (c *Queue) healthName() string {
+	return fmt.Sprintf("pipeline/%s/queue/%s", c.pipelineName, c.name)
+}
+
+// checkHealth reports the queue as not ready when the ring buffers are almost full, the events are consumed
+// by the sink slower than they are produced
+func (c *Queue) checkHealth() error {
+	buffered := int64(0)
+	capacity := int64(0)
+	for _, s := range c.shards {
+		buffered += atomic.LoadInt64(&s.buffered)
+		capacity += s.ringBufferMask + 1
+	}
+	usage := float64(buffered) / float64(capacity)
+	if threshold := health.GetConfig().QueueUsageThreshold; usage > threshold {
+		return fmt.Errorf("queue usage %.2f exceeds threshold %.2f", usage, threshold)
+	}
+	return nil
+}
+
+func (c *Queue) startInnerConsumer(s *shard) {
 	// inner consumer aka disruptor reader goroutine, done by innerConsumer.Close() callback
-	c.countDown.Add(1)
-	c.d.Read()
+	s.d.Read()
 }
 
 func (c *Queue) Stop() {
-	if c.d != nil {
-		_ = c.d.Close()
+	health.Unregister(c.healthName())
+	for _, s := range c.shards {
+		if s.d != nil {
+			_ = s.d.Close()
+		}
 	}
 	close(c.done)
 	c.countDown.Wait()
-	c.ringBuffer = nil
+	for _, s := range c.shards {
+		s.ringBuffer = nil
+	}
 	log.Info("queue stop")
 }
 
@@ -150,15 +228,62 @@ func (c *Queue) OutChan() chan api.Batch {
 	return c.out
 }
 
+func (c *Queue) Shards() int {
+	return len(c.shards)
+}
+
+func (c *Queue) ShardOutChan(index int) chan api.Batch {
+	return c.shards[index].out
+}
+
 func (c *Queue) Consume(event api.Event) api.Result {
-	sequence := c.d.Reserve(c.reservations)
+	s := c.lockShard()
+	sequence := s.d.Reserve(c.reservations)
 	for lower := sequence - c.reservations + 1; lower <= sequence; lower++ {
-		c.ringBuffer[lower&c.ringBufferMask] = event
+		s.ringBuffer[lower&s.ringBufferMask] = event
 	}
-	c.d.Commit(sequence-c.reservations+1, sequence)
+	s.d.Commit(sequence-c.reservations+1, sequence)
+	atomic.AddInt64(&s.buffered, c.reservations)
+	s.lock.Unlock()
 	return result.NewResult(api.SUCCESS)
 }
 
+// lockShard locks the first idle shard starting from a round-robin offset, the writer of disruptor
+// is not safe for concurrent producers
+func (c *Queue) lockShard() *shard {
+	n := len(c.shards)
+	start := int(atomic.AddUint32(&c.next, 1) % uint32(n))
+	if n > 1 {
+		for i := 0; i < n; i++ {
+			s := c.shards[(start+i)%n]
+			if s.lock.TryLock() {
+				return s
+			}
+		}
+	}
+	s := c.shards[start]
+	s.lock.Lock()
+	return s
+}
+
+// publishMetric is called by the consumer of the first shard with the sum of all shards
+func (c *Queue) publishMetric(capacity int64) {
+	eventbus.PublishOrDrop(eventbus.QueueMetricTopic, c.metricData(capacity))
+}
+
+func (c *Queue) metricData(capacity int64) eventbus.QueueMetricData {
+	size := int64(0)
+	for _, s := range c.shards {
+		size += atomic.LoadInt64(&s.size)
+	}
+	return eventbus.QueueMetricData{
+		PipelineName: c.pipelineName,
+		Type:         string(c.Type()),
+		Capacity:     capacity * int64(len(c.shards)),
+		Size:         size,
+	}
+}
+
 func (c *Queue) beforeQueueConvertBatch(events []api.Event) {
 	for _, listener := range c.listeners {
 		listener.BeforeQueueConvertBatch(events)
@@ -190,22 +315,25 @@ type innerConsumer struct {
 	done        chan struct{}
 	out         chan api.Batch
 	queue       *Queue
+	shard       *shard
+	// buckets is not nil when the batches are aligned to the event time windows
+	buckets *queue.EventTimeBuckets
 }
 
-func newConsumer(queue *Queue) *innerConsumer {
-	batchSize := queue.config.BatchSize
+func newConsumer(q *Queue, shard *shard, buckets *queue.EventTimeBuckets) *innerConsumer {
+	batchSize := q.config.BatchSize
 	ic := &innerConsumer{
 		innerBuffer: make(chan []api.Event, batchSize),
-		done:        queue.done,
-		out:         queue.out,
-		queue:       queue,
+		done:        q.done,
+		out:         q.out,
+		queue:       q,
+		shard:       shard,
+		buckets:     buckets,
 	}
-	go ic.run()
 	return ic
 }
 
 func (ic *innerConsumer) run() {
-	ic.queue.countDown.Add(1)
 	timeout := ic.queue.config.BatchAggMaxTimeout
 	flusher := time.NewTicker(timeout)
 	defer func() {
@@ -220,22 +348,29 @@ func (ic *innerConsumer) run() {
 	firstEventAppendTime := time.Now()
 	buffer := make([]api.Event, 0, batchSize)
 	flush := func() {
-		ic.out <- batch.NewBatchWithEvents(buffer)
+		ic.send(batch.NewBatchWithEvents(buffer))
 		buffer = make([]api.Event, 0, batchSize)
 		size = 0
 		bytes = 0
+		atomic.StoreInt64(&ic.shard.size, 0)
 	}
 	for {
 		select {
 		case <-ic.done:
-			eventbus.PublishOrDrop(eventbus.QueueMetricTopic, eventbus.QueueMetricData{
-				PipelineName: ic.queue.pipelineName,
-				Type:         string(ic.queue.Type()),
-				Capacity:     int64(batchSize),
-				Size:         int64(size),
-			})
+			if ic.shard.index == 0 {
+				ic.queue.publishMetric(int64(batchSize))
+			}
 			return
 		case es := <-ic.innerBuffer:
+			if ic.buckets != nil {
+				for _, e := range es {
+					for _, full := range ic.buckets.Add(e) {
+						ic.send(batch.NewBatchWithEvents(full))
+					}
+				}
+				atomic.StoreInt64(&ic.shard.size, int64(ic.buckets.Size()))
+				continue
+			}
 			for _, e := range es {
 				if size == 0 {
 					firstEventAppendTime = time.Now()
@@ -247,19 +382,23 @@ func (ic *innerConsumer) run() {
 					flush()
 				}
 			}
+			atomic.StoreInt64(&ic.shard.size, int64(size))
 		case <-flusher.C:
-			// Instead of going to flush directly, check whether the first event of batch append time exceeds the timeout.
-			// In order to ensure the integrity of batch as much as possible
-			// if size>0, firstEventAppendTime must be updated
-			if size > 0 && time.Since(firstEventAppendTime) > timeout {
+			if ic.buckets != nil {
+				// the buckets are flushed when their windows end, instead of the timeout
+				for _, expired := range ic.buckets.Expired() {
+					ic.send(batch.NewBatchWithEvents(expired))
+				}
+				atomic.StoreInt64(&ic.shard.size, int64(ic.buckets.Size()))
+			} else if size > 0 && time.Since(firstEventAppendTime) > timeout {
+				// Instead of going to flush directly, check whether the first event of batch append time exceeds the timeout.
+				// In order to ensure the integrity of batch as much as possible
+				// if size>0, firstEventAppendTime must be updated
 				flush()
 			}
-			eventbus.PublishOrDrop(eventbus.QueueMetricTopic, eventbus.QueueMetricData{
-				PipelineName: ic.queue.pipelineName,
-				Type:         string(ic.queue.Type()),
-				Capacity:     int64(batchSize),
-				Size:         int64(size),
-			})
+			if ic.shard.index == 0 {
+				ic.queue.publishMetric(int64(batchSize))
+			}
 		}
 	}
 }
@@ -267,11 +406,14 @@ func (ic *innerConsumer) run() {
 func (ic *innerConsumer) Consume(lower, upper int64) {
 	batchSize := ic.queue.config.BatchSize
 	batchBytes := ic.queue.config.BatchBytes
-	ringBuffer := ic.queue.ringBuffer
-	ringBufferMask := ic.queue.ringBufferMask
+	ringBuffer := ic.shard.ringBuffer
+	ringBufferMask := ic.shard.ringBufferMask
 	size := int(upper - lower + 1)
+	// the slots are released after the consumer returns
+	defer atomic.AddInt64(&ic.shard.buffered, -int64(size))
 	//log.Info("disruptor-consumer count: %d", size)
-	if size < batchSize {
+	// the events are handed to the buckets as a whole when aligned to the event time windows
+	if size < batchSize || ic.buckets != nil {
 		es := make([]api.Event, 0, size)
 		for lower <= upper {
 			e := ringBuffer[lower&ringBufferMask]
@@ -293,7 +435,7 @@ func (ic *innerConsumer) Consume(lower, upper int64) {
 			bytes += int64(len(e.Body()))
 			if l >= batchSize || bytes >= batchBytes {
 				ic.queue.beforeQueueConvertBatch(es)
-				ic.out <- batch.NewBatchWithEvents(es)
+				ic.send(batch.NewBatchWithEvents(es))
 				es = make([]api.Event, 0, batchSize)
 				l = 0
 				bytes = 0
@@ -312,3 +454,16 @@ func (ic *innerConsumer) Close() error {
 	log.Info("%s inner consumer stop", ic.queue.String())
 	return nil
 }
+
+// send hands the batch to the sink worker of the shard, or any worker consuming the out channel of the queue
+// when the worker of the shard is busy
+func (ic *innerConsumer) send(b api.Batch) {
+	if ic.shard.out == nil {
+		ic.out <- b
+		return
+	}
+	select {
+	case ic.shard.out <- b:
+	case ic.out <- b:
+	}
+}
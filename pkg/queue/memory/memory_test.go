@@ -0,0 +1,156 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memory
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func init() {
+	log.InitDefaultLogger()
+}
+
+func newTestQueue(t *testing.T, shards int) *Queue {
+	q := &Queue{
+		pipelineName: "test",
+		sinkCount:    1,
+		config: &Config{
+			BatchSize:          16,
+			BatchBufferFactor:  2,
+			BatchBytes:         1 << 20,
+			BatchAggMaxTimeout: 50 * time.Millisecond,
+			Shards:             shards,
+		},
+	}
+	assert.NoError(t, q.Init(context.NewContext("queue", Type, api.QUEUE, nil)))
+	return q
+}
+
+func TestQueue_Shards(t *testing.T) {
+	tests := []struct {
+		name   string
+		shards int
+		want   int
+	}{
+		{name: "default", shards: 0, want: 1},
+		{name: "single", shards: 1, want: 1},
+		{name: "multiple", shards: 4, want: 4},
+		{name: "gomaxprocs", shards: -1, want: runtime.GOMAXPROCS(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue(t, tt.shards)
+			assert.Equal(t, tt.want, q.Shards())
+			for i := 0; i < q.Shards(); i++ {
+				// the sink workers are only assigned to the shards when there are more than one
+				assert.Equal(t, tt.want > 1, q.ShardOutChan(i) != nil)
+			}
+		})
+	}
+}
+
+func TestQueue_Consume(t *testing.T) {
+	for _, shards := range []int{1, 4} {
+		t.Run(fmt.Sprintf("shards %d", shards), func(t *testing.T) {
+			q := newTestQueue(t, shards)
+			assert.NoError(t, q.Start())
+
+			total := 100
+			go func() {
+				for i := 0; i < total; i++ {
+					q.In(event.NewEvent(map[string]interface{}{}, []byte("event")))
+				}
+			}()
+
+			// consume the out channel and the shard channels like the sink workers
+			received := 0
+			timeout := time.After(5 * time.Second)
+			for received < total {
+				var b api.Batch
+				select {
+				case b = <-q.OutChan():
+				case b = <-q.ShardOutChan(0):
+				case b = <-q.ShardOutChan(q.Shards() - 1):
+				case <-timeout:
+					t.Fatalf("received %d of %d events", received, total)
+				}
+				assert.LessOrEqual(t, len(b.Events()), q.config.BatchSize)
+				received += len(b.Events())
+			}
+			assert.Equal(t, total, received)
+
+			stopped := make(chan struct{})
+			go func() {
+				q.Stop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-time.After(5 * time.Second):
+				t.Fatal("queue is not stopped")
+			}
+		})
+	}
+}
+
+func TestQueue_StopWithBufferedEvents(t *testing.T) {
+	q := newTestQueue(t, 2)
+	q.config.BatchAggMaxTimeout = time.Hour
+	assert.NoError(t, q.Start())
+
+	// the events are left in the batches being aggregated
+	for i := 0; i < 3; i++ {
+		q.In(event.NewEvent(map[string]interface{}{}, []byte("event")))
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		q.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queue is not stopped")
+	}
+	for _, s := range q.shards {
+		assert.Nil(t, s.ringBuffer)
+	}
+}
+
+func TestQueue_metricData(t *testing.T) {
+	q := newTestQueue(t, 3)
+	for i, s := range q.shards {
+		atomic.StoreInt64(&s.size, int64(i+1))
+	}
+
+	data := q.metricData(int64(q.config.BatchSize))
+	assert.Equal(t, "test", data.PipelineName)
+	assert.Equal(t, int64(6), data.Size)
+	assert.Equal(t, int64(3*16), data.Capacity)
+}
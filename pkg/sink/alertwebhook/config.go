@@ -18,9 +18,11 @@ package alertwebhook
 
 import (
 	"github.com/loggie-io/loggie/pkg/core/logalert"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
 )
 
 type Config struct {
 	Addr                 string `yaml:"addr,omitempty"`
 	logalert.AlertConfig `yaml:",inline"`
+	HostLimit            hostlimit.Config `yaml:"hostLimit,omitempty"`
 }
@@ -34,6 +34,7 @@ import (
 	"github.com/loggie-io/loggie/pkg/eventbus"
 	"github.com/loggie-io/loggie/pkg/pipeline"
 	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
 	"github.com/loggie-io/loggie/pkg/util"
 	"github.com/loggie-io/loggie/pkg/util/bufferpool"
 	"github.com/loggie-io/loggie/pkg/util/pattern"
@@ -59,6 +60,7 @@ type Sink struct {
 	temp         *template.Template
 	bp           *bufferpool.BufferPool
 	client       *http.Client
+	limiter      *hostlimit.Transport
 	method       string
 	subscribe    *eventbus.Subscribe
 	listener     *Listener
@@ -150,6 +152,11 @@ func (s *Sink) Start() error {
 		s.temp = temp
 	}
 
+	if s.config.HostLimit.Enabled() {
+		s.limiter = hostlimit.NewTransport(s.client.Transport, &s.config.HostLimit, s.pipelineName, s.name)
+		s.client.Transport = s.limiter
+	}
+
 	_ = s.listener.Start()
 
 	return nil
@@ -158,6 +165,9 @@ func (s *Sink) Start() error {
 func (s *Sink) Stop() {
 	eventbus.UnRegistrySubscribeTemporary(s.subscribe)
 	s.listener.Stop()
+	if s.limiter != nil {
+		s.limiter.Stop()
+	}
 }
 
 func (s *Sink) Consume(batch api.Batch) api.Result {
@@ -0,0 +1,234 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azureblob
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/spool"
+	"github.com/pkg/errors"
+)
+
+type uploadState struct {
+	Name string `json:"name"`
+	// Blocks are the ids of the staged blocks of a block blob
+	Blocks []string `json:"blocks,omitempty"`
+	// Position is the size of the append blob before the object is appended, it is unknown until the blob is created
+	Position *int64 `json:"position,omitempty"`
+	// Appended is the bytes of the object appended
+	Appended int64 `json:"appended,omitempty"`
+}
+
+// newUploadState records the blob name of the sealed spool
+func (s *Sink) newUploadState(id string, meta *spool.Meta) interface{} {
+	return &uploadState{Name: meta.Prefix + s.blobName(id)}
+}
+
+// blobName is unique for each flush of block blobs, or shared by the flushes of a partition of append blobs
+func (s *Sink) blobName(id string) string {
+	if s.config.BlobType == BlobTypeAppend {
+		return s.node + s.config.extension()
+	}
+	return s.node + "-" + id + s.config.extension()
+}
+
+// encodeObject compresses the lines of the spool
+func (s *Sink) encodeObject(in io.Reader, out io.Writer) (int, error) {
+	w, err := newCompressor(out, s.config.Compression)
+	if err != nil {
+		return 0, err
+	}
+	lines, err := spool.ReadLines(in, func(line []byte) error {
+		_, err := w.Write(line)
+		return err
+	})
+	if err != nil {
+		return lines, err
+	}
+	return lines, w.Close()
+}
+
+func (s *Sink) upload(ctx context.Context, id string) error {
+	state := &uploadState{}
+	if err := s.buffer.ReadState(id, state); err != nil {
+		return errors.WithMessagef(err, "read upload state %s", id)
+	}
+
+	f, err := s.buffer.Open(id)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if s.config.BlobType == BlobTypeAppend {
+		err = s.appendBlob(ctx, f, info.Size(), id, state)
+	} else {
+		err = s.uploadBlockBlob(ctx, f, info.Size(), id, state)
+	}
+	if err != nil {
+		return err
+	}
+	log.Debug("[%s] uploaded %s to blob %s/%s", s.name, id, s.config.Container, state.Name)
+	s.buffer.Remove(id)
+	return nil
+}
+
+// uploadBlockBlob puts the blob in one request, or stages the blocks and commits them, the staged blocks are recorded
+// in the state, so the upload is resumed from the next block after restarting
+func (s *Sink) uploadBlockBlob(ctx context.Context, f *os.File, size int64, id string, state *uploadState) error {
+	contentType := s.config.contentType()
+	if size <= s.config.BlockSize && len(state.Blocks) == 0 {
+		body, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		return errors.WithMessagef(s.cli.putBlob(ctx, state.Name, body, contentType), "put blob %s", state.Name)
+	}
+
+	block := make([]byte, s.config.BlockSize)
+	for offset := int64(len(state.Blocks)) * s.config.BlockSize; offset < size; offset += s.config.BlockSize {
+		n, err := f.ReadAt(block, offset)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		blockId := newBlockId(len(state.Blocks))
+		if err := s.cli.putBlock(ctx, state.Name, blockId, block[:n]); err != nil {
+			return errors.WithMessagef(err, "put block %d of %s", len(state.Blocks), state.Name)
+		}
+		state.Blocks = append(state.Blocks, blockId)
+		if err := s.buffer.WriteState(id, state); err != nil {
+			return err
+		}
+	}
+
+	if err := s.cli.putBlockList(ctx, state.Name, state.Blocks, contentType); err != nil {
+		// the uncommitted blocks are garbage collected after a week
+		if isCode(err, codeInvalidBlockList) {
+			log.Warn("[%s] staged blocks of %s are gone, restart the upload", s.name, state.Name)
+			state.Blocks = nil
+			if werr := s.buffer.WriteState(id, state); werr != nil {
+				log.Warn("[%s] reset upload state error: %v", s.name, werr)
+			}
+		}
+		return errors.WithMessagef(err, "put block list of %s", state.Name)
+	}
+	return nil
+}
+
+// newBlockId returns the id of the nth block, the ids of a blob should have the same length
+func newBlockId(n int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", n)))
+}
+
+// appendBlob appends the object to the blob of the partition. The appends are conditional on the position, so a block
+// appended before the state was saved is detected by the size of the blob rather than being appended twice.
+func (s *Sink) appendBlob(ctx context.Context, f *os.File, size int64, id string, state *uploadState) error {
+	if state.Position == nil {
+		if err := s.cli.createAppendBlob(ctx, state.Name, s.config.contentType()); err != nil {
+			return errors.WithMessagef(err, "create append blob %s", state.Name)
+		}
+		position, err := s.cli.blobSize(ctx, state.Name)
+		if err != nil {
+			return errors.WithMessagef(err, "get size of blob %s", state.Name)
+		}
+		state.Position = &position
+		state.Appended = 0
+		if err := s.buffer.WriteState(id, state); err != nil {
+			return err
+		}
+	}
+
+	block := make([]byte, s.config.BlockSize)
+	for state.Appended < size {
+		n, err := f.ReadAt(block, state.Appended)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		position := *state.Position + state.Appended
+		if err := s.cli.appendBlock(ctx, state.Name, position, block[:n]); err != nil {
+			if !s.appended(ctx, err, id, state, position+int64(n)) {
+				return errors.WithMessagef(err, "append block to %s at %d", state.Name, position)
+			}
+		}
+		state.Appended += int64(n)
+		if err := s.buffer.WriteState(id, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appended checks whether the failed block has been appended by the last attempt
+func (s *Sink) appended(ctx context.Context, err error, id string, state *uploadState, end int64) bool {
+	if isCode(err, codeBlobNotFound) || isStatus(err, http.StatusNotFound) {
+		s.resetAppend(id, state)
+		return false
+	}
+	if !isCode(err, codeAppendPosition) {
+		return false
+	}
+	size, serr := s.cli.blobSize(ctx, state.Name)
+	if serr != nil {
+		return false
+	}
+	if size == end {
+		return true
+	}
+	// the blob was modified by others, the object is appended again at the end
+	s.resetAppend(id, state)
+	return false
+}
+
+// resetAppend restarts appending the object, such as the blob was deleted by the lifecycle rules
+func (s *Sink) resetAppend(id string, state *uploadState) {
+	log.Warn("[%s] append blob %s is changed, restart appending", s.name, state.Name)
+	state.Position = nil
+	state.Appended = 0
+	if err := s.buffer.WriteState(id, state); err != nil {
+		log.Warn("[%s] reset upload state error: %v", s.name, err)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func newCompressor(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	}
+	return nopWriteCloser{w}, nil
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azureblob
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/azure"
+	"github.com/pkg/errors"
+)
+
+const (
+	resource   = "https://storage.azure.com/"
+	apiVersion = "2021-08-06"
+
+	codeBlobAlreadyExists = "BlobAlreadyExists"
+	codeAppendPosition    = "AppendPositionConditionNotMet"
+	codeBlobNotFound      = "BlobNotFound"
+	codeInvalidBlockList  = "InvalidBlockList"
+	headerErrorCode       = "x-ms-error-code"
+	headerAppendPosition  = "x-ms-blob-condition-appendpos"
+	headerBlobType        = "x-ms-blob-type"
+	headerBlobContentType = "x-ms-blob-content-type"
+	blobTypeBlockBlob     = "BlockBlob"
+	blobTypeAppendBlob    = "AppendBlob"
+)
+
+// responseError is the error returned by the blob service, such as BlobNotFound
+type responseError struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("status %d, code %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func isCode(err error, code string) bool {
+	var re *responseError
+	return errors.As(err, &re) && re.Code == code
+}
+
+type blockList struct {
+	XMLName xml.Name `xml:"BlockList"`
+	Latest  []string `xml:"Latest"`
+}
+
+type client struct {
+	config *Config
+	tokens *azure.TokenProvider
+	http   *http.Client
+}
+
+func newClient(config *Config) *client {
+	c := &client{
+		config: config,
+		http: &http.Client{
+			Timeout: config.Timeout,
+		},
+	}
+	if config.SASToken == "" {
+		c.tokens = azure.NewTokenProvider(&config.Config, resource)
+	}
+	return c
+}
+
+func (c *client) blobURL(name string, query url.Values) string {
+	u := fmt.Sprintf("%s/%s/%s", c.config.endpoint(), c.config.Container, escapePath(name))
+	q := query.Encode()
+	if c.config.SASToken != "" {
+		if q != "" {
+			q += "&"
+		}
+		q += c.config.SASToken
+	}
+	if q != "" {
+		u += "?" + q
+	}
+	return u
+}
+
+func escapePath(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+func (c *client) putBlob(ctx context.Context, name string, body []byte, contentType string) error {
+	header := http.Header{}
+	header.Set(headerBlobType, blobTypeBlockBlob)
+	header.Set("Content-Type", contentType)
+	_, _, err := c.do(ctx, http.MethodPut, name, nil, header, body)
+	return err
+}
+
+func (c *client) putBlock(ctx context.Context, name string, blockId string, body []byte) error {
+	query := url.Values{
+		"comp":    {"block"},
+		"blockid": {blockId},
+	}
+	_, _, err := c.do(ctx, http.MethodPut, name, query, nil, body)
+	return err
+}
+
+func (c *client) putBlockList(ctx context.Context, name string, blocks []string, contentType string) error {
+	body, err := xml.Marshal(&blockList{Latest: blocks})
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set(headerBlobContentType, contentType)
+	_, _, err = c.do(ctx, http.MethodPut, name, url.Values{"comp": {"blocklist"}}, header, append([]byte(xml.Header), body...))
+	return err
+}
+
+// createAppendBlob creates the append blob if it does not exist
+func (c *client) createAppendBlob(ctx context.Context, name string, contentType string) error {
+	header := http.Header{}
+	header.Set(headerBlobType, blobTypeAppendBlob)
+	header.Set("Content-Type", contentType)
+	header.Set("If-None-Match", "*")
+	_, _, err := c.do(ctx, http.MethodPut, name, nil, header, nil)
+	if err != nil && (isCode(err, codeBlobAlreadyExists) || isStatus(err, http.StatusConflict)) {
+		return nil
+	}
+	return err
+}
+
+// appendBlock appends the block only if the blob has the size of position
+func (c *client) appendBlock(ctx context.Context, name string, position int64, body []byte) error {
+	header := http.Header{}
+	header.Set(headerAppendPosition, strconv.FormatInt(position, 10))
+	_, _, err := c.do(ctx, http.MethodPut, name, url.Values{"comp": {"appendblock"}}, header, body)
+	return err
+}
+
+// blobSize returns the size of the blob
+func (c *client) blobSize(ctx context.Context, name string) (int64, error) {
+	header, _, err := c.do(ctx, http.MethodHead, name, nil, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+}
+
+func isStatus(err error, status int) bool {
+	var re *responseError
+	return errors.As(err, &re) && re.StatusCode == status
+}
+
+func (c *client) do(ctx context.Context, method string, name string, query url.Values, header http.Header, body []byte) (http.Header, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.blobURL(name, query), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("x-ms-version", apiVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+
+	if c.tokens != nil {
+		token, err := c.tokens.Token(ctx)
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "retrieve azure ad token")
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "read response")
+	}
+	if resp.StatusCode/100 != 2 {
+		re := &responseError{StatusCode: resp.StatusCode}
+		_ = xml.Unmarshal(respBody, re)
+		// there is no body of the head requests
+		if code := resp.Header.Get(headerErrorCode); code != "" {
+			re.Code = code
+		}
+		return nil, nil, re
+	}
+	return resp.Header, respBody, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azureblob
+
+import (
+	"strings"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/azure"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/pkg/errors"
+)
+
+const (
+	BlobTypeBlock  = "block"
+	BlobTypeAppend = "append"
+
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+
+	// the max size of an append block
+	maxAppendBlockSize = 4 * 1024 * 1024
+)
+
+type Config struct {
+	azure.Config `yaml:",inline"`
+
+	Account string `yaml:"account,omitempty"`
+	// Endpoint overrides https://<account>.blob.core.windows.net, such as http://127.0.0.1:10000/devstoreaccount1 of Azurite
+	Endpoint  string `yaml:"endpoint,omitempty"`
+	Container string `yaml:"container,omitempty" validate:"required"`
+	// SASToken is the query string of a shared access signature which allows to create and write the blobs,
+	// Azure AD is used to authorize when it is empty, by the workload identity or managed identity on AKS
+	SASToken string `yaml:"sasToken,omitempty"`
+
+	// BlobType could be block or append. A block blob is uploaded for each flush of a partition, the blob of a partition
+	// is appended by each flush in append mode, which is named without the id and is appended until the partition changes
+	BlobType string `yaml:"blobType,omitempty" default:"block" validate:"oneof=block append"`
+	// Key is the prefix of the blobs with the partitions, such as logs/dt=${+YYYY-MM-DD}/app=${fields.app}/
+	Key      string `yaml:"key,omitempty" default:"loggie/dt=${+YYYY-MM-DD}/" validate:"required"`
+	TimeZone string `yaml:"timeZone,omitempty" default:"UTC"` // of the time partitions
+	// Compression of the ndjson blobs, each flush is an individual gzip member or zstd frame in append mode,
+	// which are decompressed as a whole
+	Compression string `yaml:"compression,omitempty" default:"gzip" validate:"oneof=none gzip zstd"`
+
+	// BufferDir keeps the events not uploaded yet, the uploads are resumed from it after restarting
+	BufferDir     string        `yaml:"bufferDir,omitempty" default:"./data/azureblob"`
+	MaxFlushBytes int64         `yaml:"maxFlushBytes,omitempty" default:"67108864"` // the buffered bytes of a partition before uploading
+	FlushInterval time.Duration `yaml:"flushInterval,omitempty" default:"5m"`       // the max time a partition is buffered
+	MaxPartitions int           `yaml:"maxPartitions,omitempty" default:"100"`      // the oldest partition is uploaded when exceeded
+	BlockSize     int64         `yaml:"blockSize,omitempty" default:"4194304"`      // of the staged or appended blocks
+	Timeout       time.Duration `yaml:"timeout,omitempty" default:"1m"`             // of each request
+}
+
+func (c *Config) SetDefaults() {
+	c.Config.SetDefaults()
+	c.SASToken = strings.TrimPrefix(c.SASToken, "?")
+}
+
+func (c *Config) Validate() error {
+	if c.Account == "" && c.Endpoint == "" {
+		return errors.New("azure blob sink account or endpoint is required")
+	}
+	if err := pattern.Validate(c.Key); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(c.TimeZone); err != nil {
+		return errors.WithMessagef(err, "azure blob sink timeZone %s is invalid", c.TimeZone)
+	}
+	if c.BlockSize <= 0 {
+		return errors.New("azure blob sink blockSize should be positive")
+	}
+	if c.BlobType == BlobTypeAppend && c.BlockSize > maxAppendBlockSize {
+		return errors.Errorf("azure blob sink blockSize should not be greater than %d in append mode", maxAppendBlockSize)
+	}
+	return nil
+}
+
+func (c *Config) endpoint() string {
+	if c.Endpoint != "" {
+		return strings.TrimSuffix(c.Endpoint, "/")
+	}
+	return "https://" + c.Account + ".blob.core.windows.net"
+}
+
+// extension of the blob names, such as .ndjson.gz
+func (c *Config) extension() string {
+	switch c.Compression {
+	case CompressionGzip:
+		return ".ndjson.gz"
+	case CompressionZstd:
+		return ".ndjson.zst"
+	}
+	return ".ndjson"
+}
+
+func (c *Config) contentType() string {
+	switch c.Compression {
+	case CompressionGzip:
+		return "application/gzip"
+	case CompressionZstd:
+		return "application/zstd"
+	}
+	return "application/x-ndjson"
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azureblob
+
+import (
+	gocontext "context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/codec/json"
+)
+
+func init() {
+	log.InitDefaultLogger()
+}
+
+func newTestSink(t *testing.T, endpoint string, extra string) *Sink {
+	s := NewSink("test")
+	raw := `
+endpoint: ` + endpoint + `/account
+container: logs
+sasToken: ?sv=2021-08-06&sig=secret
+bufferDir: ` + t.TempDir() + `
+` + extra
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), s.config).Defaults().Validate().Do())
+	c := json.NewJson()
+	c.Init(&codec.Config{})
+	s.SetCodec(c)
+	assert.NoError(t, s.Init(context.NewContext("azureblob", Type, api.SINK, nil)))
+	assert.NoError(t, os.MkdirAll(s.buffer.Dir(), 0755))
+	return s
+}
+
+func TestBlobName(t *testing.T) {
+	tests := []struct {
+		blobType    string
+		compression string
+		want        string
+	}{
+		{BlobTypeBlock, CompressionGzip, "node-1.ndjson.gz"},
+		{BlobTypeBlock, CompressionNone, "node-1.ndjson"},
+		{BlobTypeAppend, CompressionZstd, "node.ndjson.zst"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.blobType+"/"+tt.compression, func(t *testing.T) {
+			s := &Sink{node: "node", config: &Config{BlobType: tt.blobType, Compression: tt.compression}}
+			assert.Equal(t, tt.want, s.blobName("1"))
+		})
+	}
+}
+
+func TestUpload(t *testing.T) {
+	const name = "logs/node.ndjson"
+	appended := int64(2)
+	tests := []struct {
+		name     string
+		blobType string
+		// the blob existed before uploading
+		blob   *string
+		object string
+		state  *uploadState
+		// the blocks staged before restarting
+		blocks       map[string]string
+		wantRequests []string
+		wantBlob     string
+	}{
+		{
+			name:         "put block blob",
+			object:       "0123",
+			state:        &uploadState{Name: name},
+			wantRequests: []string{"put"},
+			wantBlob:     "0123",
+		},
+		{
+			name:         "staged blocks",
+			object:       "0123456789",
+			state:        &uploadState{Name: name},
+			wantRequests: []string{"block", "block", "block", "blocklist"},
+			wantBlob:     "0123456789",
+		},
+		{
+			name:         "resumed from the next block",
+			object:       "0123456789",
+			state:        &uploadState{Name: name, Blocks: []string{newBlockId(0)}},
+			blocks:       map[string]string{newBlockId(0): "0123"},
+			wantRequests: []string{"block", "block", "blocklist"},
+			wantBlob:     "0123456789",
+		},
+		{
+			name:         "append blob created",
+			blobType:     BlobTypeAppend,
+			object:       "012345",
+			state:        &uploadState{Name: name},
+			wantRequests: []string{"create", "size", "append", "append"},
+			wantBlob:     "012345",
+		},
+		{
+			name:         "append blob existed",
+			blobType:     BlobTypeAppend,
+			blob:         strPtr("xx"),
+			object:       "0123",
+			state:        &uploadState{Name: name},
+			wantRequests: []string{"create", "size", "append"},
+			wantBlob:     "xx0123",
+		},
+		{
+			name:     "appended before crash",
+			blobType: BlobTypeAppend,
+			// the first block was appended, but the state was not saved
+			blob:         strPtr("xx0123"),
+			object:       "0123456789",
+			state:        &uploadState{Name: name, Position: &appended},
+			wantRequests: []string{"append", "size", "append", "append"},
+			wantBlob:     "xx0123456789",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob := tt.blob
+			blocks := make(map[string]string)
+			for id, block := range tt.blocks {
+				blocks[id] = block
+			}
+			var requests []string
+			fail := func(w http.ResponseWriter, status int, code string) {
+				w.Header().Set(headerErrorCode, code)
+				w.WriteHeader(status)
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/account/logs/"+name, r.URL.Path)
+				assert.Equal(t, "secret", r.URL.Query().Get("sig"))
+				body, _ := io.ReadAll(r.Body)
+				query := r.URL.Query()
+				switch {
+				case r.Method == http.MethodHead:
+					requests = append(requests, "size")
+					w.Header().Set("Content-Length", strconv.Itoa(len(*blob)))
+				case query.Get("comp") == "block":
+					requests = append(requests, "block")
+					blocks[query.Get("blockid")] = string(body)
+					w.WriteHeader(http.StatusCreated)
+				case query.Get("comp") == "blocklist":
+					requests = append(requests, "blocklist")
+					list := &blockList{}
+					assert.NoError(t, xml.Unmarshal(body, list))
+					var content string
+					for _, id := range list.Latest {
+						content += blocks[id]
+					}
+					blob = &content
+					w.WriteHeader(http.StatusCreated)
+				case query.Get("comp") == "appendblock":
+					requests = append(requests, "append")
+					if r.Header.Get(headerAppendPosition) != strconv.Itoa(len(*blob)) {
+						fail(w, http.StatusPreconditionFailed, codeAppendPosition)
+						return
+					}
+					content := *blob + string(body)
+					blob = &content
+					w.WriteHeader(http.StatusCreated)
+				case r.Header.Get(headerBlobType) == blobTypeAppendBlob:
+					requests = append(requests, "create")
+					if blob != nil {
+						fail(w, http.StatusConflict, codeBlobAlreadyExists)
+						return
+					}
+					blob = strPtr("")
+					w.WriteHeader(http.StatusCreated)
+				default:
+					requests = append(requests, "put")
+					content := string(body)
+					blob = &content
+					w.WriteHeader(http.StatusCreated)
+				}
+			}))
+			defer server.Close()
+
+			extra := ""
+			if tt.blobType != "" {
+				extra = "blobType: " + tt.blobType
+			}
+			s := newTestSink(t, server.URL, extra)
+			s.config.BlockSize = 4
+			assert.NoError(t, os.WriteFile(filepath.Join(s.buffer.Dir(), "1.object"), []byte(tt.object), 0644))
+			assert.NoError(t, s.buffer.WriteState("1", tt.state))
+
+			s.process(gocontext.Background())
+			assert.Equal(t, tt.wantRequests, requests)
+			assert.Equal(t, tt.wantBlob, *blob)
+			entries, err := os.ReadDir(s.buffer.Dir())
+			assert.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		raw          string
+		wantErr      string
+		wantEndpoint string
+	}{
+		{
+			name:         "account",
+			raw:          "account: acc\ncontainer: logs",
+			wantEndpoint: "https://acc.blob.core.windows.net",
+		},
+		{
+			name:         "endpoint",
+			raw:          "endpoint: http://127.0.0.1:10000/devstoreaccount1/\ncontainer: logs",
+			wantEndpoint: "http://127.0.0.1:10000/devstoreaccount1",
+		},
+		{
+			name:    "without account or endpoint",
+			raw:     "container: logs",
+			wantErr: "account or endpoint is required",
+		},
+		{
+			name:    "append block size too large",
+			raw:     "account: acc\ncontainer: logs\nblobType: append\nblockSize: 8388608",
+			wantErr: "should not be greater than",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			err := cfg.UnPackFromRaw([]byte(tt.raw), c).Defaults().Validate().Do()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantEndpoint, c.endpoint())
+		})
+	}
+}
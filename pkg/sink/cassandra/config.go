@@ -0,0 +1,117 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cassandra
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	defaultPort = "9042"
+	// maxTTL is the max ttl of cassandra, 20 years
+	maxTTL = 630720000 * time.Second
+)
+
+var consistencies = map[string]uint16{
+	"any":          0x0000,
+	"one":          0x0001,
+	"two":          0x0002,
+	"three":        0x0003,
+	"quorum":       0x0004,
+	"all":          0x0005,
+	"local_quorum": 0x0006,
+	"each_quorum":  0x0007,
+	"local_one":    0x000A,
+}
+
+type Config struct {
+	// Hosts are the contact points such as 127.0.0.1:9042, the other nodes are discovered from system.peers
+	Hosts    []string `yaml:"hosts,omitempty" validate:"required"`
+	Keyspace string   `yaml:"keyspace,omitempty" validate:"required"`
+	Table    string   `yaml:"table,omitempty" validate:"required"`
+	Columns  []Column `yaml:"columns,omitempty" validate:"required,dive"`
+	// TimestampColumn is filled with the time when the event was produced, it should be a timestamp column
+	TimestampColumn string `yaml:"timestampColumn,omitempty"`
+	// TTL expires the rows, TTLKey refers to the ttl of each event in seconds or a duration such as 72h, which overrides TTL
+	TTL         time.Duration `yaml:"ttl,omitempty"`
+	TTLKey      string        `yaml:"ttlKey,omitempty"`
+	Consistency string        `yaml:"consistency,omitempty" default:"local_quorum" validate:"oneof=any one two three quorum all local_quorum each_quorum local_one"`
+
+	Username           string `yaml:"username,omitempty"`
+	Password           string `yaml:"password,omitempty"`
+	PasswordFile       string `yaml:"passwordFile,omitempty"`
+	TLS                bool   `yaml:"tls,omitempty"`
+	CACertPath         string `yaml:"caCertPath,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+
+	// MaxBatchRows is the max rows in an unlogged batch, cassandra warns when a batch covers more than 10 partitions
+	MaxBatchRows int `yaml:"maxBatchRows,omitempty" default:"10" validate:"gte=1"`
+	// TokenAware sends the rows to the node owning the partition instead of the contact points
+	TokenAware          *bool         `yaml:"tokenAware,omitempty" default:"true"`
+	RingRefreshInterval time.Duration `yaml:"ringRefreshInterval,omitempty" default:"5m"`
+	Timeout             time.Duration `yaml:"timeout,omitempty" default:"10s"`
+}
+
+// Column maps a key of the event to a column of the table
+type Column struct {
+	Name string `yaml:"name,omitempty" validate:"required"`
+	Key  string `yaml:"key,omitempty"` // such as fields.app, the same as name if empty, body means the event body
+}
+
+func (c *Column) SetDefaults() {
+	if c.Key == "" {
+		c.Key = c.Name
+	}
+}
+
+func (c *Config) SetDefaults() {
+	for i, h := range c.Hosts {
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			c.Hosts[i] = net.JoinHostPort(h, defaultPort)
+		}
+	}
+}
+
+func (c *Config) Validate() error {
+	names := make(map[string]struct{})
+	if c.TimestampColumn != "" {
+		names[c.TimestampColumn] = struct{}{}
+	}
+	for _, col := range c.Columns {
+		if _, ok := names[col.Name]; ok {
+			return fmt.Errorf("cassandra sink column %s is duplicated", col.Name)
+		}
+		names[col.Name] = struct{}{}
+	}
+	if c.TTL < 0 || c.TTL > maxTTL {
+		return fmt.Errorf("cassandra sink ttl should be between 0 and %s", maxTTL)
+	}
+	if c.Password != "" && c.PasswordFile != "" {
+		return fmt.Errorf("password and passwordFile cannot be set at the same time")
+	}
+	return nil
+}
+
+func (c *Config) tokenAware() bool {
+	return c.TokenAware == nil || *c.TokenAware
+}
+
+func (c *Config) withTTL() bool {
+	return c.TTL > 0 || c.TTLKey != ""
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cassandra
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+)
+
+const cqlVersion = "3.0.0"
+
+// column is a bind marker of a prepared statement or a column of the rows
+type column struct {
+	name string
+	typ  typeInfo
+}
+
+type prepared struct {
+	id        []byte
+	columns   []column
+	pkIndexes []int
+}
+
+type rows struct {
+	columns []column
+	values  [][][]byte
+}
+
+// conn is a connection to a node, the requests are sent one by one
+type conn struct {
+	addr    string
+	timeout time.Duration
+
+	lock     sync.Mutex
+	c        net.Conn
+	prepared map[string]*prepared
+}
+
+func dialTLSConfig(config *Config) (*tls.Config, error) {
+	if !config.TLS && config.CACertPath == "" {
+		return nil, nil
+	}
+	return netutils.NewTLSConfig(config.CACertPath, "", "", config.InsecureSkipVerify)
+}
+
+// dial connects to the node and authenticates with the PasswordAuthenticator if it is required
+func dial(addr string, timeout time.Duration, tlsConfig *tls.Config, username string, password string) (*conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	var (
+		c   net.Conn
+		err error
+	)
+	if tlsConfig != nil {
+		c, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		c, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &conn{
+		addr:     addr,
+		timeout:  timeout,
+		c:        c,
+		prepared: make(map[string]*prepared),
+	}
+	if err := cn.startup(username, password); err != nil {
+		c.Close()
+		return nil, errors.WithMessagef(err, "startup %s", addr)
+	}
+	return cn, nil
+}
+
+func (c *conn) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.c.Close()
+}
+
+// request sends the message and waits for the response, the ERROR response is returned as a *cqlError
+func (c *conn) request(opcode byte, body []byte) (*frame, error) {
+	c.c.SetDeadline(time.Now().Add(c.timeout))
+	if err := writeFrame(c.c, protoVersion, 0, opcode, body); err != nil {
+		return nil, err
+	}
+	f, err := readFrame(c.c)
+	if err != nil {
+		return nil, err
+	}
+	if f.opcode == opError {
+		return nil, parseError(f.body)
+	}
+	return f, nil
+}
+
+func (c *conn) startup(username string, password string) error {
+	b := &builder{}
+	b.stringMap(map[string]string{"CQL_VERSION": cqlVersion})
+	f, err := c.request(opStartup, b.buf)
+	if err != nil {
+		return err
+	}
+	if f.opcode == opReady {
+		return nil
+	}
+	if f.opcode != opAuthenticate {
+		return errors.Errorf("unexpected opcode 0x%02x of startup", f.opcode)
+	}
+	if username == "" {
+		return errors.New("authentication is required by the server")
+	}
+
+	// the PasswordAuthenticator accepts the SASL PLAIN token
+	token := make([]byte, 0, len(username)+len(password)+2)
+	token = append(token, 0)
+	token = append(token, username...)
+	token = append(token, 0)
+	token = append(token, password...)
+	b = &builder{}
+	b.bytes(token)
+	f, err = c.request(opAuthResponse, b.buf)
+	if err != nil {
+		return err
+	}
+	if f.opcode != opAuthSuccess {
+		return errors.Errorf("unexpected opcode 0x%02x of authentication", f.opcode)
+	}
+	return nil
+}
+
+func (c *conn) query(stmt string, consistency uint16) (*rows, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	b := &builder{}
+	b.longString(stmt)
+	b.short(consistency)
+	b.byte(0)
+	f, err := c.request(opQuery, b.buf)
+	if err != nil {
+		return nil, err
+	}
+	r := &reader{buf: f.body}
+	kind := r.readInt()
+	if kind != resultRows {
+		return &rows{}, r.err
+	}
+	columns := readMetadata(r)
+	n := int(r.readInt())
+	out := &rows{columns: columns}
+	for i := 0; i < n && r.err == nil; i++ {
+		row := make([][]byte, len(columns))
+		for j := range row {
+			row[j] = r.readBytes()
+		}
+		out.values = append(out.values, row)
+	}
+	return out, r.err
+}
+
+// prepare returns the cached statement, or prepares it on the node
+func (c *conn) prepare(stmt string) (*prepared, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.prepareLocked(stmt)
+}
+
+func (c *conn) prepareLocked(stmt string) (*prepared, error) {
+	if p, ok := c.prepared[stmt]; ok {
+		return p, nil
+	}
+	b := &builder{}
+	b.longString(stmt)
+	f, err := c.request(opPrepare, b.buf)
+	if err != nil {
+		return nil, err
+	}
+	r := &reader{buf: f.body}
+	if kind := r.readInt(); kind != resultPrepared {
+		return nil, errors.Errorf("unexpected result kind %d of prepare", kind)
+	}
+	p := &prepared{id: r.readShortBytes()}
+	flags := r.readInt()
+	n := int(r.readInt())
+	pkCount := int(r.readInt())
+	for i := 0; i < pkCount && r.err == nil; i++ {
+		p.pkIndexes = append(p.pkIndexes, int(r.readShort()))
+	}
+	p.columns = readColumns(r, flags, n)
+	if r.err != nil {
+		return nil, errors.WithMessage(r.err, "parse prepared result")
+	}
+	c.prepared[stmt] = p
+	return p, nil
+}
+
+// batch executes the rows of the statement in an unlogged batch, the statement is prepared again
+// when the node has evicted it
+func (c *conn) batch(stmt string, rows [][][]byte, consistency uint16) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	p, err := c.prepareLocked(stmt)
+	if err != nil {
+		return err
+	}
+	err = c.execBatch(p, rows, consistency)
+	if e, ok := err.(*cqlError); ok && e.code == errUnprepared {
+		delete(c.prepared, stmt)
+		if p, err = c.prepareLocked(stmt); err != nil {
+			return err
+		}
+		err = c.execBatch(p, rows, consistency)
+	}
+	return err
+}
+
+func (c *conn) execBatch(p *prepared, rows [][][]byte, consistency uint16) error {
+	b := &builder{}
+	b.byte(batchUnlogged)
+	b.short(uint16(len(rows)))
+	for _, row := range rows {
+		b.byte(batchPrepared)
+		b.shortBytes(p.id)
+		b.short(uint16(len(row)))
+		for _, v := range row {
+			b.bytes(v)
+		}
+	}
+	b.short(consistency)
+	b.byte(0)
+	_, err := c.request(opBatch, b.buf)
+	return err
+}
+
+// readMetadata reads the metadata of the rows
+func readMetadata(r *reader) []column {
+	flags := r.readInt()
+	n := int(r.readInt())
+	if flags&metadataHasMorePages != 0 {
+		r.readBytes()
+	}
+	if flags&metadataNoMetadata != 0 {
+		return make([]column, n)
+	}
+	return readColumns(r, flags, n)
+}
+
+func readColumns(r *reader, flags int32, n int) []column {
+	if flags&metadataGlobalTableSpec != 0 {
+		r.readString() // keyspace
+		r.readString() // table
+	}
+	columns := make([]column, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		if flags&metadataGlobalTableSpec == 0 {
+			r.readString()
+			r.readString()
+		}
+		columns = append(columns, column{
+			name: r.readString(),
+			typ:  r.readOption(),
+		})
+	}
+	return columns
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cassandra
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// the cql native protocol v4, see https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v4.spec
+const (
+	protoVersion  = 0x04
+	responseFlag  = 0x80
+	headerSize    = 9
+	maxFrameBytes = 256 << 20
+
+	opError         = 0x00
+	opStartup       = 0x01
+	opReady         = 0x02
+	opAuthenticate  = 0x03
+	opQuery         = 0x07
+	opResult        = 0x08
+	opPrepare       = 0x09
+	opBatch         = 0x0D
+	opAuthChallenge = 0x0E
+	opAuthResponse  = 0x0F
+	opAuthSuccess   = 0x10
+
+	resultVoid     = 0x0001
+	resultRows     = 0x0002
+	resultPrepared = 0x0004
+
+	batchUnlogged = 0x01
+	batchPrepared = 0x01
+
+	metadataGlobalTableSpec = 0x0001
+	metadataHasMorePages    = 0x0002
+	metadataNoMetadata      = 0x0004
+
+	errServer       = 0x0000
+	errProtocol     = 0x000A
+	errUnavailable  = 0x1000
+	errOverloaded   = 0x1001
+	errBootstrap    = 0x1002
+	errTruncate     = 0x1003
+	errWriteTimeout = 0x1100
+	errUnprepared   = 0x2500
+)
+
+type frame struct {
+	stream int16
+	opcode byte
+	body   []byte
+}
+
+func writeFrame(w io.Writer, version byte, stream int16, opcode byte, body []byte) error {
+	buf := make([]byte, headerSize, headerSize+len(body))
+	buf[0] = version
+	binary.BigEndian.PutUint16(buf[2:], uint16(stream))
+	buf[4] = opcode
+	binary.BigEndian.PutUint32(buf[5:], uint32(len(body)))
+	_, err := w.Write(append(buf, body...))
+	return err
+}
+
+func readFrame(r io.Reader) (*frame, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0]&^responseFlag != protoVersion {
+		return nil, errors.Errorf("unsupported protocol version %d", header[0]&^responseFlag)
+	}
+	length := binary.BigEndian.Uint32(header[5:])
+	if length > maxFrameBytes {
+		return nil, errors.Errorf("frame of %d bytes is too large", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &frame{
+		stream: int16(binary.BigEndian.Uint16(header[2:])),
+		opcode: header[4],
+		body:   body,
+	}, nil
+}
+
+// cqlError is the ERROR message returned by the server
+type cqlError struct {
+	code    int32
+	message string
+	// id of the unprepared statement
+	id []byte
+}
+
+func (e *cqlError) Error() string {
+	return fmt.Sprintf("cassandra error 0x%04x: %s", e.code, e.message)
+}
+
+// retryable reports whether the request could succeed on another coordinator
+func (e *cqlError) retryable() bool {
+	switch e.code {
+	case errServer, errProtocol, errUnavailable, errOverloaded, errBootstrap, errTruncate, errWriteTimeout:
+		return true
+	}
+	return false
+}
+
+func parseError(body []byte) error {
+	r := &reader{buf: body}
+	e := &cqlError{
+		code:    r.readInt(),
+		message: r.readString(),
+	}
+	if e.code == errUnprepared {
+		e.id = r.readShortBytes()
+	}
+	if r.err != nil {
+		return errors.WithMessage(r.err, "parse error message")
+	}
+	return e
+}
+
+// builder encodes the notations of the protocol
+type builder struct {
+	buf []byte
+}
+
+func (b *builder) byte(v byte) {
+	b.buf = append(b.buf, v)
+}
+
+func (b *builder) short(v uint16) {
+	b.buf = append(b.buf, byte(v>>8), byte(v))
+}
+
+func (b *builder) int(v int32) {
+	b.buf = append(b.buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (b *builder) string(s string) {
+	b.short(uint16(len(s)))
+	b.buf = append(b.buf, s...)
+}
+
+func (b *builder) longString(s string) {
+	b.int(int32(len(s)))
+	b.buf = append(b.buf, s...)
+}
+
+// bytes writes a [bytes], nil is a null value
+func (b *builder) bytes(v []byte) {
+	if v == nil {
+		b.int(-1)
+		return
+	}
+	b.int(int32(len(v)))
+	b.buf = append(b.buf, v...)
+}
+
+func (b *builder) shortBytes(v []byte) {
+	b.short(uint16(len(v)))
+	b.buf = append(b.buf, v...)
+}
+
+func (b *builder) stringMap(m map[string]string) {
+	b.short(uint16(len(m)))
+	for k, v := range m {
+		b.string(k)
+		b.string(v)
+	}
+}
+
+// reader decodes the notations of the protocol, the first error is kept and the following reads return zero values
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buf) < n {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	v := r.buf[:n]
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *reader) readByte() byte {
+	if v := r.next(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (r *reader) readShort() uint16 {
+	if v := r.next(2); v != nil {
+		return binary.BigEndian.Uint16(v)
+	}
+	return 0
+}
+
+func (r *reader) readInt() int32 {
+	if v := r.next(4); v != nil {
+		return int32(binary.BigEndian.Uint32(v))
+	}
+	return 0
+}
+
+func (r *reader) readString() string {
+	return string(r.next(int(r.readShort())))
+}
+
+// readBytes reads a [bytes], a null value is returned as nil
+func (r *reader) readBytes() []byte {
+	n := r.readInt()
+	if n < 0 {
+		return nil
+	}
+	return r.next(int(n))
+}
+
+func (r *reader) readShortBytes() []byte {
+	return r.next(int(r.readShort()))
+}
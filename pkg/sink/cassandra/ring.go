@@ -0,0 +1,204 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cassandra
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+const (
+	murmurC1 = 0x87c37b91114253d5
+	murmurC2 = 0x4cf5ad432745937f
+)
+
+// murmur3Token returns the token of the partition key by the Murmur3Partitioner, which is the h1 of murmur3 x64_128.
+// Unlike the reference implementation, the tail bytes are sign extended as cassandra does.
+func murmur3Token(key []byte) int64 {
+	length := len(key)
+	nblocks := length / 16
+
+	var h1, h2 uint64
+	for i := 0; i < nblocks; i++ {
+		k1 := binary.LittleEndian.Uint64(key[i*16:])
+		k2 := binary.LittleEndian.Uint64(key[i*16+8:])
+
+		k1 *= murmurC1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmurC2
+		h1 ^= k1
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= murmurC2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmurC1
+		h2 ^= k2
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	tail := key[nblocks*16:]
+	signed := func(i int) uint64 {
+		return uint64(int64(int8(tail[i])))
+	}
+	var k1, k2 uint64
+	switch len(tail) {
+	case 15:
+		k2 ^= signed(14) << 48
+		fallthrough
+	case 14:
+		k2 ^= signed(13) << 40
+		fallthrough
+	case 13:
+		k2 ^= signed(12) << 32
+		fallthrough
+	case 12:
+		k2 ^= signed(11) << 24
+		fallthrough
+	case 11:
+		k2 ^= signed(10) << 16
+		fallthrough
+	case 10:
+		k2 ^= signed(9) << 8
+		fallthrough
+	case 9:
+		k2 ^= signed(8)
+		k2 *= murmurC2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmurC1
+		h2 ^= k2
+		fallthrough
+	case 8:
+		k1 ^= signed(7) << 56
+		fallthrough
+	case 7:
+		k1 ^= signed(6) << 48
+		fallthrough
+	case 6:
+		k1 ^= signed(5) << 40
+		fallthrough
+	case 5:
+		k1 ^= signed(4) << 32
+		fallthrough
+	case 4:
+		k1 ^= signed(3) << 24
+		fallthrough
+	case 3:
+		k1 ^= signed(2) << 16
+		fallthrough
+	case 2:
+		k1 ^= signed(1) << 8
+		fallthrough
+	case 1:
+		k1 ^= signed(0)
+		k1 *= murmurC1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmurC2
+		h1 ^= k1
+	}
+
+	h1 ^= uint64(length)
+	h2 ^= uint64(length)
+	h1 += h2
+	h2 += h1
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+	h1 += h2
+
+	token := int64(h1)
+	if token == math.MinInt64 {
+		// the min token is reserved by the partitioner
+		return math.MaxInt64
+	}
+	return token
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
+
+// routingKey serializes the partition key, the components of a composite key are
+// encoded as <length><value><0> in turn
+func routingKey(values [][]byte, pkIndexes []int) []byte {
+	if len(pkIndexes) == 1 {
+		return values[pkIndexes[0]]
+	}
+	var key []byte
+	for _, i := range pkIndexes {
+		v := values[i]
+		key = append(key, byte(len(v)>>8), byte(len(v)))
+		key = append(key, v...)
+		key = append(key, 0)
+	}
+	return key
+}
+
+// ring maps the tokens to the nodes owning them, the primary replica of a token is the first node
+// whose token is greater than or equal to it
+type ring struct {
+	tokens []int64
+	hosts  []string
+}
+
+func newRing(hostTokens map[string][]int64) *ring {
+	type entry struct {
+		token int64
+		host  string
+	}
+	var entries []entry
+	for host, tokens := range hostTokens {
+		for _, t := range tokens {
+			entries = append(entries, entry{token: t, host: host})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].token < entries[j].token
+	})
+
+	r := &ring{
+		tokens: make([]int64, len(entries)),
+		hosts:  make([]string, len(entries)),
+	}
+	for i, e := range entries {
+		r.tokens[i] = e.token
+		r.hosts[i] = e.host
+	}
+	return r
+}
+
+func (r *ring) owner(token int64) string {
+	if r == nil || len(r.tokens) == 0 {
+		return ""
+	}
+	i := sort.Search(len(r.tokens), func(i int) bool {
+		return r.tokens[i] >= token
+	})
+	if i == len(r.tokens) {
+		i = 0
+	}
+	return r.hosts[i]
+}
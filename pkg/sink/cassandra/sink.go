@@ -0,0 +1,481 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cassandra
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	eventer "github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const Type = "cassandra"
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink(info.PipelineName)
+}
+
+type Sink struct {
+	pipelineName string
+	name         string
+	config       *Config
+	stmt         string
+	consistency  uint16
+	password     string
+	tlsConfig    *tls.Config
+
+	lock      sync.Mutex
+	conns     map[string]*conn
+	statement *prepared
+	ring      *ring
+	ringTime  time.Time
+}
+
+func NewSink(pipelineName string) *Sink {
+	return &Sink{
+		pipelineName: pipelineName,
+		config:       &Config{},
+		conns:        make(map[string]*conn),
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.consistency = consistencies[s.config.Consistency]
+
+	columns := make([]string, 0, len(s.config.Columns)+1)
+	if s.config.TimestampColumn != "" {
+		columns = append(columns, quote(s.config.TimestampColumn))
+	}
+	for _, c := range s.config.Columns {
+		columns = append(columns, quote(c.Name))
+	}
+	markers := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	s.stmt = fmt.Sprintf("INSERT INTO %s.%s (%s) VALUES (%s)", quote(s.config.Keyspace), quote(s.config.Table),
+		strings.Join(columns, ", "), markers)
+	if s.config.withTTL() {
+		s.stmt += " USING TTL ?"
+	}
+	return nil
+}
+
+func (s *Sink) Start() error {
+	s.password = s.config.Password
+	if s.config.PasswordFile != "" {
+		content, err := os.ReadFile(s.config.PasswordFile)
+		if err != nil {
+			return errors.WithMessage(err, "read password")
+		}
+		s.password = strings.TrimSpace(string(content))
+	}
+	tlsConfig, err := dialTLSConfig(s.config)
+	if err != nil {
+		return err
+	}
+	s.tlsConfig = tlsConfig
+
+	// the nodes may be unavailable now, the statement would be prepared again when consuming
+	if err := s.prepare(); err != nil {
+		log.Warn("%s prepare statement %s failed: %v", s.String(), s.stmt, err)
+	}
+	log.Info("%s start, hosts: %v, table: %s.%s", s.String(), s.config.Hosts, s.config.Keyspace, s.config.Table)
+	return nil
+}
+
+func (s *Sink) Stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for addr, c := range s.conns {
+		c.close()
+		delete(s.conns, addr)
+	}
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+	if err := s.prepare(); err != nil {
+		return result.Fail(errors.WithMessagef(err, "prepare statement %s", s.stmt))
+	}
+	s.refreshRing()
+
+	groups := make(map[string][][][]byte)
+	for _, e := range events {
+		values, err := s.values(e)
+		if err != nil {
+			// the event could never be inserted
+			log.Error("convert event to cassandra row error: %v; event is: %s", err, e.String())
+			continue
+		}
+		host := s.route(values)
+		groups[host] = append(groups[host], values)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errLock  sync.Mutex
+		firstErr error
+	)
+	for host, rows := range groups {
+		wg.Add(1)
+		go func(host string, rows [][][]byte) {
+			defer wg.Done()
+			for len(rows) > 0 {
+				n := s.config.MaxBatchRows
+				if n > len(rows) {
+					n = len(rows)
+				}
+				if err := s.send(host, rows[:n]); err != nil {
+					errLock.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errLock.Unlock()
+					return
+				}
+				rows = rows[n:]
+			}
+		}(host, rows)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return result.Fail(errors.WithMessage(firstErr, "insert into cassandra"))
+	}
+	return result.Success()
+}
+
+// values converts the event to the bound values of the statement
+func (s *Sink) values(e api.Event) ([][]byte, error) {
+	columns := s.statement.columns
+	values := make([][]byte, 0, len(columns))
+	add := func(v interface{}) error {
+		c := columns[len(values)]
+		data, err := encode(c.typ, v)
+		if err != nil {
+			return errors.WithMessagef(err, "column %s", c.name)
+		}
+		values = append(values, data)
+		return nil
+	}
+
+	if s.config.TimestampColumn != "" {
+		if err := add(timestamp(e)); err != nil {
+			return nil, err
+		}
+	}
+	obj := runtime.NewObject(e.Header())
+	for _, c := range s.config.Columns {
+		var v interface{}
+		if c.Key == codec.BodyKey {
+			v = string(e.Body())
+		} else {
+			v = obj.GetPath(c.Key).Value()
+		}
+		if err := add(v); err != nil {
+			return nil, err
+		}
+	}
+	if s.config.withTTL() {
+		ttl, err := s.ttl(obj)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(int64(ttl / time.Second)); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// ttl returns the ttl of the event, which could be the seconds or a duration
+func (s *Sink) ttl(obj *runtime.Object) (time.Duration, error) {
+	if s.config.TTLKey == "" {
+		return s.config.TTL, nil
+	}
+	v := obj.GetPath(s.config.TTLKey).Value()
+	if v == nil {
+		return s.config.TTL, nil
+	}
+	var ttl time.Duration
+	if str, ok := v.(string); ok {
+		if d, err := time.ParseDuration(str); err == nil {
+			ttl = d
+		} else if n, err := strconv.ParseInt(str, 10, 64); err == nil {
+			ttl = time.Duration(n) * time.Second
+		} else {
+			return 0, errors.Errorf("invalid ttl %s", str)
+		}
+	} else {
+		n, err := toInt(v)
+		if err != nil {
+			return 0, errors.WithMessage(err, "invalid ttl")
+		}
+		ttl = time.Duration(n) * time.Second
+	}
+	if ttl < 0 || ttl > maxTTL {
+		return 0, errors.Errorf("ttl %s is out of range", ttl)
+	}
+	return ttl, nil
+}
+
+func timestamp(e api.Event) time.Time {
+	if e.Meta() != nil {
+		if v, ok := e.Meta().Get(eventer.SystemProductTimeKey); ok {
+			if t, ok := v.(time.Time); ok {
+				return t
+			}
+		}
+	}
+	return time.Now()
+}
+
+// route returns the node owning the partition of the row, or empty to send it to the contact points
+func (s *Sink) route(values [][]byte) string {
+	s.lock.Lock()
+	r := s.ring
+	s.lock.Unlock()
+	if r == nil || len(s.statement.pkIndexes) == 0 {
+		return ""
+	}
+	return r.owner(murmur3Token(routingKey(values, s.statement.pkIndexes)))
+}
+
+// send inserts the rows by the node, the contact points are tried in turn when the node is unavailable
+func (s *Sink) send(host string, rows [][][]byte) error {
+	hosts := s.config.Hosts
+	if host != "" {
+		hosts = append([]string{host}, hosts...)
+	}
+	var lastErr error
+	for _, h := range hosts {
+		c, err := s.conn(h)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		err = c.batch(s.stmt, rows, s.consistency)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if e, ok := err.(*cqlError); ok {
+			if !e.retryable() {
+				return err
+			}
+			continue
+		}
+		// the connection is broken
+		s.closeConn(h, c)
+	}
+	return lastErr
+}
+
+// prepare gets the column types and the partition key of the statement
+func (s *Sink) prepare() error {
+	s.lock.Lock()
+	ok := s.statement != nil
+	s.lock.Unlock()
+	if ok {
+		return nil
+	}
+
+	var lastErr error
+	for _, h := range s.config.Hosts {
+		c, err := s.conn(h)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		p, err := c.prepare(s.stmt)
+		if err != nil {
+			if _, ok := err.(*cqlError); !ok {
+				s.closeConn(h, c)
+			}
+			lastErr = err
+			continue
+		}
+		if len(p.columns) != s.markers() {
+			return errors.Errorf("statement has %d bind markers, but %d are prepared", s.markers(), len(p.columns))
+		}
+		s.lock.Lock()
+		s.statement = p
+		s.lock.Unlock()
+		return nil
+	}
+	return lastErr
+}
+
+func (s *Sink) markers() int {
+	n := len(s.config.Columns)
+	if s.config.TimestampColumn != "" {
+		n++
+	}
+	if s.config.withTTL() {
+		n++
+	}
+	return n
+}
+
+// refreshRing loads the tokens of the nodes from a contact point if the ring is stale
+func (s *Sink) refreshRing() {
+	if !s.config.tokenAware() {
+		return
+	}
+	s.lock.Lock()
+	if time.Since(s.ringTime) < s.config.RingRefreshInterval {
+		s.lock.Unlock()
+		return
+	}
+	s.ringTime = time.Now()
+	s.lock.Unlock()
+
+	for _, h := range s.config.Hosts {
+		c, err := s.conn(h)
+		if err != nil {
+			continue
+		}
+		r, err := loadRing(c)
+		if err != nil {
+			log.Warn("%s load token ring from %s failed: %v", s.String(), h, err)
+			if _, ok := err.(*cqlError); !ok {
+				s.closeConn(h, c)
+			}
+			continue
+		}
+		s.lock.Lock()
+		s.ring = r
+		s.lock.Unlock()
+		return
+	}
+}
+
+// loadRing reads the tokens of the local node and the peers, the peers are connected by the port of the contact point
+func loadRing(c *conn) (*ring, error) {
+	_, port, err := net.SplitHostPort(c.addr)
+	if err != nil {
+		return nil, err
+	}
+	hostTokens := make(map[string][]int64)
+	for _, table := range []string{"local", "peers"} {
+		rs, err := c.query("SELECT rpc_address, tokens FROM system."+table, consistencies["one"])
+		if err != nil {
+			return nil, err
+		}
+		addrIdx, tokensIdx := -1, -1
+		for i, col := range rs.columns {
+			switch col.name {
+			case "rpc_address":
+				addrIdx = i
+			case "tokens":
+				tokensIdx = i
+			}
+		}
+		if addrIdx < 0 || tokensIdx < 0 {
+			return nil, errors.Errorf("rpc_address or tokens is missing in system.%s", table)
+		}
+		for _, row := range rs.values {
+			host := c.addr
+			if table == "peers" {
+				ip := decodeInet(row[addrIdx])
+				if ip == "" || net.ParseIP(ip).IsUnspecified() {
+					continue
+				}
+				host = net.JoinHostPort(ip, port)
+			}
+			for _, t := range decodeStringSet(row[tokensIdx]) {
+				token, err := strconv.ParseInt(t, 10, 64)
+				if err != nil {
+					// not the Murmur3Partitioner
+					return nil, errors.Errorf("unsupported token %s", t)
+				}
+				hostTokens[host] = append(hostTokens[host], token)
+			}
+		}
+	}
+	return newRing(hostTokens), nil
+}
+
+func (s *Sink) conn(addr string) (*conn, error) {
+	s.lock.Lock()
+	c, ok := s.conns[addr]
+	s.lock.Unlock()
+	if ok {
+		return c, nil
+	}
+
+	c, err := dial(addr, s.config.Timeout, s.tlsConfig, s.config.Username, s.password)
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if exist, ok := s.conns[addr]; ok {
+		c.close()
+		return exist, nil
+	}
+	s.conns[addr] = c
+	return c, nil
+}
+
+func (s *Sink) closeConn(addr string, c *conn) {
+	s.lock.Lock()
+	if s.conns[addr] == c {
+		delete(s.conns, addr)
+	}
+	s.lock.Unlock()
+	c.close()
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
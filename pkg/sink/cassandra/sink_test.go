@@ -0,0 +1,365 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cassandra
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+var preparedId = []byte("insert-logs")
+
+// fakeNode serves the cql native protocol with a table of (ts timestamp, app text, message text) whose partition key is app
+type fakeNode struct {
+	ln       net.Listener
+	username string
+	password string
+	tokens   []int64
+	peers    map[string][]int64
+	// unprepared returns an UNPREPARED error to the first batch
+	unprepared bool
+
+	lock     sync.Mutex
+	queries  []string
+	prepares int
+	rows     [][][]byte
+}
+
+func newFakeNode(t *testing.T, ip string, port int) *fakeNode {
+	ln, err := net.Listen("tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	assert.NoError(t, err)
+	n := &fakeNode{ln: ln}
+	go n.serve()
+	t.Cleanup(func() {
+		ln.Close()
+	})
+	return n
+}
+
+func (n *fakeNode) addr() string {
+	return n.ln.Addr().String()
+}
+
+func (n *fakeNode) serve() {
+	for {
+		c, err := n.ln.Accept()
+		if err != nil {
+			return
+		}
+		go n.handle(c)
+	}
+}
+
+func (n *fakeNode) handle(c net.Conn) {
+	defer c.Close()
+	for {
+		f, err := readFrame(c)
+		if err != nil {
+			return
+		}
+		op, body := n.response(f)
+		if err := writeFrame(c, protoVersion|responseFlag, f.stream, op, body); err != nil {
+			return
+		}
+	}
+}
+
+func (n *fakeNode) response(f *frame) (byte, []byte) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	b := &builder{}
+	r := &reader{buf: f.body}
+	switch f.opcode {
+	case opStartup:
+		if n.username == "" {
+			return opReady, nil
+		}
+		b.string("org.apache.cassandra.auth.PasswordAuthenticator")
+		return opAuthenticate, b.buf
+
+	case opAuthResponse:
+		token := string(r.readBytes())
+		if token != "\x00"+n.username+"\x00"+n.password {
+			b.int(0x0100)
+			b.string("bad credentials")
+			return opError, b.buf
+		}
+		b.bytes(nil)
+		return opAuthSuccess, b.buf
+
+	case opQuery:
+		stmt := string(r.next(int(r.readInt())))
+		n.queries = append(n.queries, stmt)
+		b.int(resultRows)
+		b.int(metadataGlobalTableSpec)
+		b.int(2)
+		b.string("system")
+		b.string("local")
+		b.string("rpc_address")
+		b.short(typeInet)
+		b.string("tokens")
+		b.short(typeSet)
+		b.short(typeVarchar)
+		if strings.HasSuffix(stmt, "system.local") {
+			b.int(1)
+			b.bytes(net.ParseIP("127.0.0.1").To4())
+			b.bytes(tokenSet(n.tokens))
+			return opResult, b.buf
+		}
+		b.int(int32(len(n.peers)))
+		for ip, tokens := range n.peers {
+			b.bytes(net.ParseIP(ip).To4())
+			b.bytes(tokenSet(tokens))
+		}
+		return opResult, b.buf
+
+	case opPrepare:
+		n.prepares++
+		b.int(resultPrepared)
+		b.shortBytes(preparedId)
+		b.int(metadataGlobalTableSpec)
+		b.int(4)
+		b.int(1)
+		b.short(1) // app
+		b.string("test")
+		b.string("logs")
+		for _, c := range []struct {
+			name string
+			typ  uint16
+		}{{"ts", typeTimestamp}, {"app", typeVarchar}, {"message", typeVarchar}, {"[ttl]", typeInt}} {
+			b.string(c.name)
+			b.short(c.typ)
+		}
+		b.int(metadataNoMetadata)
+		b.int(0)
+		return opResult, b.buf
+
+	case opBatch:
+		if n.unprepared {
+			n.unprepared = false
+			b.int(errUnprepared)
+			b.string("prepared statement is evicted")
+			b.shortBytes(preparedId)
+			return opError, b.buf
+		}
+		r.readByte()
+		count := int(r.readShort())
+		for i := 0; i < count; i++ {
+			r.readByte()
+			r.readShortBytes()
+			values := make([][]byte, r.readShort())
+			for j := range values {
+				values[j] = r.readBytes()
+			}
+			n.rows = append(n.rows, values)
+		}
+		b.int(resultVoid)
+		return opResult, b.buf
+	}
+	b.int(errProtocol)
+	b.string("unsupported opcode")
+	return opError, b.buf
+}
+
+func tokenSet(tokens []int64) []byte {
+	b := &builder{}
+	b.int(int32(len(tokens)))
+	for _, t := range tokens {
+		b.bytes([]byte(strconv.FormatInt(t, 10)))
+	}
+	return b.buf
+}
+
+func newTestSink(t *testing.T, raw string) *Sink {
+	s := NewSink("test")
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), s.config).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(context.NewContext("cassandra", Type, api.SINK, nil)))
+	assert.NoError(t, s.Start())
+	t.Cleanup(s.Stop)
+	return s
+}
+
+func newTestEvent(app string, body string, ttl interface{}) api.Event {
+	fields := map[string]interface{}{"app": app}
+	if ttl != nil {
+		fields["ttl"] = ttl
+	}
+	e := event.NewEvent(map[string]interface{}{"fields": fields}, []byte(body))
+	meta := event.NewDefaultMeta()
+	meta.Set(event.SystemProductTimeKey, time.Date(2023, 7, 22, 4, 26, 40, 0, time.UTC))
+	e.Fill(meta, e.Header(), e.Body())
+	return e
+}
+
+func TestConsumeTokenAware(t *testing.T) {
+	log.InitDefaultLogger()
+
+	node1 := newFakeNode(t, "127.0.0.1", 0)
+	port := node1.ln.Addr().(*net.TCPAddr).Port
+	node2 := newFakeNode(t, "127.0.0.2", port)
+	node1.username, node1.password = "loggie", "secret"
+	node2.username, node2.password = "loggie", "secret"
+	node1.unprepared = true
+
+	// nginx is owned by node1 and redis by node2
+	node1.tokens = []int64{murmur3Token([]byte("nginx"))}
+	node1.peers = map[string][]int64{"127.0.0.2": {murmur3Token([]byte("redis"))}}
+
+	s := newTestSink(t, `
+hosts: ["`+node1.addr()+`"]
+keyspace: test
+table: logs
+timestampColumn: ts
+ttl: 24h
+ttlKey: fields.ttl
+username: loggie
+password: secret
+columns:
+  - name: app
+    key: fields.app
+  - name: message
+    key: body
+`)
+	assert.Equal(t, `INSERT INTO "test"."logs" ("ts", "app", "message") VALUES (?, ?, ?) USING TTL ?`, s.stmt)
+
+	res := s.Consume(batch.NewBatchWithEvents([]api.Event{
+		newTestEvent("nginx", "a", nil),
+		newTestEvent("redis", "b", "1h"),
+		newTestEvent("nginx", "c", 60),
+	}))
+	assert.Equal(t, api.SUCCESS, res.Status())
+
+	// the statement evicted by node1 is prepared again
+	assert.Equal(t, 2, node1.prepares)
+	assert.Len(t, node1.rows, 2)
+	assert.Len(t, node2.rows, 1)
+	assert.Equal(t, be64(uint64(time.Date(2023, 7, 22, 4, 26, 40, 0, time.UTC).UnixMilli())), node1.rows[0][0])
+	assert.Equal(t, "nginx", string(node1.rows[0][1]))
+	assert.Equal(t, "a", string(node1.rows[0][2]))
+	assert.Equal(t, be32(86400), node1.rows[0][3])
+	assert.Equal(t, "c", string(node1.rows[1][2]))
+	assert.Equal(t, be32(60), node1.rows[1][3])
+	assert.Equal(t, "redis", string(node2.rows[0][1]))
+	assert.Equal(t, be32(3600), node2.rows[0][3])
+	assert.Equal(t, []string{"SELECT rpc_address, tokens FROM system.local", "SELECT rpc_address, tokens FROM system.peers"}, node1.queries)
+}
+
+func TestConsumeFallback(t *testing.T) {
+	log.InitDefaultLogger()
+
+	node := newFakeNode(t, "127.0.0.1", 0)
+	// the peer is unreachable, its rows are sent to the contact point
+	ln, err := net.Listen("tcp", "127.0.0.2:0")
+	assert.NoError(t, err)
+	unreachable := ln.Addr().(*net.TCPAddr)
+	ln.Close()
+	node.tokens = []int64{murmur3Token([]byte("nginx"))}
+	node.peers = map[string][]int64{unreachable.IP.String(): {murmur3Token([]byte("redis"))}}
+
+	s := newTestSink(t, `
+hosts: ["`+node.addr()+`"]
+keyspace: test
+table: logs
+timestampColumn: ts
+ttl: 24h
+maxBatchRows: 1
+columns:
+  - name: app
+    key: fields.app
+  - name: message
+    key: body
+`)
+	res := s.Consume(batch.NewBatchWithEvents([]api.Event{
+		newTestEvent("nginx", "a", nil),
+		newTestEvent("redis", "b", nil),
+	}))
+	assert.Equal(t, api.SUCCESS, res.Status())
+	assert.Len(t, node.rows, 2)
+}
+
+func TestMurmur3Token(t *testing.T) {
+	tests := []struct {
+		key  []byte
+		want int64
+	}{
+		{key: []byte(""), want: 0},
+		{key: []byte{0}, want: 5048724184180415669},
+		{key: []byte("hello"), want: -3758069500696749310},
+		{key: []byte("loggie-nginx-0123456789"), want: -5591089172777601455},
+		// the tail bytes are sign extended
+		{key: []byte{0xff, 0x80, 1, 2, 3, 4, 5, 6, 7, 8, 9, 0xaa}, want: -2147937614712145973},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, murmur3Token(tt.key), "key %x", tt.key)
+	}
+}
+
+func TestRingOwner(t *testing.T) {
+	r := newRing(map[string][]int64{
+		"a": {-100, 100},
+		"b": {0},
+	})
+	assert.Equal(t, "a", r.owner(-200))
+	assert.Equal(t, "a", r.owner(-100))
+	assert.Equal(t, "b", r.owner(-99))
+	assert.Equal(t, "a", r.owner(50))
+	// wraps around the ring
+	assert.Equal(t, "a", r.owner(200))
+}
+
+func TestEncode(t *testing.T) {
+	b, err := encode(typeInfo{id: typeInt}, "42")
+	assert.NoError(t, err)
+	assert.Equal(t, be32(42), b)
+
+	_, err = encode(typeInfo{id: typeTinyint}, 300)
+	assert.Error(t, err)
+
+	b, err = encode(typeInfo{id: typeVarchar}, map[string]interface{}{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(b))
+
+	b, err = encode(typeInfo{id: typeDate}, "1970-01-02")
+	assert.NoError(t, err)
+	assert.Equal(t, be32(dateEpoch+1), b)
+
+	b, err = encode(typeInfo{id: typeUUID}, "123e4567-e89b-12d3-a456-426614174000")
+	assert.NoError(t, err)
+	assert.Len(t, b, 16)
+
+	b, err = encode(typeInfo{id: typeMap, elems: []typeInfo{{id: typeVarchar}, {id: typeBigint}}}, map[string]interface{}{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 1, 0, 0, 0, 1, 'a', 0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0, 1}, b)
+
+	b, err = encode(typeInfo{id: typeVarchar}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, b)
+}
@@ -0,0 +1,388 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cassandra
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	stdjson "encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	typeCustom    = 0x0000
+	typeAscii     = 0x0001
+	typeBigint    = 0x0002
+	typeBlob      = 0x0003
+	typeBoolean   = 0x0004
+	typeCounter   = 0x0005
+	typeDecimal   = 0x0006
+	typeDouble    = 0x0007
+	typeFloat     = 0x0008
+	typeInt       = 0x0009
+	typeTimestamp = 0x000B
+	typeUUID      = 0x000C
+	typeVarchar   = 0x000D
+	typeVarint    = 0x000E
+	typeTimeUUID  = 0x000F
+	typeInet      = 0x0010
+	typeDate      = 0x0011
+	typeTime      = 0x0012
+	typeSmallint  = 0x0013
+	typeTinyint   = 0x0014
+	typeList      = 0x0020
+	typeMap       = 0x0021
+	typeSet       = 0x0022
+	typeUDT       = 0x0030
+	typeTuple     = 0x0031
+
+	// dates are the days since the epoch centered at 2^31
+	dateEpoch = 1 << 31
+)
+
+type typeInfo struct {
+	id    uint16
+	elems []typeInfo
+}
+
+func (r *reader) readOption() typeInfo {
+	t := typeInfo{id: r.readShort()}
+	switch t.id {
+	case typeCustom:
+		r.readString()
+	case typeList, typeSet:
+		t.elems = []typeInfo{r.readOption()}
+	case typeMap:
+		t.elems = []typeInfo{r.readOption(), r.readOption()}
+	case typeUDT:
+		r.readString() // keyspace
+		r.readString() // name
+		n := int(r.readShort())
+		for i := 0; i < n && r.err == nil; i++ {
+			r.readString()
+			t.elems = append(t.elems, r.readOption())
+		}
+	case typeTuple:
+		n := int(r.readShort())
+		for i := 0; i < n && r.err == nil; i++ {
+			t.elems = append(t.elems, r.readOption())
+		}
+	}
+	return t
+}
+
+// encode converts the value of the event to the cql type, nil is a null value
+func encode(t typeInfo, v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch t.id {
+	case typeAscii, typeVarchar:
+		return []byte(toString(v)), nil
+
+	case typeBlob:
+		if b, ok := v.([]byte); ok {
+			return b, nil
+		}
+		return []byte(toString(v)), nil
+
+	case typeBoolean:
+		b, err := toBool(v)
+		if err != nil {
+			return nil, err
+		}
+		if b {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+
+	case typeBigint, typeCounter:
+		n, err := toInt(v)
+		if err != nil {
+			return nil, err
+		}
+		return be64(uint64(n)), nil
+
+	case typeInt:
+		n, err := toIntRange(v, math.MinInt32, math.MaxInt32)
+		if err != nil {
+			return nil, err
+		}
+		return be32(uint32(n)), nil
+
+	case typeSmallint:
+		n, err := toIntRange(v, math.MinInt16, math.MaxInt16)
+		if err != nil {
+			return nil, err
+		}
+		return be16(uint16(n)), nil
+
+	case typeTinyint:
+		n, err := toIntRange(v, math.MinInt8, math.MaxInt8)
+		if err != nil {
+			return nil, err
+		}
+		return []byte{byte(n)}, nil
+
+	case typeDouble:
+		f, err := toFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		return be64(math.Float64bits(f)), nil
+
+	case typeFloat:
+		f, err := toFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		return be32(math.Float32bits(float32(f))), nil
+
+	case typeTimestamp:
+		ts, err := toTime(v)
+		if err != nil {
+			return nil, err
+		}
+		return be64(uint64(ts.UnixMilli())), nil
+
+	case typeDate:
+		ts, err := toTime(v)
+		if err != nil {
+			return nil, err
+		}
+		days := ts.Unix() / 86400
+		if ts.Unix() < 0 && ts.Unix()%86400 != 0 {
+			days--
+		}
+		return be32(uint32(days + dateEpoch)), nil
+
+	case typeUUID, typeTimeUUID:
+		s := strings.ReplaceAll(toString(v), "-", "")
+		b, err := hex.DecodeString(s)
+		if err != nil || len(b) != 16 {
+			return nil, errors.Errorf("invalid uuid %v", v)
+		}
+		return b, nil
+
+	case typeInet:
+		ip := net.ParseIP(toString(v))
+		if ip == nil {
+			return nil, errors.Errorf("invalid inet %v", v)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4, nil
+		}
+		return ip, nil
+
+	case typeList, typeSet:
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, errors.Errorf("value %v is not a list", v)
+		}
+		b := &builder{}
+		b.int(int32(len(items)))
+		for _, item := range items {
+			data, err := encode(t.elems[0], item)
+			if err != nil {
+				return nil, err
+			}
+			b.bytes(data)
+		}
+		return b.buf, nil
+
+	case typeMap:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("value %v is not a map", v)
+		}
+		b := &builder{}
+		b.int(int32(len(m)))
+		for k, item := range m {
+			key, err := encode(t.elems[0], k)
+			if err != nil {
+				return nil, err
+			}
+			data, err := encode(t.elems[1], item)
+			if err != nil {
+				return nil, err
+			}
+			b.bytes(key)
+			b.bytes(data)
+		}
+		return b.buf, nil
+	}
+	return nil, errors.Errorf("unsupported cql type 0x%04x", t.id)
+}
+
+// toString returns the strings and bytes as they are, and the json of the others such as a map
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case fmt.Stringer:
+		return val.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+func toBool(v interface{}) (bool, error) {
+	switch val := v.(type) {
+	case bool:
+		return val, nil
+	case string:
+		return strconv.ParseBool(val)
+	}
+	return false, errors.Errorf("value %v is not a boolean", v)
+}
+
+func toInt(v interface{}) (int64, error) {
+	switch val := v.(type) {
+	case int:
+		return int64(val), nil
+	case int8:
+		return int64(val), nil
+	case int16:
+		return int64(val), nil
+	case int32:
+		return int64(val), nil
+	case int64:
+		return val, nil
+	case uint:
+		return int64(val), nil
+	case uint8:
+		return int64(val), nil
+	case uint16:
+		return int64(val), nil
+	case uint32:
+		return int64(val), nil
+	case uint64:
+		return int64(val), nil
+	case float32:
+		return int64(val), nil
+	case float64:
+		return int64(val), nil
+	case stdjson.Number:
+		return val.Int64()
+	case string:
+		return strconv.ParseInt(val, 10, 64)
+	}
+	return 0, errors.Errorf("value %v is not an integer", v)
+}
+
+func toIntRange(v interface{}, min int64, max int64) (int64, error) {
+	n, err := toInt(v)
+	if err != nil {
+		return 0, err
+	}
+	if n < min || n > max {
+		return 0, errors.Errorf("value %v is out of range", v)
+	}
+	return n, nil
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch val := v.(type) {
+	case float32:
+		return float64(val), nil
+	case float64:
+		return val, nil
+	case stdjson.Number:
+		return val.Float64()
+	case string:
+		return strconv.ParseFloat(val, 64)
+	}
+	n, err := toInt(v)
+	if err != nil {
+		return 0, errors.Errorf("value %v is not a number", v)
+	}
+	return float64(n), nil
+}
+
+// toTime accepts a time, the milliseconds since the epoch, or a RFC3339 or date string
+func toTime(v interface{}) (time.Time, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, val); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse("2006-01-02", val); err == nil {
+			return t, nil
+		}
+		return time.Time{}, errors.Errorf("invalid time %s", val)
+	}
+	ms, err := toInt(v)
+	if err != nil {
+		return time.Time{}, errors.Errorf("value %v is not a time", v)
+	}
+	return time.UnixMilli(ms), nil
+}
+
+func be16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func be64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func decodeInet(b []byte) string {
+	if len(b) != net.IPv4len && len(b) != net.IPv6len {
+		return ""
+	}
+	return net.IP(b).String()
+}
+
+// decodeStringSet decodes a set<text> or list<text>
+func decodeStringSet(b []byte) []string {
+	if b == nil {
+		return nil
+	}
+	r := &reader{buf: b}
+	n := int(r.readInt())
+	values := make([]string, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		values = append(values, string(r.readBytes()))
+	}
+	return values
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clickhouse
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+)
+
+type Config struct {
+	// Hosts are the http addresses of the replicas, such as http://127.0.0.1:8123, the next one is tried
+	// when the insert failed with a retryable error
+	Hosts    []string `yaml:"hosts,omitempty" validate:"required"`
+	Username string   `yaml:"username,omitempty" default:"default"`
+	Password string   `yaml:"password,omitempty"`
+	Database string   `yaml:"database,omitempty" default:"default"`
+	Table    string   `yaml:"table,omitempty" validate:"required"`
+	Columns  []Column `yaml:"columns,omitempty" validate:"required,dive"`
+	// TimestampColumn is filled with the time when the event was produced, such as a DateTime64(3) column
+	TimestampColumn string `yaml:"timestampColumn,omitempty"`
+
+	// AsyncInsert buffers the inserts in the server, which is recommended when there are lots of agents
+	AsyncInsert        bool  `yaml:"asyncInsert,omitempty"`
+	WaitForAsyncInsert *bool `yaml:"waitForAsyncInsert,omitempty" default:"true"` // otherwise the events could be lost when the server fails to flush
+	Compress           bool  `yaml:"compress,omitempty"`                          // gzip the request body
+	// Settings are the additional query settings, such as insert_quorum: 2
+	Settings map[string]string `yaml:"settings,omitempty"`
+
+	MaxRetries int              `yaml:"maxRetries,omitempty" validate:"gte=0"` // every replica is tried once by default
+	Timeout    time.Duration    `yaml:"timeout,omitempty" default:"30s"`
+	HostLimit  hostlimit.Config `yaml:"hostLimit,omitempty"`
+}
+
+// Column maps a key of the event to a column of the table
+type Column struct {
+	Name string `yaml:"name,omitempty" validate:"required"`
+	Key  string `yaml:"key,omitempty"` // such as fields.app, the same as name if empty, body means the event body
+}
+
+func (c *Column) SetDefaults() {
+	if c.Key == "" {
+		c.Key = c.Name
+	}
+}
+
+func (c *Config) SetDefaults() {
+	if c.MaxRetries == 0 && len(c.Hosts) > 1 {
+		c.MaxRetries = len(c.Hosts) - 1
+	}
+}
+
+func (c *Config) Validate() error {
+	names := make(map[string]struct{})
+	if c.TimestampColumn != "" {
+		names[c.TimestampColumn] = struct{}{}
+	}
+	for _, col := range c.Columns {
+		if _, ok := names[col.Name]; ok {
+			return fmt.Errorf("clickhouse sink column %s is duplicated", col.Name)
+		}
+		names[col.Name] = struct{}{}
+	}
+	return nil
+}
+
+func (c *Config) waitForAsyncInsert() bool {
+	return c.WaitForAsyncInsert == nil || *c.WaitForAsyncInsert
+}
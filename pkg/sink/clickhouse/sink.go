@@ -0,0 +1,286 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clickhouse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	eventer "github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const (
+	Type = "clickhouse"
+
+	exceptionCodeHeader = "X-ClickHouse-Exception-Code"
+)
+
+// retryableCodes are the exception codes of the replica instead of the data, the insert could succeed on another replica
+var retryableCodes = map[int]struct{}{
+	159: {}, // TIMEOUT_EXCEEDED
+	164: {}, // READONLY
+	202: {}, // TOO_MANY_SIMULTANEOUS_QUERIES
+	203: {}, // NO_FREE_CONNECTION
+	209: {}, // SOCKET_TIMEOUT
+	210: {}, // NETWORK_ERROR
+	225: {}, // NO_ZOOKEEPER
+	242: {}, // TABLE_IS_READ_ONLY
+	252: {}, // TOO_MANY_PARTS
+	319: {}, // UNKNOWN_STATUS_OF_INSERT
+	999: {}, // KEEPER_EXCEPTION
+}
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink(info.PipelineName)
+}
+
+type Sink struct {
+	pipelineName string
+	name         string
+	config       *Config
+	client       *http.Client
+	limiter      *hostlimit.Transport
+
+	query string
+	next  uint32 // the host to start with, so the inserts are spread to the replicas
+}
+
+func NewSink(pipelineName string) *Sink {
+	return &Sink{
+		pipelineName: pipelineName,
+		config:       &Config{},
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.client = &http.Client{
+		Timeout: s.config.Timeout,
+	}
+
+	columns := make([]string, 0, len(s.config.Columns)+1)
+	if s.config.TimestampColumn != "" {
+		columns = append(columns, quote(s.config.TimestampColumn))
+	}
+	for _, c := range s.config.Columns {
+		columns = append(columns, quote(c.Name))
+	}
+	s.query = fmt.Sprintf("INSERT INTO %s.%s (%s) FORMAT JSONColumns", quote(s.config.Database), quote(s.config.Table),
+		strings.Join(columns, ", "))
+	return nil
+}
+
+func (s *Sink) Start() error {
+	if s.config.HostLimit.Enabled() {
+		s.limiter = hostlimit.NewTransport(s.client.Transport, &s.config.HostLimit, s.pipelineName, s.name)
+		s.client.Transport = s.limiter
+	}
+	log.Info("%s start, hosts: %v, table: %s.%s", s.String(), s.config.Hosts, s.config.Database, s.config.Table)
+	return nil
+}
+
+func (s *Sink) Stop() {
+	if s.limiter != nil {
+		s.limiter.Stop()
+	}
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	body, err := json.Marshal(s.columns(events))
+	if err != nil {
+		return result.Fail(errors.WithMessage(err, "marshal clickhouse columns"))
+	}
+
+	if err := s.insert(context.Background(), body); err != nil {
+		return result.Fail(errors.WithMessage(err, "insert into clickhouse"))
+	}
+	return result.Success()
+}
+
+// columns converts the events into the JSONColumns format, such as {"ts": [...], "body": [...]}
+func (s *Sink) columns(events []api.Event) map[string][]interface{} {
+	columns := make(map[string][]interface{}, len(s.config.Columns)+1)
+	for _, e := range events {
+		if s.config.TimestampColumn != "" {
+			columns[s.config.TimestampColumn] = append(columns[s.config.TimestampColumn], timestamp(e).Format(time.RFC3339Nano))
+		}
+		obj := runtime.NewObject(e.Header())
+		for _, c := range s.config.Columns {
+			var value interface{}
+			if c.Key == codec.BodyKey {
+				value = string(e.Body())
+			} else {
+				value = obj.GetPath(c.Key).Value()
+			}
+			columns[c.Name] = append(columns[c.Name], value)
+		}
+	}
+	return columns
+}
+
+func timestamp(e api.Event) time.Time {
+	if e.Meta() != nil {
+		if v, ok := e.Meta().Get(eventer.SystemProductTimeKey); ok {
+			if t, ok := v.(time.Time); ok {
+				return t
+			}
+		}
+	}
+	return time.Now()
+}
+
+// insert tries the replicas in turn until success or a non retryable error,
+// the deduplication token makes the retries idempotent for the replicated tables
+func (s *Sink) insert(ctx context.Context, body []byte) error {
+	sum := sha256.Sum256(body)
+	params := s.params(hex.EncodeToString(sum[:16]))
+
+	if s.config.Compress {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(body); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	start := int(atomic.AddUint32(&s.next, 1))
+	var err error
+	for i := 0; i <= s.config.MaxRetries; i++ {
+		host := s.config.Hosts[(start+i)%len(s.config.Hosts)]
+		var retryable bool
+		retryable, err = s.post(ctx, host, params, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable {
+			return err
+		}
+		log.Warn("%s insert into %s failed, try the next replica: %v", s.String(), host, err)
+	}
+	return err
+}
+
+func (s *Sink) params(dedupToken string) url.Values {
+	params := url.Values{}
+	params.Set("query", s.query)
+	params.Set("date_time_input_format", "best_effort")
+	params.Set("insert_deduplication_token", dedupToken)
+	if s.config.AsyncInsert {
+		params.Set("async_insert", "1")
+		if s.config.waitForAsyncInsert() {
+			params.Set("wait_for_async_insert", "1")
+		} else {
+			params.Set("wait_for_async_insert", "0")
+		}
+	}
+	for k, v := range s.config.Settings {
+		params.Set(k, v)
+	}
+	return params
+}
+
+func (s *Sink) post(ctx context.Context, host string, params url.Values, body []byte) (bool, error) {
+	u := strings.TrimSuffix(host, "/") + "/?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("X-ClickHouse-User", s.config.Username)
+	if s.config.Password != "" {
+		req.Header.Set("X-ClickHouse-Key", s.config.Password)
+	}
+	if s.config.Compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) || errors.Is(err, io.EOF), err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	code, _ := strconv.Atoi(resp.Header.Get(exceptionCodeHeader))
+	err = errors.Errorf("clickhouse returned status %d, code %d: %s", resp.StatusCode, code, strings.TrimSpace(string(msg)))
+	if _, ok := retryableCodes[code]; ok {
+		return true, err
+	}
+	// the errors without exception code are returned by the proxies, such as 502 and 503
+	return code == 0 && resp.StatusCode >= http.StatusInternalServerError, err
+}
+
+// quote escapes the identifier, so the names could contain any characters except backquote
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "") + "`"
+}
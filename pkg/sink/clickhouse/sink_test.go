@@ -0,0 +1,157 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clickhouse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	lcontext "github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func newTestSink(t *testing.T, raw string) *Sink {
+	log.InitDefaultLogger()
+	s := NewSink("test")
+	raw += `
+table: logs
+timestampColumn: ts
+columns:
+  - name: app
+    key: fields.app
+  - name: message
+    key: body
+`
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), s.config).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(lcontext.NewContext("clickhouse", Type, api.SINK, nil)))
+	return s
+}
+
+func TestColumns(t *testing.T) {
+	s := newTestSink(t, "hosts: [http://127.0.0.1:8123]")
+	assert.Equal(t, "INSERT INTO `default`.`logs` (`ts`, `app`, `message`) FORMAT JSONColumns", s.query)
+
+	var events []api.Event
+	for _, app := range []string{"nginx", "redis"} {
+		e := event.NewEvent(map[string]interface{}{"fields": map[string]interface{}{"app": app}}, []byte(app+" log"))
+		meta := event.NewDefaultMeta()
+		meta.Set(event.SystemProductTimeKey, time.Date(2023, 7, 22, 4, 26, 40, 0, time.UTC))
+		e.Fill(meta, e.Header(), e.Body())
+		events = append(events, e)
+	}
+	assert.Equal(t, map[string][]interface{}{
+		"ts":      {"2023-07-22T04:26:40Z", "2023-07-22T04:26:40Z"},
+		"app":     {"nginx", "redis"},
+		"message": {"nginx log", "redis log"},
+	}, s.columns(events))
+}
+
+func TestParams(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		async string
+		wait  string
+	}{
+		{name: "sync insert", raw: "hosts: [http://127.0.0.1:8123]"},
+		{name: "async insert", raw: "hosts: [http://127.0.0.1:8123]\nasyncInsert: true", async: "1", wait: "1"},
+		{name: "async insert without wait", raw: "hosts: [http://127.0.0.1:8123]\nasyncInsert: true\nwaitForAsyncInsert: false", async: "1", wait: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := newTestSink(t, tt.raw+"\nsettings: {insert_quorum: \"2\"}").params("token")
+			assert.Equal(t, tt.async, params.Get("async_insert"))
+			assert.Equal(t, tt.wait, params.Get("wait_for_async_insert"))
+			assert.Equal(t, "token", params.Get("insert_deduplication_token"))
+			assert.Equal(t, "2", params.Get("insert_quorum"))
+		})
+	}
+}
+
+type reply struct {
+	status int
+	code   int // the exception code of clickhouse
+}
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		name      string
+		replicas  []reply
+		wantTried []int
+		wantErr   bool
+	}{
+		{name: "success", replicas: []reply{{status: http.StatusOK}, {status: http.StatusOK}}, wantTried: []int{1, 0}},
+		{name: "retried on the next replica", replicas: []reply{{status: http.StatusInternalServerError, code: 242}, {status: http.StatusOK}}, wantTried: []int{1, 1}},
+		{name: "proxy error retried", replicas: []reply{{status: http.StatusBadGateway}, {status: http.StatusOK}}, wantTried: []int{1, 1}},
+		{name: "data error not retried", replicas: []reply{{status: http.StatusBadRequest, code: 27}, {status: http.StatusOK}}, wantTried: []int{1, 0}, wantErr: true},
+		{
+			name:      "all replicas failed",
+			replicas:  []reply{{status: http.StatusInternalServerError, code: 242}, {status: http.StatusServiceUnavailable}},
+			wantTried: []int{1, 1},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tried := make([]int, len(tt.replicas))
+			var hosts []string
+			for i, r := range tt.replicas {
+				i, r := i, r
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					tried[i]++
+					if r.code != 0 {
+						w.Header().Set(exceptionCodeHeader, strconv.Itoa(r.code))
+					}
+					w.WriteHeader(r.status)
+				}))
+				defer server.Close()
+				hosts = append(hosts, strconv.Quote(server.URL))
+			}
+
+			s := newTestSink(t, "hosts: ["+hosts[0]+", "+hosts[1]+"]")
+			// the replicas are tried in turn from the first one
+			s.next = uint32(len(hosts) - 1)
+			err := s.insert(context.Background(), []byte(`{"app":["nginx"]}`))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantTried, tried)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	c := &Config{}
+	raw := "hosts: [a, b, c]\ntable: logs\ntimestampColumn: ts\ncolumns: [{name: app}]"
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), c).Defaults().Validate().Do())
+	// every replica is tried once by default
+	assert.Equal(t, 2, c.MaxRetries)
+
+	raw = "hosts: [a]\ntable: logs\ntimestampColumn: ts\ncolumns: [{name: ts}]"
+	assert.Error(t, cfg.UnPackFromRaw([]byte(raw), &Config{}).Defaults().Validate().Do())
+}
@@ -0,0 +1,300 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package avro
+
+import (
+	"encoding/binary"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const (
+	Type = "avro"
+
+	VersionLatest = "latest"
+
+	// magicByte leads the confluent wire format, followed by the schema id in 4 bytes big endian and the avro binary
+	magicByte = 0
+)
+
+func init() {
+	codec.Register(Type, makeAvroCodec)
+}
+
+// Avro encodes the events in avro binary with the schema, which is defined locally or fetched from the schema registry.
+// When the schema registry is used, the events are encoded in the confluent wire format, so the kafka consumers
+// using the confluent avro deserializer could decode them without any configuration.
+type Avro struct {
+	config    *Config
+	codecConf *codec.Config
+	registry  *registry
+	// definition is the local schema
+	definition string
+
+	lock      sync.RWMutex
+	current   *schema
+	fetchTime time.Time
+}
+
+// schema is a resolved version of the schema, id is -1 when the schema registry is not used
+type schema struct {
+	id        int
+	codec     *goavro.Codec
+	converter *converter
+}
+
+type Config struct {
+	// Schema is the definition of the avro schema, or use SchemaFile instead
+	Schema     string `yaml:"schema,omitempty"`
+	SchemaFile string `yaml:"schemaFile,omitempty"`
+	// Registry is the confluent schema registry, the schema is fetched from it when no local schema is defined
+	Registry RegistryConfig `yaml:"registry,omitempty"`
+	// Subject of the schema in the registry, defaults to the full name of the record, as the record name strategy does
+	Subject string `yaml:"subject,omitempty"`
+	// AutoRegister registers the local schema to the subject, otherwise the schema should have been registered
+	AutoRegister *bool `yaml:"autoRegister,omitempty" default:"true"`
+	// Version of the subject is used when no local schema is defined, latest or a version number
+	Version string `yaml:"version,omitempty" default:"latest"`
+	// RefreshInterval fetches the latest version periodically, so the new versions of the schema are used without
+	// restarting, the fields added are filled by their defaults if the events do not contain them
+	RefreshInterval time.Duration `yaml:"refreshInterval,omitempty" default:"5m"`
+	// Fields maps the top level fields of the record to the paths of the event, such as service: fields.app
+	Fields map[string]string `yaml:"fields,omitempty"`
+}
+
+type RegistryConfig struct {
+	URL      string        `yaml:"url,omitempty"`
+	Username string        `yaml:"username,omitempty"`
+	Password string        `yaml:"password,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty" default:"10s"`
+}
+
+func (c *Config) Validate() error {
+	if c.Schema != "" && c.SchemaFile != "" {
+		return errors.New("schema and schemaFile cannot be set at the same time")
+	}
+	if c.Version != VersionLatest {
+		if v, err := strconv.Atoi(c.Version); err != nil || v <= 0 {
+			return errors.Errorf("version %s should be latest or a positive number", c.Version)
+		}
+	}
+
+	definition, err := c.definition()
+	if err != nil {
+		return err
+	}
+	if definition == "" {
+		if c.Registry.URL == "" {
+			return errors.New("schema, schemaFile or registry is required")
+		}
+		if c.Subject == "" {
+			return errors.New("subject is required when the schema is fetched from the registry")
+		}
+		return nil
+	}
+
+	if _, err := goavro.NewCodec(definition); err != nil {
+		return errors.WithMessage(err, "parse avro schema")
+	}
+	if c.Registry.URL != "" && c.Subject == "" && newConverter(definition).recordName() == "" {
+		return errors.New("subject is required when the schema is not a record")
+	}
+	return nil
+}
+
+func (c *Config) definition() (string, error) {
+	if c.SchemaFile == "" {
+		return c.Schema, nil
+	}
+	content, err := os.ReadFile(c.SchemaFile)
+	if err != nil {
+		return "", errors.WithMessagef(err, "read schema file %s", c.SchemaFile)
+	}
+	return string(content), nil
+}
+
+func makeAvroCodec() codec.Codec {
+	return NewAvro()
+}
+
+func NewAvro() *Avro {
+	return &Avro{
+		config: &Config{},
+	}
+}
+
+func (a *Avro) Config() interface{} {
+	return a.config
+}
+
+func (a *Avro) Init(config *codec.Config) {
+	a.codecConf = config
+	definition, err := a.config.definition()
+	if err != nil {
+		// the schema file has been validated, it may be changed since then
+		log.Panic("init avro codec failed: %v", err)
+	}
+	a.definition = definition
+
+	if a.config.Registry.URL != "" {
+		a.registry = newRegistry(&a.config.Registry)
+		if a.config.Subject == "" {
+			a.config.Subject = newConverter(definition).recordName()
+		}
+		// the schema registry is requested when encoding the first event, so the pipeline could start when it is unavailable
+		return
+	}
+
+	s, err := newSchema(-1, definition)
+	if err != nil {
+		log.Panic("init avro codec failed: %v", err)
+	}
+	a.current = s
+}
+
+func newSchema(id int, definition string) (*schema, error) {
+	c, err := goavro.NewCodec(definition)
+	if err != nil {
+		return nil, errors.WithMessage(err, "parse avro schema")
+	}
+	return &schema{
+		id:        id,
+		codec:     c,
+		converter: newConverter(definition),
+	}, nil
+}
+
+func (a *Avro) Encode(e api.Event) ([]byte, error) {
+	s, err := a.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	header := e.Header()
+	data := make(map[string]interface{}, len(header)+len(a.config.Fields)+1)
+	for k, v := range header {
+		data[k] = v
+	}
+	if len(e.Body()) != 0 {
+		data[event.Body] = string(e.Body())
+	}
+	if len(a.config.Fields) > 0 {
+		obj := runtime.NewObject(data)
+		for name, path := range a.config.Fields {
+			if val := obj.GetPath(path).Value(); val != nil {
+				data[name] = val
+			}
+		}
+	}
+
+	native, err := s.converter.native(data)
+	if err != nil {
+		return nil, errors.WithMessage(err, "convert event to avro")
+	}
+	var buf []byte
+	if s.id >= 0 {
+		buf = make([]byte, 5, 256)
+		buf[0] = magicByte
+		binary.BigEndian.PutUint32(buf[1:], uint32(s.id))
+	}
+	out, err := s.codec.BinaryFromNative(buf, native)
+	if err != nil {
+		return nil, errors.WithMessage(err, "encode avro")
+	}
+
+	if a.codecConf.PrintEvents {
+		log.Info("[print events] %x", out)
+	}
+	return out, nil
+}
+
+// schema returns the current schema, which is resolved from the registry at the first time, and refreshed
+// periodically when the latest version of the subject is used
+func (a *Avro) schema() (*schema, error) {
+	a.lock.RLock()
+	s, fetchTime := a.current, a.fetchTime
+	a.lock.RUnlock()
+	if s != nil && !a.refreshDue(fetchTime) {
+		return s, nil
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.current != nil && !a.refreshDue(a.fetchTime) {
+		return a.current, nil
+	}
+
+	resolved, err := a.resolve()
+	a.fetchTime = time.Now()
+	if err != nil {
+		if a.current != nil {
+			log.Warn("refresh avro schema of subject %s failed, keep using schema %d: %v", a.config.Subject, a.current.id, err)
+			return a.current, nil
+		}
+		return nil, err
+	}
+	if a.current == nil || a.current.id != resolved.id {
+		log.Info("avro codec uses schema %d of subject %s", resolved.id, a.config.Subject)
+	}
+	a.current = resolved
+	return a.current, nil
+}
+
+func (a *Avro) refreshDue(fetchTime time.Time) bool {
+	if a.registry == nil || a.definition != "" || a.config.Version != VersionLatest || a.config.RefreshInterval <= 0 {
+		return false
+	}
+	return time.Since(fetchTime) >= a.config.RefreshInterval
+}
+
+func (a *Avro) resolve() (*schema, error) {
+	subject := a.config.Subject
+	if a.definition != "" {
+		if a.config.AutoRegister != nil && *a.config.AutoRegister {
+			id, err := a.registry.register(subject, a.definition)
+			if err != nil {
+				return nil, err
+			}
+			return newSchema(id, a.definition)
+		}
+		registered, err := a.registry.lookup(subject, a.definition)
+		if err != nil {
+			return nil, err
+		}
+		return newSchema(registered.Id, a.definition)
+	}
+
+	v, err := a.registry.version(subject, a.config.Version)
+	if err != nil {
+		return nil, err
+	}
+	if a.current != nil && a.current.id == v.Id {
+		return a.current, nil
+	}
+	return newSchema(v.Id, v.Schema)
+}
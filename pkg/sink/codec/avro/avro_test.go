@@ -0,0 +1,269 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package avro
+
+import (
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const logSchema = `{
+	"type": "record",
+	"name": "Log",
+	"namespace": "app",
+	"fields": [
+		{"name": "body", "type": "string"},
+		{"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["INFO", "WARN", "ERROR"]}},
+		{"name": "offset", "type": "long"},
+		{"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "host", "type": ["null", "string"], "default": null},
+		{"name": "code", "type": ["null", "int", "string"]},
+		{"name": "tags", "type": {"type": "array", "items": "string"}, "default": []},
+		{"name": "labels", "type": {"type": "map", "values": "string"}, "default": {}},
+		{"name": "source", "type": ["null", {"type": "record", "name": "Source", "fields": [{"name": "file", "type": "string"}]}], "default": null},
+		{"name": "service", "type": "string", "default": "unknown"}
+	]
+}`
+
+func TestMain(m *testing.M) {
+	log.InitDefaultLogger()
+	m.Run()
+}
+
+func newAvro(t *testing.T, config cfg.CommonCfg) *Avro {
+	a := NewAvro()
+	assert.NoError(t, cfg.UnpackFromCommonCfg(config, a.config).Defaults().Validate().Do())
+	a.Init(&codec.Config{Type: Type})
+	return a
+}
+
+func decode(t *testing.T, schema string, b []byte) map[string]interface{} {
+	c, err := goavro.NewCodec(schema)
+	assert.NoError(t, err)
+	native, rest, err := c.NativeFromBinary(b)
+	assert.NoError(t, err)
+	assert.Empty(t, rest)
+	return native.(map[string]interface{})
+}
+
+func TestAvro_EncodeLocalSchema(t *testing.T) {
+	a := newAvro(t, cfg.CommonCfg{
+		"schema": logSchema,
+		"fields": map[string]interface{}{
+			"service": "fields.app",
+		},
+	})
+
+	e := event.NewEvent(map[string]interface{}{
+		"level":  "WARN",
+		"offset": float64(1024),
+		"time":   "2023-01-02T03:04:05.006Z",
+		"code":   200,
+		"tags":   []interface{}{"a", 1},
+		"labels": map[string]interface{}{"env": "prod"},
+		"source": map[string]interface{}{"file": "/var/log/a.log"},
+		"fields": map[string]interface{}{"app": "web"},
+		"extra":  "ignored",
+	}, []byte("hello"))
+	out, err := a.Encode(e)
+	assert.NoError(t, err)
+
+	native := decode(t, logSchema, out)
+	assert.Equal(t, "hello", native["body"])
+	assert.Equal(t, "WARN", native["level"])
+	assert.Equal(t, int64(1024), native["offset"])
+	assert.Equal(t, time.Date(2023, 1, 2, 3, 4, 5, 6e6, time.UTC), native["time"].(time.Time).UTC())
+	assert.Nil(t, native["host"])
+	assert.Equal(t, map[string]interface{}{"int": int32(200)}, native["code"])
+	assert.Equal(t, []interface{}{"a", "1"}, native["tags"])
+	assert.Equal(t, map[string]interface{}{"env": "prod"}, native["labels"])
+	assert.Equal(t, map[string]interface{}{"app.Source": map[string]interface{}{"file": "/var/log/a.log"}}, native["source"])
+	assert.Equal(t, "web", native["service"])
+
+	// the strings are kept in the string member of the union, rather than parsed as int
+	out, err = a.Encode(event.NewEvent(map[string]interface{}{
+		"level": "INFO", "offset": 1, "time": 1672628645006, "code": "200",
+	}, []byte("hello")))
+	assert.NoError(t, err)
+	native = decode(t, logSchema, out)
+	assert.Equal(t, map[string]interface{}{"string": "200"}, native["code"])
+	assert.Equal(t, "unknown", native["service"])
+
+	_, err = a.Encode(event.NewEvent(map[string]interface{}{
+		"level": "DEBUG", "offset": 1, "time": 1, "code": nil,
+	}, []byte("hello")))
+	assert.Error(t, err)
+}
+
+type fakeRegistry struct {
+	lock     sync.Mutex
+	down     bool
+	versions []string
+	requests []string
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	if f.down {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if user, pass, _ := r.BasicAuth(); user != "loggie" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error_code": 401, "message": "Unauthorized"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", registryContentType)
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/subjects/app.Log/versions":
+		body, _ := io.ReadAll(r.Body)
+		in := &registrySchema{}
+		_ = json.Unmarshal(body, in)
+		for i, v := range f.versions {
+			if v == in.Schema {
+				_, _ = w.Write([]byte(`{"id": ` + strconv.Itoa(i+1) + `}`))
+				return
+			}
+		}
+		f.versions = append(f.versions, in.Schema)
+		_, _ = w.Write([]byte(`{"id": ` + strconv.Itoa(len(f.versions)) + `}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/subjects/app.Log/versions/latest":
+		if len(f.versions) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error_code": 40401, "message": "Subject 'app.Log' not found."}`))
+			return
+		}
+		out, _ := json.Marshal(&registrySchema{
+			Subject: "app.Log",
+			Id:      len(f.versions),
+			Version: len(f.versions),
+			Schema:  f.versions[len(f.versions)-1],
+		})
+		_, _ = w.Write(out)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestAvro_EncodeRegisteredSchema(t *testing.T) {
+	fake := &fakeRegistry{versions: []string{"{}"}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	a := newAvro(t, cfg.CommonCfg{
+		"schema": logSchema,
+		"registry": map[string]interface{}{
+			"url":      server.URL,
+			"username": "loggie",
+			"password": "secret",
+		},
+	})
+	assert.Equal(t, "app.Log", a.config.Subject)
+
+	e := event.NewEvent(map[string]interface{}{"level": "INFO", "offset": 1, "time": 1, "code": nil}, []byte("hello"))
+	out, err := a.Encode(e)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(magicByte), out[0])
+	assert.Equal(t, uint32(2), binary.BigEndian.Uint32(out[1:5]))
+	assert.Equal(t, "hello", decode(t, logSchema, out[5:])["body"])
+
+	// the schema is registered only once
+	_, err = a.Encode(e)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"POST /subjects/app.Log/versions"}, fake.requests)
+}
+
+func TestAvro_SchemaEvolution(t *testing.T) {
+	v1 := `{"type": "record", "name": "Log", "namespace": "app", "fields": [{"name": "body", "type": "string"}]}`
+	v2 := `{"type": "record", "name": "Log", "namespace": "app", "fields": [{"name": "body", "type": "string"}, {"name": "level", "type": "string", "default": "INFO"}]}`
+	fake := &fakeRegistry{down: true}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	a := newAvro(t, cfg.CommonCfg{
+		"subject": "app.Log",
+		"registry": map[string]interface{}{
+			"url":      server.URL,
+			"username": "loggie",
+			"password": "secret",
+		},
+	})
+	a.config.RefreshInterval = time.Nanosecond
+	e := event.NewEvent(map[string]interface{}{}, []byte("hello"))
+
+	// the event could be retried when the registry is unavailable
+	_, err := a.Encode(e)
+	assert.Error(t, err)
+
+	fake.lock.Lock()
+	fake.down = false
+	fake.versions = []string{v1}
+	fake.lock.Unlock()
+	out, err := a.Encode(e)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), binary.BigEndian.Uint32(out[1:5]))
+	assert.Equal(t, map[string]interface{}{"body": "hello"}, decode(t, v1, out[5:]))
+
+	// the new version is used, the added field is filled by its default
+	fake.lock.Lock()
+	fake.versions = append(fake.versions, v2)
+	fake.lock.Unlock()
+	out, err = a.Encode(e)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), binary.BigEndian.Uint32(out[1:5]))
+	assert.Equal(t, map[string]interface{}{"body": "hello", "level": "INFO"}, decode(t, v2, out[5:]))
+
+	// the current version is kept when refreshing failed
+	fake.lock.Lock()
+	fake.down = true
+	fake.lock.Unlock()
+	out, err = a.Encode(e)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), binary.BigEndian.Uint32(out[1:5]))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	validate := func(config cfg.CommonCfg) error {
+		return cfg.UnpackFromCommonCfg(config, &Config{}).Defaults().Validate().Do()
+	}
+	assert.NoError(t, validate(cfg.CommonCfg{"schema": logSchema}))
+	assert.NoError(t, validate(cfg.CommonCfg{"subject": "app.Log", "version": "3", "registry": map[string]interface{}{"url": "http://registry:8081"}}))
+	assert.Error(t, validate(cfg.CommonCfg{}))
+	assert.Error(t, validate(cfg.CommonCfg{"schema": `{"type": "record"}`}))
+	assert.Error(t, validate(cfg.CommonCfg{"schema": logSchema, "schemaFile": "log.avsc"}))
+	assert.Error(t, validate(cfg.CommonCfg{"registry": map[string]interface{}{"url": "http://registry:8081"}}))
+	assert.Error(t, validate(cfg.CommonCfg{"schema": `"string"`, "registry": map[string]interface{}{"url": "http://registry:8081"}}))
+	assert.Error(t, validate(cfg.CommonCfg{"subject": "app.Log", "version": "v1", "registry": map[string]interface{}{"url": "http://registry:8081"}}))
+}
@@ -0,0 +1,549 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package avro
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+// converter converts the values of events to the native values of goavro by the schema. The values are coerced to
+// the types of the schema, the union values are wrapped with the names of the matched types, and the fields missing
+// in the events are left to the defaults of the schema, so the events need not know the schema versions.
+type converter struct {
+	schema interface{}
+	names  map[string]namedType
+}
+
+type namedType struct {
+	schema    map[string]interface{}
+	namespace string
+}
+
+var primitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true, "float": true, "double": true, "bytes": true, "string": true,
+}
+
+// logicalTypes are the logical types supported by goavro, the others are encoded as their underlying types
+var logicalTypes = map[string]bool{
+	"long.timestamp-millis": true, "long.timestamp-micros": true, "int.time-millis": true, "long.time-micros": true,
+	"int.date": true, "bytes.decimal": true,
+}
+
+func newConverter(schema string) *converter {
+	var s interface{}
+	if err := stdjson.Unmarshal([]byte(schema), &s); err != nil {
+		// the schema could be the name of a primitive type without quotes
+		s = strings.TrimSpace(schema)
+	}
+	c := &converter{
+		schema: s,
+		names:  make(map[string]namedType),
+	}
+	c.index(s, "")
+	return c
+}
+
+// fullName returns the full name of the named type and the namespace of its nested types
+func fullName(s map[string]interface{}, enclosing string) (string, string) {
+	name, _ := s["name"].(string)
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name, name[:i]
+	}
+	ns := enclosing
+	if n, ok := s["namespace"].(string); ok {
+		ns = n
+	}
+	if ns == "" {
+		return name, ""
+	}
+	return ns + "." + name, ns
+}
+
+func (c *converter) index(s interface{}, namespace string) {
+	switch t := s.(type) {
+	case []interface{}:
+		for _, member := range t {
+			c.index(member, namespace)
+		}
+	case map[string]interface{}:
+		typ, ok := t["type"].(string)
+		if !ok {
+			c.index(t["type"], namespace)
+			return
+		}
+		switch typ {
+		case "record", "error", "enum", "fixed":
+			full, ns := fullName(t, namespace)
+			c.names[full] = namedType{schema: t, namespace: namespace}
+			fields, _ := t["fields"].([]interface{})
+			for _, f := range fields {
+				if field, ok := f.(map[string]interface{}); ok {
+					c.index(field["type"], ns)
+				}
+			}
+		case "array":
+			c.index(t["items"], namespace)
+		case "map":
+			c.index(t["values"], namespace)
+		}
+	}
+}
+
+func (c *converter) lookup(name string, namespace string) (namedType, bool) {
+	if namespace != "" && !strings.Contains(name, ".") {
+		if t, ok := c.names[namespace+"."+name]; ok {
+			return t, true
+		}
+	}
+	t, ok := c.names[name]
+	return t, ok
+}
+
+// recordName returns the full name of the top level record, which is the subject of the record name strategy
+func (c *converter) recordName() string {
+	if s, ok := c.schema.(map[string]interface{}); ok {
+		if typ, _ := s["type"].(string); typ == "record" {
+			name, _ := fullName(s, "")
+			return name
+		}
+	}
+	return ""
+}
+
+func (c *converter) native(val interface{}) (interface{}, error) {
+	return c.convert(c.schema, "", val, false)
+}
+
+// convert coerces the value to the type of the schema, the strict mode only accepts the values of the same kind,
+// which is used to choose the member of a union before trying the coercions
+func (c *converter) convert(s interface{}, namespace string, val interface{}, strict bool) (interface{}, error) {
+	switch t := s.(type) {
+	case string:
+		if primitives[t] {
+			return convertPrimitive(t, val, strict)
+		}
+		named, ok := c.lookup(t, namespace)
+		if !ok {
+			return nil, errors.Errorf("unknown type %s", t)
+		}
+		return c.convert(named.schema, named.namespace, val, strict)
+
+	case []interface{}:
+		return c.convertUnion(t, namespace, val, strict)
+
+	case map[string]interface{}:
+		typ, ok := t["type"].(string)
+		if !ok {
+			return c.convert(t["type"], namespace, val, strict)
+		}
+		switch typ {
+		case "record", "error":
+			return c.convertRecord(t, namespace, val, strict)
+		case "enum":
+			return convertEnum(t, val, strict)
+		case "fixed":
+			return convertFixed(t, val)
+		case "array":
+			list, ok := toList(val)
+			if !ok {
+				return nil, errors.Errorf("%T could not be encoded as array", val)
+			}
+			out := make([]interface{}, 0, len(list))
+			for i, item := range list {
+				v, err := c.convert(t["items"], namespace, item, strict)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "item %d", i)
+				}
+				out = append(out, v)
+			}
+			return out, nil
+		case "map":
+			m, ok := val.(map[string]interface{})
+			if !ok {
+				return nil, errors.Errorf("%T could not be encoded as map", val)
+			}
+			out := make(map[string]interface{}, len(m))
+			for k, item := range m {
+				v, err := c.convert(t["values"], namespace, item, strict)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "value of %s", k)
+				}
+				out[k] = v
+			}
+			return out, nil
+		}
+		if lt, ok := t["logicalType"].(string); ok && logicalTypes[typ+"."+lt] {
+			return convertLogical(lt, val)
+		}
+		return c.convert(typ, namespace, val, strict)
+	}
+	return nil, errors.Errorf("invalid schema %v", s)
+}
+
+func (c *converter) convertUnion(members []interface{}, namespace string, val interface{}, strict bool) (interface{}, error) {
+	if val == nil {
+		for _, m := range members {
+			if m == "null" {
+				return nil, nil
+			}
+		}
+		return nil, errors.New("null is not in the union")
+	}
+
+	modes := []bool{true, false}
+	if strict {
+		modes = modes[:1]
+	}
+	for _, mode := range modes {
+		for _, m := range members {
+			if m == "null" {
+				continue
+			}
+			v, err := c.convert(m, namespace, val, mode)
+			if err == nil {
+				return goavro.Union(c.unionName(m, namespace), v), nil
+			}
+		}
+	}
+	return nil, errors.Errorf("%T matches none of the union %v", val, members)
+}
+
+// unionName is the name of the union member known by goavro
+func (c *converter) unionName(s interface{}, namespace string) string {
+	switch t := s.(type) {
+	case string:
+		if primitives[t] {
+			return t
+		}
+		if named, ok := c.lookup(t, namespace); ok {
+			name, _ := fullName(named.schema, named.namespace)
+			return name
+		}
+		return t
+	case map[string]interface{}:
+		typ, ok := t["type"].(string)
+		if !ok {
+			return c.unionName(t["type"], namespace)
+		}
+		switch typ {
+		case "record", "error", "enum", "fixed":
+			name, _ := fullName(t, namespace)
+			return name
+		case "array", "map":
+			return typ
+		}
+		if lt, ok := t["logicalType"].(string); ok && logicalTypes[typ+"."+lt] {
+			return typ + "." + lt
+		}
+		return c.unionName(typ, namespace)
+	}
+	return ""
+}
+
+func (c *converter) convertRecord(s map[string]interface{}, namespace string, val interface{}, strict bool) (interface{}, error) {
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("%T could not be encoded as record", val)
+	}
+	_, ns := fullName(s, namespace)
+	fields, _ := s["fields"].([]interface{})
+	out := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		field, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := field["name"].(string)
+		v, ok := m[name]
+		if !ok {
+			// the default of the schema is used, and the nullable fields without defaults are set to null
+			if _, hasDefault := field["default"]; !hasDefault {
+				if _, err := c.convert(field["type"], ns, nil, strict); err == nil {
+					out[name] = nil
+				}
+			}
+			continue
+		}
+		native, err := c.convert(field["type"], ns, v, strict)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "field %s", name)
+		}
+		out[name] = native
+	}
+	return out, nil
+}
+
+func convertEnum(s map[string]interface{}, val interface{}, strict bool) (interface{}, error) {
+	str, ok := val.(string)
+	if !ok {
+		if strict {
+			return nil, errors.Errorf("%T could not be encoded as enum", val)
+		}
+		str = toString(val)
+	}
+	symbols, _ := s["symbols"].([]interface{})
+	for _, sym := range symbols {
+		if sym == str {
+			return str, nil
+		}
+	}
+	return nil, errors.Errorf("%s is not a symbol of enum %v", str, s["name"])
+}
+
+func convertFixed(s map[string]interface{}, val interface{}) (interface{}, error) {
+	var b []byte
+	switch v := val.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return nil, errors.Errorf("%T could not be encoded as fixed", val)
+	}
+	if size, _ := s["size"].(float64); len(b) != int(size) {
+		return nil, errors.Errorf("size of fixed %v is %v, but got %d", s["name"], s["size"], len(b))
+	}
+	return b, nil
+}
+
+func convertPrimitive(typ string, val interface{}, strict bool) (interface{}, error) {
+	if val == nil {
+		if typ == "null" {
+			return nil, nil
+		}
+		return nil, errors.Errorf("null could not be encoded as %s", typ)
+	}
+	if strict && !sameKind(typ, val) {
+		return nil, errors.Errorf("%T could not be encoded as %s", val, typ)
+	}
+
+	switch typ {
+	case "boolean":
+		switch v := val.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case "int":
+		v, err := toInt(val, 32)
+		return int32(v), err
+	case "long":
+		return toInt(val, 64)
+	case "float":
+		v, err := toFloat(val)
+		return float32(v), err
+	case "double":
+		return toFloat(val)
+	case "string":
+		return toString(val), nil
+	case "bytes":
+		switch v := val.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+	}
+	return nil, errors.Errorf("%T could not be encoded as %s", val, typ)
+}
+
+func sameKind(typ string, val interface{}) bool {
+	switch val.(type) {
+	case bool:
+		return typ == "boolean"
+	case string:
+		return typ == "string" || typ == "bytes"
+	case []byte:
+		return typ == "bytes"
+	case int, int32, int64, uint32, uint64, stdjson.Number:
+		return typ == "int" || typ == "long" || typ == "float" || typ == "double"
+	case float32, float64:
+		if typ == "int" || typ == "long" {
+			f, _ := toFloat(val)
+			return f == math.Trunc(f)
+		}
+		return typ == "float" || typ == "double"
+	}
+	return false
+}
+
+// convertLogical accepts time.Time, the strings in RFC3339 or the unix epoch numbers in the unit of the logical types
+func convertLogical(lt string, val interface{}) (interface{}, error) {
+	switch lt {
+	case "timestamp-millis", "timestamp-micros", "date":
+		switch v := val.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			if lt == "date" {
+				if t, err := time.Parse("2006-01-02", v); err == nil {
+					return t, nil
+				}
+			}
+			return time.Parse(time.RFC3339Nano, v)
+		}
+		n, err := toInt(val, 64)
+		if err != nil {
+			return nil, errors.Errorf("%T could not be encoded as %s", val, lt)
+		}
+		switch lt {
+		case "timestamp-millis":
+			return time.UnixMilli(n), nil
+		case "timestamp-micros":
+			return time.UnixMicro(n), nil
+		}
+		return time.Unix(n*24*3600, 0).UTC(), nil
+
+	case "time-millis", "time-micros":
+		switch v := val.(type) {
+		case time.Duration:
+			return v, nil
+		case string:
+			return time.ParseDuration(v)
+		}
+		n, err := toInt(val, 64)
+		if err != nil {
+			return nil, errors.Errorf("%T could not be encoded as %s", val, lt)
+		}
+		if lt == "time-millis" {
+			return time.Duration(n) * time.Millisecond, nil
+		}
+		return time.Duration(n) * time.Microsecond, nil
+
+	case "decimal":
+		r := new(big.Rat)
+		switch v := val.(type) {
+		case *big.Rat:
+			return v, nil
+		case float64:
+			r.SetFloat64(v)
+			return r, nil
+		}
+		if _, ok := r.SetString(toString(val)); !ok {
+			return nil, errors.Errorf("%v could not be encoded as decimal", val)
+		}
+		return r, nil
+	}
+	return nil, errors.Errorf("logical type %s is not supported", lt)
+}
+
+func toList(val interface{}) ([]interface{}, bool) {
+	switch v := val.(type) {
+	case []interface{}:
+		return v, true
+	case []string:
+		list := make([]interface{}, 0, len(v))
+		for _, s := range v {
+			list = append(list, s)
+		}
+		return list, true
+	case []map[string]interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, m := range v {
+			list = append(list, m)
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+func toInt(val interface{}, bits int) (int64, error) {
+	var v int64
+	switch n := val.(type) {
+	case int:
+		v = int64(n)
+	case int32:
+		v = int64(n)
+	case int64:
+		v = n
+	case uint32:
+		v = int64(n)
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, errors.Errorf("%d overflows int%d", n, bits)
+		}
+		v = int64(n)
+	case float32:
+		return toInt(float64(n), bits)
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, errors.Errorf("%v is not an integer", n)
+		}
+		v = int64(n)
+	case stdjson.Number:
+		return strconv.ParseInt(string(n), 10, bits)
+	case string:
+		return strconv.ParseInt(n, 10, bits)
+	default:
+		return 0, errors.Errorf("%T could not be encoded as integer", val)
+	}
+	if bits == 32 && (v > math.MaxInt32 || v < math.MinInt32) {
+		return 0, errors.Errorf("%d overflows int32", v)
+	}
+	return v, nil
+}
+
+func toFloat(val interface{}) (float64, error) {
+	switch n := val.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint32:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case stdjson.Number:
+		return strconv.ParseFloat(string(n), 64)
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+	return 0, errors.Errorf("%T could not be encoded as float", val)
+}
+
+// toString encodes the values other than strings in json, such as the nested objects
+func toString(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	out, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return string(out)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package avro
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const registryContentType = "application/vnd.schemaregistry.v1+json"
+
+// registry is the client of the confluent schema registry rest api
+type registry struct {
+	config *RegistryConfig
+	http   *http.Client
+}
+
+type registrySchema struct {
+	Subject string `json:"subject,omitempty"`
+	Id      int    `json:"id,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Schema  string `json:"schema,omitempty"`
+}
+
+type registryError struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func newRegistry(config *RegistryConfig) *registry {
+	return &registry{
+		config: config,
+		http: &http.Client{
+			Timeout: config.Timeout,
+		},
+	}
+}
+
+// register returns the id of the schema, a new version is created if the schema is not registered under the subject,
+// which would be rejected by the registry if it is incompatible with the previous versions
+func (r *registry) register(subject string, schema string) (int, error) {
+	out := &registrySchema{}
+	path := fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject))
+	if err := r.do(http.MethodPost, path, &registrySchema{Schema: schema}, out); err != nil {
+		return 0, errors.WithMessagef(err, "register schema of subject %s", subject)
+	}
+	return out.Id, nil
+}
+
+// lookup returns the version of the schema which has been registered under the subject
+func (r *registry) lookup(subject string, schema string) (*registrySchema, error) {
+	out := &registrySchema{}
+	path := fmt.Sprintf("/subjects/%s", url.PathEscape(subject))
+	if err := r.do(http.MethodPost, path, &registrySchema{Schema: schema}, out); err != nil {
+		return nil, errors.WithMessagef(err, "lookup schema of subject %s", subject)
+	}
+	return out, nil
+}
+
+// version fetches the schema of the version, which is a number or latest
+func (r *registry) version(subject string, version string) (*registrySchema, error) {
+	out := &registrySchema{}
+	path := fmt.Sprintf("/subjects/%s/versions/%s", url.PathEscape(subject), url.PathEscape(version))
+	if err := r.do(http.MethodGet, path, nil, out); err != nil {
+		return nil, errors.WithMessagef(err, "get version %s of subject %s", version, subject)
+	}
+	return out, nil
+}
+
+func (r *registry) do(method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(r.config.URL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", registryContentType)
+	if in != nil {
+		req.Header.Set("Content-Type", registryContentType)
+	}
+	if r.config.Username != "" {
+		req.SetBasicAuth(r.config.Username, r.config.Password)
+	}
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return errors.WithMessage(err, "read response")
+	}
+	if resp.StatusCode/100 != 2 {
+		e := &registryError{}
+		if err := json.Unmarshal(respBody, e); err == nil && e.Message != "" {
+			return errors.Errorf("schema registry returned status %d, error code %d: %s", resp.StatusCode, e.ErrorCode, e.Message)
+		}
+		return errors.Errorf("schema registry returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return json.Unmarshal(respBody, out)
+}
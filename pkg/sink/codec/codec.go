@@ -18,9 +18,13 @@ package codec
 
 import (
 	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
 	"github.com/loggie-io/loggie/pkg/core/log"
 )
 
+// BodyKey as the key of a column or a field refers to the event body, used by the sinks writing the events as rows
+const BodyKey = event.Body
+
 type SinkCodec interface {
 	SetCodec(c Codec)
 }
@@ -30,6 +34,14 @@ type Codec interface {
 	Encode(event api.Event) ([]byte, error)
 }
 
+// BatchCodec encodes the events of a batch at once, such as wrapping them in an envelope,
+// the sinks writing a batch as a whole could use it when the codec supports
+type BatchCodec interface {
+	// BatchEnabled returns whether the events are expected to be encoded as a batch
+	BatchEnabled() bool
+	EncodeBatch(events []api.Event) ([]byte, error)
+}
+
 type Factory func() Codec
 
 var center = make(map[string]Factory)
@@ -50,3 +62,16 @@ func Get(name string) (Codec, bool) {
 	}
 	return f(), ok
 }
+
+// OriginalBody returns the original bytes received by the source, which are kept by source codec or interceptor preserveRaw,
+// the body is returned when they are not kept, so the sinks could forward the payloads without reshaping them
+func OriginalBody(e api.Event) []byte {
+	if e.Meta() != nil {
+		if raw, ok := e.Meta().Get(event.RawBodyKey); ok {
+			if b, ok := raw.([]byte); ok {
+				return b
+			}
+		}
+	}
+	return e.Body()
+}
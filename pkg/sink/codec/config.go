@@ -17,6 +17,7 @@ limitations under the License.
 package codec
 
 import (
+	"github.com/loggie-io/loggie/pkg/core/api"
 	"github.com/loggie-io/loggie/pkg/core/cfg"
 	"github.com/pkg/errors"
 )
@@ -28,9 +29,17 @@ type Config struct {
 }
 
 func (c *Config) Validate() error {
-	if c.Type != "json" && c.Type != "raw" {
+	if c.Type != "json" && c.Type != "raw" && c.Type != "avro" && c.Type != "template" && c.Type != "protobuf" && c.Type != "msgpack" {
 		return errors.Errorf("codec %s is not supported", c.Type)
 	}
+
+	cod, ok := Get(c.Type)
+	if !ok {
+		return nil
+	}
+	if conf, ok := cod.(api.Config); ok {
+		return cfg.UnpackFromCommonCfg(c.CommonCfg, conf.Config()).Defaults().Validate().Do()
+	}
 	return nil
 }
 
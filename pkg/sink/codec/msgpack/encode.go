@@ -0,0 +1,296 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package msgpack
+
+import (
+	stdjson "encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+// timestampExt is the type of the timestamp extension defined by the msgpack spec
+const timestampExt = -1
+
+type encoder struct {
+	buf      []byte
+	sortKeys bool
+}
+
+func (e *encoder) keys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	if e.sortKeys {
+		sort.Strings(keys)
+	}
+	return keys
+}
+
+func (e *encoder) encode(val interface{}) error {
+	switch v := val.(type) {
+	case nil:
+		e.buf = append(e.buf, 0xc0)
+	case bool:
+		if v {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case int:
+		e.writeInt(int64(v))
+	case int8:
+		e.writeInt(int64(v))
+	case int16:
+		e.writeInt(int64(v))
+	case int32:
+		e.writeInt(int64(v))
+	case int64:
+		e.writeInt(v)
+	case uint:
+		e.writeUint(uint64(v))
+	case uint8:
+		e.writeUint(uint64(v))
+	case uint16:
+		e.writeUint(uint64(v))
+	case uint32:
+		e.writeUint(uint64(v))
+	case uint64:
+		e.writeUint(v)
+	case float32:
+		e.buf = append(e.buf, 0xca)
+		e.buf = appendUint32(e.buf, math.Float32bits(v))
+	case float64:
+		e.writeFloat(v)
+	case stdjson.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			e.writeInt(i)
+		} else if f, err := strconv.ParseFloat(string(v), 64); err == nil {
+			e.writeFloat(f)
+		} else {
+			e.writeString(string(v))
+		}
+	case string:
+		e.writeString(v)
+	case []byte:
+		e.writeBinary(v)
+	case time.Time:
+		e.writeTime(v)
+	case map[string]interface{}:
+		e.writeMapHeader(len(v))
+		for _, k := range e.keys(v) {
+			e.writeString(k)
+			if err := e.encode(v[k]); err != nil {
+				return errors.WithMessagef(err, "key %s", k)
+			}
+		}
+	case map[string]string:
+		e.writeMapHeader(len(v))
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		if e.sortKeys {
+			sort.Strings(keys)
+		}
+		for _, k := range keys {
+			e.writeString(k)
+			e.writeString(v[k])
+		}
+	case []interface{}:
+		e.writeArrayHeader(len(v))
+		for i, item := range v {
+			if err := e.encode(item); err != nil {
+				return errors.WithMessagef(err, "item %d", i)
+			}
+		}
+	case []string:
+		e.writeArrayHeader(len(v))
+		for _, item := range v {
+			e.writeString(item)
+		}
+	case []map[string]interface{}:
+		e.writeArrayHeader(len(v))
+		for i, item := range v {
+			if err := e.encode(item); err != nil {
+				return errors.WithMessagef(err, "item %d", i)
+			}
+		}
+	default:
+		// the other types are converted to the generic values by json, such as structs
+		out, err := json.Marshal(v)
+		if err != nil {
+			return errors.WithMessagef(err, "encode %T", val)
+		}
+		var generic interface{}
+		if err := json.Unmarshal(out, &generic); err != nil {
+			return errors.WithMessagef(err, "encode %T", val)
+		}
+		return e.encode(generic)
+	}
+	return nil
+}
+
+func (e *encoder) writeInt(v int64) {
+	switch {
+	case v >= 0:
+		e.writeUint(uint64(v))
+	case v >= -32:
+		e.buf = append(e.buf, byte(v))
+	case v >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		e.buf = append(e.buf, 0xd1)
+		e.buf = appendUint16(e.buf, uint16(v))
+	case v >= math.MinInt32:
+		e.buf = append(e.buf, 0xd2)
+		e.buf = appendUint32(e.buf, uint32(v))
+	default:
+		e.buf = append(e.buf, 0xd3)
+		e.buf = appendUint64(e.buf, uint64(v))
+	}
+}
+
+func (e *encoder) writeUint(v uint64) {
+	switch {
+	case v <= 0x7f:
+		e.buf = append(e.buf, byte(v))
+	case v <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		e.buf = append(e.buf, 0xcd)
+		e.buf = appendUint16(e.buf, uint16(v))
+	case v <= math.MaxUint32:
+		e.buf = append(e.buf, 0xce)
+		e.buf = appendUint32(e.buf, uint32(v))
+	default:
+		e.buf = append(e.buf, 0xcf)
+		e.buf = appendUint64(e.buf, v)
+	}
+}
+
+// writeFloat encodes the integral floats as integers, since the numbers decoded from json are all float64
+func (e *encoder) writeFloat(v float64) {
+	if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+		e.writeInt(int64(v))
+		return
+	}
+	e.buf = append(e.buf, 0xcb)
+	e.buf = appendUint64(e.buf, math.Float64bits(v))
+}
+
+func (e *encoder) writeString(s string) {
+	e.writeStringHeader(len(s))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) writeStringBytes(b []byte) {
+	e.writeStringHeader(len(b))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) writeStringHeader(n int) {
+	switch {
+	case n <= 31:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xda)
+		e.buf = appendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdb)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *encoder) writeBinary(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xc5)
+		e.buf = appendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xc6)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) writeMapHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf = append(e.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xde)
+		e.buf = appendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdf)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *encoder) writeArrayHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf = append(e.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xdc)
+		e.buf = appendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdd)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+}
+
+// writeTime encodes the timestamp extension in the smallest of the 32, 64 and 96 bits formats
+func (e *encoder) writeTime(t time.Time) {
+	sec := t.Unix()
+	nsec := int64(t.Nanosecond())
+	switch {
+	case sec>>34 == 0 && nsec == 0 && sec <= math.MaxUint32:
+		e.buf = append(e.buf, 0xd6, byte(0xff&timestampExt))
+		e.buf = appendUint32(e.buf, uint32(sec))
+	case sec>>34 == 0:
+		e.buf = append(e.buf, 0xd7, byte(0xff&timestampExt))
+		e.buf = appendUint64(e.buf, uint64(nsec)<<34|uint64(sec))
+	default:
+		e.buf = append(e.buf, 0xc7, 12, byte(0xff&timestampExt))
+		e.buf = appendUint32(e.buf, uint32(nsec))
+		e.buf = appendUint64(e.buf, uint64(sec))
+	}
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v>>32)), uint32(v))
+}
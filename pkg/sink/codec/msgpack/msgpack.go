@@ -0,0 +1,107 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package msgpack
+
+import (
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+)
+
+const (
+	Type = "msgpack"
+)
+
+func init() {
+	codec.Register(Type, makeMsgpackCodec)
+}
+
+// Msgpack encodes the header and body of events as a msgpack map, which is smaller and cheaper to encode than json
+type Msgpack struct {
+	config    *Config
+	codecConf *codec.Config
+}
+
+type Config struct {
+	// Original encodes the original bytes received by the source as the body, rather than the decoded one
+	Original bool `yaml:"original,omitempty"`
+	// BinaryBody encodes the body as bin rather than str, for the bodies which may not be valid utf-8
+	BinaryBody bool `yaml:"binaryBody,omitempty"`
+	// SortKeys encodes the keys of maps in order, so the same event is always encoded into the same bytes
+	SortKeys bool `yaml:"sortKeys,omitempty"`
+}
+
+func makeMsgpackCodec() codec.Codec {
+	return NewMsgpack()
+}
+
+func NewMsgpack() *Msgpack {
+	return &Msgpack{
+		config: &Config{},
+	}
+}
+
+func (m *Msgpack) Config() interface{} {
+	return m.config
+}
+
+func (m *Msgpack) Init(config *codec.Config) {
+	m.codecConf = config
+}
+
+func (m *Msgpack) Encode(e api.Event) ([]byte, error) {
+	header := e.Header()
+	body := e.Body()
+	if m.config.Original {
+		body = codec.OriginalBody(e)
+	}
+
+	size := len(header)
+	if len(body) != 0 {
+		if _, ok := header[event.Body]; !ok {
+			size++
+		}
+	}
+
+	enc := &encoder{sortKeys: m.config.SortKeys, buf: make([]byte, 0, len(body)+64)}
+	enc.writeMapHeader(size)
+	keys := enc.keys(header)
+	for _, k := range keys {
+		if k == event.Body && len(body) != 0 {
+			// the body overrides the field with the same name, as json codec does
+			continue
+		}
+		enc.writeString(k)
+		if err := enc.encode(header[k]); err != nil {
+			return nil, err
+		}
+	}
+	if len(body) != 0 {
+		enc.writeString(event.Body)
+		if m.config.BinaryBody {
+			enc.writeBinary(body)
+		} else {
+			enc.writeStringBytes(body)
+		}
+	}
+
+	if m.codecConf.PrintEvents {
+		log.Info("[print events] %x", enc.buf)
+	}
+	return enc.buf, nil
+}
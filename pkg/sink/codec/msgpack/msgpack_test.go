@@ -0,0 +1,118 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package msgpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+)
+
+func TestMsgpack_Encode(t *testing.T) {
+	withRaw := event.NewEvent(map[string]interface{}{}, []byte("decoded"))
+	meta := event.NewDefaultMeta()
+	meta.Set(event.RawBodyKey, []byte("raw"))
+	withRaw.Fill(meta, withRaw.Header(), withRaw.Body())
+
+	tests := []struct {
+		name   string
+		config Config
+		event  api.Event
+		want   []byte
+	}{
+		{
+			name:   "headerAndBody",
+			config: Config{SortKeys: true},
+			event: event.NewEvent(map[string]interface{}{
+				"a": "b",
+				"c": map[string]interface{}{"d": int64(-1)},
+			}, []byte("hi")),
+			// {"a":"b","c":{"d":-1},"body":"hi"}
+			want: []byte{0x83, 0xa1, 'a', 0xa1, 'b', 0xa1, 'c', 0x81, 0xa1, 'd', 0xff, 0xa4, 'b', 'o', 'd', 'y', 0xa2, 'h', 'i'},
+		},
+		{
+			name:   "bodyInHeader",
+			config: Config{SortKeys: true},
+			event: event.NewEvent(map[string]interface{}{
+				"body": "message",
+			}, []byte{}),
+			want: []byte{0x81, 0xa4, 'b', 'o', 'd', 'y', 0xa7, 'm', 'e', 's', 's', 'a', 'g', 'e'},
+		},
+		{
+			name:   "binaryBody",
+			config: Config{BinaryBody: true},
+			event:  event.NewEvent(map[string]interface{}{}, []byte{0xff, 0x00}),
+			want:   []byte{0x81, 0xa4, 'b', 'o', 'd', 'y', 0xc4, 0x02, 0xff, 0x00},
+		},
+		{
+			name:   "original",
+			config: Config{Original: true},
+			event:  withRaw,
+			want:   []byte{0x81, 0xa4, 'b', 'o', 'd', 'y', 0xa3, 'r', 'a', 'w'},
+		},
+		{
+			name:   "numbers",
+			config: Config{SortKeys: true},
+			event: event.NewEvent(map[string]interface{}{
+				"f": 1.5,
+				"i": float64(300),
+				"u": uint16(65535),
+			}, nil),
+			want: []byte{0x83,
+				0xa1, 'f', 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+				0xa1, 'i', 0xcd, 0x01, 0x2c,
+				0xa1, 'u', 0xcd, 0xff, 0xff},
+		},
+		{
+			name:   "arrayAndTime",
+			config: Config{SortKeys: true},
+			event: event.NewEvent(map[string]interface{}{
+				"l": []interface{}{nil, true, "x"},
+				"t": time.Unix(1, 0),
+			}, nil),
+			want: []byte{0x82,
+				0xa1, 'l', 0x93, 0xc0, 0xc3, 0xa1, 'x',
+				0xa1, 't', 0xd6, 0xff, 0, 0, 0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := tt.config
+			m := &Msgpack{config: &conf}
+			m.Init(&codec.Config{})
+			got, err := m.Encode(tt.event)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEncoder_Sizes(t *testing.T) {
+	e := &encoder{}
+	e.writeInt(-129)
+	e.writeUint(1 << 32)
+	e.writeString(string(make([]byte, 32)))
+	assert.Equal(t, []byte{0xd1, 0xff, 0x7f}, e.buf[:3])
+	assert.Equal(t, []byte{0xcf, 0, 0, 0, 1, 0, 0, 0, 0}, e.buf[3:12])
+	assert.Equal(t, []byte{0xd9, 32}, e.buf[12:14])
+	assert.Len(t, e.buf, 14+32)
+}
@@ -0,0 +1,448 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protobuf
+
+import (
+	"encoding/base64"
+	stdjson "encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	timestampMessage = "google.protobuf.Timestamp"
+	durationMessage  = "google.protobuf.Duration"
+)
+
+// encodeMessage appends the fields of the message in the order of the descriptor, the fields missing in data are skipped
+func encodeMessage(b []byte, md protoreflect.MessageDescriptor, data map[string]interface{}) ([]byte, error) {
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		val, ok := data[string(fd.Name())]
+		if !ok {
+			val, ok = data[fd.JSONName()]
+		}
+		if !ok || val == nil {
+			continue
+		}
+
+		var err error
+		switch {
+		case fd.IsMap():
+			b, err = encodeMap(b, fd, val)
+		case fd.IsList():
+			b, err = encodeList(b, fd, val)
+		default:
+			b, err = encodeField(b, fd, val)
+		}
+		if err != nil {
+			return nil, errors.WithMessagef(err, "field %s", fd.Name())
+		}
+	}
+	return b, nil
+}
+
+func encodeField(b []byte, fd protoreflect.FieldDescriptor, val interface{}) ([]byte, error) {
+	if fd.Kind() == protoreflect.MessageKind {
+		msg, err := encodeNested(fd.Message(), val)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, fd.Number(), protowire.BytesType)
+		return protowire.AppendBytes(b, msg), nil
+	}
+	if fd.Kind() == protoreflect.GroupKind {
+		return nil, errors.New("group is not supported")
+	}
+
+	b = protowire.AppendTag(b, fd.Number(), wireType(fd.Kind()))
+	return appendScalar(b, fd, val)
+}
+
+func encodeNested(md protoreflect.MessageDescriptor, val interface{}) ([]byte, error) {
+	switch md.FullName() {
+	case timestampMessage:
+		return encodeTimestamp(val)
+	case durationMessage:
+		return encodeDuration(val)
+	}
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("%T could not be encoded as message %s", val, md.FullName())
+	}
+	return encodeMessage(nil, md, m)
+}
+
+func encodeList(b []byte, fd protoreflect.FieldDescriptor, val interface{}) ([]byte, error) {
+	list, ok := toList(val)
+	if !ok {
+		// a single value is encoded as a list of one
+		list = []interface{}{val}
+	}
+
+	if fd.IsPacked() {
+		var packed []byte
+		for _, v := range list {
+			var err error
+			if packed, err = appendScalar(packed, fd, v); err != nil {
+				return nil, err
+			}
+		}
+		b = protowire.AppendTag(b, fd.Number(), protowire.BytesType)
+		return protowire.AppendBytes(b, packed), nil
+	}
+
+	for _, v := range list {
+		var err error
+		if b, err = encodeField(b, fd, v); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func toList(val interface{}) ([]interface{}, bool) {
+	switch v := val.(type) {
+	case []interface{}:
+		return v, true
+	case []string:
+		list := make([]interface{}, 0, len(v))
+		for _, s := range v {
+			list = append(list, s)
+		}
+		return list, true
+	case []map[string]interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, m := range v {
+			list = append(list, m)
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// encodeMap encodes each entry as a message with the key and value fields, the keys are sorted to be deterministic
+func encodeMap(b []byte, fd protoreflect.FieldDescriptor, val interface{}) ([]byte, error) {
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("%T could not be encoded as map", val)
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	keyField, valueField := fd.MapKey(), fd.MapValue()
+	for _, k := range keys {
+		if m[k] == nil {
+			continue
+		}
+		entry, err := encodeField(nil, keyField, k)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "key %s", k)
+		}
+		if entry, err = encodeField(entry, valueField, m[k]); err != nil {
+			return nil, errors.WithMessagef(err, "value of %s", k)
+		}
+		b = protowire.AppendTag(b, fd.Number(), protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	return b, nil
+}
+
+func wireType(kind protoreflect.Kind) protowire.Type {
+	switch kind {
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		return protowire.Fixed32Type
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		return protowire.Fixed64Type
+	case protoreflect.StringKind, protoreflect.BytesKind, protoreflect.MessageKind:
+		return protowire.BytesType
+	}
+	return protowire.VarintType
+}
+
+// appendScalar appends the value without tag, the values of the event are converted to the kind of the field,
+// such as the numbers in strings
+func appendScalar(b []byte, fd protoreflect.FieldDescriptor, val interface{}) ([]byte, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		v, err := toBool(val)
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendVarint(b, protowire.EncodeBool(v)), nil
+
+	case protoreflect.EnumKind:
+		if s, ok := val.(string); ok {
+			if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+				return protowire.AppendVarint(b, uint64(ev.Number())), nil
+			}
+		}
+		v, err := toInt(val, 32)
+		if err != nil {
+			return nil, errors.Errorf("%v is not a value of enum %s", val, fd.Enum().FullName())
+		}
+		return protowire.AppendVarint(b, uint64(v)), nil
+
+	case protoreflect.Int32Kind, protoreflect.Int64Kind:
+		v, err := toInt(val, bitSize(fd.Kind()))
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendVarint(b, uint64(v)), nil
+
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		v, err := toInt(val, bitSize(fd.Kind()))
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendVarint(b, protowire.EncodeZigZag(v)), nil
+
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+		v, err := toUint(val, bitSize(fd.Kind()))
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendVarint(b, v), nil
+
+	case protoreflect.Sfixed32Kind:
+		v, err := toInt(val, 32)
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendFixed32(b, uint32(v)), nil
+
+	case protoreflect.Fixed32Kind:
+		v, err := toUint(val, 32)
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendFixed32(b, uint32(v)), nil
+
+	case protoreflect.Sfixed64Kind:
+		v, err := toInt(val, 64)
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendFixed64(b, uint64(v)), nil
+
+	case protoreflect.Fixed64Kind:
+		v, err := toUint(val, 64)
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendFixed64(b, v), nil
+
+	case protoreflect.FloatKind:
+		v, err := toFloat(val)
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendFixed32(b, math.Float32bits(float32(v))), nil
+
+	case protoreflect.DoubleKind:
+		v, err := toFloat(val)
+		if err != nil {
+			return nil, err
+		}
+		return protowire.AppendFixed64(b, math.Float64bits(v)), nil
+
+	case protoreflect.StringKind:
+		return protowire.AppendString(b, toString(val)), nil
+
+	case protoreflect.BytesKind:
+		switch v := val.(type) {
+		case []byte:
+			return protowire.AppendBytes(b, v), nil
+		case string:
+			return protowire.AppendString(b, v), nil
+		}
+		return nil, errors.Errorf("%T could not be encoded as bytes", val)
+	}
+	return nil, errors.Errorf("kind %s is not supported", fd.Kind())
+}
+
+func bitSize(kind protoreflect.Kind) int {
+	switch kind {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Uint32Kind:
+		return 32
+	}
+	return 64
+}
+
+func toBool(val interface{}) (bool, error) {
+	switch v := val.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, errors.Errorf("%T could not be encoded as bool", val)
+}
+
+func toInt(val interface{}, bits int) (int64, error) {
+	var v int64
+	switch n := val.(type) {
+	case int:
+		v = int64(n)
+	case int32:
+		v = int64(n)
+	case int64:
+		v = n
+	case uint32:
+		v = int64(n)
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, errors.Errorf("%d overflows int%d", n, bits)
+		}
+		v = int64(n)
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, errors.Errorf("%v is not an integer", n)
+		}
+		v = int64(n)
+	case stdjson.Number:
+		return strconv.ParseInt(string(n), 10, bits)
+	case string:
+		return strconv.ParseInt(n, 10, bits)
+	default:
+		return 0, errors.Errorf("%T could not be encoded as integer", val)
+	}
+	if bits == 32 && (v > math.MaxInt32 || v < math.MinInt32) {
+		return 0, errors.Errorf("%d overflows int32", v)
+	}
+	return v, nil
+}
+
+func toUint(val interface{}, bits int) (uint64, error) {
+	if s, ok := val.(string); ok {
+		return strconv.ParseUint(s, 10, bits)
+	}
+	if n, ok := val.(uint64); ok {
+		if bits == 32 && n > math.MaxUint32 {
+			return 0, errors.Errorf("%d overflows uint32", n)
+		}
+		return n, nil
+	}
+	v, err := toInt(val, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 || (bits == 32 && v > math.MaxUint32) {
+		return 0, errors.Errorf("%d overflows uint%d", v, bits)
+	}
+	return uint64(v), nil
+}
+
+func toFloat(val interface{}) (float64, error) {
+	switch n := val.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case stdjson.Number:
+		return strconv.ParseFloat(string(n), 64)
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+	return 0, errors.Errorf("%T could not be encoded as float", val)
+}
+
+// toString encodes the values other than strings in json, such as the nested objects
+func toString(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	}
+	out, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return string(out)
+}
+
+// encodeTimestamp accepts time.Time, the strings in RFC3339 or the unix seconds
+func encodeTimestamp(val interface{}) ([]byte, error) {
+	var t time.Time
+	switch v := val.(type) {
+	case time.Time:
+		t = v
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, err
+		}
+		t = parsed
+	default:
+		f, err := toFloat(val)
+		if err != nil {
+			return nil, errors.Errorf("%T could not be encoded as timestamp", val)
+		}
+		sec, frac := math.Modf(f)
+		t = time.Unix(int64(sec), int64(frac*1e9))
+	}
+	return appendSecondsNanos(nil, t.Unix(), int32(t.Nanosecond())), nil
+}
+
+// encodeDuration accepts the strings such as 1.5s, or the seconds
+func encodeDuration(val interface{}) ([]byte, error) {
+	var d time.Duration
+	if s, ok := val.(string); ok {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		d = parsed
+	} else {
+		f, err := toFloat(val)
+		if err != nil {
+			return nil, errors.Errorf("%T could not be encoded as duration", val)
+		}
+		d = time.Duration(f * float64(time.Second))
+	}
+	return appendSecondsNanos(nil, int64(d/time.Second), int32(d%time.Second)), nil
+}
+
+func appendSecondsNanos(b []byte, seconds int64, nanos int32) []byte {
+	if seconds != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(seconds))
+	}
+	if nanos != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(int64(nanos)))
+	}
+	return b
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protobuf
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const Type = "protobuf"
+
+func init() {
+	codec.Register(Type, makeProtobufCodec)
+}
+
+// Protobuf serializes the events into the message type of the descriptor set, the fields of the message are
+// set from the fields of the event with the same proto or json names, and the nested messages from the nested objects
+type Protobuf struct {
+	config    *Config
+	codecConf *codec.Config
+	message   protoreflect.MessageDescriptor
+}
+
+type Config struct {
+	// DescriptorSet is the path of the compiled descriptor set including the imports, such as generated by
+	// protoc --include_imports --descriptor_set_out=log.desc log.proto
+	DescriptorSet string `yaml:"descriptorSet,omitempty" validate:"required"`
+	// Message is the full name of the message type, such as acme.log.v1.LogRecord
+	Message string `yaml:"message,omitempty" validate:"required"`
+	// Fields maps the top level fields of the message to the paths of the event, such as service: fields.app
+	Fields map[string]string `yaml:"fields,omitempty"`
+	// Delimited prefixes each message with its varint length, for the streams of messages such as files
+	Delimited bool `yaml:"delimited,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	md, err := loadMessage(c.DescriptorSet, c.Message)
+	if err != nil {
+		return err
+	}
+	for name := range c.Fields {
+		if md.Fields().ByName(protoreflect.Name(name)) == nil && md.Fields().ByJSONName(name) == nil {
+			return errors.Errorf("field %s is not found in message %s", name, c.Message)
+		}
+	}
+	return nil
+}
+
+func loadMessage(path string, message string) (protoreflect.MessageDescriptor, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "read descriptor set %s", path)
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(content, set); err != nil {
+		return nil, errors.WithMessagef(err, "unmarshal descriptor set %s", path)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "resolve descriptor set %s", path)
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(message))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "find message %s", message)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, errors.Errorf("%s is not a message", message)
+	}
+	return md, nil
+}
+
+func makeProtobufCodec() codec.Codec {
+	return NewProtobuf()
+}
+
+func NewProtobuf() *Protobuf {
+	return &Protobuf{
+		config: &Config{},
+	}
+}
+
+func (p *Protobuf) Config() interface{} {
+	return p.config
+}
+
+func (p *Protobuf) Init(config *codec.Config) {
+	p.codecConf = config
+	md, err := loadMessage(p.config.DescriptorSet, p.config.Message)
+	if err != nil {
+		// the descriptor set has been validated, it may be changed since then
+		log.Panic("init protobuf codec failed: %v", err)
+	}
+	p.message = md
+}
+
+func (p *Protobuf) Encode(e api.Event) ([]byte, error) {
+	header := e.Header()
+	data := make(map[string]interface{}, len(header)+len(p.config.Fields)+1)
+	for k, v := range header {
+		data[k] = v
+	}
+	if len(e.Body()) != 0 {
+		data[event.Body] = string(e.Body())
+	}
+	if len(p.config.Fields) > 0 {
+		obj := runtime.NewObject(data)
+		for name, path := range p.config.Fields {
+			if val := obj.GetPath(path).Value(); val != nil {
+				data[name] = val
+			}
+		}
+	}
+
+	out, err := encodeMessage(nil, p.message, data)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "encode %s", p.config.Message)
+	}
+	if p.config.Delimited {
+		out = protowire.AppendBytes(make([]byte, 0, len(out)+protowire.SizeVarint(uint64(len(out)))), out)
+	}
+
+	if p.codecConf.PrintEvents {
+		log.Info("[print events] %x", out)
+	}
+	return out, nil
+}
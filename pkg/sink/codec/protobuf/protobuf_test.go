@@ -0,0 +1,218 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protobuf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+)
+
+func writeDescriptorSet(t *testing.T, files ...*descriptorpb.FileDescriptorProto) string {
+	out, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: files})
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "test.desc")
+	assert.NoError(t, os.WriteFile(path, out, 0644))
+	return path
+}
+
+func newProtobuf(t *testing.T, config cfg.CommonCfg) *Protobuf {
+	p := NewProtobuf()
+	assert.NoError(t, cfg.UnpackFromCommonCfg(config, p.config).Defaults().Validate().Do())
+	p.Init(&codec.Config{Type: Type})
+	return p
+}
+
+// the message types of descriptor.proto are used, so the encoded bytes could be decoded by descriptorpb
+func TestProtobuf_EncodeDescriptor(t *testing.T) {
+	path := writeDescriptorSet(t, protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto))
+	p := newProtobuf(t, cfg.CommonCfg{
+		"descriptorSet": path,
+		"message":       "google.protobuf.DescriptorProto",
+		"fields": map[string]interface{}{
+			"name": "fields.app",
+		},
+	})
+
+	e := event.NewEvent(map[string]interface{}{
+		"fields": map[string]interface{}{"app": "web"},
+		"field": []interface{}{
+			map[string]interface{}{
+				"name":     "status",
+				"number":   float64(1),
+				"label":    "LABEL_OPTIONAL",
+				"type":     "TYPE_INT32",
+				"jsonName": "status",
+				"options":  map[string]interface{}{"deprecated": true},
+			},
+			map[string]interface{}{
+				"name":   "path",
+				"number": "2",
+				"type":   9,
+			},
+		},
+		"reserved_name": []interface{}{"a", "b"},
+		"unknown":       "ignored",
+	}, nil)
+	out, err := p.Encode(e)
+	assert.NoError(t, err)
+
+	msg := &descriptorpb.DescriptorProto{}
+	assert.NoError(t, proto.Unmarshal(out, msg))
+	assert.Equal(t, "web", msg.GetName())
+	assert.Len(t, msg.GetField(), 2)
+	assert.Equal(t, "status", msg.GetField()[0].GetName())
+	assert.Equal(t, int32(1), msg.GetField()[0].GetNumber())
+	assert.Equal(t, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, msg.GetField()[0].GetLabel())
+	assert.Equal(t, descriptorpb.FieldDescriptorProto_TYPE_INT32, msg.GetField()[0].GetType())
+	assert.Equal(t, "status", msg.GetField()[0].GetJsonName())
+	assert.True(t, msg.GetField()[0].GetOptions().GetDeprecated())
+	assert.Equal(t, int32(2), msg.GetField()[1].GetNumber())
+	assert.Equal(t, descriptorpb.FieldDescriptorProto_TYPE_STRING, msg.GetField()[1].GetType())
+	assert.Equal(t, []string{"a", "b"}, msg.GetReservedName())
+
+	_, err = p.Encode(event.NewEvent(map[string]interface{}{"field": []interface{}{map[string]interface{}{"number": "abc"}}}, nil))
+	assert.Error(t, err)
+}
+
+func logFile() *descriptorpb.FileDescriptorProto {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Type:     typ.Enum(),
+			Label:    label.Enum(),
+			JsonName: proto.String(name),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("log.proto"),
+		Package:    proto.String("test.log"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Record"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("time", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional, ".google.protobuf.Timestamp"),
+				field("body", 2, descriptorpb.FieldDescriptorProto_TYPE_BYTES, optional, ""),
+				field("labels", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, repeated, ".test.log.Record.LabelsEntry"),
+				field("codes", 4, descriptorpb.FieldDescriptorProto_TYPE_SINT64, repeated, ""),
+				field("ratio", 5, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, optional, ""),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("LabelsEntry"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+					field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+				},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			}},
+		}},
+	}
+}
+
+// consume returns the values of the fields in order, the length delimited values are returned as bytes
+func consume(t *testing.T, b []byte) (nums []protowire.Number, values []interface{}) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		assert.True(t, n > 0)
+		b = b[n:]
+		var v interface{}
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+		assert.True(t, n > 0)
+		b = b[n:]
+		nums = append(nums, num)
+		values = append(values, v)
+	}
+	return nums, values
+}
+
+func TestProtobuf_EncodeWellKnownAndMap(t *testing.T) {
+	path := writeDescriptorSet(t, protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto), logFile())
+	p := newProtobuf(t, cfg.CommonCfg{
+		"descriptorSet": path,
+		"message":       "test.log.Record",
+		"delimited":     true,
+	})
+
+	out, err := p.Encode(event.NewEvent(map[string]interface{}{
+		"time":   "2023-01-02T03:04:05.5Z",
+		"labels": map[string]interface{}{"env": "prod", "app": "web"},
+		"codes":  []interface{}{-1, 2},
+		"ratio":  0.5,
+	}, []byte("hello")))
+	assert.NoError(t, err)
+
+	msg, n := protowire.ConsumeBytes(out)
+	assert.Equal(t, len(out), n)
+	nums, values := consume(t, msg)
+	assert.Equal(t, []protowire.Number{1, 2, 3, 3, 4, 5}, nums)
+
+	ts := &timestamppb.Timestamp{}
+	assert.NoError(t, proto.Unmarshal(values[0].([]byte), ts))
+	assert.Equal(t, int64(1672628645), ts.GetSeconds())
+	assert.Equal(t, int32(500000000), ts.GetNanos())
+	assert.Equal(t, []byte("hello"), values[1])
+
+	// the map entries are sorted by the keys
+	_, entry := consume(t, values[2].([]byte))
+	assert.Equal(t, []interface{}{[]byte("app"), []byte("web")}, entry)
+
+	// the repeated scalars are packed in proto3
+	packed := values[4].([]byte)
+	first, n := protowire.ConsumeVarint(packed)
+	second, _ := protowire.ConsumeVarint(packed[n:])
+	assert.Equal(t, int64(-1), protowire.DecodeZigZag(first))
+	assert.Equal(t, int64(2), protowire.DecodeZigZag(second))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	path := writeDescriptorSet(t, protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto), logFile())
+	assert.NoError(t, (&Config{DescriptorSet: path, Message: "test.log.Record"}).Validate())
+	assert.Error(t, (&Config{DescriptorSet: path, Message: "test.log.Unknown"}).Validate())
+	assert.Error(t, (&Config{DescriptorSet: path, Message: "test.log.Record", Fields: map[string]string{"none": "a"}}).Validate())
+	assert.Error(t, (&Config{DescriptorSet: filepath.Join(t.TempDir(), "none"), Message: "test.log.Record"}).Validate())
+
+	// the dependencies are required in the descriptor set
+	assert.Error(t, (&Config{DescriptorSet: writeDescriptorSet(t, logFile()), Message: "test.log.Record"}).Validate())
+}
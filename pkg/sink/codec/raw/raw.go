@@ -30,16 +30,29 @@ func init() {
 	codec.Register(Type, makeRawCodec)
 }
 
+// Raw forwards the body bytes untouched without any re-encoding, which is cheap for the relay topologies
 type Raw struct {
+	config    *Config
 	codecConf *codec.Config
 }
 
+type Config struct {
+	// Original encodes the original bytes received by the source, which are kept by source codec or interceptor preserveRaw
+	Original bool `yaml:"original,omitempty"`
+}
+
 func makeRawCodec() codec.Codec {
 	return NewRaw()
 }
 
 func NewRaw() *Raw {
-	return &Raw{}
+	return &Raw{
+		config: &Config{},
+	}
+}
+
+func (j *Raw) Config() interface{} {
+	return j.config
 }
 
 func (j *Raw) Init(config *codec.Config) {
@@ -47,8 +60,13 @@ func (j *Raw) Init(config *codec.Config) {
 }
 
 func (j *Raw) Encode(e api.Event) ([]byte, error) {
+	body := e.Body()
+	if j.config.Original {
+		body = codec.OriginalBody(e)
+	}
+
 	if j.codecConf.PrintEvents {
-		log.Info("[print events] %s", string(e.Body()))
+		log.Info("[print events] %s", string(body))
 	}
-	return e.Body(), nil
+	return body, nil
 }
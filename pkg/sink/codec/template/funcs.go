@@ -0,0 +1,209 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+// funcMap is a subset of the sprig functions, whose arguments are in the same order,
+// so the templates written for sprig such as {{ .msg | replace "\n" " " | quote }} work as well
+func funcMap() template.FuncMap {
+	return template.FuncMap{
+		"toJson":       toJson,
+		"toPrettyJson": toPrettyJson,
+		"toString":     toString,
+		"quote":        quote,
+		"squote":       squote,
+		"csvQuote":     csvQuote,
+		"upper":        strings.ToUpper,
+		"lower":        strings.ToLower,
+		"trim":         strings.TrimSpace,
+		"trimPrefix":   func(prefix string, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix":   func(suffix string, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":      func(old string, new string, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":     func(substr string, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":    func(prefix string, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":    func(suffix string, s string) bool { return strings.HasSuffix(s, suffix) },
+		"splitList":    func(sep string, s string) []string { return strings.Split(s, sep) },
+		"join":         join,
+		"indent":       indent,
+		"nindent":      func(n int, s string) string { return "\n" + indent(n, s) },
+		"default":      dfault,
+		"empty":        empty,
+		"coalesce":     coalesce,
+		"now":          time.Now,
+		"date":         date,
+		"unixEpoch":    func(t time.Time) string { return fmt.Sprint(t.Unix()) },
+		"b64enc":       func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":       b64dec,
+	}
+}
+
+func toJson(v interface{}) string {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+func toPrettyJson(v interface{}) string {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	}
+	return fmt.Sprint(v)
+}
+
+func quote(v ...interface{}) string {
+	out := make([]string, 0, len(v))
+	for _, s := range v {
+		if s != nil {
+			out = append(out, fmt.Sprintf("%q", toString(s)))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func squote(v ...interface{}) string {
+	out := make([]string, 0, len(v))
+	for _, s := range v {
+		if s != nil {
+			out = append(out, "'"+toString(s)+"'")
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// csvQuote quotes the field when it contains the comma, quote or line breaks, as RFC 4180
+func csvQuote(v interface{}) string {
+	s := toString(v)
+	if !strings.ContainsAny(s, ",\"\r\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func join(sep string, v interface{}) string {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return toString(v)
+	}
+	out := make([]string, 0, val.Len())
+	for i := 0; i < val.Len(); i++ {
+		out = append(out, toString(val.Index(i).Interface()))
+	}
+	return strings.Join(out, sep)
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// dfault returns d if the given value is empty, it is named default in templates
+func dfault(d interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || empty(given[0]) {
+		return d
+	}
+	return given[0]
+}
+
+func empty(v interface{}) bool {
+	val := reflect.ValueOf(v)
+	if !val.IsValid() {
+		return true
+	}
+	switch val.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return val.Len() == 0
+	case reflect.Bool:
+		return !val.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return val.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return val.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return val.IsNil()
+	}
+	return false
+}
+
+func coalesce(v ...interface{}) interface{} {
+	for _, val := range v {
+		if !empty(val) {
+			return val
+		}
+	}
+	return nil
+}
+
+// date formats the time in the go layout, the strings in RFC3339 such as @timestamp are parsed first
+func date(layout string, v interface{}) string {
+	var t time.Time
+	switch d := v.(type) {
+	case time.Time:
+		t = d
+	case *time.Time:
+		t = *d
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, d)
+		if err != nil {
+			return d
+		}
+		t = parsed
+	case int64:
+		t = time.Unix(d, 0)
+	case int:
+		t = time.Unix(int64(d), 0)
+	case float64:
+		t = time.Unix(int64(d), 0)
+	default:
+		t = time.Now()
+	}
+	return t.Format(layout)
+}
+
+func b64dec(s string) string {
+	out, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
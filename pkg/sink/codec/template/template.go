@@ -0,0 +1,183 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util"
+)
+
+const (
+	Type = "template"
+
+	// EventsKey refers to the events in the batch template, such as {{ range .events }}
+	EventsKey = "events"
+)
+
+func init() {
+	codec.Register(Type, makeTemplateCodec)
+}
+
+// Template renders the events by the go templates, the fields of the header and the body could be referred
+// such as {{ .fields.app }} and {{ .body }}, so the custom json envelopes, csv lines or any other wire formats
+// could be produced without a new sink
+type Template struct {
+	config    *Config
+	codecConf *codec.Config
+	event     *template.Template
+	batch     *template.Template
+}
+
+type Config struct {
+	// Template renders each event
+	Template string `yaml:"template,omitempty"`
+	// BatchTemplate renders the events of a batch at once for the sinks supporting, such as wrapping them in an envelope
+	BatchTemplate string `yaml:"batchTemplate,omitempty"`
+	// Strict fails the rendering when a field is missing, or it is rendered as <no value>
+	Strict bool `yaml:"strict,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	if c.Template == "" && c.BatchTemplate == "" {
+		return errors.New("one of template or batchTemplate is required")
+	}
+	if _, err := parse("template", c.Template, c.Strict); err != nil {
+		return err
+	}
+	if _, err := parse("batchTemplate", c.BatchTemplate, c.Strict); err != nil {
+		return err
+	}
+	return nil
+}
+
+func parse(name string, text string, strict bool) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	t := template.New(name).Funcs(funcMap())
+	if strict {
+		t = t.Option("missingkey=error")
+	}
+	t, err := t.Parse(text)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "parse %s", name)
+	}
+	return t, nil
+}
+
+func makeTemplateCodec() codec.Codec {
+	return NewTemplate()
+}
+
+func NewTemplate() *Template {
+	return &Template{
+		config: &Config{},
+	}
+}
+
+func (t *Template) Config() interface{} {
+	return t.config
+}
+
+func (t *Template) Init(config *codec.Config) {
+	t.codecConf = config
+	// the templates have been validated
+	t.event = template.Must(parse("template", t.config.Template, t.config.Strict))
+	t.batch = template.Must(parse("batchTemplate", t.config.BatchTemplate, t.config.Strict))
+}
+
+func (t *Template) Encode(e api.Event) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+	if t.event != nil {
+		out, err = render(t.event, data(e))
+	} else {
+		out, err = render(t.batch, map[string]interface{}{EventsKey: []interface{}{data(e)}})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if t.codecConf.PrintEvents {
+		log.Info("[print events] %s", string(out))
+	}
+	return out, nil
+}
+
+func (t *Template) BatchEnabled() bool {
+	return t.batch != nil
+}
+
+// EncodeBatch renders the events by the batch template, or joins the events rendered by the template with line breaks
+func (t *Template) EncodeBatch(events []api.Event) ([]byte, error) {
+	if t.batch == nil {
+		var buf bytes.Buffer
+		for i, e := range events {
+			if i > 0 {
+				buf.WriteByte('\n')
+			}
+			if err := t.event.Execute(&buf, data(e)); err != nil {
+				return nil, err
+			}
+		}
+		return buf.Bytes(), nil
+	}
+
+	items := make([]interface{}, 0, len(events))
+	for _, e := range events {
+		items = append(items, data(e))
+	}
+	out, err := render(t.batch, map[string]interface{}{EventsKey: items})
+	if err != nil {
+		return nil, err
+	}
+	if t.codecConf.PrintEvents {
+		log.Info("[print events] %s", string(out))
+	}
+	return out, nil
+}
+
+// data is the header with the body, the same as the fields encoded by the json codec
+func data(e api.Event) map[string]interface{} {
+	header := e.Header()
+	out := make(map[string]interface{}, len(header)+1)
+	for k, v := range header {
+		out[k] = v
+	}
+	if len(e.Body()) != 0 {
+		out[event.Body] = util.ByteToStringUnsafe(e.Body())
+	}
+	return out
+}
+
+func render(t *template.Template, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+)
+
+func newTemplate(t *testing.T, raw string) *Template {
+	tpl := NewTemplate()
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), tpl.config).Defaults().Validate().Do())
+	tpl.Init(&codec.Config{Type: Type})
+	return tpl
+}
+
+func TestTemplate_Encode(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		event    api.Event
+		want     string
+	}{
+		{
+			name:     "envelope",
+			template: `template: '{"service":{{ .fields.app | quote }},"level":{{ .level | default "info" | upper | quote }},"fields":{{ toJson .fields }},"message":{{ .body | quote }}}'`,
+			event:    event.NewEvent(map[string]interface{}{"fields": map[string]interface{}{"app": "web"}}, []byte("hello")),
+			want:     `{"service":"web","level":"INFO","fields":{"app":"web"},"message":"hello"}`,
+		},
+		{
+			name:     "csv",
+			template: `template: '{{ .ip }},{{ .msg | csvQuote }},{{ .status }}'`,
+			event:    event.NewEvent(map[string]interface{}{"ip": "10.0.0.1", "msg": `say "hi", bye`, "status": 200}, nil),
+			want:     `10.0.0.1,"say ""hi"", bye",200`,
+		},
+		{
+			name:     "functions",
+			template: `template: '{{ .path | trimPrefix "/api" | replace "/" "." }} {{ date "2006-01-02" .ts }} {{ join "|" .tags }} {{ coalesce .none .name }}'`,
+			event: event.NewEvent(map[string]interface{}{
+				"path": "/api/v1/users",
+				"ts":   "2023-05-06T07:08:09Z",
+				"tags": []interface{}{"a", "b"},
+				"name": "loggie",
+			}, nil),
+			want: `.v1.users 2023-05-06 a|b loggie`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := newTemplate(t, tt.template).Encode(tt.event)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(out))
+		})
+	}
+}
+
+func TestTemplate_EncodeBatch(t *testing.T) {
+	events := []api.Event{
+		event.NewEvent(map[string]interface{}{"id": 1}, []byte("a")),
+		event.NewEvent(map[string]interface{}{"id": 2}, []byte("b")),
+	}
+
+	tpl := newTemplate(t, `batchTemplate: '{"count":{{ len .events }},"records":[{{ range $i, $e := .events }}{{ if $i }},{{ end }}{"id":{{ $e.id }},"msg":{{ toJson $e.body }}}{{ end }}]}'`)
+	assert.True(t, tpl.BatchEnabled())
+	out, err := tpl.EncodeBatch(events)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"count":2,"records":[{"id":1,"msg":"a"},{"id":2,"msg":"b"}]}`, string(out))
+
+	// a single event is rendered as a batch of one
+	out, err = tpl.Encode(events[0])
+	assert.NoError(t, err)
+	assert.Equal(t, `{"count":1,"records":[{"id":1,"msg":"a"}]}`, string(out))
+
+	tpl = newTemplate(t, `template: '{{ .id }}={{ .body }}'`)
+	assert.False(t, tpl.BatchEnabled())
+	out, err = tpl.EncodeBatch(events)
+	assert.NoError(t, err)
+	assert.Equal(t, "1=a\n2=b", string(out))
+}
+
+func TestTemplate_Strict(t *testing.T) {
+	tpl := newTemplate(t, "template: '{{ .missing }}'\nstrict: true")
+	_, err := tpl.Encode(event.NewEvent(map[string]interface{}{}, nil))
+	assert.Error(t, err)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.Error(t, (&Config{}).Validate())
+	assert.Error(t, (&Config{Template: "{{ .a "}).Validate())
+	assert.Error(t, (&Config{Template: "{{ unknown .a }}"}).Validate())
+	assert.NoError(t, (&Config{Template: "{{ .a | upper }}"}).Validate())
+
+	c := &codec.Config{Type: Type, CommonCfg: cfg.CommonCfg{"template": "{{ .a "}}
+	assert.Error(t, c.Validate())
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datadog
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
+
+// sites are the regions of datadog
+var sites = map[string]struct{}{
+	"datadoghq.com":     {},
+	"us3.datadoghq.com": {},
+	"us5.datadoghq.com": {},
+	"datadoghq.eu":      {},
+	"ap1.datadoghq.com": {},
+	"ddog-gov.com":      {},
+}
+
+type Config struct {
+	APIKey string `yaml:"apiKey,omitempty" validate:"required"`
+	// Site selects the regional intake, such as datadoghq.eu, Endpoint overrides it, such as a proxy
+	Site     string `yaml:"site,omitempty" default:"datadoghq.com"`
+	Endpoint string `yaml:"endpoint,omitempty"`
+
+	// Service, Source and Hostname could be patterns such as ${fields.app}
+	Service  string `yaml:"service,omitempty"`
+	Source   string `yaml:"source,omitempty" default:"loggie"`
+	Hostname string `yaml:"hostname,omitempty"`
+	// Tags are added to the ddtags as <name>:<value>, the keys are the tag names and the values are the keys of the events
+	Tags       map[string]string `yaml:"tags,omitempty"`
+	StaticTags []string          `yaml:"staticTags,omitempty"` // such as env:prod
+
+	// the limits of a request of the logs intake, the batch is split when exceeded
+	MaxBatchEvents int `yaml:"maxBatchEvents,omitempty" default:"1000" validate:"gte=1,lte=1000"`
+	MaxBatchBytes  int `yaml:"maxBatchBytes,omitempty" default:"1048576" validate:"gte=1,lte=5242880"`
+
+	Compress  *bool            `yaml:"compress,omitempty" default:"true"` // gzip the request body
+	Timeout   time.Duration    `yaml:"timeout,omitempty" default:"30s"`
+	HostLimit hostlimit.Config `yaml:"hostLimit,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	if c.Endpoint != "" {
+		if _, err := url.ParseRequestURI(c.Endpoint); err != nil {
+			return errors.WithMessagef(err, "datadog sink endpoint %s is invalid", c.Endpoint)
+		}
+	} else if _, ok := sites[c.Site]; !ok {
+		return errors.Errorf("datadog sink site %s is unknown, set the endpoint instead", c.Site)
+	}
+	for _, p := range []string{c.Service, c.Source, c.Hostname} {
+		if err := pattern.Validate(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) url() string {
+	if c.Endpoint != "" {
+		return c.Endpoint
+	}
+	return "https://http-intake.logs." + c.Site + "/api/v2/logs"
+}
+
+func (c *Config) compress() bool {
+	return c.Compress == nil || *c.Compress
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datadog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	eventer "github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const (
+	Type = "datadog"
+
+	apiKeyHeader = "DD-API-KEY"
+)
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink(info.PipelineName)
+}
+
+type Sink struct {
+	pipelineName string
+	name         string
+	config       *Config
+	codec        codec.Codec
+	client       *http.Client
+	limiter      *hostlimit.Transport
+
+	servicePattern  *pattern.Pattern
+	sourcePattern   *pattern.Pattern
+	hostnamePattern *pattern.Pattern
+	tagNames        []string // sorted, so the ddtags are stable
+}
+
+func NewSink(pipelineName string) *Sink {
+	return &Sink{
+		pipelineName: pipelineName,
+		config:       &Config{},
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) SetCodec(c codec.Codec) {
+	s.codec = c
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.servicePattern, _ = pattern.Init(s.config.Service)
+	s.sourcePattern, _ = pattern.Init(s.config.Source)
+	s.hostnamePattern, _ = pattern.Init(s.config.Hostname)
+
+	for name := range s.config.Tags {
+		s.tagNames = append(s.tagNames, name)
+	}
+	sort.Strings(s.tagNames)
+
+	s.client = &http.Client{
+		Timeout: s.config.Timeout,
+	}
+	return nil
+}
+
+func (s *Sink) Start() error {
+	if s.config.HostLimit.Enabled() {
+		s.limiter = hostlimit.NewTransport(s.client.Transport, &s.config.HostLimit, s.pipelineName, s.name)
+		s.client.Transport = s.limiter
+	}
+	log.Info("%s start, url: %s", s.String(), s.config.url())
+	return nil
+}
+
+func (s *Sink) Stop() {
+	if s.limiter != nil {
+		s.limiter.Stop()
+	}
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	entries := make([][]byte, 0, len(events))
+	for _, e := range events {
+		entry, err := s.encode(e)
+		if err != nil {
+			log.Warn("[%s] encode event error: %v", s.name, err)
+			continue
+		}
+		// the intake would reject the whole request
+		if len(entry)+2 > s.config.MaxBatchBytes {
+			log.Warn("[%s] drop the event of %d bytes which exceeds maxBatchBytes", s.name, len(entry))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, payload := range split(entries, s.config.MaxBatchEvents, s.config.MaxBatchBytes) {
+		if err := s.send(context.Background(), payload); err != nil {
+			return result.Fail(errors.WithMessage(err, "send logs to datadog"))
+		}
+	}
+	return result.Success()
+}
+
+// entry is a log of the logs intake, the reserved attributes are parsed by datadog
+type entry struct {
+	Message   string `json:"message"`
+	DDSource  string `json:"ddsource,omitempty"`
+	DDTags    string `json:"ddtags,omitempty"`
+	Hostname  string `json:"hostname,omitempty"`
+	Service   string `json:"service,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"` // in milliseconds
+}
+
+func (s *Sink) encode(e api.Event) ([]byte, error) {
+	obj := runtime.NewObject(e.Header())
+	out := &entry{}
+
+	var err error
+	if out.Service, err = s.servicePattern.WithObject(obj).Render(); err != nil {
+		return nil, err
+	}
+	if out.DDSource, err = s.sourcePattern.WithObject(obj).Render(); err != nil {
+		return nil, err
+	}
+	if out.Hostname, err = s.hostnamePattern.WithObject(obj).Render(); err != nil {
+		return nil, err
+	}
+	out.DDTags = s.tags(obj)
+
+	if e.Meta() != nil {
+		if v, ok := e.Meta().Get(eventer.SystemProductTimeKey); ok {
+			if t, ok := v.(time.Time); ok {
+				out.Timestamp = t.UnixMilli()
+			}
+		}
+	}
+
+	// a json message is parsed into the attributes by datadog
+	message, err := s.codec.Encode(e)
+	if err != nil {
+		return nil, err
+	}
+	out.Message = string(message)
+	return json.Marshal(out)
+}
+
+func (s *Sink) tags(obj *runtime.Object) string {
+	tags := make([]string, 0, len(s.config.StaticTags)+len(s.tagNames))
+	tags = append(tags, s.config.StaticTags...)
+	for _, name := range s.tagNames {
+		v := obj.GetPath(s.config.Tags[name]).Value()
+		if v == nil {
+			continue
+		}
+		value := fmt.Sprint(v)
+		if value == "" {
+			continue
+		}
+		tags = append(tags, name+":"+value)
+	}
+	return strings.Join(tags, ",")
+}
+
+// split packs the entries into json arrays limited by the number of entries and the bytes
+func split(entries [][]byte, maxEvents int, maxBytes int) [][]byte {
+	var payloads [][]byte
+	var buf bytes.Buffer
+	count := 0
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		buf.WriteByte(']')
+		payloads = append(payloads, append([]byte(nil), buf.Bytes()...))
+		buf.Reset()
+		count = 0
+	}
+
+	for _, e := range entries {
+		// the brackets and a comma
+		if count >= maxEvents || (count > 0 && buf.Len()+len(e)+2 > maxBytes) {
+			flush()
+		}
+		if count == 0 {
+			buf.WriteByte('[')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.Write(e)
+		count++
+	}
+	flush()
+	return payloads
+}
+
+func (s *Sink) send(ctx context.Context, body []byte) error {
+	header := http.Header{}
+	if s.config.compress() {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(body); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+		header.Set("Content-Encoding", "gzip")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.url(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(apiKeyHeader, s.config.APIKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return errors.Errorf("datadog returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datadog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/codec/json"
+	ljson "github.com/loggie-io/loggie/pkg/util/json"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		header map[string]interface{}
+		want   entry
+	}{
+		{
+			name:   "service and tags",
+			config: &Config{Service: "${fields.app}", Tags: map[string]string{"pod": "fields.pod", "namespace": "fields.namespace"}, StaticTags: []string{"env:test"}},
+			header: map[string]interface{}{"fields": map[string]interface{}{"app": "foo", "namespace": "default"}},
+			want:   entry{Service: "foo", DDTags: "env:test,namespace:default", Timestamp: 1700000000123},
+		},
+		{
+			name:   "without tags",
+			config: &Config{Source: "loggie", Hostname: "${host}"},
+			header: map[string]interface{}{"host": "node-1"},
+			want:   entry{DDSource: "loggie", Hostname: "node-1", Timestamp: 1700000000123},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSink("test")
+			s.config = tt.config
+			c := json.NewJson()
+			c.Init(&codec.Config{})
+			s.SetCodec(c)
+			assert.NoError(t, s.Init(context.NewContext("datadog", Type, api.SINK, nil)))
+
+			e := event.NewEvent(tt.header, []byte("log"))
+			meta := event.NewDefaultMeta()
+			meta.Set(event.SystemProductTimeKey, time.UnixMilli(1700000000123))
+			e.Fill(meta, e.Header(), e.Body())
+
+			data, err := s.encode(e)
+			assert.NoError(t, err)
+			got := entry{}
+			assert.NoError(t, ljson.Unmarshal(data, &got))
+			assert.Contains(t, got.Message, `"body":"log"`)
+			got.Message = ""
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	entries := [][]byte{[]byte(`{"a":1}`), []byte(`{"b":2}`), []byte(`{"c":3}`)}
+	tests := []struct {
+		name      string
+		maxEvents int
+		maxBytes  int
+		want      []string
+	}{
+		{name: "one payload", maxEvents: 10, maxBytes: 100, want: []string{`[{"a":1},{"b":2},{"c":3}]`}},
+		{name: "by max events", maxEvents: 2, maxBytes: 100, want: []string{`[{"a":1},{"b":2}]`, `[{"c":3}]`}},
+		{name: "by max bytes", maxEvents: 10, maxBytes: 17, want: []string{`[{"a":1},{"b":2}]`, `[{"c":3}]`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range split(entries, tt.maxEvents, tt.maxBytes) {
+				got = append(got, string(p))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfigURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		want    string
+		wantErr bool
+	}{
+		{name: "site", config: &Config{Site: "datadoghq.eu"}, want: "https://http-intake.logs.datadoghq.eu/api/v2/logs"},
+		{name: "endpoint", config: &Config{Site: "example.com", Endpoint: "http://proxy:8080/logs"}, want: "http://proxy:8080/logs"},
+		{name: "unknown site", config: &Config{Site: "example.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				assert.Error(t, tt.config.Validate())
+				return
+			}
+			assert.NoError(t, tt.config.Validate())
+			assert.Equal(t, tt.want, tt.config.url())
+		})
+	}
+}
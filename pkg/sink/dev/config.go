@@ -29,4 +29,23 @@ type Config struct {
 
 	// resultStatus can be used to simulate failure, drop
 	ResultStatus string `yaml:"resultStatus,omitempty" default:"success"`
+
+	// Console prints every event to stdout in a human-friendly way instead of the codec, for developing parsing rules locally
+	Console ConsoleConfig `yaml:"console,omitempty"`
+}
+
+type ConsoleConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Layout could be line, which prints the fields of each event as key=value, or table, which aligns the fields
+	// of the events in each batch in columns
+	Layout string `yaml:"layout,omitempty" default:"line" validate:"oneof=line table"`
+	// Fields are the fields printed in order, `body` refers to the event body. All the header fields and the body
+	// are printed when it is empty
+	Fields []string `yaml:"fields,omitempty"`
+	// LevelField is printed first and colorized
+	LevelField string `yaml:"levelField,omitempty" default:"level"`
+	// Color could be auto, which colorizes only when stdout is a terminal, always or never
+	Color string `yaml:"color,omitempty" default:"auto" validate:"oneof=auto always never"`
+	// MaxLength truncates each value to the number of characters, 0 means no truncation
+	MaxLength int `yaml:"maxLength,omitempty" default:"256" validate:"gte=0"`
 }
@@ -0,0 +1,239 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dev
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/util/eventops"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	LayoutLine  = "line"
+	LayoutTable = "table"
+
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorGray  = "\x1b[90m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorYell  = "\x1b[33m"
+	colorBlue  = "\x1b[34m"
+
+	ellipsis = "…"
+)
+
+// console prints the events for humans, it is not meant to be parsed
+type console struct {
+	config *ConsoleConfig
+	out    io.Writer
+	color  bool
+}
+
+func newConsole(config *ConsoleConfig, out *os.File) *console {
+	c := &console{
+		config: config,
+		out:    out,
+	}
+	switch config.Color {
+	case ColorAlways:
+		c.color = true
+	case ColorAuto:
+		c.color = isTerminal(out)
+	}
+	return c
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// print writes the events at once, so the batches of different pipelines are not interleaved
+func (c *console) print(events []api.Event) {
+	buf := &bytes.Buffer{}
+	if c.config.Layout == LayoutTable {
+		c.table(buf, events)
+	} else {
+		for _, e := range events {
+			c.line(buf, e)
+		}
+	}
+	_, _ = c.out.Write(buf.Bytes())
+}
+
+func (c *console) line(buf *bytes.Buffer, e api.Event) {
+	level := c.value(e, c.config.LevelField)
+	if level != "" {
+		buf.WriteString(c.colorize(levelColor(level), fmt.Sprintf("%-5s", strings.ToUpper(level))))
+		buf.WriteByte(' ')
+	}
+
+	for i, f := range c.fields([]api.Event{e}) {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		v := c.value(e, f)
+		if f == event.Body {
+			buf.WriteString(v)
+			continue
+		}
+		buf.WriteString(c.colorize(colorBlue, f))
+		buf.WriteByte('=')
+		buf.WriteString(v)
+	}
+	buf.WriteByte('\n')
+}
+
+func (c *console) table(buf *bytes.Buffer, events []api.Event) {
+	columns := c.fields(events)
+	if c.config.LevelField != "" {
+		columns = append([]string{c.config.LevelField}, columns...)
+	}
+
+	rows := make([][]string, len(events))
+	widths := make([]int, len(columns))
+	for i, col := range columns {
+		widths[i] = utf8.RuneCountInString(col)
+	}
+	for r, e := range events {
+		row := make([]string, len(columns))
+		for i, col := range columns {
+			row[i] = c.value(e, col)
+			if w := utf8.RuneCountInString(row[i]); w > widths[i] {
+				widths[i] = w
+			}
+		}
+		rows[r] = row
+	}
+
+	for i, col := range columns {
+		c.cell(buf, i, colorBold, col, widths)
+	}
+	buf.WriteByte('\n')
+	for _, row := range rows {
+		for i, v := range row {
+			color := ""
+			if i == 0 && c.config.LevelField != "" {
+				color = levelColor(v)
+			}
+			c.cell(buf, i, color, v, widths)
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+func (c *console) cell(buf *bytes.Buffer, idx int, color string, v string, widths []int) {
+	if idx > 0 {
+		buf.WriteString("  ")
+	}
+	// the last column is not padded to avoid trailing spaces
+	if idx < len(widths)-1 {
+		v += strings.Repeat(" ", widths[idx]-utf8.RuneCountInString(v))
+	}
+	buf.WriteString(c.colorize(color, v))
+}
+
+// fields returns the configured fields, or all the header fields of the events in order and the body
+func (c *console) fields(events []api.Event) []string {
+	if len(c.config.Fields) > 0 {
+		return c.config.Fields
+	}
+
+	keys := make(map[string]struct{})
+	for _, e := range events {
+		for k := range e.Header() {
+			keys[k] = struct{}{}
+		}
+	}
+	delete(keys, c.config.LevelField)
+	fields := make([]string, 0, len(keys)+1)
+	for k := range keys {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	return append(fields, event.Body)
+}
+
+// value returns the field in one line, truncated to the max length
+func (c *console) value(e api.Event, field string) string {
+	if field == "" {
+		return ""
+	}
+
+	var v string
+	if field == event.Body {
+		v = string(e.Body())
+	} else if e.Header() != nil {
+		switch val := eventops.Get(e, field).(type) {
+		case nil:
+		case string:
+			v = val
+		case []byte:
+			v = string(val)
+		case map[string]interface{}, []interface{}:
+			out, _ := json.Marshal(val)
+			v = string(out)
+		default:
+			v = fmt.Sprintf("%v", val)
+		}
+	}
+
+	v = strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`).Replace(v)
+	if c.config.MaxLength > 0 && utf8.RuneCountInString(v) > c.config.MaxLength {
+		runes := []rune(v)
+		v = string(runes[:c.config.MaxLength]) + ellipsis
+	}
+	return v
+}
+
+func (c *console) colorize(color string, s string) string {
+	if !c.color || color == "" || s == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func levelColor(level string) string {
+	switch strings.ToLower(level) {
+	case "trace", "debug":
+		return colorGray
+	case "info", "notice":
+		return colorGreen
+	case "warn", "warning":
+		return colorYell
+	case "error", "err", "fatal", "critical", "panic":
+		return colorRed
+	}
+	return ""
+}
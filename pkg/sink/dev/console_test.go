@@ -0,0 +1,61 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dev
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+)
+
+func newTestConsole(config ConsoleConfig) (*console, *bytes.Buffer) {
+	if config.LevelField == "" {
+		config.LevelField = "level"
+	}
+	out := &bytes.Buffer{}
+	return &console{config: &config, out: out}, out
+}
+
+func TestConsoleLine(t *testing.T) {
+	c, out := newTestConsole(ConsoleConfig{Layout: LayoutLine, MaxLength: 8})
+	c.print([]api.Event{
+		event.NewEvent(map[string]interface{}{"level": "warn", "logger": "app", "ctx": map[string]interface{}{"a": 1}}, []byte("slow\nquery")),
+		event.NewEvent(nil, []byte("plain")),
+	})
+	assert.Equal(t, "WARN  ctx={\"a\":1} logger=app slow\\nqu…\nplain\n", out.String())
+
+	c.color = true
+	out.Reset()
+	c.print([]api.Event{event.NewEvent(map[string]interface{}{"level": "error"}, []byte("x"))})
+	assert.Equal(t, colorRed+"ERROR"+colorReset+" x\n", out.String())
+}
+
+func TestConsoleTable(t *testing.T) {
+	c, out := newTestConsole(ConsoleConfig{Layout: LayoutTable, Fields: []string{"logger", "body"}})
+	c.print([]api.Event{
+		event.NewEvent(map[string]interface{}{"level": "info", "logger": "checkout"}, []byte("paid")),
+		event.NewEvent(map[string]interface{}{"level": "debug"}, []byte("cache miss")),
+	})
+	assert.Equal(t, ""+
+		"level  logger    body\n"+
+		"info   checkout  paid\n"+
+		"debug            cache miss\n", out.String())
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/loggie-io/loggie/pkg/sink/codec"
 	"go.uber.org/atomic"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 )
@@ -55,6 +56,7 @@ type Sink struct {
 	pipelineName string
 	config       *Config
 	codec        codec.Codec
+	console      *console
 	done         chan struct{}
 
 	totalCount       *atomic.Uint64
@@ -103,6 +105,9 @@ func (s *Sink) handleHttp() {
 func (s *Sink) Init(context api.Context) error {
 	s.name = context.Name()
 	s.resultStatusFlag = atomic.NewString(s.config.ResultStatus)
+	if s.config.Console.Enabled {
+		s.console = newConsole(&s.config.Console, os.Stdout)
+	}
 	once.Do(func() {
 		s.handleHttp()
 	})
@@ -178,6 +183,11 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 		s.totalCount.Add(uint64(l))
 	}
 
+	if s.console != nil {
+		s.console.print(events)
+		return result.NewResult(api.SUCCESS)
+	}
+
 	for i, e := range events {
 		// json encode
 		out, err := s.codec.Encode(e)
@@ -0,0 +1,64 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// credentials are resolved from the config, secrets could be loaded from files such as a mounted kubernetes Secret
+type credentials struct {
+	password     string
+	apiKey       string
+	serviceToken string
+}
+
+func resolveCredentials(config *Config) (*credentials, error) {
+	password, err := readSecret(config.Password, config.PasswordFile)
+	if err != nil {
+		return nil, errors.WithMessage(err, "read password")
+	}
+	apiKey, err := readSecret(config.APIKey, config.APIKeyFile)
+	if err != nil {
+		return nil, errors.WithMessage(err, "read apiKey")
+	}
+	serviceToken, err := readSecret(config.ServiceToken, config.ServiceTokenFile)
+	if err != nil {
+		return nil, errors.WithMessage(err, "read serviceToken")
+	}
+	return &credentials{
+		password:     password,
+		apiKey:       apiKey,
+		serviceToken: serviceToken,
+	}, nil
+}
+
+// readSecret returns the value if it is not empty, otherwise reads it from the file
+func readSecret(value string, file string) (string, error) {
+	if value != "" || file == "" {
+		return value, nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	// files created by editors or `echo` usually end with a newline
+	return strings.TrimSpace(string(content)), nil
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveCredentials(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "api-key")
+	assert.NoError(t, os.WriteFile(file, []byte("id:key\n"), 0600))
+
+	creds, err := resolveCredentials(&Config{Password: "pass", APIKeyFile: file})
+	assert.NoError(t, err)
+	assert.Equal(t, "pass", creds.password)
+	assert.Equal(t, "id:key", creds.apiKey)
+	assert.Equal(t, "", creds.serviceToken)
+
+	_, err = resolveCredentials(&Config{ServiceTokenFile: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	assert.Error(t, (&Config{}).Validate())
+	assert.Error(t, (&Config{Hosts: []string{"localhost:9200"}, CloudID: "name:xxx"}).Validate())
+	assert.NoError(t, (&Config{CloudID: "name:xxx"}).Validate())
+	assert.Error(t, (&Config{CloudID: "name:xxx", APIKey: "a", ServiceTokenFile: "/token"}).Validate())
+}
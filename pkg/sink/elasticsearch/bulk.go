@@ -16,6 +16,68 @@ limitations under the License.
 
 package elasticsearch
 
+import (
+	"math"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+// BulkSize splits the events of a batch into bulk requests by the payload bytes, since the sizes of the events vary
+// a lot and batching by the event count only produces requests from a few KB to hundreds of MB.
+type BulkSize struct {
+	// MaxBytes is the payload bytes from which a bulk request is flushed, 0 only splits by MaxContentLength
+	MaxBytes int `yaml:"maxBytes,omitempty" default:"10485760" validate:"gte=0"`
+	// MaxContentLength should be the `http.max_content_length` of elasticsearch, a request is never larger than it,
+	// and a document larger than it is dropped since it would always be rejected. 0 means unlimited.
+	// A request rejected with 413 is split into halves and sent again.
+	MaxContentLength int `yaml:"maxContentLength,omitempty" default:"104857600"`
+}
+
+func (l line) size() int {
+	return len(l.meta) + len(l.body) + 1
+}
+
+// limit returns the bytes from which a bulk request is flushed
+func (s BulkSize) limit() int {
+	if s.MaxContentLength <= 0 {
+		if s.MaxBytes > 0 {
+			return s.MaxBytes
+		}
+		return math.MaxInt32
+	}
+	if s.MaxBytes > 0 && s.MaxBytes < s.MaxContentLength {
+		return s.MaxBytes
+	}
+	return s.MaxContentLength
+}
+
+// split takes the lines of the next bulk request within the limit, a line larger than the limit is sent alone,
+// and a line larger than maxContentLength is dropped
+func (s BulkSize) split(lines []line, limit int) (chunk []line, rest []line) {
+	size := 0
+	for i, l := range lines {
+		n := l.size()
+		if s.MaxContentLength > 0 && n > s.MaxContentLength {
+			log.Warn("drop the event of %d bytes which exceeds the maxContentLength %d of elasticsearch bulk", n, s.MaxContentLength)
+			continue
+		}
+		if len(chunk) > 0 && size+n > limit {
+			return chunk, lines[i:]
+		}
+		chunk = append(chunk, l)
+		size += n
+	}
+	return chunk, nil
+}
+
+func chunkSize(lines []line) int {
+	size := 0
+	for _, l := range lines {
+		size += l.size()
+	}
+	return size
+}
+
 // BulkIndexerResponse represents the Elasticsearch response.
 type BulkIndexerResponse struct {
 	Took      int                                   `json:"took"`
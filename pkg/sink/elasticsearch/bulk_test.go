@@ -0,0 +1,91 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func created(n int, docs []string) []int {
+	statuses := make([]int, len(docs))
+	for i := range statuses {
+		statuses[i] = 201
+	}
+	return statuses
+}
+
+func TestSplit(t *testing.T) {
+	log.InitDefaultLogger()
+	// each line is 38 bytes with the meta `{"index":{"_index":"test"}}\n` of 28 bytes
+	lines := testLines(`{"a":"1"}`, `{"a":"2"}`, `{"a":"3"}`, `{"a":"`+strings.Repeat("x", 100)+`"}`, `{"a":"4"}`)
+	s := BulkSize{MaxBytes: 80, MaxContentLength: 100}
+
+	chunk, rest := s.split(lines, s.limit())
+	assert.Equal(t, 2, len(chunk))
+	// the line larger than maxContentLength is dropped
+	chunk, rest = s.split(rest, s.limit())
+	assert.Equal(t, 2, len(chunk))
+	assert.Equal(t, `{"a":"4"}`, string(chunk[1].body))
+	assert.Nil(t, rest)
+
+	// a line larger than maxBytes is sent alone
+	s = BulkSize{MaxBytes: 10, MaxContentLength: 100}
+	chunk, rest = s.split(lines, s.limit())
+	assert.Equal(t, 1, len(chunk))
+	assert.Equal(t, 4, len(rest))
+}
+
+func TestFlushByBytes(t *testing.T) {
+	log.InitDefaultLogger()
+	fake := &fakeBulk{statuses: created}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cli := newTestClient(t, server.URL, false)
+	cli.config.Bulk = BulkSize{MaxBytes: 80, MaxContentLength: 1000}
+	cli.bulkLimit = int64(cli.config.Bulk.limit())
+
+	err := cli.flush(context.Background(), testLines(`{"a":"1"}`, `{"a":"2"}`, `{"a":"3"}`, `{"a":"4"}`, `{"a":"5"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{`{"a":"1"}`, `{"a":"2"}`}, {`{"a":"3"}`, `{"a":"4"}`}, {`{"a":"5"}`}}, fake.requests)
+}
+
+func TestFlushTooLarge(t *testing.T) {
+	log.InitDefaultLogger()
+	// http.max_content_length of elasticsearch is smaller than configured
+	fake := &fakeBulk{statuses: created, maxContentLength: 80}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cli := newTestClient(t, server.URL, false)
+	cli.config.Bulk = BulkSize{MaxContentLength: 1000}
+	cli.bulkLimit = int64(cli.config.Bulk.limit())
+
+	big := `{"a":"` + strings.Repeat("x", 100) + `"}`
+	err := cli.flush(context.Background(), testLines(`{"a":"1"}`, `{"a":"2"}`, `{"a":"3"}`, big, `{"a":"4"}`))
+	assert.NoError(t, err)
+	// the rejected requests are not recorded by the fake bulk, the too large event is dropped
+	assert.Equal(t, [][]string{{`{"a":"1"}`}, {`{"a":"2"}`}, {`{"a":"3"}`}, {`{"a":"4"}`}}, fake.requests)
+	assert.Equal(t, int64(57), cli.bulkLimit)
+}
@@ -19,8 +19,11 @@ package elasticsearch
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	es "github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/estransport"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/loggie-io/loggie/pkg/core/api"
 	eventer "github.com/loggie-io/loggie/pkg/core/event"
@@ -29,13 +32,18 @@ import (
 	"github.com/loggie-io/loggie/pkg/util/pattern"
 	"github.com/loggie-io/loggie/pkg/util/runtime"
 	"github.com/pkg/errors"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 var (
 	json = jsoniter.ConfigFastest
+
+	errTooLarge = errors.New("request entity too large")
 )
 
 type ClientSet struct {
@@ -49,6 +57,10 @@ type ClientSet struct {
 	indexPattern        *pattern.Pattern
 	defaultIndexPattern *pattern.Pattern
 	documentIdPattern   *pattern.Pattern
+	deadLetterPattern   *pattern.Pattern
+
+	// bulkLimit is the bytes from which a bulk request is flushed, it is lowered when a request is rejected as too large
+	bulkLimit int64
 }
 
 type bulkRequest struct {
@@ -58,6 +70,8 @@ type bulkRequest struct {
 type line struct {
 	meta []byte
 	body []byte
+	// object is the header of the event, used to render the dead letter index
+	object *runtime.Object
 }
 
 func (b *bulkRequest) body() []byte {
@@ -76,7 +90,7 @@ func (b *bulkRequest) body() []byte {
 	return buf.Bytes()
 }
 
-func (b *bulkRequest) add(body []byte, action string, documentID string, index string) {
+func (b *bulkRequest) add(body []byte, action string, documentID string, index string, object *runtime.Object) {
 	if len(body) == 0 {
 		return
 	}
@@ -108,8 +122,9 @@ func (b *bulkRequest) add(body []byte, action string, documentID string, index s
 	buf.WriteRune('\n')
 
 	l := line{
-		meta: buf.Bytes(),
-		body: body,
+		meta:   buf.Bytes(),
+		body:   body,
+		object: object,
 	}
 
 	b.lines = append(b.lines, l)
@@ -120,8 +135,10 @@ type Client interface {
 	Stop()
 }
 
+// NewClient creates the elasticsearch client, wrapTransport is optional and wraps the http transport, such as
+// limiting the requests to each host
 func NewClient(config *Config, cod codec.Codec, indexPattern *pattern.Pattern, documentIdPattern *pattern.Pattern,
-	defaultIndexPattern *pattern.Pattern) (*ClientSet, error) {
+	defaultIndexPattern *pattern.Pattern, wrapTransport func(http.RoundTripper) http.RoundTripper) (*ClientSet, error) {
 	for i, h := range config.Hosts {
 		if !strings.HasPrefix(h, "http") && !strings.HasPrefix(h, "https") {
 			config.Hosts[i] = fmt.Sprintf("http://%s", h)
@@ -136,32 +153,96 @@ func NewClient(config *Config, cod codec.Codec, indexPattern *pattern.Pattern, d
 		ca = caData
 	}
 
+	creds, err := resolveCredentials(config)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := es.Config{
 		Addresses:             config.Hosts,
+		CloudID:               config.CloudID,
 		DisableRetry:          true,
 		Username:              config.UserName,
-		Password:              config.Password,
-		APIKey:                config.APIKey,
-		ServiceToken:          config.ServiceToken,
+		Password:              creds.password,
+		APIKey:                creds.apiKey,
+		ServiceToken:          creds.serviceToken,
 		CompressRequestBody:   config.Compress,
 		DiscoverNodesOnStart:  config.DiscoverNodesOnStart,
 		DiscoverNodesInterval: config.DiscoverNodesInterval,
 		CACert:                ca,
 	}
+	var hosts *hostPool
+	if config.LoadBalance.Enabled {
+		hosts = newHostPool(&config.LoadBalance)
+		cfg.ConnectionPoolFunc = func(conns []*estransport.Connection, _ estransport.Selector) estransport.ConnectionPool {
+			return hosts.update(conns)
+		}
+	}
+	if wrapTransport != nil || hosts != nil {
+		// the ca could only be configured by the client itself for a *http.Transport
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		if len(ca) > 0 {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, errors.New("no valid ca certificate found")
+			}
+			base.TLSClientConfig = &tls.Config{RootCAs: pool}
+		}
+		var rt http.RoundTripper = base
+		if hosts != nil {
+			// the requests rejected by the host limiter should not eject the host
+			rt = &healthTransport{base: rt, pool: hosts}
+		}
+		if wrapTransport != nil {
+			rt = wrapTransport(rt)
+		}
+		cfg.Transport = rt
+		cfg.CACert = nil
+	}
 	cli, err := es.NewClient(cfg)
 	if err != nil {
 		return nil, err
 	}
+	if hosts != nil && (config.DiscoverNodesOnStart || config.DiscoverNodesInterval > 0) {
+		d := &discoverer{
+			interval: config.LoadBalance.EjectDuration,
+			discover: cli.DiscoverNodes,
+		}
+		hosts.setOnEject(d.trigger)
+	}
+
+	opType := config.OpType
+	if config.DataStream {
+		// data streams only accept create
+		opType = opTypeCreate
+	}
+
+	var deadLetterPattern *pattern.Pattern
+	if config.DeadLetter.Enabled {
+		deadLetterPattern, err = pattern.Init(config.DeadLetter.Index)
+		if err != nil {
+			return nil, err
+		}
+		if config.TimeZone != "" {
+			loc, err := time.LoadLocation(config.TimeZone)
+			if err != nil {
+				return nil, err
+			}
+			deadLetterPattern.WithLocation(loc)
+		}
+	}
 
 	return &ClientSet{
 		config:              config,
 		cli:                 cli,
-		opType:              config.OpType,
+		opType:              opType,
 		reqCount:            0,
 		codec:               cod,
 		indexPattern:        indexPattern,
 		defaultIndexPattern: defaultIndexPattern,
 		documentIdPattern:   documentIdPattern,
+		deadLetterPattern:   deadLetterPattern,
+		bulkLimit:           int64(config.Bulk.limit()),
 	}, nil
 }
 
@@ -180,6 +261,9 @@ func (c *ClientSet) Bulk(ctx context.Context, batch api.Batch) error {
 
 		// select index
 		idx, err := c.indexPattern.WithObject(headerObj).RenderWithStrict()
+		if err == nil {
+			err = validateIndexName(idx)
+		}
 		if err != nil {
 			failedConfig := c.config.IfRenderIndexFailed
 			if !failedConfig.IgnoreError {
@@ -188,6 +272,9 @@ func (c *ClientSet) Bulk(ctx context.Context, batch api.Batch) error {
 
 			if failedConfig.DefaultIndex != "" { // if we had a default index, send events to this one
 				defaultIdx, defaultIdxErr := c.defaultIndexPattern.WithObject(headerObj).Render()
+				if defaultIdxErr == nil {
+					defaultIdxErr = validateIndexName(defaultIdx)
+				}
 				if defaultIdxErr != nil {
 					log.Error("render default index error: %v", defaultIdxErr)
 					continue
@@ -216,51 +303,66 @@ func (c *ClientSet) Bulk(ctx context.Context, batch api.Batch) error {
 		}
 
 		c.reqCount++
-		req.add(data, c.opType, docId, idx)
+		req.add(data, c.opType, docId, idx, headerObj)
 	}
 
 	if c.reqCount == 0 {
 		return errors.WithMessagef(eventer.ErrorDropEvent, "request to elasticsearch bulk is null")
 	}
 
-	resp, err := c.cli.Bulk(bytes.NewReader(req.body()),
-		c.cli.Bulk.WithDocumentType(c.config.Etype),
-		c.cli.Bulk.WithParameters(c.config.Params),
-		c.cli.Bulk.WithHeader(c.config.Headers))
-	if err != nil {
-		return errors.WithMessagef(err, "request to elasticsearch bulk failed")
-	}
-	if resp.Body != nil {
-		defer resp.Body.Close()
-	}
-
-	blkResp := BulkIndexerResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(&blkResp); err != nil {
-		out, _ := json.Marshal(resp.Body)
-		return errors.Errorf("elasticsearch response error: %s", out)
-	}
+	return c.flush(ctx, req.lines)
+}
 
-	if blkResp.HasErrors {
-		failed := blkResp.Failed()
-		failedCount := len(failed)
-		// to avoid too many error messages
-		if failedCount > 2 {
-			failed = blkResp.Failed()[:1]
+// flush sends the lines in bulk requests split by the payload bytes. The previous requests have been indexed when
+// a request fails, they may be duplicated since the batch would be retried by the pipeline, the same as the items
+// resent by the bulk retry.
+func (c *ClientSet) flush(ctx context.Context, lines []line) error {
+	requests := 0
+	for len(lines) > 0 {
+		limit := int(atomic.LoadInt64(&c.bulkLimit))
+		var chunk []line
+		chunk, lines = c.config.Bulk.split(lines, limit)
+		if len(chunk) == 0 {
+			continue
 		}
-		out, _ := json.Marshal(failed)
 
-		// if there are some events succeed, retry will cause these events to be sent repeatedly, so here we will not retry
-		if failedCount < len(blkResp.Items) {
-			log.Error("partial bulk to elasticsearch response error, will drop failed events, all(%d), failed(%d), reason: %s", len(blkResp.Items), len(blkResp.Failed()), out)
-			return nil
+		err := c.send(ctx, chunk)
+		if err == nil {
+			requests++
+			continue
+		}
+		if !errors.Is(err, errTooLarge) {
+			if requests > 0 {
+				return errors.WithMessagef(err, "%d bulk requests of the batch have been sent", requests)
+			}
+			return err
 		}
 
-		return errors.Errorf("all bulk to elasticsearch response error, all(%d), failed(%d), reason: %s", len(blkResp.Items), len(blkResp.Failed()), out)
+		if len(chunk) == 1 {
+			log.Warn("drop the event of %d bytes which is rejected by elasticsearch as too large", chunk[0].size())
+			continue
+		}
+		c.lowerBulkLimit(limit, chunkSize(chunk))
+		lines = append(chunk, lines...)
 	}
-
 	return nil
 }
 
+// lowerBulkLimit halves the bytes of a bulk request rejected with 413, when http.max_content_length of
+// elasticsearch is smaller than maxContentLength configured
+func (c *ClientSet) lowerBulkLimit(limit int, rejected int) {
+	lowered := rejected / 2
+	if lowered > limit {
+		lowered = limit / 2
+	}
+	if lowered < 1 {
+		lowered = 1
+	}
+	if atomic.CompareAndSwapInt64(&c.bulkLimit, int64(limit), int64(lowered)) {
+		log.Warn("bulk request of %d bytes is rejected by elasticsearch as too large, lower the bulk bytes from %d to %d", rejected, limit, lowered)
+	}
+}
+
 func (c *ClientSet) Stop() {
 	// Do nothing
 }
@@ -17,30 +17,50 @@ limitations under the License.
 package elasticsearch
 
 import (
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
 	"github.com/loggie-io/loggie/pkg/util/pattern"
 	"time"
 )
 
 type Config struct {
-	Hosts                 []string          `yaml:"hosts,omitempty" validate:"required"`
+	Hosts                 []string          `yaml:"hosts,omitempty"`
+	CloudID               string            `yaml:"cloudId,omitempty"` // endpoint of Elastic Cloud, instead of hosts
 	UserName              string            `yaml:"username,omitempty"`
 	Password              string            `yaml:"password,omitempty"`
+	PasswordFile          string            `yaml:"passwordFile,omitempty"`
 	Index                 string            `yaml:"index,omitempty"`
+	TimeZone              string            `yaml:"timezone,omitempty"` // time zone of the time vars in index, local by default
 	Headers               map[string]string `yaml:"headers,omitempty"`
 	Params                map[string]string `yaml:"parameters,omitempty"`
 	IfRenderIndexFailed   RenderIndexFail   `yaml:"ifRenderIndexFailed,omitempty"`
 	Etype                 string            `yaml:"etype,omitempty"` // elasticsearch type, for v5.* backward compatibility
 	DocumentId            string            `yaml:"documentId,omitempty"`
 	APIKey                string            `yaml:"apiKey,omitempty"`
+	APIKeyFile            string            `yaml:"apiKeyFile,omitempty"`
 	ServiceToken          string            `yaml:"serviceToken,omitempty"`
+	ServiceTokenFile      string            `yaml:"serviceTokenFile,omitempty"`
 	CACertPath            string            `yaml:"caCertPath,omitempty"`
 	Compress              bool              `yaml:"compress,omitempty"`
 	Gzip                  *bool             `yaml:"gzip,omitempty"` // deprecated, use compress above
 	OpType                string            `yaml:"opType,omitempty" default:"index"`
 	DiscoverNodesOnStart  bool              `yaml:"discoverNodesOnStart,omitempty"`
 	DiscoverNodesInterval time.Duration     `yaml:"discoverNodesInterval,omitempty"`
+	LoadBalance           LoadBalance       `yaml:"loadBalance,omitempty"`
+	HealthCheck           HealthCheck       `yaml:"healthCheck,omitempty"`
+	HostLimit             hostlimit.Config  `yaml:"hostLimit,omitempty"`
+	Retry                 BulkRetry         `yaml:"retry,omitempty"`
+	Bulk                  BulkSize          `yaml:"bulk,omitempty"`
+	DeadLetter            DeadLetter        `yaml:"deadLetter,omitempty"`
+	// DataStream writes to the data streams named by the index, documents are always created with opType create
+	DataStream    bool          `yaml:"dataStream,omitempty"`
+	IndexTemplate IndexTemplate `yaml:"indexTemplate,omitempty"`
+	ILM           ILM           `yaml:"ilm,omitempty"`
 }
 
+// RenderIndexFail handles the events whose index could not be rendered, such as the referenced field is missing,
+// or the rendered index name is invalid
 type RenderIndexFail struct {
 	DropEvent    bool   `yaml:"dropEvent,omitempty" default:"true"`
 	IgnoreError  bool   `yaml:"ignoreError,omitempty"`
@@ -48,10 +68,36 @@ type RenderIndexFail struct {
 }
 
 func (c *Config) Validate() error {
+	if len(c.Hosts) == 0 && c.CloudID == "" {
+		return errors.New("hosts or cloudId is required")
+	}
+	if len(c.Hosts) > 0 && c.CloudID != "" {
+		return errors.New("hosts and cloudId cannot be set at the same time")
+	}
+
+	if c.Password != "" && c.PasswordFile != "" {
+		return errors.New("password and passwordFile cannot be set at the same time")
+	}
+	if c.APIKey != "" && c.APIKeyFile != "" {
+		return errors.New("apiKey and apiKeyFile cannot be set at the same time")
+	}
+	if c.ServiceToken != "" && c.ServiceTokenFile != "" {
+		return errors.New("serviceToken and serviceTokenFile cannot be set at the same time")
+	}
+	if (c.APIKey != "" || c.APIKeyFile != "") && (c.ServiceToken != "" || c.ServiceTokenFile != "") {
+		return errors.New("apiKey and serviceToken cannot be set at the same time")
+	}
+
 	if err := pattern.Validate(c.Index); err != nil {
 		return err
 	}
 
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			return errors.WithMessagef(err, "invalid timezone %s", c.TimeZone)
+		}
+	}
+
 	if c.IfRenderIndexFailed.DefaultIndex != "" {
 		if err := pattern.Validate(c.IfRenderIndexFailed.DefaultIndex); err != nil {
 			return err
@@ -62,5 +108,44 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if c.DataStream && c.OpType != "" && c.OpType != opTypeIndex && c.OpType != opTypeCreate {
+		return errors.Errorf("opType %s is not supported by data streams", c.OpType)
+	}
+
+	if c.IndexTemplate.Enabled && len(c.IndexTemplate.IndexPatterns) == 0 && c.IndexTemplate.File == "" {
+		return errors.New("indexPatterns of indexTemplate is required")
+	}
+
+	if c.ILM.RolloverAlias != "" {
+		if c.DataStream {
+			return errors.New("rolloverAlias of ilm is not used by data streams")
+		}
+		if c.Index != c.ILM.RolloverAlias {
+			return errors.New("index should be the rolloverAlias of ilm")
+		}
+	}
+
+	if c.LoadBalance.Enabled {
+		if err := c.LoadBalance.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Bulk.MaxContentLength < 0 {
+		return errors.New("maxContentLength of bulk should not be negative")
+	}
+	if c.Bulk.MaxContentLength > 0 && c.Bulk.MaxBytes > c.Bulk.MaxContentLength {
+		return errors.New("maxBytes of bulk should not be greater than maxContentLength")
+	}
+
+	if c.DeadLetter.Enabled {
+		if c.DeadLetter.Index == "" {
+			return errors.New("index of deadLetter is required")
+		}
+		if err := pattern.Validate(c.DeadLetter.Index); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
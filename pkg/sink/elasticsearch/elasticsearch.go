@@ -19,6 +19,8 @@ package elasticsearch
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -28,6 +30,7 @@ import (
 	"github.com/loggie-io/loggie/pkg/core/result"
 	"github.com/loggie-io/loggie/pkg/pipeline"
 	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
 	"github.com/loggie-io/loggie/pkg/util/pattern"
 )
 
@@ -40,18 +43,25 @@ func init() {
 }
 
 func makeSink(info pipeline.Info) api.Component {
-	return NewSink()
+	return NewSink(info.PipelineName)
 }
 
 type Sink struct {
-	config *Config
-	cli    Client
-	codec  codec.Codec
+	pipelineName string
+	name         string
+	config       *Config
+	cli          Client
+	codec        codec.Codec
+	health       *healthChecker
+	limiter      *hostlimit.Transport
+	done         chan struct{}
 }
 
-func NewSink() *Sink {
+func NewSink(pipelineName string) *Sink {
 	return &Sink{
-		config: &Config{},
+		pipelineName: pipelineName,
+		config:       &Config{},
+		done:         make(chan struct{}),
 	}
 }
 
@@ -76,6 +86,7 @@ func (s *Sink) SetCodec(c codec.Codec) {
 }
 
 func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
 	return nil
 }
 
@@ -83,19 +94,47 @@ func (s *Sink) Start() error {
 	indexPattern, _ := pattern.Init(s.config.Index)
 	documentIdPattern, _ := pattern.Init(s.config.DocumentId)
 	defaultIndexPattern, _ := pattern.Init(s.config.IfRenderIndexFailed.DefaultIndex)
-	cli, err := NewClient(s.config, s.codec, indexPattern, documentIdPattern, defaultIndexPattern)
+	if s.config.TimeZone != "" {
+		loc, err := time.LoadLocation(s.config.TimeZone)
+		if err != nil {
+			return err
+		}
+		indexPattern.WithLocation(loc)
+		defaultIndexPattern.WithLocation(loc)
+	}
+	var wrapTransport func(http.RoundTripper) http.RoundTripper
+	if s.config.HostLimit.Enabled() {
+		wrapTransport = func(base http.RoundTripper) http.RoundTripper {
+			s.limiter = hostlimit.NewTransport(base, &s.config.HostLimit, s.pipelineName, s.name)
+			return s.limiter
+		}
+	}
+	cli, err := NewClient(s.config, s.codec, indexPattern, documentIdPattern, defaultIndexPattern, wrapTransport)
 	if err != nil {
 		log.Error("start elasticsearch connection fail, err: %v", err)
 		return err
 	}
+	if s.config.ILM.Enabled || s.config.IndexTemplate.Enabled {
+		if err := setup(cli.cli, s.config); err != nil {
+			log.Error("setup elasticsearch index template and ilm policy fail, err: %v", err)
+			return err
+		}
+	}
 	s.cli = cli
+	if s.config.HealthCheck.Enabled {
+		s.health = newHealthChecker(&s.config.HealthCheck, cli.cli)
+	}
 	return nil
 }
 
 func (s *Sink) Stop() {
+	close(s.done)
 	if s.cli != nil {
 		s.cli.Stop()
 	}
+	if s.limiter != nil {
+		s.limiter.Stop()
+	}
 }
 
 func (s *Sink) Consume(batch api.Batch) api.Result {
@@ -103,6 +142,12 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 		return result.Fail(clientNotInitError)
 	}
 
+	if s.health != nil {
+		if err := s.health.waitHealthy(s.done); err != nil {
+			return result.Fail(err)
+		}
+	}
+
 	err := s.cli.Bulk(context.Background(), batch)
 	if err != nil {
 		if errors.Is(err, eventer.ErrorDropEvent) {
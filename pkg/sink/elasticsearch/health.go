@@ -0,0 +1,261 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	es "github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+const (
+	healthRed = "red"
+
+	settingFloodStage          = "cluster.routing.allocation.disk.watermark.flood_stage"
+	settingThresholdEnabled    = "cluster.routing.allocation.disk.threshold_enabled"
+	settingReadOnly            = "cluster.blocks.read_only"
+	settingReadOnlyAllowDelete = "cluster.blocks.read_only_allow_delete"
+)
+
+type HealthCheck struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Interval is how long a health check result is cached, the cluster is not queried for every bulk request
+	Interval          time.Duration `yaml:"interval,omitempty" default:"30s"`
+	Timeout           time.Duration `yaml:"timeout,omitempty" default:"5s"`
+	PauseOnRed        *bool         `yaml:"pauseOnRed,omitempty" default:"true"`
+	PauseOnFloodStage *bool         `yaml:"pauseOnFloodStage,omitempty" default:"true"`
+	BackoffMin        time.Duration `yaml:"backoffMin,omitempty" default:"1s"`
+	BackoffMax        time.Duration `yaml:"backoffMax,omitempty" default:"1m"`
+}
+
+// healthChecker pauses the sink before flushing when the cluster is red or read-only, such as the disk usage
+// of a node exceeds the flood stage watermark, instead of sending bulk requests which would be rejected anyway.
+type healthChecker struct {
+	config *HealthCheck
+	cli    *es.Client
+
+	lock      sync.Mutex
+	checkedAt time.Time
+	reason    string
+}
+
+func newHealthChecker(config *HealthCheck, cli *es.Client) *healthChecker {
+	return &healthChecker{
+		config: config,
+		cli:    cli,
+	}
+}
+
+// waitHealthy blocks with backoff until the cluster becomes healthy or done is closed
+func (h *healthChecker) waitHealthy(done <-chan struct{}) error {
+	backoff := h.config.BackoffMin
+	for {
+		reason := h.unhealthyReason()
+		if reason == "" {
+			return nil
+		}
+
+		log.Debug("elasticsearch backend unhealthy: %s, retry after %s", reason, backoff)
+		select {
+		case <-done:
+			return errors.Errorf("elasticsearch backend unhealthy: %s", reason)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > h.config.BackoffMax {
+			backoff = h.config.BackoffMax
+		}
+	}
+}
+
+func (h *healthChecker) unhealthyReason() string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !h.checkedAt.IsZero() && time.Since(h.checkedAt) < h.config.Interval && h.reason == "" {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+	reason, err := h.check(ctx)
+	if err != nil {
+		// bulk requests would report the error if the cluster is unreachable
+		log.Warn("check elasticsearch health failed: %v", err)
+		reason = h.reason
+		if reason == "" {
+			return ""
+		}
+	}
+
+	if reason != "" && h.reason == "" {
+		// errors are sent to the alert channels if errorAlert is configured
+		log.Error("elasticsearch backend unhealthy: %s, pause sending until it recovers", reason)
+	} else if reason == "" && h.reason != "" {
+		log.Info("elasticsearch backend recovered from: %s", h.reason)
+	}
+	h.reason = reason
+	h.checkedAt = time.Now()
+	return reason
+}
+
+type allocation struct {
+	Node        string  `json:"node"`
+	DiskPercent *string `json:"disk.percent"`
+	DiskAvail   *string `json:"disk.avail"`
+}
+
+func (h *healthChecker) check(ctx context.Context) (string, error) {
+	if h.config.PauseOnRed == nil || *h.config.PauseOnRed {
+		health := struct {
+			Status string `json:"status"`
+		}{}
+		resp, err := h.cli.Cluster.Health(h.cli.Cluster.Health.WithContext(ctx))
+		if err := decodeResponse(resp, err, &health); err != nil {
+			return "", errors.WithMessage(err, "get cluster health")
+		}
+		if health.Status == healthRed {
+			return "cluster health is red", nil
+		}
+	}
+
+	if h.config.PauseOnFloodStage == nil || *h.config.PauseOnFloodStage {
+		settings := map[string]map[string]interface{}{}
+		resp, err := h.cli.Cluster.GetSettings(h.cli.Cluster.GetSettings.WithContext(ctx),
+			h.cli.Cluster.GetSettings.WithIncludeDefaults(true), h.cli.Cluster.GetSettings.WithFlatSettings(true))
+		if err := decodeResponse(resp, err, &settings); err != nil {
+			return "", errors.WithMessage(err, "get cluster settings")
+		}
+		var allocations []allocation
+		resp, err = h.cli.Cat.Allocation(h.cli.Cat.Allocation.WithContext(ctx),
+			h.cli.Cat.Allocation.WithFormat("json"), h.cli.Cat.Allocation.WithBytes("b"))
+		if err := decodeResponse(resp, err, &allocations); err != nil {
+			return "", errors.WithMessage(err, "cat allocation")
+		}
+		return readOnlyReason(settings, allocations), nil
+	}
+	return "", nil
+}
+
+func decodeResponse(resp *esapi.Response, err error, out interface{}) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("status %d: %s", resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// readOnlyReason checks the cluster blocks and compares the disk usage of each node with the flood stage watermark
+func readOnlyReason(settings map[string]map[string]interface{}, allocations []allocation) string {
+	if isTrue(setting(settings, settingReadOnly)) {
+		return "cluster is read-only"
+	}
+	if isTrue(setting(settings, settingReadOnlyAllowDelete)) {
+		return "cluster is read-only-allow-delete"
+	}
+	if isFalse(setting(settings, settingThresholdEnabled)) {
+		return ""
+	}
+
+	floodStage := setting(settings, settingFloodStage)
+	if floodStage == "" {
+		return ""
+	}
+	percent, bytes, err := parseWatermark(floodStage)
+	if err != nil {
+		log.Warn("parse flood stage watermark %s failed: %v", floodStage, err)
+		return ""
+	}
+
+	for _, a := range allocations {
+		if percent > 0 && a.DiskPercent != nil {
+			used, err := strconv.ParseFloat(*a.DiskPercent, 64)
+			if err == nil && used >= percent {
+				return fmt.Sprintf("disk usage %s%% of node %s exceeds the flood stage watermark %s", *a.DiskPercent, a.Node, floodStage)
+			}
+		}
+		if bytes > 0 && a.DiskAvail != nil {
+			avail, err := strconv.ParseInt(*a.DiskAvail, 10, 64)
+			if err == nil && avail <= bytes {
+				return fmt.Sprintf("disk available %s bytes of node %s is below the flood stage watermark %s", *a.DiskAvail, a.Node, floodStage)
+			}
+		}
+	}
+	return ""
+}
+
+// setting returns the value of a flat setting, transient settings override persistent ones and then the defaults
+func setting(settings map[string]map[string]interface{}, key string) string {
+	for _, scope := range []string{"transient", "persistent", "defaults"} {
+		if v, ok := settings[scope][key]; ok {
+			return fmt.Sprintf("%v", v)
+		}
+	}
+	return ""
+}
+
+func isTrue(v string) bool {
+	return strings.EqualFold(v, "true")
+}
+
+func isFalse(v string) bool {
+	return strings.EqualFold(v, "false")
+}
+
+// parseWatermark parses a watermark which could be a percentage such as 95%, a ratio such as 0.95,
+// or an absolute value of the free disk space such as 10gb
+func parseWatermark(w string) (percent float64, bytes int64, err error) {
+	w = strings.ToLower(strings.TrimSpace(w))
+	if strings.HasSuffix(w, "%") {
+		percent, err = strconv.ParseFloat(strings.TrimSuffix(w, "%"), 64)
+		return percent, 0, err
+	}
+	if ratio, err := strconv.ParseFloat(w, 64); err == nil {
+		return ratio * 100, 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"pb", 1 << 50}, {"tb", 1 << 40}, {"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10}, {"b", 1},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(w, u.suffix) {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(w, u.suffix), 64)
+			if err != nil {
+				return 0, 0, err
+			}
+			return 0, int64(v * float64(u.size)), nil
+		}
+	}
+	return 0, 0, errors.Errorf("unknown watermark %s", w)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWatermark(t *testing.T) {
+	percent, bytes, err := parseWatermark("95%")
+	assert.NoError(t, err)
+	assert.Equal(t, 95.0, percent)
+	assert.Equal(t, int64(0), bytes)
+
+	percent, _, err = parseWatermark("0.9")
+	assert.NoError(t, err)
+	assert.InDelta(t, 90.0, percent, 0.001)
+
+	_, bytes, err = parseWatermark("10gb")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10<<30), bytes)
+
+	_, _, err = parseWatermark("lots")
+	assert.Error(t, err)
+}
+
+func TestReadOnlyReason(t *testing.T) {
+	str := func(s string) *string { return &s }
+	defaults := map[string]map[string]interface{}{
+		"defaults": {settingFloodStage: "95%"},
+	}
+
+	assert.Equal(t, "", readOnlyReason(defaults, []allocation{
+		{Node: "n1", DiskPercent: str("80")},
+		{Node: "UNASSIGNED"},
+	}))
+	assert.Equal(t, "disk usage 96% of node n2 exceeds the flood stage watermark 95%", readOnlyReason(defaults, []allocation{
+		{Node: "n1", DiskPercent: str("80")},
+		{Node: "n2", DiskPercent: str("96")},
+	}))
+
+	absolute := map[string]map[string]interface{}{
+		"persistent": {settingFloodStage: "1gb"},
+		"defaults":   {settingFloodStage: "95%"},
+	}
+	assert.Equal(t, "disk available 1024 bytes of node n1 is below the flood stage watermark 1gb", readOnlyReason(absolute, []allocation{
+		{Node: "n1", DiskPercent: str("99"), DiskAvail: str("1024")},
+	}))
+
+	disabled := map[string]map[string]interface{}{
+		"defaults": {settingFloodStage: "95%", settingThresholdEnabled: "false"},
+	}
+	assert.Equal(t, "", readOnlyReason(disabled, []allocation{{Node: "n1", DiskPercent: str("99")}}))
+
+	blocked := map[string]map[string]interface{}{
+		"transient": {settingReadOnlyAllowDelete: "true"},
+	}
+	assert.Equal(t, "cluster is read-only-allow-delete", readOnlyReason(blocked, nil))
+}
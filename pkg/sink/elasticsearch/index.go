@@ -0,0 +1,56 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxIndexNameBytes = 255
+	invalidIndexChars = `\/*?"<>| ,#:`
+)
+
+// validateIndexName checks the index name with the restrictions of elasticsearch, so the events with an invalid index
+// are handled by ifRenderIndexFailed instead of failing the whole bulk request
+func validateIndexName(index string) error {
+	if index == "" {
+		return errors.New("index name is empty")
+	}
+	// date math names such as <logs-{now/d}> are resolved by elasticsearch
+	if strings.HasPrefix(index, "<") && strings.HasSuffix(index, ">") {
+		return nil
+	}
+	if len(index) > maxIndexNameBytes {
+		return errors.Errorf("index name %s is longer than %d bytes", index, maxIndexNameBytes)
+	}
+	if index == "." || index == ".." {
+		return errors.Errorf("index name cannot be %s", index)
+	}
+	if strings.ContainsAny(index[:1], "-_+") {
+		return errors.Errorf("index name %s cannot start with -, _ or +", index)
+	}
+	if strings.ContainsAny(index, invalidIndexChars) {
+		return errors.Errorf("index name %s cannot contain any of %s", index, invalidIndexChars)
+	}
+	if strings.ToLower(index) != index {
+		return errors.Errorf("index name %s must be lowercase", index)
+	}
+	return nil
+}
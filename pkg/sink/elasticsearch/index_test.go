@@ -0,0 +1,32 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateIndexName(t *testing.T) {
+	for _, index := range []string{"logs-web-2023.01.01", "<logs-{now/d}>", ".ds-logs"} {
+		assert.NoError(t, validateIndexName(index), index)
+	}
+	for _, index := range []string{"", "logs-Web", "-logs", "_logs", "logs web", "logs/web", "..", "logs#1"} {
+		assert.Error(t, validateIndexName(index), index)
+	}
+}
@@ -0,0 +1,306 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v7/estransport"
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+const (
+	StrategyRoundRobin   = "roundRobin"
+	StrategyLeastPending = "leastPending"
+)
+
+// LoadBalance selects the host of each request among the configured hosts and the nodes discovered by
+// discoverNodesOnStart or discoverNodesInterval, and ejects the failing hosts temporarily
+type LoadBalance struct {
+	Enabled  bool   `yaml:"enabled,omitempty"`
+	Strategy string `yaml:"strategy,omitempty" default:"roundRobin" validate:"oneof=roundRobin leastPending"`
+	// NodeRoles keeps the discovered nodes which have any of the roles, such as data or ingest
+	NodeRoles []string `yaml:"nodeRoles,omitempty"`
+	// FailureThreshold is the number of consecutive failures to eject a host
+	FailureThreshold int           `yaml:"failureThreshold,omitempty" default:"3" validate:"gte=1"`
+	FailOnStatus     []int         `yaml:"failOnStatus,omitempty" default:"[502,503,504]"`
+	EjectDuration    time.Duration `yaml:"ejectDuration,omitempty" default:"30s"`
+	// EjectDurationMax caps the eject duration, which is doubled each time the host is ejected again
+	EjectDurationMax time.Duration `yaml:"ejectDurationMax,omitempty" default:"5m"`
+}
+
+func (l *LoadBalance) Validate() error {
+	if l.EjectDurationMax < l.EjectDuration {
+		return errors.New("ejectDurationMax of loadBalance should not be less than ejectDuration")
+	}
+	return nil
+}
+
+type hostState struct {
+	conn     *estransport.Connection
+	pending  int
+	failures int
+	// ejections is the number of consecutive ejections, used to double the eject duration
+	ejections    int
+	ejectedUntil time.Time
+}
+
+// hostPool implements estransport.ConnectionPool. The health of hosts is reported by the healthTransport,
+// because the client only reports network errors to the pool, but not the responses such as 503.
+type hostPool struct {
+	config *LoadBalance
+
+	lock  sync.Mutex
+	hosts []*hostState
+	next  int
+	now   func() time.Time
+	// onEject is called without the lock when a host is ejected, such as discovering nodes again
+	onEject func()
+}
+
+func newHostPool(config *LoadBalance) *hostPool {
+	return &hostPool{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+func (p *hostPool) setOnEject(f func()) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.onEject = f
+}
+
+// update replaces the hosts with the discovered connections, the states of the remaining hosts are kept
+func (p *hostPool) update(conns []*estransport.Connection) estransport.ConnectionPool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	existing := make(map[string]*hostState, len(p.hosts))
+	for _, h := range p.hosts {
+		existing[h.conn.URL.Host] = h
+	}
+
+	var hosts []*hostState
+	for _, c := range conns {
+		if !p.matchRoles(c) {
+			continue
+		}
+		if h, ok := existing[c.URL.Host]; ok {
+			h.conn = c
+			hosts = append(hosts, h)
+			continue
+		}
+		hosts = append(hosts, &hostState{conn: c})
+	}
+	if len(hosts) == 0 {
+		if len(p.hosts) > 0 {
+			log.Warn("no elasticsearch node matches roles %v, keep the previous hosts", p.config.NodeRoles)
+			return p
+		}
+		// use the configured hosts at least
+		for _, c := range conns {
+			hosts = append(hosts, &hostState{conn: c})
+		}
+	}
+	p.hosts = hosts
+	p.next = 0
+	return p
+}
+
+// matchRoles checks the roles of the discovered nodes, the configured hosts have no roles and are always kept
+func (p *hostPool) matchRoles(c *estransport.Connection) bool {
+	if len(p.config.NodeRoles) == 0 || len(c.Roles) == 0 {
+		return true
+	}
+	for _, r := range c.Roles {
+		for _, want := range p.config.NodeRoles {
+			if r == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *hostPool) Next() (*estransport.Connection, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.hosts) == 0 {
+		return nil, errors.New("no elasticsearch host available")
+	}
+
+	now := p.now()
+	selected := -1
+	for i := 0; i < len(p.hosts); i++ {
+		idx := (p.next + i) % len(p.hosts)
+		h := p.hosts[idx]
+		if h.ejectedUntil.After(now) {
+			continue
+		}
+		if selected < 0 {
+			selected = idx
+			if p.config.Strategy != StrategyLeastPending {
+				break
+			}
+			continue
+		}
+		if h.pending < p.hosts[selected].pending {
+			selected = idx
+		}
+	}
+	if selected < 0 {
+		// all the hosts are ejected, try the one which would come back first
+		for idx, h := range p.hosts {
+			if selected < 0 || h.ejectedUntil.Before(p.hosts[selected].ejectedUntil) {
+				selected = idx
+			}
+		}
+	}
+	p.next = (selected + 1) % len(p.hosts)
+	p.hosts[selected].pending++
+	return p.hosts[selected].conn, nil
+}
+
+// OnSuccess and OnFailure are called by the client after each round trip
+func (p *hostPool) OnSuccess(c *estransport.Connection) error {
+	p.done(c)
+	return nil
+}
+
+func (p *hostPool) OnFailure(c *estransport.Connection) error {
+	p.done(c)
+	return nil
+}
+
+func (p *hostPool) done(c *estransport.Connection) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if h := p.find(c.URL.Host); h != nil && h.pending > 0 {
+		h.pending--
+	}
+}
+
+func (p *hostPool) URLs() []*url.URL {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	urls := make([]*url.URL, 0, len(p.hosts))
+	for _, h := range p.hosts {
+		urls = append(urls, h.conn.URL)
+	}
+	return urls
+}
+
+func (p *hostPool) find(host string) *hostState {
+	for _, h := range p.hosts {
+		if h.conn.URL.Host == host {
+			return h
+		}
+	}
+	return nil
+}
+
+// report records the result of a request to the host
+func (p *hostPool) report(host string, ok bool) {
+	p.lock.Lock()
+	h := p.find(host)
+	if h == nil {
+		p.lock.Unlock()
+		return
+	}
+	if ok {
+		if h.ejections > 0 {
+			log.Info("elasticsearch host %s recovered", host)
+		}
+		h.failures = 0
+		h.ejections = 0
+		p.lock.Unlock()
+		return
+	}
+
+	h.failures++
+	if h.failures < p.config.FailureThreshold || h.ejectedUntil.After(p.now()) {
+		p.lock.Unlock()
+		return
+	}
+	duration := p.config.EjectDuration << uint(h.ejections)
+	if duration > p.config.EjectDurationMax || duration <= 0 {
+		duration = p.config.EjectDurationMax
+	}
+	h.ejections++
+	// a failure after coming back ejects the host again
+	h.failures = p.config.FailureThreshold - 1
+	h.ejectedUntil = p.now().Add(duration)
+	onEject := p.onEject
+	p.lock.Unlock()
+
+	log.Warn("elasticsearch host %s is ejected for %s", host, duration)
+	if onEject != nil {
+		onEject()
+	}
+}
+
+func (p *hostPool) failOnStatus(status int) bool {
+	for _, s := range p.config.FailOnStatus {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// healthTransport reports the result of each request to the pool passively
+type healthTransport struct {
+	base http.RoundTripper
+	pool *hostPool
+}
+
+func (t *healthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	t.pool.report(req.URL.Host, err == nil && !t.pool.failOnStatus(resp.StatusCode))
+	return resp, err
+}
+
+// discoverer discovers nodes again when a host is ejected, at most once in the interval
+type discoverer struct {
+	lock     sync.Mutex
+	interval time.Duration
+	last     time.Time
+	discover func() error
+}
+
+func (d *discoverer) trigger() {
+	d.lock.Lock()
+	if time.Since(d.last) < d.interval {
+		d.lock.Unlock()
+		return
+	}
+	d.last = time.Now()
+	d.lock.Unlock()
+
+	go func() {
+		if err := d.discover(); err != nil {
+			log.Warn("discover elasticsearch nodes failed: %v", err)
+		}
+	}()
+}
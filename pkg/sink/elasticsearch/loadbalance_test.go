@@ -0,0 +1,195 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v7/estransport"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func testConns(hosts ...string) []*estransport.Connection {
+	var conns []*estransport.Connection
+	for _, h := range hosts {
+		conns = append(conns, &estransport.Connection{URL: &url.URL{Scheme: "http", Host: h}})
+	}
+	return conns
+}
+
+func testPool(strategy string) (*hostPool, *time.Time) {
+	now := time.Unix(0, 0)
+	p := newHostPool(&LoadBalance{
+		Strategy:         strategy,
+		FailureThreshold: 2,
+		FailOnStatus:     []int{503},
+		EjectDuration:    time.Second,
+		EjectDurationMax: 3 * time.Second,
+	})
+	p.now = func() time.Time { return now }
+	p.update(testConns("a", "b", "c"))
+	return p, &now
+}
+
+func nextHost(t *testing.T, p *hostPool) string {
+	c, err := p.Next()
+	assert.NoError(t, err)
+	p.OnSuccess(c)
+	return c.URL.Host
+}
+
+func TestHostPoolRoundRobin(t *testing.T) {
+	log.InitDefaultLogger()
+	p, now := testPool(StrategyRoundRobin)
+
+	assert.Equal(t, []string{"a", "b", "c", "a"}, []string{nextHost(t, p), nextHost(t, p), nextHost(t, p), nextHost(t, p)})
+
+	// ejected after the consecutive failures reach the threshold
+	p.report("b", false)
+	p.report("b", true)
+	p.report("b", false)
+	assert.Equal(t, "b", nextHost(t, p))
+	p.report("b", false)
+	assert.Equal(t, []string{"c", "a", "c"}, []string{nextHost(t, p), nextHost(t, p), nextHost(t, p)})
+
+	// comes back after the eject duration, and is ejected again with twice the duration for one more failure
+	*now = now.Add(time.Second)
+	assert.Equal(t, "a", nextHost(t, p))
+	assert.Equal(t, "b", nextHost(t, p))
+	p.report("b", false)
+	*now = now.Add(time.Second)
+	assert.Equal(t, []string{"c", "a", "c"}, []string{nextHost(t, p), nextHost(t, p), nextHost(t, p)})
+	*now = now.Add(time.Second)
+	assert.Equal(t, "a", nextHost(t, p))
+	assert.Equal(t, "b", nextHost(t, p))
+
+	// recovered after a success
+	p.report("b", true)
+	p.report("b", false)
+	assert.Equal(t, "c", nextHost(t, p))
+	assert.Equal(t, "a", nextHost(t, p))
+	assert.Equal(t, "b", nextHost(t, p))
+}
+
+func TestHostPoolAllEjected(t *testing.T) {
+	log.InitDefaultLogger()
+	p, now := testPool(StrategyRoundRobin)
+
+	for _, h := range []string{"c", "a", "b"} {
+		p.report(h, false)
+		p.report(h, false)
+		*now = now.Add(time.Millisecond)
+	}
+	// the host which would come back first is used
+	assert.Equal(t, "c", nextHost(t, p))
+}
+
+func TestHostPoolLeastPending(t *testing.T) {
+	p, _ := testPool(StrategyLeastPending)
+
+	a, _ := p.Next()
+	b, _ := p.Next()
+	assert.Equal(t, "a", a.URL.Host)
+	assert.Equal(t, "b", b.URL.Host)
+	p.OnSuccess(a)
+
+	// b is still pending
+	c, _ := p.Next()
+	assert.Equal(t, "c", c.URL.Host)
+	assert.Equal(t, "a", nextHost(t, p))
+}
+
+func TestHostPoolUpdate(t *testing.T) {
+	log.InitDefaultLogger()
+	p, _ := testPool(StrategyRoundRobin)
+	p.config.NodeRoles = []string{"data"}
+	p.report("a", false)
+	p.report("a", false)
+
+	conns := testConns("a", "b", "d")
+	conns[0].Roles = []string{"data", "ingest"}
+	conns[1].Roles = []string{"master"}
+	conns[2].Roles = []string{"data"}
+	p.update(conns)
+
+	// b is not a data node, and a is still ejected
+	assert.Equal(t, []string{"d", "d"}, []string{nextHost(t, p), nextHost(t, p)})
+
+	// no node matches
+	conns = testConns("e")
+	conns[0].Roles = []string{"master"}
+	p.update(conns)
+	assert.Equal(t, 2, len(p.URLs()))
+}
+
+func TestLoadBalanceEjectsFailingHost(t *testing.T) {
+	log.InitDefaultLogger()
+
+	handle := func(status int, count *int32) http.Handler {
+		healthy := &fakeBulk{statuses: func(n int, docs []string) []int {
+			statuses := make([]int, len(docs))
+			for i := range statuses {
+				statuses[i] = http.StatusCreated
+			}
+			return statuses
+		}}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/_bulk") {
+				atomic.AddInt32(count, 1)
+				if status != http.StatusOK {
+					w.WriteHeader(status)
+					return
+				}
+			}
+			healthy.ServeHTTP(w, r)
+		})
+	}
+	var good, bad int32
+	goodSrv := httptest.NewServer(handle(http.StatusOK, &good))
+	defer goodSrv.Close()
+	badSrv := httptest.NewServer(handle(http.StatusServiceUnavailable, &bad))
+	defer badSrv.Close()
+
+	config := &Config{
+		Hosts: []string{goodSrv.URL, badSrv.URL},
+		LoadBalance: LoadBalance{
+			Enabled:          true,
+			Strategy:         StrategyRoundRobin,
+			FailureThreshold: 1,
+			FailOnStatus:     []int{503},
+			EjectDuration:    time.Minute,
+			EjectDurationMax: time.Minute,
+		},
+	}
+	cli, err := NewClient(config, nil, nil, nil, nil, nil)
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		cli.send(context.Background(), testLines(`{"a":1}`))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&bad))
+	assert.Equal(t, int32(9), atomic.LoadInt32(&good))
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+// BulkRetry retries the items rejected with a retryable status in a bulk response, such as 429 when the write
+// thread pool queue of a node is full. Only the rejected items are resent, so the indexed ones are not duplicated.
+type BulkRetry struct {
+	MaxRetries    int           `yaml:"maxRetries,omitempty" default:"3" validate:"gte=0"`
+	BackoffMin    time.Duration `yaml:"backoffMin,omitempty" default:"500ms"`
+	BackoffMax    time.Duration `yaml:"backoffMax,omitempty" default:"10s"`
+	RetryOnStatus []int         `yaml:"retryOnStatus,omitempty" default:"[429,502,503,504]"`
+}
+
+// DeadLetter indexes the permanently rejected documents, such as mapping conflicts, into another index with
+// the rejection reason attached instead of dropping them. The original document is kept as a string in
+// the `document` field, so it would not be rejected by the same mapping again.
+type DeadLetter struct {
+	Enabled bool   `yaml:"enabled,omitempty"`
+	Index   string `yaml:"index,omitempty" default:"loggie-dead-letter-${+YYYY.MM.DD}"`
+}
+
+type deadLetterDocument struct {
+	Timestamp string          `json:"@timestamp"`
+	Index     string          `json:"index"`
+	Status    int             `json:"status"`
+	Error     deadLetterError `json:"error"`
+	Document  string          `json:"document"`
+}
+
+type deadLetterError struct {
+	Type   string `json:"type,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type rejectedItem struct {
+	line   line
+	index  string
+	status int
+	err    deadLetterError
+}
+
+func newRejectedItem(l line, item *BulkIndexerResponseItem) rejectedItem {
+	reason := item.Error.Reason
+	if item.Error.Cause.Reason != "" {
+		reason = fmt.Sprintf("%s, caused by: %s", reason, item.Error.Cause.Reason)
+	}
+	return rejectedItem{
+		line:   l,
+		index:  item.Index,
+		status: item.Status,
+		err: deadLetterError{
+			Type:   item.Error.Type,
+			Reason: reason,
+		},
+	}
+}
+
+// itemResult returns the result of a bulk response item, which is keyed by the action
+func itemResult(item map[string]*BulkIndexerResponseItem) *BulkIndexerResponseItem {
+	for _, result := range item {
+		return result
+	}
+	return nil
+}
+
+func (c *ClientSet) retryable(status int) bool {
+	for _, s := range c.config.Retry.RetryOnStatus {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// send bulks the lines, the items rejected with a retryable status are resent with backoff, and the others
+// are sent to the dead letter index if enabled
+func (c *ClientSet) send(ctx context.Context, lines []line) error {
+	retry := &c.config.Retry
+	all := len(lines)
+	succeeded := 0
+	var rejected []rejectedItem
+	// the items still rejected with a retryable status after the retries
+	exhausted := 0
+
+	backoff := retry.BackoffMin
+	for attempt := 0; len(lines) > 0; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				log.Warn("bulk to elasticsearch is canceled, will drop %d rejected events", len(lines))
+				lines = nil
+				continue
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > retry.BackoffMax {
+				backoff = retry.BackoffMax
+			}
+		}
+
+		blkResp, err := c.bulk(lines)
+		if err != nil {
+			if attempt == 0 {
+				return err
+			}
+			// the previous items may have been indexed, resending the whole batch would duplicate them
+			log.Error("retry bulk to elasticsearch failed, will drop %d rejected events: %v", len(lines), err)
+			break
+		}
+		if !blkResp.HasErrors {
+			succeeded += len(lines)
+			break
+		}
+		if len(blkResp.Items) != len(lines) {
+			return errors.Errorf("elasticsearch bulk response contains %d items, but %d were requested", len(blkResp.Items), len(lines))
+		}
+
+		var retryLines []line
+		exhausted = 0
+		for i, item := range blkResp.Items {
+			result := itemResult(item)
+			if result == nil {
+				continue
+			}
+			switch {
+			case result.Status >= 200 && result.Status <= 299:
+				succeeded++
+			case c.retryable(result.Status) && attempt < retry.MaxRetries:
+				retryLines = append(retryLines, lines[i])
+			default:
+				if c.retryable(result.Status) {
+					exhausted++
+				}
+				rejected = append(rejected, newRejectedItem(lines[i], result))
+			}
+		}
+		if len(retryLines) > 0 {
+			log.Warn("%d events are rejected by elasticsearch with retryable status, retry after %s", len(retryLines), backoff)
+		}
+		lines = retryLines
+	}
+
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	out := rejectedReason(rejected)
+	// nothing is indexed, so the whole batch could be retried by the pipeline without duplicates.
+	// This keeps the sink backing off when the cluster is overloaded.
+	if succeeded == 0 && (!c.config.DeadLetter.Enabled || exhausted == len(rejected)) {
+		return errors.Errorf("all bulk to elasticsearch response error, all(%d), failed(%d), reason: %s", all, len(rejected), out)
+	}
+
+	if !c.config.DeadLetter.Enabled {
+		log.Error("partial bulk to elasticsearch response error, will drop failed events, all(%d), failed(%d), reason: %s", all, len(rejected), out)
+		return nil
+	}
+
+	if err := c.sendDeadLetter(rejected); err != nil {
+		if succeeded == 0 {
+			return errors.WithMessage(err, "send rejected events to dead letter index")
+		}
+		log.Error("send rejected events to dead letter index failed, will drop %d events: %v, reason: %s", len(rejected), err, out)
+		return nil
+	}
+	log.Warn("%d events rejected by elasticsearch are sent to dead letter index, reason: %s", len(rejected), out)
+	return nil
+}
+
+// rejectedReason returns the reason of the first rejected item, to avoid too many error messages
+func rejectedReason(rejected []rejectedItem) string {
+	r := rejected[0]
+	return fmt.Sprintf("index: %s, status: %d, type: %s, reason: %s", r.index, r.status, r.err.Type, r.err.Reason)
+}
+
+func (c *ClientSet) sendDeadLetter(rejected []rejectedItem) error {
+	now := time.Now()
+	req := bulkRequest{}
+	for _, r := range rejected {
+		idx, err := c.deadLetterPattern.WithObject(r.line.object).Render()
+		if err != nil {
+			return errors.WithMessage(err, "render dead letter index")
+		}
+
+		doc := deadLetterDocument{
+			Timestamp: now.Format(time.RFC3339Nano),
+			Index:     r.index,
+			Status:    r.status,
+			Error:     r.err,
+			Document:  string(r.line.body),
+		}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		// documents in the dead letter index are always created, the documentId of the original one is not kept
+		req.add(data, opTypeCreate, "", idx, r.line.object)
+	}
+
+	blkResp, err := c.bulk(req.lines)
+	if err != nil {
+		return err
+	}
+	if blkResp.HasErrors {
+		failed := blkResp.Failed()
+		if len(failed) > 0 {
+			f := failed[0]
+			return errors.Errorf("%d documents are rejected by dead letter index %s, status: %d, reason: %s", len(failed), f.Index, f.Status, f.Error.Reason)
+		}
+	}
+	return nil
+}
+
+func (c *ClientSet) bulk(lines []line) (*BulkIndexerResponse, error) {
+	req := bulkRequest{lines: lines}
+	resp, err := c.cli.Bulk(bytes.NewReader(req.body()),
+		c.cli.Bulk.WithDocumentType(c.config.Etype),
+		c.cli.Bulk.WithParameters(c.config.Params),
+		c.cli.Bulk.WithHeader(c.config.Headers))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "request to elasticsearch bulk failed")
+	}
+	if resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		return nil, errTooLarge
+	}
+
+	blkResp := &BulkIndexerResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(blkResp); err != nil {
+		out, _ := json.Marshal(resp.Body)
+		return nil, errors.Errorf("elasticsearch response error: %s", out)
+	}
+	return blkResp, nil
+}
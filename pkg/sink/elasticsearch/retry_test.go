@@ -0,0 +1,187 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+type fakeBulk struct {
+	requests [][]string
+	// statuses returns the status of each document in the nth request
+	statuses func(n int, docs []string) []int
+	// maxContentLength rejects the larger requests with 413 if it is positive
+	maxContentLength int
+}
+
+func (f *fakeBulk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+		// product check of the client
+		fmt.Fprint(w, `{"version":{"number":"7.17.0"},"tagline":"You Know, for Search"}`)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if f.maxContentLength > 0 && len(body) > f.maxContentLength {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	var docs []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for i := 0; scanner.Scan(); i++ {
+		if i%2 == 1 {
+			docs = append(docs, scanner.Text())
+		}
+	}
+	statuses := f.statuses(len(f.requests), docs)
+	f.requests = append(f.requests, docs)
+
+	var items []string
+	hasErrors := false
+	for _, s := range statuses {
+		if s/100 == 2 {
+			items = append(items, fmt.Sprintf(`{"index":{"_index":"test","status":%d}}`, s))
+			continue
+		}
+		hasErrors = true
+		items = append(items, fmt.Sprintf(`{"index":{"_index":"test","status":%d,"error":{"type":"err_%d","reason":"rejected"}}}`, s, s))
+	}
+	fmt.Fprintf(w, `{"took":1,"errors":%t,"items":[%s]}`, hasErrors, strings.Join(items, ","))
+}
+
+func newTestClient(t *testing.T, url string, deadLetter bool) *ClientSet {
+	config := &Config{
+		Hosts: []string{url},
+		Retry: BulkRetry{
+			MaxRetries:    2,
+			BackoffMin:    time.Millisecond,
+			BackoffMax:    time.Millisecond,
+			RetryOnStatus: []int{429},
+		},
+		DeadLetter: DeadLetter{
+			Enabled: deadLetter,
+			Index:   "dead-letter",
+		},
+	}
+	cli, err := NewClient(config, nil, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	return cli
+}
+
+func testLines(docs ...string) []line {
+	req := bulkRequest{}
+	for _, d := range docs {
+		req.add([]byte(d), "index", "", "test", runtime.NewObject(map[string]interface{}{}))
+	}
+	return req.lines
+}
+
+func TestSendRetryRejectedItems(t *testing.T) {
+	log.InitDefaultLogger()
+
+	fake := &fakeBulk{statuses: func(n int, docs []string) []int {
+		var statuses []int
+		for _, d := range docs {
+			switch {
+			case d == `{"a":"mapping"}` && n == 0:
+				statuses = append(statuses, 400)
+			case d == `{"a":"busy"}` && n < 2:
+				statuses = append(statuses, 429)
+			default:
+				statuses = append(statuses, 201)
+			}
+		}
+		return statuses
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cli := newTestClient(t, server.URL, true)
+	err := cli.send(context.Background(), testLines(`{"a":"ok"}`, `{"a":"busy"}`, `{"a":"mapping"}`))
+	assert.NoError(t, err)
+
+	// the first bulk, two retries of the busy document, and the dead letter one
+	assert.Equal(t, 4, len(fake.requests))
+	assert.Equal(t, []string{`{"a":"busy"}`}, fake.requests[1])
+	assert.Equal(t, []string{`{"a":"busy"}`}, fake.requests[2])
+	assert.Equal(t, 1, len(fake.requests[3]))
+	assert.Contains(t, fake.requests[3][0], `"status":400`)
+	assert.Contains(t, fake.requests[3][0], `"type":"err_400"`)
+	assert.Contains(t, fake.requests[3][0], `"document":"{\"a\":\"mapping\"}"`)
+}
+
+func TestSendAllRejected(t *testing.T) {
+	log.InitDefaultLogger()
+
+	fake := &fakeBulk{statuses: func(n int, docs []string) []int {
+		statuses := make([]int, len(docs))
+		for i := range statuses {
+			statuses[i] = 429
+		}
+		return statuses
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	// nothing is indexed, so the batch is left to be retried by the pipeline instead of the dead letter index
+	cli := newTestClient(t, server.URL, true)
+	err := cli.send(context.Background(), testLines(`{"a":"1"}`, `{"a":"2"}`))
+	assert.Error(t, err)
+	assert.Equal(t, 3, len(fake.requests))
+}
+
+func TestSendPartialRejectedWithoutDeadLetter(t *testing.T) {
+	log.InitDefaultLogger()
+
+	fake := &fakeBulk{statuses: func(n int, docs []string) []int {
+		return []int{201, 400}
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cli := newTestClient(t, server.URL, false)
+	err := cli.send(context.Background(), testLines(`{"a":"1"}`, `{"a":"2"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(fake.requests))
+}
+
+func TestDeadLetterIndex(t *testing.T) {
+	p, err := pattern.Init("dead-${fields.topic}")
+	assert.NoError(t, err)
+	idx, err := p.WithObject(runtime.NewObject(map[string]interface{}{
+		"fields": map[string]interface{}{"topic": "app"},
+	})).Render()
+	assert.NoError(t, err)
+	assert.Equal(t, "dead-app", idx)
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	es "github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+const (
+	opTypeIndex  = "index"
+	opTypeCreate = "create"
+
+	setupTimeout = 30 * time.Second
+
+	settingLifecycleName          = "index.lifecycle.name"
+	settingLifecycleRolloverAlias = "index.lifecycle.rollover_alias"
+)
+
+// IndexTemplate bootstraps a composable index template on startup, which requires elasticsearch 7.8+
+type IndexTemplate struct {
+	Enabled       bool     `yaml:"enabled,omitempty"`
+	Name          string   `yaml:"name,omitempty" default:"loggie"`
+	IndexPatterns []string `yaml:"indexPatterns,omitempty"`
+	Priority      int      `yaml:"priority,omitempty" default:"150"`
+	// File is a json file of the template body, such as the settings and mappings,
+	// the index patterns, priority, data stream and lifecycle settings are filled by loggie
+	File      string `yaml:"file,omitempty"`
+	Overwrite bool   `yaml:"overwrite,omitempty"`
+}
+
+// ILM bootstraps the index lifecycle policy on startup
+type ILM struct {
+	Enabled    bool   `yaml:"enabled,omitempty"`
+	PolicyName string `yaml:"policyName,omitempty" default:"loggie"`
+	// PolicyFile is a json file of the policy, a rollover policy is generated with the options below if it is empty
+	PolicyFile      string `yaml:"policyFile,omitempty"`
+	RolloverMaxSize string `yaml:"rolloverMaxSize,omitempty" default:"50gb"`
+	RolloverMaxAge  string `yaml:"rolloverMaxAge,omitempty" default:"30d"`
+	// DeleteAfter deletes the indices after the rollover, they are kept forever if it is empty
+	DeleteAfter string `yaml:"deleteAfter,omitempty"`
+	// RolloverAlias is the write alias of the rolling indices, the first index <alias>-000001 is created if the alias
+	// does not exist. It is not used by data streams, which are rolled over by themselves.
+	RolloverAlias string `yaml:"rolloverAlias,omitempty"`
+	Overwrite     bool   `yaml:"overwrite,omitempty"`
+}
+
+// setup creates the lifecycle policy, index template and the rollover alias in order if they do not exist
+func setup(cli *es.Client, config *Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	if config.ILM.Enabled {
+		if err := setupPolicy(ctx, cli, &config.ILM); err != nil {
+			return errors.WithMessagef(err, "setup ilm policy %s", config.ILM.PolicyName)
+		}
+	}
+	if config.IndexTemplate.Enabled {
+		if err := setupTemplate(ctx, cli, config); err != nil {
+			return errors.WithMessagef(err, "setup index template %s", config.IndexTemplate.Name)
+		}
+	}
+	if config.ILM.Enabled && config.ILM.RolloverAlias != "" {
+		if err := setupRolloverAlias(ctx, cli, config.ILM.RolloverAlias); err != nil {
+			return errors.WithMessagef(err, "setup rollover alias %s", config.ILM.RolloverAlias)
+		}
+	}
+	return nil
+}
+
+func setupPolicy(ctx context.Context, cli *es.Client, config *ILM) error {
+	if !config.Overwrite {
+		resp, err := cli.ILM.GetLifecycle(cli.ILM.GetLifecycle.WithContext(ctx), cli.ILM.GetLifecycle.WithPolicy(config.PolicyName))
+		exists, err := existsResponse(resp, err)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+	}
+
+	body, err := buildPolicy(config)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.ILM.PutLifecycle(config.PolicyName, cli.ILM.PutLifecycle.WithContext(ctx),
+		cli.ILM.PutLifecycle.WithBody(bytes.NewReader(body)))
+	if err := checkResponse(resp, err); err != nil {
+		return err
+	}
+	log.Info("elasticsearch ilm policy %s is created", config.PolicyName)
+	return nil
+}
+
+func buildPolicy(config *ILM) ([]byte, error) {
+	if config.PolicyFile != "" {
+		return os.ReadFile(config.PolicyFile)
+	}
+
+	rollover := map[string]interface{}{}
+	if config.RolloverMaxSize != "" {
+		rollover["max_size"] = config.RolloverMaxSize
+	}
+	if config.RolloverMaxAge != "" {
+		rollover["max_age"] = config.RolloverMaxAge
+	}
+	phases := map[string]interface{}{
+		"hot": map[string]interface{}{
+			"actions": map[string]interface{}{
+				"rollover": rollover,
+			},
+		},
+	}
+	if config.DeleteAfter != "" {
+		phases["delete"] = map[string]interface{}{
+			"min_age": config.DeleteAfter,
+			"actions": map[string]interface{}{
+				"delete": map[string]interface{}{},
+			},
+		}
+	}
+	return json.Marshal(map[string]interface{}{
+		"policy": map[string]interface{}{
+			"phases": phases,
+		},
+	})
+}
+
+func setupTemplate(ctx context.Context, cli *es.Client, config *Config) error {
+	name := config.IndexTemplate.Name
+	if !config.IndexTemplate.Overwrite {
+		resp, err := cli.Indices.ExistsIndexTemplate(name, cli.Indices.ExistsIndexTemplate.WithContext(ctx))
+		exists, err := existsResponse(resp, err)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+	}
+
+	body, err := buildTemplate(config)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.Indices.PutIndexTemplate(name, bytes.NewReader(body), cli.Indices.PutIndexTemplate.WithContext(ctx))
+	if err := checkResponse(resp, err); err != nil {
+		return err
+	}
+	log.Info("elasticsearch index template %s is created", name)
+	return nil
+}
+
+func buildTemplate(config *Config) ([]byte, error) {
+	tpl := map[string]interface{}{}
+	if config.IndexTemplate.File != "" {
+		content, err := os.ReadFile(config.IndexTemplate.File)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(content, &tpl); err != nil {
+			return nil, errors.WithMessagef(err, "unmarshal index template file %s", config.IndexTemplate.File)
+		}
+	}
+
+	if len(config.IndexTemplate.IndexPatterns) > 0 {
+		tpl["index_patterns"] = config.IndexTemplate.IndexPatterns
+	}
+	if _, ok := tpl["index_patterns"]; !ok {
+		return nil, errors.New("index patterns of the template are required")
+	}
+	tpl["priority"] = config.IndexTemplate.Priority
+	if config.DataStream {
+		tpl["data_stream"] = map[string]interface{}{}
+	}
+
+	if config.ILM.Enabled {
+		body, _ := tpl["template"].(map[string]interface{})
+		if body == nil {
+			body = map[string]interface{}{}
+			tpl["template"] = body
+		}
+		settings, _ := body["settings"].(map[string]interface{})
+		if settings == nil {
+			settings = map[string]interface{}{}
+			body["settings"] = settings
+		}
+		settings[settingLifecycleName] = config.ILM.PolicyName
+		if config.ILM.RolloverAlias != "" {
+			settings[settingLifecycleRolloverAlias] = config.ILM.RolloverAlias
+		}
+	}
+	return json.Marshal(tpl)
+}
+
+func setupRolloverAlias(ctx context.Context, cli *es.Client, alias string) error {
+	resp, err := cli.Indices.ExistsAlias([]string{alias}, cli.Indices.ExistsAlias.WithContext(ctx))
+	exists, err := existsResponse(resp, err)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"aliases": map[string]interface{}{
+			alias: map[string]interface{}{
+				"is_write_index": true,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	index := alias + "-000001"
+	resp, err = cli.Indices.Create(index, cli.Indices.Create.WithContext(ctx), cli.Indices.Create.WithBody(bytes.NewReader(body)))
+	if err := checkResponse(resp, err); err != nil {
+		// created by other agents at the same time
+		if strings.Contains(err.Error(), "resource_already_exists_exception") {
+			return nil
+		}
+		return err
+	}
+	log.Info("elasticsearch index %s is created with write alias %s", index, alias)
+	return nil
+}
+
+func existsResponse(resp *esapi.Response, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.IsError() {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, errors.Errorf("status %d: %s", resp.StatusCode, body)
+	}
+	return true, nil
+}
+
+func checkResponse(resp *esapi.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func TestBuildPolicy(t *testing.T) {
+	body, err := buildPolicy(&ILM{RolloverMaxSize: "50gb", RolloverMaxAge: "1d", DeleteAfter: "7d"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"policy":{"phases":{
+		"hot":{"actions":{"rollover":{"max_size":"50gb","max_age":"1d"}}},
+		"delete":{"min_age":"7d","actions":{"delete":{}}}}}}`, string(body))
+}
+
+func TestBuildTemplate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "template.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`{"template":{"settings":{"number_of_shards":3},"mappings":{"dynamic":false}}}`), 0644))
+
+	body, err := buildTemplate(&Config{
+		DataStream: true,
+		IndexTemplate: IndexTemplate{
+			IndexPatterns: []string{"logs-app-*"},
+			Priority:      200,
+			File:          file,
+		},
+		ILM: ILM{Enabled: true, PolicyName: "loggie"},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"index_patterns":["logs-app-*"],"priority":200,"data_stream":{},
+		"template":{"settings":{"number_of_shards":3,"index.lifecycle.name":"loggie"},"mappings":{"dynamic":false}}}`, string(body))
+
+	_, err = buildTemplate(&Config{IndexTemplate: IndexTemplate{Priority: 200}})
+	assert.Error(t, err)
+}
+
+func TestSetup(t *testing.T) {
+	log.InitDefaultLogger()
+
+	var lock sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		lock.Unlock()
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/":
+			w.Write([]byte(`{"version":{"number":"7.17.0"},"tagline":"You Know, for Search"}`))
+		case r.Method == http.MethodPut:
+			w.Write([]byte(`{"acknowledged":true}`))
+		case r.URL.Path == "/_ilm/policy/loggie":
+			w.Write([]byte(`{"loggie":{}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	config := &Config{
+		Hosts: []string{server.URL},
+		Index: "logs",
+		IndexTemplate: IndexTemplate{
+			Enabled:       true,
+			Name:          "logs",
+			IndexPatterns: []string{"logs-*"},
+		},
+		ILM: ILM{Enabled: true, PolicyName: "loggie", RolloverAlias: "logs"},
+	}
+	cli, err := NewClient(config, nil, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, setup(cli.cli, config))
+
+	// the existing policy is kept, the missing template and alias are created
+	assert.Contains(t, requests, "GET /_ilm/policy/loggie")
+	assert.NotContains(t, requests, "PUT /_ilm/policy/loggie")
+	assert.Contains(t, requests, "PUT /_index_template/logs")
+	assert.Contains(t, requests, "PUT /logs-000001")
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventhubs
+
+import (
+	"strings"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/azure"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/pkg/errors"
+)
+
+const (
+	namespaceSuffix = ".servicebus.windows.net"
+
+	// the max message size of the basic and standard tiers
+	maxBatchBytes = 1024 * 1024
+)
+
+type Config struct {
+	azure.Config `yaml:",inline"`
+
+	// ConnectionString of a shared access policy, such as
+	// Endpoint=sb://<namespace>.servicebus.windows.net/;SharedAccessKeyName=<name>;SharedAccessKey=<key>;EntityPath=<hub>
+	// the namespace, event hub and key in it are used when they are not set separately.
+	// Azure AD is used to authorize when there is no shared access key, by the workload identity or managed identity on AKS.
+	ConnectionString    string `yaml:"connectionString,omitempty"`
+	Namespace           string `yaml:"namespace,omitempty"`
+	EventHub            string `yaml:"eventHub,omitempty"`
+	SharedAccessKeyName string `yaml:"sharedAccessKeyName,omitempty"`
+	SharedAccessKey     string `yaml:"sharedAccessKey,omitempty"`
+	// Endpoint overrides the endpoint built from the namespace, such as an emulator
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// PartitionKey could be a pattern such as ${fields.podname}, the events with the same key are sent to the same partition
+	// in order, the events are distributed to the partitions in round-robin when it is empty.
+	PartitionKey string `yaml:"partitionKey,omitempty"`
+
+	// the limits of a request, the batch is split when exceeded. MaxBatchBytes is lowered automatically when it is
+	// larger than the limit of the tier of the namespace.
+	MaxBatchEvents int           `yaml:"maxBatchEvents,omitempty" default:"500" validate:"gte=1"`
+	MaxBatchBytes  int           `yaml:"maxBatchBytes,omitempty" default:"1046528" validate:"gte=1"`
+	Timeout        time.Duration `yaml:"timeout,omitempty" default:"30s"`
+}
+
+type connectionString struct {
+	namespace string
+	eventHub  string
+	keyName   string
+	key       string
+}
+
+func parseConnectionString(s string) (*connectionString, error) {
+	cs := &connectionString{}
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("invalid connectionString part: %s", part)
+		}
+		switch strings.ToLower(kv[0]) {
+		case "endpoint":
+			endpoint := strings.TrimPrefix(kv[1], "sb://")
+			cs.namespace = strings.TrimSuffix(endpoint, "/")
+		case "entitypath":
+			cs.eventHub = kv[1]
+		case "sharedaccesskeyname":
+			cs.keyName = kv[1]
+		case "sharedaccesskey":
+			cs.key = kv[1]
+		}
+	}
+	if cs.namespace == "" {
+		return nil, errors.New("endpoint is required in connectionString")
+	}
+	return cs, nil
+}
+
+func (c *Config) SetDefaults() {
+	c.Config.SetDefaults()
+	if c.ConnectionString != "" {
+		// the error is returned in Validate
+		if cs, err := parseConnectionString(c.ConnectionString); err == nil {
+			if c.Namespace == "" {
+				c.Namespace = cs.namespace
+			}
+			if c.EventHub == "" {
+				c.EventHub = cs.eventHub
+			}
+			if c.SharedAccessKeyName == "" {
+				c.SharedAccessKeyName = cs.keyName
+			}
+			if c.SharedAccessKey == "" {
+				c.SharedAccessKey = cs.key
+			}
+		}
+	}
+	if c.Namespace != "" && !strings.Contains(c.Namespace, ".") {
+		c.Namespace += namespaceSuffix
+	}
+}
+
+func (c *Config) Validate() error {
+	if c.ConnectionString != "" {
+		if _, err := parseConnectionString(c.ConnectionString); err != nil {
+			return err
+		}
+	}
+	if c.Namespace == "" {
+		return errors.New("namespace or connectionString is required")
+	}
+	if c.EventHub == "" {
+		return errors.New("eventHub is required")
+	}
+	if (c.SharedAccessKeyName == "") != (c.SharedAccessKey == "") {
+		return errors.New("sharedAccessKeyName and sharedAccessKey should be set together")
+	}
+	if c.MaxBatchBytes > maxBatchBytes {
+		return errors.Errorf("maxBatchBytes should not be greater than %d", maxBatchBytes)
+	}
+	return pattern.Validate(c.PartitionKey)
+}
+
+func (c *Config) endpoint() string {
+	if c.Endpoint != "" {
+		return strings.TrimSuffix(c.Endpoint, "/")
+	}
+	return "https://" + c.Namespace
+}
@@ -0,0 +1,307 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventhubs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/azure"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const (
+	Type = "eventhubs"
+
+	resource    = "https://eventhubs.azure.net/"
+	contentType = "application/vnd.microsoft.servicebus.json"
+	apiVersion  = "2014-01"
+	sasValidity = time.Hour
+
+	// the batch bytes would not be lowered any further
+	minBatchBytes = 1024
+)
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink()
+}
+
+type Sink struct {
+	name   string
+	config *Config
+	codec  codec.Codec
+	tokens *azure.TokenProvider
+	client *http.Client
+
+	partitionKeyPattern *pattern.Pattern
+	// batchBytes is the max bytes of a request accepted by the namespace, which starts from maxBatchBytes
+	// and is halved when the request is rejected as too large
+	batchBytes int64
+}
+
+func NewSink() *Sink {
+	return &Sink{
+		config: &Config{},
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) SetCodec(c codec.Codec) {
+	s.codec = c
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.partitionKeyPattern, _ = pattern.Init(s.config.PartitionKey)
+	if s.config.SharedAccessKey == "" {
+		s.tokens = azure.NewTokenProvider(&s.config.Config, resource)
+	}
+	s.client = &http.Client{
+		Timeout: s.config.Timeout,
+	}
+	s.batchBytes = int64(s.config.MaxBatchBytes)
+	return nil
+}
+
+func (s *Sink) Start() error {
+	auth := "azure ad"
+	if s.tokens == nil {
+		auth = "shared access key " + s.config.SharedAccessKeyName
+	}
+	log.Info("%s start, event hub: %s, endpoint: %s, auth: %s", s.String(), s.config.EventHub, s.config.endpoint(), auth)
+	return nil
+}
+
+func (s *Sink) Stop() {
+}
+
+type brokerProperties struct {
+	PartitionKey string `json:"PartitionKey,omitempty"`
+}
+
+type message struct {
+	Body             string            `json:"Body"`
+	BrokerProperties *brokerProperties `json:"BrokerProperties,omitempty"`
+
+	size int
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	messages := make([]*message, 0, len(events))
+	for _, e := range events {
+		m, err := s.encode(e)
+		if err != nil {
+			log.Warn("[%s] encode event error: %v", s.name, err)
+			continue
+		}
+		messages = append(messages, m)
+	}
+
+	if err := s.send(context.Background(), messages); err != nil {
+		return result.Fail(errors.WithMessagef(err, "send events to event hub %s", s.config.EventHub))
+	}
+	return result.Success()
+}
+
+func (s *Sink) encode(e api.Event) (*message, error) {
+	m := &message{}
+	// render the key ahead of the codec, which may modify the header
+	key, err := s.partitionKeyPattern.WithObject(runtime.NewObject(e.Header())).Render()
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		m.BrokerProperties = &brokerProperties{PartitionKey: key}
+	}
+
+	data, err := s.codec.Encode(e)
+	if err != nil {
+		return nil, err
+	}
+	m.Body = string(data)
+
+	out, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	m.size = len(out)
+	return m, nil
+}
+
+// send splits the messages into requests by the current batch bytes, a request rejected as too large
+// lowers the batch bytes and is split again
+func (s *Sink) send(ctx context.Context, messages []*message) error {
+	for len(messages) > 0 {
+		limit := int(atomic.LoadInt64(&s.batchBytes))
+		var chunk []*message
+		chunk, messages = s.next(messages, limit)
+		if len(chunk) == 0 {
+			continue
+		}
+
+		err := s.post(ctx, chunk)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, errTooLarge) {
+			return err
+		}
+
+		if len(chunk) == 1 {
+			log.Warn("[%s] drop the event of %d bytes which is rejected as too large", s.name, chunk[0].size)
+			continue
+		}
+		s.lower(limit)
+		messages = append(chunk, messages...)
+	}
+	return nil
+}
+
+// next takes the messages of a request, a message larger than the limit is dropped
+func (s *Sink) next(messages []*message, limit int) (chunk []*message, rest []*message) {
+	// the brackets of the array
+	size := 2
+	for i, m := range messages {
+		if len(chunk) == 0 && size+m.size > limit {
+			log.Warn("[%s] drop the event of %d bytes which exceeds the batch bytes %d", s.name, m.size, limit)
+			return nil, messages[i+1:]
+		}
+		// with the comma
+		if len(chunk) > 0 && (len(chunk) >= s.config.MaxBatchEvents || size+m.size+1 > limit) {
+			return chunk, messages[i:]
+		}
+		chunk = append(chunk, m)
+		size += m.size + 1
+	}
+	return chunk, nil
+}
+
+func (s *Sink) lower(limit int) {
+	lowered := limit / 2
+	if lowered < minBatchBytes {
+		lowered = minBatchBytes
+	}
+	if atomic.CompareAndSwapInt64(&s.batchBytes, int64(limit), int64(lowered)) {
+		log.Warn("[%s] request is too large for the event hub, lower the batch bytes from %d to %d", s.name, limit, lowered)
+	}
+}
+
+var errTooLarge = errors.New("request entity too large")
+
+func (s *Sink) post(ctx context.Context, messages []*message) error {
+	body, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/%s/messages?timeout=%d&api-version=%s", s.config.endpoint(), url.PathEscape(s.config.EventHub),
+		int(s.config.Timeout.Seconds()), apiVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	auth, err := s.authorization(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		return errTooLarge
+	}
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("event hubs returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+func (s *Sink) authorization(ctx context.Context) (string, error) {
+	if s.tokens != nil {
+		token, err := s.tokens.Token(ctx)
+		if err != nil {
+			return "", errors.WithMessage(err, "retrieve azure ad token")
+		}
+		return "Bearer " + token, nil
+	}
+	uri := fmt.Sprintf("https://%s/%s", s.config.Namespace, s.config.EventHub)
+	return sharedAccessSignature(uri, s.config.SharedAccessKeyName, s.config.SharedAccessKey, time.Now().Add(sasValidity)), nil
+}
+
+// sharedAccessSignature generates the sas token of the resource uri
+func sharedAccessSignature(uri string, keyName string, key string, expiry time.Time) string {
+	encoded := url.QueryEscape(strings.ToLower(uri))
+	se := strconv.FormatInt(expiry.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(encoded + "\n" + se))
+	sig := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	return fmt.Sprintf("SharedAccessSignature sr=%s&sig=%s&se=%s&skn=%s", encoded, url.QueryEscape(sig), se, keyName)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventhubs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	lcontext "github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	ljson "github.com/loggie-io/loggie/pkg/util/json"
+)
+
+func newTestMessages(sizes ...int) []*message {
+	messages := make([]*message, 0, len(sizes))
+	for _, size := range sizes {
+		m := &message{Body: strings.Repeat("x", size)}
+		out, _ := ljson.Marshal(m)
+		m.size = len(out)
+		messages = append(messages, m)
+	}
+	return messages
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxBatchEvents int
+		maxBytes       int // the requests larger than it are rejected by the event hub
+		sizes          []int
+		// the number of the messages of the accepted requests
+		wantRequests   []int
+		wantBatchBytes int64
+	}{
+		{
+			name:           "split by max batch events",
+			maxBatchEvents: 2,
+			sizes:          []int{10, 10, 10},
+			wantRequests:   []int{2, 1},
+			wantBatchBytes: 1046528,
+		},
+		{
+			// the batch bytes is halved until the requests are accepted, and kept for the later batches
+			name:           "lower batch bytes",
+			maxBatchEvents: 500,
+			maxBytes:       4096,
+			sizes:          []int{1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000},
+			wantRequests:   []int{4, 4, 2},
+			wantBatchBytes: 4088,
+		},
+		{
+			// the batch bytes is lowered to the min since the large event is rejected along with the others
+			name:           "drop too large",
+			maxBatchEvents: 500,
+			maxBytes:       1500,
+			sizes:          []int{2000, 10},
+			wantRequests:   []int{1},
+			wantBatchBytes: minBatchBytes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/logs/messages", r.URL.Path)
+				assert.Equal(t, contentType, r.Header.Get("Content-Type"))
+				body, _ := io.ReadAll(r.Body)
+				if tt.maxBytes > 0 && len(body) > tt.maxBytes {
+					w.WriteHeader(http.StatusRequestEntityTooLarge)
+					return
+				}
+				var messages []message
+				assert.NoError(t, ljson.Unmarshal(body, &messages))
+				requests = append(requests, len(messages))
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer server.Close()
+
+			log.InitDefaultLogger()
+			s := NewSink()
+			raw := "endpoint: " + server.URL + "\nmaxBatchEvents: " + strconv.Itoa(tt.maxBatchEvents) + "\n" +
+				"connectionString: Endpoint=sb://ns.servicebus.windows.net/;SharedAccessKeyName=send;SharedAccessKey=secret;EntityPath=logs"
+			assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), s.config).Defaults().Validate().Do())
+			assert.NoError(t, s.Init(lcontext.NewContext("eventhubs", Type, api.SINK, nil)))
+
+			assert.NoError(t, s.send(context.Background(), newTestMessages(tt.sizes...)))
+			assert.Equal(t, tt.wantRequests, requests)
+			assert.Equal(t, tt.wantBatchBytes, s.batchBytes)
+		})
+	}
+}
+
+func TestSharedAccessSignature(t *testing.T) {
+	sas := sharedAccessSignature("https://NS.servicebus.windows.net/logs", "send", "secret", time.Unix(1700000000, 0))
+	assert.Equal(t, "SharedAccessSignature sr=https%3A%2F%2Fns.servicebus.windows.net%2Flogs&sig=a18LT%2FuebrH0U5ZdRZ2IKie78FieIXD3WosGRqYvatk%3D&se=1700000000&skn=send", sas)
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		wantNamespace string
+		wantKeyName   string
+		wantErr       bool
+	}{
+		{name: "namespace", raw: "namespace: ns\neventHub: logs", wantNamespace: "ns.servicebus.windows.net"},
+		{
+			name:          "connection string",
+			raw:           "connectionString: Endpoint=sb://ns.servicebus.windows.net/;SharedAccessKeyName=a;SharedAccessKey=b\neventHub: logs",
+			wantNamespace: "ns.servicebus.windows.net",
+			wantKeyName:   "a",
+		},
+		{name: "without event hub", raw: "connectionString: Endpoint=sb://ns.servicebus.windows.net/", wantErr: true},
+		{name: "invalid connection string", raw: "connectionString: SharedAccessKey\neventHub: logs", wantErr: true},
+		{name: "key without name", raw: "namespace: ns\neventHub: logs\nsharedAccessKey: b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			err := cfg.UnPackFromRaw([]byte(tt.raw), c).Defaults().Validate().Do()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantNamespace, c.Namespace)
+			assert.Equal(t, "https://"+tt.wantNamespace, c.endpoint())
+			assert.Equal(t, tt.wantKeyName, c.SharedAccessKeyName)
+		})
+	}
+}
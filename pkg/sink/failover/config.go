@@ -0,0 +1,60 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/sink"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+type Config struct {
+	Primary sink.Config `yaml:"primary,omitempty" validate:"required"`
+	// Fallbacks are switched to in order when the active sink is unhealthy, such as a local file or a secondary kafka
+	Fallbacks []sink.Config `yaml:"fallbacks,omitempty" validate:"required,dive"`
+	// Errors is the number of failed batches in a row before switching to the next sink
+	Errors int `yaml:"errors,omitempty" default:"3" validate:"gte=1"`
+	// ProbeInterval is the interval to try the primary with a batch after failed over, it fails back once succeeded
+	ProbeInterval time.Duration `yaml:"probeInterval,omitempty" default:"30s"`
+
+	info pipeline.Info
+}
+
+func (c *Config) Validate() error {
+	if c.ProbeInterval <= 0 {
+		return errors.New("failover sink probeInterval should be positive")
+	}
+	if err := c.validateSink(&c.Primary); err != nil {
+		return errors.WithMessage(err, "failover sink primary")
+	}
+	for i := range c.Fallbacks {
+		if err := c.validateSink(&c.Fallbacks[i]); err != nil {
+			return errors.WithMessagef(err, "failover sink fallbacks[%d]", i)
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateSink(config *sink.Config) error {
+	if config.Type == Type {
+		return errors.New("failover sink could not be nested")
+	}
+	return pipeline.ValidateSink(c.info, config)
+}
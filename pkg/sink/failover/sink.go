@@ -0,0 +1,221 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/sink"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const Type = "failover"
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return &Sink{
+		pipelineName: info.PipelineName,
+		config:       &Config{info: info},
+	}
+}
+
+// Sink sends the batches to the primary sink, and switches to the fallbacks in order when the active one failed
+// too many batches in a row, the primary is probed periodically to fail back once it recovered
+type Sink struct {
+	pipelineName string
+	name         string
+	config       *Config
+
+	names []string
+	sinks []api.Sink
+
+	lock        sync.Mutex
+	active      int // 0 is the primary
+	failures    int // the failed batches in a row of the active sink
+	lastProbe   time.Time
+	switchovers uint64
+	failbacks   uint64
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.names = append(s.names, sinkName(&s.config.Primary, s.name+"-primary"))
+	for i := range s.config.Fallbacks {
+		s.names = append(s.names, sinkName(&s.config.Fallbacks[i], fmt.Sprintf("%s-fallback-%d", s.name, i)))
+	}
+	return nil
+}
+
+func sinkName(config *sink.Config, name string) string {
+	if config.Name != "" {
+		return config.Name
+	}
+	return name
+}
+
+func (s *Sink) Start() error {
+	configs := append([]sink.Config{s.config.Primary}, s.config.Fallbacks...)
+	for i := range configs {
+		si, err := pipeline.StartSink(s.config.info, s.names[i], &configs[i])
+		if err != nil {
+			s.Stop()
+			return errors.WithMessagef(err, "start sink %s", s.names[i])
+		}
+		s.sinks = append(s.sinks, si)
+	}
+
+	s.publish()
+	log.Info("%s start, primary: %s, fallbacks: %v", s.String(), s.names[0], s.names[1:])
+	return nil
+}
+
+func (s *Sink) Stop() {
+	for _, si := range s.sinks {
+		si.Stop()
+	}
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	active, probe := s.next(time.Now())
+	if probe {
+		res := s.sinks[0].Consume(batch)
+		if res.Status() != api.FAIL {
+			s.failBack()
+			return res
+		}
+		log.Warn("%s primary %s is still unhealthy: %v", s.String(), s.names[0], res.Error())
+	}
+
+	for {
+		res := s.sinks[active].Consume(batch)
+		if res.Status() != api.FAIL {
+			s.succeeded(active)
+			return res
+		}
+
+		next, ok := s.failed(active, res.Error())
+		if !ok {
+			return result.Fail(errors.WithMessagef(res.Error(), "sink %s", s.names[active]))
+		}
+		// the batch is sent to the next sink at once instead of being retried by the pipeline
+		active = next
+	}
+}
+
+// next returns the active sink, and whether to probe the primary before it
+func (s *Sink) next(now time.Time) (int, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.active == 0 || now.Sub(s.lastProbe) < s.config.ProbeInterval {
+		return s.active, false
+	}
+	s.lastProbe = now
+	return s.active, true
+}
+
+func (s *Sink) succeeded(index int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if index == s.active {
+		s.failures = 0
+	}
+}
+
+// failed returns the next sink to send the batch when the active sink has failed too many batches
+func (s *Sink) failed(index int, err error) (int, bool) {
+	s.lock.Lock()
+	if index != s.active {
+		// switched by the other consumers already
+		next := s.active
+		s.lock.Unlock()
+		return next, next > index
+	}
+	s.failures++
+	if s.failures < s.config.Errors || s.active == len(s.sinks)-1 {
+		s.lock.Unlock()
+		return 0, false
+	}
+
+	s.active++
+	s.failures = 0
+	s.lastProbe = time.Now()
+	s.switchovers++
+	next := s.active
+	s.lock.Unlock()
+
+	log.Warn("%s sink %s failed %d batches in a row, fail over to %s: %v",
+		s.String(), s.names[index], s.config.Errors, s.names[next], err)
+	s.publish()
+	return next, true
+}
+
+func (s *Sink) failBack() {
+	s.lock.Lock()
+	if s.active == 0 {
+		s.lock.Unlock()
+		return
+	}
+	from := s.active
+	s.active = 0
+	s.failures = 0
+	s.failbacks++
+	s.lock.Unlock()
+
+	log.Info("%s primary %s recovered, fail back from %s", s.String(), s.names[0], s.names[from])
+	s.publish()
+}
+
+func (s *Sink) publish() {
+	s.lock.Lock()
+	data := eventbus.FailoverMetricData{
+		PipelineName: s.pipelineName,
+		SinkName:     s.name,
+		Active:       s.names[s.active],
+		ActiveIndex:  s.active,
+		Switchovers:  s.switchovers,
+		Failbacks:    s.failbacks,
+	}
+	s.lock.Unlock()
+	eventbus.PublishOrDrop(eventbus.FailoverTopic, data)
+}
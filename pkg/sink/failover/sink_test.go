@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const fakeType = "failoverFake"
+
+type fakeSink struct {
+	name     string
+	lock     sync.Mutex
+	failing  bool
+	consumed int
+}
+
+var fakes sync.Map // sink name -> *fakeSink
+
+func init() {
+	pipeline.Register(api.SINK, fakeType, func(info pipeline.Info) api.Component {
+		return &fakeSink{}
+	})
+}
+
+func (f *fakeSink) Category() api.Category { return api.SINK }
+func (f *fakeSink) Type() api.Type         { return fakeType }
+func (f *fakeSink) Config() interface{}    { return &struct{}{} }
+func (f *fakeSink) String() string         { return fmt.Sprintf("%s/%s", api.SINK, fakeType) }
+func (f *fakeSink) Start() error           { return nil }
+func (f *fakeSink) Stop()                  {}
+
+func (f *fakeSink) Init(context api.Context) error {
+	f.name = context.Name()
+	fakes.Store(f.name, f)
+	return nil
+}
+
+func (f *fakeSink) Consume(b api.Batch) api.Result {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.failing {
+		return result.Fail(errors.New("unavailable"))
+	}
+	f.consumed++
+	return result.Success()
+}
+
+func (f *fakeSink) setFailing(failing bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.failing = failing
+}
+
+func fake(t *testing.T, name string) *fakeSink {
+	f, ok := fakes.Load(name)
+	assert.True(t, ok, name)
+	return f.(*fakeSink)
+}
+
+func consume(s *Sink) api.Status {
+	return s.Consume(batch.NewBatchWithEvents([]api.Event{event.NewEvent(nil, []byte("a"))})).Status()
+}
+
+func TestFailover(t *testing.T) {
+	log.InitDefaultLogger()
+	s := makeSink(pipeline.Info{PipelineName: "test"}).(*Sink)
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(`
+primary:
+  type: failoverFake
+fallbacks:
+  - type: failoverFake
+  - type: failoverFake
+    name: local
+errors: 2
+probeInterval: 50ms
+`), s.config).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(context.NewContext("out", Type, api.SINK, nil)))
+	assert.NoError(t, s.Start())
+	defer s.Stop()
+
+	primary, secondary, local := fake(t, "out-primary"), fake(t, "out-fallback-0"), fake(t, "local")
+	primary.setFailing(true)
+
+	// the first failure is retried by the pipeline
+	assert.Equal(t, api.FAIL, consume(s))
+	// the batch is sent to the fallback at once when switched
+	assert.Equal(t, api.SUCCESS, consume(s))
+	assert.Equal(t, api.SUCCESS, consume(s))
+	assert.Equal(t, 2, secondary.consumed)
+	assert.Equal(t, 1, s.active)
+
+	// the fallbacks are switched in order
+	secondary.setFailing(true)
+	assert.Equal(t, api.FAIL, consume(s))
+	assert.Equal(t, api.SUCCESS, consume(s))
+	assert.Equal(t, 1, local.consumed)
+
+	// the last sink keeps failing without switching
+	local.setFailing(true)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, api.FAIL, consume(s))
+	}
+	assert.Equal(t, 2, s.active)
+	local.setFailing(false)
+
+	// fail back once the probe to the primary succeeded
+	primary.setFailing(false)
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, api.SUCCESS, consume(s))
+	assert.Equal(t, 1, primary.consumed)
+	assert.Equal(t, 0, s.active)
+	assert.Equal(t, uint64(2), s.switchovers)
+	assert.Equal(t, uint64(1), s.failbacks)
+}
+
+func TestValidate(t *testing.T) {
+	s := makeSink(pipeline.Info{}).(*Sink)
+	err := cfg.UnPackFromRaw([]byte(`
+primary:
+  type: failoverFake
+fallbacks:
+  - type: failover
+`), s.config).Defaults().Validate().Do()
+	assert.Error(t, err)
+}
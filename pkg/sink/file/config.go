@@ -16,7 +16,21 @@ limitations under the License.
 
 package file
 
-import "github.com/loggie-io/loggie/pkg/util/pattern"
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/pkg/errors"
+)
+
+const (
+	// FsyncNone leaves the written data to be committed by the os
+	FsyncNone = "none"
+	// FsyncBatch commits the files after each batch is written, before the batch is acked
+	FsyncBatch = "batch"
+	// FsyncInterval commits the files every flushInterval
+	FsyncInterval = "interval"
+)
 
 type Config struct {
 	// WorkerCount is the number of concurrent goroutines writing files
@@ -50,6 +64,14 @@ type Config struct {
 	// Compress determines if the rotated log files should be compressed
 	// using gzip. The default is not to perform compression.
 	Compress bool `yaml:"compress,omitempty"`
+	// RotateInterval rotates the log file at the boundaries of the interval, e.g. 1h rotates on the hour
+	// and 24h rotates at midnight, the file is rotated only if something has been written to it.
+	// The default is to rotate by size only.
+	RotateInterval time.Duration `yaml:"rotateInterval,omitempty"`
+	// FlushInterval is how often the buffered data is flushed to the files.
+	FlushInterval time.Duration `yaml:"flushInterval,omitempty" default:"30s"`
+	// Fsync determines when the written data is committed to the disk, could be none, batch or interval.
+	Fsync string `yaml:"fsync,omitempty" default:"none" validate:"oneof=none batch interval"`
 }
 
 func (c *Config) Validate() error {
@@ -63,5 +85,12 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if c.RotateInterval < 0 || (c.RotateInterval > 0 && c.RotateInterval < time.Second) {
+		return errors.New("rotateInterval should be at least 1s")
+	}
+	if c.FlushInterval <= 0 {
+		return errors.New("flushInterval should be positive")
+	}
+
 	return nil
 }
@@ -24,7 +24,6 @@ import (
 
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/panjf2000/ants/v2"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 var (
@@ -39,6 +38,12 @@ type Options struct {
 	LocalTime   bool
 	Compress    bool
 	IdleTimeout time.Duration
+	// RotateInterval rotates the files at the boundaries of interval, zero disables time based rotation
+	RotateInterval time.Duration
+	// Fsync is one of none, batch and interval
+	Fsync string
+	// FlushInterval is how often the buffers are flushed, and synced when Fsync is interval
+	FlushInterval time.Duration
 }
 
 type Message struct {
@@ -74,7 +79,7 @@ func NewMultiFileWriter(opt *Options) (*MultiFileWriter, error) {
 		opt:     opt,
 		writers: make(map[string]*fw, opt.WorkerCount),
 		workers: pool,
-		ticker:  time.NewTicker(30 * time.Second),
+		ticker:  time.NewTicker(opt.FlushInterval),
 		close:   make(chan struct{}),
 		done:    make(chan struct{}),
 	}
@@ -96,21 +101,48 @@ func (w *MultiFileWriter) Write(msgs ...Message) error {
 		assignments[msgs[i].Filename] = append(assignments[msgs[i].Filename], int32(i))
 	}
 
+	// wait until all the messages have been written, so the batch would be retried once any of them failed
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	setErr := func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
 	for key, indexes := range assignments {
 		writer := w.getWriter(key)
 		is := indexes
-		w.workers.Submit(func() {
+		wg.Add(1)
+		err := w.workers.Submit(func() {
+			defer wg.Done()
 			for _, i := range is {
 				if _, err := writer.Write(msgs[i].Data); err != nil {
-					log.Error("write error: %+v", err)
+					setErr(err)
+					return
 				}
 				if _, err := writer.Write(LineEnding); err != nil {
-					log.Error("write error: %+v", err)
+					setErr(err)
+					return
+				}
+			}
+			if w.opt.Fsync == FsyncBatch {
+				if err := writer.fsync(); err != nil {
+					setErr(err)
 				}
 			}
 		})
+		if err != nil {
+			wg.Done()
+			setErr(err)
+		}
 	}
-	return nil
+	wg.Wait()
+	return firstErr
 }
 
 func (w *MultiFileWriter) Close() error {
@@ -151,7 +183,15 @@ func (w *MultiFileWriter) flushLoop() {
 			}
 			w.wsMu.Unlock()
 			for i := range tmp {
-				tmp[i].Sync()
+				if err := tmp[i].Sync(); err != nil {
+					log.Warn("flush file %s error: %v", tmp[i].filename, err)
+					continue
+				}
+				if w.opt.Fsync == FsyncInterval {
+					if err := tmp[i].wc.Sync(); err != nil {
+						log.Warn("sync file %s error: %v", tmp[i].filename, err)
+					}
+				}
 			}
 		case <-w.close:
 			return
@@ -159,19 +199,12 @@ func (w *MultiFileWriter) flushLoop() {
 	}
 }
 
-func (w *MultiFileWriter) getWriter(fn string) *Writer {
+func (w *MultiFileWriter) getWriter(fn string) *fw {
 	w.wsMu.Lock()
 	defer w.wsMu.Unlock()
 	v, ok := w.writers[fn]
 	if !ok {
-		wc := &lumberjack.Logger{
-			Filename:   fn,
-			MaxSize:    w.opt.MaxSize, // megabytes
-			MaxBackups: w.opt.MaxBackups,
-			MaxAge:     w.opt.MaxAge, // days
-			LocalTime:  w.opt.LocalTime,
-			Compress:   w.opt.Compress, // disabled by default
-		}
+		wc := newRotateFile(fn, w.opt)
 		v = &fw{
 			Writer: &Writer{
 				W:                 wc,
@@ -190,7 +223,7 @@ func (w *MultiFileWriter) getWriter(fn string) *Writer {
 	} else {
 		v.timer.Reset(w.opt.IdleTimeout)
 	}
-	return v.Writer
+	return v
 }
 
 func (w *MultiFileWriter) markClosed() error {
@@ -208,10 +241,18 @@ type fw struct {
 	*Writer
 
 	filename string
-	wc       *lumberjack.Logger
+	wc       *rotateFile
 	timer    *time.Timer
 }
 
+// fsync flushes the buffer and commits the file to the disk
+func (f *fw) fsync() error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.wc.Sync()
+}
+
 func (f *fw) Close() error {
 	if f.timer != nil {
 		f.timer.Stop()
@@ -0,0 +1,383 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+const (
+	// backupTimeFormat keeps the same backup names as lumberjack, so that files rotated by
+	// older versions are still recognized by the retention policy
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+	megabyte         = 1024 * 1024
+)
+
+// currentTime could be replaced in tests
+var currentTime = time.Now
+
+// rotateFile is an io.WriteCloser which writes to the file of the given name, the file would be
+// rotated when its size exceeds MaxSize or an interval boundary of RotateInterval is crossed.
+// Rotated files are renamed to name-<timestamp>.ext, compressed and removed by MaxBackups and MaxAge
+// in a background goroutine.
+type rotateFile struct {
+	filename string
+	opt      *Options
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	period time.Time
+
+	millCh    chan struct{}
+	startMill sync.Once
+	stopMill  chan struct{}
+	millDone  chan struct{}
+}
+
+func newRotateFile(filename string, opt *Options) *rotateFile {
+	return &rotateFile{
+		filename: filename,
+		opt:      opt,
+		millCh:   make(chan struct{}, 1),
+		stopMill: make(chan struct{}),
+		millDone: make(chan struct{}),
+	}
+}
+
+func (r *rotateFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := currentTime()
+	if r.file == nil {
+		if err := r.open(now); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.size > 0 && (r.crossed(now) || r.exceeded(int64(len(p)))) {
+		if err := r.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Sync commits the written data of the current file to the disk
+func (r *rotateFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Sync()
+}
+
+func (r *rotateFile) Close() error {
+	r.mu.Lock()
+	err := r.closeFile()
+	r.mu.Unlock()
+
+	close(r.stopMill)
+	r.startMill.Do(func() {
+		close(r.millDone)
+	})
+	<-r.millDone
+	return err
+}
+
+func (r *rotateFile) crossed(now time.Time) bool {
+	return r.opt.RotateInterval > 0 && !r.periodOf(now).Equal(r.period)
+}
+
+func (r *rotateFile) exceeded(n int64) bool {
+	return r.opt.MaxSize > 0 && r.size+n > int64(r.opt.MaxSize)*megabyte
+}
+
+// periodOf aligns t to the multiple of RotateInterval, using the local time zone when LocalTime is enabled,
+// so an interval of 24h rotates at the midnight
+func (r *rotateFile) periodOf(t time.Time) time.Time {
+	if r.opt.RotateInterval <= 0 {
+		return time.Time{}
+	}
+	if !r.opt.LocalTime {
+		return t.UTC().Truncate(r.opt.RotateInterval)
+	}
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(r.opt.RotateInterval).Add(-shift)
+}
+
+// open appends to the existing file if there is one
+func (r *rotateFile) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(r.filename), 0755); err != nil {
+		return err
+	}
+
+	r.period = r.periodOf(now)
+	if info, err := os.Stat(r.filename); err == nil {
+		r.period = r.periodOf(info.ModTime())
+	}
+
+	f, err := os.OpenFile(r.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotateFile) rotate(now time.Time) error {
+	if err := r.closeFile(); err != nil {
+		log.Warn("close file %s before rotating error: %v", r.filename, err)
+	}
+	if err := os.Rename(r.filename, r.backupName(now)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := r.open(now); err != nil {
+		return err
+	}
+	r.period = r.periodOf(now)
+	r.mill()
+	return nil
+}
+
+func (r *rotateFile) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	if r.opt.Fsync != FsyncNone {
+		// rotated files are never synced again
+		if err := r.file.Sync(); err != nil {
+			log.Warn("sync file %s error: %v", r.filename, err)
+		}
+	}
+	err := r.file.Close()
+	r.file = nil
+	r.size = 0
+	return err
+}
+
+func (r *rotateFile) backupName(now time.Time) string {
+	if !r.opt.LocalTime {
+		now = now.UTC()
+	}
+	dir := filepath.Dir(r.filename)
+	prefix, ext := r.prefixAndExt()
+	return filepath.Join(dir, fmt.Sprintf("%s%s%s", prefix, now.Format(backupTimeFormat), ext))
+}
+
+func (r *rotateFile) prefixAndExt() (string, string) {
+	name := filepath.Base(r.filename)
+	ext := filepath.Ext(name)
+	return name[:len(name)-len(ext)] + "-", ext
+}
+
+func (r *rotateFile) mill() {
+	r.startMill.Do(func() {
+		go r.millLoop()
+	})
+	select {
+	case r.millCh <- struct{}{}:
+	default:
+	}
+}
+
+func (r *rotateFile) millLoop() {
+	defer close(r.millDone)
+	for {
+		select {
+		case <-r.millCh:
+			if err := r.millRun(); err != nil {
+				log.Warn("clean up rotated files of %s error: %v", r.filename, err)
+			}
+		case <-r.stopMill:
+			return
+		}
+	}
+}
+
+type backup struct {
+	path       string
+	timestamp  time.Time
+	compressed bool
+}
+
+// millRun removes the backups beyond MaxBackups or older than MaxAge, then compresses the rest
+func (r *rotateFile) millRun() error {
+	if r.opt.MaxBackups == 0 && r.opt.MaxAge == 0 && !r.opt.Compress {
+		return nil
+	}
+
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+
+	var remove, keep []backup
+	if r.opt.MaxBackups > 0 && len(backups) > r.opt.MaxBackups {
+		// backups with the same timestamp are counted once, it should be the original and its compressed one
+		seen := make(map[time.Time]struct{})
+		for _, b := range backups {
+			seen[b.timestamp] = struct{}{}
+			if len(seen) > r.opt.MaxBackups {
+				remove = append(remove, b)
+				continue
+			}
+			keep = append(keep, b)
+		}
+		backups = keep
+		keep = nil
+	}
+	if r.opt.MaxAge > 0 {
+		cutoff := currentTime().Add(-time.Duration(r.opt.MaxAge) * 24 * time.Hour)
+		for _, b := range backups {
+			if b.timestamp.Before(cutoff) {
+				remove = append(remove, b)
+				continue
+			}
+			keep = append(keep, b)
+		}
+		backups = keep
+	}
+
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			log.Warn("remove rotated file %s error: %v", b.path, err)
+		}
+	}
+
+	if !r.opt.Compress {
+		return nil
+	}
+	compressed := make(map[time.Time]struct{})
+	for _, b := range backups {
+		if b.compressed {
+			compressed[b.timestamp] = struct{}{}
+		}
+	}
+	for _, b := range backups {
+		if _, ok := compressed[b.timestamp]; ok || b.compressed {
+			continue
+		}
+		if err := compressFile(b.path, b.path+compressSuffix); err != nil {
+			log.Warn("compress rotated file %s error: %v", b.path, err)
+		}
+	}
+	return nil
+}
+
+// backups returns the rotated files sorted by the timestamp in their names, newest first
+func (r *rotateFile) backups() ([]backup, error) {
+	entries, err := os.ReadDir(filepath.Dir(r.filename))
+	if err != nil {
+		return nil, err
+	}
+
+	loc := time.UTC
+	if r.opt.LocalTime {
+		loc = time.Local
+	}
+	prefix, ext := r.prefixAndExt()
+	var backups []backup
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		compressed := strings.HasSuffix(name, ext+compressSuffix)
+		trimmed := strings.TrimSuffix(name, compressSuffix)
+		if !strings.HasPrefix(trimmed, prefix) || !strings.HasSuffix(trimmed, ext) {
+			continue
+		}
+		ts := trimmed[len(prefix) : len(trimmed)-len(ext)]
+		t, err := time.ParseInLocation(backupTimeFormat, ts, loc)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{
+			path:       filepath.Join(filepath.Dir(r.filename), name),
+			timestamp:  t,
+			compressed: compressed,
+		})
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].timestamp.After(backups[j].timestamp)
+	})
+	return backups, nil
+}
+
+// compressFile gzips src to dst and removes src after dst has been synced
+func compressFile(src string, dst string) (err error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(dst)
+		}
+	}()
+
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, f); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
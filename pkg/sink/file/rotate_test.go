@@ -0,0 +1,140 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func fakeTime(t *testing.T, now *time.Time) {
+	currentTime = func() time.Time {
+		return *now
+	}
+	t.Cleanup(func() {
+		currentTime = time.Now
+	})
+}
+
+func TestRotateFile_Interval(t *testing.T) {
+	log.InitDefaultLogger()
+	dir := t.TempDir()
+	now := time.Date(2023, 5, 1, 10, 59, 0, 0, time.UTC)
+	fakeTime(t, &now)
+
+	r := newRotateFile(filepath.Join(dir, "app.log"), &Options{RotateInterval: time.Hour, Fsync: FsyncBatch})
+	_, err := r.Write([]byte("a\n"))
+	assert.NoError(t, err)
+
+	now = now.Add(30 * time.Second)
+	_, err = r.Write([]byte("b\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.log"}, listDir(t, dir))
+
+	now = time.Date(2023, 5, 1, 11, 0, 1, 0, time.UTC)
+	_, err = r.Write([]byte("c\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, r.Sync())
+	assert.NoError(t, r.Close())
+
+	assert.Equal(t, []string{"app-2023-05-01T11-00-01.000.log", "app.log"}, listDir(t, dir))
+	rotated, _ := os.ReadFile(filepath.Join(dir, "app-2023-05-01T11-00-01.000.log"))
+	assert.Equal(t, "a\nb\n", string(rotated))
+	current, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	assert.Equal(t, "c\n", string(current))
+}
+
+func TestRotateFile_SizeCompressAndBackups(t *testing.T) {
+	log.InitDefaultLogger()
+	dir := t.TempDir()
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	fakeTime(t, &now)
+
+	r := newRotateFile(filepath.Join(dir, "app.log"), &Options{MaxSize: 1, MaxBackups: 2, Compress: true})
+	chunk := make([]byte, megabyte/2+1)
+	for i := 0; i < 4; i++ {
+		now = now.Add(time.Second)
+		_, err := r.Write(chunk)
+		assert.NoError(t, err)
+	}
+
+	// every write after the first one exceeds 1MB and rotates, only the latest 2 backups are retained
+	// after the background compression
+	assert.Eventually(t, func() bool {
+		return len(listDir(t, dir)) == 3 && listDir(t, dir)[0] == "app-2023-05-01T10-00-03.000.log.gz"
+	}, 3*time.Second, 10*time.Millisecond)
+	assert.NoError(t, r.Close())
+	assert.Equal(t, []string{
+		"app-2023-05-01T10-00-03.000.log.gz",
+		"app-2023-05-01T10-00-04.000.log.gz",
+		"app.log",
+	}, listDir(t, dir))
+
+	f, err := os.Open(filepath.Join(dir, "app-2023-05-01T10-00-03.000.log.gz"))
+	assert.NoError(t, err)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	content, err := io.ReadAll(gr)
+	assert.NoError(t, err)
+	assert.Equal(t, len(chunk), len(content))
+}
+
+func TestMultiFileWriter_WriteFsync(t *testing.T) {
+	log.InitDefaultLogger()
+	dir := t.TempDir()
+	w, err := NewMultiFileWriter(&Options{
+		WorkerCount:   2,
+		IdleTimeout:   time.Minute,
+		Fsync:         FsyncBatch,
+		FlushInterval: time.Minute,
+	})
+	assert.NoError(t, err)
+	defer w.Close()
+
+	err = w.Write(
+		Message{Filename: filepath.Join(dir, "a.log"), Data: []byte("1")},
+		Message{Filename: filepath.Join(dir, "b", "b.log"), Data: []byte("2")},
+		Message{Filename: filepath.Join(dir, "a.log"), Data: []byte("3")},
+	)
+	assert.NoError(t, err)
+
+	// the data should be on the disk once Write returns, without waiting for the flush interval
+	a, _ := os.ReadFile(filepath.Join(dir, "a.log"))
+	assert.Equal(t, "1\n3\n", string(a))
+	b, _ := os.ReadFile(filepath.Join(dir, "b", "b.log"))
+	assert.Equal(t, "2\n", string(b))
+}
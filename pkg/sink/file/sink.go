@@ -101,6 +101,10 @@ func (s *Sink) Start() error {
 		LocalTime:   c.LocalTime,
 		Compress:    c.Compress,
 		IdleTimeout: 5 * time.Minute,
+
+		RotateInterval: c.RotateInterval,
+		Fsync:          c.Fsync,
+		FlushInterval:  c.FlushInterval,
 	})
 	if err != nil {
 		log.Panic("start multi file writer failed, error: %v", err)
@@ -124,6 +128,10 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 	if l == 0 {
 		return result.Success()
 	}
+	if bc, ok := s.cod.(codec.BatchCodec); ok && bc.BatchEnabled() {
+		return s.consumeBatch(bc, events)
+	}
+
 	msgs := make([]Message, 0, l)
 	for _, e := range events {
 		filename, err := s.selectFilename(e)
@@ -150,6 +158,41 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 	return result.Success()
 }
 
+// consumeBatch encodes the events of each file at once
+func (s *Sink) consumeBatch(bc codec.BatchCodec, events []api.Event) api.Result {
+	var filenames []string
+	groups := make(map[string][]api.Event)
+	for _, e := range events {
+		filename, err := s.selectFilename(e)
+		if err != nil {
+			log.Error("select filename error: %+v", err)
+			return result.Fail(err)
+		}
+		if _, ok := groups[filename]; !ok {
+			filenames = append(filenames, filename)
+		}
+		groups[filename] = append(groups[filename], e)
+	}
+
+	msgs := make([]Message, 0, len(filenames))
+	for _, filename := range filenames {
+		data, err := bc.EncodeBatch(groups[filename])
+		if err != nil {
+			log.Warn("codec batch error: %+v", err)
+			continue
+		}
+		msgs = append(msgs, Message{
+			Filename: filename,
+			Data:     data,
+		})
+	}
+	if err := s.writer.Write(msgs...); err != nil {
+		log.Error("write to file error: %v", err)
+		return result.Fail(err)
+	}
+	return result.Success()
+}
+
 func (s *Sink) selectFilename(e api.Event) (string, error) {
 	var dir string
 	headerObj := runtime.NewObject(e.Header())
@@ -18,6 +18,7 @@ package franz
 
 import (
 	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/pkg/errors"
 	"strings"
 	"time"
 )
@@ -32,6 +33,8 @@ type Config struct {
 	Balance                       string            `yaml:"balance,omitempty" default:"roundRobin"`
 	BatchSize                     int               `yaml:"batchSize,omitempty"`
 	BatchBytes                    int32             `yaml:"batchBytes,omitempty"`
+	Linger                        time.Duration     `yaml:"linger,omitempty"`
+	MaxInFlight                   int               `yaml:"maxInFlight,omitempty"` // max produce requests in flight per broker, only works when idempotent is disabled
 	RetryTimeout                  time.Duration     `yaml:"retryTimeout,omitempty"`
 	WriteTimeout                  time.Duration     `yaml:"writeTimeout,omitempty"`
 	Compression                   string            `yaml:"compression,omitempty" default:"gzip"`
@@ -39,6 +42,19 @@ type Config struct {
 	TLS                           TLS               `yaml:"tls,omitempty"`
 	Security                      map[string]string `yaml:"security,omitempty"`
 	PartitionKey                  string            `yaml:"partitionKey,omitempty"`
+	Idempotent                    *bool             `yaml:"idempotent,omitempty" default:"true"`
+	Transaction                   Transaction       `yaml:"transaction,omitempty"`
+}
+
+// Transaction produces each batch in a transaction, the batch is acked only after the transaction is committed.
+// The records of an aborted batch are invisible to the consumers with isolation.level=read_committed,
+// so the retries and restarts do not duplicate them.
+type Transaction struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// IDPrefix is joined with the node, pipeline and sink name as the transactional id, which should be stable
+	// across restarts, so that a new producer fences the stale one of the previous pipeline epoch
+	IDPrefix string        `yaml:"idPrefix,omitempty" default:"loggie"`
+	Timeout  time.Duration `yaml:"timeout,omitempty" default:"1m"`
 }
 
 type RenderTopicFail struct {
@@ -91,6 +107,14 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.MaxInFlight > 0 && (c.Idempotent == nil || *c.Idempotent) {
+		return errors.New("franz kafka sink maxInFlight requires idempotent to be disabled, idempotent producer allows at most 5 requests in flight")
+	}
+
+	if c.Transaction.Enabled && c.Idempotent != nil && !*c.Idempotent {
+		return errors.New("franz kafka sink transaction requires idempotent producer")
+	}
+
 	return nil
 }
 
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package franz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{
+			name: "idempotent by default",
+			raw:  `brokers: ["127.0.0.1:9092"]`,
+		},
+		{
+			name:    "maxInFlight with idempotent by default",
+			raw:     "brokers: [\"127.0.0.1:9092\"]\nmaxInFlight: 10",
+			wantErr: "maxInFlight requires idempotent to be disabled",
+		},
+		{
+			name:    "maxInFlight with idempotent",
+			raw:     "brokers: [\"127.0.0.1:9092\"]\nmaxInFlight: 10\nidempotent: true",
+			wantErr: "maxInFlight requires idempotent to be disabled",
+		},
+		{
+			name: "maxInFlight without idempotent",
+			raw:  "brokers: [\"127.0.0.1:9092\"]\nmaxInFlight: 10\nidempotent: false",
+		},
+		{
+			name: "transaction",
+			raw:  "brokers: [\"127.0.0.1:9092\"]\ntransaction:\n  enabled: true",
+		},
+		{
+			name:    "transaction without idempotent",
+			raw:     "brokers: [\"127.0.0.1:9092\"]\nidempotent: false\ntransaction:\n  enabled: true",
+			wantErr: "transaction requires idempotent producer",
+		},
+		{
+			name: "transaction disabled without idempotent",
+			raw:  "brokers: [\"127.0.0.1:9092\"]\nidempotent: false\ntransaction:\n  enabled: false",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := cfg.UnPackFromRaw([]byte(tt.raw), &Config{}).Defaults().Validate().Do()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
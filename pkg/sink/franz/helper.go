@@ -35,7 +35,8 @@ const (
 	Krb5KeytabAuth = 2
 )
 
-func getGroupBalancer(name string) kgo.GroupBalancer {
+// GetGroupBalancer returns nil when the balancer name is unknown
+func GetGroupBalancer(name string) kgo.GroupBalancer {
 	switch name {
 	case "roundRobin":
 		return kgo.RoundRobinBalancer()
@@ -20,7 +20,10 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"sync"
+
 	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/global"
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/core/result"
 	"github.com/loggie-io/loggie/pkg/pipeline"
@@ -39,7 +42,9 @@ func init() {
 }
 
 func makeSink(info pipeline.Info) api.Component {
-	return NewSink()
+	s := NewSink()
+	s.pipelineName = info.PipelineName
+	return s
 }
 
 type Sink struct {
@@ -47,6 +52,11 @@ type Sink struct {
 	writer *kgo.Client
 	cod    codec.Codec
 
+	pipelineName string
+	name         string
+	// txnLock serializes the transactions, the client could only have one transaction at a time
+	txnLock sync.Mutex
+
 	topicPattern        *pattern.Pattern
 	partitionKeyPattern *pattern.Pattern
 }
@@ -74,6 +84,7 @@ func (s *Sink) Type() api.Type {
 }
 
 func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
 	s.topicPattern, _ = pattern.Init(s.config.Topic)
 
 	if s.config.PartitionKey != "" {
@@ -106,6 +117,18 @@ func (s *Sink) Start() error {
 		opts = append(opts, kgo.MaxBufferedRecords(c.BatchSize))
 	}
 
+	if c.BatchBytes > 0 {
+		opts = append(opts, kgo.ProducerBatchMaxBytes(c.BatchBytes))
+	}
+
+	if c.Linger > 0 {
+		opts = append(opts, kgo.ProducerLinger(c.Linger))
+	}
+
+	if c.MaxInFlight > 0 {
+		opts = append(opts, kgo.MaxProduceRequestsInflightPerBroker(c.MaxInFlight))
+	}
+
 	if c.WriteTimeout != 0 {
 		opts = append(opts, kgo.ProduceRequestTimeout(c.WriteTimeout))
 	}
@@ -114,12 +137,22 @@ func (s *Sink) Start() error {
 		opts = append(opts, kgo.ProduceRequestTimeout(c.RetryTimeout))
 	}
 
-	balancer := getGroupBalancer(s.config.Balance)
+	balancer := GetGroupBalancer(s.config.Balance)
 
 	if balancer != nil {
 		opts = append(opts, kgo.Balancers(balancer))
 	}
 
+	if c.Idempotent != nil && !*c.Idempotent {
+		opts = append(opts, kgo.DisableIdempotentWrite())
+	}
+
+	if c.Transaction.Enabled {
+		id := s.transactionalID()
+		log.Info("franz kafka sink produces in transactions, transactional id: %s", id)
+		opts = append(opts, kgo.TransactionalID(id), kgo.TransactionTimeout(c.Transaction.Timeout))
+	}
+
 	if c.SASL.Enabled == true {
 		mch := GetMechanism(c.SASL)
 		if mch != nil {
@@ -206,6 +239,10 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 
 	ctx := context.Background()
 
+	if s.writer != nil && s.config.Transaction.Enabled {
+		return s.produceInTransaction(ctx, records)
+	}
+
 	if s.writer != nil {
 		ret := s.writer.ProduceSync(ctx, records...)
 		err := ret.FirstErr()
@@ -222,6 +259,41 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 	return result.Fail(errors.New("kafka sink writer not initialized"))
 }
 
+func (s *Sink) transactionalID() string {
+	return fmt.Sprintf("%s-%s-%s-%s", s.config.Transaction.IDPrefix, global.NodeName, s.pipelineName, s.name)
+}
+
+// produceInTransaction commits the batch in one transaction, the transaction is aborted if any record failed,
+// so the batch could be retried without duplicated records
+func (s *Sink) produceInTransaction(ctx context.Context, records []*kgo.Record) api.Result {
+	s.txnLock.Lock()
+	defer s.txnLock.Unlock()
+
+	if err := s.writer.BeginTransaction(); err != nil {
+		return result.Fail(errors.WithMessage(err, "begin kafka transaction"))
+	}
+
+	err := s.writer.ProduceSync(ctx, records...).FirstErr()
+	if err != nil {
+		if abortErr := s.writer.EndTransaction(ctx, kgo.TryAbort); abortErr != nil {
+			log.Warn("abort kafka transaction error: %v", abortErr)
+		}
+		if errors.Is(err, kerr.UnknownTopicOrPartition) && s.config.IgnoreUnknownTopicOrPartition {
+			return result.Success()
+		}
+		return result.Fail(errors.WithMessage(err, "franz produce in transaction"))
+	}
+
+	if err := s.writer.EndTransaction(ctx, kgo.TryCommit); err != nil {
+		// the transaction is aborted by the coordinator, the batch would be retried
+		if abortErr := s.writer.EndTransaction(ctx, kgo.TryAbort); abortErr != nil {
+			log.Warn("abort kafka transaction error: %v", abortErr)
+		}
+		return result.Fail(errors.WithMessage(err, "commit kafka transaction"))
+	}
+	return result.Success()
+}
+
 func (s *Sink) selectTopic(e api.Event) (string, error) {
 	return s.topicPattern.WithObject(runtime.NewObject(e.Header())).RenderWithStrict()
 }
@@ -0,0 +1,80 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gelf
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
+
+const (
+	ProtocolUDP = "udp"
+	ProtocolTCP = "tcp"
+
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZlib = "zlib"
+)
+
+type Config struct {
+	// Address is the GELF input of graylog, such as graylog:12201
+	Address  string `yaml:"address,omitempty" validate:"required,hostname_port"`
+	Protocol string `yaml:"protocol,omitempty" default:"udp" validate:"oneof=udp tcp"`
+	TLS      TLS    `yaml:"tls,omitempty"` // only for tcp
+
+	// Compression and ChunkSize are only for udp, the messages exceeding the chunk size are chunked
+	Compression string `yaml:"compression,omitempty" default:"gzip" validate:"oneof=none gzip zlib"`
+	ChunkSize   int    `yaml:"chunkSize,omitempty" default:"1420" validate:"gte=512,lte=65467"`
+
+	// Host is the source of the messages, which could be a pattern such as ${fields.hostname}, the node name is used if empty
+	Host string `yaml:"host,omitempty"`
+	// LevelKey is the key of the syslog level of the events, the value could be a number or a name such as error
+	LevelKey string `yaml:"levelKey,omitempty"`
+	Level    int    `yaml:"level,omitempty" default:"6" validate:"gte=0,lte=7"` // used when the level is missing
+	// Fields are the additional fields, the keys are the field names and the values are the keys of the events.
+	// All the header fields are flattened into the additional fields if empty, such as _fields_namespace
+	Fields map[string]string `yaml:"fields,omitempty"`
+
+	Timeout time.Duration `yaml:"timeout,omitempty" default:"10s"`
+}
+
+type TLS struct {
+	Enabled            bool   `yaml:"enabled,omitempty"`
+	CaCertFiles        string `yaml:"caCertFiles,omitempty"`
+	ClientCertFile     string `yaml:"clientCertFile,omitempty"`
+	ClientKeyFile      string `yaml:"clientKeyFile,omitempty"`
+	ServerName         string `yaml:"serverName,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	if c.TLS.Enabled && c.Protocol != ProtocolTCP {
+		return errors.New("gelf sink tls is only supported by tcp")
+	}
+	if (c.TLS.ClientCertFile == "") != (c.TLS.ClientKeyFile == "") {
+		return errors.New("clientCertFile and clientKeyFile should be set together")
+	}
+	for name := range c.Fields {
+		if !validFieldName(name) || name == "id" || name == "_id" {
+			return errors.Errorf("gelf sink additional field name %s is invalid", name)
+		}
+	}
+	return pattern.Validate(c.Host)
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	gelfVersion = "1.1"
+
+	chunkHeaderSize = 12
+	maxChunks       = 128
+)
+
+var chunkMagic = []byte{0x1e, 0x0f}
+
+// syslogLevels are the names of the syslog severities
+var syslogLevels = map[string]int{
+	"emerg":     0,
+	"emergency": 0,
+	"panic":     0,
+	"alert":     1,
+	"crit":      2,
+	"critical":  2,
+	"fatal":     2,
+	"err":       3,
+	"error":     3,
+	"warn":      4,
+	"warning":   4,
+	"notice":    5,
+	"info":      6,
+	"debug":     7,
+	"trace":     7,
+}
+
+func validFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '_' && r != '.' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldName converts a key to an additional field name, such as _fields_namespace
+func fieldName(name string) string {
+	var sb strings.Builder
+	sb.WriteByte('_')
+	for _, r := range strings.TrimPrefix(name, "_") {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
+
+// fieldValue returns the value as a string or a number, which are the only types allowed by GELF
+func fieldValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case []byte:
+		return string(val)
+	}
+	return fmt.Sprint(v)
+}
+
+// flatten adds the nested header fields as the additional fields
+func flatten(prefix string, header map[string]interface{}, out map[string]interface{}) {
+	for k, v := range header {
+		key := k
+		if prefix != "" {
+			key = prefix + "_" + k
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			flatten(key, m, out)
+			continue
+		}
+		if v == nil {
+			continue
+		}
+		name := fieldName(key)
+		if name == "_id" {
+			continue
+		}
+		out[name] = fieldValue(v)
+	}
+}
+
+func parseLevel(v interface{}) (int, bool) {
+	switch val := v.(type) {
+	case int:
+		return val, val >= 0 && val <= 7
+	case int64:
+		return int(val), val >= 0 && val <= 7
+	case float64:
+		return int(val), val >= 0 && val <= 7
+	case string:
+		if l, ok := syslogLevels[strings.ToLower(val)]; ok {
+			return l, true
+		}
+		l, err := strconv.Atoi(val)
+		return l, err == nil && l >= 0 && l <= 7
+	}
+	return 0, false
+}
+
+func compress(data []byte, compression string) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch compression {
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionZlib:
+		w = zlib.NewWriter(&buf)
+	default:
+		return data, nil
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// chunk splits the message into the datagrams of udp, each chunk has a header of the magic bytes,
+// the message id, the sequence number and the sequence count
+func chunk(message []byte, chunkSize int) ([][]byte, error) {
+	if len(message) <= chunkSize {
+		return [][]byte{message}, nil
+	}
+
+	payloadSize := chunkSize - chunkHeaderSize
+	count := (len(message) + payloadSize - 1) / payloadSize
+	if count > maxChunks {
+		return nil, errors.Errorf("message of %d bytes needs %d chunks, exceeds the limit %d", len(message), count, maxChunks)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * payloadSize
+		if end > len(message) {
+			end = len(message)
+		}
+		c := make([]byte, 0, chunkHeaderSize+end-i*payloadSize)
+		c = append(c, chunkMagic...)
+		c = append(c, id...)
+		c = append(c, byte(i), byte(count))
+		c = append(c, message[i*payloadSize:end]...)
+		chunks = append(chunks, c)
+	}
+	return chunks, nil
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gelf
+
+import (
+	"bufio"
+	"crypto/tls"
+	stdjson "encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	eventer "github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/global"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const Type = "gelf"
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink()
+}
+
+type Sink struct {
+	name   string
+	config *Config
+
+	hostPattern *pattern.Pattern
+	node        string
+	tlsConfig   *tls.Config
+
+	lock sync.Mutex
+	conn net.Conn
+}
+
+func NewSink() *Sink {
+	return &Sink{
+		config: &Config{},
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.hostPattern, _ = pattern.Init(s.config.Host)
+	s.node = global.NodeName
+	if s.node == "" {
+		s.node, _ = os.Hostname()
+	}
+
+	t := s.config.TLS
+	if t.Enabled {
+		tlsConfig, err := netutils.NewTLSConfig(t.CaCertFiles, t.ClientCertFile, t.ClientKeyFile, t.InsecureSkipVerify)
+		if err != nil {
+			return err
+		}
+		tlsConfig.ServerName = t.ServerName
+		s.tlsConfig = tlsConfig
+	}
+	return nil
+}
+
+func (s *Sink) Start() error {
+	log.Info("%s start, address: %s://%s", s.String(), s.config.Protocol, s.config.Address)
+	return nil
+}
+
+func (s *Sink) Stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.close()
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return result.Fail(errors.WithMessagef(err, "connect to %s", s.config.Address))
+		}
+	}
+	if err := s.write(events); err != nil {
+		// reconnect by the next batch
+		s.close()
+		return result.Fail(errors.WithMessagef(err, "send gelf messages to %s", s.config.Address))
+	}
+	return result.Success()
+}
+
+func (s *Sink) connect() error {
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	var conn net.Conn
+	var err error
+	if s.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, ProtocolTCP, s.config.Address, s.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(s.config.Protocol, s.config.Address)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *Sink) close() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+func (s *Sink) write(events []api.Event) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.config.Timeout)); err != nil {
+		return err
+	}
+
+	// the messages are delimited by the null byte over tcp
+	var w *bufio.Writer
+	if s.config.Protocol == ProtocolTCP {
+		w = bufio.NewWriter(s.conn)
+	}
+	for _, e := range events {
+		message, err := s.encode(e)
+		if err != nil {
+			log.Warn("[%s] encode event error: %v", s.name, err)
+			continue
+		}
+
+		if w != nil {
+			if _, err := w.Write(append(message, 0)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.writeDatagrams(message); err != nil {
+			return err
+		}
+	}
+	if w != nil {
+		return w.Flush()
+	}
+	return nil
+}
+
+func (s *Sink) writeDatagrams(message []byte) error {
+	compressed, err := compress(message, s.config.Compression)
+	if err != nil {
+		return err
+	}
+	chunks, err := chunk(compressed, s.config.ChunkSize)
+	if err != nil {
+		// the message could never be sent
+		log.Warn("[%s] drop gelf message: %v", s.name, err)
+		return nil
+	}
+	for _, c := range chunks {
+		if _, err := s.conn.Write(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sink) encode(e api.Event) ([]byte, error) {
+	header := e.Header()
+	obj := runtime.NewObject(header)
+
+	host, err := s.hostPattern.WithObject(obj).Render()
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		host = s.node
+	}
+	shortMessage := strings.TrimSpace(string(e.Body()))
+	if shortMessage == "" {
+		// short_message is required
+		shortMessage = "-"
+	}
+
+	level := s.config.Level
+	if s.config.LevelKey != "" {
+		if l, ok := parseLevel(obj.GetPath(s.config.LevelKey).Value()); ok {
+			level = l
+		}
+	}
+
+	message := map[string]interface{}{}
+	if len(s.config.Fields) == 0 {
+		flatten("", withoutPrivate(header), message)
+	} else {
+		for name, key := range s.config.Fields {
+			if v := obj.GetPath(key).Value(); v != nil {
+				message[fieldName(name)] = fieldValue(v)
+			}
+		}
+	}
+	message["version"] = gelfVersion
+	message["host"] = host
+	message["short_message"] = shortMessage
+	message["timestamp"] = stdjson.Number(strconv.FormatFloat(float64(timestamp(e).UnixMilli())/1000, 'f', 3, 64))
+	message["level"] = level
+	return json.Marshal(message)
+}
+
+func withoutPrivate(header map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(header))
+	for k, v := range header {
+		if strings.HasPrefix(k, eventer.PrivateKeyPrefix) {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func timestamp(e api.Event) time.Time {
+	if e.Meta() != nil {
+		if v, ok := e.Meta().Get(eventer.SystemProductTimeKey); ok {
+			if t, ok := v.(time.Time); ok {
+				return t
+			}
+		}
+	}
+	return time.Now()
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gelf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		want   string
+	}{
+		{
+			name:   "header flattened",
+			config: &Config{Host: "node-1", Level: 6},
+			want:   `{"version":"1.1","host":"node-1","short_message":"hello","timestamp":1700000000.123,"level":6,"_fields_namespace":"default","_fields_level":"error","_offset":10}`,
+		},
+		{
+			name:   "fields and level",
+			config: &Config{Host: "${fields.namespace}-host", LevelKey: "fields.level", Level: 6, Fields: map[string]string{"ns": "fields.namespace", "pod": "fields.pod"}},
+			want:   `{"version":"1.1","host":"default-host","short_message":"hello","timestamp":1700000000.123,"level":3,"_ns":"default"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSink()
+			s.config = tt.config
+			assert.NoError(t, s.Init(context.NewContext("gelf", Type, api.SINK, nil)))
+
+			e := event.NewEvent(map[string]interface{}{
+				"fields": map[string]interface{}{"namespace": "default", "level": "error"},
+				"offset": 10,
+			}, []byte(" hello\n"))
+			meta := event.NewDefaultMeta()
+			meta.Set(event.SystemProductTimeKey, time.UnixMilli(1700000000123))
+			e.Fill(meta, e.Header(), e.Body())
+
+			data, err := s.encode(e)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(data))
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		want   int
+		wantOk bool
+	}{
+		{value: "error", want: 3, wantOk: true},
+		{value: "WARN", want: 4, wantOk: true},
+		{value: "5", want: 5, wantOk: true},
+		{value: float64(2), want: 2, wantOk: true},
+		{value: 8, want: 8},
+		{value: "unknown"},
+		{value: nil},
+	}
+	for _, tt := range tests {
+		level, ok := parseLevel(tt.value)
+		assert.Equal(t, tt.wantOk, ok, tt.value)
+		if ok {
+			assert.Equal(t, tt.want, level, tt.value)
+		}
+	}
+}
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		wantChunks int
+		wantErr    bool
+	}{
+		{name: "not chunked", size: 512, wantChunks: 1},
+		{name: "chunked", size: 2000, wantChunks: 4},
+		{name: "exceeds max chunks", size: 600 * maxChunks, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := []byte(strings.Repeat("x", tt.size))
+			chunks, err := chunk(message, 512)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, chunks, tt.wantChunks)
+			if tt.wantChunks == 1 {
+				assert.Equal(t, message, chunks[0])
+				return
+			}
+
+			var joined []byte
+			for i, c := range chunks {
+				assert.LessOrEqual(t, len(c), 512)
+				assert.Equal(t, chunkMagic, c[:2])
+				// the chunks share the message id
+				assert.Equal(t, chunks[0][2:10], c[2:10])
+				assert.Equal(t, []byte{byte(i), byte(tt.wantChunks)}, c[10:chunkHeaderSize])
+				joined = append(joined, c[chunkHeaderSize:]...)
+			}
+			assert.True(t, bytes.Equal(message, joined))
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr bool
+	}{
+		{name: "tcp with tls", config: &Config{Protocol: ProtocolTCP, TLS: TLS{Enabled: true}}},
+		{name: "udp with tls", config: &Config{Protocol: ProtocolUDP, TLS: TLS{Enabled: true}}, wantErr: true},
+		{name: "client cert without key", config: &Config{Protocol: ProtocolTCP, TLS: TLS{ClientCertFile: "tls.crt"}}, wantErr: true},
+		{name: "reserved field", config: &Config{Fields: map[string]string{"_id": "id"}}, wantErr: true},
+		{name: "invalid field", config: &Config{Fields: map[string]string{"a b": "a"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, tt.config.Validate() != nil)
+		})
+	}
+}
@@ -16,11 +16,57 @@ limitations under the License.
 
 package grpc
 
-import "time"
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/envelope"
+)
 
 type Config struct {
 	Host          string        `yaml:"host,omitempty" validate:"required"`
 	LoadBalance   string        `yaml:"loadBalance,omitempty" default:"round_robin"`
 	Timeout       time.Duration `yaml:"timeout,omitempty" default:"30s"`
 	GrpcHeaderKey string        `yaml:"grpcHeaderKey,omitempty"`
+	// Compression compresses each stream, could be none, gzip or zstd
+	Compression string `yaml:"compression,omitempty" default:"none" validate:"oneof=none gzip zstd"`
+	TLS         TLS    `yaml:"tls,omitempty"`
+	// Envelope is offered to the grpc sources and negotiated down to the one they support, 2 by default
+	Envelope envelope.Config `yaml:"envelope,omitempty"`
+}
+
+// TLS connects to the grpc source with tls, the client certificate is sent for mutual tls
+// and reloaded when rotated
+type TLS struct {
+	Enabled            bool          `yaml:"enabled,omitempty"`
+	CaCertFiles        string        `yaml:"caCertFiles,omitempty"`
+	ClientCertFile     string        `yaml:"clientCertFile,omitempty"`
+	ClientKeyFile      string        `yaml:"clientKeyFile,omitempty"`
+	ServerName         string        `yaml:"serverName,omitempty"`
+	InsecureSkipVerify bool          `yaml:"insecureSkipVerify,omitempty"`
+	ReloadInterval     time.Duration `yaml:"reloadInterval,omitempty" default:"1m"`
+}
+
+func (c *Config) SetDefaults() {
+	if c.Envelope.Version == 0 {
+		c.Envelope.Version = envelope.LatestVersion
+	}
+}
+
+func (c *Config) Validate() error {
+	if err := c.Envelope.Validate(); err != nil {
+		return err
+	}
+	return c.TLS.Validate()
+}
+
+func (t *TLS) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+	if (t.ClientCertFile == "") != (t.ClientKeyFile == "") {
+		return errors.New("clientCertFile and clientKeyFile should be set together")
+	}
+	return nil
 }
@@ -18,23 +18,41 @@ package grpc
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/global"
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/core/result"
 	"github.com/loggie-io/loggie/pkg/pipeline"
 	pb "github.com/loggie-io/loggie/pkg/sink/grpc/pb"
+	"github.com/loggie-io/loggie/pkg/util/envelope"
+	grpcutil "github.com/loggie-io/loggie/pkg/util/grpc"
 	"github.com/loggie-io/loggie/pkg/util/json"
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+	"github.com/loggie-io/loggie/pkg/util/priority"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/resolver"
 )
 
-const Type = "grpc"
+const (
+	Type = "grpc"
+
+	fleetMetadataPrefix = "loggie-fleet-"
+
+	// batchSeqKey keeps the sequence number in the batch meta, so the retries of a batch are sent with the same one
+	batchSeqKey = "loggie-system-grpc-seq"
+)
 
 func init() {
 	pipeline.Register(api.SINK, Type, makeSink)
@@ -54,6 +72,11 @@ type Sink struct {
 	timeout     time.Duration
 	logClient   pb.LogServiceClient
 	conn        *grpc.ClientConn
+	sessionId   string
+	seq         uint64
+	offer       envelope.Offer
+	// negotiated is the envelope.Offer agreed by the last response, version 1 before any response
+	negotiated atomic.Value
 }
 
 func NewSink(info pipeline.Info) *Sink {
@@ -87,16 +110,23 @@ func (s *Sink) Init(context api.Context) error {
 	s.hosts = strings.Split(hosts, ",")
 	s.loadBalance = s.config.LoadBalance
 	s.timeout = s.config.Timeout
+	s.offer = s.config.Envelope.Offer()
+	s.negotiated.Store(envelope.Offer{Version: envelope.Version1})
 	return nil
 }
 
 func (s *Sink) Start() error {
 	// register grpc name resolver
 	resolver.Register(NewBuilder(s.hosts))
+	creds, err := s.transportCredentials()
+	if err != nil {
+		log.Error("grpc client load tls config error: %v", err)
+		return err
+	}
 	// init grpc client
 	conn, err := grpc.Dial(
 		fmt.Sprintf("%s:///%s", collectorScheme, collectorServiceName),
-		grpc.WithInsecure(),
+		creds,
 		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig": [{"%s":{}}]}`, s.loadBalance)),
 		grpc.WithInitialWindowSize(256),
 	)
@@ -106,10 +136,31 @@ func (s *Sink) Start() error {
 	}
 	s.conn = conn
 	s.logClient = pb.NewLogServiceClient(conn)
-	log.Info("%s start, hosts: %v, load balance: %s", s.String(), s.hosts, s.loadBalance)
+	s.sessionId = newSessionId()
+	log.Info("%s start, hosts: %v, load balance: %s, session: %s", s.String(), s.hosts, s.loadBalance, s.sessionId)
 	return nil
 }
 
+func (s *Sink) transportCredentials() (grpc.DialOption, error) {
+	t := s.config.TLS
+	if !t.Enabled {
+		return grpc.WithInsecure(), nil
+	}
+	tlsConfig, err := netutils.NewTLSConfig(t.CaCertFiles, "", "", t.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ServerName = t.ServerName
+	if t.ClientCertFile != "" {
+		reloader, err := netutils.NewCertReloader(t.ClientCertFile, t.ClientKeyFile, "", t.ReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = reloader.GetClientCertificate
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), nil
+}
+
 func (s *Sink) Stop() {
 	if s.conn != nil {
 		_ = s.conn.Close()
@@ -117,56 +168,33 @@ func (s *Sink) Stop() {
 }
 
 func (s *Sink) Consume(batch api.Batch) api.Result {
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	events := batch.Events()
+	logMsgs := make([]*pb.LogMsg, 0, len(events))
+	var levels []priority.Level
+	for _, e := range events {
+		logMsg, err := s.logMsg(e)
+		if err != nil {
+			log.Warn("Marshal event header error: %s", err)
+			continue
+		}
+		logMsgs = append(logMsgs, logMsg)
+		levels = append(levels, priority.Of(e))
+	}
+
+	env := s.envelope(logMsgs, levels)
+	ctx, cancel := context.WithTimeout(s.streamContext(s.batchSeq(batch), env), s.timeout)
 	defer cancel()
 
-	stream, err := s.logClient.LogStream(ctx, grpc.WaitForReady(true))
+	opts := []grpc.CallOption{grpc.WaitForReady(true)}
+	if s.config.Compression != grpcutil.CompressionNone {
+		opts = append(opts, grpc.UseCompressor(s.config.Compression))
+	}
+	stream, err := s.logClient.LogStream(ctx, opts...)
 	if err != nil {
 		return result.Fail(err)
 	}
 
-	events := batch.Events()
-	for _, e := range events {
-		logMsg := &pb.LogMsg{
-			RawLog: e.Body(),
-		}
-		eHeader := e.Header()
-
-		// structured log data
-		logBody, ok := eHeader["systemLogBody"]
-		if ok {
-			delete(eHeader, "systemLogBody")
-			lb, covert := logBody.(map[string]string)
-			if covert {
-				lbl := len(lb)
-				if lbl > 0 {
-					logBodyByte := make(map[string][]byte, lbl)
-					for k, v := range lb {
-						logBodyByte[k] = []byte(v)
-					}
-					logMsg.LogBody = logBodyByte
-					logMsg.IsSplit = true
-				}
-			}
-		}
-
-		// header & packedHeader
-		grpcHeaderKey := s.config.GrpcHeaderKey
-		if grpcHeaderKey != "" {
-			grpcHeader, ok := eHeader[grpcHeaderKey].(map[string][]byte)
-			if ok {
-				logMsg.Header = grpcHeader
-			} else {
-				log.Error("grpc header must be map[string][]byte: %v", grpcHeader)
-			}
-		} else {
-			packedHeader, jsonErr := json.Marshal(eHeader)
-			if jsonErr != nil {
-				log.Warn("Marshal event header error: %s", err)
-				continue
-			}
-			logMsg.PackedHeader = packedHeader
-		}
+	for _, logMsg := range logMsgs {
 		err = stream.Send(logMsg)
 		if err != nil && errors.Is(err, io.EOF) {
 			ls := logMsg.String()
@@ -175,13 +203,132 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 		}
 	}
 	logResp, err := stream.CloseAndRecv()
+	if header, headerErr := stream.Header(); headerErr == nil {
+		s.negotiate(header)
+	}
 	if err != nil {
 		log.Error("%s => get grpc response error: %v", s.String(), err)
 		return result.Fail(err)
 	}
 	if !logResp.Success {
 		log.Error("%s => get grpc response error: %v", s.String(), logResp.ErrorMsg)
-		return result.Fail(err)
+		return result.Fail(errors.New(logResp.ErrorMsg))
 	}
 	return result.Success()
 }
+
+func (s *Sink) logMsg(e api.Event) (*pb.LogMsg, error) {
+	logMsg := &pb.LogMsg{
+		RawLog: e.Body(),
+	}
+	eHeader := e.Header()
+
+	// structured log data
+	logBody, ok := eHeader["systemLogBody"]
+	if ok {
+		delete(eHeader, "systemLogBody")
+		lb, covert := logBody.(map[string]string)
+		if covert {
+			lbl := len(lb)
+			if lbl > 0 {
+				logBodyByte := make(map[string][]byte, lbl)
+				for k, v := range lb {
+					logBodyByte[k] = []byte(v)
+				}
+				logMsg.LogBody = logBodyByte
+				logMsg.IsSplit = true
+			}
+		}
+	}
+
+	// header & packedHeader
+	grpcHeaderKey := s.config.GrpcHeaderKey
+	if grpcHeaderKey != "" {
+		grpcHeader, ok := eHeader[grpcHeaderKey].(map[string][]byte)
+		if ok {
+			logMsg.Header = grpcHeader
+		} else {
+			log.Error("grpc header must be map[string][]byte: %v", grpcHeader)
+		}
+		return logMsg, nil
+	}
+	packedHeader, err := json.Marshal(eHeader)
+	if err != nil {
+		return nil, err
+	}
+	logMsg.PackedHeader = packedHeader
+	return logMsg, nil
+}
+
+// envelope fills the capabilities negotiated with the grpc sources, the checksum covers the raw logs and the packed headers
+func (s *Sink) envelope(logMsgs []*pb.LogMsg, levels []priority.Level) *envelope.Envelope {
+	env := &envelope.Envelope{Offer: s.negotiated.Load().(envelope.Offer)}
+	if env.Has(envelope.CapabilityChecksum) {
+		sum := envelope.NewChecksum()
+		for _, logMsg := range logMsgs {
+			sum.Add(logMsg.RawLog, logMsg.PackedHeader)
+		}
+		env.Checksum = sum.String()
+	}
+	if env.Has(envelope.CapabilityPriority) {
+		env.Priorities = envelope.EncodePriorities(levels)
+	}
+	return env
+}
+
+// negotiate agrees on the envelope with the one accepted by the grpc source,
+// the older sources reply nothing, so the batches sent afterwards fall back to version 1
+func (s *Sink) negotiate(header metadata.MD) {
+	agreed := envelope.Offer{Version: envelope.Version1}
+	if accept := header.Get(envelope.AcceptKey); len(accept) > 0 {
+		remote, err := envelope.ParseOffer(accept[0])
+		if err != nil {
+			log.Warn("%s => parse accepted envelope error: %v", s.String(), err)
+		} else {
+			agreed = envelope.Negotiate(s.offer, remote)
+		}
+	}
+	if prev := s.negotiated.Swap(agreed).(envelope.Offer); prev.String() != agreed.String() {
+		log.Info("%s => envelope negotiated from %s to %s", s.String(), prev, agreed)
+	}
+}
+
+// streamContext carries the session id, the batch sequence number, the envelope and the fleet labels in the metadata of the stream,
+// such as loggie-fleet-cluster: prod
+func (s *Sink) streamContext(seq uint64, env *envelope.Envelope) context.Context {
+	md := metadata.MD{}
+	md.Set(grpcutil.SessionMetadataKey, s.sessionId)
+	md.Set(grpcutil.BatchSeqMetadataKey, strconv.FormatUint(seq, 10))
+	if s.offer.Version > envelope.Version1 {
+		md.Set(envelope.OfferKey, s.offer.String())
+		env.Set(func(key string, value string) {
+			md.Set(key, value)
+		})
+	}
+	for k, v := range global.FleetLabels() {
+		md.Set(fleetMetadataPrefix+strings.ToLower(k), v)
+	}
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+// batchSeq returns the sequence number of the batch in this session, it is assigned on the first attempt
+func (s *Sink) batchSeq(batch api.Batch) uint64 {
+	meta := batch.Meta()
+	if meta == nil {
+		return atomic.AddUint64(&s.seq, 1)
+	}
+	if seq, ok := meta[batchSeqKey].(uint64); ok {
+		return seq
+	}
+	seq := atomic.AddUint64(&s.seq, 1)
+	meta[batchSeqKey] = seq
+	return seq
+}
+
+func newSessionId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
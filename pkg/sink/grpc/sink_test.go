@@ -0,0 +1,77 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/global"
+	"github.com/loggie-io/loggie/pkg/util/envelope"
+	grpcutil "github.com/loggie-io/loggie/pkg/util/grpc"
+)
+
+func TestSink_batchSeq(t *testing.T) {
+	s := &Sink{}
+	first := batch.NewBatchWithEvents([]api.Event{event.NewEvent(nil, []byte("a"))})
+	second := batch.NewBatchWithEvents([]api.Event{event.NewEvent(nil, []byte("b"))})
+
+	// the retries of a batch are sent with the sequence number kept in the batch meta
+	assert.Equal(t, uint64(1), s.batchSeq(first))
+	assert.Equal(t, uint64(2), s.batchSeq(second))
+	assert.Equal(t, uint64(1), s.batchSeq(first))
+	assert.Equal(t, uint64(1), first.Meta()[batchSeqKey])
+	assert.Equal(t, uint64(2), s.batchSeq(second))
+}
+
+func TestSink_streamContext(t *testing.T) {
+	tests := []struct {
+		name  string
+		fleet global.FleetConfig
+		want  metadata.MD
+	}{
+		{
+			name: "without fleet labels",
+			want: metadata.Pairs(grpcutil.SessionMetadataKey, "s1", grpcutil.BatchSeqMetadataKey, "3"),
+		},
+		{
+			name: "fleet labels",
+			fleet: global.FleetConfig{
+				Cluster: "prod",
+				Team:    "infra",
+				Labels:  map[string]string{"IDC": "bj", "empty": ""},
+			},
+			want: metadata.Pairs(grpcutil.SessionMetadataKey, "s1", grpcutil.BatchSeqMetadataKey, "3",
+				"loggie-fleet-cluster", "prod", "loggie-fleet-team", "infra", "loggie-fleet-idc", "bj"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			global.SetFleetLabels(tt.fleet)
+			defer global.SetFleetLabels(global.FleetConfig{})
+
+			s := &Sink{sessionId: "s1", offer: envelope.Offer{Version: envelope.Version1}}
+			md, _ := metadata.FromOutgoingContext(s.streamContext(3, &envelope.Envelope{}))
+			assert.Equal(t, tt.want, md)
+		})
+	}
+}
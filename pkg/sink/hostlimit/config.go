@@ -0,0 +1,46 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostlimit
+
+import "time"
+
+// Config limits the requests sent to each destination host of http based sinks,
+// so that a single agent cannot overwhelm a small receiving service
+type Config struct {
+	// QPS is the requests per second allowed for each host, 0 means unlimited
+	QPS float64 `yaml:"qps,omitempty" validate:"gte=0"`
+	// Burst is the maximum requests sent at once within the qps limit, defaults to qps rounded up
+	Burst int `yaml:"burst,omitempty" validate:"gte=0"`
+	// MaxInFlight is the maximum concurrent requests to each host, 0 means unlimited
+	MaxInFlight    int           `yaml:"maxInFlight,omitempty" validate:"gte=0"`
+	ReportInterval time.Duration `yaml:"reportInterval,omitempty" default:"10s" validate:"gt=0"`
+}
+
+func (c *Config) Enabled() bool {
+	return c.QPS > 0 || c.MaxInFlight > 0
+}
+
+func (c *Config) burst() int {
+	if c.Burst > 0 {
+		return c.Burst
+	}
+	b := int(c.QPS)
+	if float64(b) < c.QPS {
+		b++
+	}
+	return b
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostlimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/loggie-io/loggie/pkg/eventbus"
+)
+
+// Transport is a http.RoundTripper which limits the qps and in-flight requests of each destination host.
+// Requests over the limits are queued until they are allowed or their context is done.
+type Transport struct {
+	base         http.RoundTripper
+	config       *Config
+	pipelineName string
+	sinkName     string
+
+	lock  sync.Mutex
+	hosts map[string]*host
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+type host struct {
+	limiter *rate.Limiter
+	slots   chan struct{}
+
+	inFlight  int64
+	waiting   int64
+	throttled int64
+	waitNanos int64
+}
+
+// NewTransport wraps the base transport, http.DefaultTransport is used when base is nil.
+// The usage of each host is reported to eventbus until Stop is called.
+func NewTransport(base http.RoundTripper, config *Config, pipelineName string, sinkName string) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{
+		base:         base,
+		config:       config,
+		pipelineName: pipelineName,
+		sinkName:     sinkName,
+		hosts:        make(map[string]*host),
+		done:         make(chan struct{}),
+	}
+	go t.report()
+	return t
+}
+
+func (t *Transport) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.done)
+	})
+}
+
+func (t *Transport) host(name string) *host {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	h, ok := t.hosts[name]
+	if ok {
+		return h
+	}
+	h = &host{}
+	if t.config.QPS > 0 {
+		h.limiter = rate.NewLimiter(rate.Limit(t.config.QPS), t.config.burst())
+	}
+	if t.config.MaxInFlight > 0 {
+		h.slots = make(chan struct{}, t.config.MaxInFlight)
+	}
+	t.hosts[name] = h
+	return h
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := t.host(req.URL.Host)
+	if err := h.acquire(req.Context()); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		h.release()
+		return nil, err
+	}
+	// the request is in flight until its response body is closed
+	resp.Body = &releaseBody{ReadCloser: resp.Body, host: h}
+	return resp, nil
+}
+
+func (h *host) acquire(ctx context.Context) error {
+	start := time.Now()
+	throttled := false
+	atomic.AddInt64(&h.waiting, 1)
+	defer func() {
+		atomic.AddInt64(&h.waiting, -1)
+		if throttled {
+			atomic.AddInt64(&h.throttled, 1)
+			atomic.AddInt64(&h.waitNanos, int64(time.Since(start)))
+		}
+	}()
+
+	if h.limiter != nil {
+		r := h.limiter.Reserve()
+		if delay := r.Delay(); delay > 0 {
+			throttled = true
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				r.Cancel()
+				return ctx.Err()
+			}
+		}
+	}
+
+	if h.slots != nil {
+		select {
+		case h.slots <- struct{}{}:
+		default:
+			throttled = true
+			select {
+			case h.slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	atomic.AddInt64(&h.inFlight, 1)
+	return nil
+}
+
+func (h *host) release() {
+	atomic.AddInt64(&h.inFlight, -1)
+	if h.slots != nil {
+		<-h.slots
+	}
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	host *host
+	once sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.host.release)
+	return err
+}
+
+func (t *Transport) report() {
+	tick := time.NewTicker(t.config.ReportInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-tick.C:
+			eventbus.PublishOrDrop(eventbus.HostLimitTopic, eventbus.HostLimitMetricData{
+				PipelineName: t.pipelineName,
+				SinkName:     t.sinkName,
+				Hosts:        t.snapshot(),
+			})
+		}
+	}
+}
+
+// snapshot returns the usage of each host, the throttled counters are reset for the next period
+func (t *Transport) snapshot() []eventbus.HostLimitUsage {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	usages := make([]eventbus.HostLimitUsage, 0, len(t.hosts))
+	for name, h := range t.hosts {
+		usages = append(usages, eventbus.HostLimitUsage{
+			Host:          name,
+			InFlight:      atomic.LoadInt64(&h.inFlight),
+			Waiting:       atomic.LoadInt64(&h.waiting),
+			Throttled:     atomic.SwapInt64(&h.throttled, 0),
+			WaitTime:      time.Duration(atomic.SwapInt64(&h.waitNanos, 0)),
+			LimitQPS:      t.config.QPS,
+			LimitInFlight: t.config.MaxInFlight,
+		})
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Host < usages[j].Host
+	})
+	return usages
+}
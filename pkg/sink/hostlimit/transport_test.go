@@ -0,0 +1,92 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostlimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransportMaxInFlight(t *testing.T) {
+	var current, peak int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&current, 1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt64(&current, -1)
+	}))
+	defer srv.Close()
+
+	tr := NewTransport(nil, &Config{MaxInFlight: 2, ReportInterval: time.Hour}, "p", "s")
+	defer tr.Stop()
+	cli := &http.Client{Transport: tr}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := cli.Get(srv.URL)
+			if assert.NoError(t, err) {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, atomic.LoadInt64(&peak), int64(2))
+	usages := tr.snapshot()
+	assert.Len(t, usages, 1)
+	assert.Equal(t, int64(0), usages[0].InFlight)
+	assert.Equal(t, int64(0), usages[0].Waiting)
+	assert.Greater(t, usages[0].Throttled, int64(0))
+	assert.Equal(t, int64(0), tr.snapshot()[0].Throttled)
+}
+
+func TestTransportQPS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	tr := NewTransport(nil, &Config{QPS: 1, ReportInterval: time.Hour}, "p", "s")
+	defer tr.Stop()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := tr.RoundTrip(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	// the burst is used up, the next request waits until its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = tr.RoundTrip(req.WithContext(ctx))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int64(1), tr.snapshot()[0].Throttled)
+}
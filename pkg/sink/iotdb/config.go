@@ -0,0 +1,108 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iotdb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
+
+const (
+	TypeBoolean = "BOOLEAN"
+	TypeInt32   = "INT32"
+	TypeInt64   = "INT64"
+	TypeFloat   = "FLOAT"
+	TypeDouble  = "DOUBLE"
+	TypeText    = "TEXT"
+
+	PrecisionMs = "ms"
+	PrecisionUs = "us"
+	PrecisionNs = "ns"
+)
+
+type Config struct {
+	URL      string `yaml:"url,omitempty" validate:"required"` // the address of the REST service, such as http://127.0.0.1:18080
+	Username string `yaml:"username,omitempty" default:"root"`
+	Password string `yaml:"password,omitempty" default:"root"`
+	// Database such as root.loggie, the devices should be under the database
+	Database string `yaml:"database,omitempty" validate:"required"`
+	// Device is the path of the device, such as root.loggie.${fields.device}
+	Device string `yaml:"device,omitempty" validate:"required"`
+	// Aligned writes the measurements of a device as aligned timeseries
+	Aligned bool `yaml:"aligned,omitempty"`
+	// TimestampKey is the key of the event time, the time when the event was produced is used if empty
+	TimestampKey string `yaml:"timestampKey,omitempty"`
+	// Precision should be the same as timestamp_precision of IoTDB
+	Precision    string        `yaml:"precision,omitempty" default:"ms" validate:"oneof=ms us ns"`
+	Measurements []Measurement `yaml:"measurements,omitempty" validate:"required,dive"`
+	// AutoCreate creates the database if not exist before the first insert, the timeseries are created
+	// with the types of measurements by IoTDB when enable_auto_create_schema is true
+	AutoCreate *bool            `yaml:"autoCreate,omitempty" default:"true"`
+	Timeout    time.Duration    `yaml:"timeout,omitempty" default:"30s"`
+	HostLimit  hostlimit.Config `yaml:"hostLimit,omitempty"`
+}
+
+// Measurement maps a key of the event to a measurement of the device
+type Measurement struct {
+	Name string `yaml:"name,omitempty" validate:"required"`
+	Type string `yaml:"type,omitempty" validate:"required"`
+	Key  string `yaml:"key,omitempty"` // such as fields.temperature, the same as name if empty, body means the event body
+}
+
+func (m *Measurement) SetDefaults() {
+	m.Type = strings.ToUpper(m.Type)
+	if m.Key == "" {
+		m.Key = m.Name
+	}
+}
+
+func (m *Measurement) Validate() error {
+	switch m.Type {
+	case TypeBoolean, TypeInt32, TypeInt64, TypeFloat, TypeDouble, TypeText:
+		return nil
+	}
+	return fmt.Errorf("iotdb sink measurement %s with unsupported type %s", m.Name, m.Type)
+}
+
+func (c *Config) Validate() error {
+	if err := pattern.Validate(c.Device); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(c.Device, c.Database+".") {
+		return fmt.Errorf("iotdb sink device %s should be under the database %s", c.Device, c.Database)
+	}
+	names := make(map[string]struct{})
+	for i := range c.Measurements {
+		m := &c.Measurements[i]
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[m.Name]; ok {
+			return fmt.Errorf("iotdb sink measurement %s is duplicated", m.Name)
+		}
+		names[m.Name] = struct{}{}
+	}
+	return nil
+}
+
+func (c *Config) autoCreate() bool {
+	return c.AutoCreate == nil || *c.AutoCreate
+}
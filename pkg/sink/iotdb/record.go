@@ -0,0 +1,202 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iotdb
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	eventer "github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+// insertRecordsRequest is the body of /rest/v2/insertRecords, every record has its own measurements
+type insertRecordsRequest struct {
+	Timestamps       []int64         `json:"timestamps"`
+	MeasurementsList [][]string      `json:"measurements_list"`
+	DataTypesList    [][]string      `json:"data_types_list"`
+	ValuesList       [][]interface{} `json:"values_list"`
+	IsAligned        bool            `json:"is_aligned"`
+	Devices          []string        `json:"devices"`
+}
+
+func (r *insertRecordsRequest) size() int {
+	return len(r.Timestamps)
+}
+
+// addRecord appends the event as a record, the measurements without value are omitted
+func (s *Sink) addRecord(req *insertRecordsRequest, e api.Event) error {
+	obj := runtime.NewObject(e.Header())
+	device, err := s.devicePattern.WithObject(obj).RenderWithStrict()
+	if err != nil {
+		return errors.WithMessage(err, "render device")
+	}
+
+	ts, err := s.timestamp(e, obj)
+	if err != nil {
+		return err
+	}
+
+	var measurements, types []string
+	var values []interface{}
+	for i := range s.config.Measurements {
+		m := &s.config.Measurements[i]
+		raw := fieldValue(e, obj, m.Key)
+		if raw == nil {
+			continue
+		}
+		v, err := convert(m, raw)
+		if err != nil {
+			return err
+		}
+		measurements = append(measurements, m.Name)
+		types = append(types, m.Type)
+		values = append(values, v)
+	}
+	if len(measurements) == 0 {
+		return errors.New("none of the measurements is found")
+	}
+
+	req.Timestamps = append(req.Timestamps, ts)
+	req.Devices = append(req.Devices, device)
+	req.MeasurementsList = append(req.MeasurementsList, measurements)
+	req.DataTypesList = append(req.DataTypesList, types)
+	req.ValuesList = append(req.ValuesList, values)
+	return nil
+}
+
+func fieldValue(e api.Event, obj *runtime.Object, key string) interface{} {
+	if key == codec.BodyKey {
+		return string(e.Body())
+	}
+	return obj.GetPath(key).Value()
+}
+
+// timestamp returns the event time as an integer in the precision of IoTDB
+func (s *Sink) timestamp(e api.Event, obj *runtime.Object) (int64, error) {
+	var t time.Time
+	if s.config.TimestampKey == "" {
+		if e.Meta() != nil {
+			if v, ok := e.Meta().Get(eventer.SystemProductTimeKey); ok {
+				t, _ = v.(time.Time)
+			}
+		}
+	} else {
+		switch v := obj.GetPath(s.config.TimestampKey).Value().(type) {
+		case time.Time:
+			t = v
+		case string:
+			parsed, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return 0, errors.WithMessagef(err, "parse timestamp %s", v)
+			}
+			t = parsed
+		case float64:
+			// numbers are regarded as the timestamps in the precision already
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		case nil:
+			return 0, errors.Errorf("timestamp %s not found", s.config.TimestampKey)
+		default:
+			return 0, errors.Errorf("timestamp %s with unsupported type %T", s.config.TimestampKey, v)
+		}
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	switch s.config.Precision {
+	case PrecisionUs:
+		return t.UnixNano() / int64(time.Microsecond), nil
+	case PrecisionNs:
+		return t.UnixNano(), nil
+	default:
+		return t.UnixNano() / int64(time.Millisecond), nil
+	}
+}
+
+func convert(m *Measurement, value interface{}) (interface{}, error) {
+	switch m.Type {
+	case TypeBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, errors.Errorf("%s: %s is not a bool", m.Name, v)
+			}
+			return b, nil
+		}
+
+	case TypeInt32, TypeInt64:
+		var i int64
+		switch v := value.(type) {
+		case float64:
+			i = int64(v)
+		case int64:
+			i = v
+		case int:
+			i = int64(v)
+		case string:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, errors.Errorf("%s: %s is not an integer", m.Name, v)
+			}
+			i = n
+		default:
+			return nil, errors.Errorf("%s: %v with type %T cannot be converted to %s", m.Name, value, value, m.Type)
+		}
+		if m.Type == TypeInt32 && (i > math.MaxInt32 || i < math.MinInt32) {
+			return nil, errors.Errorf("%s: %d overflows INT32", m.Name, i)
+		}
+		return i, nil
+
+	case TypeFloat, TypeDouble:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		case string:
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, errors.Errorf("%s: %s is not a number", m.Name, v)
+			}
+			return n, nil
+		}
+
+	default:
+		if str, ok := value.(string); ok {
+			return str, nil
+		}
+		return fmt.Sprintf("%v", value), nil
+	}
+
+	return nil, errors.Errorf("%s: %v with type %T cannot be converted to %s", m.Name, value, value, m.Type)
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iotdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
+
+const (
+	Type = "iotdb"
+
+	insertRecordsPath = "/rest/v2/insertRecords"
+	nonQueryPath      = "/rest/v2/nonQuery"
+
+	codeSuccess               = 200
+	codeDatabaseAlreadyExists = 903
+)
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink(info.PipelineName)
+}
+
+type Sink struct {
+	pipelineName string
+	name         string
+	config       *Config
+	client       *http.Client
+	limiter      *hostlimit.Transport
+
+	devicePattern *pattern.Pattern
+	schemaLock    sync.Mutex
+	schemaReady   bool
+}
+
+func NewSink(pipelineName string) *Sink {
+	return &Sink{
+		pipelineName: pipelineName,
+		config:       &Config{},
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.devicePattern, _ = pattern.Init(s.config.Device)
+	s.client = &http.Client{
+		Timeout: s.config.Timeout,
+	}
+	s.schemaReady = !s.config.autoCreate()
+	return nil
+}
+
+func (s *Sink) Start() error {
+	if s.config.HostLimit.Enabled() {
+		s.limiter = hostlimit.NewTransport(s.client.Transport, &s.config.HostLimit, s.pipelineName, s.name)
+		s.client.Transport = s.limiter
+	}
+	log.Info("%s start, url: %s, database: %s", s.String(), s.config.URL, s.config.Database)
+	return nil
+}
+
+func (s *Sink) Stop() {
+	if s.limiter != nil {
+		s.limiter.Stop()
+	}
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	ctx := context.Background()
+	if err := s.ensureDatabase(ctx); err != nil {
+		return result.Fail(errors.WithMessage(err, "create iotdb database"))
+	}
+
+	req := &insertRecordsRequest{
+		IsAligned: s.config.Aligned,
+	}
+	for _, e := range events {
+		if err := s.addRecord(req, e); err != nil {
+			log.Warn("convert event to iotdb record error: %v, event: %s", err, e.String())
+		}
+	}
+	if req.size() == 0 {
+		return result.DropWith(errors.New("no valid event to insert into iotdb"))
+	}
+	if err := s.post(ctx, insertRecordsPath, req, false); err != nil {
+		return result.Fail(errors.WithMessage(err, "insert records into iotdb"))
+	}
+	return result.Success()
+}
+
+// ensureDatabase creates the database lazily, so the sink could start when IoTDB is not available
+func (s *Sink) ensureDatabase(ctx context.Context) error {
+	s.schemaLock.Lock()
+	defer s.schemaLock.Unlock()
+	if s.schemaReady {
+		return nil
+	}
+	sql := map[string]string{
+		"sql": "CREATE DATABASE " + s.config.Database,
+	}
+	if err := s.post(ctx, nonQueryPath, sql, true); err != nil {
+		return err
+	}
+	s.schemaReady = true
+	return nil
+}
+
+type response struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *Sink) post(ctx context.Context, path string, in interface{}, ignoreExists bool) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.config.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.config.Username, s.config.Password)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	out := &response{}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return errors.Errorf("iotdb returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if ignoreExists && out.Code == codeDatabaseAlreadyExists {
+		return nil
+	}
+	if resp.StatusCode/100 != 2 || out.Code != codeSuccess {
+		return errors.Errorf("iotdb returned status %d, code %d: %s", resp.StatusCode, out.Code, out.Message)
+	}
+	return nil
+}
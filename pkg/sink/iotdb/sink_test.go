@@ -0,0 +1,129 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iotdb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+)
+
+const testConfig = `
+url: http://127.0.0.1:18080
+database: root.loggie
+device: "root.loggie.${fields.device}"
+timestampKey: time
+measurements:
+  - name: message
+    type: text
+    key: body
+  - name: temperature
+    type: double
+  - name: count
+    type: int32
+  - name: online
+    type: boolean
+`
+
+func TestAddRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  map[string]interface{}
+		body    string
+		want    *insertRecordsRequest
+		wantErr bool
+	}{
+		{
+			name: "all measurements",
+			header: map[string]interface{}{
+				"time":        "2023-07-22T04:26:40Z",
+				"fields":      map[string]interface{}{"device": "d1"},
+				"temperature": 36.5,
+				"count":       "3",
+				"online":      true,
+			},
+			body: "hello",
+			want: &insertRecordsRequest{
+				Timestamps:       []int64{1690000000000},
+				MeasurementsList: [][]string{{"message", "temperature", "count", "online"}},
+				DataTypesList:    [][]string{{TypeText, TypeDouble, TypeInt32, TypeBoolean}},
+				ValuesList:       [][]interface{}{{"hello", 36.5, int64(3), true}},
+				Devices:          []string{"root.loggie.d1"},
+			},
+		},
+		{
+			name:   "missing measurements omitted",
+			header: map[string]interface{}{"time": float64(1690000001000), "fields": map[string]interface{}{"device": "d2"}},
+			body:   "world",
+			want: &insertRecordsRequest{
+				Timestamps:       []int64{1690000001000},
+				MeasurementsList: [][]string{{"message"}},
+				DataTypesList:    [][]string{{TypeText}},
+				ValuesList:       [][]interface{}{{"world"}},
+				Devices:          []string{"root.loggie.d2"},
+			},
+		},
+		{
+			name:    "overflows int32",
+			header:  map[string]interface{}{"time": "2023-07-22T04:26:40Z", "fields": map[string]interface{}{"device": "d1"}, "count": float64(1 << 40)},
+			wantErr: true,
+		},
+		{
+			name:    "device not rendered",
+			header:  map[string]interface{}{"time": "2023-07-22T04:26:40Z"},
+			body:    "hello",
+			wantErr: true,
+		},
+	}
+	s := NewSink("test")
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(testConfig), s.config).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(context.NewContext("iotdb", Type, api.SINK, nil)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &insertRecordsRequest{}
+			err := s.addRecord(req, event.NewEvent(tt.header, []byte(tt.body)))
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, 0, req.size())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, req)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "device out of database", raw: strings.Replace(testConfig, "root.loggie.${fields.device}", "root.other.${fields.device}", 1)},
+		{name: "unsupported type", raw: strings.Replace(testConfig, "type: double", "type: decimal", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, cfg.UnPackFromRaw([]byte(tt.raw), &Config{}).Defaults().Validate().Do())
+		})
+	}
+}
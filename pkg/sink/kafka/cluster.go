@@ -0,0 +1,190 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/interceptor/transformer/condition"
+)
+
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// splitter matches the events written to the secondary cluster in split mode
+type splitter struct {
+	conditions []*condition.Instance
+	connector  string
+}
+
+func newSplitter(expression string) (*splitter, error) {
+	conditions, connector, err := condition.GetConditions(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &splitter{
+		conditions: conditions,
+		connector:  connector,
+	}, nil
+}
+
+func (s *splitter) match(e api.Event) bool {
+	// AND all the conditions must return true
+	if s.connector == condition.AND {
+		for _, c := range s.conditions {
+			if c.Check(e) == c.Negative {
+				return false
+			}
+		}
+		return true
+	}
+
+	// OR need one of the conditions return true
+	for _, c := range s.conditions {
+		if c.Check(e) != c.Negative {
+			return true
+		}
+	}
+	return false
+}
+
+// failover tracks the active cluster in failover mode
+type failover struct {
+	config *Failover
+
+	lock      sync.Mutex
+	secondary bool // the secondary cluster is active
+	failures  int  // the consecutive failed batches of the primary
+	lastProbe time.Time
+}
+
+// probe returns true if the batch should be written to the primary
+func (f *failover) probe(now time.Time) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if !f.secondary {
+		return true
+	}
+	if now.Sub(f.lastProbe) < f.config.ProbeInterval {
+		return false
+	}
+	f.lastProbe = now
+	return true
+}
+
+func (f *failover) succeeded() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.secondary {
+		log.Info("kafka sink primary cluster recovered, switch back from the secondary")
+	}
+	f.secondary = false
+	f.failures = 0
+}
+
+// failed returns true if the batch should be written to the secondary
+func (f *failover) failed(now time.Time, err error) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.secondary {
+		return true
+	}
+	f.failures++
+	if f.failures < f.config.Errors {
+		return false
+	}
+	log.Warn("kafka sink primary cluster failed %d batches in a row, fail over to the secondary: %v", f.failures, err)
+	f.secondary = true
+	f.lastProbe = now
+	return true
+}
+
+// write sends the messages to the clusters by the mode, secondary marks the messages of the secondary cluster in split mode
+func (s *Sink) write(ctx context.Context, km []kafka.Message, secondary []bool) error {
+	if s.secondary == nil {
+		return s.writeTo(ctx, s.writer, km)
+	}
+
+	switch s.config.Mode {
+	case ModeFailover:
+		if s.failover.probe(time.Now()) {
+			err := s.writeTo(ctx, s.writer, km)
+			if err == nil {
+				s.failover.succeeded()
+				return nil
+			}
+			if !s.failover.failed(time.Now(), err) {
+				return err
+			}
+		}
+		return errors.WithMessage(s.writeTo(ctx, s.secondary, km), "secondary cluster")
+
+	case ModeSplit:
+		var primaries, secondaries []kafka.Message
+		for i, m := range km {
+			if secondary[i] {
+				secondaries = append(secondaries, m)
+			} else {
+				primaries = append(primaries, m)
+			}
+		}
+		return s.writeBoth(ctx, primaries, secondaries)
+
+	default:
+		return s.writeBoth(ctx, km, km)
+	}
+}
+
+// writeBoth writes the clusters concurrently, the batch fails if any of them failed, which is retried on both of them
+func (s *Sink) writeBoth(ctx context.Context, primaries []kafka.Message, secondaries []kafka.Message) error {
+	var primaryErr error
+	var wg sync.WaitGroup
+	if len(primaries) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			primaryErr = s.writeTo(ctx, s.writer, primaries)
+		}()
+	}
+	var secondaryErr error
+	if len(secondaries) > 0 {
+		secondaryErr = s.writeTo(ctx, s.secondary, secondaries)
+	}
+	wg.Wait()
+
+	if primaryErr != nil {
+		return primaryErr
+	}
+	return errors.WithMessage(secondaryErr, "secondary cluster")
+}
+
+func (s *Sink) writeTo(ctx context.Context, w messageWriter, km []kafka.Message) error {
+	err := w.WriteMessages(ctx, km...)
+	if err != nil && errors.Is(err, kafka.UnknownTopicOrPartition) && s.config.IgnoreUnknownTopicOrPartition {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+type fakeWriter struct {
+	lock     sync.Mutex
+	messages []kafka.Message
+	err      error
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	return nil
+}
+
+func newClusterSink(mode string) (*Sink, *fakeWriter, *fakeWriter) {
+	log.InitDefaultLogger()
+	primary, secondary := &fakeWriter{}, &fakeWriter{}
+	s := &Sink{
+		config: &Config{
+			Mode:     mode,
+			Failover: Failover{Errors: 2, ProbeInterval: time.Hour},
+		},
+		writer:    primary,
+		secondary: secondary,
+	}
+	s.failover = &failover{config: &s.config.Failover}
+	return s, primary, secondary
+}
+
+func TestWriteMirror(t *testing.T) {
+	s, primary, secondary := newClusterSink(ModeMirror)
+	km := []kafka.Message{{Value: []byte("a")}, {Value: []byte("b")}}
+	assert.NoError(t, s.write(context.Background(), km, nil))
+	assert.Len(t, primary.messages, 2)
+	assert.Len(t, secondary.messages, 2)
+
+	secondary.err = errors.New("down")
+	err := s.write(context.Background(), km, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "secondary cluster")
+}
+
+func TestWriteFailover(t *testing.T) {
+	s, primary, secondary := newClusterSink(ModeFailover)
+	km := []kafka.Message{{Value: []byte("a")}}
+
+	primary.err = errors.New("down")
+	// the first failure is returned to be retried
+	assert.Error(t, s.write(context.Background(), km, nil))
+	assert.Empty(t, secondary.messages)
+
+	// fail over once the errors are sustained
+	assert.NoError(t, s.write(context.Background(), km, nil))
+	assert.Len(t, secondary.messages, 1)
+	assert.NoError(t, s.write(context.Background(), km, nil))
+	assert.Len(t, secondary.messages, 2)
+
+	// switch back when the probe succeeds
+	primary.err = nil
+	s.failover.lastProbe = time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, s.write(context.Background(), km, nil))
+	assert.Len(t, primary.messages, 1)
+	assert.False(t, s.failover.secondary)
+	assert.NoError(t, s.write(context.Background(), km, nil))
+	assert.Len(t, primary.messages, 2)
+	assert.Len(t, secondary.messages, 2)
+}
+
+func TestWriteSplit(t *testing.T) {
+	s, primary, secondary := newClusterSink(ModeSplit)
+	var err error
+	s.splitter, err = newSplitter("equal(fields.region, us)")
+	assert.NoError(t, err)
+
+	var km []kafka.Message
+	var marks []bool
+	for _, region := range []string{"us", "eu", "us"} {
+		e := event.NewEvent(map[string]interface{}{
+			"fields": map[string]interface{}{"region": region},
+		}, []byte(region))
+		km = append(km, kafka.Message{Value: e.Body()})
+		marks = append(marks, s.splitter.match(e))
+	}
+	assert.NoError(t, s.write(context.Background(), km, marks))
+	assert.Len(t, primary.messages, 1)
+	assert.Equal(t, "eu", string(primary.messages[0].Value))
+	assert.Len(t, secondary.messages, 2)
+}
+
+func TestConfigSecondary(t *testing.T) {
+	c := &Config{Topic: "loggie", Secondary: &Cluster{Brokers: []string{"b:9092"}}, Mode: ModeSplit}
+	c.SetDefaults()
+	assert.Error(t, c.Validate())
+
+	c.SplitIf = "unknown(fields.a)"
+	assert.Error(t, c.Validate())
+
+	c.SplitIf = "equal(fields.region, us)"
+	assert.NoError(t, c.Validate())
+
+	c.Mode = "unknown"
+	assert.Error(t, c.Validate())
+}
+
+func TestStartSplitterError(t *testing.T) {
+	log.InitDefaultLogger()
+	s := &Sink{
+		config: &Config{
+			Brokers:   []string{"a:9092"},
+			Secondary: &Cluster{Brokers: []string{"b:9092"}},
+			Mode:      ModeSplit,
+			SplitIf:   "unknown(fields.a)",
+		},
+	}
+	assert.Error(t, s.Start())
+	assert.Nil(t, s.writer)
+	assert.Nil(t, s.secondary)
+	// the sink failed to start is stopped without closing any writer
+	s.Stop()
+}
@@ -18,10 +18,12 @@ package kafka
 
 import (
 	"fmt"
+	"github.com/loggie-io/loggie/pkg/util/envelope"
 	"github.com/loggie-io/loggie/pkg/util/pattern"
 	"time"
 
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/interceptor/transformer/condition"
 
 	"github.com/segmentio/kafka-go"
 	"github.com/segmentio/kafka-go/sasl"
@@ -33,6 +35,9 @@ const (
 	BalanceHash       = "hash"
 	BalanceRoundRobin = "roundRobin"
 	BalanceLeastBytes = "leastBytes"
+	// BalanceMurmur2 is the partitioner of the java client, producers in other languages could keep
+	// the same key in the same partition with it
+	BalanceMurmur2 = "murmur2"
 
 	CompressionGzip   = "gzip"
 	CompressionSnappy = "snappy"
@@ -42,9 +47,18 @@ const (
 	SASLNoneType  = ""
 	SASLPlainType = "plain"
 	SASLSCRAMType = "scram"
+	// SASLOAuthBearerType authenticates with a token from OAuth, see OAuth for the token providers
+	SASLOAuthBearerType = "oauthbearer"
 
 	AlgorithmSHA256 = "sha256"
 	AlgorithmSHA512 = "sha512"
+
+	// ModeMirror writes the events to both the clusters
+	ModeMirror = "mirror"
+	// ModeFailover writes to the secondary cluster on the sustained errors of the primary, and switches back when it recovers
+	ModeFailover = "failover"
+	// ModeSplit writes the events matching splitIf to the secondary cluster, and the others to the primary
+	ModeSplit = "split"
 )
 
 type Config struct {
@@ -52,23 +66,64 @@ type Config struct {
 	Topic                         string          `yaml:"topic,omitempty" validate:"required" default:"loggie"`
 	IfRenderTopicFailed           RenderTopicFail `yaml:"ifRenderTopicFailed,omitempty"`
 	IgnoreUnknownTopicOrPartition bool            `yaml:"ignoreUnknownTopicOrPartition,omitempty"`
-	Balance                       string          `yaml:"balance,omitempty" default:"roundRobin"`
+	Balance                       string          `yaml:"balance,omitempty"` // roundRobin by default, or hash if partitionKey is set
 	Compression                   string          `yaml:"compression,omitempty" default:"gzip"`
 	MaxAttempts                   int             `yaml:"maxAttempts,omitempty"`
-	BatchSize                     int             `yaml:"batchSize,omitempty"`
-	BatchBytes                    int64           `yaml:"batchBytes,omitempty"`
-	BatchTimeout                  time.Duration   `yaml:"batchTimeout,omitempty"`
+	BatchSize                     int             `yaml:"batchSize,omitempty" default:"1000"`     // max messages of a partition in one request
+	BatchBytes                    int64           `yaml:"batchBytes,omitempty" default:"1048576"` // max bytes of a partition in one request, should not exceed max.message.bytes of the broker
+	BatchTimeout                  time.Duration   `yaml:"batchTimeout,omitempty" default:"10ms"`  // linger of the partial batches, the events have been batched by the queue
+	MaxInFlight                   int             `yaml:"maxInFlight,omitempty"`                  // max batches being written concurrently by the parallel sink consumers, 0 means unlimited
 	ReadTimeout                   time.Duration   `yaml:"readTimeout,omitempty"`
 	WriteTimeout                  time.Duration   `yaml:"writeTimeout,omitempty"`
 	RequiredAcks                  int             `yaml:"requiredAcks,omitempty"`
 	SASL                          SASL            `yaml:"sasl,omitempty"`
-	PartitionKey                  string          `yaml:"partitionKey,omitempty"`
+	PartitionKey                  string          `yaml:"partitionKey,omitempty"` // such as ${fields.podName}, the events with the same key are sent to the same partition
+
+	// SanitizeTopic replaces the characters not allowed in topic names of the rendered topics with '_'
+	SanitizeTopic *bool `yaml:"sanitizeTopic,omitempty" default:"true"`
+	// AutoCreateTopic allows the brokers to create the missing topics when auto.create.topics.enable is true
+	AutoCreateTopic *bool `yaml:"autoCreateTopic,omitempty" default:"true"`
+
+	// Headers maps the record header names to the value patterns, such as app: ${fields.app}, empty values are skipped
+	Headers     map[string]string `yaml:"headers,omitempty"`
+	MetaHeaders MetaHeaders       `yaml:"metaHeaders,omitempty"`
+	// Envelope adds the envelope headers to each record, the version should be supported by all the consumers
+	// since it could not be negotiated through kafka, it is disabled by default
+	Envelope envelope.Config `yaml:"envelope,omitempty"`
+
+	// Secondary is another kafka cluster written by the mode, such as the active-active log infrastructure
+	Secondary *Cluster `yaml:"secondary,omitempty"`
+	Mode      string   `yaml:"mode,omitempty" default:"mirror"`
+	Failover  Failover `yaml:"failover,omitempty"`
+	// SplitIf is a condition as the `if` of transformer, such as equal(fields.region, us-east)
+	SplitIf string `yaml:"splitIf,omitempty"`
+}
+
+// Cluster is the secondary kafka cluster, which shares the topics and the producer settings of the primary
+type Cluster struct {
+	Brokers []string `yaml:"brokers,omitempty" validate:"required"`
+	SASL    SASL     `yaml:"sasl,omitempty"`
+}
+
+type Failover struct {
+	// Errors is the number of the consecutive failed batches of the primary before failing over
+	Errors int `yaml:"errors,omitempty" default:"3"`
+	// ProbeInterval is the interval of writing a batch to the primary after failing over, which switches back once succeeded
+	ProbeInterval time.Duration `yaml:"probeInterval,omitempty" default:"30s"`
+}
+
+// MetaHeaders are the record header names of the event metadata, empty means not added
+type MetaHeaders struct {
+	PipelineName string `yaml:"pipelineName,omitempty"`
+	SourceName   string `yaml:"sourceName,omitempty"`
 }
 
+// RenderTopicFail handles the events whose topic could not be rendered, such as the field is absent
+// or the topic is not a legal topic name
 type RenderTopicFail struct {
 	DropEvent    bool   `yaml:"dropEvent,omitempty" default:"true"`
 	IgnoreError  bool   `yaml:"ignoreError,omitempty"`
-	DefaultTopic string `yaml:"defaultTopic,omitempty"`
+	DefaultTopic string `yaml:"defaultTopic,omitempty"` // the fallback topic, takes precedence over dropEvent
 }
 
 type SASL struct {
@@ -77,47 +132,119 @@ type SASL struct {
 	Username  string `yaml:"username,omitempty"`
 	Password  string `yaml:"password,omitempty"`
 	Algorithm string `yaml:"algorithm,omitempty"`
+	OAuth     OAuth  `yaml:"oauth,omitempty"`
 }
 
 func (c *Config) SetDefaults() {
 	if c.SASL.UserName != "" {
 		c.SASL.Username = c.SASL.UserName
 	}
+	if c.Secondary != nil && c.Secondary.SASL.UserName != "" {
+		c.Secondary.SASL.Username = c.Secondary.SASL.UserName
+	}
+	if c.Balance == "" {
+		if c.PartitionKey != "" {
+			c.Balance = BalanceHash
+		} else {
+			c.Balance = BalanceRoundRobin
+		}
+	}
 }
 
 func (c *Config) Validate() error {
+	if err := c.Envelope.Validate(); err != nil {
+		return err
+	}
 
 	if err := pattern.Validate(c.Topic); err != nil {
 		return err
 	}
 
+	if c.IfRenderTopicFailed.DefaultTopic != "" {
+		if err := validateTopic(c.IfRenderTopicFailed.DefaultTopic); err != nil {
+			return fmt.Errorf("kafka sink defaultTopic: %v", err)
+		}
+	}
+
 	if c.PartitionKey != "" {
 		if err := pattern.Validate(c.PartitionKey); err != nil {
 			return err
 		}
 	}
 
-	if c.Balance != "" && c.Balance != BalanceHash && c.Balance != BalanceRoundRobin && c.Balance != BalanceLeastBytes {
+	for name, value := range c.Headers {
+		if name == "" {
+			return fmt.Errorf("kafka sink record header name is required")
+		}
+		if err := pattern.Validate(value); err != nil {
+			return err
+		}
+	}
+
+	if c.Balance != "" && c.Balance != BalanceHash && c.Balance != BalanceRoundRobin && c.Balance != BalanceLeastBytes &&
+		c.Balance != BalanceMurmur2 {
 		return fmt.Errorf("kafka sink balance %s is not supported", c.Balance)
 	}
 
+	if c.PartitionKey != "" && (c.Balance == BalanceRoundRobin || c.Balance == BalanceLeastBytes) {
+		log.Warn("kafka sink partitionKey is ignored by balance %s, use %s or %s instead", c.Balance, BalanceHash, BalanceMurmur2)
+	}
+
+	if c.BatchSize < 0 || c.BatchBytes < 0 || c.BatchTimeout < 0 || c.MaxInFlight < 0 {
+		return fmt.Errorf("kafka sink batchSize, batchBytes, batchTimeout and maxInFlight should not be negative")
+	}
+
 	if c.Compression != "" && c.Compression != CompressionGzip && c.Compression != CompressionLz4 && c.Compression != CompressionSnappy &&
 		c.Compression != CompressionZstd {
 		return fmt.Errorf("kafka sink compression %s is not suppported", c.Compression)
 	}
 
-	if c.SASL.Type != SASLPlainType && c.SASL.Type != SASLSCRAMType && c.SASL.Type != SASLNoneType {
-		return fmt.Errorf("kafka sink sasl type %s not supported", c.SASL.Type)
+	if err := c.SASL.Validate(); err != nil {
+		return err
 	}
 
-	if err := c.SASL.Validate(); err != nil {
+	if c.Secondary != nil {
+		if err := c.validateSecondary(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateSecondary() error {
+	if err := c.Secondary.SASL.Validate(); err != nil {
 		return err
 	}
 
+	switch c.Mode {
+	case ModeMirror:
+	case ModeFailover:
+		if c.Failover.Errors <= 0 || c.Failover.ProbeInterval <= 0 {
+			return fmt.Errorf("kafka sink failover errors and probeInterval should be positive")
+		}
+	case ModeSplit:
+		if c.SplitIf == "" {
+			return fmt.Errorf("kafka sink splitIf is required by mode %s", ModeSplit)
+		}
+		if _, _, err := condition.GetConditions(c.SplitIf); err != nil {
+			return fmt.Errorf("kafka sink splitIf: %v", err)
+		}
+	default:
+		return fmt.Errorf("kafka sink mode %s is not supported", c.Mode)
+	}
 	return nil
 }
 
 func (s *SASL) Validate() error {
+	if s.Type != SASLPlainType && s.Type != SASLSCRAMType && s.Type != SASLOAuthBearerType && s.Type != SASLNoneType {
+		return fmt.Errorf("kafka sink or source sasl type %s not supported", s.Type)
+	}
+
+	if s.Type == SASLOAuthBearerType {
+		return s.OAuth.Validate()
+	}
+
 	if s.Type != SASLNoneType {
 		if s.Username == "" {
 			return fmt.Errorf("kafka sink or source %s sasl with empty user name", s.Type)
@@ -144,6 +271,8 @@ func balanceInstance(balance string) kafka.Balancer {
 		return &kafka.RoundRobin{}
 	case BalanceLeastBytes:
 		return &kafka.LeastBytes{}
+	case BalanceMurmur2:
+		return &kafka.Murmur2Balancer{}
 	default:
 		log.Warn("kafka sink balance %s is not supported", balance)
 		return nil
@@ -170,6 +299,14 @@ func compression(compression string) kafka.Compression {
 	}
 }
 
+// NewMechanism returns the sasl mechanism of the config, nil is returned when sasl is not enabled
+func NewMechanism(s SASL) (sasl.Mechanism, error) {
+	if s.Type == SASLOAuthBearerType {
+		return newOAuthBearer(&s.OAuth), nil
+	}
+	return Mechanism(s.Type, s.Username, s.Password, s.Algorithm)
+}
+
 func Mechanism(saslType, userName, password, algo string) (sasl.Mechanism, error) {
 	switch saslType {
 	case SASLPlainType:
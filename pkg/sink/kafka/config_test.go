@@ -0,0 +1,210 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
+
+func TestConfigBalance(t *testing.T) {
+	log.InitDefaultLogger()
+	tests := []struct {
+		name        string
+		config      Config
+		wantBalance string
+		wantErr     bool
+	}{
+		{
+			name:        "roundRobin by default",
+			config:      Config{Topic: "loggie"},
+			wantBalance: BalanceRoundRobin,
+		},
+		{
+			name:        "hash by default with partitionKey",
+			config:      Config{Topic: "loggie", PartitionKey: "${fields.podName}"},
+			wantBalance: BalanceHash,
+		},
+		{
+			name:        "murmur2",
+			config:      Config{Topic: "loggie", PartitionKey: "${fields.podName}", Balance: BalanceMurmur2},
+			wantBalance: BalanceMurmur2,
+		},
+		{
+			name:        "partitionKey is ignored by roundRobin",
+			config:      Config{Topic: "loggie", PartitionKey: "${fields.podName}", Balance: BalanceRoundRobin},
+			wantBalance: BalanceRoundRobin,
+		},
+		{
+			name:        "unsupported balance",
+			config:      Config{Topic: "loggie", Balance: "random"},
+			wantBalance: "random",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.SetDefaults()
+			assert.Equal(t, tt.wantBalance, tt.config.Balance)
+			assert.Equal(t, tt.wantErr, tt.config.Validate() != nil)
+		})
+	}
+}
+
+func TestPartitionKey(t *testing.T) {
+	s := &Sink{config: &Config{PartitionKey: "${fields.podName}"}}
+	s.partitionKeyPattern, _ = pattern.Init(s.config.PartitionKey)
+
+	key, err := s.getPartitionKey(event.NewEvent(map[string]interface{}{
+		"fields": map[string]interface{}{"podName": "nginx-0"},
+	}, []byte("a")))
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx-0", key)
+
+	// the same key is always sent to the same partition
+	balancer := balanceInstance(BalanceMurmur2)
+	partitions := []int{0, 1, 2, 3, 4, 5}
+	p := balancer.Balance(kafka.Message{Key: []byte(key)}, partitions...)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, p, balancer.Balance(kafka.Message{Key: []byte(key)}, partitions...))
+	}
+
+	key, err = s.getPartitionKey(event.NewEvent(map[string]interface{}{}, []byte("a")))
+	assert.NoError(t, err)
+	assert.Equal(t, "", key)
+}
+
+func TestRecordHeaders(t *testing.T) {
+	s := NewSink()
+	s.config.Topic = "loggie"
+	s.config.Headers = map[string]string{
+		"app":   "${fields.app}",
+		"env":   "${fields.env}",
+		"owner": "team-${fields.team}",
+	}
+	s.config.MetaHeaders = MetaHeaders{PipelineName: "pipeline", SourceName: "source"}
+	assert.NoError(t, s.config.Validate())
+	assert.NoError(t, s.Init(nil))
+
+	e := event.NewEvent(map[string]interface{}{
+		"fields": map[string]interface{}{"app": "nginx", "team": "infra"},
+	}, []byte("a"))
+	meta := event.NewDefaultMeta()
+	meta.Set(event.SystemPipelineKey, "local")
+	meta.Set(event.SystemSourceKey, "demo")
+	e.Fill(meta, e.Header(), e.Body())
+
+	// env is skipped as the field does not exist
+	assert.Equal(t, []kafka.Header{
+		{Key: "app", Value: []byte("nginx")},
+		{Key: "owner", Value: []byte("team-infra")},
+		{Key: "pipeline", Value: []byte("local")},
+		{Key: "source", Value: []byte("demo")},
+	}, s.recordHeaders(e))
+
+	s.config.Headers = map[string]string{"": "${fields.app}"}
+	assert.Error(t, s.config.Validate())
+}
+
+func TestSelectTopic(t *testing.T) {
+	s := &Sink{config: &Config{Topic: "log-${fields.namespace}/${fields.app}"}}
+	s.topicPattern, _ = pattern.Init(s.config.Topic)
+
+	newEvent := func(fields map[string]interface{}) *event.DefaultEvent {
+		return event.NewEvent(map[string]interface{}{"fields": fields}, []byte("a"))
+	}
+
+	topic, err := s.selectTopic(newEvent(map[string]interface{}{"namespace": "default", "app": "nginx:v1"}))
+	assert.NoError(t, err)
+	assert.Equal(t, "log-default_nginx_v1", topic)
+
+	// the field is absent, the fallback topic would be used
+	_, err = s.selectTopic(newEvent(map[string]interface{}{"namespace": "default"}))
+	assert.Error(t, err)
+
+	disabled := false
+	s.config.SanitizeTopic = &disabled
+	_, err = s.selectTopic(newEvent(map[string]interface{}{"namespace": "default", "app": "nginx:v1"}))
+	assert.Error(t, err)
+
+	assert.Equal(t, maxTopicLength, len(sanitizeTopic(strings.Repeat("日志", 200))))
+	assert.Error(t, validateTopic(".."))
+
+	c := &Config{Topic: "loggie", IfRenderTopicFailed: RenderTopicFail{DefaultTopic: "loggie/default"}}
+	assert.Error(t, c.Validate())
+}
+
+func TestConfigBatch(t *testing.T) {
+	tests := []struct {
+		name             string
+		raw              string
+		wantBatchSize    int
+		wantBatchBytes   int64
+		wantBatchTimeout time.Duration
+		wantMaxInFlight  int
+		wantErr          bool
+	}{
+		{
+			name:             "defaults",
+			wantBatchSize:    1000,
+			wantBatchBytes:   1048576,
+			wantBatchTimeout: 10 * time.Millisecond,
+		},
+		{
+			name:             "tuned",
+			raw:              "batchSize: 2000\nbatchBytes: 4194304\nbatchTimeout: 50ms\nmaxInFlight: 4",
+			wantBatchSize:    2000,
+			wantBatchBytes:   4194304,
+			wantBatchTimeout: 50 * time.Millisecond,
+			wantMaxInFlight:  4,
+		},
+		{
+			name:    "negative batchTimeout",
+			raw:     "batchTimeout: -1s",
+			wantErr: true,
+		},
+		{
+			name:    "negative maxInFlight",
+			raw:     "maxInFlight: -1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			err := cfg.UnPackFromRaw([]byte("brokers: [\"127.0.0.1:9092\"]\n"+tt.raw), c).Defaults().Validate().Do()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBatchSize, c.BatchSize)
+			assert.Equal(t, tt.wantBatchBytes, c.BatchBytes)
+			assert.Equal(t, tt.wantBatchTimeout, c.BatchTimeout)
+			assert.Equal(t, tt.wantMaxInFlight, c.MaxInFlight)
+		})
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go/sasl"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+// tokenExpiryMargin refreshes the token of client credentials before it is expired
+const tokenExpiryMargin = 30 * time.Second
+
+// OAuth provides the token of sasl OAUTHBEARER, which could be a static token, a token file such as the projected
+// service account token in kubernetes which is read for every authentication, or fetched from the token endpoint
+// with the client credentials grant.
+type OAuth struct {
+	Token         string            `yaml:"token,omitempty"`
+	TokenFile     string            `yaml:"tokenFile,omitempty"`
+	TokenEndpoint string            `yaml:"tokenEndpoint,omitempty"`
+	ClientId      string            `yaml:"clientId,omitempty"`
+	ClientSecret  string            `yaml:"clientSecret,omitempty"`
+	Scopes        []string          `yaml:"scopes,omitempty"`
+	Extensions    map[string]string `yaml:"extensions,omitempty"`
+}
+
+func (o *OAuth) Validate() error {
+	if o.Token == "" && o.TokenFile == "" && o.TokenEndpoint == "" {
+		return errors.New("kafka sink or source oauthbearer sasl requires token, tokenFile or tokenEndpoint")
+	}
+	if o.TokenEndpoint != "" && o.ClientId == "" {
+		return errors.New("kafka sink or source oauthbearer sasl with empty clientId")
+	}
+	for k := range o.Extensions {
+		if k == "auth" {
+			return errors.New("kafka sink or source oauthbearer sasl extension auth is reserved")
+		}
+	}
+	return nil
+}
+
+type oauthBearer struct {
+	config *OAuth
+	client *http.Client
+
+	lock      sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func newOAuthBearer(config *OAuth) *oauthBearer {
+	return &oauthBearer{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (o *oauthBearer) Name() string {
+	return "OAUTHBEARER"
+}
+
+// Start sends the client initial response defined in RFC 7628
+func (o *oauthBearer) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	token, err := o.getToken(ctx)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "get oauthbearer token")
+	}
+	return o, initialResponse(token, o.config.Extensions), nil
+}
+
+// Next is called with the response of the server, which is empty when the authentication succeeded,
+// otherwise it is a json error message.
+func (o *oauthBearer) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	if len(challenge) == 0 {
+		return true, nil, nil
+	}
+	return false, nil, errors.Errorf("oauthbearer authentication failed: %s", challenge)
+}
+
+func initialResponse(token string, extensions map[string]string) []byte {
+	var b strings.Builder
+	b.WriteString("n,,\x01auth=Bearer ")
+	b.WriteString(token)
+	b.WriteString("\x01")
+
+	keys := make([]string, 0, len(extensions))
+	for k := range extensions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(extensions[k])
+		b.WriteString("\x01")
+	}
+	b.WriteString("\x01")
+	return []byte(b.String())
+}
+
+func (o *oauthBearer) getToken(ctx context.Context) (string, error) {
+	switch {
+	case o.config.Token != "":
+		return o.config.Token, nil
+
+	case o.config.TokenFile != "":
+		content, err := os.ReadFile(o.config.TokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.token != "" && time.Now().Before(o.expiresAt) {
+		return o.token, nil
+	}
+	token, expiresIn, err := o.fetchToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	o.token = token
+	o.expiresAt = time.Now().Add(expiresIn - tokenExpiryMargin)
+	return token, nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (o *oauthBearer) fetchToken(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(o.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.config.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.config.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(o.config.ClientId), url.QueryEscape(o.config.ClientSecret))
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, body)
+	}
+
+	out := &tokenResponse{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return "", 0, errors.WithMessage(err, "unmarshal token response")
+	}
+	if out.AccessToken == "" {
+		return "", 0, errors.New("empty access_token in token response")
+	}
+	expiresIn := time.Duration(out.ExpiresIn) * time.Second
+	if expiresIn <= tokenExpiryMargin {
+		expiresIn = tokenExpiryMargin
+	}
+	return out.AccessToken, expiresIn, nil
+}
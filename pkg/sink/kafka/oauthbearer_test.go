@@ -0,0 +1,72 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitialResponse(t *testing.T) {
+	assert.Equal(t, "n,,\x01auth=Bearer abc\x01\x01", string(initialResponse("abc", nil)))
+	assert.Equal(t, "n,,\x01auth=Bearer abc\x01logicalCluster=lkc-1\x01pool=p\x01\x01",
+		string(initialResponse("abc", map[string]string{"pool": "p", "logicalCluster": "lkc-1"})))
+}
+
+func TestClientCredentialsToken(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "loggie", user)
+		assert.Equal(t, "secret", pass)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "kafka logs", r.PostForm.Get("scope"))
+		_, _ = w.Write([]byte(`{"access_token":"t1","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	o := newOAuthBearer(&OAuth{
+		TokenEndpoint: server.URL,
+		ClientId:      "loggie",
+		ClientSecret:  "secret",
+		Scopes:        []string{"kafka", "logs"},
+	})
+	for i := 0; i < 2; i++ {
+		_, ir, err := o.Start(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "n,,\x01auth=Bearer t1\x01\x01", string(ir))
+	}
+	// the token is cached until it is expired
+	assert.Equal(t, 1, requests)
+
+	done, _, err := o.Next(context.Background(), []byte(`{"status":"invalid_token"}`))
+	assert.False(t, done)
+	assert.Error(t, err)
+}
+
+func TestSASLValidate(t *testing.T) {
+	assert.NoError(t, (&SASL{Type: SASLOAuthBearerType, OAuth: OAuth{TokenFile: "/var/run/token"}}).Validate())
+	assert.Error(t, (&SASL{Type: SASLOAuthBearerType}).Validate())
+	assert.Error(t, (&SASL{Type: SASLOAuthBearerType, OAuth: OAuth{TokenEndpoint: "http://idp"}}).Validate())
+	assert.Error(t, (&SASL{Type: "gssapi"}).Validate())
+}
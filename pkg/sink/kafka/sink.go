@@ -19,16 +19,20 @@ package kafka
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/pkg/errors"
 	"github.com/segmentio/kafka-go"
 
 	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/core/result"
 	"github.com/loggie-io/loggie/pkg/pipeline"
 	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/envelope"
 	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/priority"
 	"github.com/loggie-io/loggie/pkg/util/runtime"
 )
 
@@ -44,11 +48,26 @@ func makeSink(info pipeline.Info) api.Component {
 
 type Sink struct {
 	config *Config
-	writer *kafka.Writer
+	writer messageWriter
 	cod    codec.Codec
 
+	// secondary is the writer of the secondary cluster if configured
+	secondary messageWriter
+	failover  *failover
+	splitter  *splitter
+
 	topicPattern        *pattern.Pattern
 	partitionKeyPattern *pattern.Pattern
+	headerPatterns      []headerPattern
+	envelope            envelope.Offer
+
+	// inFlight limits the concurrent writes when maxInFlight is set
+	inFlight chan struct{}
+}
+
+type headerPattern struct {
+	name    string
+	pattern *pattern.Pattern
 }
 
 func NewSink() *Sink {
@@ -82,19 +101,65 @@ func (s *Sink) Init(context api.Context) error {
 	if s.config.PartitionKey != "" {
 		s.partitionKeyPattern, _ = pattern.Init(s.config.PartitionKey)
 	}
+	s.headerPatterns = s.headerPatterns[:0]
+	for name, value := range s.config.Headers {
+		p, _ := pattern.Init(value)
+		s.headerPatterns = append(s.headerPatterns, headerPattern{name: name, pattern: p})
+	}
+	// keep the record headers in a stable order
+	sort.Slice(s.headerPatterns, func(i, j int) bool {
+		return s.headerPatterns[i].name < s.headerPatterns[j].name
+	})
+	s.envelope = s.config.Envelope.Offer()
 	return nil
 }
 
 func (s *Sink) Start() error {
 	c := s.config
-	mechanism, err := Mechanism(c.SASL.Type, c.SASL.Username, c.SASL.Password, c.SASL.Algorithm)
+	w, err := s.newWriter(c.Brokers, c.SASL)
 	if err != nil {
-		log.Error("kafka sink sasl mechanism with error: %s", err.Error())
 		return err
 	}
 
-	w := &kafka.Writer{
-		Addr:                   kafka.TCP(c.Brokers...),
+	if c.MaxInFlight > 0 {
+		s.inFlight = make(chan struct{}, c.MaxInFlight)
+	}
+
+	if c.Secondary != nil {
+		var secondary *kafka.Writer
+		if secondary, err = s.newWriter(c.Secondary.Brokers, c.Secondary.SASL); err != nil {
+			_ = w.Close()
+			return err
+		}
+		switch c.Mode {
+		case ModeFailover:
+			s.failover = &failover{config: &c.Failover}
+		case ModeSplit:
+			if s.splitter, err = newSplitter(c.SplitIf); err != nil {
+				_ = w.Close()
+				_ = secondary.Close()
+				return err
+			}
+		}
+		s.secondary = secondary
+		log.Info("kafka-sink secondary cluster in %s mode, broker: %v", c.Mode, c.Secondary.Brokers)
+	}
+
+	log.Info("kafka-sink start,topic: %s,broker: %v", s.config.Topic, s.config.Brokers)
+	s.writer = w
+	return nil
+}
+
+func (s *Sink) newWriter(brokers []string, saslConfig SASL) (*kafka.Writer, error) {
+	c := s.config
+	mechanism, err := NewMechanism(saslConfig)
+	if err != nil {
+		log.Error("kafka sink sasl mechanism with error: %s", err.Error())
+		return nil, err
+	}
+
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
 		MaxAttempts:            c.MaxAttempts,
 		Balancer:               balanceInstance(c.Balance),
 		BatchSize:              c.BatchSize,
@@ -104,21 +169,20 @@ func (s *Sink) Start() error {
 		WriteTimeout:           c.WriteTimeout,
 		RequiredAcks:           kafka.RequiredAcks(c.RequiredAcks),
 		Compression:            compression(c.Compression),
-		AllowAutoTopicCreation: true,
+		AllowAutoTopicCreation: c.AutoCreateTopic == nil || *c.AutoCreateTopic,
 		Transport: &kafka.Transport{
 			SASL: mechanism,
 		},
-	}
-
-	log.Info("kafka-sink start,topic: %s,broker: %v", s.config.Topic, s.config.Brokers)
-	s.writer = w
-	return nil
+	}, nil
 }
 
 func (s *Sink) Stop() {
 	if s.writer != nil {
 		_ = s.writer.Close()
 	}
+	if s.secondary != nil {
+		_ = s.secondary.Close()
+	}
 }
 
 func (s *Sink) Consume(batch api.Batch) api.Result {
@@ -128,6 +192,7 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 		return nil
 	}
 	km := make([]kafka.Message, 0, l)
+	var secondary []bool
 	for _, e := range events {
 		topic, err := s.selectTopic(e)
 		if err != nil {
@@ -159,15 +224,22 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 
 		if s.partitionKeyPattern != nil {
 			key, err := s.getPartitionKey(e)
-			if err == nil {
-				message.Key = []byte(key)
-			} else {
+			if err != nil {
 				log.Warn("fail to get kafka key: %+v", err)
+			} else if key != "" {
+				// the events without key are balanced to all the partitions instead of a single one
+				message.Key = []byte(key)
 			}
 
 		}
 
+		message.Headers = s.recordHeaders(e)
+		message.Headers = s.envelopeHeaders(message.Headers, e, msg)
+
 		km = append(km, message)
+		if s.splitter != nil {
+			secondary = append(secondary, s.splitter.match(e))
+		}
 	}
 
 	if len(km) == 0 {
@@ -175,12 +247,7 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 	}
 
 	if s.writer != nil {
-		err := s.writer.WriteMessages(context.Background(), km...)
-		if err != nil {
-			if errors.Is(err, kafka.UnknownTopicOrPartition) && s.config.IgnoreUnknownTopicOrPartition {
-				return result.Success()
-			}
-
+		if err := s.writeInFlight(context.Background(), km, secondary); err != nil {
 			return result.Fail(errors.WithMessage(err, "write to kafka"))
 		}
 
@@ -190,10 +257,89 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 	return result.Fail(errors.New("kafka sink writer not initialized"))
 }
 
+// writeInFlight waits for the other writes when there are maxInFlight batches being written
+func (s *Sink) writeInFlight(ctx context.Context, km []kafka.Message, secondary []bool) error {
+	if s.inFlight != nil {
+		s.inFlight <- struct{}{}
+		defer func() { <-s.inFlight }()
+	}
+	return s.write(ctx, km, secondary)
+}
+
 func (s *Sink) selectTopic(e api.Event) (string, error) {
-	return s.topicPattern.WithObject(runtime.NewObject(e.Header())).RenderWithStrict()
+	topic, err := s.topicPattern.WithObject(runtime.NewObject(e.Header())).RenderWithStrict()
+	if err != nil {
+		return "", err
+	}
+	if s.config.SanitizeTopic == nil || *s.config.SanitizeTopic {
+		topic = sanitizeTopic(topic)
+	}
+	if err := validateTopic(topic); err != nil {
+		return "", err
+	}
+	return topic, nil
 }
 
 func (s *Sink) getPartitionKey(e api.Event) (string, error) {
 	return s.partitionKeyPattern.WithObject(runtime.NewObject(e.Header())).Render()
 }
+
+// recordHeaders renders the configured headers of the event, headers with empty value are skipped
+func (s *Sink) recordHeaders(e api.Event) []kafka.Header {
+	var headers []kafka.Header
+	if len(s.headerPatterns) > 0 {
+		obj := runtime.NewObject(e.Header())
+		for _, h := range s.headerPatterns {
+			value, err := h.pattern.WithObject(obj).Render()
+			if err != nil {
+				log.Warn("fail to render kafka record header %s: %+v", h.name, err)
+				continue
+			}
+			if value == "" {
+				continue
+			}
+			headers = append(headers, kafka.Header{Key: h.name, Value: []byte(value)})
+		}
+	}
+
+	meta := s.config.MetaHeaders
+	if meta.PipelineName != "" {
+		headers = appendMetaHeader(headers, e, meta.PipelineName, event.SystemPipelineKey)
+	}
+	if meta.SourceName != "" {
+		headers = appendMetaHeader(headers, e, meta.SourceName, event.SystemSourceKey)
+	}
+	return headers
+}
+
+// envelopeHeaders adds the envelope of each record, as the events of a batch are spread across the partitions
+func (s *Sink) envelopeHeaders(headers []kafka.Header, e api.Event, value []byte) []kafka.Header {
+	env := &envelope.Envelope{Offer: s.envelope}
+	if env.Has(envelope.CapabilityChecksum) {
+		sum := envelope.NewChecksum()
+		sum.Add(value)
+		env.Checksum = sum.String()
+	}
+	if env.Has(envelope.CapabilityPriority) {
+		env.Priorities = envelope.EncodePriorities([]priority.Level{priority.Of(e)})
+	}
+	env.Set(func(key string, value string) {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	})
+	return headers
+}
+
+func appendMetaHeader(headers []kafka.Header, e api.Event, name string, key string) []kafka.Header {
+	if e.Meta() == nil {
+		return headers
+	}
+	value, ok := e.Meta().Get(key)
+	if !ok {
+		return headers
+	}
+	v, ok := value.(string)
+	if !ok || v == "" {
+		return headers
+	}
+	return append(headers, kafka.Header{Key: name, Value: []byte(v)})
+}
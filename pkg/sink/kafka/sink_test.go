@@ -0,0 +1,104 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+// blockingWriter holds the writes until released and records the max concurrent ones
+type blockingWriter struct {
+	lock        sync.Mutex
+	inFlight    int
+	maxInFlight int
+	entered     chan struct{}
+	release     chan struct{}
+}
+
+func (w *blockingWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.lock.Lock()
+	w.inFlight++
+	if w.inFlight > w.maxInFlight {
+		w.maxInFlight = w.inFlight
+	}
+	w.lock.Unlock()
+
+	w.entered <- struct{}{}
+	<-w.release
+
+	w.lock.Lock()
+	w.inFlight--
+	w.lock.Unlock()
+	return nil
+}
+
+func (w *blockingWriter) Close() error {
+	return nil
+}
+
+func TestWriteInFlight(t *testing.T) {
+	log.InitDefaultLogger()
+	const consumers = 8
+
+	s := NewSink()
+	assert.NoError(t, cfg.UnPackFromRaw([]byte("brokers: [\"127.0.0.1:9092\"]\nmaxInFlight: 3"), s.config).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(nil))
+	assert.NoError(t, s.Start())
+	defer s.Stop()
+	w := &blockingWriter{
+		entered: make(chan struct{}, consumers),
+		release: make(chan struct{}),
+	}
+	_ = s.writer.Close()
+	s.writer = w
+
+	var wg sync.WaitGroup
+	for i := 0; i < consumers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.writeInFlight(context.Background(), []kafka.Message{{Value: []byte("a")}}, nil))
+		}()
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-w.entered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the writes within maxInFlight are blocked")
+		}
+	}
+	// the other consumers wait for the in-flight writes
+	select {
+	case <-w.entered:
+		t.Fatal("the writes exceed maxInFlight")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(w.release)
+	wg.Wait()
+	assert.Equal(t, 3, w.maxInFlight)
+}
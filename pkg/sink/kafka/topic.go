@@ -0,0 +1,62 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxTopicLength is the max length of topic names limited by kafka
+const maxTopicLength = 249
+
+func legalTopicChar(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '.' || c == '_' || c == '-'
+}
+
+// sanitizeTopic replaces the illegal characters with '_' and truncates the topic to the max length,
+// such as the rendered topic log-${fields.namespace}/${fields.app} becomes log-default_nginx
+func sanitizeTopic(topic string) string {
+	var b strings.Builder
+	b.Grow(len(topic))
+	for _, c := range topic {
+		if b.Len() >= maxTopicLength {
+			break
+		}
+		if legalTopicChar(c) {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func validateTopic(topic string) error {
+	if topic == "" || topic == "." || topic == ".." {
+		return fmt.Errorf("topic %q is illegal", topic)
+	}
+	if len(topic) > maxTopicLength {
+		return fmt.Errorf("topic %s is longer than %d", topic, maxTopicLength)
+	}
+	for _, c := range topic {
+		if !legalTopicChar(c) {
+			return fmt.Errorf("topic %s contains illegal character %q", topic, c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kinesis
+
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/aws"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/pkg/errors"
+)
+
+const (
+	ServiceKinesis  = "kinesis"
+	ServiceFirehose = "firehose"
+
+	// the limits of a PutRecords request of Kinesis Data Streams
+	kinesisMaxRecords      = 500
+	kinesisMaxBytes        = 5 * 1024 * 1024
+	kinesisMaxRecordBytes  = 1024 * 1024
+	kinesisMaxPartitionKey = 256
+	// the limits of a PutRecordBatch request of Firehose
+	firehoseMaxRecords     = 500
+	firehoseMaxBytes       = 4 * 1024 * 1024
+	firehoseMaxRecordBytes = 1000 * 1024
+)
+
+type Config struct {
+	aws.Config `yaml:",inline"`
+
+	// Service could be kinesis for Kinesis Data Streams or firehose for Amazon Data Firehose
+	Service    string `yaml:"service,omitempty" default:"kinesis" validate:"oneof=kinesis firehose"`
+	StreamName string `yaml:"streamName,omitempty" validate:"required"`
+	// PartitionKey could be a pattern such as ${fields.podname}, the records with the same key are written to the same shard
+	// in order. A random key is used when it is empty, which spreads the records evenly. It is ignored by firehose.
+	PartitionKey string `yaml:"partitionKey,omitempty"`
+	// AppendNewline appends a newline to each record, which delimits the records concatenated by firehose in the destinations
+	// such as s3. It defaults to true for firehose and false for kinesis.
+	AppendNewline *bool `yaml:"appendNewline,omitempty"`
+
+	// the limits of a request, the batch is split when exceeded, which are at most 500 records and 5MB for kinesis,
+	// 500 records and 4MB for firehose
+	MaxBatchRecords int `yaml:"maxBatchRecords,omitempty" default:"500" validate:"gte=1"`
+	MaxBatchBytes   int `yaml:"maxBatchBytes,omitempty" validate:"gte=0"`
+	// MaxRetries of the records failed in a request, such as throttled by the shards, before failing the batch
+	MaxRetries int           `yaml:"maxRetries,omitempty" default:"3" validate:"gte=0"`
+	Timeout    time.Duration `yaml:"timeout,omitempty" default:"30s"`
+}
+
+func (c *Config) SetDefaults() {
+	c.Config.SetDefaults()
+	if c.AppendNewline == nil {
+		appendNewline := c.Service == ServiceFirehose
+		c.AppendNewline = &appendNewline
+	}
+	if c.MaxBatchBytes == 0 {
+		c.MaxBatchBytes = c.maxBytes()
+	}
+}
+
+func (c *Config) Validate() error {
+	if err := c.Config.Validate(); err != nil {
+		return err
+	}
+	if err := pattern.Validate(c.PartitionKey); err != nil {
+		return err
+	}
+	if c.MaxBatchRecords > c.maxRecords() {
+		return errors.Errorf("maxBatchRecords of %s should not be greater than %d", c.Service, c.maxRecords())
+	}
+	if c.MaxBatchBytes > c.maxBytes() {
+		return errors.Errorf("maxBatchBytes of %s should not be greater than %d", c.Service, c.maxBytes())
+	}
+	return nil
+}
+
+func (c *Config) maxRecords() int {
+	if c.Service == ServiceFirehose {
+		return firehoseMaxRecords
+	}
+	return kinesisMaxRecords
+}
+
+func (c *Config) maxBytes() int {
+	if c.Service == ServiceFirehose {
+		return firehoseMaxBytes
+	}
+	return kinesisMaxBytes
+}
+
+func (c *Config) maxRecordBytes() int {
+	if c.Service == ServiceFirehose {
+		return firehoseMaxRecordBytes
+	}
+	return kinesisMaxRecordBytes
+}
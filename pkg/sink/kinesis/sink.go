@@ -0,0 +1,334 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kinesis
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/aws"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const (
+	Type = "kinesis"
+
+	contentType    = "application/x-amz-json-1.1"
+	kinesisTarget  = "Kinesis_20131202.PutRecords"
+	firehoseTarget = "Firehose_20150804.PutRecordBatch"
+
+	retryBackoff = 100 * time.Millisecond
+)
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink()
+}
+
+type Sink struct {
+	name   string
+	config *Config
+	codec  codec.Codec
+	creds  *aws.CredentialsProvider
+	client *http.Client
+
+	partitionKeyPattern *pattern.Pattern
+}
+
+func NewSink() *Sink {
+	return &Sink{
+		config: &Config{},
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) SetCodec(c codec.Codec) {
+	s.codec = c
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.partitionKeyPattern, _ = pattern.Init(s.config.PartitionKey)
+	s.creds = aws.NewCredentialsProvider(&s.config.Config)
+	s.client = &http.Client{
+		Timeout: s.config.Timeout,
+	}
+	return nil
+}
+
+func (s *Sink) Start() error {
+	log.Info("%s start, %s stream: %s, endpoint: %s", s.String(), s.config.Service, s.config.StreamName, s.config.ServiceEndpoint(s.config.Service))
+	return nil
+}
+
+func (s *Sink) Stop() {
+}
+
+type record struct {
+	Data         []byte `json:"Data"`
+	PartitionKey string `json:"PartitionKey,omitempty"`
+}
+
+// size is the bytes accounted in the limits of the requests
+func (r *record) size() int {
+	return len(r.Data) + len(r.PartitionKey)
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	records := make([]*record, 0, len(events))
+	for _, e := range events {
+		r, err := s.encode(e)
+		if err != nil {
+			log.Warn("[%s] encode event error: %v", s.name, err)
+			continue
+		}
+		// the service would reject the whole request
+		if r.size() > s.config.maxRecordBytes() || r.size() > s.config.MaxBatchBytes {
+			log.Warn("[%s] drop the record of %d bytes which exceeds the limit", s.name, r.size())
+			continue
+		}
+		records = append(records, r)
+	}
+
+	for _, chunk := range split(records, s.config.MaxBatchRecords, s.config.MaxBatchBytes) {
+		if err := s.put(context.Background(), chunk); err != nil {
+			return result.Fail(errors.WithMessagef(err, "put records to %s stream %s", s.config.Service, s.config.StreamName))
+		}
+	}
+	return result.Success()
+}
+
+func (s *Sink) encode(e api.Event) (*record, error) {
+	r := &record{}
+	// render the key ahead of the codec, which may modify the header
+	if s.config.Service == ServiceKinesis {
+		key, err := s.partitionKeyPattern.WithObject(runtime.NewObject(e.Header())).Render()
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			key = randomKey()
+		}
+		r.PartitionKey = truncate(key, kinesisMaxPartitionKey)
+	}
+
+	data, err := s.codec.Encode(e)
+	if err != nil {
+		return nil, err
+	}
+	if *s.config.AppendNewline {
+		// the data may share the array of the body
+		data = append(data[:len(data):len(data)], '\n')
+	}
+	r.Data = data
+	return r, nil
+}
+
+func randomKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// truncate cuts the string to n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// split packs the records into requests limited by the number of records and the bytes
+func split(records []*record, maxRecords int, maxBytes int) [][]*record {
+	var chunks [][]*record
+	var chunk []*record
+	size := 0
+	for _, r := range records {
+		if len(chunk) >= maxRecords || (len(chunk) > 0 && size+r.size() > maxBytes) {
+			chunks = append(chunks, chunk)
+			chunk = nil
+			size = 0
+		}
+		chunk = append(chunk, r)
+		size += r.size()
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+type kinesisRequest struct {
+	StreamName string    `json:"StreamName"`
+	Records    []*record `json:"Records"`
+}
+
+type firehoseRequest struct {
+	DeliveryStreamName string    `json:"DeliveryStreamName"`
+	Records            []*record `json:"Records"`
+}
+
+type recordResult struct {
+	ErrorCode    string `json:"ErrorCode"`
+	ErrorMessage string `json:"ErrorMessage"`
+}
+
+type putResponse struct {
+	// kinesis
+	FailedRecordCount int            `json:"FailedRecordCount"`
+	Records           []recordResult `json:"Records"`
+	// firehose
+	FailedPutCount   int            `json:"FailedPutCount"`
+	RequestResponses []recordResult `json:"RequestResponses"`
+}
+
+// put sends the records, and resends the failed ones such as throttled until maxRetries
+func (s *Sink) put(ctx context.Context, records []*record) error {
+	for attempt := 0; ; attempt++ {
+		failed, err := s.putOnce(ctx, records)
+		if err != nil {
+			return err
+		}
+		if len(failed) == 0 {
+			return nil
+		}
+		if attempt >= s.config.MaxRetries {
+			return errors.Errorf("%d of %d records failed after %d retries: %s", len(failed), len(records), attempt, failed[0].err)
+		}
+		log.Debug("[%s] retry %d failed records: %s", s.name, len(failed), failed[0].err)
+		records = records[:0:0]
+		for _, f := range failed {
+			records = append(records, f.record)
+		}
+		time.Sleep(retryBackoff << attempt)
+	}
+}
+
+type failedRecord struct {
+	record *record
+	err    string
+}
+
+func (s *Sink) putOnce(ctx context.Context, records []*record) ([]failedRecord, error) {
+	var in interface{}
+	target := kinesisTarget
+	if s.config.Service == ServiceFirehose {
+		target = firehoseTarget
+		in = &firehoseRequest{DeliveryStreamName: s.config.StreamName, Records: records}
+	} else {
+		in = &kinesisRequest{StreamName: s.config.StreamName, Records: records}
+	}
+
+	out := &putResponse{}
+	if err := s.call(ctx, target, in, out); err != nil {
+		return nil, err
+	}
+
+	results := out.Records
+	if s.config.Service == ServiceFirehose {
+		results = out.RequestResponses
+	}
+	var failed []failedRecord
+	for i, r := range results {
+		if r.ErrorCode == "" || i >= len(records) {
+			continue
+		}
+		failed = append(failed, failedRecord{
+			record: records[i],
+			err:    r.ErrorCode + ": " + r.ErrorMessage,
+		})
+	}
+	return failed, nil
+}
+
+func (s *Sink) call(ctx context.Context, target string, in interface{}, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.ServiceEndpoint(s.config.Service)+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Amz-Target", target)
+
+	creds, err := s.creds.Retrieve(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "retrieve aws credentials")
+	}
+	aws.Sign(req, body, s.config.Service, s.config.Region, creds, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		if len(respBody) > 1024 {
+			respBody = respBody[:1024]
+		}
+		return errors.Errorf("%s returned status %d: %s", s.config.Service, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kinesis
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	lcontext "github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/codec/json"
+	ljson "github.com/loggie-io/loggie/pkg/util/json"
+)
+
+func newTestSink(t *testing.T, endpoint string, extra string) *Sink {
+	log.InitDefaultLogger()
+	s := NewSink()
+	raw := "endpoint: " + endpoint + "\nregion: us-east-1\naccessKeyId: ak\nsecretAccessKey: sk\nstreamName: logs\n" + extra
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), s.config).Defaults().Validate().Do())
+	c := json.NewJson()
+	c.Init(&codec.Config{})
+	s.SetCodec(c)
+	assert.NoError(t, s.Init(lcontext.NewContext("kinesis", Type, api.SINK, nil)))
+	return s
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name        string
+		extra       string
+		wantKey     string
+		wantNewline bool
+	}{
+		{name: "kinesis", extra: "partitionKey: ${fields.pod}", wantKey: "pod-1"},
+		{name: "kinesis with random key", extra: "partitionKey: ${fields.missing}"},
+		{name: "firehose", extra: "service: firehose\npartitionKey: ${fields.pod}", wantNewline: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSink(t, "http://127.0.0.1", tt.extra)
+			e := event.NewEvent(map[string]interface{}{
+				"fields": map[string]interface{}{"pod": "pod-1"},
+			}, []byte("log"))
+
+			r, err := s.encode(e)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantNewline, strings.HasSuffix(string(r.Data), "}\n"))
+			switch {
+			case tt.wantKey != "":
+				assert.Equal(t, tt.wantKey, r.PartitionKey)
+			case s.config.Service == ServiceKinesis:
+				assert.Len(t, r.PartitionKey, 32)
+			default:
+				assert.Empty(t, r.PartitionKey)
+			}
+		})
+	}
+}
+
+func TestPut(t *testing.T) {
+	tests := []struct {
+		name         string
+		extra        string
+		throttled    int // the number of the requests, the first record of which fails with throttling
+		wantRequests []int
+		wantErr      bool
+	}{
+		{name: "kinesis", wantRequests: []int{3}},
+		{name: "throttled record resent alone", throttled: 1, wantRequests: []int{3, 1}},
+		{name: "firehose throttled", extra: "service: firehose", throttled: 1, wantRequests: []int{3, 1}},
+		{name: "retries exhausted", extra: "maxRetries: 1", throttled: 10, wantRequests: []int{3, 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttled := tt.throttled
+			var requests []int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=ak/"))
+				body, _ := io.ReadAll(r.Body)
+				req := &kinesisRequest{}
+				assert.NoError(t, ljson.Unmarshal(body, req))
+				requests = append(requests, len(req.Records))
+
+				results := make([]string, len(req.Records))
+				failed := 0
+				for i := range req.Records {
+					if throttled > 0 && i == 0 {
+						throttled--
+						failed++
+						results[i] = `{"ErrorCode":"ProvisionedThroughputExceededException","ErrorMessage":"Rate exceeded"}`
+						continue
+					}
+					results[i] = `{"SequenceNumber":"1"}`
+				}
+				if r.Header.Get("X-Amz-Target") == firehoseTarget {
+					fmt.Fprintf(w, `{"FailedPutCount":%d,"RequestResponses":[%s]}`, failed, strings.Join(results, ","))
+					return
+				}
+				fmt.Fprintf(w, `{"FailedRecordCount":%d,"Records":[%s]}`, failed, strings.Join(results, ","))
+			}))
+			defer server.Close()
+
+			s := newTestSink(t, server.URL, tt.extra)
+			records := []*record{{Data: []byte("a")}, {Data: []byte("b")}, {Data: []byte("c")}}
+			err := s.put(context.Background(), records)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "ProvisionedThroughputExceededException")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantRequests, requests)
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	records := []*record{
+		{Data: []byte("aaaa"), PartitionKey: "k"},
+		{Data: []byte("bbbb"), PartitionKey: "k"},
+		{Data: []byte("cccc"), PartitionKey: "k"},
+	}
+	tests := []struct {
+		name       string
+		maxRecords int
+		maxBytes   int
+		want       []int
+	}{
+		{name: "one request", maxRecords: 500, maxBytes: 1000, want: []int{3}},
+		{name: "by max records", maxRecords: 1, maxBytes: 1000, want: []int{1, 1, 1}},
+		{name: "by max bytes", maxRecords: 500, maxBytes: 10, want: []int{2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, chunk := range split(records, tt.maxRecords, tt.maxBytes) {
+				got = append(got, len(chunk))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name              string
+		raw               string
+		wantMaxBatchBytes int
+		wantNewline       bool
+		wantErr           bool
+	}{
+		{name: "kinesis defaults", raw: "", wantMaxBatchBytes: kinesisMaxBytes},
+		{name: "firehose defaults", raw: "service: firehose", wantMaxBatchBytes: firehoseMaxBytes, wantNewline: true},
+		{name: "firehose max batch bytes", raw: "service: firehose\nmaxBatchBytes: 5242880", wantErr: true},
+		{name: "max batch records", raw: "maxBatchRecords: 501", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			err := cfg.UnPackFromRaw([]byte("region: us-east-1\nstreamName: s\n"+tt.raw), c).Defaults().Validate().Do()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMaxBatchBytes, c.MaxBatchBytes)
+			assert.Equal(t, tt.wantNewline, *c.AppendNewline)
+		})
+	}
+}
@@ -16,7 +16,15 @@ limitations under the License.
 
 package loki
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
 
 type Config struct {
 	URL                string            `yaml:"url,omitempty" validate:"required"`
@@ -26,4 +34,27 @@ type Config struct {
 	EntryLine          string            `yaml:"entryLine,omitempty"`
 	Headers            map[string]string `yaml:"header,omitempty"`
 	InsecureSkipVerify bool              `yaml:"insecureSkipVerify" default:"false"`
+	HostLimit          hostlimit.Config  `yaml:"hostLimit,omitempty"`
+
+	// Labels are the label names and the value patterns, such as app: ${fields.app}, labels with empty value are skipped.
+	// All the string fields of the header are used as labels if empty, which may lead to high cardinality.
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// MaxLabels should not exceed max_label_names_per_series of loki, the labels are kept in the order of names
+	MaxLabels           int `yaml:"maxLabels,omitempty" default:"15" validate:"gte=1"`
+	MaxLabelValueLength int `yaml:"maxLabelValueLength,omitempty" default:"2048" validate:"gte=1"`
+	// MaxLabelValues limits the distinct values of each label, the new values beyond it are replaced with
+	// loggie_overflow until the pipeline is reloaded, 0 means no limit
+	MaxLabelValues int `yaml:"maxLabelValues,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	for name, value := range c.Labels {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("loki sink label name %s is invalid", name)
+		}
+		if err := pattern.Validate(value); err != nil {
+			return err
+		}
+	}
+	return nil
 }
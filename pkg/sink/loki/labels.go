@@ -0,0 +1,157 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loki
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const overflowLabelValue = "loggie_overflow"
+
+type labelPattern struct {
+	name    model.LabelName
+	pattern *pattern.Pattern
+}
+
+func newLabelPatterns(labels map[string]string) []labelPattern {
+	var patterns []labelPattern
+	for name, value := range labels {
+		p, _ := pattern.Init(value)
+		patterns = append(patterns, labelPattern{
+			name:    model.LabelName(name),
+			pattern: p,
+		})
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		return patterns[i].name < patterns[j].name
+	})
+	return patterns
+}
+
+// extractLabels renders the configured labels, or converts the string fields of the header if not configured
+func (s *Sink) extractLabels(obj *runtime.Object) (model.LabelSet, error) {
+	labelSet := model.LabelSet{}
+	if len(s.labelPatterns) > 0 {
+		for _, l := range s.labelPatterns {
+			value, err := l.pattern.WithObject(obj).Render()
+			if err != nil {
+				log.Warn("render loki label %s failed: %v", l.name, err)
+				continue
+			}
+			if value == "" {
+				continue
+			}
+			labelSet[l.name] = model.LabelValue(value)
+		}
+		return labelSet, nil
+	}
+
+	flatHeader, err := obj.FlatKeyValue(token)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range flatHeader {
+		// we will ignore non-string value in header
+		sv, ok := v.(string)
+		if !ok || sv == "" {
+			continue
+		}
+
+		if !model.LabelName(k).IsValid() {
+			k = tryConvertKeyToUnderscore(k)
+		}
+
+		labelSet[model.LabelName(k)] = model.LabelValue(sv)
+	}
+	return labelSet, nil
+}
+
+// guardLabels keeps the labels in the limits of loki, so the streams would not be rejected or explode the index
+func (s *Sink) guardLabels(labelSet model.LabelSet) {
+	if len(labelSet) > s.config.MaxLabels {
+		names := make([]string, 0, len(labelSet))
+		for name := range labelSet {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+		for _, name := range names[s.config.MaxLabels:] {
+			delete(labelSet, model.LabelName(name))
+		}
+		log.Debug("loki labels exceed maxLabels %d, dropped labels: %s", s.config.MaxLabels, strings.Join(names[s.config.MaxLabels:], ","))
+	}
+
+	for name, value := range labelSet {
+		if len(value) > s.config.MaxLabelValueLength {
+			value = value[:s.config.MaxLabelValueLength]
+		}
+		labelSet[name] = s.cardinality.check(name, value)
+	}
+}
+
+// cardinalityGuard counts the distinct values of each label
+type cardinalityGuard struct {
+	max int
+
+	lock   sync.Mutex
+	values map[model.LabelName]map[model.LabelValue]struct{}
+	warned map[model.LabelName]bool
+}
+
+func newCardinalityGuard(max int) *cardinalityGuard {
+	if max <= 0 {
+		return nil
+	}
+	return &cardinalityGuard{
+		max:    max,
+		values: make(map[model.LabelName]map[model.LabelValue]struct{}),
+		warned: make(map[model.LabelName]bool),
+	}
+}
+
+func (g *cardinalityGuard) check(name model.LabelName, value model.LabelValue) model.LabelValue {
+	if g == nil {
+		return value
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	values, ok := g.values[name]
+	if !ok {
+		values = make(map[model.LabelValue]struct{})
+		g.values[name] = values
+	}
+	if _, ok := values[value]; ok {
+		return value
+	}
+	if len(values) >= g.max {
+		if !g.warned[name] {
+			log.Warn("distinct values of loki label %s exceed maxLabelValues %d, the new values are replaced with %s", name, g.max, overflowLabelValue)
+			g.warned[name] = true
+		}
+		return overflowLabelValue
+	}
+	values[value] = struct{}{}
+	return value
+}
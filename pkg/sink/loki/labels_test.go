@@ -0,0 +1,78 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func newTestEvent(app string, pod string, body string) api.Event {
+	e := event.NewEvent(map[string]interface{}{
+		"fields": map[string]interface{}{"app": app, "pod": pod},
+	}, []byte(body))
+	e.Fill(event.NewDefaultMeta(), e.Header(), e.Body())
+	return e
+}
+
+func TestBatch2Streams(t *testing.T) {
+	log.InitDefaultLogger()
+	s := NewSink("test")
+	s.config = &Config{
+		Labels: map[string]string{
+			"app": "${fields.app}",
+			"pod": "${fields.pod}",
+			"env": "prod",
+		},
+		MaxLabels:           15,
+		MaxLabelValueLength: 2048,
+		MaxLabelValues:      2,
+	}
+	assert.NoError(t, s.config.Validate())
+	s.labelPatterns = newLabelPatterns(s.config.Labels)
+	s.cardinality = newCardinalityGuard(s.config.MaxLabelValues)
+
+	streams := s.batch2streams([]api.Event{
+		newTestEvent("nginx", "nginx-0", "a"),
+		newTestEvent("nginx", "nginx-1", "b"),
+		newTestEvent("nginx", "nginx-0", "c"),
+		// the third pod exceeds maxLabelValues
+		newTestEvent("nginx", "nginx-2", "d"),
+		newTestEvent("nginx", "nginx-3", "e"),
+	})
+	assert.Len(t, streams, 3)
+	assert.Equal(t, `{app="nginx", env="prod", pod="nginx-0"}`, streams[0].Labels)
+	assert.Len(t, streams[0].Entries, 2)
+	assert.Equal(t, `{app="nginx", env="prod", pod="nginx-1"}`, streams[1].Labels)
+	assert.Equal(t, `{app="nginx", env="prod", pod="loggie_overflow"}`, streams[2].Labels)
+	assert.Len(t, streams[2].Entries, 2)
+
+	// labels are kept in the order of names
+	s.config.MaxLabels = 2
+	s.config.MaxLabelValueLength = 3
+	streams = s.batch2streams([]api.Event{newTestEvent("nginx", "nginx-0", "a")})
+	assert.Len(t, streams, 1)
+	assert.Equal(t, `{app="ngi", env="pro"}`, streams[0].Labels)
+
+	s.config.Labels = map[string]string{"app-name": "${fields.app}"}
+	assert.Error(t, s.config.Validate())
+}
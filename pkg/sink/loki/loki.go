@@ -34,6 +34,7 @@ import (
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/core/result"
 	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
 	"github.com/loggie-io/loggie/pkg/sink/loki/logproto"
 	"github.com/loggie-io/loggie/pkg/util/runtime"
 	"github.com/pkg/errors"
@@ -51,18 +52,24 @@ func init() {
 }
 
 func makeSink(info pipeline.Info) api.Component {
-	return NewSink()
+	return NewSink(info.PipelineName)
 }
 
 type Sink struct {
-	name   string
-	config *Config
-	client *http.Client
+	pipelineName string
+	name         string
+	config       *Config
+	client       *http.Client
+	limiter      *hostlimit.Transport
+
+	labelPatterns []labelPattern
+	cardinality   *cardinalityGuard
 }
 
-func NewSink() *Sink {
+func NewSink(pipelineName string) *Sink {
 	return &Sink{
-		config: &Config{},
+		pipelineName: pipelineName,
+		config:       &Config{},
 	}
 }
 
@@ -84,6 +91,8 @@ func (s *Sink) String() string {
 
 func (s *Sink) Init(context api.Context) error {
 	s.name = context.Name()
+	s.labelPatterns = newLabelPatterns(s.config.Labels)
+	s.cardinality = newCardinalityGuard(s.config.MaxLabelValues)
 
 	defaultHTTPConfig := config.DefaultHTTPClientConfig
 	defaultHTTPConfig.TLSConfig.InsecureSkipVerify = s.config.InsecureSkipVerify
@@ -99,12 +108,19 @@ func (s *Sink) Init(context api.Context) error {
 }
 
 func (s *Sink) Start() error {
+	if s.client != nil && s.config.HostLimit.Enabled() {
+		s.limiter = hostlimit.NewTransport(s.client.Transport, &s.config.HostLimit, s.pipelineName, s.name)
+		s.client.Transport = s.limiter
+	}
 	log.Info("%s started", s.String())
 
 	return nil
 }
 
 func (s *Sink) Stop() {
+	if s.limiter != nil {
+		s.limiter.Stop()
+	}
 }
 
 func (s *Sink) Consume(batch api.Batch) api.Result {
@@ -125,14 +141,9 @@ func (s *Sink) sendBatch(c context.Context, batch api.Batch) api.Result {
 	ctx, cancel := context.WithTimeout(c, s.config.Timeout)
 	defer cancel()
 
-	var streams []logproto.Stream
-	for _, event := range batch.Events() {
-		stream, err := s.event2stream(event)
-		if err != nil {
-			return result.Drop().WithError(errors.WithMessage(err, "convert event to loki stream error"))
-		}
-
-		streams = append(streams, *stream)
+	streams := s.batch2streams(batch.Events())
+	if len(streams) == 0 {
+		return result.Drop().WithError(errors.New("no valid event to send to loki"))
 	}
 
 	var req *http.Request
@@ -168,32 +179,41 @@ func (s *Sink) sendBatch(c context.Context, batch api.Batch) api.Result {
 
 const token = "_"
 
-func (s *Sink) event2stream(event api.Event) (*logproto.Stream, error) {
-	t, ok := event.Meta().Get(eventer.SystemProductTimeKey)
-	if !ok {
-		t = time.Now()
-	}
-
-	obj := runtime.NewObject(event.Header())
-	flatHeader, err := obj.FlatKeyValue(token)
-	if err != nil {
-		return nil, err
-	}
+// batch2streams groups the entries with the same labels into one stream, the events could not be converted are skipped
+func (s *Sink) batch2streams(events []api.Event) []logproto.Stream {
+	var streams []logproto.Stream
+	index := make(map[string]int)
+	for _, event := range events {
+		stream, err := s.event2stream(event)
+		if err != nil {
+			log.Warn("convert event to loki stream error: %v, event: %s", err, event.String())
+			continue
+		}
 
-	labelSet := model.LabelSet{}
-	for k, v := range flatHeader {
-		// we will ignore non-string value in header
-		sv, ok := v.(string)
-		if !ok {
+		if i, ok := index[stream.Labels]; ok {
+			streams[i].Entries = append(streams[i].Entries, stream.Entries...)
 			continue
 		}
+		index[stream.Labels] = len(streams)
+		streams = append(streams, *stream)
+	}
+	return streams
+}
 
-		if !model.LabelName(k).IsValid() {
-			k = tryConvertKeyToUnderscore(k)
+func (s *Sink) event2stream(event api.Event) (*logproto.Stream, error) {
+	var t interface{} = time.Now()
+	if event.Meta() != nil {
+		if pt, ok := event.Meta().Get(eventer.SystemProductTimeKey); ok {
+			t = pt
 		}
+	}
 
-		labelSet[model.LabelName(k)] = model.LabelValue(sv)
+	obj := runtime.NewObject(event.Header())
+	labelSet, err := s.extractLabels(obj)
+	if err != nil {
+		return nil, err
 	}
+	s.guardLabels(labelSet)
 
 	// At least one label pair is required per stream in loki
 	if len(labelSet) == 0 {
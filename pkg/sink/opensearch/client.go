@@ -0,0 +1,217 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opensearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/aws"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+)
+
+type client struct {
+	config   *Config
+	http     *http.Client
+	creds    *aws.CredentialsProvider
+	password string
+	next     uint32
+}
+
+func newClient(config *Config, wrapTransport func(http.RoundTripper) http.RoundTripper) (*client, error) {
+	for i, h := range config.Hosts {
+		if !strings.HasPrefix(h, "http") {
+			config.Hosts[i] = fmt.Sprintf("https://%s", h)
+		}
+		config.Hosts[i] = strings.TrimSuffix(config.Hosts[i], "/")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig, err := netutils.NewTLSConfig(config.CACertPath, "", "", config.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	var rt http.RoundTripper = transport
+	if wrapTransport != nil {
+		rt = wrapTransport(transport)
+	}
+
+	c := &client{
+		config: config,
+		http: &http.Client{
+			Transport: rt,
+			Timeout:   config.Timeout,
+		},
+	}
+	if config.AWS != nil {
+		c.creds = aws.NewCredentialsProvider(config.AWS)
+	}
+	if config.PasswordFile != "" {
+		content, err := os.ReadFile(config.PasswordFile)
+		if err != nil {
+			return nil, errors.WithMessage(err, "read password")
+		}
+		c.password = strings.TrimSpace(string(content))
+	} else {
+		c.password = config.Password
+	}
+	return c, nil
+}
+
+// host returns the hosts in turn
+func (c *client) host() string {
+	n := atomic.AddUint32(&c.next, 1)
+	return c.config.Hosts[int(n)%len(c.config.Hosts)]
+}
+
+// do sends the request and returns the response body, errors are returned for the non 2xx responses
+// except 404, which is returned with the status so the callers could check whether the resource exists
+func (c *client) do(ctx context.Context, method string, path string, query url.Values, body []byte) (int, []byte, error) {
+	u := c.host() + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	payload := body
+	compressed := false
+	if c.config.Compress && len(body) > 0 {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(body); err != nil {
+			return 0, nil, err
+		}
+		if err := w.Close(); err != nil {
+			return 0, nil, err
+		}
+		payload = buf.Bytes()
+		compressed = true
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, err
+	}
+	for k, v := range c.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	if c.creds != nil {
+		creds, err := c.creds.Retrieve(ctx)
+		if err != nil {
+			return 0, nil, errors.WithMessage(err, "retrieve aws credentials")
+		}
+		aws.Sign(req, payload, c.config.service(), c.config.AWS.Region, creds, time.Now())
+	} else if c.config.UserName != "" {
+		req.SetBasicAuth(c.config.UserName, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, errors.WithMessagef(err, "read response of %s %s", method, path)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return resp.StatusCode, respBody, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, respBody, errors.Errorf("%s %s returned status %d: %s", method, path, resp.StatusCode, truncate(respBody, 1024))
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkResponseItem `json:"items"`
+}
+
+type bulkResponseItem struct {
+	Index  string `json:"_index"`
+	Status int    `json:"status"`
+	Error  struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// bulk sends the lines and returns the number of rejected items and the reason of the first one
+func (c *client) bulk(ctx context.Context, body []byte) (int, int, string, error) {
+	query := url.Values{}
+	for k, v := range c.config.Params {
+		query.Set(k, v)
+	}
+	status, respBody, err := c.do(ctx, http.MethodPost, "/_bulk", query, body)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if status == http.StatusNotFound {
+		return 0, 0, "", errors.Errorf("bulk returned status 404: %s", truncate(respBody, 1024))
+	}
+
+	resp := &bulkResponse{}
+	if err := json.Unmarshal(respBody, resp); err != nil {
+		return 0, 0, "", errors.WithMessagef(err, "unmarshal bulk response: %s", truncate(respBody, 1024))
+	}
+	if !resp.Errors {
+		return len(resp.Items), 0, "", nil
+	}
+
+	failed := 0
+	reason := ""
+	for _, item := range resp.Items {
+		for _, r := range item {
+			if r.Status >= 200 && r.Status <= 299 {
+				continue
+			}
+			if failed == 0 {
+				reason = fmt.Sprintf("index: %s, status: %d, type: %s, reason: %s", r.Index, r.Status, r.Error.Type, r.Error.Reason)
+			}
+			failed++
+		}
+	}
+	return len(resp.Items), failed, reason, nil
+}
+
+func truncate(b []byte, n int) string {
+	if len(b) > n {
+		return string(b[:n]) + "..."
+	}
+	return string(b)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opensearch
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/aws"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
+
+const (
+	// bulk requests to OpenSearch Serverless are limited to 10MB
+	serverlessMaxBulkBytes = 10 << 20
+
+	paramRefresh = "refresh"
+)
+
+type Config struct {
+	Hosts              []string          `yaml:"hosts,omitempty" validate:"required"`
+	Index              string            `yaml:"index,omitempty" validate:"required"`
+	DocumentId         string            `yaml:"documentId,omitempty"`
+	OpType             string            `yaml:"opType,omitempty" default:"index" validate:"oneof=index create"`
+	Headers            map[string]string `yaml:"headers,omitempty"`
+	Params             map[string]string `yaml:"parameters,omitempty"`
+	Timeout            time.Duration     `yaml:"timeout,omitempty" default:"30s"`
+	Compress           bool              `yaml:"compress,omitempty"`
+	UserName           string            `yaml:"username,omitempty"`
+	Password           string            `yaml:"password,omitempty"`
+	PasswordFile       string            `yaml:"passwordFile,omitempty"`
+	CACertPath         string            `yaml:"caCertPath,omitempty"`
+	InsecureSkipVerify bool              `yaml:"insecureSkipVerify,omitempty"`
+
+	// AWS signs the requests with SigV4 for Amazon OpenSearch Service instead of basic auth
+	AWS *aws.Config `yaml:"aws,omitempty"`
+	// Serverless sends to the collections of Amazon OpenSearch Serverless, which only accepts SigV4 signed requests
+	// and does not support _refresh, ISM and the security plugin
+	Serverless bool `yaml:"serverless,omitempty"`
+	// MaxBulkBytes splits a batch into multiple bulk requests, 10MB by default for serverless and not limited otherwise
+	MaxBulkBytes int64 `yaml:"maxBulkBytes,omitempty"`
+
+	// Distribution and Version skip getting the version from the cluster on startup, such as elasticsearch and 7.10
+	// for the legacy Elasticsearch domains, which are needed when the user is not allowed to get the cluster info
+	Distribution string `yaml:"distribution,omitempty" default:"opensearch" validate:"oneof=opensearch elasticsearch"`
+	Version      string `yaml:"version,omitempty"`
+
+	ISM       ISM              `yaml:"ism,omitempty"`
+	Role      Role             `yaml:"role,omitempty"`
+	HostLimit hostlimit.Config `yaml:"hostLimit,omitempty"`
+}
+
+// ISM bootstraps the index state management policy of the `_plugins/_ism` api on startup,
+// the policy is attached to the new indices matching the indexPatterns by the ism_template.
+type ISM struct {
+	Enabled       bool     `yaml:"enabled,omitempty"`
+	PolicyName    string   `yaml:"policyName,omitempty" default:"loggie"`
+	IndexPatterns []string `yaml:"indexPatterns,omitempty"`
+	Priority      int      `yaml:"priority,omitempty" default:"100"`
+	// PolicyFile is a json file of the policy, a policy is generated with the options below if it is empty
+	PolicyFile          string `yaml:"policyFile,omitempty"`
+	RolloverMinSize     string `yaml:"rolloverMinSize,omitempty" default:"50gb"`
+	RolloverMinIndexAge string `yaml:"rolloverMinIndexAge,omitempty" default:"1d"`
+	// DeleteAfter deletes the indices older than it, they are kept forever if it is empty
+	DeleteAfter string `yaml:"deleteAfter,omitempty"`
+	// RolloverAlias is the write alias of the rolling indices which should be the index of the sink,
+	// the first index <alias>-000001 and an index template setting the rollover alias are created if missing
+	RolloverAlias string `yaml:"rolloverAlias,omitempty"`
+	Overwrite     bool   `yaml:"overwrite,omitempty"`
+}
+
+// Role bootstraps a role of the security plugin which is allowed to write the indices, and maps it to
+// the users or backend roles such as the IAM role ARN of Amazon OpenSearch Service with fine-grained access control
+type Role struct {
+	Enabled        bool     `yaml:"enabled,omitempty"`
+	Name           string   `yaml:"name,omitempty" default:"loggie_writer"`
+	IndexPatterns  []string `yaml:"indexPatterns,omitempty"`
+	AllowedActions []string `yaml:"allowedActions,omitempty" default:"[\"crud\", \"create_index\"]"`
+	Users          []string `yaml:"users,omitempty"`
+	BackendRoles   []string `yaml:"backendRoles,omitempty"`
+}
+
+func (c *Config) SetDefaults() {
+	if c.AWS != nil {
+		c.AWS.SetDefaults()
+	}
+}
+
+func (c *Config) Validate() error {
+	if err := pattern.Validate(c.Index); err != nil {
+		return err
+	}
+	if err := pattern.Validate(c.DocumentId); err != nil {
+		return err
+	}
+	if c.Password != "" && c.PasswordFile != "" {
+		return errors.New("password and passwordFile cannot be set at the same time")
+	}
+	if c.Version != "" {
+		v, err := parseVersion(c.Distribution, c.Version)
+		if err != nil {
+			return err
+		}
+		if c.ISM.Enabled && !v.supportISM() {
+			return errors.Errorf("ism is not supported by %s", v)
+		}
+	}
+
+	if c.AWS != nil {
+		if c.UserName != "" {
+			return errors.New("username cannot be set together with aws")
+		}
+		if err := c.AWS.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Serverless {
+		if c.AWS == nil {
+			return errors.New("aws is required by serverless")
+		}
+		if _, ok := c.Params[paramRefresh]; ok {
+			return errors.New("refresh is not supported by serverless")
+		}
+		if c.ISM.Enabled {
+			return errors.New("ism is not supported by serverless, use the data lifecycle policies instead")
+		}
+		if c.Role.Enabled {
+			return errors.New("role is not supported by serverless, use the data access policies instead")
+		}
+	}
+
+	if c.ISM.Enabled {
+		if c.ISM.PolicyFile == "" && len(c.ISM.IndexPatterns) == 0 {
+			return errors.New("indexPatterns of ism is required")
+		}
+		if c.ISM.RolloverAlias != "" && c.ISM.RolloverAlias != c.Index {
+			return errors.New("index should be the rolloverAlias of ism")
+		}
+	}
+
+	if c.Role.Enabled && len(c.Role.IndexPatterns) == 0 {
+		return errors.New("indexPatterns of role is required")
+	}
+	return nil
+}
+
+func (c *Config) maxBulkBytes() int64 {
+	if c.MaxBulkBytes > 0 {
+		return c.MaxBulkBytes
+	}
+	if c.Serverless {
+		return serverlessMaxBulkBytes
+	}
+	return 0
+}
+
+func (c *Config) service() string {
+	if c.Serverless {
+		return aws.ServiceOpenSearchServerless
+	}
+	return aws.ServiceOpenSearch
+}
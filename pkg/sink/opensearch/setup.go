@@ -0,0 +1,256 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opensearch
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	setupTimeout = 30 * time.Second
+
+	settingRolloverAlias = "plugins.index_state_management.rollover_alias"
+)
+
+// setup creates the ISM policy, the rollover alias and the security role if they do not exist
+func setup(cli *client, config *Config, version clusterVersion) error {
+	if !config.ISM.Enabled && !config.Role.Enabled {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	if config.ISM.Enabled {
+		if !version.supportISM() {
+			return errors.Errorf("ism is not supported by %s", version)
+		}
+		if err := setupPolicy(ctx, cli, &config.ISM, version); err != nil {
+			return errors.WithMessagef(err, "setup ism policy %s", config.ISM.PolicyName)
+		}
+		if config.ISM.RolloverAlias != "" {
+			if err := setupRolloverAlias(ctx, cli, config.ISM.RolloverAlias, version); err != nil {
+				return errors.WithMessagef(err, "setup rollover alias %s", config.ISM.RolloverAlias)
+			}
+		}
+	}
+	if config.Role.Enabled {
+		if err := setupRole(ctx, cli, &config.Role, version); err != nil {
+			return errors.WithMessagef(err, "setup role %s", config.Role.Name)
+		}
+	}
+	return nil
+}
+
+func setupPolicy(ctx context.Context, cli *client, config *ISM, version clusterVersion) error {
+	path := version.pluginsPath() + "/_ism/policies/" + url.PathEscape(config.PolicyName)
+	status, body, err := cli.do(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if status != http.StatusNotFound {
+		if !config.Overwrite {
+			return nil
+		}
+		// updating a policy requires the sequence number and primary term of the current one
+		current := struct {
+			SeqNo       int64 `json:"_seq_no"`
+			PrimaryTerm int64 `json:"_primary_term"`
+		}{}
+		if err := json.Unmarshal(body, &current); err != nil {
+			return errors.WithMessage(err, "unmarshal current policy")
+		}
+		query.Set("if_seq_no", strconv.FormatInt(current.SeqNo, 10))
+		query.Set("if_primary_term", strconv.FormatInt(current.PrimaryTerm, 10))
+	}
+
+	policy, err := buildPolicy(config)
+	if err != nil {
+		return err
+	}
+	if _, _, err := cli.do(ctx, http.MethodPut, path, query, policy); err != nil {
+		return err
+	}
+	log.Info("opensearch ism policy %s is created", config.PolicyName)
+	return nil
+}
+
+func buildPolicy(config *ISM) ([]byte, error) {
+	if config.PolicyFile != "" {
+		return os.ReadFile(config.PolicyFile)
+	}
+
+	hot := map[string]interface{}{
+		"name":        "hot",
+		"actions":     []interface{}{},
+		"transitions": []interface{}{},
+	}
+	// rollover requires the rollover alias of the index
+	if config.RolloverAlias != "" {
+		rollover := map[string]interface{}{}
+		if config.RolloverMinSize != "" {
+			rollover["min_size"] = config.RolloverMinSize
+		}
+		if config.RolloverMinIndexAge != "" {
+			rollover["min_index_age"] = config.RolloverMinIndexAge
+		}
+		hot["actions"] = []interface{}{
+			map[string]interface{}{"rollover": rollover},
+		}
+	}
+	states := []interface{}{hot}
+	if config.DeleteAfter != "" {
+		hot["transitions"] = []interface{}{
+			map[string]interface{}{
+				"state_name": "delete",
+				"conditions": map[string]interface{}{"min_index_age": config.DeleteAfter},
+			},
+		}
+		states = append(states, map[string]interface{}{
+			"name": "delete",
+			"actions": []interface{}{
+				map[string]interface{}{"delete": map[string]interface{}{}},
+			},
+			"transitions": []interface{}{},
+		})
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"policy": map[string]interface{}{
+			"description":   "managed by loggie",
+			"default_state": "hot",
+			"states":        states,
+			"ism_template": []interface{}{
+				map[string]interface{}{
+					"index_patterns": config.IndexPatterns,
+					"priority":       config.Priority,
+				},
+			},
+		},
+	})
+}
+
+func setupRolloverAlias(ctx context.Context, cli *client, alias string, version clusterVersion) error {
+	// the indices created by rollover need the rollover alias setting too
+	settings := map[string]interface{}{version.rolloverAliasSetting(): alias}
+	template := "/_index_template/" + url.PathEscape(alias)
+	content := map[string]interface{}{
+		"index_patterns": []string{alias + "-*"},
+		"template":       map[string]interface{}{"settings": settings},
+	}
+	if !version.composableTemplate() {
+		template = "/_template/" + url.PathEscape(alias)
+		content = map[string]interface{}{
+			"index_patterns": []string{alias + "-*"},
+			"settings":       settings,
+		}
+	}
+	status, _, err := cli.do(ctx, http.MethodGet, template, nil, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNotFound {
+		body, err := json.Marshal(content)
+		if err != nil {
+			return err
+		}
+		if _, _, err := cli.do(ctx, http.MethodPut, template, nil, body); err != nil {
+			return err
+		}
+	}
+
+	status, _, err = cli.do(ctx, http.MethodGet, "/_alias/"+url.PathEscape(alias), nil, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNotFound {
+		return nil
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"aliases": map[string]interface{}{
+			alias: map[string]interface{}{"is_write_index": true},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	index := alias + "-000001"
+	if _, _, err := cli.do(ctx, http.MethodPut, "/"+url.PathEscape(index), nil, body); err != nil {
+		// created by other agents at the same time
+		if strings.Contains(err.Error(), "resource_already_exists_exception") {
+			return nil
+		}
+		return err
+	}
+	log.Info("opensearch index %s is created with write alias %s", index, alias)
+	return nil
+}
+
+func setupRole(ctx context.Context, cli *client, config *Role, version clusterVersion) error {
+	path := version.pluginsPath() + "/_security/api/roles/" + url.PathEscape(config.Name)
+	status, _, err := cli.do(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNotFound {
+		body, err := json.Marshal(map[string]interface{}{
+			"cluster_permissions": []string{"cluster_composite_ops", "cluster_monitor"},
+			"index_permissions": []interface{}{
+				map[string]interface{}{
+					"index_patterns":  config.IndexPatterns,
+					"allowed_actions": config.AllowedActions,
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if _, _, err := cli.do(ctx, http.MethodPut, path, nil, body); err != nil {
+			return err
+		}
+		log.Info("opensearch role %s is created", config.Name)
+	}
+
+	if len(config.Users) == 0 && len(config.BackendRoles) == 0 {
+		return nil
+	}
+	mapping := map[string]interface{}{}
+	if len(config.Users) > 0 {
+		mapping["users"] = config.Users
+	}
+	if len(config.BackendRoles) > 0 {
+		mapping["backend_roles"] = config.BackendRoles
+	}
+	body, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	_, _, err = cli.do(ctx, http.MethodPut, version.pluginsPath()+"/_security/api/rolesmapping/"+url.PathEscape(config.Name), nil, body)
+	return err
+}
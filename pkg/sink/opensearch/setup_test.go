@@ -0,0 +1,172 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opensearch
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+func TestBuildPolicy(t *testing.T) {
+	config := &ISM{
+		PolicyName:          "loggie",
+		IndexPatterns:       []string{"logs-*"},
+		Priority:            100,
+		RolloverMinSize:     "50gb",
+		RolloverMinIndexAge: "1d",
+		DeleteAfter:         "7d",
+		RolloverAlias:       "logs",
+	}
+	body, err := buildPolicy(config)
+	assert.NoError(t, err)
+
+	out := struct {
+		Policy struct {
+			DefaultState string `json:"default_state"`
+			States       []struct {
+				Name        string                   `json:"name"`
+				Actions     []map[string]interface{} `json:"actions"`
+				Transitions []map[string]interface{} `json:"transitions"`
+			} `json:"states"`
+			ISMTemplate []map[string]interface{} `json:"ism_template"`
+		} `json:"policy"`
+	}{}
+	assert.NoError(t, json.Unmarshal(body, &out))
+	assert.Equal(t, "hot", out.Policy.DefaultState)
+	assert.Equal(t, 2, len(out.Policy.States))
+	assert.Contains(t, out.Policy.States[0].Actions[0], "rollover")
+	assert.Equal(t, "delete", out.Policy.States[0].Transitions[0]["state_name"])
+	assert.Equal(t, "delete", out.Policy.States[1].Name)
+	assert.Equal(t, []interface{}{"logs-*"}, out.Policy.ISMTemplate[0]["index_patterns"])
+
+	// no rollover without the rollover alias
+	config.RolloverAlias = ""
+	config.DeleteAfter = ""
+	body, err = buildPolicy(config)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(body, &out))
+	assert.Equal(t, 1, len(out.Policy.States))
+	assert.Empty(t, out.Policy.States[0].Actions)
+}
+
+func TestSetup(t *testing.T) {
+	log.InitDefaultLogger()
+
+	var requests []string
+	existing := map[string]bool{
+		"/_plugins/_ism/policies/loggie": true,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodGet && !existing[r.URL.Path] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"_id":"loggie","_seq_no":3,"_primary_term":1}`)
+			return
+		}
+		fmt.Fprint(w, `{"acknowledged":true}`)
+	}))
+	defer srv.Close()
+
+	config := &Config{
+		Hosts: []string{srv.URL},
+		Index: "logs",
+		ISM: ISM{
+			Enabled:       true,
+			PolicyName:    "loggie",
+			IndexPatterns: []string{"logs-*"},
+			RolloverAlias: "logs",
+		},
+		Role: Role{
+			Enabled:        true,
+			Name:           "loggie_writer",
+			IndexPatterns:  []string{"logs*"},
+			AllowedActions: []string{"crud", "create_index"},
+			BackendRoles:   []string{"arn:aws:iam::123456789012:role/loggie"},
+		},
+	}
+	cli, err := newClient(config, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, setup(cli, config, clusterVersion{}))
+
+	assert.Equal(t, []string{
+		// the existing policy is kept without overwrite
+		"GET /_plugins/_ism/policies/loggie",
+		"GET /_index_template/logs",
+		"PUT /_index_template/logs",
+		"GET /_alias/logs",
+		"PUT /logs-000001",
+		"GET /_plugins/_security/api/roles/loggie_writer",
+		"PUT /_plugins/_security/api/roles/loggie_writer",
+		"PUT /_plugins/_security/api/rolesmapping/loggie_writer",
+	}, requests)
+}
+
+func TestSetupLegacy(t *testing.T) {
+	log.InitDefaultLogger()
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"acknowledged":true}`)
+	}))
+	defer srv.Close()
+
+	config := &Config{
+		Hosts: []string{srv.URL},
+		Index: "logs",
+		ISM: ISM{
+			Enabled:       true,
+			PolicyName:    "loggie",
+			IndexPatterns: []string{"logs-*"},
+			RolloverAlias: "logs",
+		},
+	}
+	cli, err := newClient(config, nil)
+	assert.NoError(t, err)
+	version, _ := parseVersion(DistributionElasticsearch, "7.4.2")
+	assert.NoError(t, setup(cli, config, version))
+
+	// open distro apis and the legacy index template
+	assert.Equal(t, []string{
+		"GET /_opendistro/_ism/policies/loggie",
+		"PUT /_opendistro/_ism/policies/loggie",
+		"GET /_template/logs",
+		"PUT /_template/logs",
+		"GET /_alias/logs",
+		"PUT /logs-000001",
+	}, requests)
+
+	version, _ = parseVersion(DistributionElasticsearch, "6.8.0")
+	assert.Error(t, setup(cli, config, version))
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const Type = "opensearch"
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink(info.PipelineName)
+}
+
+type Sink struct {
+	pipelineName string
+	name         string
+	config       *Config
+	codec        codec.Codec
+	cli          *client
+	limiter      *hostlimit.Transport
+	version      clusterVersion
+
+	indexPattern      *pattern.Pattern
+	documentIdPattern *pattern.Pattern
+}
+
+func NewSink(pipelineName string) *Sink {
+	return &Sink{
+		pipelineName: pipelineName,
+		config:       &Config{},
+	}
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) SetCodec(c codec.Codec) {
+	s.codec = c
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	return nil
+}
+
+func (s *Sink) Start() error {
+	s.indexPattern, _ = pattern.Init(s.config.Index)
+	s.documentIdPattern, _ = pattern.Init(s.config.DocumentId)
+
+	var wrapTransport func(http.RoundTripper) http.RoundTripper
+	if s.config.HostLimit.Enabled() {
+		wrapTransport = func(base http.RoundTripper) http.RoundTripper {
+			s.limiter = hostlimit.NewTransport(base, &s.config.HostLimit, s.pipelineName, s.name)
+			return s.limiter
+		}
+	}
+	cli, err := newClient(s.config, wrapTransport)
+	if err != nil {
+		log.Error("start opensearch client fail, err: %v", err)
+		return err
+	}
+	version, err := handshake(cli, s.config)
+	if err != nil {
+		// the cluster is regarded as opensearch when the version is unknown, such as the user is not allowed to get it
+		log.Warn("get opensearch version fail, regard it as the latest opensearch, err: %v", err)
+	} else {
+		log.Info("%s connected to %s", s.String(), version)
+	}
+	s.version = version
+	if err := setup(cli, s.config, version); err != nil {
+		log.Error("setup opensearch ism policy and role fail, err: %v", err)
+		return err
+	}
+	s.cli = cli
+	log.Info("%s start", s.String())
+	return nil
+}
+
+func (s *Sink) Stop() {
+	if s.limiter != nil {
+		s.limiter.Stop()
+	}
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	if s.cli == nil {
+		return result.Fail(errors.New("opensearch client not initialized yet"))
+	}
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.DropWith(errors.New("request to opensearch bulk is null"))
+	}
+
+	maxBytes := s.config.maxBulkBytes()
+	var chunks [][]byte
+	var buf bytes.Buffer
+	for _, e := range events {
+		line, err := s.line(e)
+		if err != nil {
+			log.Error("%v; event is: %s", err, e.String())
+			continue
+		}
+		if maxBytes > 0 && buf.Len() > 0 && int64(buf.Len()+len(line)) > maxBytes {
+			chunks = append(chunks, buf.Bytes())
+			buf = bytes.Buffer{}
+		}
+		buf.Write(line)
+	}
+	if buf.Len() > 0 {
+		chunks = append(chunks, buf.Bytes())
+	}
+	if len(chunks) == 0 {
+		return result.DropWith(errors.New("request to opensearch bulk is null"))
+	}
+
+	for _, body := range chunks {
+		all, failed, reason, err := s.cli.bulk(context.Background(), body)
+		if err != nil {
+			return result.Fail(errors.WithMessage(err, "send events to opensearch"))
+		}
+		if failed == 0 {
+			continue
+		}
+		// if there are some events succeed, retry will cause these events to be sent repeatedly
+		if failed < all {
+			log.Error("partial bulk to opensearch response error, will drop failed events, all(%d), failed(%d), reason: %s", all, failed, reason)
+			continue
+		}
+		return result.Fail(errors.Errorf("all bulk to opensearch response error, all(%d), failed(%d), reason: %s", all, failed, reason))
+	}
+	return result.Success()
+}
+
+// line returns the action and document of the event in the bulk body
+func (s *Sink) line(e api.Event) ([]byte, error) {
+	headerObj := runtime.NewObject(e.Header())
+	idx, err := s.indexPattern.WithObject(headerObj).RenderWithStrict()
+	if err != nil {
+		return nil, errors.WithMessage(err, "render opensearch index error")
+	}
+	var docId string
+	if s.config.DocumentId != "" {
+		docId, err = s.documentIdPattern.WithObject(headerObj).Render()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "format documentId %s failed", s.config.DocumentId)
+		}
+	}
+	data, err := s.codec.Encode(e)
+	if err != nil {
+		return nil, errors.WithMessage(err, "codec encode event error")
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(data) + len(idx) + len(docId) + 32)
+	buf.WriteString(`{`)
+	buf.WriteString(strconv.Quote(s.config.OpType))
+	buf.WriteString(`:{"_index":`)
+	buf.WriteString(strconv.Quote(idx))
+	if t := s.version.documentType(); t != "" {
+		buf.WriteString(`,"_type":`)
+		buf.WriteString(strconv.Quote(t))
+	}
+	if docId != "" {
+		buf.WriteString(`,"_id":`)
+		buf.WriteString(strconv.Quote(docId))
+	}
+	buf.WriteString("}}\n")
+	buf.Write(data)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
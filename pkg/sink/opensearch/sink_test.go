@@ -0,0 +1,267 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opensearch
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/codec/json"
+	"github.com/loggie-io/loggie/pkg/util/aws"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
+
+type fakeBulk struct {
+	requests []*http.Request
+	docs     [][]string
+	// status returns the status of the document
+	status func(doc string) int
+}
+
+func (f *fakeBulk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r)
+	body, _ := io.ReadAll(r.Body)
+	var docs []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for i := 0; scanner.Scan(); i++ {
+		if i%2 == 1 {
+			docs = append(docs, scanner.Text())
+		}
+	}
+	f.docs = append(f.docs, docs)
+
+	var items []string
+	hasErrors := false
+	for _, d := range docs {
+		status := http.StatusCreated
+		if f.status != nil {
+			status = f.status(d)
+		}
+		if status != http.StatusCreated {
+			hasErrors = true
+			items = append(items, fmt.Sprintf(`{"index":{"_index":"test","status":%d,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`, status))
+			continue
+		}
+		items = append(items, fmt.Sprintf(`{"index":{"_index":"test","status":%d}}`, status))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"took":1,"errors":%t,"items":[%s]}`, hasErrors, strings.Join(items, ","))
+}
+
+func newTestSink(t *testing.T, config *Config) *Sink {
+	s := NewSink("test")
+	s.config = config
+	s.indexPattern, _ = pattern.Init(config.Index)
+	s.documentIdPattern, _ = pattern.Init(config.DocumentId)
+	c := json.NewJson()
+	c.Init(&codec.Config{})
+	s.SetCodec(c)
+	cli, err := newClient(config, nil)
+	assert.NoError(t, err)
+	s.cli = cli
+	return s
+}
+
+func newTestBatch(bodies ...string) api.Batch {
+	var events []api.Event
+	for _, b := range bodies {
+		events = append(events, event.NewEvent(map[string]interface{}{}, []byte(b)))
+	}
+	return batch.NewBatchWithEvents(events)
+}
+
+func TestConsume(t *testing.T) {
+	log.InitDefaultLogger()
+
+	tests := []struct {
+		name         string
+		maxBulkBytes int64
+		status       func(doc string) int
+		wantStatus   api.Status
+		wantRequests int
+	}{
+		{
+			name:         "single bulk",
+			wantStatus:   api.SUCCESS,
+			wantRequests: 1,
+		},
+		{
+			name:         "split by maxBulkBytes",
+			maxBulkBytes: 80,
+			wantStatus:   api.SUCCESS,
+			wantRequests: 3,
+		},
+		{
+			name: "partial failure is dropped",
+			status: func(doc string) int {
+				if strings.Contains(doc, "bad") {
+					return http.StatusBadRequest
+				}
+				return http.StatusCreated
+			},
+			wantStatus:   api.SUCCESS,
+			wantRequests: 1,
+		},
+		{
+			name: "all failed",
+			status: func(doc string) int {
+				return http.StatusTooManyRequests
+			},
+			wantStatus:   api.FAIL,
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeBulk{status: tt.status}
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			s := newTestSink(t, &Config{
+				Hosts:        []string{srv.URL},
+				Index:        "test",
+				OpType:       "index",
+				MaxBulkBytes: tt.maxBulkBytes,
+			})
+			res := s.Consume(newTestBatch("good-1", "bad-2", "good-3"))
+			assert.Equal(t, tt.wantStatus, res.Status())
+			assert.Equal(t, tt.wantRequests, len(fake.requests))
+
+			var docs int
+			for _, d := range fake.docs {
+				docs += len(d)
+			}
+			assert.Equal(t, 3, docs)
+		})
+	}
+}
+
+func TestConsumeLegacyType(t *testing.T) {
+	log.InitDefaultLogger()
+
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		fmt.Fprint(w, `{"took":1,"errors":false,"items":[{"index":{"_index":"test","status":201}}]}`)
+	}))
+	defer srv.Close()
+
+	s := newTestSink(t, &Config{
+		Hosts:  []string{srv.URL},
+		Index:  "test",
+		OpType: "index",
+	})
+	s.version, _ = parseVersion(DistributionElasticsearch, "6.8.0")
+	res := s.Consume(newTestBatch("a"))
+	assert.Equal(t, api.SUCCESS, res.Status())
+	assert.True(t, strings.HasPrefix(body, `{"index":{"_index":"test","_type":"_doc"}}`), body)
+}
+
+func TestConsumeServerless(t *testing.T) {
+	log.InitDefaultLogger()
+
+	fake := &fakeBulk{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	s := newTestSink(t, &Config{
+		Hosts:  []string{srv.URL},
+		Index:  "test",
+		OpType: "index",
+		AWS: &aws.Config{
+			Region:          "us-east-1",
+			AccessKeyId:     "AKID",
+			SecretAccessKey: "SECRET",
+		},
+		Serverless: true,
+	})
+	res := s.Consume(newTestBatch("a", "b"))
+	assert.Equal(t, api.SUCCESS, res.Status())
+	assert.Equal(t, 1, len(fake.requests))
+
+	req := fake.requests[0]
+	assert.NotEmpty(t, req.Header.Get(aws.HeaderAmzContentSha256))
+	assert.Contains(t, req.Header.Get("Authorization"), "/us-east-1/aoss/aws4_request")
+}
+
+func TestConfigValidate(t *testing.T) {
+	awsConfig := &aws.Config{Region: "us-east-1"}
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr bool
+	}{
+		{
+			name:   "basic auth",
+			config: &Config{Index: "test", UserName: "admin", Password: "admin"},
+		},
+		{
+			name:    "aws with username",
+			config:  &Config{Index: "test", UserName: "admin", AWS: awsConfig},
+			wantErr: true,
+		},
+		{
+			name:   "serverless",
+			config: &Config{Index: "test", AWS: awsConfig, Serverless: true},
+		},
+		{
+			name:    "serverless without aws",
+			config:  &Config{Index: "test", Serverless: true},
+			wantErr: true,
+		},
+		{
+			name:    "serverless with refresh",
+			config:  &Config{Index: "test", AWS: awsConfig, Serverless: true, Params: map[string]string{"refresh": "true"}},
+			wantErr: true,
+		},
+		{
+			name:    "serverless with ism",
+			config:  &Config{Index: "test", AWS: awsConfig, Serverless: true, ISM: ISM{Enabled: true, IndexPatterns: []string{"test-*"}}},
+			wantErr: true,
+		},
+		{
+			name:    "rollover alias is not the index",
+			config:  &Config{Index: "test", ISM: ISM{Enabled: true, IndexPatterns: []string{"test-*"}, RolloverAlias: "other"}},
+			wantErr: true,
+		},
+		{
+			name:    "role without indexPatterns",
+			config:  &Config{Index: "test", Role: Role{Enabled: true}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			assert.Equal(t, tt.wantErr, err != nil, "err: %v", err)
+		})
+	}
+}
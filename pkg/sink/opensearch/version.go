@@ -0,0 +1,142 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opensearch
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	DistributionOpenSearch    = "opensearch"
+	DistributionElasticsearch = "elasticsearch"
+
+	handshakeTimeout = 10 * time.Second
+)
+
+// clusterVersion is the distribution and version of the cluster. Amazon OpenSearch Service still hosts the legacy
+// Elasticsearch domains up to 7.10, whose plugins are served by Open Distro under `_opendistro` instead of `_plugins`.
+// The zero value is treated as a recent OpenSearch.
+type clusterVersion struct {
+	distribution string
+	major        int
+	minor        int
+}
+
+func parseVersion(distribution string, number string) (clusterVersion, error) {
+	v := clusterVersion{distribution: distribution}
+	if v.distribution == "" {
+		// elasticsearch does not return the distribution, or opensearch with compatibility.override_main_response_version,
+		// which still serves the legacy apis of Open Distro
+		v.distribution = DistributionElasticsearch
+	}
+	parts := strings.SplitN(number, ".", 3)
+	if len(parts) < 2 {
+		return v, errors.Errorf("invalid version %s", number)
+	}
+	var err error
+	if v.major, err = strconv.Atoi(parts[0]); err != nil {
+		return v, errors.Errorf("invalid version %s", number)
+	}
+	if v.minor, err = strconv.Atoi(parts[1]); err != nil {
+		return v, errors.Errorf("invalid version %s", number)
+	}
+	return v, nil
+}
+
+func (v clusterVersion) String() string {
+	if v.distribution == "" {
+		return DistributionOpenSearch
+	}
+	return v.distribution + " " + strconv.Itoa(v.major) + "." + strconv.Itoa(v.minor)
+}
+
+func (v clusterVersion) legacy() bool {
+	return v.distribution == DistributionElasticsearch
+}
+
+// pluginsPath is the path prefix of the ism and security plugin apis
+func (v clusterVersion) pluginsPath() string {
+	if v.legacy() {
+		return "/_opendistro"
+	}
+	return "/_plugins"
+}
+
+func (v clusterVersion) rolloverAliasSetting() string {
+	if v.legacy() {
+		return "opendistro.index_state_management.rollover_alias"
+	}
+	return settingRolloverAlias
+}
+
+// documentType is required in the bulk actions by elasticsearch 6.x and earlier
+func (v clusterVersion) documentType() string {
+	if v.legacy() && v.major < 7 {
+		return "_doc"
+	}
+	return ""
+}
+
+// composableTemplate reports whether `_index_template` is supported, which is added in elasticsearch 7.8
+func (v clusterVersion) composableTemplate() bool {
+	return !v.legacy() || v.major > 7 || (v.major == 7 && v.minor >= 8)
+}
+
+// supportISM reports whether the ism plugin is available, Open Distro only provides it for elasticsearch 7.x
+func (v clusterVersion) supportISM() bool {
+	return !v.legacy() || v.major >= 7
+}
+
+// handshake returns the version of the configuration, or gets it from the cluster.
+// Unlike the elasticsearch client, clusters without the elasticsearch product header are accepted.
+func handshake(cli *client, config *Config) (clusterVersion, error) {
+	if config.Version != "" {
+		return parseVersion(config.Distribution, config.Version)
+	}
+	// OpenSearch Serverless has no cluster level apis
+	if config.Serverless {
+		return clusterVersion{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	status, body, err := cli.do(ctx, http.MethodGet, "/", nil, nil)
+	if err != nil {
+		return clusterVersion{}, err
+	}
+	if status == http.StatusNotFound {
+		return clusterVersion{}, errors.New("get cluster version returned status 404")
+	}
+	out := struct {
+		Version struct {
+			Distribution string `json:"distribution"`
+			Number       string `json:"number"`
+		} `json:"version"`
+	}{}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return clusterVersion{}, errors.WithMessagef(err, "unmarshal cluster version: %s", truncate(body, 1024))
+	}
+	return parseVersion(out.Version.Distribution, out.Version.Number)
+}
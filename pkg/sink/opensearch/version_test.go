@@ -0,0 +1,106 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opensearch
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandshake(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     clusterVersion
+		plugins  string
+		docType  string
+	}{
+		{
+			name:     "opensearch",
+			response: `{"version":{"distribution":"opensearch","number":"2.11.0"},"tagline":"The OpenSearch Project: https://opensearch.org/"}`,
+			want:     clusterVersion{distribution: DistributionOpenSearch, major: 2, minor: 11},
+			plugins:  "/_plugins",
+		},
+		{
+			name:     "legacy elasticsearch 7.10 domain",
+			response: `{"version":{"number":"7.10.2","build_flavor":"oss"},"tagline":"You Know, for Search"}`,
+			want:     clusterVersion{distribution: DistributionElasticsearch, major: 7, minor: 10},
+			plugins:  "/_opendistro",
+		},
+		{
+			name:     "legacy elasticsearch 6.8 domain",
+			response: `{"version":{"number":"6.8.0"},"tagline":"You Know, for Search"}`,
+			want:     clusterVersion{distribution: DistributionElasticsearch, major: 6, minor: 8},
+			plugins:  "/_opendistro",
+			docType:  "_doc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/", r.URL.Path)
+				fmt.Fprint(w, tt.response)
+			}))
+			defer srv.Close()
+
+			config := &Config{Hosts: []string{srv.URL}}
+			cli, err := newClient(config, nil)
+			assert.NoError(t, err)
+			v, err := handshake(cli, config)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, v)
+			assert.Equal(t, tt.plugins, v.pluginsPath())
+			assert.Equal(t, tt.docType, v.documentType())
+		})
+	}
+}
+
+func TestHandshakeSkipped(t *testing.T) {
+	// no request is sent when the version is configured or to serverless
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	config := &Config{Hosts: []string{srv.URL}, Distribution: DistributionElasticsearch, Version: "7.4"}
+	cli, err := newClient(config, nil)
+	assert.NoError(t, err)
+	v, err := handshake(cli, config)
+	assert.NoError(t, err)
+	assert.False(t, v.composableTemplate())
+
+	config = &Config{Hosts: []string{srv.URL}, Serverless: true}
+	v, err = handshake(cli, config)
+	assert.NoError(t, err)
+	assert.False(t, v.legacy())
+}
+
+func TestParseVersion(t *testing.T) {
+	_, err := parseVersion(DistributionOpenSearch, "2")
+	assert.Error(t, err)
+	_, err = parseVersion(DistributionOpenSearch, "x.1")
+	assert.Error(t, err)
+
+	v, err := parseVersion(DistributionElasticsearch, "6.8.23")
+	assert.NoError(t, err)
+	assert.False(t, v.supportISM())
+	assert.Equal(t, "elasticsearch 6.8", v.String())
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+type Config struct {
+	// Protocol could be grpc or http, the protobuf encoding is used for both
+	Protocol string `yaml:"protocol,omitempty" default:"grpc" validate:"oneof=grpc http"`
+	// Endpoint is host:port of the collector for grpc, or the url of the logs path for http,
+	// e.g. http://otel-collector:4318/v1/logs
+	Endpoint string `yaml:"endpoint,omitempty" validate:"required"`
+	// Headers are sent as the grpc metadata or the http headers, such as the api key of a backend
+	Headers     map[string]string `yaml:"headers,omitempty"`
+	Compression string            `yaml:"compression,omitempty" default:"gzip" validate:"oneof=none gzip"`
+	Timeout     time.Duration     `yaml:"timeout,omitempty" default:"30s"`
+	TLS         TLS               `yaml:"tls,omitempty"`
+
+	Mapping Mapping `yaml:"mapping,omitempty"`
+}
+
+// Mapping maps the fields of the events into the otel log data model, the defaults are the fields
+// added by the otlp source, so the records received by it are exported as they were.
+type Mapping struct {
+	// Resource are the static resource attributes, e.g. service.name
+	Resource map[string]string `yaml:"resource,omitempty"`
+	// ResourceFields are the resource attributes taken from the event fields, e.g. k8s.namespace.name: kubernetes.namespace,
+	// only the top level fields are not exported as attributes again, add the parents of nested fields to ExcludeFields
+	ResourceFields map[string]string `yaml:"resourceFields,omitempty"`
+	// ResourceKey is the field of the event whose entries are all resource attributes
+	ResourceKey string `yaml:"resourceKey,omitempty" default:"resource"`
+	// ScopeKey is the field of the event with the name and version of the instrumentation scope,
+	// ScopeName is used when the event has none
+	ScopeKey  string `yaml:"scopeKey,omitempty" default:"scope"`
+	ScopeName string `yaml:"scopeName,omitempty" default:"loggie"`
+
+	// BodyField is the field used as the log body, the event body is used when it is empty
+	BodyField string `yaml:"bodyField,omitempty"`
+	// TimestampField is a time or a string in TimestampLayout, the record has only the observed time when it is absent
+	TimestampField      string `yaml:"timestampField,omitempty" default:"timestamp"`
+	TimestampLayout     string `yaml:"timestampLayout,omitempty" default:"2006-01-02T15:04:05.999999999Z07:00"`
+	SeverityTextField   string `yaml:"severityTextField,omitempty" default:"severityText"`
+	SeverityNumberField string `yaml:"severityNumberField,omitempty" default:"severityNumber"`
+	TraceIdField        string `yaml:"traceIdField,omitempty" default:"traceId"`
+	SpanIdField         string `yaml:"spanIdField,omitempty" default:"spanId"`
+	FlagsField          string `yaml:"flagsField,omitempty" default:"flags"`
+
+	// AttributesKey is the field of the event whose entries are merged into the log attributes,
+	// the other fields of the event except the ExcludeFields are exported as attributes as well
+	AttributesKey string   `yaml:"attributesKey,omitempty" default:"attributes"`
+	ExcludeFields []string `yaml:"excludeFields,omitempty"`
+}
+
+// TLS connects to the collector with tls, the client certificate is sent for mutual tls and reloaded when rotated
+type TLS struct {
+	Enabled            bool          `yaml:"enabled,omitempty"`
+	CaCertFiles        string        `yaml:"caCertFiles,omitempty"`
+	ClientCertFile     string        `yaml:"clientCertFile,omitempty"`
+	ClientKeyFile      string        `yaml:"clientKeyFile,omitempty"`
+	ServerName         string        `yaml:"serverName,omitempty"`
+	InsecureSkipVerify bool          `yaml:"insecureSkipVerify,omitempty"`
+	ReloadInterval     time.Duration `yaml:"reloadInterval,omitempty" default:"1m"`
+}
+
+func (c *Config) Validate() error {
+	return c.TLS.Validate()
+}
+
+func (t *TLS) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+	if (t.ClientCertFile == "") != (t.ClientKeyFile == "") {
+		return errors.New("clientCertFile and clientKeyFile should be set together")
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/util/eventops"
+)
+
+// severityNumbers are the numbers of the otel severity ranges, the first one of each range is used
+var severityNumbers = map[string]int32{
+	"trace":    1,
+	"debug":    5,
+	"info":     9,
+	"notice":   10,
+	"warn":     13,
+	"warning":  13,
+	"error":    17,
+	"err":      17,
+	"critical": 21,
+	"fatal":    21,
+	"panic":    21,
+}
+
+type mapper struct {
+	config *Mapping
+	// consumed are the top level fields mapped into the record, which are not exported as attributes again
+	consumed map[string]struct{}
+}
+
+func newMapper(config *Mapping) *mapper {
+	m := &mapper{
+		config:   config,
+		consumed: make(map[string]struct{}),
+	}
+	fields := []string{config.ResourceKey, config.ScopeKey, config.BodyField, config.TimestampField, config.SeverityTextField,
+		config.SeverityNumberField, config.TraceIdField, config.SpanIdField, config.FlagsField, config.AttributesKey}
+	for _, f := range config.ResourceFields {
+		fields = append(fields, f)
+	}
+	fields = append(fields, config.ExcludeFields...)
+	for _, f := range fields {
+		if f != "" {
+			m.consumed[f] = struct{}{}
+		}
+	}
+	return m
+}
+
+// record maps the event into a log record with its resource attributes and instrumentation scope
+func (m *mapper) record(e api.Event) (resource map[string]interface{}, scopeName string, scopeVersion string, r *logRecord) {
+	c := m.config
+	r = &logRecord{}
+
+	resource = make(map[string]interface{}, len(c.Resource)+len(c.ResourceFields))
+	for k, v := range c.Resource {
+		resource[k] = v
+	}
+	if attrs, ok := m.get(e, c.ResourceKey).(map[string]interface{}); ok {
+		for k, v := range attrs {
+			resource[k] = v
+		}
+	}
+	for k, f := range c.ResourceFields {
+		if v := m.get(e, f); v != nil {
+			resource[k] = v
+		}
+	}
+
+	scopeName = c.ScopeName
+	if sc, ok := m.get(e, c.ScopeKey).(map[string]interface{}); ok {
+		if name, ok := sc["name"].(string); ok && name != "" {
+			scopeName = name
+			scopeVersion, _ = sc["version"].(string)
+		}
+	}
+
+	if c.BodyField != "" {
+		r.body = m.get(e, c.BodyField)
+	} else {
+		r.body = string(e.Body())
+	}
+
+	r.timeUnixNano = m.timestamp(e)
+	if text, ok := m.get(e, c.SeverityTextField).(string); ok {
+		r.severityText = text
+		r.severityNumber = severityNumbers[strings.ToLower(text)]
+	}
+	if n := m.get(e, c.SeverityNumberField); n != nil {
+		if num := toInt64(n); num > 0 && num <= 24 {
+			r.severityNumber = int32(num)
+		}
+	}
+	r.traceId = decodeHex(m.get(e, c.TraceIdField))
+	r.spanId = decodeHex(m.get(e, c.SpanIdField))
+	if f := m.get(e, c.FlagsField); f != nil {
+		r.flags = uint32(toInt64(f))
+	}
+
+	r.attributes = make(map[string]interface{}, len(e.Header()))
+	if attrs, ok := m.get(e, c.AttributesKey).(map[string]interface{}); ok {
+		for k, v := range attrs {
+			r.attributes[k] = v
+		}
+	}
+	for k, v := range e.Header() {
+		if _, ok := m.consumed[k]; ok {
+			continue
+		}
+		r.attributes[k] = v
+	}
+	return resource, scopeName, scopeVersion, r
+}
+
+func (m *mapper) get(e api.Event, field string) interface{} {
+	if field == "" || e.Header() == nil {
+		return nil
+	}
+	return eventops.Get(e, field)
+}
+
+func (m *mapper) timestamp(e api.Event) uint64 {
+	switch t := m.get(e, m.config.TimestampField).(type) {
+	case time.Time:
+		return uint64(t.UnixNano())
+	case string:
+		parsed, err := time.Parse(m.config.TimestampLayout, t)
+		if err != nil {
+			return 0
+		}
+		return uint64(parsed.UnixNano())
+	}
+	return 0
+}
+
+func decodeHex(v interface{}) []byte {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	return b
+}
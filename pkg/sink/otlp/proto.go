@@ -0,0 +1,328 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of opentelemetry/proto/collector/logs/v1/logs_service.proto are encoded by hand,
+// the same as they are decoded by the otlp source.
+
+type logRecord struct {
+	timeUnixNano         uint64
+	observedTimeUnixNano uint64
+	severityNumber       int32
+	severityText         string
+	body                 interface{}
+	attributes           map[string]interface{}
+	flags                uint32
+	traceId              []byte
+	spanId               []byte
+}
+
+type scopeLogs struct {
+	name    string
+	version string
+	records [][]byte
+}
+
+type resourceLogs struct {
+	resource []byte
+	scopes   []*scopeLogs
+}
+
+// requestBuilder groups the records by resource and scope into an ExportLogsServiceRequest
+type requestBuilder struct {
+	resources []*resourceLogs
+	index     map[string]*resourceLogs
+}
+
+func newRequestBuilder() *requestBuilder {
+	return &requestBuilder{
+		index: make(map[string]*resourceLogs),
+	}
+}
+
+func (b *requestBuilder) add(resource map[string]interface{}, scopeName string, scopeVersion string, r *logRecord) {
+	encoded := appendResource(nil, resource)
+	rl, ok := b.index[string(encoded)]
+	if !ok {
+		rl = &resourceLogs{resource: encoded}
+		b.index[string(encoded)] = rl
+		b.resources = append(b.resources, rl)
+	}
+
+	var sl *scopeLogs
+	for _, s := range rl.scopes {
+		if s.name == scopeName && s.version == scopeVersion {
+			sl = s
+			break
+		}
+	}
+	if sl == nil {
+		sl = &scopeLogs{name: scopeName, version: scopeVersion}
+		rl.scopes = append(rl.scopes, sl)
+	}
+	sl.records = append(sl.records, appendLogRecord(nil, r))
+}
+
+func (b *requestBuilder) marshal() []byte {
+	var req []byte
+	for _, rl := range b.resources {
+		var msg []byte
+		msg = appendMessage(msg, 1, rl.resource)
+		for _, sl := range rl.scopes {
+			var scope []byte
+			if sl.name != "" {
+				scope = appendString(scope, 1, sl.name)
+			}
+			if sl.version != "" {
+				scope = appendString(scope, 2, sl.version)
+			}
+			s := appendMessage(nil, 1, scope)
+			for _, r := range sl.records {
+				s = appendMessage(s, 2, r)
+			}
+			msg = appendMessage(msg, 2, s)
+		}
+		req = appendMessage(req, 1, msg)
+	}
+	return req
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendResource(b []byte, attributes map[string]interface{}) []byte {
+	return appendKeyValues(b, 1, attributes)
+}
+
+func appendLogRecord(b []byte, r *logRecord) []byte {
+	if r.timeUnixNano > 0 {
+		b = protowire.AppendTag(b, 1, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, r.timeUnixNano)
+	}
+	if r.severityNumber > 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(r.severityNumber))
+	}
+	if r.severityText != "" {
+		b = appendString(b, 3, r.severityText)
+	}
+	if r.body != nil {
+		b = appendMessage(b, 5, appendAnyValue(nil, r.body))
+	}
+	b = appendKeyValues(b, 6, r.attributes)
+	if r.flags > 0 {
+		b = protowire.AppendTag(b, 8, protowire.Fixed32Type)
+		b = protowire.AppendFixed32(b, r.flags)
+	}
+	if len(r.traceId) > 0 {
+		b = appendMessage(b, 9, r.traceId)
+	}
+	if len(r.spanId) > 0 {
+		b = appendMessage(b, 10, r.spanId)
+	}
+	if r.observedTimeUnixNano > 0 {
+		b = protowire.AppendTag(b, 11, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, r.observedTimeUnixNano)
+	}
+	return b
+}
+
+// appendKeyValues appends the attributes as repeated KeyValue fields, sorted by key so that the same
+// attributes are always encoded the same
+func appendKeyValues(b []byte, num protowire.Number, attributes map[string]interface{}) []byte {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		kv := appendString(nil, 1, k)
+		kv = appendMessage(kv, 2, appendAnyValue(nil, attributes[k]))
+		b = appendMessage(b, num, kv)
+	}
+	return b
+}
+
+func appendAnyValue(b []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return b
+	case string:
+		return appendString(b, 1, v)
+	case []byte:
+		return appendMessage(b, 7, v)
+	case bool:
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(toInt64(v)))
+	case float32:
+		return appendDouble(b, float64(v))
+	case float64:
+		return appendDouble(b, v)
+	case time.Time:
+		return appendString(b, 1, v.Format(time.RFC3339Nano))
+	case map[string]interface{}:
+		return appendMessage(b, 6, appendKeyValues(nil, 1, v))
+	case map[string]string:
+		kv := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			kv[k] = s
+		}
+		return appendMessage(b, 6, appendKeyValues(nil, 1, kv))
+	case []interface{}:
+		var values []byte
+		for _, e := range v {
+			values = appendMessage(values, 1, appendAnyValue(nil, e))
+		}
+		return appendMessage(b, 5, values)
+	case []string:
+		var values []byte
+		for _, e := range v {
+			values = appendMessage(values, 1, appendString(nil, 1, e))
+		}
+		return appendMessage(b, 5, values)
+	default:
+		return appendString(b, 1, fmt.Sprintf("%v", v))
+	}
+}
+
+func appendDouble(b []byte, v float64) []byte {
+	b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int8:
+		return int64(n)
+	case int16:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case uint:
+		return int64(n)
+	case uint8:
+		return int64(n)
+	case uint16:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	}
+	return 0
+}
+
+// exportRequest is an encoded ExportLogsServiceRequest, it implements the legacy proto message interfaces
+// so that it could be encoded by the default grpc codec.
+type exportRequest struct {
+	data []byte
+}
+
+func (r *exportRequest) Reset()                   { r.data = nil }
+func (r *exportRequest) String() string           { return "ExportLogsServiceRequest" }
+func (r *exportRequest) ProtoMessage()            {}
+func (r *exportRequest) Marshal() ([]byte, error) { return r.data, nil }
+
+// exportResponse is ExportLogsServiceResponse, the collector reports the records it rejected in partial_success
+type exportResponse struct {
+	rejected     int64
+	errorMessage string
+}
+
+func (r *exportResponse) Reset()         { *r = exportResponse{} }
+func (r *exportResponse) String() string { return "ExportLogsServiceResponse" }
+func (r *exportResponse) ProtoMessage()  {}
+
+func (r *exportResponse) Unmarshal(b []byte) error {
+	return rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		return rangeFields(bytes, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+			switch num {
+			case 1:
+				r.rejected = int64(v)
+			case 2:
+				r.errorMessage = string(bytes)
+			}
+			return nil
+		})
+	})
+}
+
+type fieldFunc func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error
+
+func rangeFields(b []byte, fn fieldFunc) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var (
+			bytes []byte
+			v     uint64
+		)
+		switch typ {
+		case protowire.BytesType:
+			bytes, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(b)
+			v = uint64(v32)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, bytes, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
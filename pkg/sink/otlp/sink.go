@@ -0,0 +1,246 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	grpcutil "github.com/loggie-io/loggie/pkg/util/grpc"
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+	"github.com/pkg/errors"
+)
+
+const (
+	Type = "otlp"
+
+	exportMethod        = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+	contentTypeProtobuf = "application/x-protobuf"
+)
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return &Sink{
+		config: &Config{},
+	}
+}
+
+type Sink struct {
+	name    string
+	config  *Config
+	mapper  *mapper
+	conn    *grpc.ClientConn
+	client  *http.Client
+	headers metadata.MD
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s: %s", api.SINK, Type, s.config.Endpoint)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.mapper = newMapper(&s.config.Mapping)
+	return nil
+}
+
+func (s *Sink) Start() error {
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return errors.WithMessage(err, "load tls config")
+	}
+
+	if s.config.Protocol == ProtocolHTTP {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		s.client = &http.Client{
+			Transport: transport,
+			Timeout:   s.config.Timeout,
+		}
+		log.Info("%s start", s.String())
+		return nil
+	}
+
+	creds := grpc.WithInsecure()
+	if tlsConfig != nil {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+	conn, err := grpc.Dial(s.config.Endpoint, creds)
+	if err != nil {
+		return errors.WithMessagef(err, "dial %s", s.config.Endpoint)
+	}
+	s.conn = conn
+	s.headers = metadata.New(s.config.Headers)
+	log.Info("%s start", s.String())
+	return nil
+}
+
+func (s *Sink) tlsConfig() (*tls.Config, error) {
+	t := s.config.TLS
+	if !t.Enabled {
+		return nil, nil
+	}
+	tlsConfig, err := netutils.NewTLSConfig(t.CaCertFiles, "", "", t.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ServerName = t.ServerName
+	if t.ClientCertFile != "" {
+		reloader, err := netutils.NewCertReloader(t.ClientCertFile, t.ClientKeyFile, "", t.ReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = reloader.GetClientCertificate
+	}
+	return tlsConfig, nil
+}
+
+func (s *Sink) Stop() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	builder := newRequestBuilder()
+	now := uint64(time.Now().UnixNano())
+	for _, e := range events {
+		resource, scopeName, scopeVersion, record := s.mapper.record(e)
+		record.observedTimeUnixNano = now
+		builder.add(resource, scopeName, scopeVersion, record)
+	}
+	req := builder.marshal()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	var (
+		resp *exportResponse
+		err  error
+	)
+	if s.config.Protocol == ProtocolHTTP {
+		resp, err = s.exportHTTP(ctx, req)
+	} else {
+		resp, err = s.exportGRPC(ctx, req)
+	}
+	if err != nil {
+		return result.Fail(err)
+	}
+
+	// the rejected records would never be accepted by retrying
+	if resp.rejected > 0 {
+		log.Warn("%s %d of %d records are rejected by the collector: %s", s.String(), resp.rejected, len(events), resp.errorMessage)
+	}
+	return result.Success()
+}
+
+func (s *Sink) exportGRPC(ctx context.Context, req []byte) (*exportResponse, error) {
+	ctx = metadata.NewOutgoingContext(ctx, s.headers)
+	var opts []grpc.CallOption
+	if s.config.Compression != grpcutil.CompressionNone {
+		opts = append(opts, grpc.UseCompressor(s.config.Compression))
+	}
+
+	resp := &exportResponse{}
+	if err := s.conn.Invoke(ctx, exportMethod, &exportRequest{data: req}, resp, opts...); err != nil {
+		return nil, errors.WithMessage(err, "export logs")
+	}
+	return resp, nil
+}
+
+func (s *Sink) exportHTTP(ctx context.Context, req []byte) (*exportResponse, error) {
+	body := req
+	if s.config.Compression == grpcutil.CompressionGzip {
+		buf := &bytes.Buffer{}
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(req); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		body = buf.Bytes()
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", contentTypeProtobuf)
+	if s.config.Compression == grpcutil.CompressionGzip {
+		r.Header.Set("Content-Encoding", grpcutil.CompressionGzip)
+	}
+	for k, v := range s.config.Headers {
+		r.Header.Set(k, v)
+	}
+
+	res, err := s.client.Do(r)
+	if err != nil {
+		return nil, errors.WithMessage(err, "export logs")
+	}
+	defer res.Body.Close()
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.WithMessage(err, "read export response")
+	}
+	if res.StatusCode/100 != 2 {
+		return nil, errors.Errorf("export logs returned status %d: %s", res.StatusCode, strings.TrimSpace(string(content)))
+	}
+
+	resp := &exportResponse{}
+	if strings.HasPrefix(res.Header.Get("Content-Type"), contentTypeProtobuf) {
+		if err := resp.Unmarshal(content); err != nil {
+			log.Warn("%s unmarshal export response error: %v", s.String(), err)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,334 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	_ "github.com/loggie-io/loggie/pkg/util/grpc"
+)
+
+func defaultMapping() Mapping {
+	return Mapping{
+		ResourceKey:         "resource",
+		ScopeKey:            "scope",
+		ScopeName:           "loggie",
+		TimestampField:      "timestamp",
+		TimestampLayout:     time.RFC3339Nano,
+		SeverityTextField:   "severityText",
+		SeverityNumberField: "severityNumber",
+		TraceIdField:        "traceId",
+		SpanIdField:         "spanId",
+		FlagsField:          "flags",
+		AttributesKey:       "attributes",
+	}
+}
+
+// decoded is a log record decoded by the test collector
+type decoded struct {
+	resource     map[string]interface{}
+	scopeName    string
+	timeUnixNano uint64
+	observed     uint64
+	severity     uint64
+	severityText string
+	body         interface{}
+	attributes   map[string]interface{}
+	traceId      []byte
+}
+
+func decodeRequest(t *testing.T, b []byte) []decoded {
+	var out []decoded
+	assert.NoError(t, rangeFields(b, func(num protowire.Number, typ protowire.Type, rl []byte, v uint64) error {
+		resource := map[string]interface{}{}
+		return rangeFields(rl, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+			switch num {
+			case 1:
+				return rangeFields(bytes, func(num protowire.Number, typ protowire.Type, kv []byte, v uint64) error {
+					decodeKeyValue(t, kv, resource)
+					return nil
+				})
+			case 2:
+				scopeName := ""
+				return rangeFields(bytes, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+					if num == 1 {
+						return rangeFields(bytes, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+							if num == 1 {
+								scopeName = string(bytes)
+							}
+							return nil
+						})
+					}
+					out = append(out, decodeRecord(t, bytes, resource, scopeName))
+					return nil
+				})
+			}
+			return nil
+		})
+	}))
+	return out
+}
+
+func decodeRecord(t *testing.T, b []byte, resource map[string]interface{}, scopeName string) decoded {
+	d := decoded{resource: resource, scopeName: scopeName, attributes: map[string]interface{}{}}
+	assert.NoError(t, rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		switch num {
+		case 1:
+			d.timeUnixNano = v
+		case 2:
+			d.severity = v
+		case 3:
+			d.severityText = string(bytes)
+		case 5:
+			d.body = decodeAnyValue(t, bytes)
+		case 6:
+			decodeKeyValue(t, bytes, d.attributes)
+		case 9:
+			d.traceId = bytes
+		case 11:
+			d.observed = v
+		}
+		return nil
+	}))
+	return d
+}
+
+func decodeKeyValue(t *testing.T, b []byte, out map[string]interface{}) {
+	var key string
+	var value interface{}
+	assert.NoError(t, rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		if num == 1 {
+			key = string(bytes)
+		} else {
+			value = decodeAnyValue(t, bytes)
+		}
+		return nil
+	}))
+	out[key] = value
+}
+
+func decodeAnyValue(t *testing.T, b []byte) interface{} {
+	var value interface{}
+	assert.NoError(t, rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		switch num {
+		case 1:
+			value = string(bytes)
+		case 2:
+			value = v != 0
+		case 3:
+			value = int64(v)
+		case 6:
+			kv := map[string]interface{}{}
+			_ = rangeFields(bytes, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+				decodeKeyValue(t, bytes, kv)
+				return nil
+			})
+			value = kv
+		}
+		return nil
+	}))
+	return value
+}
+
+func newEvent(header map[string]interface{}, body string) api.Event {
+	return event.NewEvent(header, []byte(body))
+}
+
+func TestMapping(t *testing.T) {
+	config := defaultMapping()
+	config.Resource = map[string]string{"service.name": "checkout"}
+	config.ResourceFields = map[string]string{"k8s.namespace.name": "kubernetes.namespace"}
+	config.SeverityTextField = "level"
+	config.ExcludeFields = []string{"secret", "kubernetes"}
+	m := newMapper(&config)
+
+	builder := newRequestBuilder()
+	for _, e := range []api.Event{
+		newEvent(map[string]interface{}{
+			"kubernetes": map[string]interface{}{"namespace": "shop"},
+			"level":      "ERROR",
+			"timestamp":  "2023-06-01T12:00:00Z",
+			"traceId":    "0102",
+			"attributes": map[string]interface{}{"user": "u1"},
+			"status":     500,
+			"secret":     "x",
+		}, "payment failed"),
+		newEvent(map[string]interface{}{
+			"kubernetes":     map[string]interface{}{"namespace": "shop"},
+			"level":          "whatever",
+			"severityNumber": int32(9),
+			"scope":          map[string]interface{}{"name": "slf4j"},
+		}, "ok"),
+		newEvent(map[string]interface{}{
+			"kubernetes": map[string]interface{}{"namespace": "search"},
+		}, "other"),
+	} {
+		resource, name, version, r := m.record(e)
+		builder.add(resource, name, version, r)
+	}
+
+	assert.Len(t, builder.resources, 2)
+	assert.Len(t, builder.resources[0].scopes, 2)
+
+	records := decodeRequest(t, builder.marshal())
+	assert.Len(t, records, 3)
+
+	r := records[0]
+	assert.Equal(t, map[string]interface{}{"service.name": "checkout", "k8s.namespace.name": "shop"}, r.resource)
+	assert.Equal(t, "loggie", r.scopeName)
+	assert.Equal(t, uint64(1685620800000000000), r.timeUnixNano)
+	assert.Equal(t, uint64(17), r.severity)
+	assert.Equal(t, "ERROR", r.severityText)
+	assert.Equal(t, "payment failed", r.body)
+	assert.Equal(t, []byte{0x01, 0x02}, r.traceId)
+	assert.Equal(t, map[string]interface{}{"user": "u1", "status": int64(500)}, r.attributes)
+
+	r = records[1]
+	assert.Equal(t, "slf4j", r.scopeName)
+	assert.Equal(t, uint64(9), r.severity)
+	assert.Equal(t, uint64(0), r.timeUnixNano)
+
+	r = records[2]
+	assert.Equal(t, "search", r.resource["k8s.namespace.name"])
+	assert.Equal(t, "other", r.body)
+}
+
+type rawRequest struct {
+	data []byte
+}
+
+func (r *rawRequest) Reset()                   { r.data = nil }
+func (r *rawRequest) String() string           { return "ExportLogsServiceRequest" }
+func (r *rawRequest) ProtoMessage()            {}
+func (r *rawRequest) Unmarshal(b []byte) error { r.data = append([]byte(nil), b...); return nil }
+
+type partialResponse struct{}
+
+func (r *partialResponse) Reset()         {}
+func (r *partialResponse) String() string { return "ExportLogsServiceResponse" }
+func (r *partialResponse) ProtoMessage()  {}
+func (r *partialResponse) Marshal() ([]byte, error) {
+	partial := protowire.AppendTag(nil, 1, protowire.VarintType)
+	partial = protowire.AppendVarint(partial, 1)
+	partial = appendString(partial, 2, "too old")
+	return appendMessage(nil, 1, partial), nil
+}
+
+func newSink(t *testing.T, config Config) *Sink {
+	log.InitDefaultLogger()
+	config.Mapping = defaultMapping()
+	config.Timeout = 5 * time.Second
+	s := makeSink(pipeline.Info{}).(*Sink)
+	s.config = &config
+	s.mapper = newMapper(&s.config.Mapping)
+	assert.NoError(t, s.Start())
+	return s
+}
+
+func TestConsumeGRPC(t *testing.T) {
+	received := make(chan []byte, 1)
+	token := make(chan string, 1)
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "opentelemetry.proto.collector.logs.v1.LogsService",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Export",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &rawRequest{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				md, _ := metadata.FromIncomingContext(ctx)
+				token <- md.Get("x-token")[0]
+				received <- in.data
+				return &partialResponse{}, nil
+			},
+		}},
+	}, struct{}{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	s := newSink(t, Config{
+		Protocol:    ProtocolGRPC,
+		Endpoint:    listener.Addr().String(),
+		Compression: "gzip",
+		Headers:     map[string]string{"x-token": "t1"},
+	})
+	defer s.Stop()
+
+	res := s.Consume(batch.NewBatchWithEvents([]api.Event{newEvent(map[string]interface{}{"severityText": "INFO"}, "hello")}))
+	assert.Equal(t, api.SUCCESS, res.Status())
+	assert.Equal(t, "t1", <-token)
+
+	records := decodeRequest(t, <-received)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "hello", records[0].body)
+	assert.Equal(t, uint64(9), records[0].severity)
+	assert.NotZero(t, records[0].observed)
+}
+
+func TestConsumeHTTP(t *testing.T) {
+	received := make(chan []byte, 1)
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, contentTypeProtobuf, r.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gr, err := gzip.NewReader(r.Body)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(gr)
+		if status != http.StatusOK {
+			http.Error(w, "unavailable", status)
+			return
+		}
+		received <- body
+		w.Header().Set("Content-Type", contentTypeProtobuf)
+	}))
+	defer server.Close()
+
+	s := newSink(t, Config{Protocol: ProtocolHTTP, Endpoint: server.URL + "/v1/logs", Compression: "gzip"})
+	defer s.Stop()
+
+	res := s.Consume(batch.NewBatchWithEvents([]api.Event{newEvent(map[string]interface{}{"resource": map[string]interface{}{"host.name": "n1"}}, "hello")}))
+	assert.Equal(t, api.SUCCESS, res.Status())
+	records := decodeRequest(t, <-received)
+	assert.Len(t, records, 1)
+	assert.Equal(t, map[string]interface{}{"host.name": "n1"}, records[0].resource)
+
+	status = http.StatusServiceUnavailable
+	res = s.Consume(batch.NewBatchWithEvents([]api.Event{newEvent(nil, "hello")}))
+	assert.Equal(t, api.FAIL, res.Status())
+}
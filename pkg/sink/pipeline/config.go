@@ -0,0 +1,39 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import "github.com/pkg/errors"
+
+type Config struct {
+	// Targets are the endpoints of the pipeline sources in the same process,
+	// which are the names of their pipelines by default, every event is handed off to all of them
+	Targets []string `yaml:"targets,omitempty" validate:"required"`
+}
+
+func (c *Config) Validate() error {
+	unique := make(map[string]struct{})
+	for _, t := range c.Targets {
+		if t == "" {
+			return errors.New("pipeline sink target should not be empty")
+		}
+		if _, ok := unique[t]; ok {
+			return errors.Errorf("pipeline sink target %s is duplicated", t)
+		}
+		unique[t] = struct{}{}
+	}
+	return nil
+}
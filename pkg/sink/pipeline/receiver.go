@@ -0,0 +1,62 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+)
+
+// Receiver accepts the events handed off by the pipeline sinks, which is registered by the pipeline source
+type Receiver interface {
+	Receive(e api.Event) api.Result
+}
+
+var (
+	lock      sync.RWMutex
+	receivers = make(map[string]Receiver)
+)
+
+// Register makes the receiver reachable by the pipeline sinks of the process with the endpoint
+func Register(endpoint string, r Receiver) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, ok := receivers[endpoint]; ok {
+		return errors.Errorf("pipeline endpoint %s is registered already", endpoint)
+	}
+	receivers[endpoint] = r
+	return nil
+}
+
+// Deregister removes the endpoint only if it is still registered by the receiver
+func Deregister(endpoint string, r Receiver) {
+	lock.Lock()
+	defer lock.Unlock()
+	if receivers[endpoint] == r {
+		delete(receivers, endpoint)
+	}
+}
+
+func lookup(endpoint string) (Receiver, bool) {
+	lock.RLock()
+	defer lock.RUnlock()
+	r, ok := receivers[endpoint]
+	return r, ok
+}
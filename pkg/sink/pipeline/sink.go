@@ -0,0 +1,111 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	loggiepipeline "github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const Type = "pipeline"
+
+func init() {
+	loggiepipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info loggiepipeline.Info) api.Component {
+	return &Sink{
+		pipelineName: info.PipelineName,
+		config:       &Config{},
+	}
+}
+
+// Sink hands off the events to the pipeline sources of other pipelines in the same process,
+// the events are copied by the sources, so there is no serialization like kafka or grpc
+type Sink struct {
+	pipelineName string
+	name         string
+	config       *Config
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	for _, t := range s.config.Targets {
+		if t == s.pipelineName {
+			return errors.Errorf("pipeline sink could not hand off to its own pipeline %s", t)
+		}
+	}
+	return nil
+}
+
+func (s *Sink) Start() error {
+	log.Info("%s start, targets: %v", s.String(), s.config.Targets)
+	return nil
+}
+
+func (s *Sink) Stop() {
+}
+
+// Consume returns success once the events are accepted by the queues of all the targets, the batch is retried
+// when any of the targets failed or is not running, so the events could be received more than once
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	targets := make([]Receiver, 0, len(s.config.Targets))
+	for _, t := range s.config.Targets {
+		r, ok := lookup(t)
+		if !ok {
+			return result.Fail(errors.Errorf("pipeline endpoint %s is not running", t))
+		}
+		targets = append(targets, r)
+	}
+
+	for i, r := range targets {
+		for _, e := range events {
+			res := r.Receive(e)
+			if res.Status() == api.FAIL {
+				return result.Fail(errors.WithMessagef(res.Error(), "hand off to pipeline endpoint %s", s.config.Targets[i]))
+			}
+		}
+	}
+	return result.Success()
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/gcp"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/pkg/errors"
+)
+
+const (
+	envEmulatorHost = "PUBSUB_EMULATOR_HOST"
+	defaultEndpoint = "https://pubsub.googleapis.com"
+
+	LimitExceededBlock = "block"
+	LimitExceededFail  = "fail"
+
+	// the limits of the attributes of a message
+	maxAttributes           = 100
+	maxAttributeKeyBytes    = 256
+	maxAttributeValueBytes  = 1024
+	maxOrderingKeyBytes     = 1024
+	reservedAttributePrefix = "goog"
+)
+
+type Config struct {
+	gcp.Config `yaml:",inline"`
+
+	// Topic could be a topic id, a full name such as projects/p/topics/t, or a pattern such as logs-${fields.app}
+	Topic string `yaml:"topic,omitempty" validate:"required"`
+	// OrderingKey could be a pattern such as ${fields.podname}, the messages with the same key are delivered in order
+	// to the subscriptions with message ordering enabled. The order is kept within a batch, so keep the sink parallelism 1
+	// when the order matters across batches.
+	OrderingKey string `yaml:"orderingKey,omitempty"`
+	// Attributes are added to the messages, the keys are the attribute names and the values are the keys of the events
+	Attributes       map[string]string `yaml:"attributes,omitempty"`
+	StaticAttributes map[string]string `yaml:"staticAttributes,omitempty"`
+
+	// the limits of a publish request, the batch is split when exceeded
+	MaxBatchMessages int `yaml:"maxBatchMessages,omitempty" default:"1000" validate:"gte=1,lte=1000"`
+	MaxBatchBytes    int `yaml:"maxBatchBytes,omitempty" default:"5242880" validate:"gte=1,lte=10000000"`
+
+	FlowControl FlowControl   `yaml:"flowControl,omitempty"`
+	Timeout     time.Duration `yaml:"timeout,omitempty" default:"30s"`
+}
+
+// FlowControl limits the messages and bytes being published by all the parallel consumers of the sink, 0 means unlimited.
+// The publishing waits for the outstanding requests when the behavior is block, and fails the batch when it is fail.
+type FlowControl struct {
+	MaxOutstandingMessages int    `yaml:"maxOutstandingMessages,omitempty" validate:"gte=0"`
+	MaxOutstandingBytes    int    `yaml:"maxOutstandingBytes,omitempty" validate:"gte=0"`
+	LimitExceededBehavior  string `yaml:"limitExceededBehavior,omitempty" default:"block" validate:"oneof=block fail"`
+}
+
+func (c *Config) SetDefaults() {
+	c.Config.SetDefaults()
+	// the emulator accepts the requests without any token
+	if host := os.Getenv(envEmulatorHost); host != "" && c.Endpoint == "" {
+		c.Endpoint = "http://" + host
+		c.DisableAuth = true
+		c.CredentialsFile = ""
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = defaultEndpoint
+	}
+}
+
+func (c *Config) Validate() error {
+	if err := c.Config.Validate(); err != nil {
+		return err
+	}
+	for _, p := range []string{c.Topic, c.OrderingKey} {
+		if err := pattern.Validate(p); err != nil {
+			return err
+		}
+	}
+	if len(c.Attributes)+len(c.StaticAttributes) > maxAttributes {
+		return errors.Errorf("pubsub sink supports at most %d attributes", maxAttributes)
+	}
+	for _, attrs := range []map[string]string{c.Attributes, c.StaticAttributes} {
+		for k := range attrs {
+			if k == "" || len(k) > maxAttributeKeyBytes {
+				return errors.Errorf("pubsub attribute name %q should be 1 to %d bytes", k, maxAttributeKeyBytes)
+			}
+			if strings.HasPrefix(k, reservedAttributePrefix) {
+				return errors.Errorf("pubsub attribute name %s is reserved", k)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var errLimitExceeded = errors.New("pubsub flow control limit exceeded")
+
+// flowController limits the outstanding messages and bytes of the publish requests, a request larger than
+// the limits is still allowed when there is nothing outstanding
+type flowController struct {
+	maxMessages int
+	maxBytes    int
+	block       bool
+
+	lock     sync.Mutex
+	cond     *sync.Cond
+	messages int
+	bytes    int
+}
+
+func newFlowController(c *FlowControl) *flowController {
+	f := &flowController{
+		maxMessages: c.MaxOutstandingMessages,
+		maxBytes:    c.MaxOutstandingBytes,
+		block:       c.LimitExceededBehavior != LimitExceededFail,
+	}
+	f.cond = sync.NewCond(&f.lock)
+	return f
+}
+
+func (f *flowController) acquire(messages int, bytes int) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for !f.fits(messages, bytes) {
+		if !f.block {
+			return errLimitExceeded
+		}
+		f.cond.Wait()
+	}
+	f.messages += messages
+	f.bytes += bytes
+	return nil
+}
+
+func (f *flowController) fits(messages int, bytes int) bool {
+	if f.messages == 0 && f.bytes == 0 {
+		return true
+	}
+	if f.maxMessages > 0 && f.messages+messages > f.maxMessages {
+		return false
+	}
+	if f.maxBytes > 0 && f.bytes+bytes > f.maxBytes {
+		return false
+	}
+	return true
+}
+
+func (f *flowController) release(messages int, bytes int) {
+	f.lock.Lock()
+	f.messages -= messages
+	f.bytes -= bytes
+	f.lock.Unlock()
+	f.cond.Broadcast()
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/gcp"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const (
+	Type = "pubsub"
+
+	pubsubScope = "https://www.googleapis.com/auth/pubsub"
+)
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink()
+}
+
+type Sink struct {
+	name    string
+	config  *Config
+	codec   codec.Codec
+	client  *http.Client
+	tokens  *gcp.TokenProvider
+	flow    *flowController
+	project string
+
+	topicPattern       *pattern.Pattern
+	orderingKeyPattern *pattern.Pattern
+	attributeNames     []string // sorted, so the attributes exceeding the limits are always the same
+}
+
+func NewSink() *Sink {
+	return &Sink{
+		config: &Config{},
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) SetCodec(c codec.Codec) {
+	s.codec = c
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.topicPattern, _ = pattern.Init(s.config.Topic)
+	s.orderingKeyPattern, _ = pattern.Init(s.config.OrderingKey)
+
+	for name := range s.config.Attributes {
+		s.attributeNames = append(s.attributeNames, name)
+	}
+	sort.Strings(s.attributeNames)
+
+	tokens, err := gcp.NewTokenProvider(&s.config.Config, pubsubScope)
+	if err != nil {
+		return err
+	}
+	s.tokens = tokens
+	s.flow = newFlowController(&s.config.FlowControl)
+	s.client = &http.Client{
+		Timeout: s.config.Timeout,
+	}
+	return nil
+}
+
+func (s *Sink) Start() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	project, err := s.tokens.ProjectId(ctx)
+	if err != nil && !strings.HasPrefix(s.config.Topic, "projects/") {
+		return errors.WithMessage(err, "resolve the project of pubsub topic")
+	}
+	s.project = project
+	log.Info("%s start, project: %s, topic: %s", s.String(), s.project, s.config.Topic)
+	return nil
+}
+
+func (s *Sink) Stop() {
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	// keep the order of the events of each topic
+	var topics []string
+	messages := make(map[string][][]byte)
+	for _, e := range events {
+		obj := runtime.NewObject(e.Header())
+		topic, err := s.topicPattern.WithObject(obj).RenderWithStrict()
+		if err != nil {
+			log.Warn("[%s] render pubsub topic error: %v", s.name, err)
+			continue
+		}
+		msg, err := s.encode(e, obj)
+		if err != nil {
+			log.Warn("[%s] encode event error: %v", s.name, err)
+			continue
+		}
+		// pubsub would reject the whole request
+		if len(msg)+len(`{"messages":[]}`) > s.config.MaxBatchBytes {
+			log.Warn("[%s] drop the event of %d bytes which exceeds maxBatchBytes", s.name, len(msg))
+			continue
+		}
+		if _, ok := messages[topic]; !ok {
+			topics = append(topics, topic)
+		}
+		messages[topic] = append(messages[topic], msg)
+	}
+
+	for _, topic := range topics {
+		for _, req := range split(messages[topic], s.config.MaxBatchMessages, s.config.MaxBatchBytes) {
+			if err := s.publish(context.Background(), topic, req); err != nil {
+				return result.Fail(errors.WithMessagef(err, "publish to pubsub topic %s", topic))
+			}
+		}
+	}
+	return result.Success()
+}
+
+type message struct {
+	Data        string            `json:"data,omitempty"` // base64 encoded
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+}
+
+func (s *Sink) encode(e api.Event, obj *runtime.Object) ([]byte, error) {
+	data, err := s.codec.Encode(e)
+	if err != nil {
+		return nil, err
+	}
+	out := &message{
+		Data:       base64.StdEncoding.EncodeToString(data),
+		Attributes: s.attributes(obj),
+	}
+	if out.OrderingKey, err = s.orderingKeyPattern.WithObject(obj).Render(); err != nil {
+		return nil, err
+	}
+	if len(out.OrderingKey) > maxOrderingKeyBytes {
+		return nil, errors.Errorf("ordering key exceeds %d bytes", maxOrderingKeyBytes)
+	}
+	if out.Data == "" && len(out.Attributes) == 0 {
+		return nil, errors.New("message without data or attributes")
+	}
+	return json.Marshal(out)
+}
+
+func (s *Sink) attributes(obj *runtime.Object) map[string]string {
+	if len(s.config.StaticAttributes) == 0 && len(s.attributeNames) == 0 {
+		return nil
+	}
+	attrs := make(map[string]string, len(s.config.StaticAttributes)+len(s.attributeNames))
+	for k, v := range s.config.StaticAttributes {
+		attrs[k] = truncate(v, maxAttributeValueBytes)
+	}
+	for _, name := range s.attributeNames {
+		v := obj.GetPath(s.config.Attributes[name]).Value()
+		if v == nil {
+			continue
+		}
+		value := fmt.Sprint(v)
+		if value == "" {
+			continue
+		}
+		attrs[name] = truncate(value, maxAttributeValueBytes)
+	}
+	return attrs
+}
+
+// truncate cuts the string to n bytes without breaking any utf-8 character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+type request struct {
+	body     []byte
+	messages int
+}
+
+// split packs the messages into publish requests limited by the number of messages and the bytes
+func split(messages [][]byte, maxMessages int, maxBytes int) []request {
+	const prefix, suffix = `{"messages":[`, `]}`
+	var requests []request
+	var buf bytes.Buffer
+	count := 0
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		buf.WriteString(suffix)
+		requests = append(requests, request{
+			body:     append([]byte(nil), buf.Bytes()...),
+			messages: count,
+		})
+		buf.Reset()
+		count = 0
+	}
+
+	for _, m := range messages {
+		if count >= maxMessages || (count > 0 && buf.Len()+len(m)+1+len(suffix) > maxBytes) {
+			flush()
+		}
+		if count == 0 {
+			buf.WriteString(prefix)
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.Write(m)
+		count++
+	}
+	flush()
+	return requests
+}
+
+func (s *Sink) topicPath(topic string) string {
+	if strings.HasPrefix(topic, "projects/") {
+		return topic
+	}
+	return "projects/" + s.project + "/topics/" + topic
+}
+
+func (s *Sink) publish(ctx context.Context, topic string, req request) error {
+	if err := s.flow.acquire(req.messages, len(req.body)); err != nil {
+		return err
+	}
+	defer s.flow.release(req.messages, len(req.body))
+
+	token, err := s.tokens.Token(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "retrieve gcp access token")
+	}
+
+	url := strings.TrimSuffix(s.config.Endpoint, "/") + "/v1/" + s.topicPath(topic) + ":publish"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(req.body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return errors.Errorf("pubsub returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+}
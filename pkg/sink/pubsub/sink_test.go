@@ -0,0 +1,178 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/codec/json"
+	ljson "github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name           string
+		raw            string
+		pod            string
+		wantAttributes map[string]string
+		wantKey        string
+		wantErr        bool
+	}{
+		{
+			name:           "attributes and ordering key",
+			raw:            "orderingKey: ${fields.pod}\nattributes: {namespace: fields.namespace, node: fields.node}\nstaticAttributes: {cluster: test}",
+			pod:            "pod-1",
+			wantAttributes: map[string]string{"cluster": "test", "namespace": "default"},
+			wantKey:        "pod-1",
+		},
+		{
+			name: "without attributes",
+			pod:  "pod-1",
+		},
+		{
+			name:    "ordering key too long",
+			raw:     "orderingKey: ${fields.pod}",
+			pod:     strings.Repeat("x", maxOrderingKeyBytes+1),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSink()
+			assert.NoError(t, cfg.UnPackFromRaw([]byte("topic: t\nproject: p\ndisableAuth: true\n"+tt.raw), s.config).Defaults().Validate().Do())
+			c := json.NewJson()
+			c.Init(&codec.Config{})
+			s.SetCodec(c)
+			assert.NoError(t, s.Init(context.NewContext("pubsub", Type, api.SINK, nil)))
+
+			e := event.NewEvent(map[string]interface{}{
+				"fields": map[string]interface{}{"namespace": "default", "pod": tt.pod},
+			}, []byte("log"))
+			data, err := s.encode(e, runtime.NewObject(e.Header()))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			m := &message{}
+			assert.NoError(t, ljson.Unmarshal(data, m))
+			assert.Equal(t, tt.wantAttributes, m.Attributes)
+			assert.Equal(t, tt.wantKey, m.OrderingKey)
+			body, err := base64.StdEncoding.DecodeString(m.Data)
+			assert.NoError(t, err)
+			assert.Contains(t, string(body), `"body":"log"`)
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	messages := [][]byte{[]byte(`{"data":"YQ=="}`), []byte(`{"data":"Yg=="}`), []byte(`{"data":"Yw=="}`)}
+	tests := []struct {
+		name        string
+		maxMessages int
+		maxBytes    int
+		want        []string
+	}{
+		{name: "one request", maxMessages: 10, maxBytes: 1000, want: []string{`{"messages":[{"data":"YQ=="},{"data":"Yg=="},{"data":"Yw=="}]}`}},
+		{name: "by max messages", maxMessages: 1, maxBytes: 1000, want: []string{`{"messages":[{"data":"YQ=="}]}`, `{"messages":[{"data":"Yg=="}]}`, `{"messages":[{"data":"Yw=="}]}`}},
+		{name: "by max bytes", maxMessages: 10, maxBytes: 50, want: []string{`{"messages":[{"data":"YQ=="},{"data":"Yg=="}]}`, `{"messages":[{"data":"Yw=="}]}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			count := 0
+			for _, r := range split(messages, tt.maxMessages, tt.maxBytes) {
+				got = append(got, string(r.body))
+				count += r.messages
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, len(messages), count)
+		})
+	}
+}
+
+func TestTopicPath(t *testing.T) {
+	s := &Sink{project: "p"}
+	assert.Equal(t, "projects/p/topics/logs", s.topicPath("logs"))
+	assert.Equal(t, "projects/other/topics/logs", s.topicPath("projects/other/topics/logs"))
+}
+
+func TestFlowController(t *testing.T) {
+	f := newFlowController(&FlowControl{MaxOutstandingMessages: 10, LimitExceededBehavior: LimitExceededFail})
+	// a request larger than the limits is allowed when nothing is outstanding
+	assert.NoError(t, f.acquire(20, 100))
+	assert.ErrorIs(t, f.acquire(1, 1), errLimitExceeded)
+	f.release(20, 100)
+	assert.NoError(t, f.acquire(6, 1))
+	assert.ErrorIs(t, f.acquire(5, 1), errLimitExceeded)
+	assert.NoError(t, f.acquire(4, 1))
+
+	f = newFlowController(&FlowControl{MaxOutstandingBytes: 10, LimitExceededBehavior: LimitExceededBlock})
+	assert.NoError(t, f.acquire(1, 8))
+	acquired := make(chan struct{})
+	go func() {
+		_ = f.acquire(1, 8)
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquire should block until released")
+	default:
+	}
+	f.release(1, 8)
+	<-acquired
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{s: "ab", n: 3, want: "ab"},
+		{s: "a你", n: 3, want: "a"},
+		{s: "a你b", n: 4, want: "a你"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, truncate(tt.s, tt.n))
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	c := &Config{}
+	assert.NoError(t, cfg.UnPackFromRaw([]byte("topic: t"), c).Defaults().Validate().Do())
+	assert.Equal(t, defaultEndpoint, c.Endpoint)
+
+	c = &Config{}
+	assert.Error(t, cfg.UnPackFromRaw([]byte("topic: t\nstaticAttributes:\n  googId: x"), c).Defaults().Validate().Do())
+
+	t.Setenv(envEmulatorHost, "localhost:8085")
+	c = &Config{}
+	assert.NoError(t, cfg.UnPackFromRaw([]byte("topic: t\nproject: p"), c).Defaults().Validate().Do())
+	assert.Equal(t, "http://localhost:8085", c.Endpoint)
+	assert.True(t, c.DisableAuth)
+}
@@ -3,6 +3,7 @@ package pulsar
 import (
 	"errors"
 	"github.com/apache/pulsar-client-go/pulsar/log"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
 	"github.com/sirupsen/logrus"
 	"time"
 
@@ -11,8 +12,13 @@ import (
 
 type pulsarConfig struct {
 	// Configure the service URL for the Pulsar service. If you have multiple brokers, you can set multiple Pulsar cluster addresses for a client.This parameter is required.
-	URL   string `yaml:"url,omitempty" validate:"required"`
-	Topic string `yaml:"topic,omitempty" validate:"required"`
+	URL string `yaml:"url,omitempty" validate:"required"`
+	// Topic could be a pattern such as persistent://public/default/log-${fields.app}, a producer is created for each rendered topic
+	Topic               string          `yaml:"topic,omitempty" validate:"required"`
+	IfRenderTopicFailed RenderTopicFail `yaml:"ifRenderTopicFailed,omitempty"`
+	// Key such as ${fields.podName}, the messages with the same key are routed to the same partition by hashingSchema,
+	// the messages without key are sent in round robin
+	Key string `yaml:"key,omitempty"`
 	// Set the operation timeout. Producer-create, subscribe and unsubscribe operations will be retried until this interval, after which the operation will be marked as failed
 	OperationTimeoutSeconds    time.Duration `yaml:"operationTimeoutSeconds,omitempty" default:"30s" validate:"gt=0"`
 	UseTLS                     bool          `yaml:"useTLS,omitempty"`
@@ -45,9 +51,29 @@ type pulsarConfig struct {
 	// If set to a value greater than 1, messages will be queued until this threshold is reached or
 	// BatchingMaxMessages (see above) has been reached or the batch interval has elapsed.
 	BatchingMaxSize uint `yaml:"batchingMaxSize,omitempty" default:"2048" validate:"gt=0"`
+	// DisableBatching sends every message in a single request
+	DisableBatching bool `yaml:"disableBatching,omitempty"`
+}
+
+// RenderTopicFail handles the events whose topic could not be rendered, such as the field is absent
+type RenderTopicFail struct {
+	DropEvent    bool   `yaml:"dropEvent,omitempty" default:"true"`
+	IgnoreError  bool   `yaml:"ignoreError,omitempty"`
+	DefaultTopic string `yaml:"defaultTopic,omitempty"` // the fallback topic, takes precedence over dropEvent
 }
 
 func (c *pulsarConfig) Validate() error {
+	if err := pattern.Validate(c.Topic); err != nil {
+		return err
+	}
+	if c.Key != "" {
+		if err := pattern.Validate(c.Key); err != nil {
+			return err
+		}
+	}
+	if len(c.Token) > 0 && len(c.TokenFilePath) > 0 {
+		return errors.New("only one of token and tokenFilePath could be configured")
+	}
 	if c.UseTLS {
 		if len(c.TLSTrustCertsFilePath) == 0 {
 			return errors.New("no tls_trust_certs_file_path configured")
@@ -102,6 +128,7 @@ func getOptions(
 		BatchingMaxSize:         config.BatchingMaxSize,
 		BatchingMaxPublishDelay: config.BatchingMaxPublishDelay,
 		BatchingMaxMessages:     config.BatchingMaxMessages,
+		DisableBatching:         config.DisableBatching,
 	}
 	if len(config.Name) > 0 {
 		producerOptions.Name = config.Name
@@ -3,14 +3,18 @@ package pulsar
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
+
 	"github.com/apache/pulsar-client-go/pulsar"
 	"github.com/loggie-io/loggie/pkg/core/api"
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/core/result"
 	"github.com/loggie-io/loggie/pkg/pipeline"
 	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
 	"github.com/pkg/errors"
-	"time"
 )
 
 const Type = "pulsar"
@@ -28,13 +32,19 @@ type Sink struct {
 	config *pulsarConfig
 	clt    *ProducerClient
 	cod    codec.Codec
+
+	topicPattern *pattern.Pattern
+	keyPattern   *pattern.Pattern
 }
 
 type ProducerClient struct {
 	pulsarClient    pulsar.Client
 	clientOptions   pulsar.ClientOptions
 	producerOptions pulsar.ProducerOptions
-	producer        pulsar.Producer
+
+	// producers of the rendered topics
+	mu        sync.Mutex
+	producers map[string]pulsar.Producer
 }
 
 func NewSink() *Sink {
@@ -60,6 +70,10 @@ func (s *Sink) Type() api.Type {
 }
 
 func (s *Sink) Init(_ api.Context) error {
+	s.topicPattern, _ = pattern.Init(s.config.Topic)
+	if s.config.Key != "" {
+		s.keyPattern, _ = pattern.Init(s.config.Key)
+	}
 	return nil
 }
 
@@ -79,13 +93,16 @@ func (s *Sink) Start() error {
 	}
 	client.pulsarClient, err = pulsar.NewClient(client.clientOptions)
 	if err != nil {
-		log.Debug("Create pulsar producer failed: %v", err)
+		log.Debug("Create pulsar client failed: %v", err)
 		return err
 	}
-	client.producer, err = client.pulsarClient.CreateProducer(client.producerOptions)
-	if err != nil {
-		log.Debug("Create pulsar producer failed: %v", err)
-		return err
+	// create the producer in advance to check the connection, unless the topic is rendered from the events
+	if s.topicPattern.IsConst() {
+		if _, err = client.getProducer(c.Topic); err != nil {
+			log.Debug("Create pulsar producer failed: %v", err)
+			client.pulsarClient.Close()
+			return err
+		}
 	}
 	s.clt = client
 	return nil
@@ -98,47 +115,144 @@ func newPulsarClient(
 	c := &ProducerClient{
 		clientOptions:   clientOptions,
 		producerOptions: producerOptions,
+		producers:       make(map[string]pulsar.Producer),
 	}
 	return c, nil
 }
 
+func (c *ProducerClient) getProducer(topic string) (pulsar.Producer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.producers[topic]; ok {
+		return p, nil
+	}
+	opts := c.producerOptions
+	opts.Topic = topic
+	p, err := c.pulsarClient.CreateProducer(opts)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "create pulsar producer of topic %s", topic)
+	}
+	c.producers[topic] = p
+	return p, nil
+}
+
+func (c *ProducerClient) close() {
+	c.mu.Lock()
+	for topic, p := range c.producers {
+		p.Close()
+		delete(c.producers, topic)
+	}
+	c.mu.Unlock()
+	c.pulsarClient.Close()
+}
+
 func (s *Sink) String() string {
 	return fmt.Sprintf("%s/%s", api.SINK, Type)
 }
 
 func (s *Sink) Stop() {
 	if s.clt != nil {
-		s.clt.pulsarClient.Close()
+		s.clt.close()
 	}
 }
 
 func (s *Sink) Consume(batch api.Batch) api.Result {
 	events := batch.Events()
-	var sendErr error
-	for i := range events {
-		event := &events[i]
-		serializerEncode, err := s.cod.Encode(*event)
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	var (
+		wg      sync.WaitGroup
+		errMu   sync.Mutex
+		sendErr error
+	)
+	// the batch is acked after all the messages are acknowledged by the brokers
+	defer wg.Wait()
+
+	for _, e := range events {
+		topic, ok, err := s.topicOf(e)
+		if err != nil {
+			return result.Fail(err)
+		}
+		if !ok {
+			continue
+		}
+
+		producer, err := s.clt.getProducer(topic)
+		if err != nil {
+			return result.Fail(err)
+		}
+
+		payload, err := s.cod.Encode(e)
 		if err != nil {
 			log.Warn("encode event error: %+v", err)
 			return result.Fail(err)
 		}
-		pTime := time.Now()
-		s.clt.producer.SendAsync(context.Background(), &pulsar.ProducerMessage{
-			EventTime: pTime,
-			Key:       fmt.Sprintf("%d", pTime.Nanosecond()),
-			Payload:   serializerEncode,
-		}, func(msgId pulsar.MessageID, message *pulsar.ProducerMessage, err error) {
+
+		msg := &pulsar.ProducerMessage{
+			EventTime: time.Now(),
+			Payload:   payload,
+			Key:       s.messageKey(e),
+		}
+
+		wg.Add(1)
+		producer.SendAsync(context.Background(), msg, func(msgId pulsar.MessageID, message *pulsar.ProducerMessage, err error) {
+			defer wg.Done()
 			if err != nil {
+				errMu.Lock()
 				sendErr = err
-				log.Error("send event failed, msgId:%s", msgId)
-			} else {
-				log.Debug("Pulsar send message event success")
+				errMu.Unlock()
 			}
 		})
-		if sendErr != nil {
-			return result.Fail(sendErr)
-		}
 	}
 
+	wg.Wait()
+	if sendErr != nil {
+		log.Error("send events to pulsar failed: %v", sendErr)
+		return result.Fail(sendErr)
+	}
 	return result.Success()
 }
+
+// topicOf returns the topic of the event, the default topic is used when the topic could not be rendered,
+// or the event should be dropped if ok is false
+func (s *Sink) topicOf(e api.Event) (topic string, ok bool, err error) {
+	topic, err = s.selectTopic(e)
+	if err == nil {
+		return topic, true, nil
+	}
+
+	failedConfig := s.config.IfRenderTopicFailed
+	if !failedConfig.IgnoreError {
+		log.Error("render pulsar topic error: %v; event is: %s", err, e.String())
+	}
+	if failedConfig.DefaultTopic != "" {
+		return failedConfig.DefaultTopic, true, nil
+	}
+	if failedConfig.DropEvent {
+		return "", false, nil
+	}
+	return "", false, errors.WithMessage(err, "render pulsar topic error")
+}
+
+// messageKey renders the key of the message, the messages without key are sent in round robin
+func (s *Sink) messageKey(e api.Event) string {
+	if s.keyPattern == nil {
+		return ""
+	}
+	key, err := s.keyPattern.WithObject(runtime.NewObject(e.Header())).Render()
+	if err != nil {
+		log.Warn("fail to get pulsar message key: %+v", err)
+		return ""
+	}
+	return key
+}
+
+func (s *Sink) selectTopic(e api.Event) (string, error) {
+	if s.topicPattern.IsConst() {
+		return s.config.Topic, nil
+	}
+	return s.topicPattern.WithObject(runtime.NewObject(e.Header())).RenderWithStrict()
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pulsar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func init() {
+	log.InitDefaultLogger()
+}
+
+func newTestSink(t *testing.T, raw string) *Sink {
+	s := NewSink()
+	assert.NoError(t, cfg.UnPackFromRaw([]byte("url: pulsar://127.0.0.1:6650\n"+raw), s.config).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(context.NewContext("pulsar", Type, api.SINK, nil)))
+	return s
+}
+
+func newEvent(fields map[string]interface{}) api.Event {
+	return event.NewEvent(map[string]interface{}{"fields": fields}, []byte("a"))
+}
+
+func TestTopicOf(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		fields    map[string]interface{}
+		keepEvent bool
+		wantTopic string
+		wantOk    bool
+		wantErr   bool
+	}{
+		{
+			name:      "const",
+			raw:       "topic: persistent://public/default/loggie",
+			wantTopic: "persistent://public/default/loggie",
+			wantOk:    true,
+		},
+		{
+			name:      "rendered",
+			raw:       "topic: persistent://public/${fields.namespace}/${fields.app}",
+			fields:    map[string]interface{}{"namespace": "default", "app": "nginx"},
+			wantTopic: "persistent://public/default/nginx",
+			wantOk:    true,
+		},
+		{
+			name:   "dropped by default",
+			raw:    "topic: persistent://public/default/${fields.app}",
+			fields: map[string]interface{}{},
+		},
+		{
+			name:      "default topic",
+			raw:       "topic: persistent://public/default/${fields.app}\nifRenderTopicFailed:\n  defaultTopic: persistent://public/default/unknown",
+			fields:    map[string]interface{}{},
+			wantTopic: "persistent://public/default/unknown",
+			wantOk:    true,
+		},
+		{
+			name:      "failed",
+			raw:       "topic: persistent://public/default/${fields.app}\nifRenderTopicFailed:\n  ignoreError: true",
+			fields:    map[string]interface{}{},
+			keepEvent: true,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSink(t, tt.raw)
+			s.config.IfRenderTopicFailed.DropEvent = !tt.keepEvent
+			topic, ok, err := s.topicOf(newEvent(tt.fields))
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantTopic, topic)
+		})
+	}
+}
+
+func TestMessageKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		fields map[string]interface{}
+		want   string
+	}{
+		{
+			name:   "without key",
+			raw:    "topic: loggie",
+			fields: map[string]interface{}{"podName": "nginx-0"},
+		},
+		{
+			name:   "rendered",
+			raw:    "topic: loggie\nkey: ${fields.namespace}/${fields.podName}",
+			fields: map[string]interface{}{"namespace": "default", "podName": "nginx-0"},
+			want:   "default/nginx-0",
+		},
+		{
+			name:   "absent field",
+			raw:    "topic: loggie\nkey: ${fields.podName}",
+			fields: map[string]interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSink(t, tt.raw)
+			assert.Equal(t, tt.want, s.messageKey(newEvent(tt.fields)))
+		})
+	}
+}
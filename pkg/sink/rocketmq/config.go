@@ -22,6 +22,7 @@ import (
 
 	"github.com/apache/rocketmq-client-go/v2/primitive"
 	"github.com/apache/rocketmq-client-go/v2/producer"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
 	"os"
 )
 
@@ -36,7 +37,7 @@ type Config struct {
 	IgnoreUnknownTopicOrPartition bool                 `yaml:"ignoreUnknownTopicOrPartition,omitempty"`
 	Group                         string               `yaml:"group,omitempty" default:"DEFAULT_PRODUCER"`
 	Namespace                     string               `yaml:"namespace,omitempty"`
-	MessageKeys                   []string             `yaml:"messageKeys,omitempty"`
+	MessageKeys                   []string             `yaml:"messageKeys,omitempty"` // patterns such as ${fields.traceId}, empty keys are skipped
 	Retry                         int                  `yaml:"retry,omitempty" default:"2" validate:"gte=0"`
 	SendMsgTimeout                time.Duration        `yaml:"sendMsgTimeout,omitempty" default:"3s" validate:"gte=-1"`
 	VIPChannel                    bool                 `yaml:"vipChannel,omitempty" default:"false"`
@@ -48,6 +49,11 @@ type Config struct {
 		SecretKey     string `yaml:"secretKey,omitempty"`
 		SecurityToken string `yaml:"securityToken,omitempty"`
 	} `yaml:"credentials,omitempty"`
+	// Properties are the user properties of messages, the values could be patterns such as app: ${fields.app}
+	Properties map[string]string `yaml:"properties,omitempty"`
+	// Transaction sends every message as a half message and commits it right away, which requires
+	// the group to be a transactional producer group
+	Transaction bool `yaml:"transaction,omitempty"`
 }
 
 type RenderTopicOrTagFail struct {
@@ -60,8 +66,21 @@ func (c *Config) Validate() error {
 	if len(c.NameServer) == 0 && len(c.NsResolver) == 0 {
 		return errors.New("no nameServer or nsResolver configured")
 	}
+	for _, k := range c.MessageKeys {
+		if err := pattern.Validate(k); err != nil {
+			return err
+		}
+	}
+	for name, value := range c.Properties {
+		if name == "" {
+			return errors.New("rocketmq message property name is required")
+		}
+		if err := pattern.Validate(value); err != nil {
+			return err
+		}
+	}
 	if c.Credentials != nil {
-		if c.Credentials.AccessKey == "" && c.Credentials.SecretKey == "" {
+		if c.Credentials.AccessKey == "" || c.Credentials.SecretKey == "" {
 			return errors.New("the credentials must be configured completely, the accessKey, secretKey are required")
 		}
 	}
@@ -49,15 +49,70 @@ type Sink struct {
 	clt    *rocketmqClient
 	cod    codec.Codec
 
-	topicPattern *pattern.Pattern
-	tagPattern   *pattern.Pattern
+	topicPattern     *pattern.Pattern
+	tagPattern       *pattern.Pattern
+	keyPatterns      []*pattern.Pattern
+	propertyPatterns map[string]*pattern.Pattern
+}
+
+type producerClient interface {
+	Start() error
+	Shutdown() error
 }
 
 type rocketmqClient struct {
-	rocketmqClient rocketmq.Producer
+	rocketmqClient producerClient
 	options        []producer.Option
 }
 
+// send sends the messages in batches of the same topic, which is required by the batch protocol,
+// or one by one in transactions
+func (c *rocketmqClient) send(ctx context.Context, msgs []*primitive.Message) error {
+	switch p := c.rocketmqClient.(type) {
+	case rocketmq.TransactionProducer:
+		for _, msg := range msgs {
+			res, err := p.SendMessageInTransaction(ctx, msg)
+			if err != nil {
+				return err
+			}
+			if res.State != primitive.CommitMessageState {
+				return errors.Errorf("rocketmq transaction of topic %s is not committed, state: %d", msg.Topic, res.State)
+			}
+		}
+		return nil
+
+	case rocketmq.Producer:
+		var (
+			topics  []string
+			byTopic = make(map[string][]*primitive.Message)
+		)
+		for _, msg := range msgs {
+			if _, ok := byTopic[msg.Topic]; !ok {
+				topics = append(topics, msg.Topic)
+			}
+			byTopic[msg.Topic] = append(byTopic[msg.Topic], msg)
+		}
+		for _, topic := range topics {
+			if _, err := p.SendSync(ctx, byTopic[topic]...); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errors.New("unknown rocketmq producer")
+}
+
+// commitListener commits the half messages directly, since there is no local transaction of a log
+type commitListener struct{}
+
+func (commitListener) ExecuteLocalTransaction(*primitive.Message) primitive.LocalTransactionState {
+	return primitive.CommitMessageState
+}
+
+func (commitListener) CheckLocalTransaction(*primitive.MessageExt) primitive.LocalTransactionState {
+	return primitive.CommitMessageState
+}
+
 func NewSink() *Sink {
 	return &Sink{
 		config: &Config{},
@@ -83,6 +138,16 @@ func (s *Sink) Type() api.Type {
 func (s *Sink) Init(context api.Context) error {
 	s.topicPattern, _ = pattern.Init(s.config.Topic)
 	s.tagPattern, _ = pattern.Init(s.config.Tag)
+	for _, k := range s.config.MessageKeys {
+		p, _ := pattern.Init(k)
+		s.keyPatterns = append(s.keyPatterns, p)
+	}
+	if len(s.config.Properties) > 0 {
+		s.propertyPatterns = make(map[string]*pattern.Pattern, len(s.config.Properties))
+		for name, value := range s.config.Properties {
+			s.propertyPatterns[name], _ = pattern.Init(value)
+		}
+	}
 	return nil
 }
 
@@ -93,7 +158,7 @@ func (s *Sink) Start() error {
 	if err != nil {
 		return err
 	}
-	client, err := newRocketmqClient(options)
+	client, err := newRocketmqClient(options, c.Transaction)
 	if err != nil {
 		return errors.WithMessagef(err, "new rocketmq client failed")
 	}
@@ -111,8 +176,17 @@ func (s *Sink) Start() error {
 
 func newRocketmqClient(
 	options []producer.Option,
+	transaction bool,
 ) (*rocketmqClient, error) {
-	client, err := rocketmq.NewProducer(options...)
+	var (
+		client producerClient
+		err    error
+	)
+	if transaction {
+		client, err = rocketmq.NewTransactionProducer(commitListener{}, options...)
+	} else {
+		client, err = rocketmq.NewProducer(options...)
+	}
 	if err == nil {
 		return &rocketmqClient{
 			rocketmqClient: client,
@@ -184,19 +258,62 @@ func (s *Sink) Consume(batch api.Batch) api.Result {
 		} else {
 			msg.Body = serializerEncode
 		}
-		// set message keys
-		if len(s.config.MessageKeys) > 0 {
-			msg.WithKeys(s.config.MessageKeys)
+		// set message keys and properties
+		if keys := s.messageKeys(event); len(keys) > 0 {
+			msg.WithKeys(keys)
+		}
+		if properties := s.messageProperties(event); len(properties) > 0 {
+			msg.WithProperties(properties)
 		}
 		msgs = append(msgs, msg)
 	}
-	// send batch messages to rocketmq async
-	if _, sendErr := s.clt.rocketmqClient.SendSync(context.Background(), msgs...); sendErr != nil {
+	if len(msgs) == 0 {
+		return result.Success()
+	}
+	if sendErr := s.clt.send(context.Background(), msgs); sendErr != nil {
 		return result.Fail(sendErr)
 	}
 	return result.Success()
 }
 
+func (s *Sink) messageKeys(e api.Event) []string {
+	if len(s.keyPatterns) == 0 {
+		return nil
+	}
+	obj := runtime.NewObject(e.Header())
+	keys := make([]string, 0, len(s.keyPatterns))
+	for _, p := range s.keyPatterns {
+		key, err := p.WithObject(obj).Render()
+		if err != nil {
+			log.Warn("fail to render rocketmq message key %s: %+v", p.Raw, err)
+			continue
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (s *Sink) messageProperties(e api.Event) map[string]string {
+	if len(s.propertyPatterns) == 0 {
+		return nil
+	}
+	obj := runtime.NewObject(e.Header())
+	properties := make(map[string]string, len(s.propertyPatterns))
+	for name, p := range s.propertyPatterns {
+		value, err := p.WithObject(obj).Render()
+		if err != nil {
+			log.Warn("fail to render rocketmq message property %s: %+v", name, err)
+			continue
+		}
+		if value != "" {
+			properties[name] = value
+		}
+	}
+	return properties
+}
+
 func (s *Sink) selectTopic(e api.Event) (string, error) {
 	return s.topicPattern.WithObject(runtime.NewObject(e.Header())).RenderWithStrict()
 }
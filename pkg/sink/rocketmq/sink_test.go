@@ -0,0 +1,124 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rocketmq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func TestRender(t *testing.T) {
+	log.InitDefaultLogger()
+	tests := []struct {
+		name           string
+		raw            string
+		fields         map[string]interface{}
+		wantKeys       []string
+		wantProperties map[string]string
+	}{
+		{
+			name:   "nothing to render",
+			raw:    "topic: loggie",
+			fields: map[string]interface{}{"app": "nginx"},
+		},
+		{
+			name: "keys and properties",
+			raw: `
+topic: loggie
+messageKeys: ["${fields.traceId}", "${fields.app}-${fields.pod}"]
+properties:
+  app: ${fields.app}
+  cluster: prod`,
+			fields:         map[string]interface{}{"traceId": "t1", "app": "nginx", "pod": "nginx-0"},
+			wantKeys:       []string{"t1", "nginx-nginx-0"},
+			wantProperties: map[string]string{"app": "nginx", "cluster": "prod"},
+		},
+		{
+			name: "empty values skipped",
+			raw: `
+topic: loggie
+messageKeys: ["${fields.traceId}", "${fields.app}"]
+properties:
+  trace: ${fields.traceId}
+  app: ${fields.app}`,
+			fields:         map[string]interface{}{"app": "nginx"},
+			wantKeys:       []string{"nginx"},
+			wantProperties: map[string]string{"app": "nginx"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSink()
+			raw := "nameServer: [\"127.0.0.1:9876\"]\n" + tt.raw
+			assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), s.config).Defaults().Validate().Do())
+			assert.NoError(t, s.Init(context.NewContext("rocketmq", Type, api.SINK, nil)))
+
+			e := event.NewEvent(map[string]interface{}{"fields": tt.fields}, []byte("a"))
+			assert.Equal(t, tt.wantKeys, s.messageKeys(e))
+			assert.Equal(t, tt.wantProperties, s.messageProperties(e))
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{
+			name: "name server",
+			raw:  "nameServer: [\"127.0.0.1:9876\"]\nmessageKeys: [\"${fields.traceId}\"]\nproperties: {app: \"${fields.app}\"}",
+		},
+		{
+			name: "ns resolver",
+			raw:  "nsResolver: [\"127.0.0.1:9876\"]\ncredentials: {accessKey: ak, secretKey: sk}",
+		},
+		{
+			name:    "without name server",
+			raw:     "topic: loggie",
+			wantErr: "no nameServer or nsResolver configured",
+		},
+		{
+			name:    "empty property name",
+			raw:     "nameServer: [\"127.0.0.1:9876\"]\nproperties: {\"\": \"${fields.app}\"}",
+			wantErr: "property name is required",
+		},
+		{
+			name:    "incomplete credentials",
+			raw:     "nameServer: [\"127.0.0.1:9876\"]\ncredentials: {accessKey: ak}",
+			wantErr: "accessKey, secretKey are required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := cfg.UnPackFromRaw([]byte(tt.raw), &Config{}).Defaults().Validate().Do()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/sink"
+	"github.com/loggie-io/loggie/pkg/interceptor/transformer/condition"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+type Config struct {
+	// Routes are matched in order and the first matched one wins
+	Routes []Route `yaml:"routes,omitempty" validate:"required,dive"`
+	// Default receives the events which do not match any route, they are dropped if not set
+	Default *sink.Config `yaml:"default,omitempty"`
+
+	info pipeline.Info
+}
+
+type Route struct {
+	Name string `yaml:"name,omitempty" validate:"required"`
+	// If is the condition expression the same as the transformer interceptor,
+	// such as `equal(fields.level, error) AND oneOf(_k8s.namespace, prod, pay)`
+	If   string      `yaml:"if,omitempty" validate:"required"`
+	Sink sink.Config `yaml:"sink,omitempty" validate:"required"`
+}
+
+func (c *Config) Validate() error {
+	names := make(map[string]struct{})
+	for _, r := range c.Routes {
+		if _, ok := names[r.Name]; ok {
+			return errors.Errorf("router sink route %s is duplicated", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		if _, _, err := condition.GetConditions(r.If); err != nil {
+			return errors.WithMessagef(err, "router sink route %s", r.Name)
+		}
+		if err := c.validateSink(&r.Sink); err != nil {
+			return errors.WithMessagef(err, "router sink route %s", r.Name)
+		}
+	}
+
+	if c.Default != nil {
+		if err := c.validateSink(c.Default); err != nil {
+			return errors.WithMessage(err, "router sink default")
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateSink(config *sink.Config) error {
+	if config.Type == Type {
+		return errors.New("router sink could not be nested")
+	}
+	return pipeline.ValidateSink(c.info, config)
+}
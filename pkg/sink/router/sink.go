@@ -0,0 +1,220 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/core/sink"
+	"github.com/loggie-io/loggie/pkg/interceptor/transformer/condition"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const Type = "router"
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return &Sink{
+		config: &Config{info: info},
+	}
+}
+
+// Sink dispatches the events to the sinks of the first matched routes, so that one pipeline
+// could replace several ones which differ only in the sink
+type Sink struct {
+	name   string
+	config *Config
+
+	routes   []*route
+	fallback *route
+}
+
+type route struct {
+	name       string
+	conditions []*condition.Instance
+	connector  string
+	sink       api.Sink
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	for _, r := range s.config.Routes {
+		conditions, connector, err := condition.GetConditions(r.If)
+		if err != nil {
+			return errors.WithMessagef(err, "route %s", r.Name)
+		}
+		s.routes = append(s.routes, &route{
+			name:       r.Name,
+			conditions: conditions,
+			connector:  connector,
+		})
+	}
+	if s.config.Default != nil {
+		s.fallback = &route{name: "default"}
+	}
+	return nil
+}
+
+func (s *Sink) Start() error {
+	for i, r := range s.routes {
+		si, err := s.startSink(r.name, &s.config.Routes[i].Sink)
+		if err != nil {
+			s.Stop()
+			return errors.WithMessagef(err, "start sink of route %s", r.name)
+		}
+		r.sink = si
+	}
+	if s.fallback != nil {
+		si, err := s.startSink(s.fallback.name, s.config.Default)
+		if err != nil {
+			s.Stop()
+			return errors.WithMessage(err, "start default sink")
+		}
+		s.fallback.sink = si
+	}
+
+	log.Info("%s start with %d routes", s.String(), len(s.routes))
+	return nil
+}
+
+func (s *Sink) startSink(routeName string, config *sink.Config) (api.Sink, error) {
+	name := config.Name
+	if name == "" {
+		name = s.name + "-" + routeName
+	}
+	return pipeline.StartSink(s.config.info, name, config)
+}
+
+func (s *Sink) Stop() {
+	for _, r := range s.all() {
+		if r.sink != nil {
+			r.sink.Stop()
+		}
+	}
+}
+
+func (s *Sink) all() []*route {
+	if s.fallback == nil {
+		return s.routes
+	}
+	return append(s.routes[:len(s.routes):len(s.routes)], s.fallback)
+}
+
+// Consume sends the events of each route to its sink concurrently, the whole batch is retried if any of the sinks failed,
+// so the events of the succeeded routes could be sent more than once
+func (s *Sink) Consume(b api.Batch) api.Result {
+	groups := make(map[*route][]api.Event)
+	var dropped int
+	for _, e := range b.Events() {
+		r := s.match(e)
+		if r == nil {
+			dropped++
+			continue
+		}
+		groups[r] = append(groups[r], e)
+	}
+	if dropped > 0 {
+		log.Debug("%s dropped %d events which do not match any route", s.String(), dropped)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs []string
+	)
+	for r, events := range groups {
+		wg.Add(1)
+		go func(r *route, events []api.Event) {
+			defer wg.Done()
+			sub := batch.NewBatchWithEvents(events)
+			for k, v := range b.Meta() {
+				sub.Meta()[k] = v
+			}
+			res := r.sink.Consume(sub)
+			batch.ReleaseBatch(sub)
+			if res.Status() != api.FAIL {
+				return
+			}
+			lock.Lock()
+			errs = append(errs, fmt.Sprintf("route %s: %v", r.name, res.Error()))
+			lock.Unlock()
+		}(r, events)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return result.Fail(errors.New(strings.Join(errs, "; ")))
+	}
+	return result.Success()
+}
+
+// match returns the first matched route, or the default one if there is no matched route
+func (s *Sink) match(e api.Event) *route {
+	for _, r := range s.routes {
+		if r.matches(e) {
+			return r
+		}
+	}
+	return s.fallback
+}
+
+func (r *route) matches(e api.Event) bool {
+	// AND all the conditions must return true
+	if r.connector == condition.AND {
+		for _, c := range r.conditions {
+			if c.Check(e) == c.Negative {
+				return false
+			}
+		}
+		return true
+	}
+
+	// OR need one of the conditions return true
+	for _, c := range r.conditions {
+		if c.Check(e) != c.Negative {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const fakeType = "routerFake"
+
+var fakes sync.Map // sink name -> *fakeSink
+
+type fakeConfig struct {
+	Fail bool `yaml:"fail,omitempty"`
+}
+
+type fakeSink struct {
+	name   string
+	config *fakeConfig
+
+	lock   sync.Mutex
+	bodies []string
+}
+
+func init() {
+	pipeline.Register(api.SINK, fakeType, func(info pipeline.Info) api.Component {
+		return &fakeSink{config: &fakeConfig{}}
+	})
+}
+
+func (f *fakeSink) Category() api.Category { return api.SINK }
+func (f *fakeSink) Type() api.Type         { return fakeType }
+func (f *fakeSink) Config() interface{}    { return f.config }
+func (f *fakeSink) String() string         { return fmt.Sprintf("%s/%s", api.SINK, fakeType) }
+func (f *fakeSink) Start() error           { return nil }
+func (f *fakeSink) Stop()                  {}
+
+func (f *fakeSink) Init(context api.Context) error {
+	f.name = context.Name()
+	fakes.Store(f.name, f)
+	return nil
+}
+
+func (f *fakeSink) Consume(b api.Batch) api.Result {
+	if f.config.Fail {
+		return result.Fail(errors.New("unavailable"))
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, e := range b.Events() {
+		f.bodies = append(f.bodies, string(e.Body()))
+	}
+	return result.Success()
+}
+
+func fake(t *testing.T, name string) *fakeSink {
+	f, ok := fakes.Load(name)
+	assert.True(t, ok, name)
+	return f.(*fakeSink)
+}
+
+func newTestSink(t *testing.T, raw string) *Sink {
+	s := makeSink(pipeline.Info{}).(*Sink)
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), s.config).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(context.NewContext("router", Type, api.SINK, nil)))
+	assert.NoError(t, s.Start())
+	return s
+}
+
+func newTestEvent(level string, namespace string, body string) api.Event {
+	return event.NewEvent(map[string]interface{}{
+		"fields": map[string]interface{}{"level": level, "namespace": namespace},
+	}, []byte(body))
+}
+
+func TestConsume(t *testing.T) {
+	log.InitDefaultLogger()
+	s := newTestSink(t, `
+routes:
+  - name: errors
+    if: equal(fields.level, error) AND oneOf(fields.namespace, prod, pay)
+    sink:
+      type: routerFake
+  - name: pay
+    if: equal(fields.namespace, pay)
+    sink:
+      type: routerFake
+default:
+  type: routerFake
+`)
+	defer s.Stop()
+
+	res := s.Consume(batch.NewBatchWithEvents([]api.Event{
+		newTestEvent("error", "prod", "a"),
+		newTestEvent("info", "pay", "b"),
+		newTestEvent("error", "pay", "c"),
+		newTestEvent("error", "dev", "d"),
+	}))
+	assert.Equal(t, api.SUCCESS, res.Status())
+	assert.Equal(t, []string{"a", "c"}, fake(t, "router-errors").bodies)
+	assert.Equal(t, []string{"b"}, fake(t, "router-pay").bodies)
+	assert.Equal(t, []string{"d"}, fake(t, "router-default").bodies)
+}
+
+func TestConsumeFailed(t *testing.T) {
+	log.InitDefaultLogger()
+	s := newTestSink(t, `
+routes:
+  - name: broken
+    if: equal(fields.level, error)
+    sink:
+      name: broken
+      type: routerFake
+      fail: true
+  - name: healthy
+    if: equal(fields.level, info)
+    sink:
+      name: healthy
+      type: routerFake
+`)
+	defer s.Stop()
+
+	res := s.Consume(batch.NewBatchWithEvents([]api.Event{
+		newTestEvent("error", "prod", "a"),
+		newTestEvent("info", "prod", "b"),
+		newTestEvent("debug", "prod", "c"),
+	}))
+	assert.Equal(t, api.FAIL, res.Status())
+	assert.Contains(t, res.Error().Error(), "route broken")
+	// the unmatched events are dropped without the default sink
+	assert.Equal(t, []string{"b"}, fake(t, "healthy").bodies)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{
+			name: "invalid condition",
+			raw: `
+routes:
+  - name: a
+    if: unknown(fields.level)
+    sink:
+      type: routerFake
+`,
+		},
+		{
+			name: "nested router",
+			raw: `
+routes:
+  - name: a
+    if: equal(fields.level, error)
+    sink:
+      type: router
+`,
+		},
+		{
+			name: "unknown sink",
+			raw: `
+routes:
+  - name: a
+    if: equal(fields.level, error)
+    sink:
+      type: notExist
+`,
+		},
+		{
+			name: "duplicated route",
+			raw: `
+routes:
+  - name: a
+    if: equal(fields.level, error)
+    sink:
+      type: routerFake
+  - name: a
+    if: equal(fields.level, info)
+    sink:
+      type: routerFake
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := makeSink(pipeline.Info{}).(*Sink)
+			assert.Error(t, cfg.UnPackFromRaw([]byte(tt.raw), s.config).Defaults().Validate().Do())
+		})
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/aws"
+	"github.com/pkg/errors"
+)
+
+const codeNoSuchUpload = "NoSuchUpload"
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber" json:"partNumber"`
+	ETag       string `xml:"ETag" json:"etag"`
+}
+
+type initiateMultipartUploadResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// responseError is the error returned by s3, such as NoSuchUpload
+type responseError struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("status %d, code %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func isNoSuchUpload(err error) bool {
+	var re *responseError
+	return errors.As(err, &re) && re.Code == codeNoSuchUpload
+}
+
+type client struct {
+	config *Config
+	creds  *aws.CredentialsProvider
+	http   *http.Client
+}
+
+func newClient(config *Config) *client {
+	return &client{
+		config: config,
+		creds:  aws.NewCredentialsProvider(&config.Config),
+		http: &http.Client{
+			Timeout: config.Timeout,
+		},
+	}
+}
+
+func (c *client) objectURL(key string) string {
+	escaped := aws.EscapePath(key)
+	if c.config.Endpoint != "" || c.config.ForcePathStyle {
+		return fmt.Sprintf("%s/%s/%s", c.config.ServiceEndpoint(aws.ServiceS3), c.config.Bucket, escaped)
+	}
+	endpoint := strings.TrimPrefix(c.config.ServiceEndpoint(aws.ServiceS3), "https://")
+	return fmt.Sprintf("https://%s.%s/%s", c.config.Bucket, endpoint, escaped)
+}
+
+func (c *client) putObject(ctx context.Context, key string, body []byte, contentType string) error {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	_, _, err := c.do(ctx, http.MethodPut, key, nil, header, body)
+	return err
+}
+
+func (c *client) createMultipartUpload(ctx context.Context, key string, contentType string) (string, error) {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	_, resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		return "", err
+	}
+	out := &initiateMultipartUploadResult{}
+	if err := xml.Unmarshal(resp, out); err != nil {
+		return "", errors.WithMessage(err, "unmarshal create multipart upload result")
+	}
+	if out.UploadId == "" {
+		return "", errors.New("empty upload id")
+	}
+	return out.UploadId, nil
+}
+
+func (c *client) uploadPart(ctx context.Context, key string, uploadId string, number int, body []byte) (string, error) {
+	query := url.Values{
+		"partNumber": {strconv.Itoa(number)},
+		"uploadId":   {uploadId},
+	}
+	header, _, err := c.do(ctx, http.MethodPut, key, query, nil, body)
+	if err != nil {
+		return "", err
+	}
+	etag := header.Get("ETag")
+	if etag == "" {
+		return "", errors.Errorf("empty etag of part %d", number)
+	}
+	return etag, nil
+}
+
+func (c *client) completeMultipartUpload(ctx context.Context, key string, uploadId string, parts []completedPart) error {
+	body, err := xml.Marshal(&completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+	_, resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadId}}, nil, body)
+	if err != nil {
+		return err
+	}
+	// s3 may return an error with status 200 once the response has started
+	if bytes.Contains(resp, []byte("<Error>")) {
+		re := &responseError{StatusCode: http.StatusOK}
+		_ = xml.Unmarshal(resp, re)
+		return re
+	}
+	return nil
+}
+
+func (c *client) headObject(ctx context.Context, key string) (bool, error) {
+	_, _, err := c.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err == nil {
+		return true, nil
+	}
+	var re *responseError
+	if errors.As(err, &re) && re.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return false, err
+}
+
+func (c *client) do(ctx context.Context, method string, key string, query url.Values, header http.Header, body []byte) (http.Header, []byte, error) {
+	u := c.objectURL(key)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	creds, err := c.creds.Retrieve(ctx)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "retrieve aws credentials")
+	}
+	aws.Sign(req, body, aws.ServiceS3, c.config.Region, creds, time.Now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "read response")
+	}
+	if resp.StatusCode/100 != 2 {
+		re := &responseError{StatusCode: resp.StatusCode}
+		_ = xml.Unmarshal(respBody, re)
+		return nil, nil, re
+	}
+	return resp.Header, respBody, nil
+}
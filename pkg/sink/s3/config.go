@@ -0,0 +1,118 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/aws"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/pkg/errors"
+)
+
+const (
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+
+	minPartSize = 5 * 1024 * 1024
+)
+
+type Config struct {
+	aws.Config `yaml:",inline"`
+
+	Bucket string `yaml:"bucket,omitempty" validate:"required"`
+	// ForcePathStyle is required by MinIO, the path style is also used when the endpoint is set
+	ForcePathStyle bool `yaml:"forcePathStyle,omitempty"`
+	// Key is the prefix of the objects with the partitions, such as logs/dt=${+YYYY-MM-DD}/app=${fields.app}/
+	Key      string `yaml:"key,omitempty" default:"loggie/dt=${+YYYY-MM-DD}/" validate:"required"`
+	TimeZone string `yaml:"timeZone,omitempty" default:"UTC"` // of the time partitions
+
+	Format      string   `yaml:"format,omitempty" default:"ndjson" validate:"oneof=ndjson parquet"`
+	Compression string   `yaml:"compression,omitempty" default:"gzip" validate:"oneof=none gzip zstd"`
+	Columns     []Column `yaml:"columns,omitempty" validate:"dive"` // required by parquet, all the columns are strings
+
+	// BufferDir keeps the events not uploaded yet, the uploads are resumed from it after restarting
+	BufferDir      string        `yaml:"bufferDir,omitempty" default:"./data/s3"`
+	MaxObjectBytes int64         `yaml:"maxObjectBytes,omitempty" default:"67108864"` // the buffered bytes of a partition before uploading
+	FlushInterval  time.Duration `yaml:"flushInterval,omitempty" default:"5m"`        // the max time a partition is buffered
+	MaxPartitions  int           `yaml:"maxPartitions,omitempty" default:"100"`       // the oldest partition is uploaded when exceeded
+	PartSize       int64         `yaml:"partSize,omitempty" default:"8388608"`        // of the multipart uploads, at least 5MB
+	Timeout        time.Duration `yaml:"timeout,omitempty" default:"1m"`              // of each request
+}
+
+// Column maps a key of the event to a column of the parquet objects
+type Column struct {
+	Name string `yaml:"name,omitempty" validate:"required"`
+	Key  string `yaml:"key,omitempty"` // such as fields.app, the same as name if empty, body means the event body
+}
+
+func (c *Column) SetDefaults() {
+	if c.Key == "" {
+		c.Key = c.Name
+	}
+}
+
+func (c *Config) Validate() error {
+	if err := c.Config.Validate(); err != nil {
+		return err
+	}
+	if err := pattern.Validate(c.Key); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(c.TimeZone); err != nil {
+		return errors.WithMessagef(err, "s3 sink timeZone %s is invalid", c.TimeZone)
+	}
+	if c.Format == FormatParquet && len(c.Columns) == 0 {
+		return errors.New("s3 sink columns are required by parquet")
+	}
+	if c.PartSize < minPartSize {
+		return errors.New("s3 sink partSize should be at least 5MB")
+	}
+	return nil
+}
+
+// extension of the object keys, such as .ndjson.gz
+func (c *Config) extension() string {
+	if c.Format == FormatParquet {
+		// parquet compresses the pages inside
+		return ".parquet"
+	}
+	switch c.Compression {
+	case CompressionGzip:
+		return ".ndjson.gz"
+	case CompressionZstd:
+		return ".ndjson.zst"
+	}
+	return ".ndjson"
+}
+
+func (c *Config) contentType() string {
+	if c.Format == FormatParquet {
+		return "application/vnd.apache.parquet"
+	}
+	switch c.Compression {
+	case CompressionGzip:
+		return "application/gzip"
+	case CompressionZstd:
+		return "application/zstd"
+	}
+	return "application/x-ndjson"
+}
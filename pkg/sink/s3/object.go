@@ -0,0 +1,213 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/spool"
+	"github.com/pkg/errors"
+)
+
+// uploadState keeps the uploaded parts, so the multipart upload is resumed after restarting
+type uploadState struct {
+	Key      string          `json:"key"`
+	UploadId string          `json:"uploadId,omitempty"`
+	Parts    []completedPart `json:"parts,omitempty"`
+}
+
+// newUploadState records the object key of the sealed spool
+func (s *Sink) newUploadState(id string, meta *spool.Meta) interface{} {
+	return &uploadState{Key: meta.Prefix + s.objectName(id)}
+}
+
+func (s *Sink) objectName(id string) string {
+	return s.node + "-" + id + s.config.extension()
+}
+
+// encodeObject returns the number of lines encoded, the broken rows of parquet are dropped
+func (s *Sink) encodeObject(in io.Reader, out io.Writer) (int, error) {
+	if s.config.Format != FormatParquet {
+		w, err := newCompressor(out, s.config.Compression)
+		if err != nil {
+			return 0, err
+		}
+		lines, err := spool.ReadLines(in, func(line []byte) error {
+			_, err := w.Write(line)
+			return err
+		})
+		if err != nil {
+			return lines, err
+		}
+		return lines, w.Close()
+	}
+
+	var rows [][]*string
+	_, err := spool.ReadLines(in, func(line []byte) error {
+		var row []*string
+		if err := json.Unmarshal(line, &row); err != nil {
+			log.Warn("[%s] drop the broken row of spool: %v", s.name, err)
+			return nil
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil || len(rows) == 0 {
+		return len(rows), err
+	}
+	names := make([]string, 0, len(s.config.Columns))
+	for _, c := range s.config.Columns {
+		names = append(names, c.Name)
+	}
+	return len(rows), writeParquet(out, names, rows, s.config.Compression)
+}
+
+// upload puts the object in one request or by the multipart upload, the uploaded parts are recorded in the state,
+// so the upload is resumed from the next part after restarting
+func (s *Sink) upload(ctx context.Context, id string) error {
+	state := &uploadState{}
+	if err := s.buffer.ReadState(id, state); err != nil {
+		return errors.WithMessagef(err, "read upload state %s", id)
+	}
+
+	f, err := s.buffer.Open(id)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	contentType := s.config.contentType()
+
+	if size <= s.config.PartSize && state.UploadId == "" {
+		body, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		if err := s.cli.putObject(ctx, state.Key, body, contentType); err != nil {
+			return errors.WithMessagef(err, "put object %s", state.Key)
+		}
+		s.removeObject(id, state.Key)
+		return nil
+	}
+
+	if state.UploadId == "" {
+		uploadId, err := s.cli.createMultipartUpload(ctx, state.Key, contentType)
+		if err != nil {
+			return errors.WithMessagef(err, "create multipart upload %s", state.Key)
+		}
+		state.UploadId = uploadId
+		if err := s.buffer.WriteState(id, state); err != nil {
+			return err
+		}
+	}
+
+	part := make([]byte, s.config.PartSize)
+	for offset := int64(len(state.Parts)) * s.config.PartSize; offset < size; offset += s.config.PartSize {
+		n, err := f.ReadAt(part, offset)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		number := len(state.Parts) + 1
+		etag, err := s.cli.uploadPart(ctx, state.Key, state.UploadId, number, part[:n])
+		if err != nil {
+			s.resetUpload(err, id, state)
+			return errors.WithMessagef(err, "upload part %d of %s", number, state.Key)
+		}
+		state.Parts = append(state.Parts, completedPart{PartNumber: number, ETag: etag})
+		if err := s.buffer.WriteState(id, state); err != nil {
+			return err
+		}
+	}
+
+	if err := s.cli.completeMultipartUpload(ctx, state.Key, state.UploadId, state.Parts); err != nil {
+		// the upload may have been completed before the state was removed
+		if isNoSuchUpload(err) {
+			if exists, herr := s.cli.headObject(ctx, state.Key); herr == nil && exists {
+				s.removeObject(id, state.Key)
+				return nil
+			}
+		}
+		s.resetUpload(err, id, state)
+		return errors.WithMessagef(err, "complete multipart upload %s", state.Key)
+	}
+	s.removeObject(id, state.Key)
+	return nil
+}
+
+// resetUpload restarts the multipart upload when it was aborted, such as by the lifecycle rules of the bucket
+func (s *Sink) resetUpload(err error, id string, state *uploadState) {
+	if !isNoSuchUpload(err) {
+		return
+	}
+	log.Warn("[%s] multipart upload of %s is gone, restart it", s.name, state.Key)
+	state.UploadId = ""
+	state.Parts = nil
+	if err := s.buffer.WriteState(id, state); err != nil {
+		log.Warn("[%s] reset upload state error: %v", s.name, err)
+	}
+}
+
+func (s *Sink) removeObject(id string, key string) {
+	log.Debug("[%s] uploaded object s3://%s/%s", s.name, s.config.Bucket, key)
+	s.buffer.Remove(id)
+}
+
+func compress(data []byte, compression string) ([]byte, error) {
+	if compression == CompressionNone || compression == "" {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	w, err := newCompressor(&buf, compression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func newCompressor(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	}
+	return nopWriteCloser{w}, nil
+}
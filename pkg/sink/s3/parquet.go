@@ -0,0 +1,294 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// A minimal parquet writer, every column is an optional UTF8 byte array, each column is written as a single
+// PLAIN encoded data page in one row group, which is readable by Athena, Spark, DuckDB and so on.
+
+const (
+	parquetMagic = "PAR1"
+
+	parquetTypeByteArray   = 6
+	parquetOptional        = 1
+	parquetConvertedUTF8   = 0
+	parquetEncodingPlain   = 0
+	parquetEncodingRLE     = 3
+	parquetDataPage        = 0
+	parquetCodecNone       = 0
+	parquetCodecGzip       = 2
+	parquetCodecZstd       = 6
+	parquetCreatedBy       = "loggie"
+	parquetMaxDefLevel     = 1
+	parquetDefLevelBitSize = 1
+)
+
+// thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+type thriftWriter struct {
+	buf    bytes.Buffer
+	lastID int16
+	stack  []int16
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	w.buf.Write(b[:n])
+}
+
+func (w *thriftWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	if delta := id - w.lastID; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.zigzag(int64(id))
+	}
+	w.lastID = id
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.zigzag(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) binary(b []byte) {
+	w.varint(uint64(len(b)))
+	w.buf.Write(b)
+}
+
+func (w *thriftWriter) string(id int16, s string) {
+	w.field(id, thriftBinary)
+	w.binary([]byte(s))
+}
+
+func (w *thriftWriter) list(id int16, elemType byte, size int) {
+	w.field(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elemType)
+	w.varint(uint64(size))
+}
+
+// beginStruct starts a struct field, or a struct element of a list when id is 0
+func (w *thriftWriter) beginStruct(id int16) {
+	if id > 0 {
+		w.field(id, thriftStruct)
+	}
+	w.stack = append(w.stack, w.lastID)
+	w.lastID = 0
+}
+
+func (w *thriftWriter) endStruct() {
+	w.buf.WriteByte(0)
+	w.lastID = w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+}
+
+type columnChunk struct {
+	name             string
+	offset           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// writeParquet writes the rows as a parquet file, nil values are null
+func writeParquet(w io.Writer, names []string, rows [][]*string, compression string) error {
+	codec, err := parquetCodec(compression)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, parquetMagic); err != nil {
+		return err
+	}
+
+	offset := int64(len(parquetMagic))
+	chunks := make([]columnChunk, 0, len(names))
+	for i, name := range names {
+		page := encodePage(rows, i)
+		compressed, err := compress(page, compression)
+		if err != nil {
+			return err
+		}
+		header := pageHeader(len(rows), len(page), len(compressed))
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		if _, err := w.Write(compressed); err != nil {
+			return err
+		}
+
+		chunks = append(chunks, columnChunk{
+			name:             name,
+			offset:           offset,
+			uncompressedSize: int64(len(header) + len(page)),
+			compressedSize:   int64(len(header) + len(compressed)),
+		})
+		offset += int64(len(header) + len(compressed))
+	}
+
+	footer := fileMetadata(chunks, int64(len(rows)), codec)
+	if _, err := w.Write(footer); err != nil {
+		return err
+	}
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(footer)))
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, parquetMagic)
+	return err
+}
+
+// encodePage encodes the definition levels by RLE and the present values by PLAIN
+func encodePage(rows [][]*string, column int) []byte {
+	levels := &thriftWriter{}
+	run := 0
+	var current byte
+	flush := func() {
+		if run > 0 {
+			levels.varint(uint64(run) << 1)
+			levels.buf.WriteByte(current)
+		}
+	}
+	var values bytes.Buffer
+	for _, row := range rows {
+		var level byte
+		if column < len(row) && row[column] != nil {
+			level = parquetMaxDefLevel
+			var l [4]byte
+			binary.LittleEndian.PutUint32(l[:], uint32(len(*row[column])))
+			values.Write(l[:])
+			values.WriteString(*row[column])
+		}
+		if level != current {
+			flush()
+			current, run = level, 0
+		}
+		run++
+	}
+	flush()
+
+	page := make([]byte, 4, 4+levels.buf.Len()+values.Len())
+	binary.LittleEndian.PutUint32(page, uint32(levels.buf.Len()))
+	page = append(page, levels.buf.Bytes()...)
+	return append(page, values.Bytes()...)
+}
+
+func pageHeader(numValues int, uncompressed int, compressed int) []byte {
+	w := &thriftWriter{}
+	w.i32(1, parquetDataPage)
+	w.i32(2, int32(uncompressed))
+	w.i32(3, int32(compressed))
+	w.beginStruct(5) // DataPageHeader
+	w.i32(1, int32(numValues))
+	w.i32(2, parquetEncodingPlain)
+	w.i32(3, parquetEncodingRLE)
+	w.i32(4, parquetEncodingRLE)
+	w.endStruct()
+	w.buf.WriteByte(0)
+	return w.buf.Bytes()
+}
+
+func fileMetadata(chunks []columnChunk, numRows int64, codec int32) []byte {
+	w := &thriftWriter{}
+	w.i32(1, 1)
+
+	w.list(2, thriftStruct, len(chunks)+1)
+	w.beginStruct(0) // the root of the schema
+	w.string(4, "schema")
+	w.i32(5, int32(len(chunks)))
+	w.endStruct()
+	for _, c := range chunks {
+		w.beginStruct(0)
+		w.i32(1, parquetTypeByteArray)
+		w.i32(3, parquetOptional)
+		w.string(4, c.name)
+		w.i32(6, parquetConvertedUTF8)
+		w.endStruct()
+	}
+
+	w.i64(3, numRows)
+
+	var total int64
+	w.list(4, thriftStruct, 1)
+	w.beginStruct(0) // RowGroup
+	w.list(1, thriftStruct, len(chunks))
+	for _, c := range chunks {
+		total += c.uncompressedSize
+		w.beginStruct(0) // ColumnChunk
+		w.i64(2, c.offset)
+		w.beginStruct(3) // ColumnMetaData
+		w.i32(1, parquetTypeByteArray)
+		w.list(2, thriftI32, 2)
+		w.zigzag(parquetEncodingPlain)
+		w.zigzag(parquetEncodingRLE)
+		w.list(3, thriftBinary, 1)
+		w.binary([]byte(c.name))
+		w.i32(4, codec)
+		w.i64(5, numRows)
+		w.i64(6, c.uncompressedSize)
+		w.i64(7, c.compressedSize)
+		w.i64(9, c.offset)
+		w.endStruct()
+		w.endStruct()
+	}
+	w.i64(2, total)
+	w.i64(3, numRows)
+	w.endStruct()
+
+	w.string(6, parquetCreatedBy)
+	w.buf.WriteByte(0)
+	return w.buf.Bytes()
+}
+
+func parquetCodec(compression string) (int32, error) {
+	switch compression {
+	case CompressionGzip:
+		return parquetCodecGzip, nil
+	case CompressionZstd:
+		return parquetCodecZstd, nil
+	case CompressionNone, "":
+		return parquetCodecNone, nil
+	}
+	return 0, errors.Errorf("unsupported compression %s", compression)
+}
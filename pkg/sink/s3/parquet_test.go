@@ -0,0 +1,75 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func str(s string) *string {
+	return &s
+}
+
+func TestEncodePage(t *testing.T) {
+	page := encodePage([][]*string{{str("a")}, {nil}, {str("bc")}}, 0)
+	expected := []byte{
+		6, 0, 0, 0, // length of the definition levels
+		2, 1, 2, 0, 2, 1, // rle runs of 1, 0, 1
+		1, 0, 0, 0, 'a',
+		2, 0, 0, 0, 'b', 'c',
+	}
+	assert.Equal(t, expected, page)
+}
+
+func TestPageHeader(t *testing.T) {
+	expected := []byte{
+		0x15, 0x00, // type: DATA_PAGE
+		0x15, 0x14, // uncompressed_page_size: 10
+		0x15, 0x14, // compressed_page_size: 10
+		0x2c,       // data_page_header
+		0x15, 0x06, // num_values: 3
+		0x15, 0x00, // encoding: PLAIN
+		0x15, 0x06, // definition_level_encoding: RLE
+		0x15, 0x06, // repetition_level_encoding: RLE
+		0x00, 0x00,
+	}
+	assert.Equal(t, expected, pageHeader(3, 10, 10))
+}
+
+func TestWriteParquet(t *testing.T) {
+	rows := [][]*string{
+		{str("foo"), str("hello")},
+		{nil, str("world")},
+	}
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionZstd} {
+		var buf bytes.Buffer
+		assert.NoError(t, writeParquet(&buf, []string{"app", "message"}, rows, compression))
+
+		data := buf.Bytes()
+		assert.Equal(t, parquetMagic, string(data[:4]), compression)
+		assert.Equal(t, parquetMagic, string(data[len(data)-4:]), compression)
+
+		footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8 : len(data)-4]))
+		footer := data[len(data)-8-footerLen : len(data)-8]
+		assert.Contains(t, string(footer), "message", compression)
+		assert.Contains(t, string(footer), parquetCreatedBy, compression)
+	}
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/global"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+	"github.com/loggie-io/loggie/pkg/util/spool"
+)
+
+const (
+	Type = "s3"
+
+	checkInterval = time.Second
+	retryInterval = 10 * time.Second
+)
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink(info.PipelineName)
+}
+
+// Sink buffers the events of each partition in local files and uploads them as objects,
+// the events are acked once they are written to the buffer, which survives the restarts.
+type Sink struct {
+	pipelineName string
+	name         string
+	config       *Config
+	codec        codec.Codec
+	cli          *client
+
+	keyPattern *pattern.Pattern
+	location   *time.Location
+	node       string
+	buffer     *spool.Buffer
+
+	done      chan struct{}
+	notify    chan struct{}
+	wg        sync.WaitGroup
+	retryAt   time.Time
+	closeOnce sync.Once
+}
+
+func NewSink(pipelineName string) *Sink {
+	return &Sink{
+		pipelineName: pipelineName,
+		config:       &Config{},
+		done:         make(chan struct{}),
+		notify:       make(chan struct{}, 1),
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) SetCodec(c codec.Codec) {
+	s.codec = c
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.keyPattern, _ = pattern.Init(s.config.Key)
+	s.location, _ = time.LoadLocation(s.config.TimeZone)
+
+	s.node = global.NodeName
+	if s.node == "" {
+		s.node, _ = os.Hostname()
+	}
+	s.cli = newClient(s.config)
+	s.buffer = spool.New(filepath.Join(s.config.BufferDir, s.pipelineName, s.name), s.config.MaxObjectBytes, s.config.FlushInterval, s.config.MaxPartitions)
+	return nil
+}
+
+func (s *Sink) Start() error {
+	if err := os.MkdirAll(s.buffer.Dir(), 0755); err != nil {
+		return errors.WithMessagef(err, "create buffer dir %s", s.buffer.Dir())
+	}
+
+	// the objects left by the last run are sealed and uploaded by the first round
+	s.wg.Add(1)
+	go s.run()
+
+	log.Info("%s start, bucket: %s, key: %s, format: %s", s.String(), s.config.Bucket, s.config.Key, s.config.Format)
+	return nil
+}
+
+func (s *Sink) Stop() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		// the spools are uploaded after restarting
+		s.buffer.CloseAll()
+	})
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	lines := make(map[string][]byte)
+	for _, e := range events {
+		prefix, err := s.keyPattern.WithObject(runtime.NewObject(e.Header())).WithLocation(s.location).Render()
+		if err != nil {
+			return result.Fail(errors.WithMessage(err, "render object key"))
+		}
+		line, err := s.encode(e)
+		if err != nil {
+			log.Warn("[%s] encode event error: %v", s.name, err)
+			continue
+		}
+		lines[prefix] = append(append(lines[prefix], line...), '\n')
+	}
+
+	rolled, err := s.buffer.Append(lines)
+	if rolled {
+		s.wakeup()
+	}
+	if err != nil {
+		return result.Fail(err)
+	}
+	return result.Success()
+}
+
+// encode returns a line of the spool, which is the encoded event for ndjson, or an array of the column values for parquet
+func (s *Sink) encode(e api.Event) ([]byte, error) {
+	var line []byte
+	if s.config.Format != FormatParquet {
+		var err error
+		if line, err = s.codec.Encode(e); err != nil {
+			return nil, err
+		}
+		if bytes.IndexByte(line, '\n') >= 0 {
+			return nil, errors.New("encoded event contains line breaks")
+		}
+		return line, nil
+	}
+
+	obj := runtime.NewObject(e.Header())
+	row := make([]*string, 0, len(s.config.Columns))
+	for _, c := range s.config.Columns {
+		var value interface{}
+		if c.Key == codec.BodyKey {
+			value = string(e.Body())
+		} else {
+			value = obj.GetPath(c.Key).Value()
+		}
+		row = append(row, columnValue(value))
+	}
+	return json.Marshal(row)
+}
+
+func columnValue(value interface{}) *string {
+	var str string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			str = fmt.Sprint(v)
+		} else {
+			str = string(b)
+		}
+	}
+	return &str
+}
+
+func (s *Sink) wakeup() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Sink) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.done
+		cancel()
+	}()
+
+	for {
+		s.buffer.RollExpired(time.Now())
+		s.process(ctx)
+
+		select {
+		case <-s.done:
+			return
+		case <-s.notify:
+		case <-ticker.C:
+		}
+	}
+}
+
+// process seals the rolled spools and uploads the sealed objects in the buffer dir
+func (s *Sink) process(ctx context.Context) {
+	if time.Now().Before(s.retryAt) {
+		return
+	}
+
+	ids, err := s.buffer.Seal(s.encodeObject, s.newUploadState)
+	if err != nil {
+		log.Warn("[%s] %v", s.name, err)
+		return
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.upload(ctx, id); err != nil {
+			log.Warn("[%s] upload to s3 bucket %s error: %v", s.name, s.config.Bucket, err)
+			s.retryAt = time.Now().Add(retryInterval)
+			return
+		}
+	}
+}
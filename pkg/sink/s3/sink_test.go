@@ -0,0 +1,254 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	gocontext "context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/codec/json"
+)
+
+func init() {
+	log.InitDefaultLogger()
+}
+
+func newTestSink(t *testing.T, endpoint string, extra string) *Sink {
+	s := NewSink("test")
+	raw := `
+endpoint: ` + endpoint + `
+region: us-east-1
+accessKeyId: ak
+secretAccessKey: sk
+bucket: bucket
+bufferDir: ` + t.TempDir() + `
+` + extra
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), s.config).Defaults().Validate().Do())
+	c := json.NewJson()
+	c.Init(&codec.Config{})
+	s.SetCodec(c)
+	assert.NoError(t, s.Init(context.NewContext("s3", Type, api.SINK, nil)))
+	assert.NoError(t, os.MkdirAll(s.buffer.Dir(), 0755))
+	return s
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra string
+		want  string
+	}{
+		{
+			name: "ndjson",
+			want: `{"fields":{"app":"foo"},"body":"hello"}`,
+		},
+		{
+			name: "parquet row",
+			extra: `
+format: parquet
+columns:
+  - name: app
+    key: fields.app
+  - name: message
+    key: body
+  - name: missing
+`,
+			want: `["foo","hello",null]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSink(t, "http://127.0.0.1", tt.extra)
+			e := event.NewEvent(map[string]interface{}{
+				"fields": map[string]interface{}{"app": "foo"},
+			}, []byte("hello"))
+			line, err := s.encode(e)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(line))
+		})
+	}
+}
+
+func TestUpload(t *testing.T) {
+	const key = "logs/1.ndjson.gz"
+	tests := []struct {
+		name   string
+		object string
+		state  *uploadState
+		// the parts uploaded before restarting
+		parts        map[string]string
+		noSuchUpload bool
+		wantRequests []string
+	}{
+		{
+			name:         "put in one request",
+			object:       "0123",
+			state:        &uploadState{Key: key},
+			wantRequests: []string{"put"},
+		},
+		{
+			name:         "multipart upload",
+			object:       "012345",
+			state:        &uploadState{Key: key},
+			wantRequests: []string{"create", "part1", "part2", "complete"},
+		},
+		{
+			name:         "resumed from the next part",
+			object:       "0123456789",
+			state:        &uploadState{Key: key, UploadId: "u1", Parts: []completedPart{{PartNumber: 1, ETag: `"1"`}}},
+			parts:        map[string]string{"1": "0123"},
+			wantRequests: []string{"part2", "part3", "complete"},
+		},
+		{
+			name:         "restarted after aborted",
+			object:       "012345",
+			state:        &uploadState{Key: key, UploadId: "u1"},
+			noSuchUpload: true,
+			wantRequests: []string{"create", "part1", "part2", "complete"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := make(map[string]string)
+			for number, part := range tt.parts {
+				parts[number] = part
+			}
+			noSuchUpload := tt.noSuchUpload
+			var requests []string
+			var uploaded string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				query := r.URL.Query()
+				switch {
+				case r.Method == http.MethodPost && query.Has("uploads"):
+					requests = append(requests, "create")
+					w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>u2</UploadId></InitiateMultipartUploadResult>`))
+				case r.Method == http.MethodPut && query.Has("uploadId"):
+					if noSuchUpload {
+						noSuchUpload = false
+						w.WriteHeader(http.StatusNotFound)
+						w.Write([]byte(`<Error><Code>NoSuchUpload</Code></Error>`))
+						return
+					}
+					number := query.Get("partNumber")
+					requests = append(requests, "part"+number)
+					parts[number] = string(body)
+					w.Header().Set("ETag", `"`+number+`"`)
+				case r.Method == http.MethodPost:
+					requests = append(requests, "complete")
+					for i := 1; i <= len(parts); i++ {
+						uploaded += parts[string(rune('0'+i))]
+					}
+				case r.Method == http.MethodPut:
+					requests = append(requests, "put")
+					uploaded = string(body)
+				}
+				assert.Equal(t, "/bucket/"+key, r.URL.Path)
+			}))
+			defer server.Close()
+
+			s := newTestSink(t, server.URL, "")
+			s.config.PartSize = 4
+			assert.NoError(t, os.WriteFile(filepath.Join(s.buffer.Dir(), "1.object"), []byte(tt.object), 0644))
+			assert.NoError(t, s.buffer.WriteState("1", tt.state))
+
+			// the aborted upload is restarted by the next round
+			for i := 0; i < 2; i++ {
+				s.retryAt = time.Time{}
+				s.process(gocontext.Background())
+			}
+			assert.Equal(t, tt.wantRequests, requests)
+			assert.Equal(t, tt.object, uploaded)
+			entries, err := os.ReadDir(s.buffer.Dir())
+			assert.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestConfigExtension(t *testing.T) {
+	tests := []struct {
+		format          string
+		compression     string
+		wantExtension   string
+		wantContentType string
+	}{
+		{FormatNDJSON, CompressionGzip, ".ndjson.gz", "application/gzip"},
+		{FormatNDJSON, CompressionZstd, ".ndjson.zst", "application/zstd"},
+		{FormatNDJSON, CompressionNone, ".ndjson", "application/x-ndjson"},
+		{FormatParquet, CompressionZstd, ".parquet", "application/vnd.apache.parquet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.compression, func(t *testing.T) {
+			c := &Config{Format: tt.format, Compression: tt.compression}
+			assert.Equal(t, tt.wantExtension, c.extension())
+			assert.Equal(t, tt.wantContentType, c.contentType())
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		extra   string
+		wantErr string
+	}{
+		{
+			name: "ok",
+		},
+		{
+			name:    "parquet without columns",
+			extra:   "format: parquet",
+			wantErr: "columns are required by parquet",
+		},
+		{
+			name:    "part size too small",
+			extra:   "partSize: 1024",
+			wantErr: "partSize should be at least 5MB",
+		},
+		{
+			name:    "invalid time zone",
+			extra:   "timeZone: Mars/Base",
+			wantErr: "timeZone Mars/Base is invalid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := "region: us-east-1\nbucket: bucket\n" + tt.extra
+			err := cfg.UnPackFromRaw([]byte(raw), &Config{}).Defaults().Validate().Do()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -16,6 +16,11 @@ limitations under the License.
 
 package sls
 
+import (
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/pkg/errors"
+)
+
 type Config struct {
 	Endpoint                  string   `yaml:"endpoint,omitempty" validate:"required"`
 	AccessKeyId               string   `yaml:"accessKeyId,omitempty"`
@@ -23,7 +28,26 @@ type Config struct {
 	CredentialProviderCommand string   `yaml:"cridentialProviderCommand,omitempty"`
 	CredentialProviderArgs    []string `yaml:"credentialProviderArgs,omitempty"`
 	CredentialProviderTimeout int      `yaml:"credentialProviderTimeout,omitempty" default:"5"`
-	Project                   string   `yaml:"project,omitempty" validate:"required"`
-	LogStore                  string   `yaml:"logstore,omitempty" validate:"required"`
-	Topic                     string   `yaml:"topic,omitempty"` // empty topic is supported in sls storage
+	// SecurityToken is the token of STS temporary credentials along with the access key pair
+	SecurityToken string `yaml:"securityToken,omitempty"`
+	// RamRole fetches the STS credentials of the RAM role attached to the ECS instance from the metadata service
+	RamRole string `yaml:"ramRole,omitempty"`
+	// Project, LogStore and Topic could be patterns such as ${fields.namespace}
+	Project  string `yaml:"project,omitempty" validate:"required"`
+	LogStore string `yaml:"logstore,omitempty" validate:"required"`
+	Topic    string `yaml:"topic,omitempty"` // empty topic is supported in sls storage
+	// Compression of the PutLogs requests, could be lz4 or none
+	Compression string `yaml:"compression,omitempty" default:"lz4" validate:"oneof=lz4 none"`
+}
+
+func (c *Config) Validate() error {
+	for _, p := range []string{c.Project, c.LogStore, c.Topic} {
+		if err := pattern.Validate(p); err != nil {
+			return err
+		}
+	}
+	if c.SecurityToken != "" && (c.AccessKeyId == "" || c.AccessKeySecret == "") {
+		return errors.New("securityToken should be used along with accessKeyId and accessKeySecret")
+	}
+	return nil
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os/exec"
 	"strings"
 	"time"
@@ -57,3 +59,44 @@ func (c *credentialProvider) GetCredentials() (accessKeyId string, accessKeySecr
 
 	return sts.AccessKeyId, sts.AccessKeySecret, sts.SecurityToken, expiration, nil
 }
+
+// ramRoleMetadataURL is the metadata service of ECS instances serving the STS credentials of the attached RAM role
+var ramRoleMetadataURL = "http://100.100.100.200/latest/meta-data/ram/security-credentials/"
+
+type ramRoleProvider struct {
+	role   string
+	client *http.Client
+}
+
+func newRamRoleProvider(role string, timeout int) *ramRoleProvider {
+	return &ramRoleProvider{
+		role:   role,
+		client: &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}
+}
+
+func (r *ramRoleProvider) GetCredentials() (accessKeyId string, accessKeySecret string, securityToken string, expiration time.Time, err error) {
+	resp, err := r.client.Get(ramRoleMetadataURL + r.role)
+	if err != nil {
+		return "", "", "", time.Time{}, fmt.Errorf("request ram role credential failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", "", time.Time{}, fmt.Errorf("read ram role credential failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", "", time.Time{}, fmt.Errorf("request ram role credential returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var sts stsCredential
+	if err = json.Unmarshal(body, &sts); err != nil {
+		return "", "", "", time.Time{}, fmt.Errorf("unmarshal ram role credential failed: %w", err)
+	}
+	expiration, err = time.Parse(time.RFC3339, sts.Expiration)
+	if err != nil {
+		return "", "", "", time.Time{}, fmt.Errorf("parse ram role credential expiration failed: %w", err)
+	}
+	return sts.AccessKeyId, sts.AccessKeySecret, sts.SecurityToken, expiration, nil
+}
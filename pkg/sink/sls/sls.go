@@ -28,6 +28,7 @@ import (
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/core/result"
 	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
 	"github.com/loggie-io/loggie/pkg/util/runtime"
 	"github.com/pkg/errors"
 )
@@ -45,8 +46,13 @@ func makeSink(info pipeline.Info) api.Component {
 type Sink struct {
 	config *Config
 
-	client sls.ClientInterface
+	client       sls.ClientInterface
 	shutdownChan chan struct{}
+
+	projectPattern  *pattern.Pattern
+	logStorePattern *pattern.Pattern
+	topicPattern    *pattern.Pattern
+	compressType    int
 }
 
 func NewSink() *Sink {
@@ -72,45 +78,55 @@ func (s *Sink) String() string {
 }
 
 func (s *Sink) Init(context api.Context) error {
+	s.projectPattern, _ = pattern.Init(s.config.Project)
+	s.logStorePattern, _ = pattern.Init(s.config.LogStore)
+	s.topicPattern, _ = pattern.Init(s.config.Topic)
+	s.compressType = sls.Compress_LZ4
+	if s.config.Compression == compressionNone {
+		s.compressType = sls.Compress_None
+	}
 	return nil
 }
 
 func (s *Sink) Start() error {
 	log.Info("starting %s", s.String())
 	conf := s.config
-	if (conf.AccessKeyId == "" || conf.AccessKeySecret == "") && conf.CredentialProviderCommand == "" {
-		return errors.New("Neither access key pair nor credential provider command is provided")
+	update, err := s.credentialUpdater()
+	if err != nil {
+		return err
 	}
 
-	if conf.AccessKeyId != "" {
-		s.client = sls.CreateNormalInterface(conf.Endpoint, conf.AccessKeyId, conf.AccessKeySecret, "")
+	if update == nil {
+		s.client = sls.CreateNormalInterface(conf.Endpoint, conf.AccessKeyId, conf.AccessKeySecret, conf.SecurityToken)
 	} else {
-		var err error
 		s.shutdownChan = make(chan struct{})
-
-		provider := newCredentialProvider(conf.CredentialProviderCommand, conf.CredentialProviderArgs, conf.CredentialProviderTimeout)
-		s.client, err = sls.CreateTokenAutoUpdateClient(conf.Endpoint, provider.GetCredentials, s.shutdownChan)
+		s.client, err = sls.CreateTokenAutoUpdateClient(conf.Endpoint, update, s.shutdownChan)
 		if err != nil {
 			return errors.WithMessagef(err, "Create sls client failed")
 		}
 	}
 
-	// Check if project exist
-	exist, err := s.client.CheckProjectExist(conf.Project)
-	if err != nil {
-		return errors.WithMessagef(err, "Check sls project %s failed", conf.Project)
-	}
-	if !exist {
-		return errors.Errorf("Project %s is not exist", conf.Project)
-	}
+	// the rendered projects and logstores could only be checked when sending
+	if s.projectPattern.IsConst() {
+		// Check if project exist
+		exist, err := s.client.CheckProjectExist(conf.Project)
+		if err != nil {
+			return errors.WithMessagef(err, "Check sls project %s failed", conf.Project)
+		}
+		if !exist {
+			return errors.Errorf("Project %s is not exist", conf.Project)
+		}
 
-	// Check if LogStore exist
-	exist, err = s.client.CheckLogstoreExist(conf.Project, conf.LogStore)
-	if err != nil {
-		return errors.WithMessagef(err, "Check logstore %s failed", conf.LogStore)
-	}
-	if !exist {
-		return errors.Errorf("Logstore %s is not exist", conf.LogStore)
+		if s.logStorePattern.IsConst() {
+			// Check if LogStore exist
+			exist, err = s.client.CheckLogstoreExist(conf.Project, conf.LogStore)
+			if err != nil {
+				return errors.WithMessagef(err, "Check logstore %s failed", conf.LogStore)
+			}
+			if !exist {
+				return errors.Errorf("Logstore %s is not exist", conf.LogStore)
+			}
+		}
 	}
 
 	s.client.SetUserAgent(sls.DefaultLogUserAgent + " loggie/" + global.GetVersion())
@@ -118,80 +134,143 @@ func (s *Sink) Start() error {
 	return nil
 }
 
+// credentialUpdater selects the credentials in the order of the access key pair, the credential provider command and the ram role,
+// it returns nil if the access key pair is used
+func (s *Sink) credentialUpdater() (sls.UpdateTokenFunction, error) {
+	conf := s.config
+	switch {
+	case conf.AccessKeyId != "" && conf.AccessKeySecret != "":
+		return nil, nil
+	case conf.CredentialProviderCommand != "":
+		return newCredentialProvider(conf.CredentialProviderCommand, conf.CredentialProviderArgs, conf.CredentialProviderTimeout).GetCredentials, nil
+	case conf.RamRole != "":
+		return newRamRoleProvider(conf.RamRole, conf.CredentialProviderTimeout).GetCredentials, nil
+	}
+	return nil, errors.New("Neither access key pair, credential provider command nor ram role is provided")
+}
+
 func (s *Sink) Stop() {
 	if s.client != nil {
 		s.client.Close()
 	}
 
- 	if s.shutdownChan != nil {
+	if s.shutdownChan != nil {
 		close(s.shutdownChan)
 		s.shutdownChan = nil
 	}
 }
 
-func (s *Sink) Consume(batch api.Batch) api.Result {
-	// convert events to sls logs
-	logs := genSlsLogs(batch)
+// destination is where a log group is put to
+type destination struct {
+	project  string
+	logStore string
+	topic    string
+}
 
-	logGroup := &sls.LogGroup{
-		Topic:  proto.String(s.config.Topic),
-		Source: proto.String(global.NodeName),
-		Logs:   logs,
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	// group the logs by the rendered destinations, a PutLogs request is sent for each of them
+	var (
+		destinations []destination
+		groups       = make(map[destination][]*sls.Log)
+	)
+	for _, e := range batch.Events() {
+		dest, err := s.destination(e)
+		if err != nil {
+			log.Error("render sls destination error: %v; event is: %s", err, e.String())
+			continue
+		}
+		l, ok := genSlsLog(e)
+		if !ok {
+			continue
+		}
+		if _, exist := groups[dest]; !exist {
+			destinations = append(destinations, dest)
+		}
+		groups[dest] = append(groups[dest], l)
 	}
 
-	if err := s.client.PutLogs(s.config.Project, s.config.LogStore, logGroup); err != nil {
-		return result.Fail(err)
+	for _, dest := range destinations {
+		logGroup := &sls.LogGroup{
+			Topic:  proto.String(dest.topic),
+			Source: proto.String(global.NodeName),
+			Logs:   groups[dest],
+		}
+		if err := s.client.PutLogsWithCompressType(dest.project, dest.logStore, logGroup, s.compressType); err != nil {
+			return result.Fail(errors.WithMessagef(err, "put logs to %s/%s", dest.project, dest.logStore))
+		}
 	}
 
 	return result.NewResult(api.SUCCESS)
 }
 
-const token = "."
+func (s *Sink) destination(e api.Event) (destination, error) {
+	obj := runtime.NewObject(e.Header())
+	project, err := s.projectPattern.WithObject(obj).RenderWithStrict()
+	if err != nil {
+		return destination{}, err
+	}
+	logStore, err := s.logStorePattern.WithObject(obj).RenderWithStrict()
+	if err != nil {
+		return destination{}, err
+	}
+	topic, err := s.topicPattern.WithObject(obj).Render()
+	if err != nil {
+		return destination{}, err
+	}
+	return destination{project: project, logStore: logStore, topic: topic}, nil
+}
 
-func genSlsLogs(batch api.Batch) []*sls.Log {
-	var logs []*sls.Log
+const (
+	token = "."
 
-	for _, e := range batch.Events() {
-		logData := &sls.Log{}
-		if timestamp, exist := e.Meta().Get(eventer.SystemProductTimeKey); exist {
-			if t, ok := timestamp.(time.Time); ok {
-				logData.Time = proto.Uint32(uint32(t.Unix()))
-			}
-		}
+	compressionNone = "none"
+)
 
-		obj := runtime.NewObject(e.Header())
-		flatHeader, err := obj.FlatKeyValue(token)
-		if err != nil {
-			log.Error("flatten key/value pair in events error: %v", err)
-			log.Debug("flatten failed events: %s", e.String())
-			continue
+func genSlsLog(e api.Event) (*sls.Log, bool) {
+	// the time of logs is required by sls
+	logData := &sls.Log{Time: proto.Uint32(uint32(time.Now().Unix()))}
+	if timestamp, exist := e.Meta().Get(eventer.SystemProductTimeKey); exist {
+		if t, ok := timestamp.(time.Time); ok {
+			logData.Time = proto.Uint32(uint32(t.Unix()))
 		}
+	}
 
-		var contents []*sls.LogContent
-		for k, v := range flatHeader {
-			sv, ok := v.(string)
-			if !ok {
-				continue // TODO(ethfoo) convert to string instead of drop fields
-			}
+	obj := runtime.NewObject(e.Header())
+	flatHeader, err := obj.FlatKeyValue(token)
+	if err != nil {
+		log.Error("flatten key/value pair in events error: %v", err)
+		log.Debug("flatten failed events: %s", e.String())
+		return nil, false
+	}
 
-			logContent := &sls.LogContent{
-				Key:   proto.String(k),
-				Value: proto.String(sv),
-			}
-			contents = append(contents, logContent)
+	var contents []*sls.LogContent
+	for k, v := range flatHeader {
+		var sv string
+		switch val := v.(type) {
+		case nil:
+			continue
+		case string:
+			sv = val
+		case []byte:
+			sv = string(val)
+		default:
+			sv = fmt.Sprint(val)
 		}
 
-		if len(e.Body()) > 0 {
-			contents = append(contents, &sls.LogContent{
-				Key:   proto.String(eventer.Body),
-				Value: proto.String(string(e.Body())),
-			})
+		logContent := &sls.LogContent{
+			Key:   proto.String(k),
+			Value: proto.String(sv),
 		}
+		contents = append(contents, logContent)
+	}
 
-		logData.Contents = contents
-
-		logs = append(logs, logData)
+	if len(e.Body()) > 0 {
+		contents = append(contents, &sls.LogContent{
+			Key:   proto.String(eventer.Body),
+			Value: proto.String(string(e.Body())),
+		})
 	}
 
-	return logs
+	logData.Contents = contents
+	return logData, true
 }
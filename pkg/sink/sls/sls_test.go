@@ -0,0 +1,305 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sls
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sls "github.com/aliyun/aliyun-log-go-sdk"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func init() {
+	log.InitDefaultLogger()
+}
+
+const stsCredentialJSON = `{"AccessKeyId":"STS.id","AccessKeySecret":"secret","SecurityToken":"token","Expiration":"2030-01-01T00:00:00Z"}`
+
+type put struct {
+	project      string
+	logStore     string
+	topic        string
+	logs         int
+	compressType int
+}
+
+// fakeClient records the PutLogs requests, the other methods of sls.ClientInterface are not expected to be called
+type fakeClient struct {
+	sls.ClientInterface
+	err  error
+	puts []put
+}
+
+func (f *fakeClient) PutLogsWithCompressType(project, logstore string, lg *sls.LogGroup, compressType int) error {
+	f.puts = append(f.puts, put{
+		project:      project,
+		logStore:     logstore,
+		topic:        lg.GetTopic(),
+		logs:         len(lg.Logs),
+		compressType: compressType,
+	})
+	return f.err
+}
+
+func newTestSink(t *testing.T, raw string) *Sink {
+	s := NewSink()
+	assert.NoError(t, cfg.UnPackFromRaw([]byte("endpoint: cn-hangzhou.log.aliyuncs.com\n"+raw), s.config).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(context.NewContext("sls", Type, api.SINK, nil)))
+	return s
+}
+
+func newEvent(fields map[string]interface{}) api.Event {
+	e := event.NewEvent(map[string]interface{}{"fields": fields}, []byte("a"))
+	e.Fill(event.NewDefaultMeta(), e.Header(), e.Body())
+	return e
+}
+
+func TestConsume(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		fields     []map[string]interface{}
+		err        error
+		wantPuts   []put
+		wantStatus api.Status
+	}{
+		{
+			name:   "const destination",
+			raw:    "project: p\nlogstore: l\ntopic: t",
+			fields: []map[string]interface{}{{"app": "web"}, {"app": "api"}},
+			wantPuts: []put{
+				{project: "p", logStore: "l", topic: "t", logs: 2, compressType: sls.Compress_LZ4},
+			},
+			wantStatus: api.SUCCESS,
+		},
+		{
+			name: "grouped by rendered destinations",
+			raw:  "project: ${fields.ns}\nlogstore: app-${fields.app}\ntopic: ${fields.topic}",
+			fields: []map[string]interface{}{
+				{"ns": "a", "app": "web", "topic": "t1"},
+				{"ns": "b", "app": "web", "topic": "t1"},
+				{"ns": "a", "app": "web", "topic": "t1"},
+				{"ns": "a", "app": "web", "topic": "t2"},
+			},
+			wantPuts: []put{
+				{project: "a", logStore: "app-web", topic: "t1", logs: 2, compressType: sls.Compress_LZ4},
+				{project: "b", logStore: "app-web", topic: "t1", logs: 1, compressType: sls.Compress_LZ4},
+				{project: "a", logStore: "app-web", topic: "t2", logs: 1, compressType: sls.Compress_LZ4},
+			},
+			wantStatus: api.SUCCESS,
+		},
+		{
+			name: "dropped when the project or logstore is not rendered",
+			raw:  "project: ${fields.ns}\nlogstore: ${fields.app}\ntopic: ${fields.topic}",
+			fields: []map[string]interface{}{
+				{"ns": "a", "app": "web"},
+				{"app": "web"},
+				{"ns": "a"},
+			},
+			wantPuts: []put{
+				{project: "a", logStore: "web", logs: 1, compressType: sls.Compress_LZ4},
+			},
+			wantStatus: api.SUCCESS,
+		},
+		{
+			name:   "without compression",
+			raw:    "project: p\nlogstore: l\ncompression: none",
+			fields: []map[string]interface{}{{"app": "web"}},
+			wantPuts: []put{
+				{project: "p", logStore: "l", logs: 1, compressType: sls.Compress_None},
+			},
+			wantStatus: api.SUCCESS,
+		},
+		{
+			name:   "failed to put",
+			raw:    "project: ${fields.ns}\nlogstore: l",
+			fields: []map[string]interface{}{{"ns": "a"}, {"ns": "b"}},
+			err:    errors.New("project not exist"),
+			wantPuts: []put{
+				{project: "a", logStore: "l", logs: 1, compressType: sls.Compress_LZ4},
+			},
+			wantStatus: api.FAIL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSink(t, tt.raw)
+			client := &fakeClient{err: tt.err}
+			s.client = client
+
+			var events []api.Event
+			for _, fields := range tt.fields {
+				events = append(events, newEvent(fields))
+			}
+			assert.Equal(t, tt.wantStatus, s.Consume(batch.NewBatchWithEvents(events)).Status())
+			assert.Equal(t, tt.wantPuts, client.puts)
+		})
+	}
+}
+
+func TestCredentialUpdater(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loggie":
+			fmt.Fprint(w, stsCredentialJSON)
+		case "/expired":
+			fmt.Fprint(w, `{"AccessKeyId":"STS.id","Expiration":"tomorrow"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer func(url string) { ramRoleMetadataURL = url }(ramRoleMetadataURL)
+	ramRoleMetadataURL = srv.URL + "/"
+
+	// the credential provider command prints the sts credential
+	credential := filepath.Join(t.TempDir(), "credential.json")
+	assert.NoError(t, os.WriteFile(credential, []byte(stsCredentialJSON), 0644))
+
+	tests := []struct {
+		name string
+		raw  string
+		// the static access key pair is used without the updater
+		wantStatic    bool
+		wantKeyId     string
+		wantErr       bool
+		wantUpdateErr bool
+	}{
+		{
+			name:       "access key pair",
+			raw:        "accessKeyId: id\naccessKeySecret: secret\nramRole: loggie",
+			wantStatic: true,
+		},
+		{
+			name:       "sts token along with access key pair",
+			raw:        "accessKeyId: id\naccessKeySecret: secret\nsecurityToken: token",
+			wantStatic: true,
+		},
+		{
+			name:      "credential provider command",
+			raw:       "cridentialProviderCommand: cat\ncredentialProviderArgs: [" + credential + "]\nramRole: loggie",
+			wantKeyId: "STS.id",
+		},
+		{
+			name:          "credential provider command failed",
+			raw:           "cridentialProviderCommand: cat\ncredentialProviderArgs: [" + credential + ".missing]",
+			wantUpdateErr: true,
+		},
+		{
+			name:      "ram role",
+			raw:       "ramRole: loggie",
+			wantKeyId: "STS.id",
+		},
+		{
+			name:      "ram role with incomplete access key pair",
+			raw:       "accessKeyId: id\nramRole: loggie",
+			wantKeyId: "STS.id",
+		},
+		{
+			name:          "ram role not found",
+			raw:           "ramRole: unknown",
+			wantUpdateErr: true,
+		},
+		{
+			name:          "ram role with invalid expiration",
+			raw:           "ramRole: expired",
+			wantUpdateErr: true,
+		},
+		{
+			name:    "none",
+			raw:     "accessKeyId: id",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSink(t, "project: p\nlogstore: l\n"+tt.raw)
+			update, err := s.credentialUpdater()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.wantStatic {
+				assert.Nil(t, update)
+				return
+			}
+
+			keyId, keySecret, token, expiration, err := update()
+			if tt.wantUpdateErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantKeyId, keyId)
+			assert.Equal(t, "secret", keySecret)
+			assert.Equal(t, "token", token)
+			assert.Equal(t, 2030, expiration.Year())
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			raw:  "project: ${fields.ns}\nlogstore: l\ntopic: ${fields.topic}",
+		},
+		{
+			name: "sts token along with access key pair",
+			raw:  "project: p\nlogstore: l\naccessKeyId: id\naccessKeySecret: secret\nsecurityToken: token",
+		},
+		{
+			name:    "sts token without access key pair",
+			raw:     "project: p\nlogstore: l\nsecurityToken: token",
+			wantErr: true,
+		},
+		{
+			name:    "unknown compression",
+			raw:     "project: p\nlogstore: l\ncompression: gzip",
+			wantErr: true,
+		},
+		{
+			name:    "without project",
+			raw:     "logstore: l",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := cfg.UnPackFromRaw([]byte("endpoint: cn-hangzhou.log.aliyuncs.com\n"+tt.raw), &Config{}).Defaults().Validate().Do()
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package splunk
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
+
+type Config struct {
+	// URL is the address of the HTTP Event Collector, such as https://splunk:8088
+	URL   string `yaml:"url,omitempty" validate:"required"`
+	Token string `yaml:"token,omitempty" validate:"required"`
+
+	// Index, Source, Sourcetype and Host could be patterns such as ${fields.index}, the defaults of the token are used if empty
+	Index      string `yaml:"index,omitempty"`
+	Source     string `yaml:"source,omitempty"`
+	Sourcetype string `yaml:"sourcetype,omitempty"`
+	Host       string `yaml:"host,omitempty"`
+	// Fields are the indexed fields of the events, the keys are the field names and the values are the keys of the events
+	Fields map[string]string `yaml:"fields,omitempty"`
+
+	// Ack waits for the events to be indexed by polling the indexer acknowledgement, which should be enabled for the token
+	Ack             bool          `yaml:"ack,omitempty"`
+	AckTimeout      time.Duration `yaml:"ackTimeout,omitempty" default:"1m"`
+	AckPollInterval time.Duration `yaml:"ackPollInterval,omitempty" default:"1s"`
+
+	Compress           *bool            `yaml:"compress,omitempty" default:"true"` // gzip the request body
+	InsecureSkipVerify bool             `yaml:"insecureSkipVerify,omitempty"`
+	Timeout            time.Duration    `yaml:"timeout,omitempty" default:"30s"`
+	HostLimit          hostlimit.Config `yaml:"hostLimit,omitempty"`
+}
+
+func (c *Config) SetDefaults() {
+	c.URL = strings.TrimSuffix(c.URL, "/")
+}
+
+func (c *Config) Validate() error {
+	if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+		return errors.Errorf("splunk sink url %s should start with http:// or https://", c.URL)
+	}
+	for _, p := range []string{c.Index, c.Source, c.Sourcetype, c.Host} {
+		if err := pattern.Validate(p); err != nil {
+			return err
+		}
+	}
+	if c.Ack && c.AckPollInterval <= 0 {
+		return errors.New("splunk sink ackPollInterval should be positive")
+	}
+	return nil
+}
+
+func (c *Config) compress() bool {
+	return c.Compress == nil || *c.Compress
+}
@@ -0,0 +1,348 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package splunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	stdjson "encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	eventer "github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const (
+	Type = "splunk"
+
+	eventPath = "/services/collector/event"
+	ackPath   = "/services/collector/ack"
+
+	channelHeader = "X-Splunk-Request-Channel"
+)
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink(info.PipelineName)
+}
+
+type Sink struct {
+	pipelineName string
+	name         string
+	config       *Config
+	codec        codec.Codec
+	client       *http.Client
+	limiter      *hostlimit.Transport
+
+	indexPattern      *pattern.Pattern
+	sourcePattern     *pattern.Pattern
+	sourcetypePattern *pattern.Pattern
+	hostPattern       *pattern.Pattern
+
+	// channel identifies the sink to the indexer acknowledgement, the ack ids are scoped by it
+	channel string
+}
+
+func NewSink(pipelineName string) *Sink {
+	return &Sink{
+		pipelineName: pipelineName,
+		config:       &Config{},
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) SetCodec(c codec.Codec) {
+	s.codec = c
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.indexPattern, _ = pattern.Init(s.config.Index)
+	s.sourcePattern, _ = pattern.Init(s.config.Source)
+	s.sourcetypePattern, _ = pattern.Init(s.config.Sourcetype)
+	s.hostPattern, _ = pattern.Init(s.config.Host)
+
+	channel, err := newChannel()
+	if err != nil {
+		return err
+	}
+	s.channel = channel
+
+	tlsConfig, err := netutils.NewTLSConfig("", "", "", s.config.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	s.client = &http.Client{
+		Transport: transport,
+		Timeout:   s.config.Timeout,
+	}
+	return nil
+}
+
+func (s *Sink) Start() error {
+	if s.config.HostLimit.Enabled() {
+		s.limiter = hostlimit.NewTransport(s.client.Transport, &s.config.HostLimit, s.pipelineName, s.name)
+		s.client.Transport = s.limiter
+	}
+	log.Info("%s start, url: %s, ack: %t", s.String(), s.config.URL, s.config.Ack)
+	return nil
+}
+
+func (s *Sink) Stop() {
+	if s.limiter != nil {
+		s.limiter.Stop()
+	}
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	var body bytes.Buffer
+	for _, e := range events {
+		data, err := s.encode(e)
+		if err != nil {
+			log.Warn("[%s] encode event error: %v", s.name, err)
+			continue
+		}
+		body.Write(data)
+	}
+	if body.Len() == 0 {
+		return result.Success()
+	}
+
+	ctx := context.Background()
+	ackId, err := s.send(ctx, body.Bytes())
+	if err != nil {
+		return result.Fail(errors.WithMessage(err, "send events to splunk"))
+	}
+	if s.config.Ack {
+		if err := s.waitAck(ctx, ackId); err != nil {
+			return result.Fail(err)
+		}
+	}
+	return result.Success()
+}
+
+// hecEvent is the event of the HTTP Event Collector, the events of a batch are concatenated in the request body
+type hecEvent struct {
+	Time       stdjson.Number         `json:"time"`
+	Host       string                 `json:"host,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	Sourcetype string                 `json:"sourcetype,omitempty"`
+	Index      string                 `json:"index,omitempty"`
+	Event      interface{}            `json:"event"`
+	Fields     map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (s *Sink) encode(e api.Event) ([]byte, error) {
+	obj := runtime.NewObject(e.Header())
+	out := &hecEvent{
+		Time: stdjson.Number(strconv.FormatFloat(float64(timestamp(e).UnixMilli())/1000, 'f', 3, 64)),
+	}
+
+	var err error
+	if out.Index, err = s.indexPattern.WithObject(obj).Render(); err != nil {
+		return nil, err
+	}
+	if out.Source, err = s.sourcePattern.WithObject(obj).Render(); err != nil {
+		return nil, err
+	}
+	if out.Sourcetype, err = s.sourcetypePattern.WithObject(obj).Render(); err != nil {
+		return nil, err
+	}
+	if out.Host, err = s.hostPattern.WithObject(obj).Render(); err != nil {
+		return nil, err
+	}
+
+	for name, key := range s.config.Fields {
+		if v := obj.GetPath(key).Value(); v != nil {
+			if out.Fields == nil {
+				out.Fields = make(map[string]interface{}, len(s.config.Fields))
+			}
+			out.Fields[name] = v
+		}
+	}
+
+	data, err := s.codec.Encode(e)
+	if err != nil {
+		return nil, err
+	}
+	// a json object is searchable by the fields in splunk, others are sent as the raw text
+	if stdjson.Valid(data) {
+		out.Event = stdjson.RawMessage(data)
+	} else {
+		out.Event = string(data)
+	}
+	return json.Marshal(out)
+}
+
+func timestamp(e api.Event) time.Time {
+	if e.Meta() != nil {
+		if v, ok := e.Meta().Get(eventer.SystemProductTimeKey); ok {
+			if t, ok := v.(time.Time); ok {
+				return t
+			}
+		}
+	}
+	return time.Now()
+}
+
+type hecResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckId *int64 `json:"ackId,omitempty"`
+}
+
+type ackResponse struct {
+	Acks map[string]bool `json:"acks"`
+}
+
+func (s *Sink) send(ctx context.Context, body []byte) (int64, error) {
+	header := http.Header{}
+	if s.config.compress() {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(body); err != nil {
+			return 0, err
+		}
+		if err := w.Close(); err != nil {
+			return 0, err
+		}
+		body = buf.Bytes()
+		header.Set("Content-Encoding", "gzip")
+	}
+
+	out := &hecResponse{}
+	if err := s.post(ctx, eventPath, header, body, out); err != nil {
+		return 0, err
+	}
+	if !s.config.Ack {
+		return 0, nil
+	}
+	if out.AckId == nil {
+		return 0, errors.New("no ackId returned, indexer acknowledgement may be disabled for the token")
+	}
+	return *out.AckId, nil
+}
+
+// waitAck polls the status of the ack id until the events are indexed, the events are resent after timeout,
+// since the indexer may have failed before indexing them
+func (s *Sink) waitAck(ctx context.Context, ackId int64) error {
+	body, err := json.Marshal(map[string][]int64{"acks": {ackId}})
+	if err != nil {
+		return err
+	}
+	id := strconv.FormatInt(ackId, 10)
+
+	deadline := time.Now().Add(s.config.AckTimeout)
+	for {
+		out := &ackResponse{}
+		if err := s.post(ctx, ackPath, nil, body, out); err != nil {
+			log.Warn("[%s] query splunk ack %d error: %v", s.name, ackId, err)
+		} else if out.Acks[id] {
+			return nil
+		}
+
+		if time.Now().Add(s.config.AckPollInterval).After(deadline) {
+			return errors.Errorf("splunk ack %d is not acknowledged in %s", ackId, s.config.AckTimeout)
+		}
+		time.Sleep(s.config.AckPollInterval)
+	}
+}
+
+func (s *Sink) post(ctx context.Context, path string, header http.Header, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Authorization", "Splunk "+s.config.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(channelHeader, s.channel)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		herr := &hecResponse{}
+		if json.Unmarshal(respBody, herr) == nil && herr.Text != "" {
+			return errors.Errorf("splunk returned status %d, code %d: %s", resp.StatusCode, herr.Code, herr.Text)
+		}
+		return errors.Errorf("splunk returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// newChannel returns a random uuid as the channel
+func newChannel() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package splunk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	lcontext "github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/codec/json"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		header map[string]interface{}
+		want   string
+	}{
+		{
+			name:   "index and fields",
+			config: &Config{Index: "${fields.index}", Sourcetype: "loggie", Fields: map[string]string{"app": "fields.app"}},
+			header: map[string]interface{}{"fields": map[string]interface{}{"index": "main", "app": "foo"}},
+			want:   `{"time":1700000000.123,"sourcetype":"loggie","index":"main","event":{"body":"hello","fields":{"app":"foo","index":"main"}},"fields":{"app":"foo"}}`,
+		},
+		{
+			name:   "missing fields are omitted",
+			config: &Config{Host: "${host}", Fields: map[string]string{"app": "fields.app"}},
+			header: map[string]interface{}{"host": "node-1"},
+			want:   `{"time":1700000000.123,"host":"node-1","event":{"body":"hello","host":"node-1"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSink("test")
+			s.config = tt.config
+			c := json.NewJson()
+			c.Init(&codec.Config{})
+			s.SetCodec(c)
+			assert.NoError(t, s.Init(lcontext.NewContext("splunk", Type, api.SINK, nil)))
+
+			e := event.NewEvent(tt.header, []byte("hello"))
+			meta := event.NewDefaultMeta()
+			meta.Set(event.SystemProductTimeKey, time.Unix(1700000000, 123000000))
+			e.Fill(meta, e.Header(), e.Body())
+
+			data, err := s.encode(e)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(data))
+		})
+	}
+}
+
+func TestWaitAck(t *testing.T) {
+	log.InitDefaultLogger()
+	tests := []struct {
+		name      string
+		ackAfter  int // the ack is acknowledged after polled so many times
+		wantPolls int
+		wantErr   bool
+	}{
+		{name: "acknowledged", wantPolls: 1},
+		{name: "acknowledged after polls", ackAfter: 2, wantPolls: 3},
+		{name: "timeout", ackAfter: 1000, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				polls++
+				assert.Equal(t, ackPath, r.URL.Path)
+				if polls > tt.ackAfter {
+					w.Write([]byte(`{"acks":{"7":true}}`))
+					return
+				}
+				w.Write([]byte(`{"acks":{"7":false}}`))
+			}))
+			defer server.Close()
+
+			s := NewSink("test")
+			s.config = &Config{URL: server.URL, AckPollInterval: 10 * time.Millisecond, AckTimeout: 50 * time.Millisecond}
+			assert.NoError(t, s.Init(lcontext.NewContext("splunk", Type, api.SINK, nil)))
+
+			err := s.waitAck(context.Background(), 7)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantPolls, polls)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package starrocks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+)
+
+const (
+	FormatJson = "json"
+	FormatCsv  = "csv"
+)
+
+var labelPrefixRegex = regexp.MustCompile(`^[-_A-Za-z0-9]{1,64}$`)
+
+// Config of the stream load sink, it works with both StarRocks and Apache Doris
+type Config struct {
+	// Hosts are the http addresses of the frontends, such as http://127.0.0.1:8030, the next one is tried
+	// when the load failed with a retryable error
+	Hosts    []string `yaml:"hosts,omitempty" validate:"required"`
+	Username string   `yaml:"username,omitempty" default:"root"`
+	Password string   `yaml:"password,omitempty"`
+	Database string   `yaml:"database,omitempty" validate:"required"`
+	Table    string   `yaml:"table,omitempty" validate:"required"`
+	Columns  []Column `yaml:"columns,omitempty" validate:"required,dive"`
+	// TimestampColumn is filled with the time when the event was produced, such as a DATETIME column
+	TimestampColumn string `yaml:"timestampColumn,omitempty"`
+	// DerivedColumns are computed by the server from the loaded columns, such as dt=date(ts)
+	DerivedColumns []string `yaml:"derivedColumns,omitempty"`
+
+	Format          string `yaml:"format,omitempty" default:"json" validate:"oneof=json csv"`
+	ColumnSeparator string `yaml:"columnSeparator,omitempty" default:"\t"` // only for csv
+	// LabelPrefix prefixes the labels of the loads, the label of a batch is the same when retried
+	// so that the batch is loaded exactly once
+	LabelPrefix string `yaml:"labelPrefix,omitempty" default:"loggie"`
+	// Properties are the additional headers of the stream load, such as max_filter_ratio: "0.1"
+	Properties map[string]string `yaml:"properties,omitempty"`
+
+	MaxRetries int              `yaml:"maxRetries,omitempty" validate:"gte=0"` // every frontend is tried once by default
+	Timeout    time.Duration    `yaml:"timeout,omitempty" default:"60s"`
+	HostLimit  hostlimit.Config `yaml:"hostLimit,omitempty"`
+}
+
+// Column maps a key of the event to a column of the table
+type Column struct {
+	Name string `yaml:"name,omitempty" validate:"required"`
+	Key  string `yaml:"key,omitempty"` // such as fields.app, the same as name if empty, body means the event body
+}
+
+func (c *Column) SetDefaults() {
+	if c.Key == "" {
+		c.Key = c.Name
+	}
+}
+
+func (c *Config) SetDefaults() {
+	if c.MaxRetries == 0 && len(c.Hosts) > 1 {
+		c.MaxRetries = len(c.Hosts) - 1
+	}
+}
+
+func (c *Config) Validate() error {
+	if !labelPrefixRegex.MatchString(c.LabelPrefix) {
+		return fmt.Errorf("starrocks sink labelPrefix %s should only contain letters, digits, - and _", c.LabelPrefix)
+	}
+	if c.Format == FormatCsv && c.ColumnSeparator == "" {
+		return fmt.Errorf("starrocks sink columnSeparator is required for csv")
+	}
+
+	names := make(map[string]struct{})
+	if c.TimestampColumn != "" {
+		names[c.TimestampColumn] = struct{}{}
+	}
+	for _, col := range c.Columns {
+		if strings.Contains(col.Name, ",") {
+			return fmt.Errorf("starrocks sink column %s should not contain comma", col.Name)
+		}
+		if _, ok := names[col.Name]; ok {
+			return fmt.Errorf("starrocks sink column %s is duplicated", col.Name)
+		}
+		names[col.Name] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package starrocks
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stdjson "encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	eventer "github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const (
+	Type = "starrocks"
+
+	timestampLayout = "2006-01-02 15:04:05.000"
+	nullValue       = `\N`
+	maxRedirects    = 10
+
+	statusSuccess        = "Success"
+	statusPublishTimeout = "Publish Timeout" // the data is committed and will be visible later
+	statusLabelExists    = "Label Already Exists"
+)
+
+// committedJobStatus are the status of the existing load with the same label, which means the batch was loaded already
+var committedJobStatus = map[string]struct{}{
+	"FINISHED":  {},
+	"COMMITTED": {},
+	"VISIBLE":   {},
+}
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink(info.PipelineName)
+}
+
+type Sink struct {
+	pipelineName string
+	name         string
+	config       *Config
+	client       *http.Client
+	limiter      *hostlimit.Transport
+
+	path    string
+	columns string
+	next    uint32 // the frontend to start with, so the loads are spread to the frontends
+}
+
+type loadResponse struct {
+	Status            string `json:"Status"`
+	Message           string `json:"Message"`
+	ExistingJobStatus string `json:"ExistingJobStatus"`
+	ErrorURL          string `json:"ErrorURL"`
+	NumberLoadedRows  int64  `json:"NumberLoadedRows"`
+}
+
+func NewSink(pipelineName string) *Sink {
+	return &Sink{
+		pipelineName: pipelineName,
+		config:       &Config{},
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+
+	// the frontends require the 100-continue and redirect the loads to the backends
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ExpectContinueTimeout = time.Second
+	s.client = &http.Client{
+		Transport:     transport,
+		Timeout:       s.config.Timeout,
+		CheckRedirect: s.checkRedirect,
+	}
+
+	columns := make([]string, 0, len(s.config.Columns)+len(s.config.DerivedColumns)+1)
+	if s.config.TimestampColumn != "" {
+		columns = append(columns, s.config.TimestampColumn)
+	}
+	for _, c := range s.config.Columns {
+		columns = append(columns, c.Name)
+	}
+	columns = append(columns, s.config.DerivedColumns...)
+	s.columns = strings.Join(columns, ",")
+	s.path = fmt.Sprintf("/api/%s/%s/_stream_load", s.config.Database, s.config.Table)
+	return nil
+}
+
+func (s *Sink) Start() error {
+	if s.config.HostLimit.Enabled() {
+		s.limiter = hostlimit.NewTransport(s.client.Transport, &s.config.HostLimit, s.pipelineName, s.name)
+		s.client.Transport = s.limiter
+	}
+	log.Info("%s start, hosts: %v, table: %s.%s", s.String(), s.config.Hosts, s.config.Database, s.config.Table)
+	return nil
+}
+
+func (s *Sink) Stop() {
+	if s.limiter != nil {
+		s.limiter.Stop()
+	}
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	var body []byte
+	var err error
+	if s.config.Format == FormatCsv {
+		body, err = s.csvRows(events)
+	} else {
+		// the keys are sorted by the standard library, so the label of a retried batch keeps the same
+		body, err = stdjson.Marshal(s.jsonRows(events))
+	}
+	if err != nil {
+		return result.Fail(errors.WithMessagef(err, "encode %s rows", s.config.Format))
+	}
+
+	if err := s.load(context.Background(), s.label(events, body), body); err != nil {
+		return result.Fail(errors.WithMessagef(err, "stream load into %s.%s", s.config.Database, s.config.Table))
+	}
+	return result.Success()
+}
+
+// jsonRows converts the events into the rows of json format, such as [{"ts": "...", "body": "..."}]
+func (s *Sink) jsonRows(events []api.Event) []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, len(events))
+	for _, e := range events {
+		row := make(map[string]interface{}, len(s.config.Columns)+1)
+		if s.config.TimestampColumn != "" {
+			row[s.config.TimestampColumn] = timestamp(e).Format(timestampLayout)
+		}
+		obj := runtime.NewObject(e.Header())
+		for _, c := range s.config.Columns {
+			row[c.Name] = value(obj, e, c.Key)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// csvRows converts the events into the rows of csv format, the stream load does not support quoting,
+// so the separators and newlines in the values are replaced with spaces
+func (s *Sink) csvRows(events []api.Event) ([]byte, error) {
+	replacer := strings.NewReplacer(s.config.ColumnSeparator, " ", "\n", " ", "\r", " ")
+	var buf bytes.Buffer
+	for _, e := range events {
+		fields := make([]string, 0, len(s.config.Columns)+1)
+		if s.config.TimestampColumn != "" {
+			fields = append(fields, timestamp(e).Format(timestampLayout))
+		}
+		obj := runtime.NewObject(e.Header())
+		for _, c := range s.config.Columns {
+			field, err := csvField(value(obj, e, c.Key))
+			if err != nil {
+				return nil, errors.WithMessagef(err, "column %s", c.Name)
+			}
+			fields = append(fields, replacer.Replace(field))
+		}
+		buf.WriteString(strings.Join(fields, s.config.ColumnSeparator))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func value(obj *runtime.Object, e api.Event, key string) interface{} {
+	if key == codec.BodyKey {
+		return string(e.Body())
+	}
+	return obj.GetPath(key).Value()
+}
+
+func csvField(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return nullValue, nil
+	case string:
+		return val, nil
+	case []byte:
+		return string(val), nil
+	}
+	out, err := stdjson.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func timestamp(e api.Event) time.Time {
+	if e.Meta() != nil {
+		if v, ok := e.Meta().Get(eventer.SystemProductTimeKey); ok {
+			if t, ok := v.(time.Time); ok {
+				return t
+			}
+		}
+	}
+	return time.Now()
+}
+
+// label is derived from the table, the rows and the produced time of the events, so a retried batch is deduplicated
+// by the server, while the batches with the same content produced at different times are still loaded
+func (s *Sink) label(events []api.Event, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(s.config.Database + "." + s.config.Table + "\n"))
+	h.Write(body)
+	for _, e := range events {
+		h.Write([]byte(timestamp(e).Format(time.RFC3339Nano)))
+	}
+	return s.config.LabelPrefix + "_" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// load tries the frontends in turn until success or a non retryable error
+func (s *Sink) load(ctx context.Context, label string, body []byte) error {
+	start := int(atomic.AddUint32(&s.next, 1))
+	var err error
+	for i := 0; i <= s.config.MaxRetries; i++ {
+		host := s.config.Hosts[(start+i)%len(s.config.Hosts)]
+		var retryable bool
+		retryable, err = s.put(ctx, host, label, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable {
+			return err
+		}
+		log.Warn("%s stream load %s to %s failed, try the next frontend: %v", s.String(), label, host, err)
+	}
+	return err
+}
+
+func (s *Sink) put(ctx context.Context, host string, label string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimSuffix(host, "/")+s.path, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	s.setHeaders(req, label)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) || errors.Is(err, io.EOF), err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return true, errors.WithMessage(err, "read response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = errors.Errorf("stream load returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		return resp.StatusCode >= http.StatusInternalServerError, err
+	}
+
+	out := &loadResponse{}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return false, errors.WithMessagef(err, "unmarshal response %s", string(respBody))
+	}
+	switch out.Status {
+	case statusSuccess, statusPublishTimeout:
+		return false, nil
+
+	case statusLabelExists:
+		if _, ok := committedJobStatus[out.ExistingJobStatus]; ok {
+			log.Info("%s label %s was loaded already, skip the batch", s.String(), label)
+			return false, nil
+		}
+		// the previous load is still running, the batch would be retried by the pipeline
+		return false, errors.Errorf("the load with label %s is %s", label, out.ExistingJobStatus)
+	}
+
+	if out.ErrorURL != "" {
+		return false, errors.Errorf("stream load %s: %s, see %s", out.Status, out.Message, out.ErrorURL)
+	}
+	return false, errors.Errorf("stream load %s: %s", out.Status, out.Message)
+}
+
+func (s *Sink) setHeaders(req *http.Request, label string) {
+	req.SetBasicAuth(s.config.Username, s.config.Password)
+	req.Header.Set("Expect", "100-continue")
+	req.Header.Set("label", label)
+	req.Header.Set("format", s.config.Format)
+	req.Header.Set("columns", s.columns)
+	if s.config.Format == FormatCsv {
+		req.Header.Set("column_separator", s.config.ColumnSeparator)
+	} else {
+		req.Header.Set("strip_outer_array", "true")
+	}
+	if s.config.Timeout > 0 {
+		req.Header.Set("timeout", fmt.Sprintf("%d", int(s.config.Timeout.Seconds())))
+	}
+	for k, v := range s.config.Properties {
+		req.Header.Set(k, v)
+	}
+}
+
+// checkRedirect keeps the credentials when the frontend redirects the load to a backend,
+// which are removed by the http client because the host is different
+func (s *Sink) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errors.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	req.SetBasicAuth(s.config.Username, s.config.Password)
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package starrocks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	lcontext "github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+const testConfig = `
+hosts: ["http://127.0.0.1:8030"]
+password: secret
+database: db
+table: logs
+columns:
+  - name: app
+    key: fields.app
+  - name: level
+    key: fields.level
+  - name: message
+    key: body
+`
+
+func newTestSink(t *testing.T, raw string) *Sink {
+	log.InitDefaultLogger()
+	s := NewSink("test")
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(testConfig+raw), s.config).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(lcontext.NewContext("starrocks", Type, api.SINK, nil)))
+	return s
+}
+
+func newTestEvents(produced time.Time) []api.Event {
+	var events []api.Event
+	for _, app := range []string{"nginx", "redis"} {
+		e := event.NewEvent(map[string]interface{}{
+			"fields": map[string]interface{}{"app": app},
+		}, []byte(app+"\tlog\n"))
+		meta := event.NewDefaultMeta()
+		meta.Set(event.SystemProductTimeKey, produced)
+		e.Fill(meta, e.Header(), e.Body())
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestRows(t *testing.T) {
+	events := newTestEvents(time.Date(2023, 7, 22, 4, 26, 40, 0, time.UTC))
+
+	s := newTestSink(t, "timestampColumn: ts\nderivedColumns: [\"dt=date(ts)\"]")
+	assert.Equal(t, "ts,app,level,message,dt=date(ts)", s.columns)
+	assert.Equal(t, []map[string]interface{}{
+		{"ts": "2023-07-22 04:26:40.000", "app": "nginx", "level": nil, "message": "nginx\tlog\n"},
+		{"ts": "2023-07-22 04:26:40.000", "app": "redis", "level": nil, "message": "redis\tlog\n"},
+	}, s.jsonRows(events))
+
+	// the separators and newlines are replaced in csv
+	s = newTestSink(t, "format: csv")
+	rows, err := s.csvRows(events)
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx\t\\N\tnginx log \nredis\t\\N\tredis log \n", string(rows))
+}
+
+func TestLabel(t *testing.T) {
+	s := newTestSink(t, "")
+	produced := time.Date(2023, 7, 22, 4, 26, 40, 0, time.UTC)
+	body := []byte("rows")
+
+	label := s.label(newTestEvents(produced), body)
+	assert.Regexp(t, `^loggie_[0-9a-f]{32}$`, label)
+	// a retried batch is deduplicated by the label
+	assert.Equal(t, label, s.label(newTestEvents(produced), body))
+	// the same rows produced at another time are loaded
+	assert.NotEqual(t, label, s.label(newTestEvents(produced.Add(time.Second)), body))
+}
+
+func TestPut(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		wantErr  string
+	}{
+		{name: "success", response: `{"Status": "Success", "NumberLoadedRows": 2}`},
+		{name: "loaded already", response: `{"Status": "Label Already Exists", "ExistingJobStatus": "FINISHED"}`},
+		{name: "loading", response: `{"Status": "Label Already Exists", "ExistingJobStatus": "RUNNING"}`, wantErr: "RUNNING"},
+		{name: "failed", response: `{"Status": "Fail", "Message": "too many filtered rows", "ErrorURL": "http://be/error"}`, wantErr: "http://be/error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			be := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// the credentials are kept after redirected
+				user, password, ok := r.BasicAuth()
+				assert.True(t, ok && user == "root" && password == "secret")
+				assert.Equal(t, "label", r.Header.Get("label"))
+				w.Write([]byte(tt.response))
+			}))
+			defer be.Close()
+			// the frontend redirects the loads to the backend
+			fe := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/db/logs/_stream_load", r.URL.Path)
+				http.Redirect(w, r, be.URL+r.URL.Path, http.StatusTemporaryRedirect)
+			}))
+			defer fe.Close()
+
+			s := newTestSink(t, "")
+			retryable, err := s.put(context.Background(), fe.URL, "label", []byte(`[]`))
+			assert.False(t, retryable)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "invalid label prefix", raw: "labelPrefix: a.b"},
+		{name: "column with comma", raw: "columns: [{name: \"a,b\"}]"},
+		{name: "duplicated column", raw: "timestampColumn: app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, cfg.UnPackFromRaw([]byte(testConfig+tt.raw), &Config{}).Defaults().Validate().Do())
+		})
+	}
+}
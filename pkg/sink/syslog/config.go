@@ -0,0 +1,120 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syslog
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
+
+const (
+	ProtocolUDP = "udp"
+	ProtocolTCP = "tcp"
+
+	// FramingOctetCounting prefixes every message with its length over tcp, as RFC5425 and RFC6587 describe
+	FramingOctetCounting = "octet-counting"
+	// FramingNonTransparent delimits the messages by LF, the newlines in messages are replaced with spaces
+	FramingNonTransparent = "non-transparent"
+)
+
+type Config struct {
+	// Address is the syslog receiver, such as siem:6514
+	Address  string `yaml:"address,omitempty" validate:"required,hostname_port"`
+	Protocol string `yaml:"protocol,omitempty" default:"tcp" validate:"oneof=udp tcp"`
+	TLS      TLS    `yaml:"tls,omitempty"` // only for tcp
+	Framing  string `yaml:"framing,omitempty" default:"octet-counting" validate:"oneof=octet-counting non-transparent"`
+
+	// Facility is a name such as local0 or a number, FacilityKey is the key of the facility of the events which overrides it
+	Facility    string `yaml:"facility,omitempty" default:"local0"`
+	FacilityKey string `yaml:"facilityKey,omitempty"`
+	// Severity is used when the severity of the event is missing, SeverityKey is the key of the level of the events,
+	// the value could be a number, a syslog severity name, or mapped to a severity name by SeverityMapping, such as WARN: warning
+	Severity        string            `yaml:"severity,omitempty" default:"info"`
+	SeverityKey     string            `yaml:"severityKey,omitempty"`
+	SeverityMapping map[string]string `yaml:"severityMapping,omitempty"`
+
+	// Hostname, AppName, ProcID and MsgID are patterns such as ${fields.app}, the node name is used if Hostname is empty
+	Hostname string `yaml:"hostname,omitempty"`
+	AppName  string `yaml:"appName,omitempty" default:"loggie"`
+	ProcID   string `yaml:"procId,omitempty"`
+	MsgID    string `yaml:"msgId,omitempty"`
+
+	StructuredData []SDElement `yaml:"structuredData,omitempty"`
+
+	// MaxMessageBytes truncates the long messages over udp, which are always sent in a single datagram
+	MaxMessageBytes int           `yaml:"maxMessageBytes,omitempty" default:"2048" validate:"gte=480,lte=65507"`
+	Timeout         time.Duration `yaml:"timeout,omitempty" default:"10s"`
+}
+
+// SDElement is a structured data element, the keys of params are the param names and the values are patterns
+type SDElement struct {
+	ID     string            `yaml:"id,omitempty" validate:"required"`
+	Params map[string]string `yaml:"params,omitempty"`
+}
+
+type TLS struct {
+	Enabled            bool   `yaml:"enabled,omitempty"`
+	CaCertFiles        string `yaml:"caCertFiles,omitempty"`
+	ClientCertFile     string `yaml:"clientCertFile,omitempty"`
+	ClientKeyFile      string `yaml:"clientKeyFile,omitempty"`
+	ServerName         string `yaml:"serverName,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	if c.TLS.Enabled && c.Protocol != ProtocolTCP {
+		return errors.New("syslog sink tls is only supported by tcp")
+	}
+	if (c.TLS.ClientCertFile == "") != (c.TLS.ClientKeyFile == "") {
+		return errors.New("clientCertFile and clientKeyFile should be set together")
+	}
+	if _, ok := parseFacility(c.Facility); !ok {
+		return errors.Errorf("syslog facility %s is invalid", c.Facility)
+	}
+	if _, ok := parseSeverity(c.Severity); !ok {
+		return errors.Errorf("syslog severity %s is invalid", c.Severity)
+	}
+	for value, severity := range c.SeverityMapping {
+		if _, ok := parseSeverity(severity); !ok {
+			return errors.Errorf("syslog severity %s mapped from %s is invalid", severity, value)
+		}
+	}
+
+	for _, sd := range c.StructuredData {
+		if !validSDName(sd.ID) {
+			return errors.Errorf("structured data id %s is invalid", sd.ID)
+		}
+		for name, value := range sd.Params {
+			if !validSDName(name) {
+				return errors.Errorf("structured data param name %s of %s is invalid", name, sd.ID)
+			}
+			if err := pattern.Validate(value); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range []string{c.Hostname, c.AppName, c.ProcID, c.MsgID} {
+		if err := pattern.Validate(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syslog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	nilValue = "-"
+
+	maxHostnameLen = 255
+	maxAppNameLen  = 48
+	maxProcIDLen   = 128
+	maxMsgIDLen    = 32
+	maxSDNameLen   = 32
+
+	timestampLayout = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"ntp":      12,
+	"security": 13,
+	"console":  14,
+	"clock":    15,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// severities are the names of the syslog severities, with the common names of log levels
+var severities = map[string]int{
+	"emerg":     0,
+	"emergency": 0,
+	"panic":     0,
+	"alert":     1,
+	"crit":      2,
+	"critical":  2,
+	"fatal":     2,
+	"err":       3,
+	"error":     3,
+	"warn":      4,
+	"warning":   4,
+	"notice":    5,
+	"info":      6,
+	"debug":     7,
+	"trace":     7,
+}
+
+func parseFacility(v interface{}) (int, bool) {
+	return parseCode(v, facilities, 23)
+}
+
+func parseSeverity(v interface{}) (int, bool) {
+	return parseCode(v, severities, 7)
+}
+
+func parseCode(v interface{}, names map[string]int, max int) (int, bool) {
+	switch val := v.(type) {
+	case int:
+		return val, val >= 0 && val <= max
+	case int64:
+		return int(val), val >= 0 && val <= int64(max)
+	case float64:
+		return int(val), val >= 0 && val <= float64(max)
+	case string:
+		if c, ok := names[strings.ToLower(val)]; ok {
+			return c, true
+		}
+		c, err := strconv.Atoi(val)
+		return c, err == nil && c >= 0 && c <= max
+	}
+	return 0, false
+}
+
+func validSDName(name string) bool {
+	if name == "" || len(name) > maxSDNameLen {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' || c == ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// headerField converts the value to the PRINTUSASCII of the header fields, the nil value is used if empty
+func headerField(v string, maxLen int) string {
+	if v == "" {
+		return nilValue
+	}
+	b := []byte(v)
+	if len(b) > maxLen {
+		b = b[:maxLen]
+	}
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+type sdParam struct {
+	name  string
+	value string
+}
+
+type sdElement struct {
+	id     string
+	params []sdParam
+}
+
+// message is the RFC5424 message without framing
+type message struct {
+	priority       int
+	timestamp      time.Time
+	hostname       string
+	appName        string
+	procID         string
+	msgID          string
+	structuredData []sdElement
+	msg            string
+}
+
+func (m *message) encode() []byte {
+	var sb strings.Builder
+	sb.WriteByte('<')
+	sb.WriteString(strconv.Itoa(m.priority))
+	sb.WriteString(">1 ")
+	sb.WriteString(m.timestamp.Format(timestampLayout))
+	sb.WriteByte(' ')
+	sb.WriteString(headerField(m.hostname, maxHostnameLen))
+	sb.WriteByte(' ')
+	sb.WriteString(headerField(m.appName, maxAppNameLen))
+	sb.WriteByte(' ')
+	sb.WriteString(headerField(m.procID, maxProcIDLen))
+	sb.WriteByte(' ')
+	sb.WriteString(headerField(m.msgID, maxMsgIDLen))
+	sb.WriteByte(' ')
+
+	written := false
+	for _, sd := range m.structuredData {
+		if len(sd.params) == 0 {
+			continue
+		}
+		written = true
+		sb.WriteByte('[')
+		sb.WriteString(sd.id)
+		for _, p := range sd.params {
+			sb.WriteByte(' ')
+			sb.WriteString(p.name)
+			sb.WriteString(`="`)
+			sb.WriteString(escapeParamValue(p.value))
+			sb.WriteByte('"')
+		}
+		sb.WriteByte(']')
+	}
+	if !written {
+		sb.WriteString(nilValue)
+	}
+
+	if m.msg != "" {
+		sb.WriteByte(' ')
+		sb.WriteString(m.msg)
+	}
+	return []byte(sb.String())
+}
+
+var paramValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func escapeParamValue(v string) string {
+	return paramValueEscaper.Replace(v)
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syslog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	eventer "github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/global"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const Type = "syslog"
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink()
+}
+
+type sdParamPattern struct {
+	name    string
+	pattern *pattern.Pattern
+}
+
+type sdElementPattern struct {
+	id     string
+	params []sdParamPattern
+}
+
+type Sink struct {
+	name   string
+	config *Config
+
+	facility        int
+	severity        int
+	hostnamePattern *pattern.Pattern
+	appNamePattern  *pattern.Pattern
+	procIDPattern   *pattern.Pattern
+	msgIDPattern    *pattern.Pattern
+	sdPatterns      []sdElementPattern
+	node            string
+	tlsConfig       *tls.Config
+
+	lock sync.Mutex
+	conn net.Conn
+}
+
+func NewSink() *Sink {
+	return &Sink{
+		config: &Config{},
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.facility, _ = parseFacility(s.config.Facility)
+	s.severity, _ = parseSeverity(s.config.Severity)
+	s.hostnamePattern, _ = pattern.Init(s.config.Hostname)
+	s.appNamePattern, _ = pattern.Init(s.config.AppName)
+	s.procIDPattern, _ = pattern.Init(s.config.ProcID)
+	s.msgIDPattern, _ = pattern.Init(s.config.MsgID)
+
+	for _, sd := range s.config.StructuredData {
+		element := sdElementPattern{id: sd.ID}
+		for name, value := range sd.Params {
+			p, _ := pattern.Init(value)
+			element.params = append(element.params, sdParamPattern{name: name, pattern: p})
+		}
+		sort.Slice(element.params, func(i, j int) bool {
+			return element.params[i].name < element.params[j].name
+		})
+		s.sdPatterns = append(s.sdPatterns, element)
+	}
+
+	s.node = global.NodeName
+	if s.node == "" {
+		s.node, _ = os.Hostname()
+	}
+
+	t := s.config.TLS
+	if t.Enabled {
+		tlsConfig, err := netutils.NewTLSConfig(t.CaCertFiles, t.ClientCertFile, t.ClientKeyFile, t.InsecureSkipVerify)
+		if err != nil {
+			return err
+		}
+		tlsConfig.ServerName = t.ServerName
+		s.tlsConfig = tlsConfig
+	}
+	return nil
+}
+
+func (s *Sink) Start() error {
+	log.Info("%s start, address: %s://%s", s.String(), s.config.Protocol, s.config.Address)
+	return nil
+}
+
+func (s *Sink) Stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.close()
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return result.Fail(errors.WithMessagef(err, "connect to %s", s.config.Address))
+		}
+	}
+	if err := s.write(events); err != nil {
+		// reconnect by the next batch
+		s.close()
+		return result.Fail(errors.WithMessagef(err, "send syslog messages to %s", s.config.Address))
+	}
+	return result.Success()
+}
+
+func (s *Sink) connect() error {
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	var conn net.Conn
+	var err error
+	if s.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, ProtocolTCP, s.config.Address, s.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(s.config.Protocol, s.config.Address)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *Sink) close() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+func (s *Sink) write(events []api.Event) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.config.Timeout)); err != nil {
+		return err
+	}
+
+	if s.config.Protocol == ProtocolUDP {
+		for _, e := range events {
+			data := truncate(s.encode(e).encode(), s.config.MaxMessageBytes)
+			if _, err := s.conn.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := bufio.NewWriter(s.conn)
+	for _, e := range events {
+		m := s.encode(e)
+		if s.config.Framing == FramingNonTransparent {
+			m.msg = strings.ReplaceAll(m.msg, "\n", " ")
+			if _, err := w.Write(append(m.encode(), '\n')); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data := m.encode()
+		if _, err := w.WriteString(strconv.Itoa(len(data)) + " "); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (s *Sink) encode(e api.Event) *message {
+	obj := runtime.NewObject(e.Header())
+
+	facility := s.facility
+	if s.config.FacilityKey != "" {
+		if f, ok := parseFacility(obj.GetPath(s.config.FacilityKey).Value()); ok {
+			facility = f
+		}
+	}
+
+	m := &message{
+		priority:  facility*8 + s.severityOf(obj),
+		timestamp: timestamp(e),
+		hostname:  s.render(s.hostnamePattern, obj),
+		appName:   s.render(s.appNamePattern, obj),
+		procID:    s.render(s.procIDPattern, obj),
+		msgID:     s.render(s.msgIDPattern, obj),
+		msg:       strings.TrimRight(string(e.Body()), "\r\n"),
+	}
+	if m.hostname == "" {
+		m.hostname = s.node
+	}
+
+	for _, sd := range s.sdPatterns {
+		element := sdElement{id: sd.id}
+		for _, p := range sd.params {
+			// params rendered to empty are skipped
+			if v := s.render(p.pattern, obj); v != "" {
+				element.params = append(element.params, sdParam{name: p.name, value: v})
+			}
+		}
+		m.structuredData = append(m.structuredData, element)
+	}
+	return m
+}
+
+func (s *Sink) severityOf(obj *runtime.Object) int {
+	if s.config.SeverityKey == "" {
+		return s.severity
+	}
+	v := obj.GetPath(s.config.SeverityKey).Value()
+	if str, ok := v.(string); ok {
+		if mapped, ok := s.config.SeverityMapping[str]; ok {
+			v = mapped
+		}
+	}
+	if severity, ok := parseSeverity(v); ok {
+		return severity
+	}
+	return s.severity
+}
+
+func (s *Sink) render(p *pattern.Pattern, obj *runtime.Object) string {
+	v, err := p.WithObject(obj).Render()
+	if err != nil {
+		log.Debug("[%s] render pattern %s error: %v", s.name, p.Raw, err)
+		return ""
+	}
+	return v
+}
+
+// truncate cuts the data to the max bytes without breaking the last utf-8 character
+func truncate(data []byte, max int) []byte {
+	if len(data) <= max {
+		return data
+	}
+	end := max
+	for end > 0 && !utf8.RuneStart(data[end]) {
+		end--
+	}
+	return data[:end]
+}
+
+func timestamp(e api.Event) time.Time {
+	if e.Meta() != nil {
+		if v, ok := e.Meta().Get(eventer.SystemProductTimeKey); ok {
+			if t, ok := v.(time.Time); ok {
+				return t
+			}
+		}
+	}
+	return time.Now()
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syslog
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+var testTime = time.Date(2023, 11, 14, 22, 13, 20, 123000000, time.UTC)
+
+func newTestSink(t *testing.T, raw string) *Sink {
+	log.InitDefaultLogger()
+	s := NewSink()
+	assert.NoError(t, cfg.UnPackFromRaw([]byte("address: siem:514\n"+raw), s.config).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(context.NewContext("syslog", Type, api.SINK, nil)))
+	return s
+}
+
+func newTestEvent(body string, level string) api.Event {
+	e := event.NewEvent(map[string]interface{}{
+		"fields": map[string]interface{}{"namespace": "default", "app": "pay", "level": level},
+	}, []byte(body))
+	meta := event.NewDefaultMeta()
+	meta.Set(event.SystemProductTimeKey, testTime)
+	e.Fill(meta, e.Header(), e.Body())
+	return e
+}
+
+func TestMessageEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		m    *message
+		want string
+	}{
+		{
+			name: "structured data escaped",
+			m: &message{
+				priority:  134,
+				timestamp: testTime,
+				hostname:  "node 1",
+				appName:   "pay",
+				structuredData: []sdElement{
+					{id: "k8s@32473", params: []sdParam{{name: "ns", value: `a"b]c\d`}}},
+					{id: "empty@32473"},
+				},
+				msg: "hello",
+			},
+			want: `<134>1 2023-11-14T22:13:20.123000Z node_1 pay - - [k8s@32473 ns="a\"b\]c\\d"] hello`,
+		},
+		{
+			name: "nil values",
+			m:    &message{priority: 134, timestamp: testTime, hostname: "node1", appName: "pay"},
+			want: `<134>1 2023-11-14T22:13:20.123000Z node1 pay - - -`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(tt.m.encode()))
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	raw := `
+facility: local3
+hostname: node1
+appName: ${fields.app}
+severityKey: fields.level
+severityMapping:
+  WARN: warning
+structuredData:
+  - id: loggie@32473
+    params:
+      namespace: ${fields.namespace}
+      missing: ${fields.missing}
+`
+	tests := []struct {
+		name  string
+		level string
+		body  string
+		want  string
+	}{
+		{
+			// local3 is 19 and warning is 4
+			name:  "mapped severity",
+			level: "WARN",
+			body:  "line one\nline two\n",
+			want:  "<156>1 2023-11-14T22:13:20.123000Z node1 pay - - [loggie@32473 namespace=\"default\"] line one\nline two",
+		},
+		{
+			name:  "default severity",
+			level: "unknown",
+			body:  "done",
+			want:  `<158>1 2023-11-14T22:13:20.123000Z node1 pay - - [loggie@32473 namespace="default"] done`,
+		},
+		{
+			name:  "severity name",
+			level: "err",
+			body:  "done",
+			want:  `<155>1 2023-11-14T22:13:20.123000Z node1 pay - - [loggie@32473 namespace="default"] done`,
+		},
+	}
+	s := newTestSink(t, raw)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(s.encode(newTestEvent(tt.body, tt.level)).encode()))
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			name: "octet counting",
+			raw:  "hostname: node1",
+			want: "57 <134>1 2023-11-14T22:13:20.123000Z node1 loggie - - - a\nb",
+		},
+		{
+			name: "non transparent",
+			raw:  "hostname: node1\nframing: non-transparent",
+			want: "<134>1 2023-11-14T22:13:20.123000Z node1 loggie - - - a b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSink(t, tt.raw)
+			client, server := net.Pipe()
+			s.conn = client
+
+			received := make(chan string, 1)
+			go func() {
+				data, _ := io.ReadAll(server)
+				received <- string(data)
+			}()
+			assert.NoError(t, s.write([]api.Event{newTestEvent("a\nb", "")}))
+			client.Close()
+			assert.Equal(t, tt.want, <-received)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, raw := range []string{
+		"facility: local9",
+		"severity: loud",
+		"severityMapping: {W: loud}",
+		"protocol: udp\ntls: {enabled: true}",
+		"structuredData: [{id: \"a b\"}]",
+		"structuredData: [{id: a, params: {\"x=y\": v}}]",
+	} {
+		c := &Config{}
+		assert.Error(t, cfg.UnPackFromRaw([]byte("address: siem:514\n"+raw), c).Defaults().Validate().Do(), raw)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		data string
+		max  int
+		want string
+	}{
+		{data: "ab", max: 3, want: "ab"},
+		{data: "abcd", max: 3, want: "abc"},
+		{data: "a中", max: 3, want: "a"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, string(truncate([]byte(tt.data), tt.max)))
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tdengine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
+
+const (
+	TypeBool    = "bool"
+	TypeInt     = "int"
+	TypeBigint  = "bigint"
+	TypeFloat   = "float"
+	TypeDouble  = "double"
+	TypeBinary  = "binary"
+	TypeVarchar = "varchar"
+	TypeNchar   = "nchar"
+
+	PrecisionMs = "ms"
+	PrecisionUs = "us"
+	PrecisionNs = "ns"
+)
+
+type Config struct {
+	URL      string `yaml:"url,omitempty" validate:"required"` // the REST address of taosAdapter, such as http://127.0.0.1:6041
+	Username string `yaml:"username,omitempty" default:"root"`
+	Password string `yaml:"password,omitempty" default:"taosdata"`
+	Database string `yaml:"database,omitempty" validate:"required"`
+	// Precision of the database, the timestamps are sent as integers in the precision
+	Precision string `yaml:"precision,omitempty" default:"ms" validate:"oneof=ms us ns"`
+	STable    string `yaml:"stable,omitempty" validate:"required"`
+	// Table is the name of the child table, such as ${fields.device}, the child tables are created by the super table automatically
+	Table string `yaml:"table,omitempty" validate:"required"`
+	// TimestampKey is the key of the event time, the time when the event was produced is used if empty
+	TimestampKey    string  `yaml:"timestampKey,omitempty"`
+	TimestampColumn string  `yaml:"timestampColumn,omitempty" default:"ts"`
+	Tags            []Field `yaml:"tags,omitempty" validate:"required,dive"`
+	Columns         []Field `yaml:"columns,omitempty" validate:"required,dive"`
+	// AutoCreate creates the database and the super table if not exist before the first insert
+	AutoCreate  *bool            `yaml:"autoCreate,omitempty" default:"true"`
+	MaxSQLBytes int              `yaml:"maxSQLBytes,omitempty" default:"1000000" validate:"gt=0"` // the insert statement is split by the maxSQLLength of TDengine
+	Timeout     time.Duration    `yaml:"timeout,omitempty" default:"30s"`
+	HostLimit   hostlimit.Config `yaml:"hostLimit,omitempty"`
+}
+
+// Field maps a key of the event to a column or tag
+type Field struct {
+	Name   string `yaml:"name,omitempty" validate:"required"`
+	Type   string `yaml:"type,omitempty" validate:"required"`
+	Length int    `yaml:"length,omitempty" default:"256"` // length of binary, varchar and nchar
+	Key    string `yaml:"key,omitempty"`                  // such as fields.device, the same as name if empty, body means the event body
+}
+
+func (f *Field) SetDefaults() {
+	f.Type = strings.ToLower(f.Type)
+	if f.Key == "" {
+		f.Key = f.Name
+	}
+}
+
+func (f *Field) Validate() error {
+	switch f.Type {
+	case TypeBool, TypeInt, TypeBigint, TypeFloat, TypeDouble, TypeBinary, TypeVarchar, TypeNchar:
+		return nil
+	}
+	return fmt.Errorf("tdengine sink field %s with unsupported type %s", f.Name, f.Type)
+}
+
+func (f *Field) ddl() string {
+	switch f.Type {
+	case TypeBinary, TypeVarchar, TypeNchar:
+		return fmt.Sprintf("%s %s(%d)", quote(f.Name), strings.ToUpper(f.Type), f.Length)
+	}
+	return fmt.Sprintf("%s %s", quote(f.Name), strings.ToUpper(f.Type))
+}
+
+func (c *Config) Validate() error {
+	if err := pattern.Validate(c.Table); err != nil {
+		return err
+	}
+	names := make(map[string]struct{})
+	names[c.TimestampColumn] = struct{}{}
+	for _, f := range append(append([]Field{}, c.Tags...), c.Columns...) {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[f.Name]; ok {
+			return fmt.Errorf("tdengine sink column or tag %s is duplicated", f.Name)
+		}
+		names[f.Name] = struct{}{}
+	}
+	return nil
+}
+
+func (c *Config) autoCreate() bool {
+	return c.AutoCreate == nil || *c.AutoCreate
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tdengine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
+)
+
+const (
+	Type = "tdengine"
+
+	sqlPath      = "/rest/sql"
+	insertPrefix = "INSERT INTO "
+)
+
+func init() {
+	pipeline.Register(api.SINK, Type, makeSink)
+}
+
+func makeSink(info pipeline.Info) api.Component {
+	return NewSink(info.PipelineName)
+}
+
+type Sink struct {
+	pipelineName string
+	name         string
+	config       *Config
+	client       *http.Client
+	limiter      *hostlimit.Transport
+
+	tablePattern *pattern.Pattern
+	schemaLock   sync.Mutex
+	schemaReady  bool
+}
+
+func NewSink(pipelineName string) *Sink {
+	return &Sink{
+		pipelineName: pipelineName,
+		config:       &Config{},
+	}
+}
+
+func (s *Sink) Category() api.Category {
+	return api.SINK
+}
+
+func (s *Sink) Type() api.Type {
+	return Type
+}
+
+func (s *Sink) Config() interface{} {
+	return s.config
+}
+
+func (s *Sink) String() string {
+	return fmt.Sprintf("%s/%s", api.SINK, Type)
+}
+
+func (s *Sink) Init(context api.Context) error {
+	s.name = context.Name()
+	s.tablePattern, _ = pattern.Init(s.config.Table)
+	s.client = &http.Client{
+		Timeout: s.config.Timeout,
+	}
+	s.schemaReady = !s.config.autoCreate()
+	return nil
+}
+
+func (s *Sink) Start() error {
+	if s.config.HostLimit.Enabled() {
+		s.limiter = hostlimit.NewTransport(s.client.Transport, &s.config.HostLimit, s.pipelineName, s.name)
+		s.client.Transport = s.limiter
+	}
+	log.Info("%s start, url: %s, database: %s", s.String(), s.config.URL, s.config.Database)
+	return nil
+}
+
+func (s *Sink) Stop() {
+	if s.limiter != nil {
+		s.limiter.Stop()
+	}
+}
+
+func (s *Sink) Consume(batch api.Batch) api.Result {
+	events := batch.Events()
+	if len(events) == 0 {
+		return result.Success()
+	}
+
+	ctx := context.Background()
+	if err := s.ensureSchema(ctx); err != nil {
+		return result.Fail(errors.WithMessage(err, "create tdengine schema"))
+	}
+
+	statements := s.buildInserts(events)
+	if len(statements) == 0 {
+		return result.DropWith(errors.New("no valid event to insert into tdengine"))
+	}
+	for _, stmt := range statements {
+		if err := s.exec(ctx, stmt); err != nil {
+			return result.Fail(errors.WithMessage(err, "insert into tdengine"))
+		}
+	}
+	return result.Success()
+}
+
+// ensureSchema creates the schema lazily, so the sink could start when TDengine is not available
+func (s *Sink) ensureSchema(ctx context.Context) error {
+	s.schemaLock.Lock()
+	defer s.schemaLock.Unlock()
+	if s.schemaReady {
+		return nil
+	}
+	if err := s.exec(ctx, s.config.createDatabaseSQL()); err != nil {
+		return err
+	}
+	if err := s.exec(ctx, s.config.createSTableSQL()); err != nil {
+		return err
+	}
+	s.schemaReady = true
+	return nil
+}
+
+// buildInserts joins the rows into insert statements no longer than maxSQLBytes,
+// the invalid events are skipped
+func (s *Sink) buildInserts(events []api.Event) []string {
+	var statements []string
+	var b strings.Builder
+	for _, e := range events {
+		row, err := s.row(e)
+		if err != nil {
+			log.Warn("convert event to tdengine row error: %v, event: %s", err, e.String())
+			continue
+		}
+		if b.Len() > 0 && b.Len()+len(row)+1 > s.config.MaxSQLBytes {
+			statements = append(statements, b.String())
+			b.Reset()
+		}
+		if b.Len() == 0 {
+			b.WriteString(insertPrefix)
+		} else {
+			b.WriteString(" ")
+		}
+		b.WriteString(row)
+	}
+	if b.Len() > 0 {
+		statements = append(statements, b.String())
+	}
+	return statements
+}
+
+type response struct {
+	Status string `json:"status"` // only returned by TDengine 2.x
+	Code   int    `json:"code"`
+	Desc   string `json:"desc"`
+}
+
+func (s *Sink) exec(ctx context.Context, sql string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.config.URL, "/")+sqlPath, strings.NewReader(sql))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.config.Username, s.config.Password)
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	out := &response{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return errors.Errorf("tdengine returned status %d: %s", resp.StatusCode, string(body))
+	}
+	if resp.StatusCode/100 != 2 || out.Code != 0 || out.Status == "error" {
+		return errors.Errorf("tdengine returned status %d, code %d: %s", resp.StatusCode, out.Code, out.Desc)
+	}
+	return nil
+}
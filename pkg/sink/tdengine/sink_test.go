@@ -0,0 +1,214 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tdengine
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const testConfig = `
+url: http://127.0.0.1:6041
+database: loggie
+stable: device_logs
+table: "d_${fields.device}"
+timestampKey: time
+tags:
+  - name: device
+    type: NCHAR
+    length: 32
+    key: fields.device
+columns:
+  - name: message
+    type: nchar
+    length: 16
+    key: body
+  - name: temperature
+    type: double
+  - name: online
+    type: bool
+`
+
+func newTestSink(t *testing.T, extra string) *Sink {
+	log.InitDefaultLogger()
+	s := NewSink("test")
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(testConfig+extra), s.config).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(context.NewContext("tdengine", Type, api.SINK, nil)))
+	return s
+}
+
+func newTestEvent(device string, body string) api.Event {
+	return event.NewEvent(map[string]interface{}{
+		"time":        "2023-07-22T04:26:40Z",
+		"fields":      map[string]interface{}{"device": device},
+		"temperature": 36.5,
+		"online":      "true",
+	}, []byte(body))
+}
+
+func TestCreateSQL(t *testing.T) {
+	s := newTestSink(t, "")
+	assert.Equal(t, "CREATE DATABASE IF NOT EXISTS `loggie` PRECISION 'ms'", s.config.createDatabaseSQL())
+	assert.Equal(t, "CREATE STABLE IF NOT EXISTS `loggie`.`device_logs` (`ts` TIMESTAMP, `message` NCHAR(16), `temperature` DOUBLE, `online` BOOL) TAGS (`device` NCHAR(32))", s.config.createSTableSQL())
+}
+
+func TestRow(t *testing.T) {
+	tests := []struct {
+		name    string
+		e       api.Event
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "escaped and truncated",
+			e:    newTestEvent("d1", "it's a very long message"),
+			want: "`loggie`.`d_d1` USING `loggie`.`device_logs` TAGS ('d1') VALUES (1690000000000, 'it\\'s a very long', 36.5, true)",
+		},
+		{
+			name:    "invalid timestamp",
+			e:       event.NewEvent(map[string]interface{}{"time": "now", "fields": map[string]interface{}{"device": "d1"}}, []byte("bad")),
+			wantErr: true,
+		},
+		{
+			name:    "table not rendered",
+			e:       event.NewEvent(map[string]interface{}{"time": "2023-07-22T04:26:40Z"}, []byte("bad")),
+			wantErr: true,
+		},
+	}
+	s := newTestSink(t, "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row, err := s.row(tt.e)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, row)
+		})
+	}
+}
+
+func TestBuildInserts(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxSQLBytes    string
+		wantStatements int
+	}{
+		{name: "one statement", maxSQLBytes: "1000000", wantStatements: 1},
+		{name: "split by max sql bytes", maxSQLBytes: "200", wantStatements: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSink(t, "maxSQLBytes: "+tt.maxSQLBytes)
+			var events []api.Event
+			for i := 0; i < 5; i++ {
+				events = append(events, newTestEvent("d1", "message"))
+			}
+			// the invalid event is skipped
+			events = append(events, event.NewEvent(map[string]interface{}{"time": "now"}, []byte("bad")))
+
+			statements := s.buildInserts(events)
+			assert.Len(t, statements, tt.wantStatements)
+			for _, stmt := range statements {
+				assert.True(t, strings.HasPrefix(stmt, insertPrefix))
+				assert.LessOrEqual(t, len(stmt), s.config.MaxSQLBytes)
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		field   Field
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{field: Field{Type: TypeBool}, value: "true", want: "true"},
+		{field: Field{Type: TypeBool}, value: "yes", wantErr: true},
+		{field: Field{Type: TypeBigint}, value: float64(42), want: "42"},
+		{field: Field{Type: TypeInt}, value: "x", wantErr: true},
+		{field: Field{Type: TypeDouble}, value: "36.5", want: "36.5"},
+		{field: Field{Type: TypeNchar, Length: 2}, value: "中文字", want: "'中文'"},
+		{field: Field{Type: TypeBinary, Length: 4}, value: "a中文", want: "'a中'"},
+		{field: Field{Type: TypeVarchar, Length: 8}, value: `a\b`, want: `'a\\b'`},
+		{field: Field{Type: TypeVarchar}, value: nil, want: null},
+	}
+	for _, tt := range tests {
+		got, err := formatValue(&tt.field, tt.value)
+		if tt.wantErr {
+			assert.Error(t, err, tt.value)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	tests := []struct {
+		name         string
+		precision    string
+		timestampKey string
+		header       map[string]interface{}
+		want         int64
+	}{
+		{name: "produce time in us", precision: PrecisionUs, want: 1690000000000000},
+		{name: "produce time in ns", precision: PrecisionNs, want: 1690000000000000000},
+		{name: "time string", precision: PrecisionMs, timestampKey: "time", header: map[string]interface{}{"time": "2023-07-22T04:26:40Z"}, want: 1690000000000},
+		{name: "number in precision", precision: PrecisionUs, timestampKey: "time", header: map[string]interface{}{"time": float64(1690000000000001)}, want: 1690000000000001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Sink{config: &Config{Precision: tt.precision, TimestampKey: tt.timestampKey}}
+			e := event.NewEvent(tt.header, []byte("a"))
+			meta := event.NewDefaultMeta()
+			meta.Set(event.SystemProductTimeKey, time.Unix(1690000000, 0))
+			e.Fill(meta, e.Header(), e.Body())
+
+			ts, err := s.timestamp(e, runtime.NewObject(e.Header()))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, ts)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "duplicated name", raw: testConfig + "  - name: device\n    type: int\n"},
+		{name: "unsupported type", raw: strings.Replace(testConfig, "type: double", "type: decimal", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, cfg.UnPackFromRaw([]byte(tt.raw), &Config{}).Defaults().Validate().Do())
+		})
+	}
+}
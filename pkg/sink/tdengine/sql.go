@@ -0,0 +1,234 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tdengine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	eventer "github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/util/runtime"
+)
+
+const null = "NULL"
+
+// quote escapes the identifier, so the names could contain any characters except backquote
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "") + "`"
+}
+
+func quoteString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+func (c *Config) createDatabaseSQL() string {
+	return fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s PRECISION '%s'", quote(c.Database), c.Precision)
+}
+
+func (c *Config) createSTableSQL() string {
+	columns := []string{fmt.Sprintf("%s TIMESTAMP", quote(c.TimestampColumn))}
+	for i := range c.Columns {
+		columns = append(columns, c.Columns[i].ddl())
+	}
+	tags := make([]string, 0, len(c.Tags))
+	for i := range c.Tags {
+		tags = append(tags, c.Tags[i].ddl())
+	}
+	return fmt.Sprintf("CREATE STABLE IF NOT EXISTS %s.%s (%s) TAGS (%s)", quote(c.Database), quote(c.STable),
+		strings.Join(columns, ", "), strings.Join(tags, ", "))
+}
+
+// row renders the insert clause of a event, such as `db`.`d1` USING `db`.`meters` TAGS ('d1') VALUES (1690000000000, 'msg')
+func (s *Sink) row(e api.Event) (string, error) {
+	obj := runtime.NewObject(e.Header())
+	table, err := s.tablePattern.WithObject(obj).RenderWithStrict()
+	if err != nil {
+		return "", errors.WithMessage(err, "render table")
+	}
+
+	ts, err := s.timestamp(e, obj)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(quote(s.config.Database))
+	b.WriteString(".")
+	b.WriteString(quote(table))
+	b.WriteString(" USING ")
+	b.WriteString(quote(s.config.Database))
+	b.WriteString(".")
+	b.WriteString(quote(s.config.STable))
+	b.WriteString(" TAGS (")
+	for i := range s.config.Tags {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		v, err := formatValue(&s.config.Tags[i], fieldValue(e, obj, s.config.Tags[i].Key))
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+	}
+	b.WriteString(") VALUES (")
+	b.WriteString(strconv.FormatInt(ts, 10))
+	for i := range s.config.Columns {
+		b.WriteString(", ")
+		v, err := formatValue(&s.config.Columns[i], fieldValue(e, obj, s.config.Columns[i].Key))
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+	}
+	b.WriteString(")")
+	return b.String(), nil
+}
+
+func fieldValue(e api.Event, obj *runtime.Object, key string) interface{} {
+	if key == codec.BodyKey {
+		return string(e.Body())
+	}
+	return obj.GetPath(key).Value()
+}
+
+// timestamp returns the event time as an integer in the precision of the database
+func (s *Sink) timestamp(e api.Event, obj *runtime.Object) (int64, error) {
+	var t time.Time
+	if s.config.TimestampKey == "" {
+		if e.Meta() != nil {
+			if v, ok := e.Meta().Get(eventer.SystemProductTimeKey); ok {
+				t, _ = v.(time.Time)
+			}
+		}
+	} else {
+		switch v := obj.GetPath(s.config.TimestampKey).Value().(type) {
+		case time.Time:
+			t = v
+		case string:
+			parsed, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return 0, errors.WithMessagef(err, "parse timestamp %s", v)
+			}
+			t = parsed
+		case float64:
+			// numbers are regarded as the timestamps in the precision already
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		case nil:
+			return 0, errors.Errorf("timestamp %s not found", s.config.TimestampKey)
+		default:
+			return 0, errors.Errorf("timestamp %s with unsupported type %T", s.config.TimestampKey, v)
+		}
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	switch s.config.Precision {
+	case PrecisionUs:
+		return t.UnixNano() / int64(time.Microsecond), nil
+	case PrecisionNs:
+		return t.UnixNano(), nil
+	default:
+		return t.UnixNano() / int64(time.Millisecond), nil
+	}
+}
+
+func formatValue(f *Field, value interface{}) (string, error) {
+	if value == nil {
+		return null, nil
+	}
+
+	switch f.Type {
+	case TypeBool:
+		switch v := value.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return "", errors.Errorf("%s: %s is not a bool", f.Name, v)
+			}
+			return strconv.FormatBool(b), nil
+		}
+
+	case TypeInt, TypeBigint:
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatInt(int64(v), 10), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case int:
+			return strconv.Itoa(v), nil
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return "", errors.Errorf("%s: %s is not an integer", f.Name, v)
+			}
+			return strconv.FormatInt(i, 10), nil
+		}
+
+	case TypeFloat, TypeDouble:
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case int:
+			return strconv.Itoa(v), nil
+		case string:
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return "", errors.Errorf("%s: %s is not a number", f.Name, v)
+			}
+			return strconv.FormatFloat(n, 'g', -1, 64), nil
+		}
+
+	default:
+		str, ok := value.(string)
+		if !ok {
+			str = fmt.Sprintf("%v", value)
+		}
+		// truncate by characters for nchar, and bytes for binary and varchar
+		if f.Type == TypeNchar {
+			if r := []rune(str); len(r) > f.Length {
+				str = string(r[:f.Length])
+			}
+		} else if len(str) > f.Length {
+			end := f.Length
+			for end > 0 && !utf8.RuneStart(str[end]) {
+				end--
+			}
+			str = str[:end]
+		}
+		return quoteString(str), nil
+	}
+
+	return "", errors.Errorf("%s: %v with type %T cannot be converted to %s", f.Name, value, value, f.Type)
+}
@@ -16,10 +16,13 @@ limitations under the License.
 
 package zinc
 
+import "github.com/loggie-io/loggie/pkg/sink/hostlimit"
+
 type Config struct {
-	Index         string `yaml:"index,omitempty" default:"default" validate:"required"`
-	Host          string `yaml:"host,omitempty" default:"http://127.0.0.1:4080" validate:"required"`
-	Username      string `yaml:"username,omitempty" default:"admin" validate:"required"`
-	Password      string `yaml:"password,omitempty" default:"" validate:"required"`
-	SkipSSLVerify bool   `yaml:"skipSSLVerify,omitempty" default:"true"`
+	Index         string           `yaml:"index,omitempty" default:"default" validate:"required"`
+	Host          string           `yaml:"host,omitempty" default:"http://127.0.0.1:4080" validate:"required"`
+	Username      string           `yaml:"username,omitempty" default:"admin" validate:"required"`
+	Password      string           `yaml:"password,omitempty" default:"" validate:"required"`
+	SkipSSLVerify bool             `yaml:"skipSSLVerify,omitempty" default:"true"`
+	HostLimit     hostlimit.Config `yaml:"hostLimit,omitempty"`
 }
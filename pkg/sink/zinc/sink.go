@@ -24,6 +24,7 @@ import (
 	"github.com/loggie-io/loggie/pkg/core/result"
 	"github.com/loggie-io/loggie/pkg/pipeline"
 	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/hostlimit"
 	"net/http"
 	"strings"
 )
@@ -35,20 +36,23 @@ func init() {
 }
 
 func makeSink(info pipeline.Info) api.Component {
-	return NewSink()
+	return NewSink(info.PipelineName)
 }
 
 type Sink struct {
-	name    string
-	config  *Config
-	codec   codec.Codec
-	client  *http.Client
-	pushUrl string
+	pipelineName string
+	name         string
+	config       *Config
+	codec        codec.Codec
+	client       *http.Client
+	limiter      *hostlimit.Transport
+	pushUrl      string
 }
 
-func NewSink() *Sink {
+func NewSink(pipelineName string) *Sink {
 	return &Sink{
-		config: &Config{},
+		pipelineName: pipelineName,
+		config:       &Config{},
 	}
 }
 
@@ -87,11 +91,18 @@ func (s *Sink) Init(context api.Context) error {
 }
 
 func (s *Sink) Start() error {
+	if s.config.HostLimit.Enabled() {
+		s.limiter = hostlimit.NewTransport(s.client.Transport, &s.config.HostLimit, s.pipelineName, s.name)
+		s.client.Transport = s.limiter
+	}
 	log.Info("%s start", s.String())
 	return nil
 }
 
 func (s *Sink) Stop() {
+	if s.limiter != nil {
+		s.limiter.Stop()
+	}
 }
 
 func (s *Sink) Consume(batch api.Batch) api.Result {
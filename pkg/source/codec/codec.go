@@ -18,6 +18,7 @@ package codec
 
 import (
 	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/core/result"
 )
@@ -55,6 +56,7 @@ func Get(name string) (Codec, bool) {
 var ProductFunc = func(productFunc api.ProductFunc, codec Codec) api.ProductFunc {
 	return func(event api.Event) api.Result {
 		if codec != nil {
+			preserveRawBody(event)
 			if _, err := codec.Decode(event); err != nil {
 				log.Error("source codec decode failed: %v", err)
 				// return fail would retry, ignore error here
@@ -65,3 +67,16 @@ var ProductFunc = func(productFunc api.ProductFunc, codec Codec) api.ProductFunc
 		return result.Success()
 	}
 }
+
+// preserveRawBody keeps the body before decoding, so that it could still be forwarded as it was received
+func preserveRawBody(e api.Event) {
+	meta := e.Meta()
+	if meta == nil {
+		meta = event.NewDefaultMeta()
+		e.Fill(meta, e.Header(), e.Body())
+	}
+	if _, ok := meta.Get(event.RawBodyKey); ok {
+		return
+	}
+	meta.Set(event.RawBodyKey, e.Body())
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/loggie-io/loggie/pkg/core/api"
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
 	"github.com/loggie-io/loggie/pkg/pipeline"
 	"github.com/loggie-io/loggie/pkg/util/persistence"
 )
@@ -30,6 +31,9 @@ import (
 const (
 	largeAckSize = 10 * 4096
 
+	defaultCheckpointInterval = 10 * time.Second
+	checkpointTickInterval    = time.Second
+
 	AckStart = AckTaskType("start")
 	AckStop  = AckTaskType("stop")
 )
@@ -48,10 +52,12 @@ type AckTask struct {
 	SourceName      string
 	key             string
 	StopCountDown   *sync.WaitGroup
+	checkpoint      CheckpointConfig
 	persistenceFunc persistenceFunc
+	commitFunc      commitFunc
 }
 
-func NewAckTask(epoch *pipeline.Epoch, pipelineName string, sourceName string, persistenceFunc persistenceFunc) *AckTask {
+func NewAckTask(epoch *pipeline.Epoch, pipelineName string, sourceName string, checkpoint CheckpointConfig, persistenceFunc persistenceFunc, commitFunc commitFunc) *AckTask {
 	l := log.SubLogger(subLogger+"/ack").Sample(1, 1*time.Second)
 	return &AckTask{
 		sampleLogger:    l,
@@ -60,7 +66,9 @@ func NewAckTask(epoch *pipeline.Epoch, pipelineName string, sourceName string, p
 		SourceName:      sourceName,
 		key:             fmt.Sprintf("%s:%s", pipelineName, sourceName),
 		StopCountDown:   &sync.WaitGroup{},
+		checkpoint:      checkpoint,
 		persistenceFunc: persistenceFunc,
+		commitFunc:      commitFunc,
 	}
 }
 
@@ -77,11 +85,17 @@ func (at *AckTask) isContain(s *persistence.State) bool {
 }
 
 func (at *AckTask) NewAckChain(jobWatchUid string) *JobAckChain {
-	return newJobAckChain(at.Epoch, at.PipelineName, at.SourceName, jobWatchUid, at.persistenceFunc)
+	chain := newJobAckChain(at.Epoch, at.PipelineName, at.SourceName, jobWatchUid, at.persistenceFunc)
+	chain.taskKey = at.key
+	chain.checkpoint = at.checkpoint
+	return chain
 }
 
 type persistenceFunc func(state *persistence.State)
 
+// commitFunc writes the states to the registry in one batch
+type commitFunc func(states []*persistence.State)
+
 type ack struct {
 	next  *ack
 	first bool
@@ -143,6 +157,12 @@ type JobAckChain struct {
 	Start           time.Time
 	tail            *ack
 	allAck          map[string]*ack
+
+	taskKey          string
+	checkpoint       CheckpointConfig
+	pending          *persistence.State // the latest acked state which has not been checkpointed
+	pendingSince     time.Time
+	checkpointOffset int64
 }
 
 func newJobAckChain(epoch *pipeline.Epoch, pipelineName string, sourceName string, jobWatchUid string, persistenceFunc persistenceFunc) *JobAckChain {
@@ -242,11 +262,57 @@ func (ac *JobAckChain) ackLoop(a *ack) {
 		ReleaseAck(prev)
 	}
 	// persistence ack
-	ac.persistenceFunc(prevState)
+	ac.acked(prevState)
+}
+
+func (ac *JobAckChain) acked(s *persistence.State) {
+	if ac.checkpoint.everyBatch() {
+		ac.persistenceFunc(s)
+		return
+	}
+	if ac.pending == nil {
+		ac.pendingSince = time.Now()
+	}
+	ac.pending = s
+	if ac.checkpoint.Bytes > 0 && s.NextOffset-ac.checkpointOffset >= ac.checkpoint.Bytes {
+		ac.persistenceFunc(ac.takePending())
+	}
+}
+
+// takePending returns the state waiting for the checkpoint and marks it checkpointed
+func (ac *JobAckChain) takePending() *persistence.State {
+	s := ac.pending
+	if s == nil {
+		return nil
+	}
+	ac.pending = nil
+	ac.checkpointOffset = s.NextOffset
+	return s
+}
+
+// dueCheckpoint returns the pending state when it has waited for the checkpoint interval
+func (ac *JobAckChain) dueCheckpoint(now time.Time) *persistence.State {
+	if ac.pending == nil || now.Sub(ac.pendingSince) < ac.checkpoint.interval() {
+		return nil
+	}
+	return ac.takePending()
+}
+
+// lag returns the acked bytes and the time which have not been checkpointed
+func (ac *JobAckChain) lag(now time.Time) (int64, time.Duration) {
+	if ac.pending == nil {
+		return 0, 0
+	}
+	bytes := ac.pending.NextOffset - ac.checkpointOffset
+	if bytes < 0 {
+		// the file has been truncated
+		bytes = 0
+	}
+	return bytes, now.Sub(ac.pendingSince)
 }
 
 func (ac *JobAckChain) isEmpty() bool {
-	return ac.tail == nil
+	return ac.tail == nil && ac.pending == nil
 }
 
 type AckChainHandler struct {
@@ -302,8 +368,10 @@ func (ach *AckChainHandler) run() {
 	ach.countDown.Add(1)
 	log.Info("ack chain handler start")
 	maintenanceTicker := time.NewTicker(ach.ackConfig.MaintenanceInterval)
+	checkpointTicker := time.NewTicker(checkpointTickInterval)
 	defer func() {
 		maintenanceTicker.Stop()
+		checkpointTicker.Stop()
 		ach.countDown.Done()
 		log.Info("ack chain handler stop")
 	}()
@@ -323,13 +391,27 @@ func (ach *AckChainHandler) run() {
 				}
 			} else if taskType == AckStop {
 				delete(ach.ackTasks, ackTask.Key())
-				// stop all ack jobs
+				// stop all ack jobs, the acked offsets waiting for the checkpoint are committed before
+				var pending []*persistence.State
 				for _, chain := range ach.jobAckChains {
 					if ackTask.isParentOf(chain) {
+						if s := chain.takePending(); s != nil {
+							pending = append(pending, s)
+						}
 						delete(ach.jobAckChains, chain.Key())
 						chain.Release()
 					}
 				}
+				if len(pending) > 0 {
+					ackTask.commitFunc(pending)
+				}
+				eventbus.PublishOrDrop(eventbus.FileCheckpointTopic, eventbus.FileCheckpointMetricData{
+					BaseMetric: eventbus.BaseMetric{
+						PipelineName: ackTask.PipelineName,
+						SourceName:   ackTask.SourceName,
+					},
+					Stopped: true,
+				})
 				ackTask.StopCountDown.Done()
 			}
 		case ss := <-ach.appendChan:
@@ -363,6 +445,8 @@ func (ach *AckChainHandler) run() {
 					log.Debug("ack state of source has stopped: %+v", s)
 				}
 			}
+		case <-checkpointTicker.C:
+			ach.checkpoint(time.Now())
 		case <-maintenanceTicker.C:
 			// Delete empty chain
 			for _, chain := range ach.jobAckChains {
@@ -375,3 +459,46 @@ func (ach *AckChainHandler) run() {
 		}
 	}
 }
+
+// checkpoint commits the acked offsets which reached the checkpoint interval in one batch per source,
+// and exports the checkpoint lag of the sources which do not checkpoint every batch
+func (ach *AckChainHandler) checkpoint(now time.Time) {
+	due := make(map[string][]*persistence.State)
+	lags := make(map[string]*eventbus.FileCheckpointMetricData, len(ach.ackTasks))
+	for key, task := range ach.ackTasks {
+		if task.checkpoint.everyBatch() {
+			continue
+		}
+		lags[key] = &eventbus.FileCheckpointMetricData{
+			BaseMetric: eventbus.BaseMetric{
+				PipelineName: task.PipelineName,
+				SourceName:   task.SourceName,
+			},
+		}
+	}
+
+	for _, chain := range ach.jobAckChains {
+		if s := chain.dueCheckpoint(now); s != nil {
+			due[chain.taskKey] = append(due[chain.taskKey], s)
+		}
+		bytes, age := chain.lag(now)
+		l, ok := lags[chain.taskKey]
+		if !ok || chain.pending == nil {
+			continue
+		}
+		l.Files++
+		l.LagBytes += bytes
+		if age > l.LagTime {
+			l.LagTime = age
+		}
+	}
+
+	for key, ss := range due {
+		if task, ok := ach.ackTasks[key]; ok {
+			task.commitFunc(ss)
+		}
+	}
+	for _, l := range lags {
+		eventbus.PublishOrDrop(eventbus.FileCheckpointTopic, *l)
+	}
+}
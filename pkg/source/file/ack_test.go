@@ -0,0 +1,121 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/persistence"
+)
+
+type recorder struct {
+	persisted []*persistence.State
+	committed [][]*persistence.State
+}
+
+func (r *recorder) persist(s *persistence.State) {
+	r.persisted = append(r.persisted, s)
+}
+
+func (r *recorder) commit(ss []*persistence.State) {
+	r.committed = append(r.committed, ss)
+}
+
+func newTestAckTask(checkpoint CheckpointConfig, r *recorder) *AckTask {
+	log.InitDefaultLogger()
+	return NewAckTask(pipeline.NewEpoch("p"), "p", "s", checkpoint, r.persist, r.commit)
+}
+
+func ackLines(chain *JobAckChain, from int, to int) {
+	var states []*persistence.State
+	for i := from; i < to; i++ {
+		s := &persistence.State{
+			Epoch:      chain.Epoch,
+			SourceName: chain.SourceName,
+			Offset:     int64(i * 10),
+			NextOffset: int64((i + 1) * 10),
+			EventUid:   fmt.Sprintf("%s-%d", chain.JobWatchUid, i),
+			WatchUid:   chain.JobWatchUid,
+		}
+		chain.Append(s)
+		states = append(states, s)
+	}
+	for _, s := range states {
+		chain.Ack(s)
+	}
+}
+
+func TestCheckpointEveryBatch(t *testing.T) {
+	r := &recorder{}
+	chain := newTestAckTask(CheckpointConfig{}, r).NewAckChain("w1")
+
+	ackLines(chain, 0, 3)
+	ackLines(chain, 3, 5)
+
+	assert.Len(t, r.persisted, 5)
+	assert.Equal(t, int64(50), r.persisted[4].NextOffset)
+	assert.True(t, chain.isEmpty())
+}
+
+func TestCheckpointBytes(t *testing.T) {
+	r := &recorder{}
+	chain := newTestAckTask(CheckpointConfig{Bytes: 50}, r).NewAckChain("w1")
+
+	ackLines(chain, 0, 4)
+	assert.Len(t, r.persisted, 0)
+	assert.False(t, chain.isEmpty())
+	bytes, _ := chain.lag(time.Now())
+	assert.Equal(t, int64(40), bytes)
+
+	ackLines(chain, 4, 6)
+	assert.Len(t, r.persisted, 1)
+	assert.Equal(t, int64(50), r.persisted[0].NextOffset)
+	bytes, _ = chain.lag(time.Now())
+	assert.Equal(t, int64(10), bytes)
+}
+
+func TestCheckpointInterval(t *testing.T) {
+	r := &recorder{}
+	task := newTestAckTask(CheckpointConfig{Interval: time.Minute}, r)
+	handler := &AckChainHandler{
+		jobAckChains: make(map[string]*JobAckChain),
+		ackTasks:     map[string]*AckTask{task.Key(): task},
+	}
+	for _, uid := range []string{"w1", "w2"} {
+		chain := task.NewAckChain(uid)
+		handler.jobAckChains[chain.Key()] = chain
+		ackLines(chain, 0, 3)
+	}
+	assert.Len(t, r.persisted, 0)
+
+	handler.checkpoint(time.Now())
+	assert.Len(t, r.committed, 0)
+
+	// offsets of all the files are committed together
+	handler.checkpoint(time.Now().Add(time.Minute))
+	assert.Len(t, r.committed, 1)
+	assert.Len(t, r.committed[0], 2)
+	for _, chain := range handler.jobAckChains {
+		assert.True(t, chain.isEmpty())
+	}
+}
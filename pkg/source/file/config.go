@@ -21,6 +21,8 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/loggie-io/loggie/pkg/util"
 	timeutil "github.com/loggie-io/loggie/pkg/util/time"
 )
@@ -53,6 +55,25 @@ type CollectConfig struct {
 	CleanFiles                *CleanFiles   `yaml:"cleanFiles,omitempty"`
 	FdHoldTimeoutWhenInactive time.Duration `yaml:"fdHoldTimeoutWhenInactive,omitempty" default:"5m"`
 	FdHoldTimeoutWhenRemove   time.Duration `yaml:"fdHoldTimeoutWhenRemove,omitempty" default:"5m"`
+
+	// SkipLargerThan is a size in bytes, the files found by the first scan that are larger than it and have never been
+	// collected start from their current end, only the newly appended data is collected. 0 means disabled
+	SkipLargerThan int64 `yaml:"skipLargerThan,omitempty" validate:"gte=0"`
+
+	RotationAlarm RotationAlarmConfig `yaml:"rotationAlarm,omitempty"`
+
+	Mmap MmapConfig `yaml:"mmap,omitempty"`
+}
+
+// RotationAlarmConfig detects the rotation storm of a source, such as a misconfigured application rotating every second
+type RotationAlarmConfig struct {
+	// MaxNewFiles is the max count of new files found in the interval, 0 means disabled
+	MaxNewFiles int           `yaml:"maxNewFiles,omitempty" validate:"gte=0"`
+	Interval    time.Duration `yaml:"interval,omitempty" default:"1m"`
+	// Pause stops collecting the new files of the source for PauseDuration once the storm is detected, files already
+	// being collected are not affected, and the new files which still exist after the pause would be collected by the next scan
+	Pause         bool          `yaml:"pause,omitempty"`
+	PauseDuration time.Duration `yaml:"pauseDuration,omitempty" default:"10m"`
 }
 
 type AddonMetaSchema struct {
@@ -88,8 +109,30 @@ func (cc CollectConfig) IsFileExcluded(file string) bool {
 }
 
 type AckConfig struct {
-	Enable              bool          `yaml:"enable,omitempty" default:"true"`
-	MaintenanceInterval time.Duration `yaml:"maintenanceInterval,omitempty" default:"20h"`
+	Enable              bool             `yaml:"enable,omitempty" default:"true"`
+	MaintenanceInterval time.Duration    `yaml:"maintenanceInterval,omitempty" default:"20h"`
+	Checkpoint          CheckpointConfig `yaml:"checkpoint,omitempty"`
+}
+
+// CheckpointConfig controls how often the acked offsets are written to the registry.
+// The offset is checkpointed after every acked batch when neither bytes nor interval is set,
+// otherwise it is checkpointed once bytes have been acked since the last checkpoint or the interval elapsed,
+// the offsets reached by the interval are committed to the registry together in one write.
+type CheckpointConfig struct {
+	Bytes    int64         `yaml:"bytes,omitempty"`
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
+func (c CheckpointConfig) everyBatch() bool {
+	return c.Bytes <= 0 && c.Interval <= 0
+}
+
+// interval returns the max time an acked offset waits for the checkpoint
+func (c CheckpointConfig) interval() time.Duration {
+	if c.Interval > 0 {
+		return c.Interval
+	}
+	return defaultCheckpointInterval
 }
 
 type WatchConfig struct {
@@ -134,6 +177,12 @@ type MultiConfig struct {
 	MaxLines int           `yaml:"maxLines,omitempty" default:"500"`
 	MaxBytes int64         `yaml:"maxBytes,omitempty" default:"131072"` // default 128KB
 	Timeout  time.Duration `yaml:"timeout,omitempty" default:"5s"`      // default 2 * read.timeout
+	// WriterPattern extracts the identity of the writer from each line by the first submatch, such as the hostname
+	// or pid of a file shared by multiple hosts on NFS. Lines of each writer are aggregated separately, and the lines
+	// not matched belong to the last writer of the file.
+	WriterPattern string `yaml:"writerPattern,omitempty"`
+	// WriterField adds the writer identity to the header of the aggregated events if it is not empty
+	WriterField string `yaml:"writerField,omitempty"`
 }
 
 func (c *Config) SetDefaults() {
@@ -158,6 +207,16 @@ func (c *Config) Validate() error {
 		if err != nil {
 			return err
 		}
+
+		if c.ReaderConfig.MultiConfig.WriterPattern != "" {
+			r, err := regexp.Compile(c.ReaderConfig.MultiConfig.WriterPattern)
+			if err != nil {
+				return errors.WithMessage(err, "compile writerPattern")
+			}
+			if r.NumSubexp() < 1 {
+				return errors.New("writerPattern should contain a submatch of the writer identity")
+			}
+		}
 	}
 
 	return nil
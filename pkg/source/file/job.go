@@ -23,6 +23,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -52,6 +53,9 @@ type Job struct {
 	filename          string
 	aFileName         atomic.Value
 	file              *os.File
+	mapped            []byte
+	mappedOffset      int64
+	mmapLock          sync.Mutex
 	status            JobStatus
 	aStatus           atomic.Value
 	endOffset         int64
@@ -171,6 +175,7 @@ func (j *Job) Release() bool {
 	if j.file == nil {
 		return false
 	}
+	j.unmapFile()
 	err := j.file.Close()
 	if err != nil {
 		log.Error("release job(fileName: %s) error: %s", j.filename, err)
@@ -246,6 +251,7 @@ func (j *Job) Active() (error, bool) {
 				j.currentLineNumber = int64(lineNumber)
 			}
 		}
+		j.mapFile(fileInfo.Size())
 	}
 	j.ChangeStatusTo(JobActive)
 	j.EofCount = 0
@@ -0,0 +1,146 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"io"
+	"runtime/debug"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/pkg/errors"
+)
+
+var (
+	errMmapUnsupported = errors.New("mmap is not supported on this platform")
+	errMmapFault       = errors.New("mapped memory fault, the file may be truncated")
+	errNegativeOffset  = errors.New("seek to a negative offset")
+)
+
+// MmapConfig reads the large backlog of cold files from the mapped memory with sequential readahead,
+// which saves the read syscalls and copies of the kernel when catching up. It falls back to the standard reads
+// on the platforms without mmap, and after the mapped part of the file is read.
+type MmapConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// MinBacklog is the unread bytes of a file required to map it, the smaller files are read by syscalls
+	MinBacklog int64 `yaml:"minBacklog,omitempty" default:"67108864" validate:"gte=0"`
+}
+
+// mapFile maps the file when its unread backlog is large enough, it should be called after the file is opened
+func (j *Job) mapFile(size int64) {
+	config := j.task.config.Mmap
+	if !config.Enabled || size-j.nextOffset < config.MinBacklog || size <= 0 {
+		return
+	}
+
+	j.mmapLock.Lock()
+	defer j.mmapLock.Unlock()
+	if j.mapped != nil {
+		return
+	}
+	data, err := mmap(j.file, size)
+	if err != nil {
+		log.Warn("mmap file(%s) error: %v, fall back to standard reads", j.filename, err)
+		return
+	}
+	j.mapped = data
+	// the fd has been seeked to nextOffset when opened
+	j.mappedOffset = j.nextOffset
+	log.Info("file(%s) is mapped, backlog: %d bytes", j.filename, size-j.nextOffset)
+}
+
+func (j *Job) unmapFile() {
+	j.mmapLock.Lock()
+	defer j.mmapLock.Unlock()
+	j.unmapLocked()
+}
+
+func (j *Job) unmapLocked() {
+	if j.mapped == nil {
+		return
+	}
+	if err := munmap(j.mapped); err != nil {
+		log.Warn("munmap file(%s) error: %v", j.filename, err)
+	}
+	j.mapped = nil
+	// the offset is only tracked in memory while mapped, sync it to the fd for the standard reads
+	if _, err := j.file.Seek(j.mappedOffset, io.SeekStart); err != nil {
+		log.Warn("seek file(%s) to offset %d error: %v", j.filename, j.mappedOffset, err)
+	}
+}
+
+// Seek sets the offset of the next read, it should be used instead of seeking the fd directly, because
+// the offset is tracked in memory without syscalls while the file is mapped
+func (j *Job) Seek(offset int64, whence int) (int64, error) {
+	j.mmapLock.Lock()
+	defer j.mmapLock.Unlock()
+	if j.mapped == nil {
+		return j.file.Seek(offset, whence)
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += j.mappedOffset
+	default:
+		// the size of the file may be changed after it is mapped
+		j.unmapLocked()
+		return j.file.Seek(offset, whence)
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	j.mappedOffset = offset
+	return offset, nil
+}
+
+// ReadFile reads the file from the current offset, from the mapped memory if the file is mapped.
+// The offset is tracked in memory while mapped and synced to the fd when unmapped, see Seek.
+func (j *Job) ReadFile(buf []byte) (int, error) {
+	j.mmapLock.Lock()
+	defer j.mmapLock.Unlock()
+	if j.mapped == nil {
+		return j.file.Read(buf)
+	}
+
+	offset := j.mappedOffset
+	if offset >= int64(len(j.mapped)) {
+		// the mapped part has been read, the appended data is read by syscalls
+		j.unmapLocked()
+		return j.file.Read(buf)
+	}
+
+	n, err := copyMapped(buf, j.mapped[offset:])
+	if err != nil {
+		log.Warn("read mapped file(%s) error: %v, fall back to standard reads", j.filename, err)
+		j.unmapLocked()
+		return j.file.Read(buf)
+	}
+	j.mappedOffset += int64(n)
+	return n, nil
+}
+
+// copyMapped recovers from the fault of accessing the pages beyond the end of a truncated file,
+// which would crash the process otherwise
+func copyMapped(dst []byte, src []byte) (n int, err error) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			n, err = 0, errMmapFault
+		}
+	}()
+	return copy(dst, src), nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func writeLines(t testing.TB, path string, lines int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&buf, "2023-01-01 00:00:00 INFO line %d of the cold file\n", i)
+	}
+	assert.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+	return buf.Bytes()
+}
+
+func openJob(t testing.TB, path string, config MmapConfig) *Job {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	stat, err := f.Stat()
+	assert.NoError(t, err)
+	job := &Job{
+		task:     &WatchTask{config: CollectConfig{Mmap: config}},
+		filename: path,
+		file:     f,
+	}
+	job.mapFile(stat.Size())
+	return job
+}
+
+func readAll(t testing.TB, job *Job, bufSize int) []byte {
+	var out []byte
+	buf := make([]byte, bufSize)
+	for {
+		n, err := job.ReadFile(buf)
+		if err == io.EOF || n == 0 {
+			return out
+		}
+		assert.NoError(t, err)
+		out = append(out, buf[:n]...)
+	}
+}
+
+func TestJob_ReadFileMapped(t *testing.T) {
+	log.InitDefaultLogger()
+	path := filepath.Join(t.TempDir(), "cold.log")
+	content := writeLines(t, path, 10000)
+
+	job := openJob(t, path, MmapConfig{Enabled: true, MinBacklog: 1024})
+	defer job.Release()
+	if runtime.GOOS == "linux" {
+		assert.NotNil(t, job.mapped)
+	}
+
+	// seek back like the lastline processor
+	buf := make([]byte, 100)
+	n, err := job.ReadFile(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, content[:n], buf[:n])
+	_, err = job.Seek(-30, io.SeekCurrent)
+	assert.NoError(t, err)
+	n, err = job.ReadFile(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, content[70:70+n], buf[:n])
+	_, err = job.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+
+	assert.Equal(t, content, readAll(t, job, 4096))
+
+	// the appended data is read by syscalls after the mapped part
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = f.WriteString("appended\n")
+	assert.NoError(t, err)
+	f.Close()
+	assert.Equal(t, []byte("appended\n"), readAll(t, job, 4096))
+	assert.Nil(t, job.mapped)
+
+	// the offset is synced to the fd after unmapped
+	offset, err := job.File().Seek(0, io.SeekCurrent)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)+len("appended\n")), offset)
+}
+
+func TestJob_SeekMapped(t *testing.T) {
+	log.InitDefaultLogger()
+	path := filepath.Join(t.TempDir(), "cold.log")
+	content := writeLines(t, path, 10000)
+
+	job := openJob(t, path, MmapConfig{Enabled: true, MinBacklog: 1024})
+	defer job.Release()
+	if runtime.GOOS != "linux" {
+		t.Skip("mmap is not supported")
+	}
+
+	offset, err := job.Seek(100, io.SeekStart)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), offset)
+	offset, err = job.Seek(-10, io.SeekCurrent)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(90), offset)
+	_, err = job.Seek(-100, io.SeekCurrent)
+	assert.Error(t, err)
+
+	// the fd is not seeked while mapped
+	fdOffset, err := job.File().Seek(0, io.SeekCurrent)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), fdOffset)
+
+	// seeking from the end unmaps the file
+	offset, err = job.Seek(-10, io.SeekEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)-10), offset)
+	assert.Nil(t, job.mapped)
+	assert.Equal(t, content[len(content)-10:], readAll(t, job, 4096))
+}
+
+func TestJob_ReadFileSmallBacklog(t *testing.T) {
+	log.InitDefaultLogger()
+	path := filepath.Join(t.TempDir(), "small.log")
+	content := writeLines(t, path, 10)
+
+	job := openJob(t, path, MmapConfig{Enabled: true, MinBacklog: 1 << 20})
+	defer job.Release()
+	assert.Nil(t, job.mapped)
+	assert.Equal(t, content, readAll(t, job, 4096))
+
+	disabled := openJob(t, path, MmapConfig{MinBacklog: 0})
+	defer disabled.Release()
+	assert.Nil(t, disabled.mapped)
+}
+
+func benchmarkReadFile(b *testing.B, config MmapConfig) {
+	log.InitDefaultLogger()
+	path := filepath.Join(b.TempDir(), "cold.log")
+	content := writeLines(b, path, 200000)
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		job := openJob(b, path, config)
+		readAll(b, job, 65536)
+		job.Release()
+	}
+}
+
+func BenchmarkJob_ReadFileSyscall(b *testing.B) {
+	benchmarkReadFile(b, MmapConfig{})
+}
+
+func BenchmarkJob_ReadFileMmap(b *testing.B) {
+	benchmarkReadFile(b, MmapConfig{Enabled: true})
+}
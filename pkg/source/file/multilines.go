@@ -18,6 +18,7 @@ package file
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -46,6 +47,7 @@ type MultiTask struct {
 	key          string
 	config       MultiConfig
 	matcher      util.Matcher
+	writerRegex  *regexp.Regexp
 	eventPool    *event.Pool
 	productFunc  api.ProductFunc
 	countDown    *sync.WaitGroup
@@ -65,6 +67,10 @@ func (mt *MultiTask) isParentOf(mh *MultiHolder) bool {
 
 func NewMultiTask(epoch *pipeline.Epoch, sourceName string, config MultiConfig, eventPool *event.Pool, productFunc api.ProductFunc) *MultiTask {
 	multiSampleLogger := log.SubLogger(subLogger+"/multiline").Sample(1, 10*time.Second)
+	var writerRegex *regexp.Regexp
+	if config.WriterPattern != "" {
+		writerRegex = regexp.MustCompile(config.WriterPattern)
+	}
 	return &MultiTask{
 		sampleLogger: multiSampleLogger,
 		epoch:        epoch,
@@ -72,6 +78,7 @@ func NewMultiTask(epoch *pipeline.Epoch, sourceName string, config MultiConfig,
 		key:          fmt.Sprintf("%s:%s", epoch.PipelineName, sourceName),
 		config:       config,
 		matcher:      util.MustCompile(config.Pattern),
+		writerRegex:  writerRegex,
 		eventPool:    eventPool,
 		productFunc:  productFunc,
 		countDown:    &sync.WaitGroup{},
@@ -81,9 +88,10 @@ func NewMultiTask(epoch *pipeline.Epoch, sourceName string, config MultiConfig,
 func (mt *MultiTask) newMultiHolder(state persistence.State) *MultiHolder {
 	lineEnd := globalLineEnd.GetEncodeLineEnd(state.PipelineName, state.SourceName)
 	return &MultiHolder{
-		mTask:    mt,
-		state:    state,
-		initTime: time.Now(),
+		mTask:     mt,
+		state:     state,
+		holderKey: state.WatchUid,
+		initTime:  time.Now(),
 
 		lineEnd:       lineEnd,
 		lineEndLength: int64(len(lineEnd)),
@@ -94,9 +102,41 @@ func (mt *MultiTask) isContain(s *persistence.State) bool {
 	return mt.sourceName == s.SourceName && mt.epoch.Equal(s.Epoch)
 }
 
+// writer returns the identity of the writer of the line, the line without it belongs to the last writer
+func (mt *MultiTask) writer(body []byte, last string) string {
+	m := mt.writerRegex.FindSubmatch(body)
+	if len(m) < 2 {
+		return last
+	}
+	return string(m[1])
+}
+
+// fileWriters holds the lines of each writer of a file
+type fileWriters struct {
+	last    string
+	holders map[string]*MultiHolder
+}
+
+// safeOffset returns the smallest offset of the lines held by the other writers, which have not been sent yet
+func (fw *fileWriters) safeOffset(except *MultiHolder, offset int64) int64 {
+	for _, h := range fw.holders {
+		if h == except || h.currentSize <= 0 {
+			continue
+		}
+		if h.state.Offset < offset {
+			offset = h.state.Offset
+		}
+	}
+	return offset
+}
+
 type MultiHolder struct {
-	mTask *MultiTask
-	state persistence.State
+	mTask     *MultiTask
+	state     persistence.State
+	holderKey string
+	// writer and files are only set when the writerPattern is configured
+	writer string
+	files  *fileWriters
 
 	content      []byte
 	currentLines int
@@ -109,7 +149,7 @@ type MultiHolder struct {
 }
 
 func (mh *MultiHolder) key() string {
-	return mh.state.WatchUid
+	return mh.holderKey
 }
 
 func (mh *MultiHolder) append(event api.Event) {
@@ -171,11 +211,23 @@ func (mh *MultiHolder) flush() {
 		WatchUid:     mh.state.WatchUid,
 		JobFields:    mh.state.JobFields,
 	}
+	header := mh.lastHeader
+	if mh.files != nil {
+		// the lines held by the other writers would be collected again instead of being lost after a restart
+		state.NextOffset = mh.files.safeOffset(mh, state.NextOffset)
+		if field := mh.mTask.config.WriterField; field != "" {
+			header = make(map[string]interface{}, len(mh.lastHeader)+1)
+			for k, v := range mh.lastHeader {
+				header[k] = v
+			}
+			header[field] = mh.writer
+		}
+	}
 	contentBuffer := mh.content
 
 	e := mh.mTask.eventPool.Get()
 	e.Meta().Set(SystemStateKey, state)
-	e.Fill(e.Meta(), mh.lastHeader, contentBuffer)
+	e.Fill(e.Meta(), header, contentBuffer)
 	mh.mTask.productFunc(e)
 
 	mh.content = make([]byte, 0)
@@ -190,6 +242,8 @@ type MultiProcessor struct {
 	taskChan      chan *MultiTask
 	tasks         map[string]*MultiTask
 	holderMap     map[string]*MultiHolder
+	// writers of the files aggregated by writerPattern, keyed by WatchUid
+	files map[string]*fileWriters
 }
 
 func NewMultiProcessor() *MultiProcessor {
@@ -200,6 +254,7 @@ func NewMultiProcessor() *MultiProcessor {
 		taskChan:      make(chan *MultiTask),
 		tasks:         make(map[string]*MultiTask),
 		holderMap:     make(map[string]*MultiHolder),
+		files:         make(map[string]*fileWriters),
 	}
 	go mp.run()
 	return mp
@@ -250,33 +305,13 @@ func (mp *MultiProcessor) run() {
 				// stop all task holder
 				for _, holder := range mp.holderMap {
 					if task.isParentOf(holder) {
-						delete(mp.holderMap, holder.key())
+						mp.removeHolder(holder)
 					}
 				}
 				task.countDown.Done()
 			}
 		case e := <-mp.eventChan:
-			state := getState(e)
-			watchUid := state.WatchUid
-			if mh, ok := mp.holderMap[watchUid]; ok {
-				mh.lastHeader = e.Header()
-				mh.append(e)
-			} else {
-				create := false
-				for _, task := range mp.tasks {
-					if task.isContain(state) {
-						create = true
-						mh := task.newMultiHolder(*state)
-						mp.holderMap[mh.key()] = mh
-						mh.lastHeader = e.Header()
-						mh.append(e)
-						break
-					}
-				}
-				if !create {
-					log.Debug("append event state of source has stopped: %+v", state)
-				}
-			}
+			mp.process(e)
 		case <-ticker.C:
 			mp.iterateFlush()
 		case <-maintenanceTicker.C:
@@ -285,6 +320,67 @@ func (mp *MultiProcessor) run() {
 	}
 }
 
+func (mp *MultiProcessor) process(e api.Event) {
+	state := getState(e)
+	watchUid := state.WatchUid
+	if mh, ok := mp.holderMap[watchUid]; ok {
+		mh.lastHeader = e.Header()
+		mh.append(e)
+		return
+	}
+
+	for _, task := range mp.tasks {
+		if !task.isContain(state) {
+			continue
+		}
+		if task.writerRegex != nil {
+			mp.processWriter(task, state, e)
+			return
+		}
+		mh := task.newMultiHolder(*state)
+		mp.holderMap[mh.key()] = mh
+		mh.lastHeader = e.Header()
+		mh.append(e)
+		return
+	}
+	log.Debug("append event state of source has stopped: %+v", state)
+}
+
+func (mp *MultiProcessor) processWriter(task *MultiTask, state *persistence.State, e api.Event) {
+	fw, ok := mp.files[state.WatchUid]
+	if !ok {
+		fw = &fileWriters{
+			holders: make(map[string]*MultiHolder),
+		}
+		mp.files[state.WatchUid] = fw
+	}
+	writer := task.writer(e.Body(), fw.last)
+	fw.last = writer
+
+	mh, ok := fw.holders[writer]
+	if !ok {
+		mh = task.newMultiHolder(*state)
+		mh.holderKey = state.WatchUid + "/" + writer
+		mh.writer = writer
+		mh.files = fw
+		fw.holders[writer] = mh
+		mp.holderMap[mh.key()] = mh
+	}
+	mh.lastHeader = e.Header()
+	mh.append(e)
+}
+
+func (mp *MultiProcessor) removeHolder(holder *MultiHolder) {
+	delete(mp.holderMap, holder.key())
+	if holder.files == nil {
+		return
+	}
+	delete(holder.files.holders, holder.writer)
+	if len(holder.files.holders) == 0 {
+		delete(mp.files, holder.state.WatchUid)
+	}
+}
+
 func (mp *MultiProcessor) iterateFlush() {
 	for _, holder := range mp.holderMap {
 		if time.Since(holder.initTime) >= holder.mTask.config.Timeout {
@@ -294,9 +390,9 @@ func (mp *MultiProcessor) iterateFlush() {
 }
 
 func (mp *MultiProcessor) cleanUp() {
-	for key, holder := range mp.holderMap {
+	for _, holder := range mp.holderMap {
 		if holder.currentSize <= 0 {
-			delete(mp.holderMap, key)
+			mp.removeHolder(holder)
 		}
 	}
 }
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/persistence"
+)
+
+func TestMultilineWriters(t *testing.T) {
+	log.InitDefaultLogger()
+
+	epoch := pipeline.NewEpoch("test")
+	pool := event.NewDefaultPool(16)
+	var out []api.Event
+	task := NewMultiTask(epoch, "file", MultiConfig{
+		Pattern:       `^\S+ \d{4}-`,
+		MaxLines:      100,
+		MaxBytes:      1 << 20,
+		Timeout:       time.Minute,
+		WriterPattern: `^(\S+) `,
+		WriterField:   "writer",
+	}, pool, func(e api.Event) api.Result {
+		out = append(out, e)
+		return result.Success()
+	})
+	mp := &MultiProcessor{
+		tasks:     map[string]*MultiTask{task.key: task},
+		holderMap: make(map[string]*MultiHolder),
+		files:     make(map[string]*fileWriters),
+	}
+
+	lines := []string{
+		"host-a 2023-01-01 error",
+		"host-b 2023-01-01 error",
+		"host-a   at a.java:1",
+		"host-b   at b.java:1",
+		"  at b.java:2",
+		"host-a 2023-01-01 info",
+	}
+	offset := int64(0)
+	for i, l := range lines {
+		meta := event.NewDefaultMeta()
+		meta.Set(SystemStateKey, &persistence.State{
+			Epoch:      epoch,
+			SourceName: "file",
+			Offset:     offset,
+			NextOffset: offset + int64(len(l)) + 1,
+			EventUid:   fmt.Sprintf("uid-%d", i),
+			WatchUid:   "watch",
+		})
+		offset += int64(len(l)) + 1
+		e := pool.Get()
+		e.Fill(meta, map[string]interface{}{}, []byte(l))
+		mp.process(e)
+	}
+
+	// the first event of host-a is flushed when its next one starts, the offset does not skip the lines of host-b
+	assert.Equal(t, 1, len(out))
+	assert.Equal(t, "host-a 2023-01-01 error\nhost-a   at a.java:1", string(out[0].Body()))
+	assert.Equal(t, "host-a", out[0].Header()["writer"])
+	assert.Equal(t, int64(len(lines[0])+1), getState(out[0]).NextOffset)
+
+	for _, h := range mp.holderMap {
+		h.flush()
+	}
+	assert.Equal(t, 3, len(out))
+	var hostB api.Event
+	for _, e := range out[1:] {
+		if e.Header()["writer"] == "host-b" {
+			hostB = e
+		}
+	}
+	assert.Equal(t, "host-b 2023-01-01 error\nhost-b   at b.java:1\n  at b.java:2", string(hostB.Body()))
+}
@@ -42,12 +42,11 @@ func validateJob(job *Job) (lastOffset int64, err error) {
 		log.Info("Job(uid: %s) file(%s) status(%d) is stop, Job will be ignore", job.Uid(), filename, status)
 		return 0, errors.New("Job is stop")
 	}
-	osFile := job.file
-	if osFile == nil {
+	if job.file == nil {
 		log.Error("Job(uid: %s) file(%s) released,Job will be ignore", job.Uid(), filename)
 		return 0, errors.New("Job file released")
 	}
-	lastOffset, err = osFile.Seek(0, io.SeekCurrent)
+	lastOffset, err = job.Seek(0, io.SeekCurrent)
 	if err != nil {
 		log.Error("can't get offset, file(name:%s) seek error, err: %v", filename, err)
 		return 0, err
@@ -3,6 +3,7 @@ package process
 import (
 	"time"
 
+	"github.com/loggie-io/loggie/pkg/core/pressure"
 	"github.com/loggie-io/loggie/pkg/source/file"
 )
 
@@ -49,7 +50,7 @@ func (bp *LoopProcessor) Process(processorChain file.ProcessChain, ctx *file.Job
 			// According to the number of batches 2048, a maximum of one batch can be read,
 			// and a single event is calculated according to 512 bytes, that is, the maximum reading is 1mb ,maxContinueRead = 16 by default
 			// SSD recommends that maxContinueRead be increased by 3 ~ 5x
-			if bp.continueRead > bp.maxContinueRead {
+			if bp.continueRead > pressure.MaxContinueRead(bp.maxContinueRead) {
 				break
 			}
 			if time.Since(bp.startReadTime) > bp.maxContinueReadTimeout {
@@ -3,6 +3,7 @@ package process
 import (
 	"errors"
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/pressure"
 	"github.com/loggie-io/loggie/pkg/source/file"
 	"io"
 )
@@ -31,8 +32,8 @@ func (sp *SourceProcessor) Code() string {
 
 func (sp *SourceProcessor) Process(processorChain file.ProcessChain, ctx *file.JobCollectContext) {
 	job := ctx.Job
-	ctx.ReadBuffer = ctx.ReadBuffer[:sp.readBufferSize]
-	l, err := job.File().Read(ctx.ReadBuffer)
+	ctx.ReadBuffer = ctx.ReadBuffer[:pressure.ReadBufferSize(sp.readBufferSize)]
+	l, err := job.ReadFile(ctx.ReadBuffer)
 	if errors.Is(err, io.EOF) || l == 0 {
 		ctx.IsEOF = true
 		job.EofCount++
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/eventbus"
+)
+
+const (
+	rotationStormReason = "FileRotationStorm"
+
+	rotationNewFilesKey    = "newFiles"
+	rotationIntervalKey    = "interval"
+	rotationPausedUntilKey = "pausedUntil"
+)
+
+// rotationDetector counts the new files of a source in fixed intervals, the source is in a rotation storm
+// when the count exceeds MaxNewFiles. It is only accessed by the watcher goroutine.
+type rotationDetector struct {
+	config      RotationAlarmConfig
+	windowStart time.Time
+	count       int
+	alarmed     bool
+	pausedUntil time.Time
+}
+
+// observe counts a new file, it returns whether a storm is detected, which is reported only once in an interval,
+// and whether the new file should be ignored for the source is paused
+func (d *rotationDetector) observe(now time.Time) (storm bool, paused bool) {
+	if now.Before(d.pausedUntil) {
+		return false, true
+	}
+
+	if now.Sub(d.windowStart) >= d.config.Interval {
+		d.windowStart = now
+		d.count = 0
+		d.alarmed = false
+	}
+	d.count++
+	if d.count <= d.config.MaxNewFiles || d.alarmed {
+		return false, false
+	}
+
+	d.alarmed = true
+	if d.config.Pause {
+		d.pausedUntil = now.Add(d.config.PauseDuration)
+	}
+	return true, d.config.Pause
+}
+
+// rotationPaused checks the rotation rate of the source when a new file is found after the first scan,
+// an alert is sent to the logAlert listener once the storm is detected
+func (w *Watcher) rotationPaused(job *Job) bool {
+	task := job.task
+	if task.config.RotationAlarm.MaxNewFiles <= 0 || !task.scanned {
+		return false
+	}
+
+	now := time.Now()
+	storm, paused := task.rotation.observe(now)
+	if storm {
+		msg := fmt.Sprintf("more than %d new files are found in %s, the files may be rotated too frequently",
+			task.config.RotationAlarm.MaxNewFiles, task.config.RotationAlarm.Interval)
+		if paused {
+			msg += fmt.Sprintf(", new files will not be collected until %s", task.rotation.pausedUntil.Format(time.RFC3339))
+		}
+		log.Warn("[%s-%s] rotation storm detected: %s", task.pipelineName, task.sourceName, msg)
+
+		e := rotationStormEvent(task, now, msg)
+		eventbus.PublishOrDrop(eventbus.LogAlertTopic, &e)
+	}
+	if paused {
+		log.Debug("[%s-%s] source is paused by rotation storm, file %s is ignored", task.pipelineName, task.sourceName, job.filename)
+	}
+	return paused
+}
+
+func rotationStormEvent(task *WatchTask, now time.Time, msg string) api.Event {
+	header := map[string]interface{}{
+		event.ReasonKey:     rotationStormReason,
+		rotationNewFilesKey: task.rotation.count,
+		rotationIntervalKey: task.config.RotationAlarm.Interval.String(),
+	}
+	if now.Before(task.rotation.pausedUntil) {
+		header[rotationPausedUntilKey] = task.rotation.pausedUntil.Format(time.RFC3339)
+	}
+
+	meta := event.NewDefaultMeta()
+	meta.Set(event.SystemProductTimeKey, now)
+	meta.Set(event.SystemPipelineKey, task.pipelineName)
+	meta.Set(event.SystemSourceKey, task.sourceName)
+
+	e := event.NewEvent(header, []byte(msg))
+	e.Fill(meta, header, e.Body())
+	return e
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func TestRotationDetector_observe(t *testing.T) {
+	d := &rotationDetector{config: RotationAlarmConfig{MaxNewFiles: 2, Interval: time.Minute}}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		storm, paused := d.observe(now)
+		assert.False(t, storm)
+		assert.False(t, paused)
+	}
+	storm, paused := d.observe(now.Add(time.Second))
+	assert.True(t, storm)
+	assert.False(t, paused)
+	// reported only once in an interval
+	storm, _ = d.observe(now.Add(2 * time.Second))
+	assert.False(t, storm)
+
+	// a new interval
+	storm, _ = d.observe(now.Add(time.Minute))
+	assert.False(t, storm)
+	assert.Equal(t, 1, d.count)
+}
+
+func TestRotationDetector_pause(t *testing.T) {
+	d := &rotationDetector{config: RotationAlarmConfig{MaxNewFiles: 1, Interval: time.Minute, Pause: true, PauseDuration: 10 * time.Minute}}
+	now := time.Now()
+
+	d.observe(now)
+	storm, paused := d.observe(now)
+	assert.True(t, storm)
+	assert.True(t, paused)
+
+	storm, paused = d.observe(now.Add(5 * time.Minute))
+	assert.False(t, storm)
+	assert.True(t, paused)
+
+	storm, paused = d.observe(now.Add(10 * time.Minute))
+	assert.False(t, storm)
+	assert.False(t, paused)
+}
+
+func TestWatcher_rotationPaused(t *testing.T) {
+	log.InitDefaultLogger()
+	w := &Watcher{}
+	config := CollectConfig{RotationAlarm: RotationAlarmConfig{MaxNewFiles: 1, Interval: time.Minute, Pause: true, PauseDuration: time.Minute}}
+	task := &WatchTask{
+		pipelineName: "test",
+		sourceName:   "file",
+		config:       config,
+		rotation:     &rotationDetector{config: config.RotationAlarm},
+	}
+	job := &Job{task: task, filename: "/var/log/app.log"}
+
+	// files of the first scan are not counted
+	for i := 0; i < 3; i++ {
+		assert.False(t, w.rotationPaused(job))
+	}
+
+	task.scanned = true
+	assert.False(t, w.rotationPaused(job))
+	assert.True(t, w.rotationPaused(job))
+	assert.True(t, w.rotationPaused(job))
+
+	e := rotationStormEvent(task, time.Now(), "storm")
+	assert.Equal(t, rotationStormReason, e.Header()[event.ReasonKey])
+	assert.Equal(t, 2, e.Header()[rotationNewFilesKey])
+	assert.Contains(t, e.Header(), rotationPausedUntilKey)
+	source, _ := e.Meta().Get(event.SystemSourceKey)
+	assert.Equal(t, "file", source)
+}
@@ -217,9 +217,9 @@ func (s *Source) ProductLoop(productFunc api.ProductFunc) {
 		s.productFunc = NewCharset(s.config.CollectConfig.Charset, s.productFunc).Hook
 	}
 	if s.ackEnable {
-		s.ackTask = NewAckTask(s.epoch, s.pipelineName, s.name, func(state *persistence.State) {
+		s.ackTask = NewAckTask(s.epoch, s.pipelineName, s.name, s.config.AckConfig.Checkpoint, func(state *persistence.State) {
 			s.dbHandler.State <- state
-		})
+		}, s.dbHandler.Commit)
 		s.ackChainHandler.StartTask(s.ackTask)
 		log.Info("%s ack start", s.String())
 	}
@@ -53,6 +53,8 @@ type WatchTask struct {
 	waiteForStopJobs map[string]*Job
 	stopTime         time.Time
 	sourceFields     map[string]interface{}
+	scanned          bool // whether the first scan of the paths is done
+	rotation         *rotationDetector
 }
 
 func NewWatchTask(epoch *pipeline.Epoch, pipelineName string, sourceName string, config CollectConfig,
@@ -67,6 +69,7 @@ func NewWatchTask(epoch *pipeline.Epoch, pipelineName string, sourceName string,
 		activeChan:   activeChan,
 		countDown:    &sync.WaitGroup{},
 		sourceFields: sourceFields,
+		rotation:     &rotationDetector{config: config.RotationAlarm},
 	}
 	// init excludeFilePatterns
 	l := len(w.config.ExcludeFiles)
@@ -27,6 +27,7 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/pressure"
 	"github.com/loggie-io/loggie/pkg/discovery/kubernetes/external"
 	"github.com/loggie-io/loggie/pkg/eventbus"
 	"github.com/loggie-io/loggie/pkg/util"
@@ -141,6 +142,30 @@ func (w *Watcher) preAllocationOffset(size int64, job *Job) {
 	})
 }
 
+// skipExistingFile reports whether the file was found by the first scan and is too large to be collected from the beginning.
+// The offset is marked by the pre-allocation, so the skipped data will not be read after restarting either.
+func (w *Watcher) skipExistingFile(job *Job, existRegistry reg.Registry, fileSize int64) bool {
+	threshold := job.task.config.SkipLargerThan
+	if threshold <= 0 || job.task.scanned || existRegistry.JobUid != "" || fileSize <= threshold {
+		return false
+	}
+
+	log.Warn("[%s-%s] skip %d bytes of existing file %s which is larger than %d bytes, only the newly appended data will be collected",
+		job.task.pipelineName, job.task.sourceName, fileSize, job.filename, threshold)
+	eventbus.PublishOrDrop(eventbus.FileSourceMetricTopic, eventbus.CollectMetricData{
+		BaseMetric: eventbus.BaseMetric{
+			PipelineName: job.task.pipelineName,
+			SourceName:   job.task.sourceName,
+		},
+		FileName:     job.filename,
+		Offset:       fileSize,
+		FileSize:     fileSize,
+		SkippedBytes: fileSize,
+		SourceFields: job.task.sourceFields,
+	})
+	return true
+}
+
 func (w *Watcher) findExistJobRegistry(job *Job) reg.Registry {
 	return w.dbHandler.FindBy(job.Uid(), job.task.sourceName, job.task.pipelineName)
 }
@@ -200,10 +225,30 @@ func (w *Watcher) DecideJob(job *Job) {
 		w.zombieJobChan <- job
 		return
 	}
+	// the backlog is not caught up while the node is under pressure, it is resumed by the zombie scan once relieved
+	if w.catchUpPaused(job) {
+		w.zombieJobChan <- job
+		return
+	}
 	// w.activeChan <- job
 	job.Read()
 }
 
+func (w *Watcher) catchUpPaused(job *Job) bool {
+	if !pressure.Under() || job.file == nil {
+		return false
+	}
+	stat, err := job.file.Stat()
+	if err != nil {
+		return false
+	}
+	if !pressure.CatchUpPaused(stat.Size() - job.nextOffset) {
+		return false
+	}
+	log.Debug("job fileName(%s) is paused at offset %d of %d, the node is under pressure", job.filename, job.nextOffset, stat.Size())
+	return true
+}
+
 func (w *Watcher) reportMetric(job *Job) {
 	if job.endOffset == 0 {
 		// file is not really being collected
@@ -274,6 +319,11 @@ func (w *Watcher) eventBus(e jobEvent) {
 			}
 		}
 
+		if pressure.CatchUpPaused(filesize - job.nextOffset) {
+			log.Debug("job fileName(%s) is not activated, the node is under pressure", filename)
+			return
+		}
+
 		err, fdOpen := existJob.Active()
 		if fdOpen {
 			w.currentOpenFds++
@@ -300,6 +350,9 @@ func (w *Watcher) eventBus(e jobEvent) {
 		if _, ok := w.allJobs[watchJobId]; ok {
 			return
 		}
+		if w.rotationPaused(job) {
+			return
+		}
 		stat, err := os.Stat(filename)
 		if err != nil {
 			log.Error("create job fileName(%s) fail: %s", filename, err)
@@ -320,7 +373,7 @@ func (w *Watcher) eventBus(e jobEvent) {
 		}
 		// Pre-allocation offset
 		if existAckOffset == 0 || e.job.task.config.ReadFromTail {
-			if e.job.task.config.ReadFromTail {
+			if e.job.task.config.ReadFromTail || w.skipExistingFile(job, existRegistry, fileSize) {
 				existAckOffset = fileSize
 			}
 			w.preAllocationOffset(existAckOffset, job)
@@ -387,6 +440,9 @@ func (w *Watcher) scanTaskNewFiles(watchTask *WatchTask) {
 	pipelineName := watchTask.pipelineName
 	sourceName := watchTask.sourceName
 	paths := watchTask.config.Paths
+	defer func() {
+		watchTask.scanned = true
+	}()
 	if isDynamicPath(paths) {
 		w.scanDynamicContainerLogs(pipelineName, sourceName, watchTask)
 		return
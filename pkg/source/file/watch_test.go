@@ -23,6 +23,10 @@ import (
 	"testing"
 
 	"github.com/mattn/go-zglob"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/persistence/reg"
 )
 
 func TestWatcher_scanNewFiles(t *testing.T) {
@@ -102,3 +106,27 @@ func TestMachDate(t *testing.T) {
 		fmt.Println(s)
 	}
 }
+
+func TestWatcher_skipExistingFile(t *testing.T) {
+	log.InitDefaultLogger()
+	w := &Watcher{}
+	task := &WatchTask{
+		pipelineName: "test",
+		sourceName:   "file",
+		config:       CollectConfig{SkipLargerThan: 1024},
+	}
+	job := &Job{task: task, filename: "/var/log/huge.log"}
+
+	assert.False(t, w.skipExistingFile(job, reg.Registry{}, 1024))
+	assert.True(t, w.skipExistingFile(job, reg.Registry{}, 4096))
+	// collected before
+	assert.False(t, w.skipExistingFile(job, reg.Registry{JobUid: "1-2"}, 4096))
+
+	// created after the first scan
+	task.scanned = true
+	assert.False(t, w.skipExistingFile(job, reg.Registry{}, 4096))
+
+	task.scanned = false
+	task.config.SkipLargerThan = 0
+	assert.False(t, w.skipExistingFile(job, reg.Registry{}, 4096))
+}
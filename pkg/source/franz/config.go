@@ -18,6 +18,7 @@ package franz
 
 import (
 	"github.com/loggie-io/loggie/pkg/sink/franz"
+	"github.com/loggie-io/loggie/pkg/util/pattern"
 	"github.com/pkg/errors"
 	"github.com/twmb/franz-go/pkg/kgo"
 	"regexp"
@@ -27,6 +28,10 @@ import (
 const (
 	earliestOffsetReset = "earliest"
 	latestOffsetReset   = "latest"
+
+	// the defaults of group.min.session.timeout.ms and group.max.session.timeout.ms of the brokers
+	minSessionTimeout = 6 * time.Second
+	maxSessionTimeout = 30 * time.Minute
 )
 
 type Config struct {
@@ -37,6 +42,17 @@ type Config struct {
 	ClientId string   `yaml:"clientId,omitempty"`
 	Worker   int      `yaml:"worker,omitempty" default:"1"`
 
+	// InstanceId enables static membership, a restarted member which rejoins with the same id within sessionTimeout
+	// gets its partitions back without triggering a rebalance. It should be unique and stable for each agent,
+	// env vars are supported such as ${_env.POD_NAME}. clientId is used as the instance id if it is empty, as it was before
+	InstanceId string `yaml:"instanceId,omitempty"`
+	// Balancers are the group balancers in order of preference, defaults to cooperativeSticky which rebalances incrementally
+	// instead of revoking all the partitions of the group, could also be sticky, roundRobin or range
+	Balancers []string `yaml:"balancers,omitempty"`
+	// SessionTimeout should be within group.min.session.timeout.ms and group.max.session.timeout.ms of the brokers
+	SessionTimeout   time.Duration `yaml:"sessionTimeout,omitempty"`
+	RebalanceTimeout time.Duration `yaml:"rebalanceTimeout,omitempty"`
+
 	FetchMaxWait           time.Duration `yaml:"fetchMaxWait,omitempty"`
 	FetchMaxBytes          int32         `yaml:"fetchMaxBytes,omitempty"`
 	FetchMinBytes          int32         `yaml:"fetchMinBytes,omitempty"`
@@ -62,11 +78,54 @@ func getAutoOffset(autoOffsetReset string) kgo.Offset {
 	return kgo.NewOffset().AtEnd()
 }
 
+// instanceId renders the group instance id, clientId is used if instanceId is empty
+func (c *Config) instanceId() (string, error) {
+	if c.InstanceId == "" {
+		return c.ClientId, nil
+	}
+	p, err := pattern.Init(c.InstanceId)
+	if err != nil {
+		return "", err
+	}
+	instanceId, err := p.RenderWithStrict()
+	if err != nil {
+		return "", errors.WithMessagef(err, "render kafka instanceId %s", c.InstanceId)
+	}
+	return instanceId, nil
+}
+
+func groupBalancers(names []string) []kgo.GroupBalancer {
+	var balancers []kgo.GroupBalancer
+	for _, name := range names {
+		if b := franz.GetGroupBalancer(name); b != nil {
+			balancers = append(balancers, b)
+		}
+	}
+	return balancers
+}
+
 func (c *Config) Validate() error {
 	if c.Topic == "" && len(c.Topics) == 0 {
 		return errors.New("topic or topics is required")
 	}
 
+	for _, b := range c.Balancers {
+		if franz.GetGroupBalancer(b) == nil {
+			return errors.Errorf("kafka group balancer %s is not supported", b)
+		}
+	}
+	if c.InstanceId != "" {
+		if err := pattern.Validate(c.InstanceId); err != nil {
+			return err
+		}
+	}
+	if c.SessionTimeout != 0 && (c.SessionTimeout < minSessionTimeout || c.SessionTimeout > maxSessionTimeout) {
+		return errors.Errorf("sessionTimeout should be between %s and %s", minSessionTimeout, maxSessionTimeout)
+	}
+	if c.RebalanceTimeout < 0 {
+		return errors.New("rebalanceTimeout should not be negative")
+	}
+
 	if c.Topic != "" {
 		_, err := regexp.Compile(c.Topic)
 		if err != nil {
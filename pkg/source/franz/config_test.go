@@ -0,0 +1,149 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package franz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+)
+
+func TestGroupBalancers(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:  "in order of preference",
+			names: []string{"cooperativeSticky", "sticky", "roundRobin", "range"},
+			want:  []string{"cooperative-sticky", "sticky", "roundrobin", "range"},
+		},
+		{
+			name:  "unknown",
+			names: []string{"unknown", "range"},
+			want:  []string{"range"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, b := range groupBalancers(tt.names) {
+				got = append(got, b.ProtocolName())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfig_instanceId(t *testing.T) {
+	t.Setenv("POD_NAME", "loggie-0")
+
+	tests := []struct {
+		name       string
+		instanceId string
+		clientId   string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name: "static membership disabled",
+		},
+		{
+			name:       "const",
+			instanceId: "loggie-0",
+			clientId:   "loggie",
+			want:       "loggie-0",
+		},
+		{
+			name:       "env",
+			instanceId: "${_env.POD_NAME}",
+			want:       "loggie-0",
+		},
+		{
+			name:       "env not found",
+			instanceId: "${_env.NOT_FOUND}",
+			wantErr:    true,
+		},
+		{
+			name:     "client id as before",
+			clientId: "loggie",
+			want:     "loggie",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{InstanceId: tt.instanceId, ClientId: tt.clientId}
+			got, err := c.instanceId()
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{
+			name: "static membership",
+			raw:  "instanceId: ${_env.POD_NAME}\nbalancers: [cooperativeSticky, range]\nsessionTimeout: 1m\nrebalanceTimeout: 2m",
+		},
+		{
+			name:    "unknown balancer",
+			raw:     "balancers: [cooperativeSticky, unknown]",
+			wantErr: "kafka group balancer unknown is not supported",
+		},
+		{
+			name: "min session timeout",
+			raw:  "sessionTimeout: 6s",
+		},
+		{
+			name:    "session timeout too short",
+			raw:     "sessionTimeout: 5s",
+			wantErr: "sessionTimeout should be between 6s and 30m0s",
+		},
+		{
+			name:    "session timeout too long",
+			raw:     "sessionTimeout: 31m",
+			wantErr: "sessionTimeout should be between 6s and 30m0s",
+		},
+		{
+			name:    "negative rebalance timeout",
+			raw:     "rebalanceTimeout: -1s",
+			wantErr: "rebalanceTimeout should not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := "brokers: [\"127.0.0.1:9092\"]\ntopic: loggie\n" + tt.raw
+			err := cfg.UnPackFromRaw([]byte(raw), &Config{}).Defaults().Validate().Do()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -108,9 +108,25 @@ func (k *Source) Start() error {
 		opts = append(opts, kgo.ConsumerGroup(k.config.GroupId))
 	}
 
-	// set client id
-	if k.config.ClientId != "" {
-		opts = append(opts, kgo.InstanceID(k.config.ClientId))
+	// static membership
+	instanceId, err := k.config.instanceId()
+	if err != nil {
+		return err
+	}
+	if instanceId != "" {
+		opts = append(opts, kgo.InstanceID(instanceId))
+	}
+
+	if balancers := groupBalancers(k.config.Balancers); len(balancers) > 0 {
+		opts = append(opts, kgo.Balancers(balancers...))
+	}
+
+	if k.config.SessionTimeout != 0 {
+		opts = append(opts, kgo.SessionTimeout(k.config.SessionTimeout))
+	}
+
+	if k.config.RebalanceTimeout != 0 {
+		opts = append(opts, kgo.RebalanceTimeout(k.config.RebalanceTimeout))
 	}
 
 	if k.config.FetchMaxWait != 0 {
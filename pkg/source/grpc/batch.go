@@ -150,8 +150,8 @@ func (bc *batchChain) ack(events []api.Event) {
 	bc.ackEvents <- events
 }
 
+// run is started after countDown.Add(1), so stop waits for it even if it has not been scheduled yet
 func (bc *batchChain) run() {
-	bc.countDown.Add(1)
 	bs := make(map[uint32]*batch)
 	ticker := time.NewTicker(bc.maintenanceInterval)
 	defer func() {
@@ -16,7 +16,20 @@ limitations under the License.
 
 package grpc
 
-import "time"
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/envelope"
+)
+
+const (
+	ClientAuthNone             = "none"
+	ClientAuthVerifyIfGiven    = "verifyIfGiven"
+	ClientAuthRequireAndVerify = "requireAndVerify"
+)
 
 type Config struct {
 	Network             string        `yaml:"network" default:"tcp"`
@@ -24,4 +37,65 @@ type Config struct {
 	Port                string        `yaml:"port" default:"6066"`
 	Timeout             time.Duration `yaml:"timeout" default:"20s"`
 	MaintenanceInterval time.Duration `yaml:"maintenanceInterval,omitempty" default:"30s"`
+	TLS                 TLS           `yaml:"tls,omitempty"`
+	Dedup               Dedup         `yaml:"dedup,omitempty"`
+	// Envelope is accepted from the grpc sinks, the batches verified against it, 2 by default
+	Envelope envelope.Config `yaml:"envelope,omitempty"`
+}
+
+// TLS enables tls on the grpc server, and mutual tls when caCertFiles is set.
+// The certificate and ca files are reloaded when rotated, the streams compressed by the clients
+// with gzip or zstd are always accepted.
+type TLS struct {
+	Enabled        bool          `yaml:"enabled,omitempty"`
+	CertFile       string        `yaml:"certFile,omitempty"`
+	KeyFile        string        `yaml:"keyFile,omitempty"`
+	CaCertFiles    string        `yaml:"caCertFiles,omitempty"`
+	ClientAuth     string        `yaml:"clientAuth,omitempty" default:"requireAndVerify" validate:"oneof=none verifyIfGiven requireAndVerify"`
+	ReloadInterval time.Duration `yaml:"reloadInterval,omitempty" default:"1m"`
+}
+
+// Dedup discards the batches retransmitted by the grpc sinks, which are identified by the session id and
+// sequence number in the stream metadata. The acked batches of the last window are remembered for each session,
+// and a session is forgotten after it has not sent any batch for sessionTimeout.
+type Dedup struct {
+	Enabled        *bool         `yaml:"enabled,omitempty" default:"true"`
+	Window         int           `yaml:"window,omitempty" default:"10000" validate:"gte=1"`
+	SessionTimeout time.Duration `yaml:"sessionTimeout,omitempty" default:"1h"`
+}
+
+func (c *Config) SetDefaults() {
+	if c.Envelope.Version == 0 {
+		c.Envelope.Version = envelope.LatestVersion
+	}
+}
+
+func (c *Config) Validate() error {
+	if err := c.Envelope.Validate(); err != nil {
+		return err
+	}
+	return c.TLS.Validate()
+}
+
+func (t *TLS) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		return errors.New("certFile and keyFile are required when tls is enabled")
+	}
+	if t.ClientAuth != ClientAuthNone && t.CaCertFiles == "" {
+		return errors.Errorf("caCertFiles is required when clientAuth is %s", t.ClientAuth)
+	}
+	return nil
+}
+
+func (t *TLS) clientAuthType() tls.ClientAuthType {
+	switch t.ClientAuth {
+	case ClientAuthVerifyIfGiven:
+		return tls.VerifyClientCertIfGiven
+	case ClientAuthRequireAndVerify:
+		return tls.RequireAndVerifyClientCert
+	}
+	return tls.NoClientCert
 }
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	pb "github.com/loggie-io/loggie/pkg/sink/grpc/pb"
+	grpcutil "github.com/loggie-io/loggie/pkg/util/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type session struct {
+	lastSeen time.Time
+	inflight map[uint64]*batch
+	// completed holds the event count of the recently acked batches, order is used to evict the oldest ones
+	completed map[uint64]int32
+	order     []uint64
+}
+
+// dedup remembers the batches of each sink session which have been acked, so the batches retransmitted
+// by the sinks after a lost response are replied without being produced again.
+// Only the latest window batches of a session are remembered, older ones are always accepted.
+type dedup struct {
+	lock      sync.Mutex
+	window    int
+	timeout   time.Duration
+	lastSweep time.Time
+	sessions  map[string]*session
+}
+
+func newDedup(window int, timeout time.Duration) *dedup {
+	return &dedup{
+		window:    window,
+		timeout:   timeout,
+		lastSweep: time.Now(),
+		sessions:  make(map[string]*session),
+	}
+}
+
+// batchId returns the session id and sequence number of the stream, which are absent for the sinks
+// of the older versions
+func batchId(md metadata.MD) (string, uint64, bool) {
+	ids := md.Get(grpcutil.SessionMetadataKey)
+	seqs := md.Get(grpcutil.BatchSeqMetadataKey)
+	if len(ids) == 0 || ids[0] == "" || len(seqs) == 0 {
+		return "", 0, false
+	}
+	seq, err := strconv.ParseUint(seqs[0], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return ids[0], seq, true
+}
+
+// begin registers the batch as in flight. It returns the response to reply when the batch has already been acked,
+// or the in flight batch with the same sequence number, which should be waited before calling begin again.
+func (d *dedup) begin(id string, seq uint64, b *batch) (*pb.LogResp, *batch) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	now := time.Now()
+	d.sweep(now)
+	s, ok := d.sessions[id]
+	if !ok {
+		s = &session{
+			inflight:  make(map[uint64]*batch),
+			completed: make(map[uint64]int32),
+		}
+		d.sessions[id] = s
+	}
+	s.lastSeen = now
+
+	if count, ok := s.completed[seq]; ok {
+		return &pb.LogResp{
+			Success: true,
+			Count:   count,
+		}, nil
+	}
+	if prev, ok := s.inflight[seq]; ok {
+		return nil, prev
+	}
+	s.inflight[seq] = b
+	return nil, nil
+}
+
+// finish removes the batch from in flight, and remembers it when acked successfully.
+// It may be called by both the stream of the batch and the streams waiting for it.
+func (d *dedup) finish(id string, seq uint64, b *batch, resp *pb.LogResp) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	s, ok := d.sessions[id]
+	if !ok || s.inflight[seq] != b {
+		return
+	}
+	delete(s.inflight, seq)
+	if !resp.Success {
+		return
+	}
+	s.completed[seq] = resp.Count
+	s.order = append(s.order, seq)
+	for len(s.order) > d.window {
+		delete(s.completed, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// sweep removes the sessions without any batch longer than the timeout, the sink of which was stopped or restarted
+func (d *dedup) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.timeout {
+		return
+	}
+	d.lastSweep = now
+	for id, s := range d.sessions {
+		if len(s.inflight) == 0 && now.Sub(s.lastSeen) > d.timeout {
+			delete(d.sessions, id)
+		}
+	}
+}
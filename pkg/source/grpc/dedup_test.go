@@ -0,0 +1,198 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/loggie-io/loggie/pkg/sink/grpc/pb"
+	grpcutil "github.com/loggie-io/loggie/pkg/util/grpc"
+)
+
+func acked(count int32) *pb.LogResp {
+	return &pb.LogResp{Success: true, Count: count}
+}
+
+func failed() *pb.LogResp {
+	return &pb.LogResp{Success: false, ErrorMsg: "ack timeout"}
+}
+
+func TestBatchId(t *testing.T) {
+	tests := []struct {
+		name    string
+		md      metadata.MD
+		wantId  string
+		wantSeq uint64
+		wantOk  bool
+	}{
+		{
+			name:    "ok",
+			md:      metadata.Pairs(grpcutil.SessionMetadataKey, "s1", grpcutil.BatchSeqMetadataKey, "42"),
+			wantId:  "s1",
+			wantSeq: 42,
+			wantOk:  true,
+		},
+		{
+			name: "older sink",
+			md:   metadata.MD{},
+		},
+		{
+			name: "invalid seq",
+			md:   metadata.Pairs(grpcutil.SessionMetadataKey, "s1", grpcutil.BatchSeqMetadataKey, "x"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, seq, ok := batchId(tt.md)
+			assert.Equal(t, tt.wantId, id)
+			assert.Equal(t, tt.wantSeq, seq)
+			assert.Equal(t, tt.wantOk, ok)
+		})
+	}
+}
+
+func TestDedup_Replay(t *testing.T) {
+	tests := []struct {
+		name string
+		// the response of the original batch
+		resp *pb.LogResp
+		// the retransmitted batch is replied without being produced
+		wantReplied *pb.LogResp
+	}{
+		{
+			name:        "acked batch is replayed",
+			resp:        acked(3),
+			wantReplied: acked(3),
+		},
+		{
+			name: "failed batch is produced again",
+			resp: failed(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDedup(16, time.Minute)
+			b := newBatch(time.Minute)
+			resp, prev := d.begin("s1", 1, b)
+			assert.Nil(t, resp)
+			assert.Nil(t, prev)
+			d.finish("s1", 1, b, tt.resp)
+
+			retry := newBatch(time.Minute)
+			resp, prev = d.begin("s1", 1, retry)
+			assert.Equal(t, tt.wantReplied, resp)
+			assert.Nil(t, prev)
+
+			// the same sequence number of another session is not a retransmission
+			resp, prev = d.begin("s2", 1, newBatch(time.Minute))
+			assert.Nil(t, resp)
+			assert.Nil(t, prev)
+		})
+	}
+}
+
+func TestDedup_InflightDuplicate(t *testing.T) {
+	for _, original := range []*pb.LogResp{acked(2), failed()} {
+		d := newDedup(16, time.Minute)
+		b := newBatch(time.Minute)
+		resp, prev := d.begin("s1", 1, b)
+		assert.Nil(t, resp)
+		assert.Nil(t, prev)
+
+		// the retransmitted batch waits for the original one like Source.produce
+		retry := newBatch(time.Minute)
+		result := make(chan *pb.LogResp)
+		go func() {
+			for {
+				resp, prev := d.begin("s1", 1, retry)
+				if prev == nil {
+					result <- resp
+					return
+				}
+				d.finish("s1", 1, prev, prev.wait())
+			}
+		}()
+
+		select {
+		case <-result:
+			t.Fatal("the duplicate should wait for the original batch")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		b.resp = original
+		b.done()
+		d.finish("s1", 1, b, b.wait())
+
+		select {
+		case resp := <-result:
+			if original.Success {
+				assert.Equal(t, original, resp)
+			} else {
+				// the duplicate is registered to be produced again
+				assert.Nil(t, resp)
+				assert.Equal(t, retry, d.sessions["s1"].inflight[1])
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the duplicate is not released")
+		}
+	}
+}
+
+func TestDedup_WindowEviction(t *testing.T) {
+	d := newDedup(2, time.Minute)
+	for seq := uint64(1); seq <= 3; seq++ {
+		b := newBatch(time.Minute)
+		d.begin("s1", seq, b)
+		d.finish("s1", seq, b, acked(int32(seq)))
+	}
+
+	// only the latest window batches are remembered
+	resp, _ := d.begin("s1", 1, newBatch(time.Minute))
+	assert.Nil(t, resp)
+	resp, _ = d.begin("s1", 2, newBatch(time.Minute))
+	assert.Equal(t, acked(2), resp)
+	resp, _ = d.begin("s1", 3, newBatch(time.Minute))
+	assert.Equal(t, acked(3), resp)
+	assert.Len(t, d.sessions["s1"].completed, 2)
+}
+
+func TestDedup_Sweep(t *testing.T) {
+	d := newDedup(16, time.Minute)
+	idle := newBatch(time.Minute)
+	d.begin("idle", 1, idle)
+	d.finish("idle", 1, idle, acked(1))
+	d.begin("inflight", 1, newBatch(time.Minute))
+
+	past := time.Now().Add(-time.Hour)
+	d.lastSweep = past
+	d.sessions["idle"].lastSeen = past
+	d.sessions["inflight"].lastSeen = past
+
+	// the sweep is triggered by the batches of any session
+	d.begin("active", 1, newBatch(time.Minute))
+	assert.NotContains(t, d.sessions, "idle")
+	assert.Contains(t, d.sessions, "inflight")
+	assert.Contains(t, d.sessions, "active")
+
+	// the session restarted after swept accepts the old sequence numbers
+	resp, _ := d.begin("idle", 1, newBatch(time.Minute))
+	assert.Nil(t, resp)
+}
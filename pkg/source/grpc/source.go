@@ -17,6 +17,7 @@ limitations under the License.
 package grpc
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -26,9 +27,16 @@ import (
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/pipeline"
 	pb "github.com/loggie-io/loggie/pkg/sink/grpc/pb"
+	"github.com/loggie-io/loggie/pkg/util/envelope"
 	"github.com/loggie-io/loggie/pkg/util/json"
 	"github.com/pkg/errors"
+	// registers the gzip and zstd compressors, which are used per stream as requested by the sinks
+	_ "github.com/loggie-io/loggie/pkg/util/grpc"
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+	"github.com/loggie-io/loggie/pkg/util/priority"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 )
 
 const Type = "grpc"
@@ -51,6 +59,8 @@ type Source struct {
 	config     *Config
 	grpcServer *grpc.Server
 	bc         *batchChain
+	dedup      *dedup
+	offer      envelope.Offer
 }
 
 func (s *Source) Config() interface{} {
@@ -71,6 +81,7 @@ func (s *Source) String() string {
 
 func (s *Source) Init(context api.Context) error {
 	s.name = context.Name()
+	s.offer = s.config.Envelope.Offer()
 	return nil
 }
 
@@ -90,14 +101,27 @@ func (s *Source) Stop() {
 func (s *Source) ProductLoop(productFunc api.ProductFunc) {
 	log.Info("%s start product loop", s.String())
 	s.bc = newBatchChain(productFunc, s.config.MaintenanceInterval)
+	s.bc.countDown.Add(1)
 	go s.bc.run()
+	if d := s.config.Dedup; d.Enabled != nil && *d.Enabled {
+		s.dedup = newDedup(d.Window, d.SessionTimeout)
+	}
 	// start grpc server
 	ip := fmt.Sprintf("%s:%s", s.config.Bind, s.config.Port)
 	listener, err := net.Listen(s.config.Network, ip)
 	if err != nil {
 		log.Panic("grpc server listen ip(%s) err: %v", ip, err)
 	}
-	grpcServer := grpc.NewServer()
+	var opts []grpc.ServerOption
+	if s.config.TLS.Enabled {
+		t := s.config.TLS
+		reloader, err := netutils.NewCertReloader(t.CertFile, t.KeyFile, t.CaCertFiles, t.ReloadInterval)
+		if err != nil {
+			log.Panic("grpc server load tls certificates err: %v", err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(reloader.ServerTLSConfig(t.clientAuthType()))))
+	}
+	grpcServer := grpc.NewServer(opts...)
 	pb.RegisterLogServiceServer(grpcServer, s)
 	go grpcServer.Serve(listener)
 	s.grpcServer = grpcServer
@@ -110,7 +134,11 @@ func (s *Source) Commit(events []api.Event) {
 }
 
 func (s *Source) LogStream(ls pb.LogService_LogStreamServer) error {
+	md, _ := metadata.FromIncomingContext(ls.Context())
+	s.accept(ls, md)
+
 	b := newBatch(s.config.Timeout)
+	sum := envelope.NewChecksum()
 	for {
 		logMsg, err := ls.Recv()
 		if errors.Is(err, io.EOF) {
@@ -144,10 +172,20 @@ func (s *Source) LogStream(ls pb.LogService_LogStreamServer) error {
 		e := s.eventPool.Get()
 		e.Fill(e.Meta(), header, logMsg.GetRawLog())
 		b.append(e)
+		sum.Add(logMsg.GetRawLog(), packedHeader)
+	}
+	if err := s.openEnvelope(md, b, sum); err != nil {
+		log.Warn("[%s] reject batch: %v", s.name, err)
+		for _, e := range b.events {
+			s.eventPool.Put(e)
+		}
+		return ls.SendAndClose(&pb.LogResp{
+			Success:  false,
+			ErrorMsg: err.Error(),
+		})
 	}
 	if b.size() > 0 {
-		s.bc.append(b)
-		logResp := b.wait()
+		logResp := s.produce(ls.Context(), b)
 		err := ls.SendAndClose(logResp)
 		if err != nil {
 			log.Error("send response fail: %s", err)
@@ -159,3 +197,81 @@ func (s *Source) LogStream(ls pb.LogService_LogStreamServer) error {
 		Count:   0,
 	})
 }
+
+// accept replies the envelope agreed with the offer of the grpc sink, the older sinks offer nothing
+func (s *Source) accept(ls pb.LogService_LogStreamServer, md metadata.MD) {
+	offer := md.Get(envelope.OfferKey)
+	if len(offer) == 0 {
+		return
+	}
+	remote, err := envelope.ParseOffer(offer[0])
+	if err != nil {
+		log.Warn("[%s] parse offered envelope error: %v", s.name, err)
+		return
+	}
+	agreed := envelope.Negotiate(s.offer, remote)
+	if err := ls.SetHeader(metadata.Pairs(envelope.AcceptKey, agreed.String())); err != nil {
+		log.Warn("[%s] set accepted envelope error: %v", s.name, err)
+	}
+}
+
+// openEnvelope verifies the batch by the envelope used by the grpc sink, and restores the priorities of the events
+func (s *Source) openEnvelope(md metadata.MD, b *batch, sum *envelope.Checksum) error {
+	env, err := envelope.Parse(func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	})
+	if err != nil || env == nil {
+		return err
+	}
+	if err := envelope.Accept(s.offer, env.Offer); err != nil {
+		return err
+	}
+	if env.Has(envelope.CapabilityChecksum) && env.Checksum != sum.String() {
+		return errors.Errorf("envelope checksum mismatch, expect %s but got %s", env.Checksum, sum.String())
+	}
+	if env.Has(envelope.CapabilityPriority) {
+		levels, err := envelope.DecodePriorities(env.Priorities, b.size())
+		if err != nil {
+			return err
+		}
+		for i, l := range levels {
+			priority.Set(b.events[int32(i)], l)
+		}
+	}
+	return nil
+}
+
+// produce sends the batch to the pipeline and waits for the acks,
+// a batch retransmitted by the sink is replied with the response of the original one instead
+func (s *Source) produce(ctx context.Context, b *batch) *pb.LogResp {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id, seq, ok := batchId(md)
+	if s.dedup == nil || !ok {
+		s.bc.append(b)
+		return b.wait()
+	}
+
+	for {
+		resp, prev := s.dedup.begin(id, seq, b)
+		if resp != nil {
+			log.Info("[%s] discard %d events of batch %d retransmitted by session %s", s.name, b.size(), seq, id)
+			for _, e := range b.events {
+				s.eventPool.Put(e)
+			}
+			return resp
+		}
+		if prev == nil {
+			break
+		}
+		// the original batch is still waiting for the acks, it would be produced again if failed
+		s.dedup.finish(id, seq, prev, prev.wait())
+	}
+
+	s.bc.append(b)
+	resp := b.wait()
+	s.dedup.finish(id, seq, b, resp)
+	return resp
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	corebatch "github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	grpcsink "github.com/loggie-io/loggie/pkg/sink/grpc"
+)
+
+// writeCert signs a certificate by the parent, or a self-signed ca if parent is nil, and writes name.crt and name.key
+func writeCert(t *testing.T, dir string, name string, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template.Subject = pkix.Name{CommonName: name}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert, key
+}
+
+func writeCerts(t *testing.T) string {
+	dir := t.TempDir()
+	ca, caKey := writeCert(t, dir, "ca", &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	writeCert(t, dir, "server", &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	writeCert(t, dir, "client", &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+	return dir
+}
+
+func freePort(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
+
+func TestLogStream(t *testing.T) {
+	log.InitDefaultLogger()
+	dir := writeCerts(t)
+	serverTLS := `
+tls:
+  enabled: true
+  certFile: ` + filepath.Join(dir, "server.crt") + `
+  keyFile: ` + filepath.Join(dir, "server.key") + `
+  caCertFiles: ` + filepath.Join(dir, "ca.crt")
+	clientTLS := `
+tls:
+  enabled: true
+  caCertFiles: ` + filepath.Join(dir, "ca.crt") + `
+  serverName: localhost`
+	clientCert := `
+  clientCertFile: ` + filepath.Join(dir, "client.crt") + `
+  clientKeyFile: ` + filepath.Join(dir, "client.key")
+
+	tests := []struct {
+		name   string
+		source string
+		sink   string
+		wantOk bool
+	}{
+		{
+			name:   "mutual tls",
+			source: serverTLS,
+			sink:   clientTLS + clientCert,
+			wantOk: true,
+		},
+		{
+			name:   "client certificate required",
+			source: serverTLS,
+			sink:   clientTLS,
+		},
+		{
+			name:   "gzip over mutual tls",
+			source: serverTLS,
+			sink:   clientTLS + clientCert + "\ncompression: gzip",
+			wantOk: true,
+		},
+		{
+			name:   "zstd",
+			sink:   "compression: zstd",
+			wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := freePort(t)
+			src := makeSource(pipeline.Info{EventPool: event.NewDefaultPool(10)}).(*Source)
+			assert.NoError(t, cfg.UnPackFromRaw([]byte("bind: 127.0.0.1\nport: \""+port+"\"\n"+tt.source), src.Config()).Defaults().Validate().Do())
+			assert.NoError(t, src.Init(context.NewContext("grpc", Type, api.SOURCE, nil)))
+			received := make(chan string, 1)
+			src.ProductLoop(func(e api.Event) api.Result {
+				received <- string(e.Body())
+				go src.Commit([]api.Event{e})
+				return result.Success()
+			})
+			defer src.Stop()
+
+			sink := grpcsink.NewSink(pipeline.Info{})
+			raw := "host: 127.0.0.1:" + port + "\ntimeout: 1s\n" + tt.sink
+			assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), sink.Config()).Defaults().Validate().Do())
+			assert.NoError(t, sink.Init(context.NewContext("grpc", grpcsink.Type, api.SINK, nil)))
+			assert.NoError(t, sink.Start())
+			defer sink.Stop()
+
+			res := sink.Consume(corebatch.NewBatchWithEvents([]api.Event{event.NewEvent(map[string]interface{}{}, []byte("hello"))}))
+			if !tt.wantOk {
+				assert.Error(t, res.Error())
+				return
+			}
+			assert.NoError(t, res.Error())
+			assert.Equal(t, "hello", <-received)
+		})
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/segmentio/kafka-go"
 
 	kafkaSink "github.com/loggie-io/loggie/pkg/sink/kafka"
+	"github.com/loggie-io/loggie/pkg/util/envelope"
 )
 
 const (
@@ -50,7 +51,10 @@ type Config struct {
 	AutoCommitInterval time.Duration  `yaml:"autoCommitInterval" default:"1s"`
 	AutoOffsetReset    string         `yaml:"autoOffsetReset" default:"latest" validate:"oneof=earliest latest"`
 	SASL               kafkaSink.SASL `yaml:"sasl,omitempty"`
+	TLS                TLS            `yaml:"tls,omitempty"`
 	AddonMeta          *bool          `yaml:"addonMeta,omitempty" default:"true"`
+	// Envelope verifies the records by the envelope headers added by the kafka sinks, disabled by default
+	Envelope envelope.Config `yaml:"envelope,omitempty"`
 }
 
 func getAutoOffset(autoOffsetReset string) int64 {
@@ -71,6 +75,9 @@ func (c *Config) SetDefaults() {
 }
 
 func (c *Config) Validate() error {
+	if err := c.Envelope.Validate(); err != nil {
+		return err
+	}
 	if c.Topic == "" && len(c.Topics) == 0 {
 		return errors.New("topic or topics is required")
 	}
@@ -94,5 +101,9 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,6 +34,8 @@ import (
 	"github.com/loggie-io/loggie/pkg/core/log"
 	"github.com/loggie-io/loggie/pkg/pipeline"
 	kafkaSink "github.com/loggie-io/loggie/pkg/sink/kafka"
+	"github.com/loggie-io/loggie/pkg/util/envelope"
+	"github.com/loggie-io/loggie/pkg/util/priority"
 )
 
 const (
@@ -64,6 +67,7 @@ type Source struct {
 	config    *Config
 	consumer  *kafka.Reader
 	eventPool *event.Pool
+	envelope  envelope.Offer
 }
 
 func (k *Source) Config() interface{} {
@@ -84,16 +88,21 @@ func (k *Source) String() string {
 
 func (k *Source) Init(context api.Context) error {
 	k.name = context.Name()
+	k.envelope = k.config.Envelope.Offer()
 	return nil
 }
 
 func (k *Source) Start() error {
 	c := k.config
-	mechanism, err := kafkaSink.Mechanism(c.SASL.Type, c.SASL.Username, c.SASL.Password, c.SASL.Algorithm)
+	mechanism, err := kafkaSink.NewMechanism(c.SASL)
 	if err != nil {
 		log.Error("kafka source sasl mechanism with error: %s", err.Error())
 		return err
 	}
+	tlsConfig, err := c.TLS.tlsConfig()
+	if err != nil {
+		return errors.WithMessage(err, "kafka source tls config")
+	}
 
 	client := &kafka.Client{
 		Addr: kafka.TCP(k.config.Brokers...),
@@ -102,6 +111,7 @@ func (k *Source) Start() error {
 				Timeout: 3 * time.Second,
 			}).DialContext,
 			SASL: mechanism,
+			TLS:  tlsConfig,
 		},
 	}
 
@@ -141,6 +151,7 @@ func (k *Source) Start() error {
 		Timeout:       10 * time.Second,
 		DualStack:     true,
 		SASLMechanism: mechanism,
+		TLS:           tlsConfig,
 	}
 	if k.config.ClientId != "" {
 		dial.ClientID = k.config.ClientId
@@ -231,6 +242,9 @@ func (k *Source) consume(productFunc api.ProductFunc) error {
 			fTopic:     msg.Topic,
 		}
 		for _, h := range msg.Headers {
+			if k.envelope.Version > envelope.Version1 && strings.HasPrefix(h.Key, envelope.Key) {
+				continue
+			}
 			header[h.Key] = string(h.Value)
 		}
 	}
@@ -253,11 +267,52 @@ func (k *Source) consume(productFunc api.ProductFunc) error {
 	}
 
 	e.Fill(meta, header, msg.Value)
+	if k.envelope.Version > envelope.Version1 {
+		k.openEnvelope(msg, e)
+	}
 
 	productFunc(e)
 	return nil
 }
 
+// openEnvelope verifies the record by the envelope headers and restores the priority of the event,
+// the records failed to verify are still consumed with a warning, as they could not be rejected back to the producers
+func (k *Source) openEnvelope(msg kafka.Message, e api.Event) {
+	env, err := envelope.Parse(func(key string) string {
+		for _, h := range msg.Headers {
+			if h.Key == key {
+				return string(h.Value)
+			}
+		}
+		return ""
+	})
+	if err == nil && env != nil {
+		err = envelope.Accept(k.envelope, env.Offer)
+	}
+	if err != nil || env == nil {
+		if err != nil {
+			log.Warn("[%s] open envelope of record %s/%d/%d error: %v", k.name, msg.Topic, msg.Partition, msg.Offset, err)
+		}
+		return
+	}
+
+	if env.Has(envelope.CapabilityChecksum) {
+		sum := envelope.NewChecksum()
+		sum.Add(msg.Value)
+		if env.Checksum != sum.String() {
+			log.Warn("[%s] envelope checksum of record %s/%d/%d mismatch, expect %s but got %s", k.name, msg.Topic, msg.Partition, msg.Offset, env.Checksum, sum.String())
+		}
+	}
+	if env.Has(envelope.CapabilityPriority) {
+		levels, err := envelope.DecodePriorities(env.Priorities, 1)
+		if err != nil {
+			log.Warn("[%s] envelope priority of record %s/%d/%d error: %v", k.name, msg.Topic, msg.Partition, msg.Offset, err)
+			return
+		}
+		priority.Set(e, levels[0])
+	}
+}
+
 func (k *Source) Commit(events []api.Event) {
 	// commit when sink ack
 	if !k.config.EnableAutoCommit {
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+)
+
+type TLS struct {
+	Enabled            bool   `yaml:"enabled,omitempty"`
+	CaCertFiles        string `yaml:"caCertFiles,omitempty"`
+	ClientCertFile     string `yaml:"clientCertFile,omitempty"`
+	ClientKeyFile      string `yaml:"clientKeyFile,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+	// Secret loads the certificates from a kubernetes secret instead of files
+	Secret *TLSSecret `yaml:"secret,omitempty"`
+}
+
+type TLSSecret struct {
+	Name       string `yaml:"name,omitempty" validate:"required"`
+	Namespace  string `yaml:"namespace,omitempty" default:"default"`
+	CaKey      string `yaml:"caKey,omitempty" default:"ca.crt"`
+	CertKey    string `yaml:"certKey,omitempty" default:"tls.crt"`
+	KeyKey     string `yaml:"keyKey,omitempty" default:"tls.key"`
+	KubeConfig string `yaml:"kubeconfig,omitempty"`
+	Master     string `yaml:"master,omitempty"`
+}
+
+func (t *TLS) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+	if (t.ClientCertFile == "") != (t.ClientKeyFile == "") {
+		return errors.New("kafka source tls clientCertFile and clientKeyFile should be set together")
+	}
+	if t.Secret != nil && (t.CaCertFiles != "" || t.ClientCertFile != "") {
+		return errors.New("kafka source tls certificates cannot be loaded from both files and secret")
+	}
+	return nil
+}
+
+// tlsConfig returns nil when tls is not enabled
+func (t *TLS) tlsConfig() (*tls.Config, error) {
+	if !t.Enabled {
+		return nil, nil
+	}
+	if t.Secret == nil {
+		return netutils.NewTLSConfig(t.CaCertFiles, t.ClientCertFile, t.ClientKeyFile, t.InsecureSkipVerify)
+	}
+
+	s := t.Secret
+	config, err := clientcmd.BuildConfigFromFlags(s.Master, s.KubeConfig)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build kubernetes config")
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build kubernetes clientSet")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	secret, err := client.CoreV1().Secrets(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get secret %s/%s", s.Namespace, s.Name)
+	}
+	return netutils.NewTLSConfigFromPEM(secret.Data[s.CaKey], secret.Data[s.CertKey], secret.Data[s.KeyKey], t.InsecureSkipVerify)
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+)
+
+const subscribePacketId = 1
+
+// conn is a single connection to the broker, a new conn is created after reconnecting
+type conn struct {
+	config *Config
+	nc     net.Conn
+	r      *bufio.Reader
+
+	writeLock sync.Mutex
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func dial(ctx context.Context, config *Config, clientId string) (*conn, error) {
+	u, err := url.Parse(config.Broker)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.ConnectTimeout)
+	defer cancel()
+
+	var nc net.Conn
+	dialer := &net.Dialer{}
+	if config.isTLS() {
+		tlsConfig, err := netutils.NewTLSConfig(config.TLS.CaCertFiles, config.TLS.ClientCertFile, config.TLS.ClientKeyFile, config.TLS.InsecureSkipVerify)
+		if err != nil {
+			return nil, errors.WithMessage(err, "load tls config")
+		}
+		tlsConfig.ServerName = u.Hostname()
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", u.Host)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", u.Host)
+	}
+	if err != nil {
+		return nil, errors.WithMessagef(err, "dial mqtt broker %s", config.Broker)
+	}
+
+	c := &conn{
+		config: config,
+		nc:     nc,
+		r:      bufio.NewReader(nc),
+		closed: make(chan struct{}),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = nc.SetDeadline(deadline)
+	}
+	if err := c.handshake(clientId); err != nil {
+		c.close()
+		return nil, err
+	}
+	_ = nc.SetDeadline(time.Time{})
+	return c, nil
+}
+
+func (c *conn) handshake(clientId string) error {
+	opts := connectOptions{
+		version:      c.config.ProtocolVersion,
+		clientId:     clientId,
+		username:     c.config.Username,
+		password:     c.config.Password,
+		cleanSession: *c.config.CleanSession,
+		keepAlive:    uint16(c.config.KeepAlive / time.Second),
+	}
+	if !opts.cleanSession {
+		opts.sessionExpiry = uint32(c.config.SessionExpiry / time.Second)
+	}
+	if err := c.write(packetConnect, 0, encodeConnect(opts)); err != nil {
+		return errors.WithMessage(err, "send connect")
+	}
+
+	p, err := readPacket(c.r)
+	if err != nil {
+		return errors.WithMessage(err, "read connack")
+	}
+	if p.typ != packetConnAck {
+		return errors.Errorf("expected connack, got packet type %d", p.typ)
+	}
+	_, code, err := decodeConnAck(p.body)
+	if err != nil {
+		return errors.WithMessage(err, "decode connack")
+	}
+	if code != 0 {
+		return errors.Errorf("connection refused by broker, code: %d", code)
+	}
+
+	// suback is checked in the read loop since queued messages of a persistent session may arrive first
+	subscribe := encodeSubscribe(c.config.ProtocolVersion, subscribePacketId, c.config.subscriptions(), c.config.QoS)
+	if err := c.write(packetSubscribe, 0x02, subscribe); err != nil {
+		return errors.WithMessage(err, "send subscribe")
+	}
+	return nil
+}
+
+func (c *conn) write(typ byte, flags byte, body []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return writePacket(c.nc, typ, flags, body)
+}
+
+func (c *conn) pubAck(packetId uint16) error {
+	select {
+	case <-c.closed:
+		// the broker would redeliver the message after reconnecting
+		return errors.New("connection closed")
+	default:
+	}
+	return c.write(packetPubAck, 0, encodePubAck(packetId))
+}
+
+// keepAlive sends pingreq periodically until the conn is closed
+func (c *conn) keepAlive() {
+	if c.config.KeepAlive <= 0 {
+		return
+	}
+	t := time.NewTicker(c.config.KeepAlive)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-t.C:
+			if err := c.write(packetPingReq, 0, nil); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// readLoop reads packets until the connection is broken, publish packets are handled by onPublish
+func (c *conn) readLoop(onPublish func(*publish)) error {
+	for {
+		if c.config.KeepAlive > 0 {
+			_ = c.nc.SetReadDeadline(time.Now().Add(c.config.KeepAlive * 3 / 2))
+		}
+		p, err := readPacket(c.r)
+		if err != nil {
+			return err
+		}
+
+		switch p.typ {
+		case packetPublish:
+			pub, err := decodePublish(c.config.ProtocolVersion, p)
+			if err != nil {
+				return errors.WithMessage(err, "decode publish")
+			}
+			onPublish(pub)
+
+		case packetSubAck:
+			_, codes, err := decodeSubAck(c.config.ProtocolVersion, p.body)
+			if err != nil {
+				return errors.WithMessage(err, "decode suback")
+			}
+			subs := c.config.subscriptions()
+			for i, code := range codes {
+				if code >= 0x80 && i < len(subs) {
+					return errors.Errorf("subscribe %s failed, code: %d", subs[i], code)
+				}
+			}
+
+		case packetPingResp:
+
+		case packetDisconnect:
+			return errors.New("disconnected by broker")
+
+		default:
+			return errors.Errorf("unexpected packet type %d", p.typ)
+		}
+	}
+}
+
+func (c *conn) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		_ = c.nc.SetWriteDeadline(time.Now().Add(time.Second))
+		_ = c.write(packetDisconnect, 0, nil)
+		_ = c.nc.Close()
+	})
+}
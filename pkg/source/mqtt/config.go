@@ -0,0 +1,108 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ProtocolV311 = 4
+	ProtocolV5   = 5
+
+	sharePrefix = "$share/"
+)
+
+type Config struct {
+	// Broker address, e.g. tcp://localhost:1883, ssl://localhost:8883
+	Broker          string   `yaml:"broker,omitempty" validate:"required"`
+	ClientId        string   `yaml:"clientId,omitempty"`
+	Username        string   `yaml:"username,omitempty"`
+	Password        string   `yaml:"password,omitempty"`
+	ProtocolVersion int      `yaml:"protocolVersion,omitempty" default:"4" validate:"oneof=4 5"`
+	Topics          []string `yaml:"topics,omitempty" validate:"required"`
+	QoS             byte     `yaml:"qos,omitempty" default:"1" validate:"lte=1"`
+	// SharedGroup subscribes the topics as $share/<sharedGroup>/<topic>, so a group of loggie agents could share the load
+	SharedGroup  string `yaml:"sharedGroup,omitempty"`
+	CleanSession *bool  `yaml:"cleanSession,omitempty" default:"true"`
+	// SessionExpiry is only used in MQTT v5 when cleanSession is false
+	SessionExpiry     time.Duration `yaml:"sessionExpiry,omitempty" default:"1h"`
+	KeepAlive         time.Duration `yaml:"keepAlive,omitempty" default:"30s"`
+	ConnectTimeout    time.Duration `yaml:"connectTimeout,omitempty" default:"10s"`
+	ReconnectInterval time.Duration `yaml:"reconnectInterval,omitempty" default:"5s"`
+	TLS               TLS           `yaml:"tls,omitempty"`
+	AddonMeta         *bool         `yaml:"addonMeta,omitempty" default:"true"`
+}
+
+type TLS struct {
+	Enabled            bool   `yaml:"enabled,omitempty"`
+	CaCertFiles        string `yaml:"caCertFiles,omitempty"`
+	ClientCertFile     string `yaml:"clientCertFile,omitempty"`
+	ClientKeyFile      string `yaml:"clientKeyFile,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	u, err := url.Parse(c.Broker)
+	if err != nil {
+		return errors.WithMessagef(err, "parse mqtt broker %s", c.Broker)
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts":
+	default:
+		return errors.Errorf("unsupported mqtt broker scheme %s", u.Scheme)
+	}
+
+	for _, t := range c.Topics {
+		if t == "" {
+			return errors.New("mqtt topic cannot be empty")
+		}
+		if c.SharedGroup != "" && strings.HasPrefix(t, sharePrefix) {
+			return errors.Errorf("topic %s is already a shared subscription, sharedGroup should be empty", t)
+		}
+	}
+	if strings.ContainsAny(c.SharedGroup, "/+#") {
+		return errors.Errorf("sharedGroup %s cannot contain '/', '+' or '#'", c.SharedGroup)
+	}
+	if c.KeepAlive > 0xffff*time.Second {
+		return errors.New("keepAlive is too long")
+	}
+	return nil
+}
+
+func (c *Config) subscriptions() []string {
+	if c.SharedGroup == "" {
+		return c.Topics
+	}
+	subs := make([]string, 0, len(c.Topics))
+	for _, t := range c.Topics {
+		subs = append(subs, sharePrefix+c.SharedGroup+"/"+t)
+	}
+	return subs
+}
+
+func (c *Config) isTLS() bool {
+	if c.TLS.Enabled {
+		return true
+	}
+	u, _ := url.Parse(c.Broker)
+	return u != nil && (u.Scheme == "ssl" || u.Scheme == "tls" || u.Scheme == "mqtts")
+}
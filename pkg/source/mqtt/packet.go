@@ -0,0 +1,306 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// control packet types, see https://docs.oasis-open.org/mqtt/mqtt/v3.1.1/os/mqtt-v3.1.1-os.html#_Toc398718021
+const (
+	packetConnect    byte = 1
+	packetConnAck    byte = 2
+	packetPublish    byte = 3
+	packetPubAck     byte = 4
+	packetSubscribe  byte = 8
+	packetSubAck     byte = 9
+	packetPingReq    byte = 12
+	packetPingResp   byte = 13
+	packetDisconnect byte = 14
+
+	connectFlagCleanSession byte = 0x02
+	connectFlagPassword     byte = 0x40
+	connectFlagUsername     byte = 0x80
+
+	propSessionExpiryInterval byte = 0x11
+
+	maxRemainingLength = 268435455
+)
+
+type packet struct {
+	typ   byte
+	flags byte
+	body  []byte
+}
+
+type publish struct {
+	topic    string
+	qos      byte
+	retain   bool
+	dup      bool
+	packetId uint16
+	payload  []byte
+}
+
+func readPacket(r *bufio.Reader) (*packet, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxRemainingLength {
+		return nil, errors.Errorf("malformed remaining length %d", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &packet{typ: first >> 4, flags: first & 0x0f, body: body}, nil
+}
+
+func writePacket(w io.Writer, typ byte, flags byte, body []byte) error {
+	buf := make([]byte, 0, len(body)+5)
+	buf = append(buf, typ<<4|flags)
+	buf = appendVarInt(buf, len(body))
+	buf = append(buf, body...)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readVarInt(r io.ByteReader) (int, error) {
+	value := 0
+	for shift := 0; shift < 28; shift += 7 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, errors.New("malformed variable byte integer")
+}
+
+func appendVarInt(buf []byte, v int) []byte {
+	for {
+		b := byte(v % 128)
+		v /= 128
+		if v > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if v == 0 {
+			return buf
+		}
+	}
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	return append(buf, byte(v>>8), byte(v))
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = appendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+// decoder reads the fields of a packet body
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 1 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *decoder) uint16() uint16 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 2 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := binary.BigEndian.Uint16(d.buf)
+	d.buf = d.buf[2:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := int(d.uint16())
+	if d.err != nil {
+		return ""
+	}
+	if len(d.buf) < n {
+		d.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return s
+}
+
+func (d *decoder) ReadByte() (byte, error) {
+	b := d.byte()
+	return b, d.err
+}
+
+// skipProperties skips the MQTT v5 properties, none of them are needed by the source yet
+func (d *decoder) skipProperties() {
+	if d.err != nil {
+		return
+	}
+	n, err := readVarInt(d)
+	if err != nil {
+		d.err = err
+		return
+	}
+	if len(d.buf) < n {
+		d.err = io.ErrUnexpectedEOF
+		return
+	}
+	d.buf = d.buf[n:]
+}
+
+type connectOptions struct {
+	version       int
+	clientId      string
+	username      string
+	password      string
+	cleanSession  bool
+	keepAlive     uint16
+	sessionExpiry uint32
+}
+
+func encodeConnect(o connectOptions) []byte {
+	buf := appendString(nil, "MQTT")
+	buf = append(buf, byte(o.version))
+
+	var flags byte
+	if o.cleanSession {
+		flags |= connectFlagCleanSession
+	}
+	if o.username != "" {
+		flags |= connectFlagUsername
+	}
+	if o.password != "" {
+		flags |= connectFlagPassword
+	}
+	buf = append(buf, flags)
+	buf = appendUint16(buf, o.keepAlive)
+
+	if o.version == ProtocolV5 {
+		var props []byte
+		if o.sessionExpiry > 0 {
+			props = append(props, propSessionExpiryInterval)
+			props = appendUint32(props, o.sessionExpiry)
+		}
+		buf = appendVarInt(buf, len(props))
+		buf = append(buf, props...)
+	}
+
+	buf = appendString(buf, o.clientId)
+	if o.username != "" {
+		buf = appendString(buf, o.username)
+	}
+	if o.password != "" {
+		buf = appendString(buf, o.password)
+	}
+	return buf
+}
+
+// decodeConnAck returns the return code in v3.1.1 or the reason code in v5, 0 means success
+func decodeConnAck(body []byte) (sessionPresent bool, code byte, err error) {
+	d := &decoder{buf: body}
+	ackFlags := d.byte()
+	code = d.byte()
+	return ackFlags&0x01 == 1, code, d.err
+}
+
+func encodeSubscribe(version int, packetId uint16, topics []string, qos byte) []byte {
+	buf := appendUint16(nil, packetId)
+	if version == ProtocolV5 {
+		buf = appendVarInt(buf, 0)
+	}
+	for _, t := range topics {
+		buf = appendString(buf, t)
+		buf = append(buf, qos)
+	}
+	return buf
+}
+
+func decodeSubAck(version int, body []byte) (uint16, []byte, error) {
+	d := &decoder{buf: body}
+	packetId := d.uint16()
+	if version == ProtocolV5 {
+		d.skipProperties()
+	}
+	if d.err != nil {
+		return 0, nil, d.err
+	}
+	return packetId, d.buf, nil
+}
+
+func decodePublish(version int, p *packet) (*publish, error) {
+	d := &decoder{buf: p.body}
+	pub := &publish{
+		dup:    p.flags&0x08 != 0,
+		qos:    (p.flags >> 1) & 0x03,
+		retain: p.flags&0x01 != 0,
+	}
+	if pub.qos > 1 {
+		return nil, errors.Errorf("unsupported qos %d", pub.qos)
+	}
+	pub.topic = d.string()
+	if pub.qos > 0 {
+		pub.packetId = d.uint16()
+	}
+	if version == ProtocolV5 {
+		d.skipProperties()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	pub.payload = d.buf
+	return pub, nil
+}
+
+func encodePubAck(packetId uint16) []byte {
+	return appendUint16(nil, packetId)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVarInt(t *testing.T) {
+	for _, v := range []int{0, 127, 128, 16383, 16384, 2097151, 2097152, maxRemainingLength} {
+		buf := appendVarInt(nil, v)
+		got, err := readVarInt(bytes.NewReader(buf))
+		assert.NoError(t, err)
+		assert.Equal(t, v, got)
+	}
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0x7f}, appendVarInt(nil, maxRemainingLength))
+}
+
+func TestEncodeConnect(t *testing.T) {
+	got := encodeConnect(connectOptions{
+		version:      ProtocolV311,
+		clientId:     "c",
+		username:     "u",
+		password:     "p",
+		cleanSession: true,
+		keepAlive:    30,
+	})
+	expected := []byte{
+		0, 4, 'M', 'Q', 'T', 'T', 4, 0xc2, 0, 30,
+		0, 1, 'c', 0, 1, 'u', 0, 1, 'p',
+	}
+	assert.Equal(t, expected, got)
+
+	got = encodeConnect(connectOptions{
+		version:       ProtocolV5,
+		clientId:      "c",
+		keepAlive:     30,
+		sessionExpiry: 3600,
+	})
+	expected = []byte{
+		0, 4, 'M', 'Q', 'T', 'T', 5, 0, 0, 30,
+		5, propSessionExpiryInterval, 0, 0, 0x0e, 0x10,
+		0, 1, 'c',
+	}
+	assert.Equal(t, expected, got)
+}
+
+func TestDecodePublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		flags   byte
+		body    []byte
+		want    *publish
+	}{
+		{
+			name:    "v3.1.1 qos0",
+			version: ProtocolV311,
+			flags:   0x01,
+			body:    []byte{0, 3, 'a', '/', 'b', 'h', 'i'},
+			want:    &publish{topic: "a/b", retain: true, payload: []byte("hi")},
+		},
+		{
+			name:    "v5 qos1 with properties",
+			version: ProtocolV5,
+			flags:   0x0a,
+			body:    []byte{0, 1, 'a', 0, 7, 2, 0x01, 0x01, 'h', 'i'},
+			want:    &publish{topic: "a", qos: 1, dup: true, packetId: 7, payload: []byte("hi")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, writePacket(&buf, packetPublish, tt.flags, tt.body))
+			p, err := readPacket(bufio.NewReader(&buf))
+			assert.NoError(t, err)
+
+			got, err := decodePublish(tt.version, p)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	c := &Config{Topics: []string{"a/#", "b"}, SharedGroup: "g"}
+	assert.Equal(t, []string{"$share/g/a/#", "$share/g/b"}, c.subscriptions())
+}
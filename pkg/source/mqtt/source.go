@@ -0,0 +1,230 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/global"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const (
+	Type = "mqtt"
+
+	fMqtt     = "mqtt"
+	fTopic    = "topic"
+	fQoS      = "qos"
+	fRetain   = "retain"
+	fPacketId = "packetId"
+
+	packetIdKey = event.PrivateKeyPrefix + "MqttPacketId"
+	connKey     = event.PrivateKeyPrefix + "MqttConn"
+)
+
+func init() {
+	pipeline.Register(api.SOURCE, Type, makeSource)
+}
+
+func makeSource(info pipeline.Info) api.Component {
+	return &Source{
+		pipelineName: info.PipelineName,
+		done:         make(chan struct{}),
+		config:       &Config{},
+		eventPool:    info.EventPool,
+	}
+}
+
+type Source struct {
+	pipelineName string
+	name         string
+	clientId     string
+	done         chan struct{}
+	closeOnce    sync.Once
+	config       *Config
+	eventPool    *event.Pool
+
+	connLock sync.Mutex
+	conn     *conn
+}
+
+func (s *Source) Config() interface{} {
+	return s.config
+}
+
+func (s *Source) Category() api.Category {
+	return api.SOURCE
+}
+
+func (s *Source) Type() api.Type {
+	return Type
+}
+
+func (s *Source) String() string {
+	return fmt.Sprintf("%s/%s", api.SOURCE, Type)
+}
+
+func (s *Source) Init(context api.Context) error {
+	s.name = context.Name()
+	return nil
+}
+
+func (s *Source) Start() error {
+	s.clientId = s.config.ClientId
+	if s.clientId == "" {
+		// a stable client id is required to resume the persistent session after restarting
+		node := global.NodeName
+		if node == "" {
+			node, _ = os.Hostname()
+		}
+		s.clientId = fmt.Sprintf("loggie-%s-%s-%s", node, s.pipelineName, s.name)
+	}
+	log.Info("%s start, broker: %s, clientId: %s", s.String(), s.config.Broker, s.clientId)
+	return nil
+}
+
+func (s *Source) Stop() {
+	s.closeOnce.Do(func() {
+		log.Info("stopping source %s: %s", Type, s.name)
+		close(s.done)
+
+		s.connLock.Lock()
+		if s.conn != nil {
+			s.conn.close()
+		}
+		s.connLock.Unlock()
+	})
+}
+
+func (s *Source) ProductLoop(productFunc api.ProductFunc) {
+	log.Info("%s start product loop", s.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.done
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		if err := s.consume(ctx, productFunc); err != nil && ctx.Err() == nil {
+			log.Warn("[%s] mqtt connection to %s broken: %v, reconnect after %s", s.name, s.config.Broker, err, s.config.ReconnectInterval)
+		}
+
+		select {
+		case <-s.done:
+			return
+		case <-time.After(s.config.ReconnectInterval):
+		}
+	}
+}
+
+func (s *Source) consume(ctx context.Context, productFunc api.ProductFunc) error {
+	c, err := dial(ctx, s.config, s.clientId)
+	if err != nil {
+		return err
+	}
+
+	s.connLock.Lock()
+	select {
+	case <-s.done:
+		s.connLock.Unlock()
+		c.close()
+		return nil
+	default:
+	}
+	s.conn = c
+	s.connLock.Unlock()
+
+	defer c.close()
+	go c.keepAlive()
+
+	log.Info("[%s] connected to mqtt broker %s", s.name, s.config.Broker)
+	return c.readLoop(func(pub *publish) {
+		s.emit(c, pub, productFunc)
+	})
+}
+
+func (s *Source) emit(c *conn, pub *publish, productFunc api.ProductFunc) {
+	e := s.eventPool.Get()
+	header := e.Header()
+	if header == nil {
+		header = make(map[string]interface{})
+	}
+	if s.config.AddonMeta != nil && *s.config.AddonMeta {
+		header[fMqtt] = map[string]interface{}{
+			fTopic:    pub.topic,
+			fQoS:      pub.qos,
+			fRetain:   pub.retain,
+			fPacketId: pub.packetId,
+		}
+	}
+
+	meta := e.Meta()
+	if pub.qos > 0 {
+		// puback is sent after the event is acked by the sink
+		if meta == nil {
+			meta = event.NewDefaultMeta()
+		}
+		meta.Set(packetIdKey, pub.packetId)
+		meta.Set(connKey, c)
+	}
+	e.Fill(meta, header, pub.payload)
+
+	res := productFunc(e)
+	if pub.qos > 0 && res.Status() == api.DROP {
+		// dropped events would never be committed
+		s.ack(c, pub.packetId)
+	}
+}
+
+func (s *Source) ack(c *conn, packetId uint16) {
+	if err := c.pubAck(packetId); err != nil {
+		log.Debug("[%s] send puback %d error: %v", s.name, packetId, err)
+	}
+}
+
+func (s *Source) Commit(events []api.Event) {
+	for _, e := range events {
+		meta := e.Meta()
+		if meta == nil {
+			continue
+		}
+		id, ok := meta.Get(packetIdKey)
+		if !ok {
+			continue
+		}
+		c, ok := meta.Get(connKey)
+		if !ok {
+			continue
+		}
+		s.ack(c.(*conn), id.(uint16))
+	}
+	s.eventPool.PutAll(events)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nats
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DeliverAll  = "all"
+	DeliverNew  = "new"
+	DeliverLast = "last"
+)
+
+type Config struct {
+	// Servers are the addresses of the nats cluster, e.g. nats://localhost:4222, tls://localhost:4222
+	Servers  []string `yaml:"servers,omitempty" validate:"required"`
+	Name     string   `yaml:"name,omitempty"`
+	Username string   `yaml:"username,omitempty"`
+	Password string   `yaml:"password,omitempty"`
+	Token    string   `yaml:"token,omitempty"`
+	Subjects []string `yaml:"subjects,omitempty" validate:"required"`
+	// QueueGroup distributes the messages of core nats subjects among the members of the group
+	QueueGroup        string        `yaml:"queueGroup,omitempty"`
+	JetStream         JetStream     `yaml:"jetstream,omitempty"`
+	ConnectTimeout    time.Duration `yaml:"connectTimeout,omitempty" default:"10s"`
+	PingInterval      time.Duration `yaml:"pingInterval,omitempty" default:"2m"`
+	ReconnectInterval time.Duration `yaml:"reconnectInterval,omitempty" default:"5s"`
+	TLS               TLS           `yaml:"tls,omitempty"`
+	AddonMeta         *bool         `yaml:"addonMeta,omitempty" default:"true"`
+}
+
+type JetStream struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Stream and Durable identify the durable pull consumer, which would be created if not exists
+	Stream        string        `yaml:"stream,omitempty"`
+	Durable       string        `yaml:"durable,omitempty" default:"loggie"`
+	APIPrefix     string        `yaml:"apiPrefix,omitempty" default:"$JS.API"`
+	DeliverPolicy string        `yaml:"deliverPolicy,omitempty" default:"all" validate:"oneof=all new last"`
+	AckWait       time.Duration `yaml:"ackWait,omitempty" default:"30s"`
+	MaxAckPending int           `yaml:"maxAckPending,omitempty" default:"1000"`
+	Batch         int           `yaml:"batch,omitempty" default:"100" validate:"gte=1"`
+	PullExpires   time.Duration `yaml:"pullExpires,omitempty" default:"5s"`
+}
+
+type TLS struct {
+	Enabled            bool   `yaml:"enabled,omitempty"`
+	CaCertFiles        string `yaml:"caCertFiles,omitempty"`
+	ClientCertFile     string `yaml:"clientCertFile,omitempty"`
+	ClientKeyFile      string `yaml:"clientKeyFile,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	for _, s := range c.Servers {
+		u, err := url.Parse(s)
+		if err != nil {
+			return errors.WithMessagef(err, "parse nats server %s", s)
+		}
+		if u.Scheme != "nats" && u.Scheme != "tls" {
+			return errors.Errorf("unsupported nats server scheme %s", u.Scheme)
+		}
+	}
+
+	if c.JetStream.Enabled {
+		if c.JetStream.Stream == "" {
+			return errors.New("jetstream.stream is required when jetstream is enabled")
+		}
+		if c.QueueGroup != "" {
+			return errors.New("queueGroup is only used for core nats, consumers sharing the same durable are load balanced in jetstream")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nats
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/global"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	netutils "github.com/loggie-io/loggie/pkg/util/net"
+)
+
+type serverInfo struct {
+	ServerId     string `json:"server_id"`
+	Version      string `json:"version"`
+	Headers      bool   `json:"headers"`
+	TLSRequired  bool   `json:"tls_required"`
+	AuthRequired bool   `json:"auth_required"`
+	MaxPayload   int64  `json:"max_payload"`
+}
+
+type connectInfo struct {
+	Verbose      bool   `json:"verbose"`
+	Pedantic     bool   `json:"pedantic"`
+	TLSRequired  bool   `json:"tls_required"`
+	Name         string `json:"name,omitempty"`
+	Lang         string `json:"lang"`
+	Version      string `json:"version"`
+	Protocol     int    `json:"protocol"`
+	Headers      bool   `json:"headers"`
+	NoResponders bool   `json:"no_responders"`
+	User         string `json:"user,omitempty"`
+	Pass         string `json:"pass,omitempty"`
+	AuthToken    string `json:"auth_token,omitempty"`
+}
+
+// conn is a single connection to a nats server, a new conn is created after reconnecting
+type conn struct {
+	config *Config
+	nc     net.Conn
+	r      *bufio.Reader
+	info   serverInfo
+
+	writeLock sync.Mutex
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func dial(ctx context.Context, config *Config, server string) (*conn, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.ConnectTimeout)
+	defer cancel()
+
+	nc, err := (&net.Dialer{}).DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "dial nats server %s", server)
+	}
+	c := &conn{
+		config: config,
+		nc:     nc,
+		r:      bufio.NewReader(nc),
+		closed: make(chan struct{}),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = nc.SetDeadline(deadline)
+	}
+	if err := c.handshake(u); err != nil {
+		c.close()
+		return nil, err
+	}
+	_ = c.nc.SetDeadline(time.Time{})
+	return c, nil
+}
+
+func (c *conn) handshake(u *url.URL) error {
+	op, _, args, err := readOp(c.r)
+	if err != nil {
+		return errors.WithMessage(err, "read server info")
+	}
+	if op != opInfo {
+		return errors.Errorf("expected INFO, got %s", op)
+	}
+	if err := json.Unmarshal([]byte(args), &c.info); err != nil {
+		return errors.WithMessage(err, "unmarshal server info")
+	}
+
+	useTLS := c.config.TLS.Enabled || u.Scheme == "tls" || c.info.TLSRequired
+	if useTLS {
+		tlsConfig, err := netutils.NewTLSConfig(c.config.TLS.CaCertFiles, c.config.TLS.ClientCertFile, c.config.TLS.ClientKeyFile, c.config.TLS.InsecureSkipVerify)
+		if err != nil {
+			return errors.WithMessage(err, "load tls config")
+		}
+		tlsConfig.ServerName = u.Hostname()
+		tlsConn := tls.Client(c.nc, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return errors.WithMessage(err, "tls handshake")
+		}
+		c.nc = tlsConn
+		c.r = bufio.NewReader(tlsConn)
+	}
+
+	ci := connectInfo{
+		TLSRequired:  useTLS,
+		Name:         c.config.Name,
+		Lang:         "go",
+		Version:      global.GetVersion(),
+		Protocol:     1,
+		Headers:      true,
+		NoResponders: true,
+		User:         c.config.Username,
+		Pass:         c.config.Password,
+		AuthToken:    c.config.Token,
+	}
+	if u.User != nil && ci.User == "" {
+		ci.User = u.User.Username()
+		ci.Pass, _ = u.User.Password()
+	}
+	connect, err := json.Marshal(ci)
+	if err != nil {
+		return err
+	}
+	if err := c.write([]byte("CONNECT " + string(connect) + crlf + "PING" + crlf)); err != nil {
+		return errors.WithMessage(err, "send connect")
+	}
+
+	// the server replies PONG if the connection is accepted, or -ERR otherwise
+	for {
+		op, _, args, err := readOp(c.r)
+		if err != nil {
+			return errors.WithMessage(err, "wait for connect response")
+		}
+		switch op {
+		case opPong:
+			return nil
+		case opErr:
+			return errors.Errorf("connect refused: %s", args)
+		}
+	}
+}
+
+func (c *conn) write(b []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err := c.nc.Write(b)
+	return err
+}
+
+func (c *conn) publish(subject string, reply string, data []byte) error {
+	select {
+	case <-c.closed:
+		return errors.New("connection closed")
+	default:
+	}
+	return c.write(encodePub(subject, reply, data))
+}
+
+func (c *conn) subscribe(subject string, queue string, sid string) error {
+	return c.write(encodeSub(subject, queue, sid))
+}
+
+// request publishes the request and waits for the first reply on the inbox, it should only be used before readLoop
+func (c *conn) request(subject string, inbox string, sid string, data []byte, timeout time.Duration) (*message, error) {
+	if err := c.subscribe(inbox, "", sid); err != nil {
+		return nil, err
+	}
+	defer c.write([]byte("UNSUB " + sid + crlf))
+
+	if err := c.publish(subject, inbox, data); err != nil {
+		return nil, err
+	}
+
+	_ = c.nc.SetReadDeadline(time.Now().Add(timeout))
+	defer c.nc.SetReadDeadline(time.Time{})
+	for {
+		op, m, args, err := readOp(c.r)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.write([]byte(opPong + crlf)); err != nil {
+				return nil, err
+			}
+		case opErr:
+			return nil, errors.Errorf("server error: %s", args)
+		case opMsg, opHMsg:
+			if m.sid == sid {
+				return m, nil
+			}
+		}
+	}
+}
+
+// keepAlive sends PING periodically, the server closes the connection if PINGs are not replied
+func (c *conn) keepAlive() {
+	if c.config.PingInterval <= 0 {
+		return
+	}
+	t := time.NewTicker(c.config.PingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-t.C:
+			if err := c.write([]byte(opPing + crlf)); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// readLoop reads until the connection is broken, messages are handled by onMessage
+func (c *conn) readLoop(onMessage func(*message)) error {
+	for {
+		op, m, args, err := readOp(c.r)
+		if err != nil {
+			return err
+		}
+		switch op {
+		case opMsg, opHMsg:
+			onMessage(m)
+		case opPing:
+			if err := c.write([]byte(opPong + crlf)); err != nil {
+				return err
+			}
+		case opErr:
+			return errors.Errorf("server error: %s", args)
+		case opPong, opOK, opInfo:
+		default:
+			return errors.Errorf("unknown protocol operation %s", op)
+		}
+	}
+}
+
+func (c *conn) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		_ = c.nc.Close()
+	})
+}
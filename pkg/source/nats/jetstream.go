@@ -0,0 +1,178 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nats
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	ackPayload = "+ACK"
+
+	statusNoMessages     = "404"
+	statusRequestTimeout = "408"
+	statusConflict       = "409"
+	statusHeartbeat      = "100"
+
+	apiRequestTimeout = 5 * time.Second
+)
+
+type consumerConfig struct {
+	DurableName    string        `json:"durable_name"`
+	DeliverPolicy  string        `json:"deliver_policy"`
+	AckPolicy      string        `json:"ack_policy"`
+	AckWait        time.Duration `json:"ack_wait"`
+	MaxAckPending  int           `json:"max_ack_pending"`
+	FilterSubject  string        `json:"filter_subject,omitempty"`
+	FilterSubjects []string      `json:"filter_subjects,omitempty"`
+}
+
+type createConsumerRequest struct {
+	Stream string         `json:"stream_name"`
+	Config consumerConfig `json:"config"`
+}
+
+type apiResponse struct {
+	Error *struct {
+		Code        int    `json:"code"`
+		ErrCode     int    `json:"err_code"`
+		Description string `json:"description"`
+	} `json:"error,omitempty"`
+}
+
+type pullRequest struct {
+	Batch   int           `json:"batch"`
+	Expires time.Duration `json:"expires"`
+}
+
+func newInbox() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "_INBOX." + hex.EncodeToString(b)
+}
+
+// createConsumer creates the durable pull consumer, it is idempotent when the consumer exists with the same config
+func createConsumer(c *conn, js *JetStream, subjects []string) error {
+	cc := consumerConfig{
+		DurableName:   js.Durable,
+		DeliverPolicy: js.DeliverPolicy,
+		AckPolicy:     "explicit",
+		AckWait:       js.AckWait,
+		MaxAckPending: js.MaxAckPending,
+	}
+	if len(subjects) == 1 {
+		cc.FilterSubject = subjects[0]
+	} else {
+		cc.FilterSubjects = subjects
+	}
+	req, err := json.Marshal(&createConsumerRequest{Stream: js.Stream, Config: cc})
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("%s.CONSUMER.DURABLE.CREATE.%s.%s", js.APIPrefix, js.Stream, js.Durable)
+	m, err := c.request(subject, newInbox(), "api", req, apiRequestTimeout)
+	if err != nil {
+		return errors.WithMessagef(err, "create consumer %s of stream %s", js.Durable, js.Stream)
+	}
+	if m.status != "" {
+		return errors.Errorf("create consumer %s of stream %s failed, status: %s, is jetstream enabled?", js.Durable, js.Stream, m.status)
+	}
+	resp := &apiResponse{}
+	if err := json.Unmarshal(m.data, resp); err != nil {
+		return errors.WithMessage(err, "unmarshal create consumer response")
+	}
+	if resp.Error != nil {
+		return errors.Errorf("create consumer %s of stream %s failed: %s (%d)", js.Durable, js.Stream, resp.Error.Description, resp.Error.ErrCode)
+	}
+	return nil
+}
+
+// puller sends pull requests of the durable consumer, a new request is sent after the previous one is fulfilled or expired
+type puller struct {
+	name     string
+	c        *conn
+	js       *JetStream
+	inbox    string
+	trigger  chan struct{}
+	received int
+}
+
+func newPuller(name string, c *conn, js *JetStream) *puller {
+	return &puller{
+		name:    name,
+		c:       c,
+		js:      js,
+		inbox:   newInbox(),
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+func (p *puller) run() {
+	subject := fmt.Sprintf("%s.CONSUMER.MSG.NEXT.%s.%s", p.js.APIPrefix, p.js.Stream, p.js.Durable)
+	req, _ := json.Marshal(&pullRequest{Batch: p.js.Batch, Expires: p.js.PullExpires})
+	for {
+		if err := p.c.publish(subject, p.inbox, req); err != nil {
+			return
+		}
+		select {
+		case <-p.c.closed:
+			return
+		case <-p.trigger:
+		case <-time.After(p.js.PullExpires + apiRequestTimeout):
+			// the response of the request may be lost
+		}
+	}
+}
+
+func (p *puller) next() {
+	p.received = 0
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// handle returns true if the message is a jetstream message which should be consumed, it is only called in the read loop
+func (p *puller) handle(m *message) bool {
+	switch m.status {
+	case "":
+		p.received++
+		if p.received >= p.js.Batch {
+			p.next()
+		}
+		return true
+	case statusNoMessages, statusRequestTimeout:
+		p.next()
+	case statusConflict:
+		log.Warn("[%s] pull request of consumer %s conflicted: %s", p.name, p.js.Durable, m.description)
+		p.next()
+	case statusHeartbeat:
+	default:
+		log.Warn("[%s] unexpected status %s of pull request", p.name, m.status)
+		p.next()
+	}
+	return false
+}
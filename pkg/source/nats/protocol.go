@@ -0,0 +1,207 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// see https://docs.nats.io/reference/reference-protocols/nats-protocol
+const (
+	opInfo = "INFO"
+	opMsg  = "MSG"
+	opHMsg = "HMSG"
+	opPing = "PING"
+	opPong = "PONG"
+	opOK   = "+OK"
+	opErr  = "-ERR"
+
+	crlf = "\r\n"
+
+	headerLine = "NATS/1.0"
+
+	maxControlLine = 4096
+)
+
+type message struct {
+	subject string
+	sid     string
+	reply   string
+	// status is the inline status of a header only message, such as 404 or 408 of a pull request
+	status      string
+	description string
+	header      map[string]string
+	data        []byte
+}
+
+// readOp reads a protocol line, the message payload is also read if it is a MSG or HMSG
+func readOp(r *bufio.Reader) (string, *message, string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", nil, "", err
+	}
+	if len(line) > maxControlLine {
+		return "", nil, "", errors.New("maximum control line exceeded")
+	}
+	line = strings.TrimRight(line, crlf)
+
+	op, args := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		op, args = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	op = strings.ToUpper(op)
+
+	switch op {
+	case opMsg, opHMsg:
+		m, err := readMessage(r, op, args)
+		return op, m, "", err
+	default:
+		return op, nil, args, nil
+	}
+}
+
+func readMessage(r *bufio.Reader, op string, args string) (*message, error) {
+	fields := strings.Fields(args)
+	m := &message{}
+
+	// MSG <subject> <sid> [reply-to] <#bytes>
+	// HMSG <subject> <sid> [reply-to] <#header bytes> <#total bytes>
+	sizes := 1
+	if op == opHMsg {
+		sizes = 2
+	}
+	switch len(fields) {
+	case 2 + sizes:
+	case 3 + sizes:
+		m.reply = fields[2]
+	default:
+		return nil, errors.Errorf("malformed %s: %s", op, args)
+	}
+	m.subject, m.sid = fields[0], fields[1]
+
+	total, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return nil, errors.Errorf("malformed %s size: %s", op, args)
+	}
+	headerSize := 0
+	if op == opHMsg {
+		if headerSize, err = strconv.Atoi(fields[len(fields)-2]); err != nil || headerSize > total {
+			return nil, errors.Errorf("malformed %s header size: %s", op, args)
+		}
+	}
+
+	payload := make([]byte, total+len(crlf))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if headerSize > 0 {
+		if err := m.parseHeader(string(payload[:headerSize])); err != nil {
+			return nil, err
+		}
+	}
+	m.data = payload[headerSize:total]
+	return m, nil
+}
+
+func (m *message) parseHeader(raw string) error {
+	lines := strings.Split(raw, crlf)
+	if !strings.HasPrefix(lines[0], headerLine) {
+		return errors.Errorf("malformed message header: %s", lines[0])
+	}
+	if status := strings.TrimSpace(strings.TrimPrefix(lines[0], headerLine)); status != "" {
+		m.status = strings.Fields(status)[0]
+		m.description = strings.TrimSpace(strings.TrimPrefix(status, m.status))
+	}
+
+	m.header = make(map[string]string)
+	for _, l := range lines[1:] {
+		i := strings.Index(l, ":")
+		if i <= 0 {
+			continue
+		}
+		m.header[strings.TrimSpace(l[:i])] = strings.TrimSpace(l[i+1:])
+	}
+	return nil
+}
+
+func encodePub(subject string, reply string, data []byte) []byte {
+	var head string
+	if reply == "" {
+		head = fmt.Sprintf("PUB %s %d\r\n", subject, len(data))
+	} else {
+		head = fmt.Sprintf("PUB %s %s %d\r\n", subject, reply, len(data))
+	}
+	buf := make([]byte, 0, len(head)+len(data)+len(crlf))
+	buf = append(buf, head...)
+	buf = append(buf, data...)
+	return append(buf, crlf...)
+}
+
+func encodeSub(subject string, queue string, sid string) []byte {
+	if queue == "" {
+		return []byte(fmt.Sprintf("SUB %s %s\r\n", subject, sid))
+	}
+	return []byte(fmt.Sprintf("SUB %s %s %s\r\n", subject, queue, sid))
+}
+
+// ackMetadata is parsed from the reply subject of a jetstream message
+type ackMetadata struct {
+	Stream    string
+	Consumer  string
+	Delivered uint64
+	StreamSeq uint64
+	Timestamp time.Time
+}
+
+// parseAckSubject parses $JS.ACK.<stream>.<consumer>.<delivered>.<stream seq>.<consumer seq>.<timestamp>.<pending>,
+// or the newer format with domain and account hash: $JS.ACK.<domain>.<account hash>.<stream>.<consumer>.<delivered>.<stream seq>.<consumer seq>.<timestamp>.<pending>.<random>
+func parseAckSubject(subject string) (*ackMetadata, error) {
+	tokens := strings.Split(subject, ".")
+	if len(tokens) < 9 || tokens[0] != "$JS" || tokens[1] != "ACK" {
+		return nil, errors.Errorf("not a jetstream ack subject: %s", subject)
+	}
+	if len(tokens) >= 12 {
+		tokens = tokens[4:]
+	} else {
+		tokens = tokens[2:]
+	}
+
+	meta := &ackMetadata{
+		Stream:   tokens[0],
+		Consumer: tokens[1],
+	}
+	var err error
+	if meta.Delivered, err = strconv.ParseUint(tokens[2], 10, 64); err != nil {
+		return nil, errors.Errorf("malformed delivered count in %s", subject)
+	}
+	if meta.StreamSeq, err = strconv.ParseUint(tokens[3], 10, 64); err != nil {
+		return nil, errors.Errorf("malformed stream sequence in %s", subject)
+	}
+	ts, err := strconv.ParseInt(tokens[5], 10, 64)
+	if err != nil {
+		return nil, errors.Errorf("malformed timestamp in %s", subject)
+	}
+	meta.Timestamp = time.Unix(0, ts)
+	return meta, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nats
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOp(t *testing.T) {
+	raw := "INFO {\"server_id\":\"a\"}\r\n" +
+		"MSG logs.app 1 5\r\nhello\r\n" +
+		"MSG logs.app 1 _INBOX.x 0\r\n\r\n" +
+		"HMSG logs.app pull $JS.ACK.S.C.1.2.3.4.5 18 23\r\nNATS/1.0\r\nK: V\r\n\r\nhello\r\n" +
+		"HMSG _INBOX.p pull 32 32\r\nNATS/1.0 408 Request Timeout\r\n\r\n\r\n" +
+		"PING\r\n"
+	r := bufio.NewReader(strings.NewReader(raw))
+
+	op, _, args, err := readOp(r)
+	assert.NoError(t, err)
+	assert.Equal(t, opInfo, op)
+	assert.Equal(t, `{"server_id":"a"}`, args)
+
+	_, m, _, err := readOp(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &message{subject: "logs.app", sid: "1", data: []byte("hello")}, m)
+
+	_, m, _, err = readOp(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "_INBOX.x", m.reply)
+	assert.Empty(t, m.data)
+
+	op, m, _, err = readOp(r)
+	assert.NoError(t, err)
+	assert.Equal(t, opHMsg, op)
+	assert.Equal(t, "$JS.ACK.S.C.1.2.3.4.5", m.reply)
+	assert.Equal(t, map[string]string{"K": "V"}, m.header)
+	assert.Equal(t, "", m.status)
+	assert.Equal(t, []byte("hello"), m.data)
+
+	_, m, _, err = readOp(r)
+	assert.NoError(t, err)
+	assert.Equal(t, statusRequestTimeout, m.status)
+	assert.Equal(t, "Request Timeout", m.description)
+
+	op, _, _, err = readOp(r)
+	assert.NoError(t, err)
+	assert.Equal(t, opPing, op)
+}
+
+func TestParseAckSubject(t *testing.T) {
+	ts := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	tsNano := "1682899200000000000"
+
+	m, err := parseAckSubject("$JS.ACK.LOGS.loggie.2.100.90." + tsNano + ".5")
+	assert.NoError(t, err)
+	assert.Equal(t, &ackMetadata{Stream: "LOGS", Consumer: "loggie", Delivered: 2, StreamSeq: 100, Timestamp: ts.Local()}, m)
+
+	m, err = parseAckSubject("$JS.ACK.hub.ACCHASH.LOGS.loggie.1.7.7." + tsNano + ".0.abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "LOGS", m.Stream)
+	assert.Equal(t, uint64(7), m.StreamSeq)
+
+	_, err = parseAckSubject("_INBOX.abc")
+	assert.Error(t, err)
+}
+
+func TestEncode(t *testing.T) {
+	assert.Equal(t, "PUB a.b 2\r\nhi\r\n", string(encodePub("a.b", "", []byte("hi"))))
+	assert.Equal(t, "PUB a.b _INBOX.x 0\r\n\r\n", string(encodePub("a.b", "_INBOX.x", nil)))
+	assert.Equal(t, "SUB a.> q 1\r\n", string(encodeSub("a.>", "q", "1")))
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nats
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const (
+	Type = "nats"
+
+	fNats      = "nats"
+	fSubject   = "subject"
+	fHeader    = "header"
+	fStream    = "stream"
+	fConsumer  = "consumer"
+	fDelivered = "delivered"
+	fStreamSeq = "streamSeq"
+	fTimestamp = "timestamp"
+
+	replyKey = event.PrivateKeyPrefix + "NatsReply"
+	connKey  = event.PrivateKeyPrefix + "NatsConn"
+)
+
+func init() {
+	pipeline.Register(api.SOURCE, Type, makeSource)
+}
+
+func makeSource(info pipeline.Info) api.Component {
+	return &Source{
+		done:      make(chan struct{}),
+		config:    &Config{},
+		eventPool: info.EventPool,
+	}
+}
+
+type Source struct {
+	name      string
+	done      chan struct{}
+	closeOnce sync.Once
+	config    *Config
+	eventPool *event.Pool
+
+	connLock sync.Mutex
+	conn     *conn
+}
+
+func (s *Source) Config() interface{} {
+	return s.config
+}
+
+func (s *Source) Category() api.Category {
+	return api.SOURCE
+}
+
+func (s *Source) Type() api.Type {
+	return Type
+}
+
+func (s *Source) String() string {
+	return fmt.Sprintf("%s/%s", api.SOURCE, Type)
+}
+
+func (s *Source) Init(context api.Context) error {
+	s.name = context.Name()
+	return nil
+}
+
+func (s *Source) Start() error {
+	log.Info("%s start, servers: %v, subjects: %v", s.String(), s.config.Servers, s.config.Subjects)
+	return nil
+}
+
+func (s *Source) Stop() {
+	s.closeOnce.Do(func() {
+		log.Info("stopping source %s: %s", Type, s.name)
+		close(s.done)
+
+		s.connLock.Lock()
+		if s.conn != nil {
+			s.conn.close()
+		}
+		s.connLock.Unlock()
+	})
+}
+
+func (s *Source) ProductLoop(productFunc api.ProductFunc) {
+	log.Info("%s start product loop", s.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.done
+		cancel()
+	}()
+
+	for i := 0; ; i++ {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		server := s.config.Servers[i%len(s.config.Servers)]
+		if err := s.consume(ctx, server, productFunc); err != nil && ctx.Err() == nil {
+			log.Warn("[%s] nats connection to %s broken: %v, reconnect after %s", s.name, server, err, s.config.ReconnectInterval)
+		}
+
+		select {
+		case <-s.done:
+			return
+		case <-time.After(s.config.ReconnectInterval):
+		}
+	}
+}
+
+func (s *Source) consume(ctx context.Context, server string, productFunc api.ProductFunc) error {
+	c, err := dial(ctx, s.config, server)
+	if err != nil {
+		return err
+	}
+
+	s.connLock.Lock()
+	select {
+	case <-s.done:
+		s.connLock.Unlock()
+		c.close()
+		return nil
+	default:
+	}
+	s.conn = c
+	s.connLock.Unlock()
+	defer c.close()
+
+	var p *puller
+	if s.config.JetStream.Enabled {
+		if err := createConsumer(c, &s.config.JetStream, s.config.Subjects); err != nil {
+			return err
+		}
+		p = newPuller(s.name, c, &s.config.JetStream)
+		if err := c.subscribe(p.inbox, "", "pull"); err != nil {
+			return err
+		}
+		go p.run()
+	} else {
+		for i, subject := range s.config.Subjects {
+			if err := c.subscribe(subject, s.config.QueueGroup, strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+	go c.keepAlive()
+
+	log.Info("[%s] connected to nats server %s(%s)", s.name, server, c.info.Version)
+	return c.readLoop(func(m *message) {
+		if p != nil && !p.handle(m) {
+			return
+		}
+		s.emit(c, m, p != nil, productFunc)
+	})
+}
+
+func (s *Source) emit(c *conn, m *message, jetstream bool, productFunc api.ProductFunc) {
+	e := s.eventPool.Get()
+	header := e.Header()
+	if header == nil {
+		header = make(map[string]interface{})
+	}
+	if s.config.AddonMeta != nil && *s.config.AddonMeta {
+		meta := map[string]interface{}{
+			fSubject: m.subject,
+		}
+		if len(m.header) > 0 {
+			meta[fHeader] = m.header
+		}
+		if jetstream {
+			if am, err := parseAckSubject(m.reply); err == nil {
+				meta[fStream] = am.Stream
+				meta[fConsumer] = am.Consumer
+				meta[fDelivered] = am.Delivered
+				meta[fStreamSeq] = am.StreamSeq
+				meta[fTimestamp] = am.Timestamp.Format(time.RFC3339Nano)
+			}
+		}
+		header[fNats] = meta
+	}
+
+	meta := e.Meta()
+	if jetstream && m.reply != "" {
+		// the message is acked after the event is acked by the sink, or redelivered after ackWait
+		if meta == nil {
+			meta = event.NewDefaultMeta()
+		}
+		meta.Set(replyKey, m.reply)
+		meta.Set(connKey, c)
+	}
+	e.Fill(meta, header, m.data)
+
+	res := productFunc(e)
+	if jetstream && m.reply != "" && res.Status() == api.DROP {
+		// dropped events would never be committed
+		s.ack(c, m.reply)
+	}
+}
+
+func (s *Source) ack(c *conn, reply string) {
+	if err := c.publish(reply, "", []byte(ackPayload)); err != nil {
+		log.Debug("[%s] ack message %s error: %v", s.name, reply, err)
+	}
+}
+
+func (s *Source) Commit(events []api.Event) {
+	for _, e := range events {
+		meta := e.Meta()
+		if meta == nil {
+			continue
+		}
+		reply, ok := meta.Get(replyKey)
+		if !ok {
+			continue
+		}
+		c, ok := meta.Get(connKey)
+		if !ok {
+			continue
+		}
+		s.ack(c.(*conn), reply.(string))
+	}
+	s.eventPool.PutAll(events)
+}
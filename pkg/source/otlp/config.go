@@ -0,0 +1,66 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	GRPC GRPCConfig `yaml:"grpc,omitempty"`
+	HTTP HTTPConfig `yaml:"http,omitempty"`
+
+	// ResourceKey, ScopeKey and AttributesKey are the header keys of the resource attributes, instrumentation scope
+	// and log record attributes, the attributes are merged into the header when the key is set to empty
+	ResourceKey   string `yaml:"resourceKey" default:"resource"`
+	ScopeKey      string `yaml:"scopeKey" default:"scope"`
+	AttributesKey string `yaml:"attributesKey" default:"attributes"`
+}
+
+type GRPCConfig struct {
+	Enabled        *bool  `yaml:"enabled,omitempty" default:"true"`
+	Network        string `yaml:"network,omitempty" default:"tcp"`
+	Bind           string `yaml:"bind,omitempty" default:"0.0.0.0"`
+	Port           string `yaml:"port,omitempty" default:"4317"`
+	MaxRecvMsgSize int    `yaml:"maxRecvMsgSize,omitempty" default:"4194304" validate:"gt=0"`
+}
+
+type HTTPConfig struct {
+	Enabled      *bool         `yaml:"enabled,omitempty" default:"true"`
+	Bind         string        `yaml:"bind,omitempty" default:"0.0.0.0"`
+	Port         string        `yaml:"port,omitempty" default:"4318"`
+	Path         string        `yaml:"path,omitempty" default:"/v1/logs"`
+	MaxBodyBytes int64         `yaml:"maxBodyBytes,omitempty" default:"4194304" validate:"gt=0"`
+	ReadTimeout  time.Duration `yaml:"readTimeout,omitempty" default:"30s"`
+}
+
+func (c *Config) Validate() error {
+	if !c.GRPC.enabled() && !c.HTTP.enabled() {
+		return errors.New("at least one of grpc and http should be enabled")
+	}
+	return nil
+}
+
+func (c *GRPCConfig) enabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+func (c *HTTPConfig) enabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
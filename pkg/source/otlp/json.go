@@ -0,0 +1,200 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	stdjson "encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The OTLP/JSON encoding uses lowerCamelCase field names, 64 bit integers may be encoded as strings,
+// trace and span ids are hex encoded instead of base64.
+
+type jsonExportRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []jsonKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs                  []jsonScopeLogs `json:"scopeLogs"`
+		InstrumentationLibraryLogs []jsonScopeLogs `json:"instrumentationLibraryLogs"`
+	} `json:"resourceLogs"`
+}
+
+type jsonScopeLogs struct {
+	Scope      *jsonScope      `json:"scope"`
+	Library    *jsonScope      `json:"instrumentationLibrary"`
+	LogRecords []jsonLogRecord `json:"logRecords"`
+}
+
+type jsonScope struct {
+	Name       string         `json:"name"`
+	Version    string         `json:"version"`
+	Attributes []jsonKeyValue `json:"attributes"`
+}
+
+type jsonLogRecord struct {
+	TimeUnixNano         jsonUint64     `json:"timeUnixNano"`
+	ObservedTimeUnixNano jsonUint64     `json:"observedTimeUnixNano"`
+	SeverityNumber       int32          `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 *jsonAnyValue  `json:"body"`
+	Attributes           []jsonKeyValue `json:"attributes"`
+	Flags                uint32         `json:"flags"`
+	TraceId              string         `json:"traceId"`
+	SpanId               string         `json:"spanId"`
+}
+
+type jsonKeyValue struct {
+	Key   string        `json:"key"`
+	Value *jsonAnyValue `json:"value"`
+}
+
+type jsonAnyValue struct {
+	StringValue *string     `json:"stringValue"`
+	BoolValue   *bool       `json:"boolValue"`
+	IntValue    *jsonUint64 `json:"intValue"`
+	DoubleValue *float64    `json:"doubleValue"`
+	ArrayValue  *struct {
+		Values []*jsonAnyValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []jsonKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+	BytesValue *string `json:"bytesValue"`
+}
+
+// jsonUint64 accepts both json numbers and strings
+type jsonUint64 uint64
+
+func (u *jsonUint64) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*u = 0
+		return nil
+	}
+	if strings.HasPrefix(s, "-") {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		*u = jsonUint64(v)
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*u = jsonUint64(v)
+	return nil
+}
+
+func decodeJsonExportRequest(b []byte) ([]*logRecord, error) {
+	req := &jsonExportRequest{}
+	if err := stdjson.Unmarshal(b, req); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal otlp json")
+	}
+
+	var records []*logRecord
+	for _, rl := range req.ResourceLogs {
+		resource := jsonAttributes(rl.Resource.Attributes)
+		for _, sl := range append(rl.ScopeLogs, rl.InstrumentationLibraryLogs...) {
+			s := &scope{}
+			js := sl.Scope
+			if js == nil {
+				js = sl.Library
+			}
+			if js != nil {
+				s.name = js.Name
+				s.version = js.Version
+				s.attributes = jsonAttributes(js.Attributes)
+			}
+			for _, lr := range sl.LogRecords {
+				r := &logRecord{
+					resource:             resource,
+					scope:                s,
+					timeUnixNano:         uint64(lr.TimeUnixNano),
+					observedTimeUnixNano: uint64(lr.ObservedTimeUnixNano),
+					severityNumber:       lr.SeverityNumber,
+					severityText:         lr.SeverityText,
+					body:                 lr.Body.value(),
+					attributes:           jsonAttributes(lr.Attributes),
+					flags:                lr.Flags,
+				}
+				var err error
+				if r.traceId, err = hex.DecodeString(lr.TraceId); err != nil {
+					return nil, errors.WithMessagef(err, "decode traceId %s", lr.TraceId)
+				}
+				if r.spanId, err = hex.DecodeString(lr.SpanId); err != nil {
+					return nil, errors.WithMessagef(err, "decode spanId %s", lr.SpanId)
+				}
+				records = append(records, r)
+			}
+		}
+	}
+	return records, nil
+}
+
+func jsonAttributes(kvs []jsonKeyValue) map[string]interface{} {
+	if len(kvs) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(kvs))
+	for _, kv := range kvs {
+		out[kv.Key] = kv.Value.value()
+	}
+	return out
+}
+
+func (v *jsonAnyValue) value() interface{} {
+	if v == nil {
+		return nil
+	}
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return int64(*v.IntValue)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.ArrayValue != nil:
+		values := make([]interface{}, 0, len(v.ArrayValue.Values))
+		for _, av := range v.ArrayValue.Values {
+			values = append(values, av.value())
+		}
+		return values
+	case v.KvlistValue != nil:
+		kv := jsonAttributes(v.KvlistValue.Values)
+		if kv == nil {
+			kv = make(map[string]interface{})
+		}
+		return kv
+	case v.BytesValue != nil:
+		b, err := base64.StdEncoding.DecodeString(*v.BytesValue)
+		if err != nil {
+			return *v.BytesValue
+		}
+		return b
+	}
+	return nil
+}
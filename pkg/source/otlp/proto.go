@@ -0,0 +1,342 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of opentelemetry/proto/collector/logs/v1/logs_service.proto are decoded by hand,
+// which keeps the receiver free of the generated otlp packages.
+
+type scope struct {
+	name       string
+	version    string
+	attributes map[string]interface{}
+}
+
+type logRecord struct {
+	resource             map[string]interface{}
+	scope                *scope
+	timeUnixNano         uint64
+	observedTimeUnixNano uint64
+	severityNumber       int32
+	severityText         string
+	body                 interface{}
+	attributes           map[string]interface{}
+	flags                uint32
+	traceId              []byte
+	spanId               []byte
+}
+
+// exportRequest is ExportLogsServiceRequest, it implements the legacy proto message interfaces
+// so that it could be decoded by the default grpc codec.
+type exportRequest struct {
+	records []*logRecord
+}
+
+func (r *exportRequest) Reset()         { r.records = nil }
+func (r *exportRequest) String() string { return "ExportLogsServiceRequest" }
+func (r *exportRequest) ProtoMessage()  {}
+
+func (r *exportRequest) Unmarshal(b []byte) error {
+	records, err := decodeExportRequest(b)
+	if err != nil {
+		return err
+	}
+	r.records = records
+	return nil
+}
+
+// exportResponse is an empty ExportLogsServiceResponse, which indicates that all the records were accepted
+type exportResponse struct{}
+
+func (r *exportResponse) Reset()                   {}
+func (r *exportResponse) String() string           { return "ExportLogsServiceResponse" }
+func (r *exportResponse) ProtoMessage()            {}
+func (r *exportResponse) Marshal() ([]byte, error) { return []byte{}, nil }
+
+// field consumes a field of the message, fn is called with the value of length delimited fields
+// or the varint/fixed value of the others.
+type fieldFunc func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error
+
+func rangeFields(b []byte, fn fieldFunc) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var (
+			bytes []byte
+			v     uint64
+		)
+		switch typ {
+		case protowire.BytesType:
+			bytes, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(b)
+			v = uint64(v32)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, bytes, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeExportRequest(b []byte) ([]*logRecord, error) {
+	var records []*logRecord
+	err := rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		rs, err := decodeResourceLogs(bytes)
+		if err != nil {
+			return errors.WithMessage(err, "decode resource_logs")
+		}
+		records = append(records, rs...)
+		return nil
+	})
+	return records, err
+}
+
+func decodeResourceLogs(b []byte) ([]*logRecord, error) {
+	var (
+		resource  map[string]interface{}
+		scopeLogs [][]byte
+	)
+	err := rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1: // resource
+			attrs, err := decodeResource(bytes)
+			if err != nil {
+				return err
+			}
+			resource = attrs
+		case 2, 1000: // scope_logs, and the deprecated instrumentation_library_logs
+			scopeLogs = append(scopeLogs, bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*logRecord
+	for _, sl := range scopeLogs {
+		rs, err := decodeScopeLogs(sl)
+		if err != nil {
+			return nil, errors.WithMessage(err, "decode scope_logs")
+		}
+		for _, r := range rs {
+			r.resource = resource
+		}
+		records = append(records, rs...)
+	}
+	return records, nil
+}
+
+func decodeResource(b []byte) (map[string]interface{}, error) {
+	attrs := make(map[string]interface{})
+	err := rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		if num == 1 && typ == protowire.BytesType {
+			return decodeKeyValue(bytes, attrs)
+		}
+		return nil
+	})
+	return attrs, err
+}
+
+func decodeScopeLogs(b []byte) ([]*logRecord, error) {
+	s := &scope{}
+	var records []*logRecord
+	err := rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1: // scope
+			return decodeScope(bytes, s)
+		case 2: // log_records
+			r, err := decodeLogRecord(bytes)
+			if err != nil {
+				return errors.WithMessage(err, "decode log_records")
+			}
+			records = append(records, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		r.scope = s
+	}
+	return records, nil
+}
+
+func decodeScope(b []byte, s *scope) error {
+	return rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			s.name = string(bytes)
+		case 2:
+			s.version = string(bytes)
+		case 3:
+			if s.attributes == nil {
+				s.attributes = make(map[string]interface{})
+			}
+			return decodeKeyValue(bytes, s.attributes)
+		}
+		return nil
+	})
+}
+
+func decodeLogRecord(b []byte) (*logRecord, error) {
+	r := &logRecord{}
+	err := rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		switch num {
+		case 1:
+			r.timeUnixNano = v
+		case 11:
+			r.observedTimeUnixNano = v
+		case 2:
+			r.severityNumber = int32(v)
+		case 3:
+			r.severityText = string(bytes)
+		case 5:
+			body, err := decodeAnyValue(bytes)
+			if err != nil {
+				return err
+			}
+			r.body = body
+		case 6:
+			if r.attributes == nil {
+				r.attributes = make(map[string]interface{})
+			}
+			return decodeKeyValue(bytes, r.attributes)
+		case 8:
+			r.flags = uint32(v)
+		case 9:
+			r.traceId = append([]byte(nil), bytes...)
+		case 10:
+			r.spanId = append([]byte(nil), bytes...)
+		}
+		return nil
+	})
+	return r, err
+}
+
+func decodeKeyValue(b []byte, out map[string]interface{}) error {
+	var (
+		key   string
+		value interface{}
+	)
+	err := rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			key = string(bytes)
+		case 2:
+			val, err := decodeAnyValue(bytes)
+			if err != nil {
+				return err
+			}
+			value = val
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	out[key] = value
+	return nil
+}
+
+func decodeAnyValue(b []byte) (interface{}, error) {
+	var value interface{}
+	err := rangeFields(b, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+		switch num {
+		case 1:
+			value = string(bytes)
+		case 2:
+			value = v != 0
+		case 3:
+			value = int64(v)
+		case 4:
+			value = math.Float64frombits(v)
+		case 5: // array_value
+			var values []interface{}
+			err := rangeFields(bytes, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+				if num != 1 || typ != protowire.BytesType {
+					return nil
+				}
+				val, err := decodeAnyValue(bytes)
+				if err != nil {
+					return err
+				}
+				values = append(values, val)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			value = values
+		case 6: // kvlist_value
+			kv := make(map[string]interface{})
+			err := rangeFields(bytes, func(num protowire.Number, typ protowire.Type, bytes []byte, v uint64) error {
+				if num != 1 || typ != protowire.BytesType {
+					return nil
+				}
+				return decodeKeyValue(bytes, kv)
+			})
+			if err != nil {
+				return err
+			}
+			value = kv
+		case 7:
+			value = append([]byte(nil), bytes...)
+		}
+		return nil
+	})
+	return value, err
+}
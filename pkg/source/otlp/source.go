@@ -0,0 +1,347 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	_ "github.com/loggie-io/loggie/pkg/util/grpc"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+const (
+	Type = "otlp"
+
+	fTimestamp         = "timestamp"
+	fObservedTimestamp = "observedTimestamp"
+	fSeverityText      = "severityText"
+	fSeverityNumber    = "severityNumber"
+	fTraceId           = "traceId"
+	fSpanId            = "spanId"
+	fFlags             = "flags"
+	fScopeName         = "name"
+	fScopeVersion      = "version"
+	fScopeAttributes   = "attributes"
+
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJson     = "application/json"
+)
+
+func init() {
+	pipeline.Register(api.SOURCE, Type, makeSource)
+}
+
+func makeSource(info pipeline.Info) api.Component {
+	return &Source{
+		config:    &Config{},
+		eventPool: info.EventPool,
+	}
+}
+
+type Source struct {
+	name        string
+	config      *Config
+	eventPool   *event.Pool
+	productFunc api.ProductFunc
+	grpcServer  *grpc.Server
+	httpServer  *http.Server
+	stopOnce    sync.Once
+}
+
+func (s *Source) Config() interface{} {
+	return s.config
+}
+
+func (s *Source) Category() api.Category {
+	return api.SOURCE
+}
+
+func (s *Source) Type() api.Type {
+	return Type
+}
+
+func (s *Source) String() string {
+	return fmt.Sprintf("%s/%s", api.SOURCE, Type)
+}
+
+func (s *Source) Init(context api.Context) error {
+	s.name = context.Name()
+	return nil
+}
+
+func (s *Source) Start() error {
+	return nil
+}
+
+func (s *Source) Stop() {
+	s.stopOnce.Do(func() {
+		log.Info("stopping source %s: %s", Type, s.name)
+		if s.grpcServer != nil {
+			s.grpcServer.GracefulStop()
+		}
+		if s.httpServer != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = s.httpServer.Shutdown(ctx)
+		}
+	})
+}
+
+func (s *Source) ProductLoop(productFunc api.ProductFunc) {
+	log.Info("%s start product loop", s.String())
+	s.productFunc = productFunc
+
+	if s.config.GRPC.enabled() {
+		addr := fmt.Sprintf("%s:%s", s.config.GRPC.Bind, s.config.GRPC.Port)
+		listener, err := net.Listen(s.config.GRPC.Network, addr)
+		if err != nil {
+			log.Panic("otlp grpc server listen %s err: %v", addr, err)
+		}
+		s.grpcServer = grpc.NewServer(grpc.MaxRecvMsgSize(s.config.GRPC.MaxRecvMsgSize))
+		s.grpcServer.RegisterService(&logsServiceDesc, s)
+		go s.grpcServer.Serve(listener)
+		log.Info("otlp grpc server start listening: %s", addr)
+	}
+
+	if s.config.HTTP.enabled() {
+		addr := fmt.Sprintf("%s:%s", s.config.HTTP.Bind, s.config.HTTP.Port)
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Panic("otlp http server listen %s err: %v", addr, err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc(s.config.HTTP.Path, s.handleHTTP)
+		s.httpServer = &http.Server{
+			Handler:     mux,
+			ReadTimeout: s.config.HTTP.ReadTimeout,
+		}
+		go s.httpServer.Serve(listener)
+		log.Info("otlp http server start listening: %s%s", addr, s.config.HTTP.Path)
+	}
+}
+
+func (s *Source) Commit(events []api.Event) {
+	s.eventPool.PutAll(events)
+}
+
+type logsServer interface {
+	export(ctx context.Context, req *exportRequest) (*exportResponse, error)
+}
+
+var logsServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opentelemetry.proto.collector.logs.v1.LogsService",
+	HandlerType: (*logsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Export",
+			Handler:    exportHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "opentelemetry/proto/collector/logs/v1/logs_service.proto",
+}
+
+func exportHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := &exportRequest{}
+	if err := dec(in); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if interceptor == nil {
+		return srv.(logsServer).export(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opentelemetry.proto.collector.logs.v1.LogsService/Export",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(logsServer).export(ctx, req.(*exportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func (s *Source) export(ctx context.Context, req *exportRequest) (*exportResponse, error) {
+	if err := s.product(req.records); err != nil {
+		// the otlp exporters retry with backoff on UNAVAILABLE
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &exportResponse{}, nil
+}
+
+func (s *Source) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, s.config.HTTP.MaxBodyBytes)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gr, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gr.Close()
+		body = gr
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	var records []*logRecord
+	switch {
+	case strings.HasPrefix(contentType, contentTypeProtobuf):
+		records, err = decodeExportRequest(content)
+	case strings.HasPrefix(contentType, contentTypeJson):
+		records, err = decodeJsonExportRequest(content)
+	default:
+		http.Error(w, fmt.Sprintf("unsupported content type %s", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.product(records); err != nil {
+		// the otlp exporters retry with backoff on 503
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	// respond an empty ExportLogsServiceResponse in the same encoding as the request
+	if strings.HasPrefix(contentType, contentTypeJson) {
+		w.Header().Set("Content-Type", contentTypeJson)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("{}"))
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeProtobuf)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Source) product(records []*logRecord) error {
+	for _, r := range records {
+		e := s.eventPool.Get()
+		header := e.Header()
+		if header == nil {
+			header = make(map[string]interface{})
+		}
+		s.fillHeader(header, r)
+		e.Fill(e.Meta(), header, recordBody(r.body))
+
+		res := s.productFunc(e)
+		if res.Status() == api.FAIL {
+			return res.Error()
+		}
+	}
+	return nil
+}
+
+func (s *Source) fillHeader(header map[string]interface{}, r *logRecord) {
+	if r.timeUnixNano > 0 {
+		header[fTimestamp] = time.Unix(0, int64(r.timeUnixNano)).UTC().Format(time.RFC3339Nano)
+	}
+	if r.observedTimeUnixNano > 0 {
+		header[fObservedTimestamp] = time.Unix(0, int64(r.observedTimeUnixNano)).UTC().Format(time.RFC3339Nano)
+	}
+	if r.severityText != "" {
+		header[fSeverityText] = r.severityText
+	}
+	if r.severityNumber > 0 {
+		header[fSeverityNumber] = r.severityNumber
+	}
+	if len(r.traceId) > 0 {
+		header[fTraceId] = hex.EncodeToString(r.traceId)
+	}
+	if len(r.spanId) > 0 {
+		header[fSpanId] = hex.EncodeToString(r.spanId)
+	}
+	if r.flags > 0 {
+		header[fFlags] = r.flags
+	}
+
+	setFields(header, s.config.ResourceKey, r.resource)
+	if r.scope != nil && (r.scope.name != "" || r.scope.version != "" || len(r.scope.attributes) > 0) {
+		sc := make(map[string]interface{}, 3)
+		if r.scope.name != "" {
+			sc[fScopeName] = r.scope.name
+		}
+		if r.scope.version != "" {
+			sc[fScopeVersion] = r.scope.version
+		}
+		if len(r.scope.attributes) > 0 {
+			sc[fScopeAttributes] = r.scope.attributes
+		}
+		setFields(header, s.config.ScopeKey, sc)
+	}
+	setFields(header, s.config.AttributesKey, r.attributes)
+}
+
+// setFields puts the fields under key, or merges them into the header when key is empty.
+// The fields of resource and scope are shared by many records, so they are always copied.
+func setFields(header map[string]interface{}, key string, fields map[string]interface{}) {
+	if len(fields) == 0 {
+		return
+	}
+	if key == "" {
+		for k, v := range fields {
+			header[k] = v
+		}
+		return
+	}
+	out := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		out[k] = v
+	}
+	header[key] = out
+}
+
+func recordBody(body interface{}) []byte {
+	switch b := body.(type) {
+	case nil:
+		return []byte{}
+	case string:
+		return []byte(b)
+	case []byte:
+		return b
+	}
+	out, err := json.Marshal(body)
+	if err != nil {
+		return []byte(fmt.Sprintf("%v", body))
+	}
+	return out
+}
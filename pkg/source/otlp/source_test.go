@@ -0,0 +1,125 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func stringKeyValue(key string, value string) []byte {
+	kv := appendString(nil, 1, key)
+	return appendMessage(kv, 2, appendString(nil, 1, value))
+}
+
+func TestDecodeExportRequest(t *testing.T) {
+	resource := appendMessage(nil, 1, stringKeyValue("service.name", "checkout"))
+
+	scope := appendString(nil, 1, "io.opentelemetry.slf4j")
+	scope = appendString(scope, 2, "1.0.0")
+
+	intValue := protowire.AppendTag(nil, 3, protowire.VarintType)
+	intValue = protowire.AppendVarint(intValue, 42)
+	attr := appendMessage(appendString(nil, 1, "http.status"), 2, intValue)
+
+	record := protowire.AppendTag(nil, 1, protowire.Fixed64Type)
+	record = protowire.AppendFixed64(record, 1685620800000000000)
+	record = protowire.AppendTag(record, 2, protowire.VarintType)
+	record = protowire.AppendVarint(record, 17)
+	record = appendString(record, 3, "ERROR")
+	record = appendMessage(record, 5, appendString(nil, 1, "payment failed"))
+	record = appendMessage(record, 6, attr)
+	record = appendMessage(record, 9, []byte{0x01, 0x02})
+
+	scopeLogs := appendMessage(nil, 1, scope)
+	scopeLogs = appendMessage(scopeLogs, 2, record)
+
+	resourceLogs := appendMessage(nil, 1, resource)
+	resourceLogs = appendMessage(resourceLogs, 2, scopeLogs)
+
+	req := appendMessage(nil, 1, resourceLogs)
+
+	records, err := decodeExportRequest(req)
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	r := records[0]
+	assert.Equal(t, map[string]interface{}{"service.name": "checkout"}, r.resource)
+	assert.Equal(t, "io.opentelemetry.slf4j", r.scope.name)
+	assert.Equal(t, "1.0.0", r.scope.version)
+	assert.Equal(t, uint64(1685620800000000000), r.timeUnixNano)
+	assert.Equal(t, int32(17), r.severityNumber)
+	assert.Equal(t, "ERROR", r.severityText)
+	assert.Equal(t, "payment failed", r.body)
+	assert.Equal(t, map[string]interface{}{"http.status": int64(42)}, r.attributes)
+	assert.Equal(t, []byte{0x01, 0x02}, r.traceId)
+}
+
+func TestDecodeJsonExportRequest(t *testing.T) {
+	body := `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]},
+"scopeLogs":[{"scope":{"name":"app"},"logRecords":[{"timeUnixNano":"1685620800000000000","severityNumber":9,
+"severityText":"INFO","body":{"kvlistValue":{"values":[{"key":"order","value":{"intValue":"7"}}]}},
+"traceId":"5b8efff798038103d269b633813fc60c","spanId":"eee19b7ec3c1b174"}]}]}]}`
+
+	records, err := decodeJsonExportRequest([]byte(body))
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	r := records[0]
+	assert.Equal(t, map[string]interface{}{"service.name": "checkout"}, r.resource)
+	assert.Equal(t, "app", r.scope.name)
+	assert.Equal(t, uint64(1685620800000000000), r.timeUnixNano)
+	assert.Equal(t, map[string]interface{}{"order": int64(7)}, r.body)
+	assert.Equal(t, "eee19b7ec3c1b174", hex.EncodeToString(r.spanId))
+}
+
+func TestFillHeader(t *testing.T) {
+	s := &Source{config: &Config{ResourceKey: "", ScopeKey: "scope", AttributesKey: "attributes"}}
+	r := &logRecord{
+		resource:       map[string]interface{}{"service.name": "checkout"},
+		scope:          &scope{name: "app"},
+		timeUnixNano:   1685620800000000000,
+		severityText:   "WARN",
+		severityNumber: 13,
+		attributes:     map[string]interface{}{"user": "u1"},
+		spanId:         []byte{0xee, 0xe1},
+	}
+
+	header := make(map[string]interface{})
+	s.fillHeader(header, r)
+	assert.Equal(t, map[string]interface{}{
+		"service.name":   "checkout",
+		"scope":          map[string]interface{}{"name": "app"},
+		"attributes":     map[string]interface{}{"user": "u1"},
+		"timestamp":      "2023-06-01T12:00:00Z",
+		"severityText":   "WARN",
+		"severityNumber": int32(13),
+		"spanId":         "eee1",
+	}, header)
+	assert.Equal(t, []byte("{\"a\":1}"), recordBody(map[string]interface{}{"a": 1}))
+}
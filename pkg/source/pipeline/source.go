@@ -0,0 +1,151 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	loggiepipeline "github.com/loggie-io/loggie/pkg/pipeline"
+	pipelineSink "github.com/loggie-io/loggie/pkg/sink/pipeline"
+)
+
+const Type = "pipeline"
+
+var errStopped = errors.New("pipeline source is stopped")
+
+func init() {
+	loggiepipeline.Register(api.SOURCE, Type, makeSource)
+}
+
+func makeSource(info loggiepipeline.Info) api.Component {
+	return &Source{
+		pipelineName: info.PipelineName,
+		config:       &Config{},
+		eventPool:    info.EventPool,
+		done:         make(chan struct{}),
+	}
+}
+
+// Source receives the events handed off by the pipeline sinks of other pipelines in the same process
+type Source struct {
+	pipelineName string
+	name         string
+	config       *Config
+	eventPool    *event.Pool
+	endpoint     string
+	productFunc  api.ProductFunc
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *Source) Config() interface{} {
+	return s.config
+}
+
+func (s *Source) Category() api.Category {
+	return api.SOURCE
+}
+
+func (s *Source) Type() api.Type {
+	return Type
+}
+
+func (s *Source) String() string {
+	return fmt.Sprintf("%s/%s", api.SOURCE, Type)
+}
+
+func (s *Source) Init(context api.Context) error {
+	s.name = context.Name()
+	s.endpoint = s.config.Endpoint
+	if s.endpoint == "" {
+		s.endpoint = s.pipelineName
+	}
+	return nil
+}
+
+func (s *Source) Start() error {
+	return nil
+}
+
+func (s *Source) Stop() {
+	s.closeOnce.Do(func() {
+		log.Info("stopping source %s: %s", Type, s.name)
+		close(s.done)
+	})
+}
+
+func (s *Source) ProductLoop(productFunc api.ProductFunc) {
+	log.Info("%s start product loop, endpoint: %s", s.String(), s.endpoint)
+
+	s.productFunc = productFunc
+	if err := pipelineSink.Register(s.endpoint, s); err != nil {
+		log.Error("[%s] register pipeline endpoint failed: %v", s.name, err)
+		return
+	}
+	defer pipelineSink.Deregister(s.endpoint, s)
+
+	<-s.done
+}
+
+// Receive copies the event into the event pool of the pipeline, since the event would be released
+// by the pipeline of the sink after the batch is committed
+func (s *Source) Receive(e api.Event) api.Result {
+	select {
+	case <-s.done:
+		return result.Fail(errStopped)
+	default:
+	}
+
+	body := make([]byte, len(e.Body()))
+	copy(body, e.Body())
+
+	out := s.eventPool.Get()
+	meta := out.Meta()
+	if meta == nil {
+		meta = event.NewDefaultMeta()
+	}
+	out.Fill(meta, copyMap(e.Header()), body)
+
+	res := s.productFunc(out)
+	if res.Status() == api.FAIL {
+		return res
+	}
+	return result.Success()
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if sub, ok := v.(map[string]interface{}); ok {
+			v = copyMap(sub)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func (s *Source) Commit(events []api.Event) {
+	s.eventPool.PutAll(events)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/batch"
+	"github.com/loggie-io/loggie/pkg/core/cfg"
+	"github.com/loggie-io/loggie/pkg/core/context"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/core/result"
+	loggiepipeline "github.com/loggie-io/loggie/pkg/pipeline"
+	pipelineSink "github.com/loggie-io/loggie/pkg/sink/pipeline"
+)
+
+func newTestSink(t *testing.T, pipelineName string, raw string) api.Sink {
+	s, err := loggiepipeline.GetWithType(api.SINK, pipelineSink.Type, loggiepipeline.Info{PipelineName: pipelineName})
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(raw), s.Config()).Defaults().Validate().Do())
+	assert.NoError(t, s.Init(context.NewContext("handoff", pipelineSink.Type, api.SINK, nil)))
+	assert.NoError(t, s.Start())
+	return s.(api.Sink)
+}
+
+func TestHandoff(t *testing.T) {
+	log.InitDefaultLogger()
+	src := makeSource(loggiepipeline.Info{PipelineName: "output", EventPool: event.NewDefaultPool(10)}).(*Source)
+	assert.NoError(t, src.Init(context.NewContext("fromEnrich", Type, api.SOURCE, nil)))
+	assert.NoError(t, src.Start())
+
+	var lock sync.Mutex
+	var received []api.Event
+	go src.ProductLoop(func(e api.Event) api.Result {
+		lock.Lock()
+		defer lock.Unlock()
+		received = append(received, e)
+		return result.Success()
+	})
+
+	sink := newTestSink(t, "enrich", `targets: ["output"]`)
+	upstream := event.NewEvent(map[string]interface{}{
+		"fields": map[string]interface{}{"app": "nginx"},
+	}, []byte("a"))
+	assert.Eventually(t, func() bool {
+		return sink.Consume(batch.NewBatchWithEvents([]api.Event{upstream})).Status() == api.SUCCESS
+	}, time.Second, 10*time.Millisecond)
+
+	lock.Lock()
+	assert.Len(t, received, 1)
+	e := received[0]
+	lock.Unlock()
+	assert.Equal(t, "a", string(e.Body()))
+	// the upstream event is released after committed, which should not affect the received one
+	upstream.Header()["fields"].(map[string]interface{})["app"] = "redis"
+	upstream.Body()[0] = 'b'
+	assert.Equal(t, map[string]interface{}{"app": "nginx"}, e.Header()["fields"])
+	assert.Equal(t, "a", string(e.Body()))
+
+	src.Stop()
+	assert.Eventually(t, func() bool {
+		return sink.Consume(batch.NewBatchWithEvents([]api.Event{upstream})).Status() == api.FAIL
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHandoffToItself(t *testing.T) {
+	s, err := loggiepipeline.GetWithType(api.SINK, pipelineSink.Type, loggiepipeline.Info{PipelineName: "enrich"})
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.UnPackFromRaw([]byte(`targets: ["enrich"]`), s.Config()).Defaults().Validate().Do())
+	assert.Error(t, s.Init(context.NewContext("handoff", pipelineSink.Type, api.SINK, nil)))
+}
@@ -2,16 +2,25 @@ package prometheus_exporter
 
 import (
 	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"github.com/pkg/errors"
 	"net/url"
 	"time"
 )
 
+const (
+	SplitEndpoint = "endpoint"
+	SplitSample   = "sample"
+)
+
 type Config struct {
 	Endpoints []string          `yaml:"endpoints,omitempty" validate:"required"`
 	Interval  time.Duration     `yaml:"interval,omitempty" default:"30s"`
 	Timeout   time.Duration     `yaml:"timeout,omitempty" default:"5s"`
 	ToJson    bool              `yaml:"toJson,omitempty"`
 	Labels    map[string]string `yaml:"labels,omitempty"`
+	// Split could be endpoint or sample, endpoint sends all the metrics of an endpoint in one event,
+	// sample sends each sample as a json event
+	Split string `yaml:"split,omitempty" default:"endpoint" validate:"oneof=endpoint sample"`
 }
 
 func (c *Config) Validate() error {
@@ -23,6 +32,10 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.Split == SplitSample && c.ToJson {
+		return errors.New("toJson cannot be used when split is sample")
+	}
+
 	if len(c.Labels) != 0 {
 		for _, v := range c.Labels {
 			if err := pattern.Validate(v); err != nil {
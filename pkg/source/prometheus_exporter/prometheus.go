@@ -126,6 +126,13 @@ func (e *PromExporter) Commit(events []api.Event) {
 
 func (e *PromExporter) batchScrape(c ctx.Context, productFunc api.ProductFunc) {
 	for _, req := range e.requestPool {
+		if e.config.Split == SplitSample {
+			if err := e.scrapeSamples(c, req, productFunc); err != nil {
+				log.Warn("request to exporter error: %+v", err)
+			}
+			continue
+		}
+
 		metrics, err := e.scrape(c, req)
 		if err != nil {
 			log.Warn("request to exporter error: %+v", err)
@@ -140,32 +147,41 @@ func (e *PromExporter) batchScrape(c ctx.Context, productFunc api.ProductFunc) {
 }
 
 func (e *PromExporter) scrape(c ctx.Context, req *http.Request) ([]byte, error) {
+	var out []byte
+	err := e.fetch(c, req, func(body io.Reader) error {
+		if e.config.ToJson {
+			metrics, promErr := e.promToJson(body)
+			if promErr != nil {
+				return errors.WithMessage(promErr, "convert prometheus metrics to json failed")
+			}
+			out = metrics
+			return nil
+		}
+
+		metrics, readErr := io.ReadAll(body)
+		if readErr != nil {
+			return errors.WithMessage(readErr, "read response body failed")
+		}
+		out = metrics
+		return nil
+	})
+	return out, err
+}
+
+func (e *PromExporter) fetch(c ctx.Context, req *http.Request, read func(body io.Reader) error) error {
 	ct, cancel := ctx.WithTimeout(c, e.config.Timeout)
 	defer cancel()
 	resp, err := e.client.Do(req.WithContext(ct))
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("server returned HTTP status %s", resp.Status)
+		return errors.Errorf("server returned HTTP status %s", resp.Status)
 	}
 
-	if e.config.ToJson {
-		out, promErr := e.promToJson(resp.Body)
-		if promErr != nil {
-			return nil, errors.WithMessage(err, "convert prometheus metrics to json failed")
-		}
-		return out, nil
-	}
-
-	out, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, errors.WithMessage(err, "read response body failed")
-	}
-
-	return out, nil
+	return read(resp.Body)
 }
 
 func (e *PromExporter) promToJson(in io.Reader) ([]byte, error) {
@@ -0,0 +1,185 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus_exporter
+
+import (
+	ctx "context"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	timeutil "github.com/loggie-io/loggie/pkg/util/time"
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	suffixBucket = "_bucket"
+	suffixSum    = "_sum"
+	suffixCount  = "_count"
+
+	labelQuantile = "quantile"
+	labelLe       = "le"
+)
+
+// sample is a single series value as exposed in the prometheus text format,
+// histograms and summaries are expanded into their _bucket, _sum and _count series
+type sample struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Labels    map[string]string `json:"labels"`
+	Value     float64           `json:"value"`
+	Timestamp int64             `json:"timestamp"`
+	Endpoint  string            `json:"endpoint"`
+}
+
+func (e *PromExporter) scrapeSamples(c ctx.Context, req *http.Request, productFunc api.ProductFunc) error {
+	var samples []sample
+	err := e.fetch(c, req, func(body io.Reader) error {
+		var parser expfmt.TextParser
+		families, err := parser.TextToMetricFamilies(body)
+		if err != nil {
+			return errors.WithMessage(err, "reading text format failed")
+		}
+		samples = toSamples(families, timeutil.UnixMilli(time.Now()))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	extraLabels := e.renderLabels()
+	endpoint := req.URL.String()
+	for _, s := range samples {
+		// NaN and Inf could not be encoded to json
+		if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			continue
+		}
+		for k, v := range extraLabels {
+			s.Labels[k] = v
+		}
+		s.Endpoint = endpoint
+
+		out, err := json.Marshal(s)
+		if err != nil {
+			log.Warn("json marshal prometheus sample %s error: %v", s.Name, err)
+			continue
+		}
+		ev := e.eventPool.Get()
+		ev.Fill(ev.Meta(), ev.Header(), out)
+		productFunc(ev)
+	}
+	return nil
+}
+
+func (e *PromExporter) renderLabels() map[string]string {
+	if !e.extraLabelsEnable {
+		return nil
+	}
+	labels := make(map[string]string, len(e.labelPattern))
+	for key, p := range e.labelPattern {
+		val, err := p.Render()
+		if err != nil {
+			log.Warn("render label %s pattern failed: %v", key, err)
+			continue
+		}
+		labels[key] = val
+	}
+	return labels
+}
+
+func toSamples(families map[string]*dto.MetricFamily, nowMs int64) []sample {
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var samples []sample
+	for _, name := range names {
+		mf := families[name]
+		typ := mf.GetType().String()
+		for _, m := range mf.Metric {
+			ts := nowMs
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+			add := func(name string, value float64, extraName string, extraValue string) {
+				labels := make(map[string]string, len(m.Label)+1)
+				for _, lp := range m.Label {
+					labels[lp.GetName()] = lp.GetValue()
+				}
+				if extraName != "" {
+					labels[extraName] = extraValue
+				}
+				samples = append(samples, sample{
+					Name:      name,
+					Type:      typ,
+					Labels:    labels,
+					Value:     value,
+					Timestamp: ts,
+				})
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.Quantile {
+					add(name, q.GetValue(), labelQuantile, formatFloat(q.GetQuantile()))
+				}
+				add(name+suffixSum, s.GetSampleSum(), "", "")
+				add(name+suffixCount, float64(s.GetSampleCount()), "", "")
+
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				infSeen := false
+				for _, b := range h.Bucket {
+					if math.IsInf(b.GetUpperBound(), 1) {
+						infSeen = true
+					}
+					add(name+suffixBucket, float64(b.GetCumulativeCount()), labelLe, formatFloat(b.GetUpperBound()))
+				}
+				if !infSeen {
+					add(name+suffixBucket, float64(h.GetSampleCount()), labelLe, formatFloat(math.Inf(1)))
+				}
+				add(name+suffixSum, h.GetSampleSum(), "", "")
+				add(name+suffixCount, float64(h.GetSampleCount()), "", "")
+
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue(), "", "")
+
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue(), "", "")
+
+			default:
+				add(name, m.GetUntyped().GetValue(), "", "")
+			}
+		}
+	}
+	return samples
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus_exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+const exposition = `# TYPE http_requests_total counter
+http_requests_total{code="200"} 10
+http_requests_total{code="500"} 2 1700000000000
+# TYPE request_seconds histogram
+request_seconds_bucket{le="0.1"} 3
+request_seconds_bucket{le="1"} 5
+request_seconds_bucket{le="+Inf"} 6
+request_seconds_sum 4.5
+request_seconds_count 6
+# TYPE rpc_seconds summary
+rpc_seconds{quantile="0.5"} 0.2
+rpc_seconds_sum 1
+rpc_seconds_count 4
+`
+
+func TestToSamples(t *testing.T) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(exposition))
+	assert.NoError(t, err)
+
+	samples := toSamples(families, 1)
+	assert.Len(t, samples, 2+5+3)
+
+	assert.Equal(t, sample{Name: "http_requests_total", Type: "COUNTER", Labels: map[string]string{"code": "200"}, Value: 10, Timestamp: 1}, samples[0])
+	assert.Equal(t, int64(1700000000000), samples[1].Timestamp)
+
+	assert.Equal(t, sample{Name: "request_seconds_bucket", Type: "HISTOGRAM", Labels: map[string]string{"le": "+Inf"}, Value: 6, Timestamp: 1}, samples[4])
+	assert.Equal(t, "request_seconds_count", samples[6].Name)
+
+	assert.Equal(t, sample{Name: "rpc_seconds", Type: "SUMMARY", Labels: map[string]string{"quantile": "0.5"}, Value: 0.2, Timestamp: 1}, samples[7])
+	assert.Equal(t, float64(4), samples[9].Value)
+}
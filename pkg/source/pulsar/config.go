@@ -0,0 +1,153 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pulsar
+
+import (
+	"os"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/pkg/errors"
+)
+
+const (
+	SubscriptionExclusive = "exclusive"
+	SubscriptionShared    = "shared"
+	SubscriptionFailover  = "failover"
+	SubscriptionKeyShared = "key_shared"
+
+	PositionEarliest = "earliest"
+	PositionLatest   = "latest"
+
+	SchemaNone = "none"
+	SchemaJson = "json"
+	SchemaAvro = "avro"
+)
+
+type Config struct {
+	URL                        string        `yaml:"url,omitempty" validate:"required"`
+	OperationTimeout           time.Duration `yaml:"operationTimeout,omitempty" default:"30s" validate:"gt=0"`
+	ConnectionTimeout          time.Duration `yaml:"connectionTimeout,omitempty" default:"5s" validate:"gt=0"`
+	TLSTrustCertsFilePath      string        `yaml:"tlsTrustCertsFilePath,omitempty"`
+	TLSAllowInsecureConnection bool          `yaml:"tlsAllowInsecureConnection,omitempty"`
+	CertificatePath            string        `yaml:"certificatePath,omitempty"`
+	PrivateKeyPath             string        `yaml:"privateKeyPath,omitempty"`
+	Token                      string        `yaml:"token,omitempty"`
+	TokenFilePath              string        `yaml:"tokenFilePath,omitempty"`
+
+	Topics        []string `yaml:"topics,omitempty"`
+	TopicsPattern string   `yaml:"topicsPattern,omitempty"`
+	// SubscriptionName identifies the durable cursor, which is persisted by the broker when messages are acked
+	SubscriptionName    string        `yaml:"subscriptionName,omitempty" default:"loggie"`
+	SubscriptionType    string        `yaml:"subscriptionType,omitempty" default:"shared" validate:"oneof=exclusive shared failover key_shared"`
+	InitialPosition     string        `yaml:"initialPosition,omitempty" default:"latest" validate:"oneof=earliest latest"`
+	ConsumerName        string        `yaml:"consumerName,omitempty"`
+	ReceiverQueueSize   int           `yaml:"receiverQueueSize,omitempty" default:"1000"`
+	NackRedeliveryDelay time.Duration `yaml:"nackRedeliveryDelay,omitempty" default:"1m"`
+	AutoDiscoveryPeriod time.Duration `yaml:"autoDiscoveryPeriod,omitempty" default:"1m"`
+
+	Schema    Schema `yaml:"schema,omitempty"`
+	AddonMeta *bool  `yaml:"addonMeta,omitempty" default:"true"`
+}
+
+// Schema decodes the payload, json payloads are validated, avro payloads are decoded with the schema and converted to json
+type Schema struct {
+	Type       string `yaml:"type,omitempty" default:"none" validate:"oneof=none json avro"`
+	Definition string `yaml:"definition,omitempty"`
+	File       string `yaml:"file,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	if len(c.Topics) == 0 && c.TopicsPattern == "" {
+		return errors.New("topics or topicsPattern is required")
+	}
+	if len(c.Topics) > 0 && c.TopicsPattern != "" {
+		return errors.New("topics and topicsPattern cannot be set at the same time")
+	}
+	if c.CertificatePath != "" && c.PrivateKeyPath == "" {
+		return errors.New("privateKeyPath is required when certificatePath is set")
+	}
+	if c.Schema.Type == SchemaAvro && c.Schema.Definition == "" && c.Schema.File == "" {
+		return errors.New("schema definition or file is required for avro")
+	}
+	return nil
+}
+
+func (s *Schema) definition() (string, error) {
+	if s.Definition != "" {
+		return s.Definition, nil
+	}
+	content, err := os.ReadFile(s.File)
+	if err != nil {
+		return "", errors.WithMessagef(err, "read schema file %s", s.File)
+	}
+	return string(content), nil
+}
+
+func subscriptionType(t string) pulsar.SubscriptionType {
+	switch t {
+	case SubscriptionExclusive:
+		return pulsar.Exclusive
+	case SubscriptionFailover:
+		return pulsar.Failover
+	case SubscriptionKeyShared:
+		return pulsar.KeyShared
+	default:
+		return pulsar.Shared
+	}
+}
+
+func initialPosition(p string) pulsar.SubscriptionInitialPosition {
+	if p == PositionEarliest {
+		return pulsar.SubscriptionPositionEarliest
+	}
+	return pulsar.SubscriptionPositionLatest
+}
+
+func (c *Config) clientOptions() pulsar.ClientOptions {
+	opts := pulsar.ClientOptions{
+		URL:                        c.URL,
+		OperationTimeout:           c.OperationTimeout,
+		ConnectionTimeout:          c.ConnectionTimeout,
+		TLSTrustCertsFilePath:      c.TLSTrustCertsFilePath,
+		TLSAllowInsecureConnection: c.TLSAllowInsecureConnection,
+	}
+	if c.CertificatePath != "" {
+		opts.Authentication = pulsar.NewAuthenticationTLS(c.CertificatePath, c.PrivateKeyPath)
+	}
+	if c.Token != "" {
+		opts.Authentication = pulsar.NewAuthenticationToken(c.Token)
+	}
+	if c.TokenFilePath != "" {
+		opts.Authentication = pulsar.NewAuthenticationTokenFromFile(c.TokenFilePath)
+	}
+	return opts
+}
+
+func (c *Config) consumerOptions() pulsar.ConsumerOptions {
+	return pulsar.ConsumerOptions{
+		Topics:                      c.Topics,
+		TopicsPattern:               c.TopicsPattern,
+		AutoDiscoveryPeriod:         c.AutoDiscoveryPeriod,
+		SubscriptionName:            c.SubscriptionName,
+		Type:                        subscriptionType(c.SubscriptionType),
+		SubscriptionInitialPosition: initialPosition(c.InitialPosition),
+		Name:                        c.ConsumerName,
+		ReceiverQueueSize:           c.ReceiverQueueSize,
+		NackRedeliveryDelay:         c.NackRedeliveryDelay,
+	}
+}
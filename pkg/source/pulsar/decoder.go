@@ -0,0 +1,69 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pulsar
+
+import (
+	stdjson "encoding/json"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/pkg/errors"
+)
+
+type decoder interface {
+	decode(payload []byte) ([]byte, error)
+}
+
+func newDecoder(s *Schema) (decoder, error) {
+	switch s.Type {
+	case SchemaJson:
+		return &jsonDecoder{}, nil
+	case SchemaAvro:
+		def, err := s.definition()
+		if err != nil {
+			return nil, err
+		}
+		codec, err := goavro.NewCodec(def)
+		if err != nil {
+			return nil, errors.WithMessage(err, "parse avro schema")
+		}
+		return &avroDecoder{codec: codec}, nil
+	default:
+		return nil, nil
+	}
+}
+
+type jsonDecoder struct{}
+
+func (d *jsonDecoder) decode(payload []byte) ([]byte, error) {
+	if !stdjson.Valid(payload) {
+		return nil, errors.New("invalid json payload")
+	}
+	return payload, nil
+}
+
+// avroDecoder decodes the avro binary payload and encodes it as json, union values are wrapped with their type names as avro json encoding does
+type avroDecoder struct {
+	codec *goavro.Codec
+}
+
+func (d *avroDecoder) decode(payload []byte) ([]byte, error) {
+	native, _, err := d.codec.NativeFromBinary(payload)
+	if err != nil {
+		return nil, errors.WithMessage(err, "decode avro payload")
+	}
+	return d.codec.TextualFromNative(nil, native)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pulsar
+
+import (
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const avroSchema = `{"type":"record","name":"Log","fields":[{"name":"message","type":"string"},{"name":"level","type":["null","string"],"default":null}]}`
+
+func TestAvroDecoder(t *testing.T) {
+	codec, err := goavro.NewCodec(avroSchema)
+	assert.NoError(t, err)
+	payload, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"message": "hello",
+		"level":   goavro.Union("string", "info"),
+	})
+	assert.NoError(t, err)
+
+	d, err := newDecoder(&Schema{Type: SchemaAvro, Definition: avroSchema})
+	assert.NoError(t, err)
+	out, err := d.decode(payload)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"message":"hello","level":{"string":"info"}}`, string(out))
+
+	_, err = d.decode([]byte{0xff})
+	assert.Error(t, err)
+}
+
+func TestJsonDecoder(t *testing.T) {
+	d, err := newDecoder(&Schema{Type: SchemaJson})
+	assert.NoError(t, err)
+	_, err = d.decode([]byte(`{"a":1}`))
+	assert.NoError(t, err)
+	_, err = d.decode([]byte(`{"a":`))
+	assert.Error(t, err)
+
+	d, err = newDecoder(&Schema{Type: SchemaNone})
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pulsar
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const (
+	Type = "pulsar"
+
+	fPulsar      = "pulsar"
+	fTopic       = "topic"
+	fKey         = "key"
+	fMessageId   = "messageId"
+	fPublishTime = "publishTime"
+	fEventTime   = "eventTime"
+	fProperties  = "properties"
+	fRedelivery  = "redeliveryCount"
+
+	messageIdKey = event.PrivateKeyPrefix + "PulsarMessageId"
+)
+
+func init() {
+	pipeline.Register(api.SOURCE, Type, makeSource)
+}
+
+func makeSource(info pipeline.Info) api.Component {
+	return &Source{
+		done:      make(chan struct{}),
+		config:    &Config{},
+		eventPool: info.EventPool,
+	}
+}
+
+type Source struct {
+	name      string
+	done      chan struct{}
+	closeOnce sync.Once
+	config    *Config
+	eventPool *event.Pool
+	client    pulsar.Client
+	consumer  pulsar.Consumer
+	decoder   decoder
+}
+
+func (s *Source) Config() interface{} {
+	return s.config
+}
+
+func (s *Source) Category() api.Category {
+	return api.SOURCE
+}
+
+func (s *Source) Type() api.Type {
+	return Type
+}
+
+func (s *Source) String() string {
+	return fmt.Sprintf("%s/%s", api.SOURCE, Type)
+}
+
+func (s *Source) Init(context api.Context) error {
+	s.name = context.Name()
+	return nil
+}
+
+func (s *Source) Start() error {
+	d, err := newDecoder(&s.config.Schema)
+	if err != nil {
+		return err
+	}
+	s.decoder = d
+
+	s.client, err = pulsar.NewClient(s.config.clientOptions())
+	if err != nil {
+		return errors.WithMessage(err, "new pulsar client")
+	}
+	s.consumer, err = s.client.Subscribe(s.config.consumerOptions())
+	if err != nil {
+		s.client.Close()
+		return errors.WithMessagef(err, "subscribe %s", s.config.SubscriptionName)
+	}
+	log.Info("%s start, subscription: %s(%s)", s.String(), s.config.SubscriptionName, s.config.SubscriptionType)
+	return nil
+}
+
+func (s *Source) Stop() {
+	s.closeOnce.Do(func() {
+		log.Info("stopping source %s: %s", Type, s.name)
+		close(s.done)
+		if s.consumer != nil {
+			s.consumer.Close()
+		}
+		if s.client != nil {
+			s.client.Close()
+		}
+	})
+}
+
+func (s *Source) ProductLoop(productFunc api.ProductFunc) {
+	log.Info("%s start product loop", s.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.done
+		cancel()
+	}()
+
+	for {
+		msg, err := s.consumer.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn("[%s] receive pulsar message error: %v", s.name, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		s.emit(msg, productFunc)
+	}
+}
+
+func (s *Source) emit(msg pulsar.Message, productFunc api.ProductFunc) {
+	body := msg.Payload()
+	if s.decoder != nil {
+		decoded, err := s.decoder.decode(body)
+		if err != nil {
+			// the message could never be decoded, ack it to avoid redelivering forever
+			log.Warn("[%s] drop message %s of topic %s: %v", s.name, messageIdString(msg.ID()), msg.Topic(), err)
+			s.consumer.Ack(msg)
+			return
+		}
+		body = decoded
+	}
+
+	e := s.eventPool.Get()
+	header := e.Header()
+	if header == nil {
+		header = make(map[string]interface{})
+	}
+	if s.config.AddonMeta != nil && *s.config.AddonMeta {
+		meta := map[string]interface{}{
+			fTopic:       msg.Topic(),
+			fMessageId:   messageIdString(msg.ID()),
+			fPublishTime: msg.PublishTime().Format(time.RFC3339Nano),
+			fRedelivery:  msg.RedeliveryCount(),
+		}
+		if msg.Key() != "" {
+			meta[fKey] = msg.Key()
+		}
+		if !msg.EventTime().IsZero() {
+			meta[fEventTime] = msg.EventTime().Format(time.RFC3339Nano)
+		}
+		if len(msg.Properties()) > 0 {
+			meta[fProperties] = msg.Properties()
+		}
+		header[fPulsar] = meta
+	}
+
+	meta := e.Meta()
+	if meta == nil {
+		meta = event.NewDefaultMeta()
+	}
+	meta.Set(messageIdKey, msg.ID())
+	e.Fill(meta, header, body)
+
+	res := productFunc(e)
+	if res.Status() == api.DROP {
+		// dropped events would never be committed
+		s.consumer.AckID(msg.ID())
+	}
+}
+
+// messageIdString formats the id as ledgerId:entryId:partitionIdx:batchIdx, which is the same as pulsar-admin
+func messageIdString(id pulsar.MessageID) string {
+	return fmt.Sprintf("%d:%d:%d:%d", id.LedgerID(), id.EntryID(), id.PartitionIdx(), id.BatchIdx())
+}
+
+func (s *Source) Commit(events []api.Event) {
+	// the cursor of the subscription is moved forward by the broker once messages are acked
+	for _, e := range events {
+		meta := e.Meta()
+		if meta == nil {
+			continue
+		}
+		id, ok := meta.Get(messageIdKey)
+		if !ok {
+			continue
+		}
+		s.consumer.AckID(id.(pulsar.MessageID))
+	}
+	s.eventPool.PutAll(events)
+}
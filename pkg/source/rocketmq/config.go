@@ -0,0 +1,154 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rocketmq
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apache/rocketmq-client-go/v2/consumer"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/pkg/errors"
+)
+
+const (
+	ConsumeFromLast      = "last"
+	ConsumeFromFirst     = "first"
+	ConsumeFromTimestamp = "timestamp"
+
+	ModelClustering   = "clustering"
+	ModelBroadcasting = "broadcasting"
+
+	// consumeTimestampLayout is the format of consumeTimestamp required by rocketmq, e.g. 20230102150405
+	consumeTimestampLayout = "20060102150405"
+)
+
+type Config struct {
+	// NameServer or NsResolver must be set.
+	NameServer []string `yaml:"nameServer,omitempty"`
+	NsResolver []string `yaml:"nsResolver,omitempty"`
+	// Group is the consumer group, the consumed offsets, retry topic %RETRY%<group> and dead letter queue topic %DLQ%<group> belong to it
+	Group         string         `yaml:"group,omitempty" default:"loggie"`
+	Namespace     string         `yaml:"namespace,omitempty"`
+	Subscriptions []Subscription `yaml:"subscriptions,omitempty"`
+
+	Model            string `yaml:"model,omitempty" default:"clustering" validate:"oneof=clustering broadcasting"`
+	Orderly          bool   `yaml:"orderly,omitempty"`
+	ConsumeFrom      string `yaml:"consumeFrom,omitempty" default:"last" validate:"oneof=last first timestamp"`
+	ConsumeTimestamp string `yaml:"consumeTimestamp,omitempty"`
+	PullBatchSize    int32  `yaml:"pullBatchSize,omitempty" default:"32" validate:"gt=0"`
+	BatchSize        int    `yaml:"batchSize,omitempty" default:"16" validate:"gt=0"`
+	// MaxReconsumeTimes is the max retries of failed messages before they are sent to the dead letter queue
+	MaxReconsumeTimes int32 `yaml:"maxReconsumeTimes,omitempty" default:"16"`
+	// AckTimeout is how long to wait for the events to be sent by the sink, messages would be redelivered from the retry topic after that
+	AckTimeout time.Duration `yaml:"ackTimeout,omitempty" default:"1m" validate:"gt=0"`
+
+	Credentials *struct {
+		AccessKey     string `yaml:"accessKey,omitempty"`
+		SecretKey     string `yaml:"secretKey,omitempty"`
+		SecurityToken string `yaml:"securityToken,omitempty"`
+	} `yaml:"credentials,omitempty"`
+	AddonMeta *bool `yaml:"addonMeta,omitempty" default:"true"`
+}
+
+type Subscription struct {
+	Topic string `yaml:"topic,omitempty" validate:"required"`
+	// Tags filters messages by tag on the broker, all messages are consumed when it is empty
+	Tags []string `yaml:"tags,omitempty"`
+}
+
+func (s *Subscription) selector() consumer.MessageSelector {
+	expression := "*"
+	if len(s.Tags) > 0 {
+		expression = strings.Join(s.Tags, "||")
+	}
+	return consumer.MessageSelector{
+		Type:       consumer.TAG,
+		Expression: expression,
+	}
+}
+
+func (c *Config) Validate() error {
+	if len(c.NameServer) == 0 && len(c.NsResolver) == 0 {
+		return errors.New("no nameServer or nsResolver configured")
+	}
+	if len(c.Subscriptions) == 0 {
+		return errors.New("subscriptions is required")
+	}
+	for _, s := range c.Subscriptions {
+		if s.Topic == "" {
+			return errors.New("topic of subscription is required")
+		}
+	}
+	if c.ConsumeFrom == ConsumeFromTimestamp {
+		if _, err := time.Parse(consumeTimestampLayout, c.ConsumeTimestamp); err != nil {
+			return errors.Errorf("consumeTimestamp should be formatted as %s when consumeFrom is timestamp", consumeTimestampLayout)
+		}
+	}
+	if c.Credentials != nil {
+		if c.Credentials.AccessKey == "" && c.Credentials.SecretKey == "" {
+			return errors.New("the credentials must be configured completely, the accessKey, secretKey are required")
+		}
+	}
+	return nil
+}
+
+func getOptions(config *Config) []consumer.Option {
+	options := []consumer.Option{
+		consumer.WithGroupName(config.Group),
+		consumer.WithConsumerOrder(config.Orderly),
+		consumer.WithPullBatchSize(config.PullBatchSize),
+		consumer.WithConsumeMessageBatchMaxSize(config.BatchSize),
+		consumer.WithMaxReconsumeTimes(config.MaxReconsumeTimes),
+		consumer.WithInstance(os.Getenv("HOSTNAME")),
+	}
+	if config.NameServer != nil {
+		options = append(options, consumer.WithNameServer(config.NameServer))
+	}
+	if config.NsResolver != nil {
+		options = append(options, consumer.WithNsResolver(primitive.NewPassthroughResolver(config.NsResolver)))
+	}
+	if config.Namespace != "" {
+		options = append(options, consumer.WithNamespace(config.Namespace))
+	}
+
+	if config.Model == ModelBroadcasting {
+		options = append(options, consumer.WithConsumerModel(consumer.BroadCasting))
+	} else {
+		options = append(options, consumer.WithConsumerModel(consumer.Clustering))
+	}
+
+	switch config.ConsumeFrom {
+	case ConsumeFromFirst:
+		options = append(options, consumer.WithConsumeFromWhere(consumer.ConsumeFromFirstOffset))
+	case ConsumeFromTimestamp:
+		options = append(options, consumer.WithConsumeFromWhere(consumer.ConsumeFromTimestamp),
+			consumer.WithConsumeTimestamp(config.ConsumeTimestamp))
+	default:
+		options = append(options, consumer.WithConsumeFromWhere(consumer.ConsumeFromLastOffset))
+	}
+
+	if config.Credentials != nil {
+		options = append(options, consumer.WithCredentials(primitive.Credentials{
+			AccessKey:     config.Credentials.AccessKey,
+			SecretKey:     config.Credentials.SecretKey,
+			SecurityToken: config.Credentials.SecurityToken,
+		}))
+	}
+	return options
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rocketmq
+
+import (
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"strings"
+)
+
+type rocketMQLogWrapper struct {
+}
+
+// newLoggieLogWrapper returns a new logger that wraps the loggie logger, witch implements the rocketmq logger interface.
+// it aims to make the rocketmq logger compatible with loggie to avid rocketmq using its own log format.
+func newLoggieLogWrapper() *rocketMQLogWrapper {
+	return &rocketMQLogWrapper{}
+}
+
+func (r rocketMQLogWrapper) Debug(msg string, fields map[string]interface{}) {
+	log.Debug("rocketmq source client: %s, %v", msg, fields)
+}
+
+func (r rocketMQLogWrapper) Info(msg string, fields map[string]interface{}) {
+	log.Info("rocketmq source client: %s, %v", msg, fields)
+}
+
+func (r rocketMQLogWrapper) Warning(msg string, fields map[string]interface{}) {
+	log.Warn("rocketmq source client: %s, %v", msg, fields)
+}
+
+func (r rocketMQLogWrapper) Error(msg string, fields map[string]interface{}) {
+	log.Error("rocketmq source client: %s, %v", msg, fields)
+}
+
+func (r rocketMQLogWrapper) Fatal(msg string, fields map[string]interface{}) {
+	log.Fatal("rocketmq source client: %s, %v", msg, fields)
+}
+
+func (r rocketMQLogWrapper) Level(level string) {
+	switch strings.ToLower(level) {
+	case "debug":
+	case "warn":
+	case "error":
+	case "fatal":
+	default:
+	}
+}
+
+func (r rocketMQLogWrapper) OutputPath(path string) (err error) {
+	return nil
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rocketmq
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/rocketmq-client-go/v2"
+	"github.com/apache/rocketmq-client-go/v2/consumer"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/apache/rocketmq-client-go/v2/rlog"
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+)
+
+const (
+	Type = "rocketmq"
+
+	fRocketmq       = "rocketmq"
+	fTopic          = "topic"
+	fTags           = "tags"
+	fKeys           = "keys"
+	fMsgId          = "msgId"
+	fBrokerName     = "brokerName"
+	fQueueId        = "queueId"
+	fQueueOffset    = "queueOffset"
+	fBornTimestamp  = "bornTimestamp"
+	fStoreTimestamp = "storeTimestamp"
+	fReconsumeTimes = "reconsumeTimes"
+	fRetry          = "retry"
+	fDLQ            = "dlq"
+
+	dlqTopicPrefix = "%DLQ%"
+
+	ackKey = event.PrivateKeyPrefix + "RocketmqAck"
+)
+
+func init() {
+	pipeline.Register(api.SOURCE, Type, makeSource)
+}
+
+func makeSource(info pipeline.Info) api.Component {
+	return &Source{
+		done:      make(chan struct{}),
+		config:    &Config{},
+		eventPool: info.EventPool,
+	}
+}
+
+type Source struct {
+	name        string
+	done        chan struct{}
+	closeOnce   sync.Once
+	config      *Config
+	eventPool   *event.Pool
+	consumer    rocketmq.PushConsumer
+	productFunc api.ProductFunc
+	ready       chan struct{}
+}
+
+func (s *Source) Config() interface{} {
+	return s.config
+}
+
+func (s *Source) Category() api.Category {
+	return api.SOURCE
+}
+
+func (s *Source) Type() api.Type {
+	return Type
+}
+
+func (s *Source) String() string {
+	return fmt.Sprintf("%s/%s", api.SOURCE, Type)
+}
+
+func (s *Source) Init(context api.Context) error {
+	s.name = context.Name()
+	s.ready = make(chan struct{})
+	return nil
+}
+
+func (s *Source) Start() error {
+	rlog.SetLogger(newLoggieLogWrapper())
+
+	c, err := rocketmq.NewPushConsumer(getOptions(s.config)...)
+	if err != nil {
+		return errors.WithMessage(err, "new rocketmq push consumer")
+	}
+	for _, sub := range s.config.Subscriptions {
+		if err := c.Subscribe(sub.Topic, sub.selector(), s.consume); err != nil {
+			return errors.WithMessagef(err, "subscribe topic %s", sub.Topic)
+		}
+	}
+	if err := c.Start(); err != nil {
+		return errors.WithMessage(err, "start rocketmq push consumer")
+	}
+	s.consumer = c
+	log.Info("%s start, group: %s", s.String(), s.config.Group)
+	return nil
+}
+
+func (s *Source) Stop() {
+	s.closeOnce.Do(func() {
+		log.Info("stopping source %s: %s", Type, s.name)
+		close(s.done)
+		if s.consumer != nil {
+			_ = s.consumer.Shutdown()
+		}
+	})
+}
+
+// ProductLoop hands the productFunc to the push consumer, messages are delivered by the consumer goroutines
+func (s *Source) ProductLoop(productFunc api.ProductFunc) {
+	log.Info("%s start product loop", s.String())
+	s.productFunc = productFunc
+	close(s.ready)
+	<-s.done
+}
+
+// consume blocks until all the events of the messages have been committed by the sink, so the consumed offset
+// would never be ahead of the sink. Messages are sent back to the retry topic %RETRY%<group> when the events
+// were failed or not committed in time, and moved to the dead letter queue after maxReconsumeTimes.
+func (s *Source) consume(ctx context.Context, msgs ...*primitive.MessageExt) (consumer.ConsumeResult, error) {
+	select {
+	case <-s.ready:
+	case <-s.done:
+		return s.retryResult(), errors.New("source stopped")
+	}
+
+	ack := newBatchAck(len(msgs))
+	for _, msg := range msgs {
+		e := s.eventPool.Get()
+		header := e.Header()
+		if header == nil {
+			header = make(map[string]interface{})
+		}
+		if s.config.AddonMeta != nil && *s.config.AddonMeta {
+			header[fRocketmq] = addonMeta(msg)
+		}
+		meta := e.Meta()
+		if meta == nil {
+			meta = event.NewDefaultMeta()
+		}
+		meta.Set(ackKey, ack)
+		e.Fill(meta, header, msg.Body)
+
+		res := s.productFunc(e)
+		switch res.Status() {
+		case api.DROP:
+			// dropped events would never be committed
+			ack.ack()
+		case api.FAIL:
+			s.warnRetry(msg, res.Error())
+			return s.retryResult(), res.Error()
+		}
+	}
+
+	timer := time.NewTimer(s.config.AckTimeout)
+	defer timer.Stop()
+	select {
+	case <-ack.done:
+		return consumer.ConsumeSuccess, nil
+	case <-timer.C:
+		err := errors.Errorf("events not committed in %s", s.config.AckTimeout)
+		for _, msg := range msgs {
+			s.warnRetry(msg, err)
+		}
+		return s.retryResult(), err
+	case <-s.done:
+		return s.retryResult(), errors.New("source stopped")
+	}
+}
+
+func (s *Source) retryResult() consumer.ConsumeResult {
+	if s.config.Orderly {
+		return consumer.SuspendCurrentQueueAMoment
+	}
+	return consumer.ConsumeRetryLater
+}
+
+func (s *Source) warnRetry(msg *primitive.MessageExt, err error) {
+	if !s.config.Orderly && s.config.MaxReconsumeTimes >= 0 && msg.ReconsumeTimes >= s.config.MaxReconsumeTimes {
+		log.Warn("[%s] message %s of topic %s reached maxReconsumeTimes, it would be moved to %s%s: %v",
+			s.name, msg.MsgId, msg.Topic, dlqTopicPrefix, s.config.Group, err)
+		return
+	}
+	log.Warn("[%s] message %s of topic %s would be consumed again: %v", s.name, msg.MsgId, msg.Topic, err)
+}
+
+func addonMeta(msg *primitive.MessageExt) map[string]interface{} {
+	meta := map[string]interface{}{
+		fTopic:          msg.Topic,
+		fMsgId:          msg.MsgId,
+		fQueueOffset:    msg.QueueOffset,
+		fBornTimestamp:  msg.BornTimestamp,
+		fStoreTimestamp: msg.StoreTimestamp,
+		fReconsumeTimes: msg.ReconsumeTimes,
+		// the real topic is restored by the consumer for messages redelivered from the retry topic
+		fRetry: msg.ReconsumeTimes > 0 || msg.GetProperty(primitive.PropertyRetryTopic) != "",
+		fDLQ:   strings.HasPrefix(msg.Topic, dlqTopicPrefix),
+	}
+	if tags := msg.GetTags(); tags != "" {
+		meta[fTags] = tags
+	}
+	if keys := msg.GetKeys(); keys != "" {
+		meta[fKeys] = keys
+	}
+	if msg.Queue != nil {
+		meta[fBrokerName] = msg.Queue.BrokerName
+		meta[fQueueId] = msg.Queue.QueueId
+	}
+	return meta
+}
+
+func (s *Source) Commit(events []api.Event) {
+	for _, e := range events {
+		meta := e.Meta()
+		if meta == nil {
+			continue
+		}
+		ack, ok := meta.Get(ackKey)
+		if !ok {
+			continue
+		}
+		ack.(*batchAck).ack()
+	}
+	s.eventPool.PutAll(events)
+}
+
+// batchAck is shared by the events of the messages delivered in one consume call
+type batchAck struct {
+	pending int32
+	done    chan struct{}
+}
+
+func newBatchAck(n int) *batchAck {
+	b := &batchAck{
+		pending: int32(n),
+		done:    make(chan struct{}),
+	}
+	if n == 0 {
+		close(b.done)
+	}
+	return b
+}
+
+func (b *batchAck) ack() {
+	if atomic.AddInt32(&b.pending, -1) == 0 {
+		close(b.done)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rocketmq
+
+import (
+	"testing"
+
+	"github.com/apache/rocketmq-client-go/v2/consumer"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelector(t *testing.T) {
+	all := Subscription{Topic: "app"}
+	assert.Equal(t, consumer.MessageSelector{Type: consumer.TAG, Expression: "*"}, all.selector())
+
+	tagged := Subscription{Topic: "app", Tags: []string{"nginx", "java"}}
+	assert.Equal(t, consumer.MessageSelector{Type: consumer.TAG, Expression: "nginx||java"}, tagged.selector())
+}
+
+func TestAddonMeta(t *testing.T) {
+	msg := &primitive.MessageExt{
+		Message:        primitive.Message{Topic: "app", Queue: &primitive.MessageQueue{BrokerName: "broker-a", QueueId: 2}},
+		MsgId:          "id",
+		ReconsumeTimes: 1,
+	}
+	msg.WithTag("nginx")
+	msg.WithProperty(primitive.PropertyRetryTopic, "%RETRY%loggie")
+
+	meta := addonMeta(msg)
+	assert.Equal(t, "app", meta[fTopic])
+	assert.Equal(t, "nginx", meta[fTags])
+	assert.Equal(t, "broker-a", meta[fBrokerName])
+	assert.Equal(t, 2, meta[fQueueId])
+	assert.Equal(t, true, meta[fRetry])
+	assert.Equal(t, false, meta[fDLQ])
+
+	dlq := &primitive.MessageExt{Message: primitive.Message{Topic: "%DLQ%loggie"}}
+	meta = addonMeta(dlq)
+	assert.Equal(t, false, meta[fRetry])
+	assert.Equal(t, true, meta[fDLQ])
+}
+
+func TestBatchAck(t *testing.T) {
+	b := newBatchAck(2)
+	b.ack()
+	select {
+	case <-b.done:
+		t.Fatal("acked before all events were committed")
+	default:
+	}
+	b.ack()
+	<-b.done
+}
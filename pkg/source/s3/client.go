@@ -0,0 +1,174 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/aws"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/pkg/errors"
+)
+
+const (
+	sqsService      = "sqs"
+	sqsTargetPrefix = "AmazonSQS."
+	sqsContentType  = "application/x-amz-json-1.0"
+)
+
+type message struct {
+	MessageId     string `json:"MessageId"`
+	ReceiptHandle string `json:"ReceiptHandle"`
+	Body          string `json:"Body"`
+}
+
+type receiveMessageResponse struct {
+	Messages []message `json:"Messages"`
+}
+
+type client struct {
+	config *Config
+	creds  *aws.CredentialsProvider
+	http   *http.Client
+}
+
+func newClient(config *Config) *client {
+	return &client{
+		config: config,
+		creds:  aws.NewCredentialsProvider(&config.Config),
+		http:   &http.Client{},
+	}
+}
+
+func (c *client) receiveMessage(ctx context.Context) ([]message, error) {
+	req := map[string]interface{}{
+		"QueueUrl":            c.config.QueueUrl,
+		"MaxNumberOfMessages": c.config.MaxMessages,
+		"WaitTimeSeconds":     int(c.config.WaitTime.Seconds()),
+		"VisibilityTimeout":   int(c.config.VisibilityTimeout.Seconds()),
+	}
+	out := &receiveMessageResponse{}
+	if err := c.callSQS(ctx, "ReceiveMessage", req, out); err != nil {
+		return nil, err
+	}
+	return out.Messages, nil
+}
+
+func (c *client) deleteMessage(ctx context.Context, receiptHandle string) error {
+	req := map[string]interface{}{
+		"QueueUrl":      c.config.QueueUrl,
+		"ReceiptHandle": receiptHandle,
+	}
+	return c.callSQS(ctx, "DeleteMessage", req, nil)
+}
+
+func (c *client) sqsEndpoint() string {
+	if c.config.SQSEndpoint != "" {
+		return c.config.SQSEndpoint
+	}
+	cfg := c.config.Config
+	cfg.Endpoint = ""
+	return cfg.ServiceEndpoint(sqsService)
+}
+
+func (c *client) callSQS(ctx context.Context, action string, in interface{}, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.sqsEndpoint()+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", sqsContentType)
+	req.Header.Set("X-Amz-Target", sqsTargetPrefix+action)
+
+	if err := c.sign(ctx, req, body, sqsService); err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errors.WithMessagef(err, "sqs %s", action)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.WithMessagef(err, "read sqs %s response", action)
+	}
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("sqs %s returned status %d: %s", action, resp.StatusCode, string(respBody))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func (c *client) objectURL(bucket string, key string) string {
+	escaped := aws.EscapePath(key)
+	if c.config.Endpoint != "" || c.config.ForcePathStyle {
+		cfg := c.config.Config
+		return fmt.Sprintf("%s/%s/%s", cfg.ServiceEndpoint(aws.ServiceS3), bucket, escaped)
+	}
+	endpoint := strings.TrimPrefix(c.config.ServiceEndpoint(aws.ServiceS3), "https://")
+	return fmt.Sprintf("https://%s.%s/%s", bucket, endpoint, escaped)
+}
+
+// getObject returns the object body, the caller should close it
+func (c *client) getObject(ctx context.Context, bucket string, key string) (io.ReadCloser, string, error) {
+	u, err := url.Parse(c.objectURL(bucket, key))
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := c.sign(ctx, req, nil, aws.ServiceS3); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, "", errors.WithMessagef(err, "get object s3://%s/%s", bucket, key)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, "", errors.Errorf("get object s3://%s/%s returned status %d: %s", bucket, key, resp.StatusCode, string(b))
+	}
+	return resp.Body, resp.Header.Get("Content-Encoding"), nil
+}
+
+func (c *client) sign(ctx context.Context, req *http.Request, body []byte, service string) error {
+	creds, err := c.creds.Retrieve(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "retrieve aws credentials")
+	}
+	aws.Sign(req, body, service, c.config.Region, creds, time.Now())
+	return nil
+}
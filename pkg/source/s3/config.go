@@ -0,0 +1,58 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/aws"
+	"github.com/pkg/errors"
+)
+
+const (
+	SplitLine    = "line"
+	SplitRecords = "records"
+)
+
+type Config struct {
+	aws.Config `yaml:",inline"`
+
+	QueueUrl string `yaml:"queueUrl,omitempty" validate:"required"`
+	// SQSEndpoint overrides the sqs endpoint, Endpoint in aws.Config is used for s3
+	SQSEndpoint       string        `yaml:"sqsEndpoint,omitempty"`
+	ForcePathStyle    bool          `yaml:"forcePathStyle,omitempty"`
+	Worker            int           `yaml:"worker,omitempty" default:"1" validate:"gte=1"`
+	MaxMessages       int           `yaml:"maxMessages,omitempty" default:"5" validate:"gte=1,lte=10"`
+	WaitTime          time.Duration `yaml:"waitTime,omitempty" default:"20s"`
+	VisibilityTimeout time.Duration `yaml:"visibilityTimeout,omitempty" default:"5m"`
+	Timeout           time.Duration `yaml:"timeout,omitempty" default:"30s"`
+	// Split could be line or records, records splits the `Records` array of json objects such as CloudTrail logs
+	Split        string   `yaml:"split,omitempty" default:"line" validate:"oneof=line records"`
+	MaxLineBytes int      `yaml:"maxLineBytes,omitempty" default:"1048576"`
+	KeyPrefixes  []string `yaml:"keyPrefixes,omitempty"`
+	AddonMeta    *bool    `yaml:"addonMeta,omitempty" default:"true"`
+}
+
+func (c *Config) Validate() error {
+	if err := c.Config.Validate(); err != nil {
+		return err
+	}
+	if c.WaitTime > 20*time.Second {
+		return errors.New("sqs waitTime cannot be longer than 20s")
+	}
+	return nil
+}
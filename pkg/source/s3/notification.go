@@ -0,0 +1,89 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/pkg/errors"
+)
+
+const (
+	snsNotificationType = "Notification"
+	objectCreatedPrefix = "ObjectCreated:"
+)
+
+type object struct {
+	Bucket string
+	Key    string
+	Size   int64
+}
+
+type snsEnvelope struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+type s3Notification struct {
+	Event   string `json:"Event"`
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key  string `json:"key"`
+				Size int64  `json:"size"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// parseNotification extracts the created objects from a sqs message body,
+// the body may be a s3 event notification or wrapped in a sns notification.
+func parseNotification(body string) ([]object, error) {
+	envelope := &snsEnvelope{}
+	if err := json.Unmarshal([]byte(body), envelope); err == nil && envelope.Type == snsNotificationType {
+		body = envelope.Message
+	}
+
+	n := &s3Notification{}
+	if err := json.Unmarshal([]byte(body), n); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal s3 event notification")
+	}
+
+	var objects []object
+	for _, r := range n.Records {
+		if !strings.HasPrefix(r.EventName, objectCreatedPrefix) {
+			continue
+		}
+		// object keys in notifications are url encoded, spaces are replaced with '+'
+		key, err := url.QueryUnescape(r.S3.Object.Key)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "unescape object key %s", r.S3.Object.Key)
+		}
+		objects = append(objects, object{
+			Bucket: r.S3.Bucket.Name,
+			Key:    key,
+			Size:   r.S3.Object.Size,
+		})
+	}
+	return objects, nil
+}
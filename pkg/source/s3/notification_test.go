@@ -0,0 +1,80 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNotification(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []object
+	}{
+		{
+			name: "s3 notification",
+			body: `{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"logs"},"object":{"key":"AWSLogs/a+b%3D.gz","size":10}}}]}`,
+			want: []object{{Bucket: "logs", Key: "AWSLogs/a b=.gz", Size: 10}},
+		},
+		{
+			name: "sns wrapped",
+			body: `{"Type":"Notification","Message":"{\"Records\":[{\"eventName\":\"ObjectCreated:Post\",\"s3\":{\"bucket\":{\"name\":\"logs\"},\"object\":{\"key\":\"x.log\",\"size\":1}}}]}"}`,
+			want: []object{{Bucket: "logs", Key: "x.log", Size: 1}},
+		},
+		{
+			name: "test event",
+			body: `{"Service":"Amazon S3","Event":"s3:TestEvent","Bucket":"logs"}`,
+			want: nil,
+		},
+		{
+			name: "ignore removed objects",
+			body: `{"Records":[{"eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"logs"},"object":{"key":"x.log"}}}]}`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNotification(tt.body)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := newTracker()
+	tr.begin("m1", "r1")
+	tr.add("m1")
+	tr.add("m1")
+
+	_, ok := tr.ack("m1")
+	assert.False(t, ok)
+	_, ok = tr.seal("m1")
+	assert.False(t, ok)
+	receipt, ok := tr.ack("m1")
+	assert.True(t, ok)
+	assert.Equal(t, "r1", receipt)
+
+	tr.begin("m2", "r2")
+	tr.add("m2")
+	tr.fail("m2")
+	_, ok = tr.ack("m2")
+	assert.False(t, ok)
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	stdjson "encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/pipeline"
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/pkg/errors"
+)
+
+const (
+	Type = "s3"
+
+	fS3        = "s3"
+	fBucket    = "bucket"
+	fKey       = "key"
+	fMessageId = "messageId"
+
+	messageIdKey = event.PrivateKeyPrefix + "S3MessageId"
+)
+
+func init() {
+	pipeline.Register(api.SOURCE, Type, makeSource)
+}
+
+func makeSource(info pipeline.Info) api.Component {
+	return &Source{
+		done:      make(chan struct{}),
+		config:    &Config{},
+		eventPool: info.EventPool,
+		tracker:   newTracker(),
+	}
+}
+
+type Source struct {
+	name      string
+	done      chan struct{}
+	closeOnce sync.Once
+	config    *Config
+	cli       *client
+	eventPool *event.Pool
+	tracker   *tracker
+}
+
+func (s *Source) Config() interface{} {
+	return s.config
+}
+
+func (s *Source) Category() api.Category {
+	return api.SOURCE
+}
+
+func (s *Source) Type() api.Type {
+	return Type
+}
+
+func (s *Source) String() string {
+	return fmt.Sprintf("%s/%s", api.SOURCE, Type)
+}
+
+func (s *Source) Init(context api.Context) error {
+	s.name = context.Name()
+	return nil
+}
+
+func (s *Source) Start() error {
+	s.cli = newClient(s.config)
+	log.Info("%s start, queue: %s", s.String(), s.config.QueueUrl)
+	return nil
+}
+
+func (s *Source) Stop() {
+	s.closeOnce.Do(func() {
+		log.Info("stopping source %s: %s", Type, s.name)
+		close(s.done)
+	})
+}
+
+func (s *Source) ProductLoop(productFunc api.ProductFunc) {
+	log.Info("%s start product loop", s.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.done
+		cancel()
+	}()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < s.config.Worker; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-s.done:
+					return
+				default:
+				}
+
+				msgs, err := s.cli.receiveMessage(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Warn("[%s] receive sqs message error: %v", s.name, err)
+						time.Sleep(time.Second)
+					}
+					continue
+				}
+				for _, m := range msgs {
+					s.handleMessage(ctx, m, productFunc)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Source) handleMessage(ctx context.Context, m message, productFunc api.ProductFunc) {
+	objects, err := parseNotification(m.Body)
+	if err != nil {
+		// keep the message, it would be moved to the dead letter queue by the sqs redrive policy if there is one
+		log.Warn("[%s] parse sqs message %s error: %v", s.name, m.MessageId, err)
+		return
+	}
+
+	s.tracker.begin(m.MessageId, m.ReceiptHandle)
+	for _, o := range objects {
+		if !s.matchPrefix(o.Key) {
+			continue
+		}
+		if err := s.readObject(ctx, m.MessageId, o, productFunc); err != nil {
+			log.Warn("[%s] read object s3://%s/%s error: %v", s.name, o.Bucket, o.Key, err)
+			s.tracker.fail(m.MessageId)
+			return
+		}
+	}
+
+	if receipt, ok := s.tracker.seal(m.MessageId); ok {
+		s.deleteMessage(receipt)
+	}
+}
+
+func (s *Source) matchPrefix(key string) bool {
+	if len(s.config.KeyPrefixes) == 0 {
+		return true
+	}
+	for _, p := range s.config.KeyPrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Source) readObject(ctx context.Context, messageId string, o object, productFunc api.ProductFunc) error {
+	ct, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	body, encoding, err := s.cli.getObject(ct, o.Bucket, o.Key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	r, err := decompress(body, encoding)
+	if err != nil {
+		return err
+	}
+
+	emit := func(data []byte) error {
+		return s.emit(messageId, o, data, productFunc)
+	}
+	if s.config.Split == SplitRecords {
+		return splitRecords(r, emit)
+	}
+	return splitLines(r, s.config.MaxLineBytes, emit)
+}
+
+func (s *Source) emit(messageId string, o object, data []byte, productFunc api.ProductFunc) error {
+	e := s.eventPool.Get()
+	header := e.Header()
+	if header == nil {
+		header = make(map[string]interface{})
+	}
+	if s.config.AddonMeta != nil && *s.config.AddonMeta {
+		header[fS3] = map[string]interface{}{
+			fBucket:    o.Bucket,
+			fKey:       o.Key,
+			fMessageId: messageId,
+		}
+	}
+	meta := e.Meta()
+	if meta == nil {
+		meta = event.NewDefaultMeta()
+	}
+	meta.Set(messageIdKey, messageId)
+	e.Fill(meta, header, data)
+
+	s.tracker.add(messageId)
+	res := productFunc(e)
+	switch res.Status() {
+	case api.DROP:
+		// dropped events would never be committed
+		if receipt, ok := s.tracker.ack(messageId); ok {
+			s.deleteMessage(receipt)
+		}
+	case api.FAIL:
+		s.tracker.ack(messageId)
+		return errors.WithMessage(res.Error(), "product event")
+	}
+	return nil
+}
+
+func decompress(body io.Reader, encoding string) (io.Reader, error) {
+	br := bufio.NewReader(body)
+	magic, _ := br.Peek(2)
+	if encoding == "gzip" || (len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b) {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, errors.WithMessage(err, "new gzip reader")
+		}
+		return gr, nil
+	}
+	return br, nil
+}
+
+func splitLines(r io.Reader, maxLineBytes int, emit func([]byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		data := make([]byte, len(line))
+		copy(data, line)
+		if err := emit(data); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+type recordsObject struct {
+	Records []stdjson.RawMessage `json:"Records"`
+}
+
+func splitRecords(r io.Reader, emit func([]byte) error) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	out := &recordsObject{}
+	if err := json.Unmarshal(content, out); err != nil {
+		return errors.WithMessage(err, "unmarshal records")
+	}
+	for _, rec := range out.Records {
+		if err := emit(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Source) deleteMessage(receiptHandle string) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	if err := s.cli.deleteMessage(ctx, receiptHandle); err != nil {
+		log.Warn("[%s] delete sqs message error: %v", s.name, err)
+	}
+}
+
+func (s *Source) Commit(events []api.Event) {
+	for _, e := range events {
+		id, ok := e.Meta().Get(messageIdKey)
+		if !ok {
+			continue
+		}
+		if receipt, ok := s.tracker.ack(id.(string)); ok {
+			s.deleteMessage(receipt)
+		}
+	}
+	s.eventPool.PutAll(events)
+}
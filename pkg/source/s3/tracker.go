@@ -0,0 +1,101 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import "sync"
+
+type inflight struct {
+	receiptHandle string
+	pending       int
+	sealed        bool
+	failed        bool
+}
+
+// tracker counts the events of each sqs message which have not been acked by the sink yet,
+// a message can only be deleted after it has been fully read and all its events were acked.
+type tracker struct {
+	lock     sync.Mutex
+	messages map[string]*inflight
+}
+
+func newTracker() *tracker {
+	return &tracker{
+		messages: make(map[string]*inflight),
+	}
+}
+
+func (t *tracker) begin(id string, receiptHandle string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.messages[id] = &inflight{receiptHandle: receiptHandle}
+}
+
+func (t *tracker) add(id string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if m, ok := t.messages[id]; ok {
+		m.pending++
+	}
+}
+
+// ack returns the receipt handle when the message is ready to be deleted
+func (t *tracker) ack(id string) (string, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	m, ok := t.messages[id]
+	if !ok {
+		return "", false
+	}
+	m.pending--
+	return t.tryFinish(id, m)
+}
+
+// seal marks the message as fully read
+func (t *tracker) seal(id string) (string, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	m, ok := t.messages[id]
+	if !ok {
+		return "", false
+	}
+	m.sealed = true
+	return t.tryFinish(id, m)
+}
+
+// fail marks the message as failed, so it would never be deleted and will be redelivered by sqs after the visibility timeout
+func (t *tracker) fail(id string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	m, ok := t.messages[id]
+	if !ok {
+		return
+	}
+	m.failed = true
+	m.sealed = true
+	t.tryFinish(id, m)
+}
+
+func (t *tracker) tryFinish(id string, m *inflight) (string, bool) {
+	if !m.sealed || m.pending > 0 {
+		return "", false
+	}
+	delete(t.messages, id)
+	if m.failed {
+		return "", false
+	}
+	return m.receiptHandle, true
+}
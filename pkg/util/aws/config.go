@@ -0,0 +1,73 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	envRegion        = "AWS_REGION"
+	envDefaultRegion = "AWS_DEFAULT_REGION"
+)
+
+// Config is the common AWS client configuration shared by the components talking to AWS services.
+type Config struct {
+	Region          string `yaml:"region,omitempty"`
+	Endpoint        string `yaml:"endpoint,omitempty"`
+	AccessKeyId     string `yaml:"accessKeyId,omitempty"`
+	SecretAccessKey string `yaml:"secretAccessKey,omitempty"`
+	SessionToken    string `yaml:"sessionToken,omitempty"`
+	// RoleArn and WebIdentityTokenFile override AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE, used by IRSA
+	RoleArn              string `yaml:"roleArn,omitempty"`
+	WebIdentityTokenFile string `yaml:"webIdentityTokenFile,omitempty"`
+	// DisableIMDS disables fetching credentials from the EC2 instance metadata service
+	DisableIMDS bool `yaml:"disableIMDS,omitempty"`
+}
+
+func (c *Config) SetDefaults() {
+	if c.Region == "" {
+		c.Region = os.Getenv(envRegion)
+	}
+	if c.Region == "" {
+		c.Region = os.Getenv(envDefaultRegion)
+	}
+}
+
+func (c *Config) Validate() error {
+	if c.Region == "" {
+		return fmt.Errorf("aws region is required")
+	}
+	if (c.AccessKeyId == "") != (c.SecretAccessKey == "") {
+		return fmt.Errorf("aws accessKeyId and secretAccessKey must be set together")
+	}
+	return nil
+}
+
+// ServiceEndpoint returns the configured endpoint, or the default endpoint of the service in the region.
+func (c *Config) ServiceEndpoint(service string) string {
+	if c.Endpoint != "" {
+		return strings.TrimSuffix(c.Endpoint, "/")
+	}
+	domain := "amazonaws.com"
+	if strings.HasPrefix(c.Region, "cn-") {
+		domain = "amazonaws.com.cn"
+	}
+	return fmt.Sprintf("https://%s.%s.%s", service, c.Region, domain)
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/pkg/errors"
+)
+
+const (
+	envAccessKeyId          = "AWS_ACCESS_KEY_ID"
+	envSecretAccessKey      = "AWS_SECRET_ACCESS_KEY"
+	envSessionToken         = "AWS_SESSION_TOKEN"
+	envRoleArn              = "AWS_ROLE_ARN"
+	envWebIdentityTokenFile = "AWS_WEB_IDENTITY_TOKEN_FILE"
+
+	imdsEndpoint = "http://169.254.169.254"
+
+	// refresh temporary credentials a little earlier than they actually expire
+	expiryWindow = 5 * time.Minute
+)
+
+type Credentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time
+}
+
+func (c *Credentials) expired() bool {
+	if c.Expires.IsZero() {
+		return false
+	}
+	return time.Now().Add(expiryWindow).After(c.Expires)
+}
+
+// CredentialsProvider resolves credentials in the following order:
+// static keys in config, environment variables, web identity token (IRSA), EC2 instance profile.
+type CredentialsProvider struct {
+	config *Config
+	client *http.Client
+
+	lock   sync.Mutex
+	cached *Credentials
+}
+
+func NewCredentialsProvider(config *Config) *CredentialsProvider {
+	return &CredentialsProvider{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *CredentialsProvider) Retrieve(ctx context.Context) (*Credentials, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.cached != nil && !p.cached.expired() {
+		return p.cached, nil
+	}
+
+	c, err := p.retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.cached = c
+	return c, nil
+}
+
+func (p *CredentialsProvider) retrieve(ctx context.Context) (*Credentials, error) {
+	if p.config.AccessKeyId != "" {
+		return &Credentials{
+			AccessKeyId:     p.config.AccessKeyId,
+			SecretAccessKey: p.config.SecretAccessKey,
+			SessionToken:    p.config.SessionToken,
+		}, nil
+	}
+
+	if ak := os.Getenv(envAccessKeyId); ak != "" {
+		return &Credentials{
+			AccessKeyId:     ak,
+			SecretAccessKey: os.Getenv(envSecretAccessKey),
+			SessionToken:    os.Getenv(envSessionToken),
+		}, nil
+	}
+
+	roleArn := p.config.RoleArn
+	if roleArn == "" {
+		roleArn = os.Getenv(envRoleArn)
+	}
+	tokenFile := p.config.WebIdentityTokenFile
+	if tokenFile == "" {
+		tokenFile = os.Getenv(envWebIdentityTokenFile)
+	}
+	if roleArn != "" && tokenFile != "" {
+		return p.assumeRoleWithWebIdentity(ctx, roleArn, tokenFile)
+	}
+
+	if !p.config.DisableIMDS {
+		return p.instanceProfile(ctx)
+	}
+
+	return nil, errors.New("no valid aws credentials found")
+}
+
+type assumeRoleWithWebIdentityResponse struct {
+	Result struct {
+		Credentials struct {
+			AccessKeyId     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"Credentials"`
+	} `xml:"AssumeRoleWithWebIdentityResult"`
+}
+
+func (p *CredentialsProvider) assumeRoleWithWebIdentity(ctx context.Context, roleArn string, tokenFile string) (*Credentials, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "read web identity token file %s", tokenFile)
+	}
+
+	form := url.Values{}
+	form.Set("Action", "AssumeRoleWithWebIdentity")
+	form.Set("Version", "2011-06-15")
+	form.Set("RoleArn", roleArn)
+	form.Set("RoleSessionName", fmt.Sprintf("loggie-%d", time.Now().Unix()))
+	form.Set("WebIdentityToken", strings.TrimSpace(string(token)))
+
+	endpoint := fmt.Sprintf("https://sts.%s.amazonaws.com/", p.config.Region)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := p.do(req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "assume role with web identity")
+	}
+
+	out := &assumeRoleWithWebIdentityResponse{}
+	if err := xml.Unmarshal(body, out); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal sts response")
+	}
+	c := out.Result.Credentials
+	return &Credentials{
+		AccessKeyId:     c.AccessKeyId,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.SessionToken,
+		Expires:         c.Expiration,
+	}, nil
+}
+
+type instanceProfileCredentials struct {
+	AccessKeyId     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (p *CredentialsProvider) instanceProfile(ctx context.Context) (*Credentials, error) {
+	role, err := IMDSGet(ctx, p.client, "/latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		return nil, errors.WithMessage(err, "get instance profile role")
+	}
+	role = strings.TrimSpace(strings.SplitN(role, "\n", 2)[0])
+
+	raw, err := IMDSGet(ctx, p.client, "/latest/meta-data/iam/security-credentials/"+role)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get instance profile credentials")
+	}
+
+	c := &instanceProfileCredentials{}
+	if err := json.Unmarshal([]byte(raw), c); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal instance profile credentials")
+	}
+	return &Credentials{
+		AccessKeyId:     c.AccessKeyId,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.Token,
+		Expires:         c.Expiration,
+	}, nil
+}
+
+// IMDSGet requests the EC2 instance metadata service with an IMDSv2 session token.
+func IMDSGet(ctx context.Context, client *http.Client, path string) (string, error) {
+	tokenReq, err := http.NewRequestWithContext(ctx, http.MethodPut, imdsEndpoint+"/latest/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	tokenReq.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := doRequest(client, tokenReq)
+	if err != nil {
+		return "", errors.WithMessage(err, "get imdsv2 token")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imdsEndpoint+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	out, err := doRequest(client, req)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (p *CredentialsProvider) do(req *http.Request) ([]byte, error) {
+	return doRequest(p.client, req)
+}
+
+func doRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, errors.Errorf("request %s returned status %d: %s", req.URL.Path, resp.StatusCode, truncate(body, 512))
+	}
+	return body, nil
+}
+
+func truncate(b []byte, n int) string {
+	if len(b) > n {
+		return string(b[:n])
+	}
+	return string(b)
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	signAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat = "20060102T150405Z"
+	amzDayFormat  = "20060102"
+
+	HeaderAmzDate          = "X-Amz-Date"
+	HeaderAmzSecurityToken = "X-Amz-Security-Token"
+	HeaderAmzContentSha256 = "X-Amz-Content-Sha256"
+
+	ServiceS3 = "s3"
+	// ServiceOpenSearch is the service of Amazon OpenSearch Service domains
+	ServiceOpenSearch = "es"
+	// ServiceOpenSearchServerless is the service of Amazon OpenSearch Serverless collections
+	ServiceOpenSearchServerless = "aoss"
+)
+
+// Sign signs the request in place with AWS Signature Version 4.
+// The body must be the exact payload that will be sent with the request.
+func Sign(req *http.Request, body []byte, service string, region string, creds *Credentials, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(amzDateFormat)
+	day := now.Format(amzDayFormat)
+
+	payloadHash := hashHex(body)
+	req.Header.Set(HeaderAmzDate, amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set(HeaderAmzSecurityToken, creds.SessionToken)
+	}
+	if service == ServiceS3 || service == ServiceOpenSearchServerless {
+		req.Header.Set(HeaderAmzContentSha256, payloadHash)
+	}
+
+	signedHeaders, canonicalHeaders := canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL.Path, service),
+		canonicalQuery(req),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{day, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		signAlgorithm,
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signAlgorithm, creds.AccessKeyId, scope, signedHeaders, signature))
+}
+
+func canonicalHeaders(req *http.Request) (signed string, canonical string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if lk == "authorization" || lk == "user-agent" {
+			continue
+		}
+		values := make([]string, 0, len(v))
+		for _, s := range v {
+			values = append(values, strings.Join(strings.Fields(s), " "))
+		}
+		headers[lk] = strings.Join(values, ",")
+	}
+
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteString(":")
+		sb.WriteString(headers[k])
+		sb.WriteString("\n")
+	}
+	return strings.Join(keys, ";"), sb.String()
+}
+
+func canonicalURI(path string, service string) string {
+	if path == "" {
+		return "/"
+	}
+	encoded := uriEncode(path, false)
+	// every service except s3 expects the path to be encoded twice
+	if service != ServiceS3 {
+		encoded = uriEncode(encoded, false)
+	}
+	return encoded
+}
+
+func canonicalQuery(req *http.Request) string {
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode encodes every byte except the unreserved characters defined in RFC 3986.
+func uriEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%%%02X", c))
+	}
+	return sb.String()
+}
+
+// EscapePath encodes an object key to be used as url path.
+func EscapePath(s string) string {
+	return uriEncode(s, false)
+}
+
+func hashHex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import "os"
+
+const (
+	envClientId           = "AZURE_CLIENT_ID"
+	envTenantId           = "AZURE_TENANT_ID"
+	envFederatedTokenFile = "AZURE_FEDERATED_TOKEN_FILE"
+	envAuthorityHost      = "AZURE_AUTHORITY_HOST"
+
+	defaultAuthorityHost = "https://login.microsoftonline.com/"
+)
+
+// Config is the common Azure AD configuration shared by the components talking to Azure services.
+// The workload identity of AKS is used when the tenant, client and federated token file are all available, which are
+// injected as environment variables by the workload identity webhook, otherwise the managed identity of the node is used.
+type Config struct {
+	// ClientId selects the application of the workload identity, or the user-assigned managed identity
+	ClientId           string `yaml:"clientId,omitempty"`
+	TenantId           string `yaml:"tenantId,omitempty"`
+	FederatedTokenFile string `yaml:"federatedTokenFile,omitempty"`
+	AuthorityHost      string `yaml:"authorityHost,omitempty"`
+}
+
+func (c *Config) SetDefaults() {
+	if c.ClientId == "" {
+		c.ClientId = os.Getenv(envClientId)
+	}
+	if c.TenantId == "" {
+		c.TenantId = os.Getenv(envTenantId)
+	}
+	if c.FederatedTokenFile == "" {
+		c.FederatedTokenFile = os.Getenv(envFederatedTokenFile)
+	}
+	if c.AuthorityHost == "" {
+		c.AuthorityHost = os.Getenv(envAuthorityHost)
+	}
+	if c.AuthorityHost == "" {
+		c.AuthorityHost = defaultAuthorityHost
+	}
+}
+
+func (c *Config) workloadIdentity() bool {
+	return c.ClientId != "" && c.TenantId != "" && c.FederatedTokenFile != ""
+}
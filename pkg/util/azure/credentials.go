@@ -0,0 +1,191 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/pkg/errors"
+)
+
+const (
+	imdsEndpoint       = "http://169.254.169.254"
+	imdsApiVersion     = "2018-02-01"
+	clientAssertionJWT = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	// refresh the access tokens a little earlier than they actually expire
+	expiryWindow = 5 * time.Minute
+)
+
+type Token struct {
+	AccessToken string
+	Expires     time.Time
+}
+
+func (t *Token) expired() bool {
+	return time.Now().Add(expiryWindow).After(t.Expires)
+}
+
+// TokenProvider retrieves the Azure AD access tokens of the resource, such as https://eventhubs.azure.net/,
+// with the workload identity or the managed identity.
+type TokenProvider struct {
+	config   *Config
+	resource string
+	client   *http.Client
+	// imdsEndpoint could be replaced in tests
+	imdsEndpoint string
+
+	lock   sync.Mutex
+	cached *Token
+}
+
+func NewTokenProvider(config *Config, resource string) *TokenProvider {
+	return &TokenProvider{
+		config:       config,
+		resource:     resource,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		imdsEndpoint: imdsEndpoint,
+	}
+}
+
+func (p *TokenProvider) Token(ctx context.Context) (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.cached != nil && !p.cached.expired() {
+		return p.cached.AccessToken, nil
+	}
+
+	var t *Token
+	var err error
+	if p.config.workloadIdentity() {
+		t, err = p.workloadIdentityToken(ctx)
+	} else {
+		t, err = p.managedIdentityToken(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	p.cached = t
+	return t.AccessToken, nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	// expires_in is a number from azure ad but a string from imds
+	ExpiresIn stdjson.Number `json:"expires_in"`
+}
+
+func (r *tokenResponse) token() (*Token, error) {
+	if r.AccessToken == "" {
+		return nil, errors.New("no access token in the response")
+	}
+	seconds, err := strconv.ParseInt(r.ExpiresIn.String(), 10, 64)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "parse expires_in %s", r.ExpiresIn)
+	}
+	return &Token{
+		AccessToken: r.AccessToken,
+		Expires:     time.Now().Add(time.Duration(seconds) * time.Second),
+	}, nil
+}
+
+// workloadIdentityToken exchanges the federated token of the service account for an access token
+func (p *TokenProvider) workloadIdentityToken(ctx context.Context) (*Token, error) {
+	assertion, err := os.ReadFile(p.config.FederatedTokenFile)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "read federated token file %s", p.config.FederatedTokenFile)
+	}
+
+	form := url.Values{}
+	form.Set("client_id", p.config.ClientId)
+	form.Set("scope", strings.TrimSuffix(p.resource, "/")+"/.default")
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_assertion_type", clientAssertionJWT)
+	form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+
+	endpoint := strings.TrimSuffix(p.config.AuthorityHost, "/") + "/" + p.config.TenantId + "/oauth2/v2.0/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := p.do(req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "exchange workload identity token")
+	}
+	out := &tokenResponse{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal token response")
+	}
+	return out.token()
+}
+
+func (p *TokenProvider) managedIdentityToken(ctx context.Context) (*Token, error) {
+	query := url.Values{}
+	query.Set("api-version", imdsApiVersion)
+	query.Set("resource", p.resource)
+	if p.config.ClientId != "" {
+		query.Set("client_id", p.config.ClientId)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.imdsEndpoint+"/metadata/identity/oauth2/token?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	body, err := p.do(req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get managed identity token")
+	}
+	out := &tokenResponse{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal managed identity token")
+	}
+	return out.token()
+}
+
+func (p *TokenProvider) do(req *http.Request) ([]byte, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		if len(body) > 512 {
+			body = body[:512]
+		}
+		return nil, errors.Errorf("request %s returned status %d: %s", req.URL.Path, resp.StatusCode, string(body))
+	}
+	return body, nil
+}
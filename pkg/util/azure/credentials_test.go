@@ -0,0 +1,77 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const eventHubsResource = "https://eventhubs.azure.net/"
+
+func TestManagedIdentityToken(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Metadata") != "true" || r.URL.Path != "/metadata/identity/oauth2/token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		assert.Equal(t, eventHubsResource, r.URL.Query().Get("resource"))
+		assert.Equal(t, "identity-1", r.URL.Query().Get("client_id"))
+		// imds returns expires_in as a string
+		w.Write([]byte(`{"access_token":"mi-token","expires_in":"86399","token_type":"Bearer"}`))
+	}))
+	defer srv.Close()
+
+	p := NewTokenProvider(&Config{ClientId: "identity-1"}, eventHubsResource)
+	p.imdsEndpoint = srv.URL
+	token, err := p.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "mi-token", token)
+
+	_, err = p.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+}
+
+func TestWorkloadIdentityToken(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(file, []byte("federated-jwt\n"), 0600))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "/tenant-1/oauth2/v2.0/token", r.URL.Path)
+		assert.Equal(t, "app-1", r.PostForm.Get("client_id"))
+		assert.Equal(t, "https://eventhubs.azure.net/.default", r.PostForm.Get("scope"))
+		assert.Equal(t, "federated-jwt", r.PostForm.Get("client_assertion"))
+		assert.Equal(t, clientAssertionJWT, r.PostForm.Get("client_assertion_type"))
+		w.Write([]byte(`{"access_token":"wi-token","expires_in":3599,"token_type":"Bearer"}`))
+	}))
+	defer srv.Close()
+
+	c := &Config{ClientId: "app-1", TenantId: "tenant-1", FederatedTokenFile: file, AuthorityHost: srv.URL + "/"}
+	token, err := NewTokenProvider(c, eventHubsResource).Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "wi-token", token)
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/util/priority"
+)
+
+// The envelope describes a batch sent between the agents and the aggregators, such as by grpc or kafka.
+// Version 1 is the legacy format without envelope, the newer versions carry the capabilities added since,
+// so the senders and the receivers of mixed versions could negotiate the ones both of them understand,
+// and the new capabilities are rolled out gradually across the fleet.
+const (
+	Version1      = 1
+	Version2      = 2
+	LatestVersion = Version2
+
+	// CapabilityChecksum carries the crc32c of the events, the receivers reject the batches corrupted on the way
+	CapabilityChecksum = "checksum"
+	// CapabilityPriority carries the priorities of the events, which are restored in the meta of the events on the receivers
+	CapabilityPriority = "priority"
+)
+
+// Keys of the envelope in the grpc metadata or the kafka record headers
+const (
+	// OfferKey is the highest version and the capabilities supported by the sender,
+	// the receiver replies with the agreed ones in AcceptKey
+	OfferKey  = "loggie-envelope-offer"
+	AcceptKey = "loggie-envelope-accept"
+	// Key is the version and the capabilities used by the batch, it is absent in version 1
+	Key         = "loggie-envelope"
+	ChecksumKey = "loggie-envelope-checksum"
+	PriorityKey = "loggie-envelope-priority"
+)
+
+// capabilities are the versions introducing them
+var capabilities = map[string]int{
+	CapabilityChecksum: Version2,
+	CapabilityPriority: Version2,
+}
+
+type Config struct {
+	// Version is the highest version to use, 1 disables the envelope
+	Version int `yaml:"version,omitempty"`
+	// Capabilities are enabled among the ones of the version, all of them are enabled by default
+	Capabilities []string `yaml:"capabilities,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	if c.Version < 0 || c.Version > LatestVersion {
+		return errors.Errorf("envelope version %d is not supported, the latest is %d", c.Version, LatestVersion)
+	}
+	for _, cp := range c.Capabilities {
+		v, ok := capabilities[cp]
+		if !ok {
+			return errors.Errorf("envelope capability %s is unknown", cp)
+		}
+		if v > c.version() {
+			return errors.Errorf("envelope capability %s requires version %d", cp, v)
+		}
+	}
+	return nil
+}
+
+func (c *Config) version() int {
+	if c.Version < Version1 {
+		return Version1
+	}
+	return c.Version
+}
+
+// Offer returns the version and the capabilities enabled
+func (c *Config) Offer() Offer {
+	o := Offer{Version: c.version()}
+	if len(c.Capabilities) == 0 {
+		for cp, v := range capabilities {
+			if v <= o.Version {
+				o.Capabilities = append(o.Capabilities, cp)
+			}
+		}
+	} else {
+		o.Capabilities = append(o.Capabilities, c.Capabilities...)
+	}
+	sort.Strings(o.Capabilities)
+	return o
+}
+
+// Offer is a version and the capabilities, formatted as 2;checksum,priority
+type Offer struct {
+	Version      int
+	Capabilities []string
+}
+
+func (o Offer) Has(capability string) bool {
+	for _, cp := range o.Capabilities {
+		if cp == capability {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Offer) String() string {
+	return fmt.Sprintf("%d;%s", o.Version, strings.Join(o.Capabilities, ","))
+}
+
+// ParseOffer parses the offer, the capabilities unknown to this version are kept, so they are rejected by Accept
+func ParseOffer(s string) (Offer, error) {
+	version, caps, _ := strings.Cut(s, ";")
+	v, err := strconv.Atoi(strings.TrimSpace(version))
+	if err != nil || v < Version1 {
+		return Offer{}, errors.Errorf("envelope version %s is invalid", version)
+	}
+	o := Offer{Version: v}
+	for _, cp := range strings.Split(caps, ",") {
+		if cp = strings.TrimSpace(cp); cp != "" {
+			o.Capabilities = append(o.Capabilities, cp)
+		}
+	}
+	return o, nil
+}
+
+// Negotiate returns the highest version and the capabilities supported by both the sides
+func Negotiate(local Offer, remote Offer) Offer {
+	o := Offer{Version: local.Version}
+	if remote.Version < o.Version {
+		o.Version = remote.Version
+	}
+	for _, cp := range local.Capabilities {
+		if v, ok := capabilities[cp]; ok && v <= o.Version && remote.Has(cp) {
+			o.Capabilities = append(o.Capabilities, cp)
+		}
+	}
+	return o
+}
+
+// Accept checks whether the envelope used by a batch is supported by the local offer
+func Accept(local Offer, used Offer) error {
+	if used.Version > local.Version {
+		return errors.Errorf("envelope version %d is not supported, accept %s", used.Version, local)
+	}
+	for _, cp := range used.Capabilities {
+		if !local.Has(cp) {
+			return errors.Errorf("envelope capability %s is not supported, accept %s", cp, local)
+		}
+	}
+	return nil
+}
+
+// Envelope is the version and the capabilities used by a batch, with the values of the capabilities
+type Envelope struct {
+	Offer
+	Checksum   string
+	Priorities string
+}
+
+// Set writes the envelope by the keys, such as to the grpc metadata
+func (e *Envelope) Set(set func(key string, value string)) {
+	if e.Version <= Version1 {
+		return
+	}
+	set(Key, e.Offer.String())
+	if e.Has(CapabilityChecksum) {
+		set(ChecksumKey, e.Checksum)
+	}
+	if e.Has(CapabilityPriority) {
+		set(PriorityKey, e.Priorities)
+	}
+}
+
+// Parse reads the envelope by the keys, nil is returned for the batches of version 1
+func Parse(get func(key string) string) (*Envelope, error) {
+	s := get(Key)
+	if s == "" {
+		return nil, nil
+	}
+	o, err := ParseOffer(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		Offer:      o,
+		Checksum:   get(ChecksumKey),
+		Priorities: get(PriorityKey),
+	}, nil
+}
+
+// Checksum is the crc32c of the parts of the events in order, each part is prefixed with its length
+type Checksum struct {
+	h   hash.Hash32
+	buf [4]byte
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+func NewChecksum() *Checksum {
+	return &Checksum{h: crc32.New(castagnoli)}
+}
+
+func (c *Checksum) Add(parts ...[]byte) {
+	for _, p := range parts {
+		binary.BigEndian.PutUint32(c.buf[:], uint32(len(p)))
+		_, _ = c.h.Write(c.buf[:])
+		_, _ = c.h.Write(p)
+	}
+}
+
+func (c *Checksum) String() string {
+	return fmt.Sprintf("%08x", c.h.Sum32())
+}
+
+// EncodePriorities formats the priorities of the events in runs of the same level,
+// such as 1*30,2*1 for 30 normal events followed by a high one
+func EncodePriorities(levels []priority.Level) string {
+	var sb strings.Builder
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.Itoa(int(levels[i])))
+		sb.WriteByte('*')
+		sb.WriteString(strconv.Itoa(j - i))
+		i = j
+	}
+	return sb.String()
+}
+
+// DecodePriorities parses the priorities of n events encoded by EncodePriorities
+func DecodePriorities(s string, n int) ([]priority.Level, error) {
+	levels := make([]priority.Level, 0, n)
+	if s == "" {
+		return levels, checkCount(levels, n)
+	}
+	for _, run := range strings.Split(s, ",") {
+		level, count, ok := strings.Cut(run, "*")
+		if !ok {
+			return nil, errors.Errorf("priority run %s is invalid", run)
+		}
+		l, err := strconv.Atoi(level)
+		if err != nil || l < int(priority.Low) || l > int(priority.Critical) {
+			return nil, errors.Errorf("priority level %s is invalid", level)
+		}
+		c, err := strconv.Atoi(count)
+		if err != nil || c < 1 || len(levels)+c > n {
+			return nil, errors.Errorf("priority count %s is invalid", count)
+		}
+		for i := 0; i < c; i++ {
+			levels = append(levels, priority.Level(l))
+		}
+	}
+	return levels, checkCount(levels, n)
+}
+
+func checkCount(levels []priority.Level, n int) error {
+	if len(levels) != n {
+		return errors.Errorf("priorities of %d events are carried, but got %d events", len(levels), n)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/util/priority"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&Config{}).Validate())
+	assert.NoError(t, (&Config{Version: 2, Capabilities: []string{CapabilityChecksum}}).Validate())
+	assert.Error(t, (&Config{Version: 3}).Validate())
+	assert.Error(t, (&Config{Version: 2, Capabilities: []string{"columnar"}}).Validate())
+	assert.Error(t, (&Config{Version: 1, Capabilities: []string{CapabilityPriority}}).Validate())
+}
+
+func TestOffer(t *testing.T) {
+	c := &Config{Version: 2}
+	o := c.Offer()
+	assert.Equal(t, "2;checksum,priority", o.String())
+
+	parsed, err := ParseOffer(o.String())
+	assert.NoError(t, err)
+	assert.Equal(t, o, parsed)
+
+	assert.Equal(t, "1;", (&Config{}).Offer().String())
+
+	_, err = ParseOffer("x;checksum")
+	assert.Error(t, err)
+}
+
+func TestNegotiate(t *testing.T) {
+	local := Offer{Version: 2, Capabilities: []string{CapabilityChecksum, CapabilityPriority}}
+
+	// a newer receiver with the capabilities unknown to this version
+	agreed := Negotiate(local, Offer{Version: 3, Capabilities: []string{"columnar", CapabilityChecksum}})
+	assert.Equal(t, Offer{Version: 2, Capabilities: []string{CapabilityChecksum}}, agreed)
+
+	// an older receiver
+	agreed = Negotiate(local, Offer{Version: 1})
+	assert.Equal(t, Offer{Version: 1}, agreed)
+
+	assert.NoError(t, Accept(local, agreed))
+	assert.Error(t, Accept(local, Offer{Version: 3}))
+	assert.Error(t, Accept(Offer{Version: 2, Capabilities: []string{CapabilityChecksum}}, local))
+}
+
+func TestEnvelope(t *testing.T) {
+	sum := NewChecksum()
+	sum.Add([]byte("a"), []byte("bc"))
+	env := &Envelope{
+		Offer:      Offer{Version: 2, Capabilities: []string{CapabilityChecksum, CapabilityPriority}},
+		Checksum:   sum.String(),
+		Priorities: EncodePriorities([]priority.Level{priority.Normal, priority.Normal, priority.High}),
+	}
+	assert.Equal(t, "1*2,2*1", env.Priorities)
+
+	kv := make(map[string]string)
+	env.Set(func(key string, value string) {
+		kv[key] = value
+	})
+	assert.Len(t, kv, 3)
+
+	parsed, err := Parse(func(key string) string {
+		return kv[key]
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, env, parsed)
+
+	// the parts are prefixed with their lengths
+	other := NewChecksum()
+	other.Add([]byte("ab"), []byte("c"))
+	assert.NotEqual(t, sum.String(), other.String())
+
+	// version 1 carries nothing
+	kv = make(map[string]string)
+	(&Envelope{Offer: Offer{Version: 1}}).Set(func(key string, value string) {
+		kv[key] = value
+	})
+	assert.Empty(t, kv)
+	parsed, err = Parse(func(key string) string {
+		return kv[key]
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+}
+
+func TestDecodePriorities(t *testing.T) {
+	levels, err := DecodePriorities("1*2,2*1", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []priority.Level{priority.Normal, priority.Normal, priority.High}, levels)
+
+	levels, err = DecodePriorities("", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, levels)
+
+	_, err = DecodePriorities("1*2", 3)
+	assert.Error(t, err)
+	_, err = DecodePriorities("1*4", 3)
+	assert.Error(t, err)
+	_, err = DecodePriorities("9*1", 1)
+	assert.Error(t, err)
+	_, err = DecodePriorities("1", 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"fmt"
+	"os"
+)
+
+const envCredentials = "GOOGLE_APPLICATION_CREDENTIALS"
+
+// Config is the common Google Cloud client configuration shared by the components talking to GCP services.
+type Config struct {
+	// Project defaults to the project of the service account key, or the project of the instance
+	Project  string `yaml:"project,omitempty"`
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// CredentialsFile is a service account key file and overrides GOOGLE_APPLICATION_CREDENTIALS. Without any key file,
+	// the token of the attached service account is fetched from the metadata server, which is the workload identity on GKE
+	CredentialsFile string `yaml:"credentialsFile,omitempty"`
+	// DisableAuth sends the requests without any token, such as to an emulator
+	DisableAuth bool `yaml:"disableAuth,omitempty"`
+}
+
+func (c *Config) SetDefaults() {
+	if c.CredentialsFile == "" {
+		c.CredentialsFile = os.Getenv(envCredentials)
+	}
+}
+
+func (c *Config) Validate() error {
+	if c.CredentialsFile != "" && c.DisableAuth {
+		return fmt.Errorf("gcp credentialsFile cannot be set when auth is disabled")
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/pkg/errors"
+)
+
+const (
+	envMetadataHost     = "GCE_METADATA_HOST"
+	metadataHost        = "metadata.google.internal"
+	serviceAccountType  = "service_account"
+	defaultTokenURI     = "https://oauth2.googleapis.com/token"
+	jwtBearerGrantType  = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	CloudPlatformScope  = "https://www.googleapis.com/auth/cloud-platform"
+	assertionLifetime   = time.Hour
+	metadataFlavorKey   = "Metadata-Flavor"
+	metadataFlavorValue = "Google"
+
+	// refresh the access tokens a little earlier than they actually expire
+	expiryWindow = 5 * time.Minute
+)
+
+type Token struct {
+	AccessToken string
+	Expires     time.Time
+}
+
+func (t *Token) expired() bool {
+	return time.Now().Add(expiryWindow).After(t.Expires)
+}
+
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ProjectId    string `json:"project_id"`
+	PrivateKeyId string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// TokenProvider retrieves the oauth2 access tokens with the service account key file in config or
+// GOOGLE_APPLICATION_CREDENTIALS, or from the metadata server of GCE and GKE.
+type TokenProvider struct {
+	config *Config
+	scope  string
+	client *http.Client
+	key    *serviceAccountKey
+	signer *rsa.PrivateKey
+
+	lock   sync.Mutex
+	cached *Token
+}
+
+func NewTokenProvider(config *Config, scope string) (*TokenProvider, error) {
+	p := &TokenProvider{
+		config: config,
+		scope:  scope,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	if config.CredentialsFile == "" || config.DisableAuth {
+		return p, nil
+	}
+
+	content, err := os.ReadFile(config.CredentialsFile)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "read gcp credentials file %s", config.CredentialsFile)
+	}
+	key := &serviceAccountKey{}
+	if err := json.Unmarshal(content, key); err != nil {
+		return nil, errors.WithMessagef(err, "unmarshal gcp credentials file %s", config.CredentialsFile)
+	}
+	if key.Type != serviceAccountType {
+		return nil, errors.Errorf("gcp credentials of type %s is not supported, only service account keys are", key.Type)
+	}
+	signer, err := parsePrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "parse private key of the service account")
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = defaultTokenURI
+	}
+	p.key = key
+	p.signer = signer
+	return p, nil
+}
+
+func parsePrivateKey(raw string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(raw))
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+	if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rk, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("private key is not a rsa key")
+		}
+		return rk, nil
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// Token returns the access token, an empty token is returned when auth is disabled
+func (p *TokenProvider) Token(ctx context.Context) (string, error) {
+	if p.config.DisableAuth {
+		return "", nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.cached != nil && !p.cached.expired() {
+		return p.cached.AccessToken, nil
+	}
+
+	var t *Token
+	var err error
+	if p.key != nil {
+		t, err = p.serviceAccountToken(ctx)
+	} else {
+		t, err = p.metadataToken(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	p.cached = t
+	return t.AccessToken, nil
+}
+
+// ProjectId returns the project in config, the project of the service account key, or the project of the instance
+func (p *TokenProvider) ProjectId(ctx context.Context) (string, error) {
+	if p.config.Project != "" {
+		return p.config.Project, nil
+	}
+	if p.key != nil && p.key.ProjectId != "" {
+		return p.key.ProjectId, nil
+	}
+	if p.config.DisableAuth {
+		return "", errors.New("gcp project is required when auth is disabled")
+	}
+	body, err := p.metadataGet(ctx, "/computeMetadata/v1/project/project-id")
+	if err != nil {
+		return "", errors.WithMessage(err, "get project id from metadata server")
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (r *tokenResponse) token() (*Token, error) {
+	if r.AccessToken == "" {
+		return nil, errors.New("no access token in the response")
+	}
+	return &Token{
+		AccessToken: r.AccessToken,
+		Expires:     time.Now().Add(time.Duration(r.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// serviceAccountToken exchanges a jwt signed by the service account key for an access token
+func (p *TokenProvider) serviceAccountToken(ctx context.Context) (*Token, error) {
+	assertion, err := p.assertion(time.Now())
+	if err != nil {
+		return nil, errors.WithMessage(err, "sign jwt assertion")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", jwtBearerGrantType)
+	form.Set("assertion", assertion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := p.do(req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "exchange service account token")
+	}
+	out := &tokenResponse{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal token response")
+	}
+	return out.token()
+}
+
+func (p *TokenProvider) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": p.key.PrivateKeyId,
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   p.key.ClientEmail,
+		"scope": p.scope,
+		"aud":   p.key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(assertionLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.signer, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (p *TokenProvider) metadataToken(ctx context.Context) (*Token, error) {
+	body, err := p.metadataGet(ctx, "/computeMetadata/v1/instance/service-accounts/default/token?scopes="+url.QueryEscape(p.scope))
+	if err != nil {
+		return nil, errors.WithMessage(err, "get token from metadata server")
+	}
+	out := &tokenResponse{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal metadata token")
+	}
+	return out.token()
+}
+
+func (p *TokenProvider) metadataGet(ctx context.Context, path string) ([]byte, error) {
+	host := os.Getenv(envMetadataHost)
+	if host == "" {
+		host = metadataHost
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(metadataFlavorKey, metadataFlavorValue)
+	return p.do(req)
+}
+
+func (p *TokenProvider) do(req *http.Request) ([]byte, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		if len(body) > 512 {
+			body = body[:512]
+		}
+		return nil, errors.Errorf("request %s returned status %d: %s", req.URL.Path, resp.StatusCode, string(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loggie-io/loggie/pkg/util/json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceAccountToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	var assertion string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, jwtBearerGrantType, r.PostForm.Get("grant_type"))
+		assertion = r.PostForm.Get("assertion")
+		w.Write([]byte(`{"access_token":"ya29.token","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer srv.Close()
+
+	content, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "my-project",
+		"private_key_id": "kid1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "loggie@my-project.iam.gserviceaccount.com",
+		"token_uri":      srv.URL,
+	})
+	file := filepath.Join(t.TempDir(), "key.json")
+	assert.NoError(t, os.WriteFile(file, content, 0600))
+
+	p, err := NewTokenProvider(&Config{CredentialsFile: file}, CloudPlatformScope)
+	assert.NoError(t, err)
+	token, err := p.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "ya29.token", token)
+
+	project, err := p.ProjectId(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "my-project", project)
+
+	// the assertion is signed by the key of the service account
+	parts := strings.Split(assertion, ".")
+	assert.Len(t, parts, 3)
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(t, err)
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], signature))
+
+	claims := map[string]interface{}{}
+	raw, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(t, json.Unmarshal(raw, &claims))
+	assert.Equal(t, "loggie@my-project.iam.gserviceaccount.com", claims["iss"])
+	assert.Equal(t, srv.URL, claims["aud"])
+	assert.Equal(t, CloudPlatformScope, claims["scope"])
+
+	// the token is cached
+	assertion = ""
+	_, err = p.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, assertion)
+}
+
+func TestMetadataToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(metadataFlavorKey) != metadataFlavorValue {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			assert.Equal(t, CloudPlatformScope, r.URL.Query().Get("scopes"))
+			w.Write([]byte(`{"access_token":"ya29.metadata","expires_in":3599}`))
+		case "/computeMetadata/v1/project/project-id":
+			w.Write([]byte("gke-project"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv(envMetadataHost, strings.TrimPrefix(srv.URL, "http://"))
+
+	p, err := NewTokenProvider(&Config{}, CloudPlatformScope)
+	assert.NoError(t, err)
+	token, err := p.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "ya29.metadata", token)
+
+	project, err := p.ProjectId(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "gke-project", project)
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package grok translates the grok expressions such as `%{IP:client} %{WORD:method}` into the regex of
+// pkg/util/regex, with the standard pattern library of logstash rewritten for RE2 and the custom pattern files
+// in the same format.
+package grok
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/loggie-io/loggie/pkg/util/regex"
+	"github.com/pkg/errors"
+)
+
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+
+	groupPrefix = "_grok"
+)
+
+//go:embed patterns/grok-patterns
+var standardPatterns string
+
+// reference is %{NAME}, %{NAME:field} or %{NAME:field:type}
+var reference = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::(\w+))?\}`)
+
+// Grok is a library of the named patterns, it is not safe for concurrent modification
+type Grok struct {
+	patterns map[string]string
+}
+
+// New returns a library with the standard patterns
+func New() *Grok {
+	g := &Grok{
+		patterns: make(map[string]string),
+	}
+	if err := g.AddPatternsFromReader(strings.NewReader(standardPatterns)); err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// AddPattern adds or overrides the pattern with the name
+func (g *Grok) AddPattern(name string, expr string) {
+	g.patterns[name] = expr
+}
+
+// AddPatternsFromReader reads the patterns of `NAME regex` lines, the blank lines and the lines starting with # are skipped
+func (g *Grok) AddPatternsFromReader(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, " ", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("invalid pattern line: %s", line)
+		}
+		g.patterns[kv[0]] = strings.TrimSpace(kv[1])
+	}
+	return scanner.Err()
+}
+
+// AddPatternsFromPath loads the pattern file, or all the regular files in the directory
+func (g *Grok) AddPatternsFromPath(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return g.addPatternsFromFile(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if err := g.addPatternsFromFile(filepath.Join(path, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Grok) addPatternsFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := g.AddPatternsFromReader(f); err != nil {
+		return errors.WithMessagef(err, "load patterns from %s", path)
+	}
+	return nil
+}
+
+type field struct {
+	name string
+	typ  string
+}
+
+// Expression is a compiled grok expression which is safe for concurrent use
+type Expression struct {
+	expr   string
+	re     *regex.Regex
+	fields []*field // indexed by the groups of the regex, nil for the groups not captured
+}
+
+// Translate returns the regex of the grok expression without compiling it
+func (g *Grok) Translate(expr string) (string, error) {
+	t := &translator{patterns: g.patterns, fields: make(map[string]*field)}
+	return t.expand(expr, nil)
+}
+
+// Compile translates and compiles the grok expression, the inline named groups such as (?<name>re) are captured as well
+func (g *Grok) Compile(expr string) (*Expression, error) {
+	t := &translator{patterns: g.patterns, fields: make(map[string]*field)}
+	translated, err := t.expand(expr, nil)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regex.Compile(translated)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "compile grok %s", expr)
+	}
+
+	names := re.SubexpNames()
+	fields := make([]*field, len(names))
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		if f, ok := t.fields[name]; ok {
+			fields[i] = f
+			continue
+		}
+		fields[i] = &field{name: name, typ: TypeString}
+	}
+
+	return &Expression{
+		expr:   expr,
+		re:     re,
+		fields: fields,
+	}, nil
+}
+
+func (e *Expression) String() string {
+	return e.expr
+}
+
+// MatchWithin returns the captured fields, the result is nil when the input is not matched.
+// The empty captures are dropped unless keepEmpty, and the captures with the same name keep the first non-empty one.
+func (e *Expression) MatchWithin(b *regex.Budget, s string, keepEmpty bool) (map[string]interface{}, error) {
+	match, err := e.re.FindStringSubmatchWithin(b, s)
+	if err != nil {
+		return nil, err
+	}
+	if match == nil {
+		return nil, nil
+	}
+
+	result := make(map[string]interface{})
+	for i, val := range match {
+		f := e.fields[i]
+		if f == nil {
+			continue
+		}
+		if val == "" {
+			if _, ok := result[f.name]; !ok && keepEmpty {
+				result[f.name] = val
+			}
+			continue
+		}
+		if prev, ok := result[f.name]; ok && prev != "" {
+			continue
+		}
+
+		converted, err := convert(val, f.typ)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "convert field %s", f.name)
+		}
+		result[f.name] = converted
+	}
+	return result, nil
+}
+
+func convert(val string, typ string) (interface{}, error) {
+	switch typ {
+	case TypeInt:
+		return strconv.ParseInt(val, 10, 64)
+	case TypeFloat:
+		return strconv.ParseFloat(val, 64)
+	}
+	return val, nil
+}
+
+type translator struct {
+	patterns map[string]string
+	fields   map[string]*field
+}
+
+// expand replaces the references recursively, the named references are turned into the groups named by their
+// index, so the field names are not limited by the syntax of the regex group names
+func (t *translator) expand(expr string, stack []string) (string, error) {
+	var err error
+	result := reference.ReplaceAllStringFunc(expr, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		sub := reference.FindStringSubmatch(ref)
+		name, fieldName, typ := sub[1], sub[2], sub[3]
+
+		for _, s := range stack {
+			if s == name {
+				err = errors.Errorf("pattern %s is recursive: %s", name, strings.Join(append(stack, name), " -> "))
+				return ""
+			}
+		}
+		pattern, ok := t.patterns[name]
+		if !ok {
+			err = errors.Errorf("pattern %s is not defined", name)
+			return ""
+		}
+
+		expanded, e := t.expand(pattern, append(stack, name))
+		if e != nil {
+			err = e
+			return ""
+		}
+		if fieldName == "" {
+			return fmt.Sprintf("(?:%s)", expanded)
+		}
+
+		if typ == "" {
+			typ = TypeString
+		}
+		if typ != TypeString && typ != TypeInt && typ != TypeFloat {
+			err = errors.Errorf("type %s of field %s is not supported", typ, fieldName)
+			return ""
+		}
+		group := fmt.Sprintf("%s%d", groupPrefix, len(t.fields))
+		t.fields[group] = &field{name: fieldName, typ: typ}
+		return fmt.Sprintf("(?P<%s>%s)", group, expanded)
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
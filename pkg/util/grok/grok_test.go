@@ -0,0 +1,127 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grok
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/loggie-io/loggie/pkg/util/regex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStandardPatterns(t *testing.T) {
+	g := New()
+	for name := range g.patterns {
+		_, err := g.Compile("%{" + name + "}")
+		assert.NoError(t, err, name)
+	}
+}
+
+func TestExpression_MatchWithin(t *testing.T) {
+	tests := []struct {
+		name      string
+		patterns  string
+		expr      string
+		input     string
+		keepEmpty bool
+		want      map[string]interface{}
+	}{
+		{
+			name:  "combined apache log",
+			expr:  "%{COMBINEDAPACHELOG}",
+			input: `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`,
+			want: map[string]interface{}{
+				"clientip":    "127.0.0.1",
+				"ident":       "-",
+				"auth":        "frank",
+				"timestamp":   "10/Oct/2000:13:55:36 -0700",
+				"verb":        "GET",
+				"request":     "/apache_pb.gif",
+				"httpversion": "1.0",
+				"response":    "200",
+				"bytes":       "2326",
+				"referrer":    `"http://www.example.com/start.html"`,
+				"agent":       `"Mozilla/4.08"`,
+			},
+		},
+		{
+			name:  "typed and dotted fields",
+			expr:  `%{TIMESTAMP_ISO8601:time} %{LOGLEVEL:log.level} %{NUMBER:cost:float}ms %{INT:code:int}`,
+			input: "2023-01-02T15:04:05.123+08:00 WARN 12.5ms 404",
+			want: map[string]interface{}{
+				"time":      "2023-01-02T15:04:05.123+08:00",
+				"log.level": "WARN",
+				"cost":      12.5,
+				"code":      int64(404),
+			},
+		},
+		{
+			name:     "custom patterns and inline group",
+			patterns: "TRACEID [0-9a-f]{16}\nSPAN %{TRACEID:trace}",
+			expr:     `%{SPAN} (?<msg>.*)`,
+			input:    "0123456789abcdef hello",
+			want: map[string]interface{}{
+				"trace": "0123456789abcdef",
+				"msg":   "hello",
+			},
+		},
+		{
+			name:      "keep empty captures",
+			expr:      `%{WORD:a}:%{DATA:b}`,
+			input:     "x:",
+			keepEmpty: true,
+			want: map[string]interface{}{
+				"a": "x",
+				"b": "",
+			},
+		},
+		{
+			name:  "not matched",
+			expr:  `^%{IPV4:ip}$`,
+			input: "not an ip",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New()
+			assert.NoError(t, g.AddPatternsFromReader(strings.NewReader(tt.patterns)))
+			exp, err := g.Compile(tt.expr)
+			assert.NoError(t, err)
+
+			got, err := exp.MatchWithin(regex.NewBudget(), tt.input, tt.keepEmpty)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGrok_CompileError(t *testing.T) {
+	g := New()
+	g.AddPattern("A", "%{B}")
+	g.AddPattern("B", "%{A}")
+
+	_, err := g.Compile("%{A}")
+	assert.ErrorContains(t, err, "recursive")
+
+	_, err = g.Compile("%{UNDEFINED}")
+	assert.ErrorContains(t, err, "not defined")
+
+	_, err = g.Compile("%{INT:n:bool}")
+	assert.ErrorContains(t, err, "not supported")
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// Importing this package registers the gzip and zstd compressors, grpc servers decompress the
+// requests with the compressor named by the grpc-encoding header of each stream
+func init() {
+	encoding.RegisterCompressor(gzipCompressor{})
+	encoding.RegisterCompressor(zstdCompressor{})
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+func (gzipCompressor) Name() string {
+	return CompressionGzip
+}
+
+type zstdCompressor struct{}
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+}
+
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &zstdReader{decoder: d}, nil
+}
+
+func (zstdCompressor) Name() string {
+	return CompressionZstd
+}
+
+// zstdReader releases the decoder once the message is fully read
+type zstdReader struct {
+	decoder *zstd.Decoder
+}
+
+func (z *zstdReader) Read(p []byte) (int, error) {
+	if z.decoder == nil {
+		return 0, io.EOF
+	}
+	n, err := z.decoder.Read(p)
+	if err != nil {
+		z.decoder.Close()
+		z.decoder = nil
+	}
+	return n, err
+}
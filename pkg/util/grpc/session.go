@@ -0,0 +1,24 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+// The grpc sink identifies every batch with the session id of the sink and a sequence number in the
+// metadata of the stream, the grpc source uses them to discard the batches retransmitted after reconnects.
+const (
+	SessionMetadataKey  = "loggie-session-id"
+	BatchSeqMetadataKey = "loggie-batch-seq"
+)
@@ -19,11 +19,11 @@ package util
 import (
 	"bytes"
 	"fmt"
-	"regexp"
 	"regexp/syntax"
 	"strings"
 
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/regex"
 )
 
 type trans func(*syntax.Regexp) (bool, *syntax.Regexp)
@@ -190,7 +190,7 @@ func compile(r *syntax.Regexp) (stringMatcher, error) {
 
 	default:
 
-		r, err := regexp.Compile(r.String())
+		r, err := regex.Compile(r.String())
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package net
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/pkg/errors"
+)
+
+// CertReloader reloads the certificate and the ca files once they are modified, so that rotated certificates,
+// such as those renewed by cert-manager, take effect for new connections without restarting
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	caFiles  []string
+	interval time.Duration
+
+	lock      sync.Mutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+// NewCertReloader loads the certificate and the comma separated ca files, which would be checked for
+// modification at most once per interval
+func NewCertReloader(certFile, keyFile, caCertFiles string, interval time.Duration) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+	}
+	if caCertFiles != "" {
+		r.caFiles = strings.Split(caCertFiles, ",")
+	}
+
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.modTime = modTime
+	r.lastCheck = time.Now()
+	return r, nil
+}
+
+func (r *CertReloader) files() []string {
+	var files []string
+	if r.certFile != "" && r.keyFile != "" {
+		files = append(files, r.certFile, r.keyFile)
+	}
+	return append(files, r.caFiles...)
+}
+
+func (r *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *CertReloader) load() error {
+	var cert *tls.Certificate
+	if r.certFile != "" && r.keyFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return errors.WithMessage(err, "load certificate")
+		}
+		cert = &c
+	}
+
+	var pool *x509.CertPool
+	if len(r.caFiles) > 0 {
+		pool = x509.NewCertPool()
+		for _, f := range r.caFiles {
+			ca, err := os.ReadFile(f)
+			if err != nil {
+				return errors.WithMessage(err, "read ca certificate")
+			}
+			if !pool.AppendCertsFromPEM(ca) {
+				return errors.Errorf("no valid ca certificate found in %s", f)
+			}
+		}
+	}
+
+	r.cert = cert
+	r.pool = pool
+	return nil
+}
+
+// current returns the certificate and the ca pool, they would be reloaded first when the files changed.
+// The previous ones are kept if the reloading fails, e.g. the files are being written.
+func (r *CertReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if time.Since(r.lastCheck) < r.interval {
+		return r.cert, r.pool
+	}
+	r.lastCheck = time.Now()
+
+	modTime, err := r.latestModTime()
+	if err != nil {
+		log.Warn("stat certificate files error: %v", err)
+		return r.cert, r.pool
+	}
+	if !modTime.After(r.modTime) {
+		return r.cert, r.pool
+	}
+	if err := r.load(); err != nil {
+		log.Warn("reload certificate files error: %v", err)
+		return r.cert, r.pool
+	}
+	r.modTime = modTime
+	log.Info("certificate files %v reloaded", r.files())
+	return r.cert, r.pool
+}
+
+// ServerTLSConfig returns a server side tls config, client certificates are verified by the ca files
+// with the given auth type
+func (r *CertReloader) ServerTLSConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := r.current()
+			if cert == nil {
+				return nil, errors.New("no server certificate")
+			}
+			return &tls.Config{
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    pool,
+				ClientAuth:   clientAuth,
+			}, nil
+		},
+	}
+}
+
+// GetClientCertificate could be used as tls.Config.GetClientCertificate for clients
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, _ := r.current()
+	if cert == nil {
+		// sending no certificate, the server decides whether it's acceptable
+		return &tls.Certificate{}, nil
+	}
+	return cert, nil
+}
@@ -23,13 +23,14 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	gotime "time"
 )
 
 const (
 	Indicator       = '$'
 	SeparatorPrefix = '{'
 	SeparatorSuffix = '}'
-	matchExpr       = `\${(.+?)}`
+	matchExpr       = `\${(.+?)}|%{(\+.+?)}` // time vars could also be written as %{+yyyy.MM.dd} like beats
 
 	timeToken = "+"
 	envToken  = "_env."
@@ -50,6 +51,7 @@ type Pattern struct {
 	tmpK8sPodData  *TypePodFieldsData
 	tmpK8sNodeData *TypeNodeFieldsData
 	tmpVmData      *TypeVmFieldsData
+	location       *gotime.Location
 }
 
 type matcher struct {
@@ -71,8 +73,11 @@ func envMatcherRender(key string) string {
 func isTimeVar(key string) bool {
 	return strings.HasPrefix(key, timeToken)
 }
-func timeMatcherRender(key string) string {
-	return time.TimeFormatNow(strings.TrimLeft(key, timeToken))
+func timeMatcherRender(key string, loc *gotime.Location) string {
+	if loc == nil {
+		loc = gotime.Local
+	}
+	return time.TimeFormatNowIn(strings.TrimLeft(key, timeToken), loc)
 }
 
 // ObjectMatcher retrieve any fields from events, e.g. ${a.b}
@@ -128,6 +133,9 @@ func MustInit(pattern string) *Pattern {
 func makeMatch(m []string) matcher {
 	keyWrap := m[0]
 	key := m[1]
+	if key == "" {
+		key = m[2]
+	}
 	item := matcher{
 		keyWrap: keyWrap,
 		key:     key,
@@ -168,7 +176,7 @@ func (p *Pattern) render(strict bool) (string, error) {
 		if m.kind == kindEnv {
 			alt = envMatcherRender(m.key)
 		} else if m.kind == kindTime {
-			alt = timeMatcherRender(m.key)
+			alt = timeMatcherRender(m.key, p.location)
 		} else if m.kind == kindObject {
 			o, err := objectMatcherRender(p.tmpObj, m.key)
 			if err != nil {
@@ -193,11 +201,22 @@ func (p *Pattern) render(strict bool) (string, error) {
 	return replacer.Replace(p.Raw), nil
 }
 
+// IsConst returns true if there is no variable in the pattern
+func (p *Pattern) IsConst() bool {
+	return p.isConstVal
+}
+
 func (p *Pattern) WithObject(obj *runtime.Object) *Pattern {
 	p.tmpObj = obj
 	return p
 }
 
+// WithLocation sets the time zone of the time vars, local time is used by default
+func (p *Pattern) WithLocation(loc *gotime.Location) *Pattern {
+	p.location = loc
+	return p
+}
+
 func (p *Pattern) WithK8sPod(data *TypePodFieldsData) *Pattern {
 	p.tmpK8sPodData = data
 	return p
@@ -26,6 +26,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	gotime "time"
 )
 
 func TestExtract(t *testing.T) {
@@ -435,3 +436,13 @@ func TestK8sPatternTypeNode(t *testing.T) {
 		})
 	}
 }
+
+func TestTimePatternWithLocation(t *testing.T) {
+	loc := gotime.FixedZone("UTC+14", 14*3600)
+	p, err := Init("logs-${app}-%{+yyyy.MM.dd}")
+	assert.NoError(t, err)
+
+	got, err := p.WithObject(runtime.NewObject(map[string]interface{}{"app": "web"})).WithLocation(loc).RenderWithStrict()
+	assert.NoError(t, err)
+	assert.Equal(t, "logs-web-"+gotime.Now().In(loc).Format("2006.01.02"), got)
+}
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/health"
 	"github.com/loggie-io/loggie/pkg/core/log"
 )
 
@@ -33,6 +34,8 @@ const (
 
 	TimeFormatPattern = "2006-01-02 15:04:05.999"
 
+	healthName = "registry"
+
 	DeleteByIdOpt               = DbOptType(1)
 	DeleteByJobUidOpt           = DbOptType(2)
 	UpsertOffsetByJobWatchIdOpt = DbOptType(3)
@@ -60,6 +63,10 @@ type DbHandler struct {
 	dbFile    string
 	countDown sync.WaitGroup
 	optChan   chan DbOpt
+	commit    chan []*State
+
+	errLock  sync.Mutex
+	writeErr error // error of the last write, the registry is not ready until a write succeeds again
 }
 
 func NewDbHandler(config DbConfig) *DbHandler {
@@ -68,19 +75,49 @@ func NewDbHandler(config DbConfig) *DbHandler {
 		config:  config,
 		State:   make(chan *State, config.BufferSize),
 		optChan: make(chan DbOpt),
+		commit:  make(chan []*State),
 	}
 
 	d.dbFile = d.config.File
 	d.db = driver.Init(d.dbFile)
+	health.Register(healthName, d.checkHealth)
 
 	go d.run()
 	return d
 }
 
+func (d *DbHandler) checkHealth() error {
+	select {
+	case <-d.done:
+		return fmt.Errorf("registry db %s is closed", d.dbFile)
+	default:
+	}
+	d.errLock.Lock()
+	defer d.errLock.Unlock()
+	if d.writeErr != nil {
+		return fmt.Errorf("write registry db %s failed: %v", d.dbFile, d.writeErr)
+	}
+	return nil
+}
+
+func (d *DbHandler) setWriteErr(err error) {
+	d.errLock.Lock()
+	defer d.errLock.Unlock()
+	d.writeErr = err
+}
+
 func (d *DbHandler) HandleOpt(opt DbOpt) {
 	d.optChan <- opt
 }
 
+// Commit writes the states together with the buffered ones to the registry in one batch immediately
+func (d *DbHandler) Commit(states []*State) {
+	select {
+	case <-d.done:
+	case d.commit <- states:
+	}
+}
+
 func (d *DbHandler) processOpt(optBuffer []DbOpt) {
 	for _, opt := range optBuffer {
 		optType := opt.OptType
@@ -138,6 +175,9 @@ func (d *DbHandler) run() {
 			if len(buffer) >= bufferSize {
 				flush()
 			}
+		case ss := <-d.commit:
+			buffer = append(buffer, ss...)
+			flush()
 		case o := <-d.optChan:
 			if o.Immediately {
 				d.processOpt([]DbOpt{o})
@@ -161,6 +201,7 @@ func (d *DbHandler) run() {
 }
 
 func (d *DbHandler) Stop() {
+	health.Unregister(healthName)
 	close(d.done)
 	if d.db != nil {
 		err := d.db.Close()
@@ -238,15 +279,19 @@ func (d *DbHandler) state2Registry(stat *State) reg.Registry {
 }
 
 func (d *DbHandler) insertRegistry(registries []reg.Registry) {
-	if err := d.db.Insert(registries); err != nil {
+	err := d.db.Insert(registries)
+	if err != nil {
 		log.Error("insert registry fail: %s", err)
 	}
+	d.setWriteErr(err)
 }
 
 func (d *DbHandler) updateRegistry(registries []reg.Registry) {
-	if err := d.db.Update(registries); err != nil {
+	err := d.db.Update(registries)
+	if err != nil {
 		log.Error("update registry fail: %+v", err)
 	}
+	d.setWriteErr(err)
 }
 
 func (d *DbHandler) updateFileName(registries []reg.Registry) {
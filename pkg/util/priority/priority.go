@@ -0,0 +1,104 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package priority
+
+import (
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/event"
+	"github.com/pkg/errors"
+)
+
+// Level is the priority of an event assigned by the priority interceptor, the components degrading under pressure,
+// such as maxbytes and quota, treat the events by their levels instead of matching the fields on their own
+type Level int
+
+const (
+	Low Level = iota
+	Normal
+	High
+	Critical
+)
+
+const (
+	NameLow      = "low"
+	NameNormal   = "normal"
+	NameHigh     = "high"
+	NameCritical = "critical"
+
+	// Key keeps the level in the meta of the event
+	Key = event.SystemKeyPrefix + "Priority"
+)
+
+var names = map[Level]string{
+	Low:      NameLow,
+	Normal:   NameNormal,
+	High:     NameHigh,
+	Critical: NameCritical,
+}
+
+func (l Level) String() string {
+	if name, ok := names[l]; ok {
+		return name
+	}
+	return NameNormal
+}
+
+func Parse(name string) (Level, error) {
+	for l, n := range names {
+		if n == name {
+			return l, nil
+		}
+	}
+	return Normal, errors.Errorf("priority %s is unknown, should be one of critical, high, normal and low", name)
+}
+
+// ParseAll parses the names of the levels, such as the keys of the configuration per priority
+func ParseAll(names []string) (map[Level]struct{}, error) {
+	levels := make(map[Level]struct{}, len(names))
+	for _, n := range names {
+		l, err := Parse(n)
+		if err != nil {
+			return nil, err
+		}
+		levels[l] = struct{}{}
+	}
+	return levels, nil
+}
+
+// Of returns the level of the event, which is normal when not classified
+func Of(e api.Event) Level {
+	if e.Meta() == nil {
+		return Normal
+	}
+	v, ok := e.Meta().Get(Key)
+	if !ok {
+		return Normal
+	}
+	if l, ok := v.(Level); ok {
+		return l
+	}
+	return Normal
+}
+
+func Set(e api.Event, l Level) {
+	meta := e.Meta()
+	if meta == nil {
+		meta = event.NewDefaultMeta()
+		e.Fill(meta, e.Header(), e.Body())
+	}
+	meta.Set(Key, l)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package regex
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrTimeout = errors.New("regex execution exceeds the time budget")
+
+// Budget is the execution time left of a caller, the executions sharing a budget are limited together.
+// The processors, actions and conditions create a budget each time they process an event, so an event passing
+// through N of them could take up to N times the timeout.
+// RE2 executions could not be interrupted, the execution timed out keeps running in the background
+// and holds its concurrency slot until finished, so the runaway executions are bounded by the concurrency.
+type Budget struct {
+	deadline time.Time
+}
+
+// NewBudget returns nil when the timeout is not configured, a nil budget never times out
+func NewBudget() *Budget {
+	if config.Timeout <= 0 {
+		return nil
+	}
+	return &Budget{
+		deadline: time.Now().Add(config.Timeout),
+	}
+}
+
+func (b *Budget) exec(fn func()) error {
+	if b == nil {
+		defer release(acquire())
+		fn()
+		return nil
+	}
+
+	remaining := time.Until(b.deadline)
+	if remaining <= 0 {
+		return ErrTimeout
+	}
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+
+	s := slots
+	if s != nil {
+		select {
+		case s <- struct{}{}:
+		case <-timer.C:
+			return ErrTimeout
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		fn()
+		release(s)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return ErrTimeout
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package regex is the shared regex engine of the interceptors and sources, the patterns are validated and scored
+// when compiling, the compiled patterns are cached and shared by all the pipelines, and the executions could be
+// limited by a global concurrency and a time budget of each processing of an event.
+package regex
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config of the regex engine, it is a part of the system config
+type Config struct {
+	// MaxComplexity rejects the patterns whose complexity is higher when compiling, 0 means no limit
+	MaxComplexity int `yaml:"maxComplexity,omitempty"`
+	// Concurrency limits the executions running at the same time in all pipelines, 0 means no limit
+	Concurrency int `yaml:"concurrency,omitempty"`
+	// Timeout is the execution time budget of each processor, action or condition processing an event, 0 means no limit
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// CacheSize is the max compiled patterns shared by the pipelines
+	CacheSize int `yaml:"cacheSize,omitempty" default:"1024"`
+}
+
+var (
+	config = Config{
+		CacheSize: 1024,
+	}
+	slots chan struct{}
+
+	cacheLock sync.RWMutex
+	cache     = make(map[string]*Regex)
+)
+
+// SetConfig should be called before any pattern is compiled
+func SetConfig(c Config) {
+	config = c
+	if c.Concurrency > 0 {
+		slots = make(chan struct{}, c.Concurrency)
+	} else {
+		slots = nil
+	}
+
+	cacheLock.Lock()
+	cache = make(map[string]*Regex)
+	cacheLock.Unlock()
+}
+
+// Regex is a compiled pattern which is safe for concurrent use
+type Regex struct {
+	re         *regexp.Regexp
+	complexity int
+}
+
+// Compile validates and compiles the pattern, the java style named groups such as (?<name>re) are supported
+func Compile(expr string) (*Regex, error) {
+	cacheLock.RLock()
+	r, ok := cache[expr]
+	cacheLock.RUnlock()
+	if ok {
+		return r, nil
+	}
+
+	complexity, err := Complexity(expr)
+	if err != nil {
+		return nil, err
+	}
+	if config.MaxComplexity > 0 && complexity > config.MaxComplexity {
+		return nil, fmt.Errorf("complexity %d of regex %s exceeds the limit %d", complexity, expr, config.MaxComplexity)
+	}
+	re, err := regexp.Compile(javaStyle(expr))
+	if err != nil {
+		return nil, err
+	}
+	r = &Regex{
+		re:         re,
+		complexity: complexity,
+	}
+
+	cacheLock.Lock()
+	if len(cache) < config.CacheSize {
+		cache[expr] = r
+	}
+	cacheLock.Unlock()
+	return r, nil
+}
+
+func MustCompile(expr string) *Regex {
+	r, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Validate checks the syntax and complexity of the pattern without compiling it
+func Validate(expr string) error {
+	complexity, err := Complexity(expr)
+	if err != nil {
+		return err
+	}
+	if config.MaxComplexity > 0 && complexity > config.MaxComplexity {
+		return fmt.Errorf("complexity %d of regex %s exceeds the limit %d", complexity, expr, config.MaxComplexity)
+	}
+	return nil
+}
+
+// Complexity is the instructions of the compiled program, RE2 matches in time linear to it multiplied by the input length.
+// The counted repetitions such as (a{100}){100} are expanded, so they are more complex than they look.
+func Complexity(expr string) (int, error) {
+	re, err := syntax.Parse(javaStyle(expr), syntax.Perl)
+	if err != nil {
+		return 0, err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return 0, err
+	}
+	return len(prog.Inst), nil
+}
+
+func javaStyle(expr string) string {
+	// compile java、c# named capturing groups style
+	if strings.Contains(expr, "?<") {
+		return strings.ReplaceAll(expr, "?<", "?P<")
+	}
+	return expr
+}
+
+func (r *Regex) String() string {
+	return r.re.String()
+}
+
+func (r *Regex) SubexpNames() []string {
+	return r.re.SubexpNames()
+}
+
+func (r *Regex) Complexity() int {
+	return r.complexity
+}
+
+// Match is limited by the concurrency only, as well as MatchString and FindStringSubmatch
+func (r *Regex) Match(b []byte) bool {
+	defer release(acquire())
+	return r.re.Match(b)
+}
+
+func (r *Regex) MatchString(s string) bool {
+	defer release(acquire())
+	return r.re.MatchString(s)
+}
+
+func (r *Regex) FindStringSubmatch(s string) []string {
+	defer release(acquire())
+	return r.re.FindStringSubmatch(s)
+}
+
+// MatchStringWithin returns ErrTimeout when the budget is exhausted
+func (r *Regex) MatchStringWithin(b *Budget, s string) (bool, error) {
+	var matched bool
+	if err := b.exec(func() {
+		matched = r.re.MatchString(s)
+	}); err != nil {
+		return false, err
+	}
+	return matched, nil
+}
+
+// FindStringSubmatchWithin returns ErrTimeout when the budget is exhausted
+func (r *Regex) FindStringSubmatchWithin(b *Budget, s string) ([]string, error) {
+	var match []string
+	// the result is only read after the execution finished, the timed out execution may still write it
+	if err := b.exec(func() {
+		match = r.re.FindStringSubmatch(s)
+	}); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+// ReplaceAllStringWithin replaces the matches of src with repl, in which the capture groups could be referred
+// such as $1 or ${name}. It returns ErrTimeout when the budget is exhausted
+func (r *Regex) ReplaceAllStringWithin(b *Budget, src string, repl string) (string, error) {
+	var out string
+	if err := b.exec(func() {
+		out = r.re.ReplaceAllString(src, repl)
+	}); err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// MatchGroupWithin returns the named groups of the first match, or nil if not matched
+func (r *Regex) MatchGroupWithin(b *Budget, s string) (map[string]string, error) {
+	match, err := r.FindStringSubmatchWithin(b, s)
+	if err != nil || len(match) == 0 {
+		return nil, err
+	}
+	groups := make(map[string]string, len(match))
+	for i, name := range r.re.SubexpNames() {
+		if i > 0 && name != "" {
+			groups[name] = match[i]
+		}
+	}
+	return groups, nil
+}
+
+// acquire returns the slots it acquired from, so the slot is released to the same channel
+func acquire() chan struct{} {
+	s := slots
+	if s != nil {
+		s <- struct{}{}
+	}
+	return s
+}
+
+func release(s chan struct{}) {
+	if s != nil {
+		<-s
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package regex
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	SetConfig(Config{CacheSize: 1, MaxComplexity: 1000})
+	defer SetConfig(Config{CacheSize: 1024})
+
+	r, err := Compile(`^(?<level>\w+) (?<msg>.*)$`)
+	assert.NoError(t, err)
+	groups, err := r.MatchGroupWithin(nil, "INFO started")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"level": "INFO", "msg": "started"}, groups)
+
+	// the compiled patterns are shared
+	cached, _ := Compile(`^(?<level>\w+) (?<msg>.*)$`)
+	assert.Same(t, r, cached)
+	other, _ := Compile(`\d+`)
+	again, _ := Compile(`\d+`)
+	assert.NotSame(t, other, again, "cache is full")
+
+	out, err := r.ReplaceAllStringWithin(nil, "INFO started", "${msg}:$1")
+	assert.NoError(t, err)
+	assert.Equal(t, "started:INFO", out)
+
+	_, err = Compile(`(\w+`)
+	assert.Error(t, err)
+
+	_, err = Compile(`((a{100}){100})`)
+	assert.Error(t, err, "complexity exceeds the limit")
+	assert.Error(t, Validate(`((a{100}){100})`))
+	assert.NoError(t, Validate(`a{10}`))
+}
+
+func TestBudget(t *testing.T) {
+	assert.Nil(t, NewBudget())
+
+	SetConfig(Config{CacheSize: 1024, Concurrency: 1, Timeout: 50 * time.Millisecond})
+	defer SetConfig(Config{CacheSize: 1024})
+
+	r := MustCompile(`(\w+\s?)+$`)
+	b := NewBudget()
+	matched, err := r.MatchStringWithin(b, "hello world")
+	assert.NoError(t, err)
+	assert.True(t, matched)
+
+	// the only concurrency slot is taken, the execution times out waiting for it
+	s := acquire()
+	_, err = r.FindStringSubmatchWithin(b, "hello world")
+	assert.Equal(t, ErrTimeout, err)
+	release(s)
+
+	// the budget is shared by the executions given it
+	time.Sleep(60 * time.Millisecond)
+	_, err = r.MatchStringWithin(b, strings.Repeat("a", 10))
+	assert.Equal(t, ErrTimeout, err)
+
+	_, err = r.MatchStringWithin(NewBudget(), "hello")
+	assert.NoError(t, err)
+}
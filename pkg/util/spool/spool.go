@@ -0,0 +1,375 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package spool buffers the events of each partition in local files for the sinks uploading objects,
+// the buffer survives the restarts, so the events are acked once they are appended.
+package spool
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/util/json"
+)
+
+// the files of each object in the buffer dir, the spool is appended by the events of a partition,
+// then it is sealed into the object to upload, the upload state is kept to resume the uploads
+const (
+	extMeta   = ".meta"
+	extSpool  = ".spool"
+	extObject = ".object"
+	extUpload = ".upload"
+	extTmp    = ".tmp"
+)
+
+// Meta is written before the spool, a spool without meta could never be sealed
+type Meta struct {
+	Prefix  string    `json:"prefix"`
+	Created time.Time `json:"created"`
+}
+
+// EncodeFunc converts the lines of a spool into the object, and returns the number of lines encoded
+type EncodeFunc func(in io.Reader, out io.Writer) (int, error)
+
+// StateFunc returns the upload state of the object sealed from the spool, which is kept until the object is uploaded
+type StateFunc func(id string, meta *Meta) interface{}
+
+type spool struct {
+	id      string
+	prefix  string
+	created time.Time
+	file    *os.File
+	size    int64
+}
+
+// Buffer keeps the spools of the partitions being appended, and the sealed objects to upload
+type Buffer struct {
+	dir           string
+	maxBytes      int64
+	maxAge        time.Duration
+	maxPartitions int
+
+	lock   sync.Mutex
+	spools map[string]*spool // by the partition prefix
+}
+
+func New(dir string, maxBytes int64, maxAge time.Duration, maxPartitions int) *Buffer {
+	return &Buffer{
+		dir:           dir,
+		maxBytes:      maxBytes,
+		maxAge:        maxAge,
+		maxPartitions: maxPartitions,
+		spools:        make(map[string]*spool),
+	}
+}
+
+func (b *Buffer) Dir() string {
+	return b.dir
+}
+
+func (b *Buffer) path(id string, ext string) string {
+	return filepath.Join(b.dir, id+ext)
+}
+
+// Append writes the lines of each partition and syncs them, returns true if any spool was rolled
+func (b *Buffer) Append(lines map[string][]byte) (bool, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	rolled := false
+	for prefix, data := range lines {
+		sp, ok := b.spools[prefix]
+		if !ok {
+			if b.maxPartitions > 0 && len(b.spools) >= b.maxPartitions {
+				b.closeOldest()
+				rolled = true
+			}
+			var err error
+			if sp, err = b.create(prefix); err != nil {
+				return rolled, err
+			}
+			b.spools[prefix] = sp
+		}
+
+		if _, err := sp.file.Write(data); err != nil {
+			return rolled, errors.WithMessagef(err, "write spool %s", sp.file.Name())
+		}
+		if err := sp.file.Sync(); err != nil {
+			return rolled, errors.WithMessagef(err, "sync spool %s", sp.file.Name())
+		}
+		sp.size += int64(len(data))
+		if sp.size >= b.maxBytes {
+			b.close(sp)
+			rolled = true
+		}
+	}
+	return rolled, nil
+}
+
+func (b *Buffer) create(prefix string) (*spool, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	sp := &spool{
+		id:      id,
+		prefix:  prefix,
+		created: time.Now(),
+	}
+	if err := writeJSON(b.path(id, extMeta), &Meta{Prefix: prefix, Created: sp.created}); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(b.path(id, extSpool), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.WithMessage(err, "create spool")
+	}
+	sp.file = f
+	return sp, nil
+}
+
+func (b *Buffer) close(sp *spool) {
+	_ = sp.file.Close()
+	delete(b.spools, sp.prefix)
+}
+
+func (b *Buffer) closeOldest() {
+	var oldest *spool
+	for _, sp := range b.spools {
+		if oldest == nil || sp.created.Before(oldest.created) {
+			oldest = sp
+		}
+	}
+	if oldest != nil {
+		b.close(oldest)
+	}
+}
+
+// RollExpired closes the spools older than the max age, returns true if any spool was rolled
+func (b *Buffer) RollExpired(now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	rolled := false
+	for _, sp := range b.spools {
+		if now.Sub(sp.created) >= b.maxAge {
+			b.close(sp)
+			rolled = true
+		}
+	}
+	return rolled
+}
+
+// active means the spool is still being appended, so it cannot be sealed
+func (b *Buffer) active(id string) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, sp := range b.spools {
+		if sp.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// CloseAll closes the spools being appended, they are sealed after restarting
+func (b *Buffer) CloseAll() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, sp := range b.spools {
+		b.close(sp)
+	}
+}
+
+// Seal converts the rolled spools into the objects, and returns the ids of the objects to upload.
+// The ids are sorted by time, so the objects uploaded in order keep the order of the events.
+func (b *Buffer) Seal(encode EncodeFunc, state StateFunc) ([]string, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, errors.WithMessage(err, "read buffer dir")
+	}
+	uploads := make(map[string]struct{})
+	var metas []string
+	for _, entry := range entries {
+		name := entry.Name()
+		switch filepath.Ext(name) {
+		case extUpload:
+			uploads[strings.TrimSuffix(name, extUpload)] = struct{}{}
+		case extMeta:
+			metas = append(metas, strings.TrimSuffix(name, extMeta))
+		}
+	}
+
+	for _, id := range metas {
+		if _, ok := uploads[id]; ok {
+			// sealed before crashed
+			b.removeSpool(id)
+			continue
+		}
+		if b.active(id) {
+			continue
+		}
+		sealed, err := b.seal(id, encode, state)
+		if err != nil {
+			log.Warn("seal spool %s in %s error: %v", id, b.dir, err)
+			continue
+		}
+		if sealed {
+			uploads[id] = struct{}{}
+		}
+	}
+
+	ids := make([]string, 0, len(uploads))
+	for id := range uploads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// seal returns false if the spool is empty, which is removed without an object
+func (b *Buffer) seal(id string, encode EncodeFunc, state StateFunc) (bool, error) {
+	meta := &Meta{}
+	if err := readJSON(b.path(id, extMeta), meta); err != nil {
+		return false, errors.WithMessage(err, "read spool meta")
+	}
+
+	in, err := os.Open(b.path(id, extSpool))
+	if os.IsNotExist(err) {
+		return false, os.Remove(b.path(id, extMeta))
+	}
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+
+	tmp := b.path(id, extObject+extTmp)
+	out, err := os.Create(tmp)
+	if err != nil {
+		return false, err
+	}
+	lines, err := encode(in, out)
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return false, err
+	}
+
+	if lines == 0 {
+		_ = os.Remove(tmp)
+		b.removeSpool(id)
+		return false, nil
+	}
+	if err := os.Rename(tmp, b.path(id, extObject)); err != nil {
+		return false, err
+	}
+	if err := writeJSON(b.path(id, extUpload), state(id, meta)); err != nil {
+		return false, err
+	}
+	b.removeSpool(id)
+	return true, nil
+}
+
+func (b *Buffer) removeSpool(id string) {
+	_ = os.Remove(b.path(id, extSpool))
+	_ = os.Remove(b.path(id, extMeta))
+}
+
+// Open opens the sealed object to upload
+func (b *Buffer) Open(id string) (*os.File, error) {
+	return os.Open(b.path(id, extObject))
+}
+
+// ReadState reads the upload state of the object
+func (b *Buffer) ReadState(id string, state interface{}) error {
+	return readJSON(b.path(id, extUpload), state)
+}
+
+// WriteState saves the progress of the upload, so the upload is resumed from it after restarting
+func (b *Buffer) WriteState(id string, state interface{}) error {
+	return writeJSON(b.path(id, extUpload), state)
+}
+
+// Remove removes the object and its upload state after it is uploaded
+func (b *Buffer) Remove(id string) {
+	_ = os.Remove(b.path(id, extObject))
+	_ = os.Remove(b.path(id, extUpload))
+}
+
+// ReadLines calls fn with each line of the spool, and returns the number of lines read. The last line without '\n'
+// is dropped, which is partially written when loggie crashed.
+func ReadLines(in io.Reader, fn func(line []byte) error) (int, error) {
+	r := bufio.NewReader(in)
+	lines := 0
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+		if err := fn(line); err != nil {
+			return lines, err
+		}
+		lines++
+	}
+}
+
+func newID() (string, error) {
+	r := make([]byte, 4)
+	if _, err := rand.Read(r); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + hex.EncodeToString(r), nil
+}
+
+// writeJSON writes the file atomically by renaming
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + extTmp
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2023 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spool
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+)
+
+func init() {
+	log.InitDefaultLogger()
+}
+
+type testState struct {
+	Key string `json:"key"`
+}
+
+func copyLines(in io.Reader, out io.Writer) (int, error) {
+	return ReadLines(in, func(line []byte) error {
+		_, err := out.Write(line)
+		return err
+	})
+}
+
+func newTestState(id string, meta *Meta) interface{} {
+	return &testState{Key: meta.Prefix + id}
+}
+
+func objects(t *testing.T, b *Buffer, ids []string) map[string]string {
+	result := make(map[string]string)
+	for _, id := range ids {
+		state := &testState{}
+		assert.NoError(t, b.ReadState(id, state))
+		f, err := b.Open(id)
+		assert.NoError(t, err)
+		content, err := io.ReadAll(f)
+		assert.NoError(t, err)
+		f.Close()
+		result[state.Key[:len(state.Key)-len(id)]] = string(content)
+	}
+	return result
+}
+
+func TestBuffer_Append(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxBytes      int64
+		maxPartitions int
+		appends       []map[string][]byte
+		wantRolled    []bool
+		wantActive    int
+		// the objects sealed from the rolled spools by the prefix
+		want map[string]string
+	}{
+		{
+			name:       "not rolled",
+			maxBytes:   1024,
+			appends:    []map[string][]byte{{"a/": []byte("1\n")}, {"a/": []byte("2\n"), "b/": []byte("3\n")}},
+			wantRolled: []bool{false, false},
+			wantActive: 2,
+			want:       map[string]string{},
+		},
+		{
+			name:       "rolled by max bytes",
+			maxBytes:   4,
+			appends:    []map[string][]byte{{"a/": []byte("1\n")}, {"a/": []byte("2\n")}, {"a/": []byte("3\n")}},
+			wantRolled: []bool{false, true, false},
+			wantActive: 1,
+			want:       map[string]string{"a/": "1\n2\n"},
+		},
+		{
+			name:          "oldest rolled by max partitions",
+			maxBytes:      1024,
+			maxPartitions: 2,
+			appends:       []map[string][]byte{{"a/": []byte("1\n")}, {"b/": []byte("2\n")}, {"c/": []byte("3\n")}},
+			wantRolled:    []bool{false, false, true},
+			wantActive:    2,
+			want:          map[string]string{"a/": "1\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(t.TempDir(), tt.maxBytes, time.Hour, tt.maxPartitions)
+			for i, lines := range tt.appends {
+				rolled, err := b.Append(lines)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantRolled[i], rolled, i)
+				// the spools are created in different nanoseconds to be ordered
+				time.Sleep(time.Millisecond)
+			}
+			assert.Len(t, b.spools, tt.wantActive)
+
+			ids, err := b.Seal(copyLines, newTestState)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, objects(t, b, ids))
+		})
+	}
+}
+
+func TestBuffer_RollExpired(t *testing.T) {
+	b := New(t.TempDir(), 1024, time.Minute, 0)
+	_, err := b.Append(map[string][]byte{"a/": []byte("1\n")})
+	assert.NoError(t, err)
+
+	assert.False(t, b.RollExpired(time.Now()))
+	assert.True(t, b.RollExpired(time.Now().Add(time.Minute)))
+	assert.Empty(t, b.spools)
+}
+
+func TestBuffer_Seal(t *testing.T) {
+	b := New(t.TempDir(), 1024, time.Minute, 0)
+	dir := b.Dir()
+
+	// left by the last run, the partial line is dropped
+	assert.NoError(t, writeJSON(b.path("1", extMeta), &Meta{Prefix: "a/"}))
+	assert.NoError(t, os.WriteFile(b.path("1", extSpool), []byte("1\n2"), 0644))
+	// the empty spool is removed without an object
+	assert.NoError(t, writeJSON(b.path("2", extMeta), &Meta{Prefix: "b/"}))
+	assert.NoError(t, os.WriteFile(b.path("2", extSpool), []byte("partial"), 0644))
+	// sealed before crashed, the spool is removed
+	assert.NoError(t, writeJSON(b.path("0", extMeta), &Meta{Prefix: "c/"}))
+	assert.NoError(t, os.WriteFile(b.path("0", extSpool), []byte("3\n"), 0644))
+	assert.NoError(t, os.WriteFile(b.path("0", extObject), []byte("3\n"), 0644))
+	assert.NoError(t, b.WriteState("0", &testState{Key: "c/0"}))
+	// the active spool is not sealed
+	_, err := b.Append(map[string][]byte{"d/": []byte("4\n")})
+	assert.NoError(t, err)
+
+	ids, err := b.Seal(copyLines, newTestState)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0", "1"}, ids)
+	assert.Equal(t, map[string]string{"a/": "1\n", "c/": "3\n"}, objects(t, b, ids))
+
+	for _, id := range ids {
+		b.Remove(id)
+	}
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	// only the active spool and its meta are left
+	assert.Len(t, entries, 2)
+
+	b.CloseAll()
+	ids, err = b.Seal(copyLines, newTestState)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"d/": "4\n"}, objects(t, b, ids))
+}
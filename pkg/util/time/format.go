@@ -17,8 +17,10 @@ limitations under the License.
 package time
 
 import (
-	"github.com/pkg/errors"
+	"strconv"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 const (
@@ -44,3 +46,26 @@ func Format(timestamp time.Time, location string, layout string) (interface{}, e
 		return timestamp.Format(layout), nil
 	}
 }
+
+// Parse parses the timestamp in the layout, LayoutUnix and LayoutUnixMs are parsed as the epoch,
+// and the location is used when the layout has no time zone
+func Parse(val string, layout string, location *time.Location) (time.Time, error) {
+	switch layout {
+	case LayoutUnix:
+		sec, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(sec, 0), nil
+
+	case LayoutUnixMs:
+		ms, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.UnixMilli(ms), nil
+
+	default:
+		return time.ParseInLocation(layout, val, location)
+	}
+}
@@ -34,12 +34,24 @@ const (
 	stdDay   = "02"
 	hour     = "hh"
 	stdHour  = "15"
+
+	// joda style used by beats and logstash, e.g. yyyy.MM.dd
+	jodaYear = "yyyy"
+	jodaDay  = "dd"
+	jodaHour = "HH"
 )
 
+var layoutReplacer = strings.NewReplacer(year, stdYear, jodaYear, stdYear, month, stdMonth, day, stdDay, jodaDay, stdDay,
+	hour, stdHour, jodaHour, stdHour)
+
 func TimeFormatNow(pattern string) string {
-	replacer := strings.NewReplacer(year, stdYear, month, stdMonth, day, stdDay, hour, stdHour)
-	layout := replacer.Replace(pattern)
-	return time.Now().Local().Format(layout)
+	return TimeFormatNowIn(pattern, time.Local)
+}
+
+// TimeFormatNowIn formats the current time in the location
+func TimeFormatNowIn(pattern string, loc *time.Location) string {
+	layout := layoutReplacer.Replace(pattern)
+	return time.Now().In(loc).Format(layout)
 }
 
 func UnixMilli(t time.Time) int64 {
@@ -73,3 +73,63 @@ func TestParseDuration(t *testing.T) {
 		t.Errorf("Expected to be equal: %s vs %s", oneDay, c.Duration.Duration())
 	}
 }
+
+func TestParse(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	tests := []struct {
+		name     string
+		val      string
+		layout   string
+		location *time.Location
+		want     time.Time
+		wantErr  bool
+	}{
+		{
+			name:   "unix",
+			val:    "1672531200",
+			layout: LayoutUnix,
+			want:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "unix ms",
+			val:    "1672531200123",
+			layout: LayoutUnixMs,
+			want:   time.Date(2023, 1, 1, 0, 0, 0, 123000000, time.UTC),
+		},
+		{
+			name:    "invalid unix",
+			val:     "2023-01-01",
+			layout:  LayoutUnix,
+			wantErr: true,
+		},
+		{
+			name:     "layout in location",
+			val:      "2023-01-01 08:00:00",
+			layout:   "2006-01-02 15:04:05",
+			location: shanghai,
+			want:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "layout with zone",
+			val:      "2023-01-01T08:00:00+08:00",
+			layout:   time.RFC3339,
+			location: time.UTC,
+			want:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := tt.location
+			if location == nil {
+				location = time.UTC
+			}
+			got, err := Parse(tt.val, tt.layout, location)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -133,6 +133,14 @@ pipelines:
 							Unstable: 15,
 							Stable:   30,
 						},
+						Aimd: &concurrency.Aimd{
+							Min:            2,
+							Max:            32,
+							Interval:       5 * time.Second,
+							Increase:       1,
+							DecreaseFactor: 0.5,
+							LatencyRatio:   2,
+						},
 					},
 				},
 			},